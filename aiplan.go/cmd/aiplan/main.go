@@ -26,7 +26,7 @@ import (
 
 var version string = "DEV"
 
-var models = []any{&dao.ActivityEvent{}, &dao.ActivityTelegramMessage{}, &dao.CommentReaction{}, &dao.DeferredNotifications{}, &dao.Doc{}, &dao.DocAccessRules{}, &dao.DocAttachment{}, &dao.DocComment{}, &dao.DocCommentReaction{}, &dao.DocFavorites{}, &dao.Estimate{}, &dao.EstimatePoint{}, &dao.FileAsset{}, &dao.ForeignKey{}, &dao.Form{}, &dao.FormAnswer{}, &dao.FormAttachment{}, &dao.ImportedProject{}, &dao.Issue{}, &dao.IssueAssignee{}, &dao.IssueAttachment{}, &dao.IssueBlocker{}, &dao.IssueComment{}, &dao.IssueDescriptionLock{}, &dao.IssueLabel{}, &dao.IssueLink{}, &dao.IssueProperty{}, &dao.IssueTemplate{}, &dao.IssueWatcher{}, &dao.JitsiTokenLog{}, &dao.Label{}, &dao.LinkedIssues{}, &dao.NotifyService{}, &dao.Project{}, &dao.ProjectFavorites{}, &dao.ProjectMember{}, &dao.ProjectMemberWithLead{}, &dao.ProjectPropertyTemplate{}, &dao.ReleaseNote{}, &dao.RulesLog{}, &dao.SearchFilter{}, &dao.SessionsReset{}, &dao.Sprint{}, &dao.SprintFolder{}, &dao.SprintIssue{}, &dao.SprintViews{}, &dao.SprintWatcher{}, &dao.State{}, &dao.Team{}, &dao.TeamMembers{}, &dao.Template{}, &dao.User{}, &dao.UserAppNotify{}, &dao.UserFeedback{}, &dao.Workspace{}, &dao.WorkspaceBackup{}, &dao.WorkspaceFavorites{}, &dao.WorkspaceMember{}, &dao.WorkspaceMemberWithOwner{}}
+var models = []any{&dao.ActivityEvent{}, &dao.ActivityTelegramMessage{}, &dao.CommentReaction{}, &dao.DeferredNotifications{}, &dao.Doc{}, &dao.DocAccessRules{}, &dao.DocAttachment{}, &dao.DocComment{}, &dao.DocCommentReaction{}, &dao.DocFavorites{}, &dao.DocShareLink{}, &dao.DocTemplate{}, &dao.EntityReference{}, &dao.Estimate{}, &dao.EstimatePoint{}, &dao.FileAsset{}, &dao.ForeignKey{}, &dao.Form{}, &dao.FormAnswer{}, &dao.FormAttachment{}, &dao.ImportedProject{}, &dao.Issue{}, &dao.IssueAssignee{}, &dao.IssueAttachment{}, &dao.IssueBlocker{}, &dao.IssueComment{}, &dao.IssueDescriptionLock{}, &dao.IssueLabel{}, &dao.IssueLink{}, &dao.IssueProperty{}, &dao.IssueTemplate{}, &dao.IssueWatcher{}, &dao.JitsiTokenLog{}, &dao.Label{}, &dao.LdapGroupMapping{}, &dao.LinkedIssues{}, &dao.NotifyService{}, &dao.PersonalAccessToken{}, &dao.Project{}, &dao.ProjectFavorites{}, &dao.ProjectMember{}, &dao.ProjectMemberWithLead{}, &dao.ProjectPropertyTemplate{}, &dao.ReleaseNote{}, &dao.RulesLog{}, &dao.SearchFilter{}, &dao.SessionsReset{}, &dao.Sprint{}, &dao.SprintFolder{}, &dao.SprintIssue{}, &dao.SprintViews{}, &dao.SprintWatcher{}, &dao.State{}, &dao.Team{}, &dao.TeamMembers{}, &dao.Template{}, &dao.User{}, &dao.UserAppNotify{}, &dao.UserFeedback{}, &dao.UserRecoveryCode{}, &dao.UserSession{}, &dao.Workspace{}, &dao.WorkspaceBackup{}, &dao.WorkspaceFavorites{}, &dao.WorkspaceMember{}, &dao.WorkspaceMemberWithOwner{}}
 
 //go:embed triggers.sql
 var triggersSQL string
@@ -55,6 +55,8 @@ type UserMeta struct {
 	AccessToken  *token.Token
 	RefreshToken *token.Token
 	TokenAuth    bool
+	// PersonalToken - персональный токен доступа, если запрос авторизован им
+	PersonalToken *dao.PersonalAccessToken
 }
 
 type Issue struct {
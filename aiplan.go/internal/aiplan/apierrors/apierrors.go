@@ -111,6 +111,25 @@ var (
 	ErrUserAlreadyExist         = DefinedError{Code: 1008, Err: "user already exist", RuErr: "Пользователь с указанным email уже зарегистрирован в системе"}
 	ErrBlockedUntil             = DefinedError{Code: 1009, StatusCode: http.StatusUnauthorized, Err: "blocked until %s", RuErr: "Учетная запись заблокирована до %s"}
 	ErrNewUserMailFailed        = DefinedError{Code: 1010, Err: "failed to deliver email with password to new user", RuErr: "Не удалось отправить пароль на указанную почту. Проверьте корректность указанного адреса"}
+	ErrSSOOnly                  = DefinedError{Code: 1011, StatusCode: http.StatusForbidden, Err: "password login is disabled, use SSO", RuErr: "Вход по паролю отключен, используйте вход через SSO"}
+	ErrSSODisabled              = DefinedError{Code: 1012, StatusCode: http.StatusNotFound, Err: "SSO login is not configured", RuErr: "Вход через SSO не настроен"}
+	ErrSSOFailed                = DefinedError{Code: 1013, StatusCode: http.StatusUnauthorized, Err: "SSO login failed", RuErr: "Не удалось войти через SSO"}
+	ErrTwoFactorCodeInvalid     = DefinedError{Code: 1014, StatusCode: http.StatusUnauthorized, Err: "invalid two-factor authentication code", RuErr: "Неверный код двухфакторной аутентификации"}
+	ErrTwoFactorTokenInvalid    = DefinedError{Code: 1015, StatusCode: http.StatusUnauthorized, Err: "two-factor sign in expired, sign in again", RuErr: "Время на ввод кода истекло, войдите заново"}
+	ErrTwoFactorRequired        = DefinedError{Code: 1016, StatusCode: http.StatusForbidden, Err: "two-factor authentication is required", RuErr: "Двухфакторная аутентификация обязательна и не может быть отключена"}
+	ErrTwoFactorAlreadyEnabled  = DefinedError{Code: 1017, StatusCode: http.StatusBadRequest, Err: "two-factor authentication is already enabled", RuErr: "Двухфакторная аутентификация уже подключена"}
+	ErrTwoFactorNotEnabled      = DefinedError{Code: 1018, StatusCode: http.StatusBadRequest, Err: "two-factor authentication is not enabled", RuErr: "Двухфакторная аутентификация не подключена"}
+	ErrTwoFactorSetupRequired   = DefinedError{Code: 1019, StatusCode: http.StatusBadRequest, Err: "two-factor authentication setup is not started", RuErr: "Сначала начните подключение приложения-аутентификатора"}
+	ErrAccessTokenScope         = DefinedError{Code: 1020, StatusCode: http.StatusForbidden, Err: "access token scope does not allow this request", RuErr: "Области действия токена не разрешают этот запрос"}
+	ErrAccessTokenWorkspace     = DefinedError{Code: 1021, StatusCode: http.StatusForbidden, Err: "access token is restricted to another workspace", RuErr: "Токен действует только в другом пространстве"}
+	ErrAccessTokenNotFound      = DefinedError{Code: 1022, StatusCode: http.StatusNotFound, Err: "access token not found", RuErr: "Токен не найден"}
+	ErrAccessTokenInvalid       = DefinedError{Code: 1023, StatusCode: http.StatusBadRequest, Err: "invalid access token parameters", RuErr: "Некорректные параметры токена: укажите название, области действия и срок действия в будущем"}
+	ErrAccessTokenManagement    = DefinedError{Code: 1024, StatusCode: http.StatusForbidden, Err: "access tokens can be managed only from a browser session", RuErr: "Токены можно изменять только после входа в приложение"}
+	ErrSessionNotFound          = DefinedError{Code: 1025, StatusCode: http.StatusNotFound, Err: "session not found", RuErr: "Сессия не найдена"}
+	ErrLdapNotConfigured        = DefinedError{Code: 1026, StatusCode: http.StatusBadRequest, Err: "LDAP is not configured", RuErr: "LDAP не настроен"}
+	ErrLdapGroupMappingNotFound = DefinedError{Code: 1027, StatusCode: http.StatusNotFound, Err: "LDAP group mapping not found", RuErr: "Сопоставление группы LDAP не найдено"}
+	ErrLdapGroupMappingInvalid  = DefinedError{Code: 1028, StatusCode: http.StatusBadRequest, Err: "LDAP group mapping requires group, workspace and role", RuErr: "Для сопоставления группы LDAP нужно указать группу, пространство и роль"}
+	ErrSSOAccountLink           = DefinedError{Code: 1029, StatusCode: http.StatusForbidden, Err: "account can not be linked to SSO automatically", RuErr: "Учетную запись нельзя автоматически связать со входом через SSO, обратитесь к администратору"}
 	ErrRequestTimeout           = DefinedError{Code: 1000, StatusCode: http.StatusRequestTimeout, Err: "request timeout", RuErr: "Время ожидания запроса истекло, повторите позже"}
 
 	// 11** - session errors
@@ -185,38 +204,57 @@ var (
 	ErrProjectArchived                   = DefinedError{Code: 3032, StatusCode: http.StatusLocked, Err: "project archived", RuErr: "Данный проект заархивирован, доступны только операции на чтение"}
 
 	// 32** - form errors
-	ErrFormNotFound           = DefinedError{Code: 3201, StatusCode: http.StatusNotFound, Err: "form not found", RuErr: "Форма не найдена"}
-	ErrFormAnswerForbidden    = DefinedError{Code: 3202, StatusCode: http.StatusForbidden, Err: "access to the form requires authorization", RuErr: "Для доступа к форме необходимо пройти авторизацию"}
-	ErrFormForbidden          = DefinedError{Code: 3203, StatusCode: http.StatusForbidden, Err: "not allowed for current role", RuErr: "У вас недостаточно прав для выполнения действия"}
-	ErrFormBadConvertRequest  = DefinedError{Code: 3204, StatusCode: http.StatusBadRequest, Err: "bad request, field: '%s'", RuErr: "При создании/обновлении формы передан неверный тип поля: '%s'"}
-	ErrFormBadRequest         = DefinedError{Code: 3205, StatusCode: http.StatusBadRequest, Err: "bad request", RuErr: "Некорректный запрос"}
-	ErrFormRequestValidate    = DefinedError{Code: 3206, StatusCode: http.StatusBadRequest, Err: "validation error", RuErr: "Введены некорректные данные"}
-	ErrFormCheckFields        = DefinedError{Code: 3207, StatusCode: http.StatusBadRequest, Err: "fields request error: '%s'", RuErr: "При создании формы задан неподдерживаемый тип поля: '%s"}
-	ErrFormCheckAnswers       = DefinedError{Code: 3208, StatusCode: http.StatusBadRequest, Err: "required field missing or wrong type", RuErr: "При отправке ответа на форму не заполнены обязательные поля или выбран не соответствующий тип значения"}
-	ErrFormEmptyAnswers       = DefinedError{Code: 3209, StatusCode: http.StatusBadRequest, Err: "empty answers", RuErr: "Для сохранения формы необходимо заполнить поля с ответами"}
-	ErrFormAnswerNotFound     = DefinedError{Code: 3210, StatusCode: http.StatusNotFound, Err: "answer not found", RuErr: "Ответ не найден"}
-	ErrFormAnswerEnd          = DefinedError{Code: 3211, StatusCode: http.StatusBadRequest, Err: "form is closed", RuErr: "Форма закрыта"}
-	ErrLenAnswers             = DefinedError{Code: 3212, StatusCode: http.StatusBadRequest, Err: "incorrect number of answers", RuErr: "Некорректное количество ответов"}
-	ErrFormEndDate            = DefinedError{Code: 3213, StatusCode: http.StatusBadRequest, Err: "the form cannot be created with a closed date", RuErr: "Форма не может быть создана с завершенной датой"}
-	ErrFormAttachmentNotFound = DefinedError{Code: 3214, StatusCode: http.StatusBadRequest, Err: "file not found by the provided UUID", RuErr: "Файл по указанному UUID не найден"}
-	ErrAttachmentInUse        = DefinedError{Code: 3215, StatusCode: http.StatusConflict, Err: "cannot delete file: it is linked to a form answer", RuErr: "Невозможно удалить файл — он привязан к ответу формы"}
-	ErrFormDependOn           = DefinedError{Code: 3216, StatusCode: http.StatusBadRequest, Err: "depend_on field has invalid value", RuErr: "Значение зависимого поля не соответствует требованиям"}
+	ErrFormNotFound            = DefinedError{Code: 3201, StatusCode: http.StatusNotFound, Err: "form not found", RuErr: "Форма не найдена"}
+	ErrFormAnswerForbidden     = DefinedError{Code: 3202, StatusCode: http.StatusForbidden, Err: "access to the form requires authorization", RuErr: "Для доступа к форме необходимо пройти авторизацию"}
+	ErrFormForbidden           = DefinedError{Code: 3203, StatusCode: http.StatusForbidden, Err: "not allowed for current role", RuErr: "У вас недостаточно прав для выполнения действия"}
+	ErrFormBadConvertRequest   = DefinedError{Code: 3204, StatusCode: http.StatusBadRequest, Err: "bad request, field: '%s'", RuErr: "При создании/обновлении формы передан неверный тип поля: '%s'"}
+	ErrFormBadRequest          = DefinedError{Code: 3205, StatusCode: http.StatusBadRequest, Err: "bad request", RuErr: "Некорректный запрос"}
+	ErrFormRequestValidate     = DefinedError{Code: 3206, StatusCode: http.StatusBadRequest, Err: "validation error", RuErr: "Введены некорректные данные"}
+	ErrFormCheckFields         = DefinedError{Code: 3207, StatusCode: http.StatusBadRequest, Err: "fields request error: '%s'", RuErr: "При создании формы задан неподдерживаемый тип поля: '%s"}
+	ErrFormCheckAnswers        = DefinedError{Code: 3208, StatusCode: http.StatusBadRequest, Err: "required field missing or wrong type", RuErr: "При отправке ответа на форму не заполнены обязательные поля или выбран не соответствующий тип значения"}
+	ErrFormEmptyAnswers        = DefinedError{Code: 3209, StatusCode: http.StatusBadRequest, Err: "empty answers", RuErr: "Для сохранения формы необходимо заполнить поля с ответами"}
+	ErrFormAnswerNotFound      = DefinedError{Code: 3210, StatusCode: http.StatusNotFound, Err: "answer not found", RuErr: "Ответ не найден"}
+	ErrFormAnswerEnd           = DefinedError{Code: 3211, StatusCode: http.StatusBadRequest, Err: "form is closed", RuErr: "Форма закрыта"}
+	ErrLenAnswers              = DefinedError{Code: 3212, StatusCode: http.StatusBadRequest, Err: "incorrect number of answers", RuErr: "Некорректное количество ответов"}
+	ErrFormEndDate             = DefinedError{Code: 3213, StatusCode: http.StatusBadRequest, Err: "the form cannot be created with a closed date", RuErr: "Форма не может быть создана с завершенной датой"}
+	ErrFormAttachmentNotFound  = DefinedError{Code: 3214, StatusCode: http.StatusBadRequest, Err: "file not found by the provided UUID", RuErr: "Файл по указанному UUID не найден"}
+	ErrAttachmentInUse         = DefinedError{Code: 3215, StatusCode: http.StatusConflict, Err: "cannot delete file: it is linked to a form answer", RuErr: "Невозможно удалить файл — он привязан к ответу формы"}
+	ErrFormDependOn            = DefinedError{Code: 3216, StatusCode: http.StatusBadRequest, Err: "depend_on field has invalid value", RuErr: "Значение зависимого поля не соответствует требованиям"}
+	ErrFormExportFormat        = DefinedError{Code: 3217, StatusCode: http.StatusBadRequest, Err: "unsupported form answers export format", RuErr: "Неподдерживаемый формат выгрузки ответов"}
+	ErrFormSectionSkipped      = DefinedError{Code: 3218, StatusCode: http.StatusBadRequest, Err: "form section is skipped by previous answers", RuErr: "Раздел формы пропускается по ответам на предыдущие разделы"}
+	ErrFormAnswersLimit        = DefinedError{Code: 3219, StatusCode: http.StatusForbidden, Err: "form answers limit reached", RuErr: "Форма больше не принимает ответы: достигнуто максимальное количество"}
+	ErrFormRateLimit           = DefinedError{Code: 3220, StatusCode: http.StatusTooManyRequests, Err: "too many form answers, try again later", RuErr: "Слишком много ответов, попробуйте позже"}
+	ErrFormAttachmentType      = DefinedError{Code: 3221, StatusCode: http.StatusBadRequest, Err: "attachment type is not allowed by form", RuErr: "Тип файла не разрешен для этой формы"}
+	ErrFormAnswerEditForbidden = DefinedError{Code: 3222, StatusCode: http.StatusForbidden, Err: "form answers editing is not allowed", RuErr: "Изменение ответов на эту форму запрещено"}
 
 	// 34** - doc errors
-	ErrDocNotFound            = DefinedError{Code: 3401, StatusCode: http.StatusNotFound, Err: "doc not found", RuErr: "Документ не найден"}
-	ErrDocUpdateForbidden     = DefinedError{Code: 3402, StatusCode: http.StatusForbidden, Err: "insufficient permissions or not the author", RuErr: "У вас недостаточно прав для изменения документа"}
-	ErrDocDeleteHasChild      = DefinedError{Code: 3403, StatusCode: http.StatusForbidden, Err: "doc has child", RuErr: "Невозможно удалить, есть дочерние документы"}
-	ErrDocBadRequest          = DefinedError{Code: 3404, StatusCode: http.StatusBadRequest, Err: "bad request", RuErr: "Некорректный запрос"}
-	ErrDocCommentNotFound     = DefinedError{Code: 3405, StatusCode: http.StatusNotFound, Err: "doc comment not found", RuErr: "Не найден комментарий"}
-	ErrDocRequestValidate     = DefinedError{Code: 3406, StatusCode: http.StatusBadRequest, Err: "validation error", RuErr: "Введены некорректные данные"}
-	ErrDocForbidden           = DefinedError{Code: 3407, StatusCode: http.StatusForbidden, Err: "not have permissions to perform this action", RuErr: "Недостаточно прав для совершения действия"}
-	ErrDocCommentBadRequest   = DefinedError{Code: 3408, StatusCode: http.StatusBadRequest, Err: "bad request", RuErr: "Некорректный запрос"}
-	ErrDocAttachmentNotFound  = DefinedError{Code: 3409, StatusCode: http.StatusNotFound, Err: "doc attachment not found", RuErr: "Не найдено вложение"}
-	ErrDocOrderBadRequest     = DefinedError{Code: 3410, StatusCode: http.StatusBadRequest, Err: "there is a document sequence error in the request", RuErr: "ошибка последовательности документов"}
-	ErrDocRoleLowerThanParent = DefinedError{Code: 3411, StatusCode: http.StatusBadRequest, Err: "current document role must not be lower than parent document role", RuErr: "Роль доступа к текущему документу не может быть ниже родительского документа"}
-	ErrDocRoleHigherThanChild = DefinedError{Code: 3412, StatusCode: http.StatusBadRequest, Err: "current document role must not be higher than child document role", RuErr: "Роль доступа к текущему документу не может быть выше чем у дочернего документа"}
-	ErrDocMoveIntoOwnChild    = DefinedError{Code: 3413, StatusCode: http.StatusBadRequest, Err: "cannot move document into its own child", RuErr: "Невозможно переместить документ в его же дочерний"}
-	ErrDocCommentEmpty        = DefinedError{Code: 3414, StatusCode: http.StatusBadRequest, Err: "comment is empty", RuErr: "Попытка отправить пустой комментарий"}
+	ErrDocNotFound              = DefinedError{Code: 3401, StatusCode: http.StatusNotFound, Err: "doc not found", RuErr: "Документ не найден"}
+	ErrDocUpdateForbidden       = DefinedError{Code: 3402, StatusCode: http.StatusForbidden, Err: "insufficient permissions or not the author", RuErr: "У вас недостаточно прав для изменения документа"}
+	ErrDocDeleteHasChild        = DefinedError{Code: 3403, StatusCode: http.StatusForbidden, Err: "doc has child", RuErr: "Невозможно удалить, есть дочерние документы"}
+	ErrDocBadRequest            = DefinedError{Code: 3404, StatusCode: http.StatusBadRequest, Err: "bad request", RuErr: "Некорректный запрос"}
+	ErrDocCommentNotFound       = DefinedError{Code: 3405, StatusCode: http.StatusNotFound, Err: "doc comment not found", RuErr: "Не найден комментарий"}
+	ErrDocRequestValidate       = DefinedError{Code: 3406, StatusCode: http.StatusBadRequest, Err: "validation error", RuErr: "Введены некорректные данные"}
+	ErrDocForbidden             = DefinedError{Code: 3407, StatusCode: http.StatusForbidden, Err: "not have permissions to perform this action", RuErr: "Недостаточно прав для совершения действия"}
+	ErrDocCommentBadRequest     = DefinedError{Code: 3408, StatusCode: http.StatusBadRequest, Err: "bad request", RuErr: "Некорректный запрос"}
+	ErrDocAttachmentNotFound    = DefinedError{Code: 3409, StatusCode: http.StatusNotFound, Err: "doc attachment not found", RuErr: "Не найдено вложение"}
+	ErrDocOrderBadRequest       = DefinedError{Code: 3410, StatusCode: http.StatusBadRequest, Err: "there is a document sequence error in the request", RuErr: "ошибка последовательности документов"}
+	ErrDocRoleLowerThanParent   = DefinedError{Code: 3411, StatusCode: http.StatusBadRequest, Err: "current document role must not be lower than parent document role", RuErr: "Роль доступа к текущему документу не может быть ниже родительского документа"}
+	ErrDocRoleHigherThanChild   = DefinedError{Code: 3412, StatusCode: http.StatusBadRequest, Err: "current document role must not be higher than child document role", RuErr: "Роль доступа к текущему документу не может быть выше чем у дочернего документа"}
+	ErrDocMoveIntoOwnChild      = DefinedError{Code: 3413, StatusCode: http.StatusBadRequest, Err: "cannot move document into its own child", RuErr: "Невозможно переместить документ в его же дочерний"}
+	ErrDocCommentEmpty          = DefinedError{Code: 3414, StatusCode: http.StatusBadRequest, Err: "comment is empty", RuErr: "Попытка отправить пустой комментарий"}
+	ErrDocCollabActive          = DefinedError{Code: 3415, StatusCode: http.StatusConflict, Err: "doc is being edited collaboratively", RuErr: "Документ редактируется совместно, изменения содержимого вносятся через сессию редактирования"}
+	ErrDocExportFormat          = DefinedError{Code: 3416, StatusCode: http.StatusBadRequest, Err: "unsupported export format", RuErr: "Неподдерживаемый формат экспорта"}
+	ErrDocVersionNotFound       = DefinedError{Code: 3417, StatusCode: http.StatusNotFound, Err: "doc version not found", RuErr: "Версия документа не найдена"}
+	ErrDocShareLinkNotFound     = DefinedError{Code: 3418, StatusCode: http.StatusNotFound, Err: "share link not found", RuErr: "Ссылка не найдена или отозвана"}
+	ErrDocShareLinkExpired      = DefinedError{Code: 3419, StatusCode: http.StatusGone, Err: "share link expired", RuErr: "Срок действия ссылки истек"}
+	ErrDocSharePassword         = DefinedError{Code: 3420, StatusCode: http.StatusUnauthorized, Err: "share link password required", RuErr: "Для просмотра документа необходим пароль"}
+	ErrDocSharePasswordWrong    = DefinedError{Code: 3421, StatusCode: http.StatusForbidden, Err: "wrong share link password", RuErr: "Неверный пароль"}
+	ErrDocShareTooManyTries     = DefinedError{Code: 3422, StatusCode: http.StatusTooManyRequests, Err: "too many password attempts", RuErr: "Слишком много попыток ввода пароля, повторите позже"}
+	ErrDocShareExpiresAt        = DefinedError{Code: 3423, StatusCode: http.StatusBadRequest, Err: "expiration time must be in the future", RuErr: "Срок действия ссылки должен быть в будущем"}
+	ErrDocTemplateNotFound      = DefinedError{Code: 3424, StatusCode: http.StatusNotFound, Err: "doc template not found", RuErr: "Шаблон документа не найден"}
+	ErrDocTemplateDuplicated    = DefinedError{Code: 3425, StatusCode: http.StatusConflict, Err: "doc template name already exist", RuErr: "Шаблон документа с таким именем уже существует"}
+	ErrDocCommentAnchorNotFound = DefinedError{Code: 3426, StatusCode: http.StatusBadRequest, Err: "selected text not found in doc", RuErr: "Выделенный фрагмент не найден в документе"}
+	ErrDocCommentThreadNotFound = DefinedError{Code: 3427, StatusCode: http.StatusNotFound, Err: "doc comment thread not found", RuErr: "Обсуждение фрагмента документа не найдено"}
 
 	// 36** - sprint errors
 	ErrSprintNotFound          = DefinedError{Code: 3601, StatusCode: http.StatusNotFound, Err: "sprint not found", RuErr: "Спринт не найден"}
@@ -256,6 +294,9 @@ var (
 	ErrIssueTargetDateExp              = DefinedError{Code: 4024, StatusCode: http.StatusBadRequest, Err: "the date has already passed", RuErr: "Заданная дата уже прошла"}
 	ErrIssueCommentEmpty               = DefinedError{Code: 4025, StatusCode: http.StatusBadRequest, Err: "comment is empty", RuErr: "Попытка отправить пустой комментарий"}
 	ErrLabelNotEmptyCannotDelete       = DefinedError{Code: 4026, StatusCode: http.StatusBadRequest, Err: "the label is not empty, only empty label can be deleted", RuErr: "Удаление тега, установленного для задачи, невозможно"}
+	ErrIssueDescriptionCollabActive    = DefinedError{Code: 4027, StatusCode: http.StatusConflict, Err: "issue description is being edited collaboratively", RuErr: "Описание задачи редактируется совместно, изменения вносятся через сессию редактирования"}
+	ErrIssueHistoryBadRequest          = DefinedError{Code: 4028, StatusCode: http.StatusBadRequest, Err: "bad request", RuErr: "Некорректный запрос"}
+	ErrIssueVersionNotFound            = DefinedError{Code: 4029, StatusCode: http.StatusNotFound, Err: "issue version not found", RuErr: "Версия не найдена"}
 	ErrForbiddenState                  = DefinedError{Code: 4100, StatusCode: http.StatusBadRequest, Err: "state-flow blocks this change", RuErr: "Попытка установить статус не соответствующий бизнес-процессу"}
 
 	// 45** - property template errors
@@ -309,8 +350,12 @@ var (
 	ErrCannotEditIntegration = DefinedError{Code: 7002, StatusCode: http.StatusBadRequest, Err: "cannot edit integration", RuErr: "Невозможно редактировать интеграцию"}
 
 	// 8*** - import errors
-	ErrImportIDRequired       = DefinedError{Code: 8001, StatusCode: http.StatusBadRequest, Err: "importId must be specified", RuErr: "Необходимо указать ID импорта"}
-	ErrJiraInvalidCredentials = DefinedError{Code: 8002, StatusCode: http.StatusBadRequest, Err: "jira invalid credentials", RuErr: "Ошибка аутентификации: проверьте логин, пароль или API-ключ для подключения к Jira"}
+	ErrImportIDRequired        = DefinedError{Code: 8001, StatusCode: http.StatusBadRequest, Err: "importId must be specified", RuErr: "Необходимо указать ID импорта"}
+	ErrJiraInvalidCredentials  = DefinedError{Code: 8002, StatusCode: http.StatusBadRequest, Err: "jira invalid credentials", RuErr: "Ошибка аутентификации: проверьте логин, пароль или API-ключ для подключения к Jira"}
+	ErrDocImportInvalidArchive = DefinedError{Code: 8003, StatusCode: http.StatusBadRequest, Err: "invalid doc import archive", RuErr: "Файл поврежден или не является zip-архивом"}
+	ErrDocImportNoPages        = DefinedError{Code: 8004, StatusCode: http.StatusBadRequest, Err: "no pages found in archive", RuErr: "В архиве не найдено страниц Markdown, HTML или экспорта Confluence"}
+	ErrDocImportInvalidFormat  = DefinedError{Code: 8005, StatusCode: http.StatusBadRequest, Err: "invalid doc import format", RuErr: "Указан неверный формат импорта документов"}
+	ErrAlreadyImportingDocs    = DefinedError{Code: 8006, StatusCode: http.StatusConflict, Err: "you are already importing docs", RuErr: "Вы уже импортируете документы"}
 
 	// 9*** - admin errors
 	ErrReleaseNoteNotFound   = DefinedError{Code: 9001, StatusCode: http.StatusNotFound, Err: "release note not found", RuErr: "Изменение версии не найдено"}
@@ -330,6 +375,22 @@ var (
 	ErrGitCommandFailed         = DefinedError{Code: 10005, StatusCode: http.StatusInternalServerError, Err: "git command failed: %s", RuErr: "Не удалось выполнить git команду: %s"}
 	ErrGitInvalidBranch         = DefinedError{Code: 10006, StatusCode: http.StatusBadRequest, Err: "invalid branch name", RuErr: "Некорректное имя ветки"}
 	ErrGitPathCreationFailed    = DefinedError{Code: 10007, StatusCode: http.StatusInternalServerError, Err: "failed to create repository directory", RuErr: "Не удалось создать директорию для репозитория"}
+	ErrGitMergeRequestNotFound  = DefinedError{Code: 10008, StatusCode: http.StatusNotFound, Err: "merge request not found", RuErr: "Merge request не найден"}
+	ErrGitMergeRequestNotOpen   = DefinedError{Code: 10009, StatusCode: http.StatusConflict, Err: "merge request is not open", RuErr: "Merge request не открыт"}
+	ErrGitMergeConflict         = DefinedError{Code: 10010, StatusCode: http.StatusConflict, Err: "merge request has conflicts", RuErr: "Merge request содержит конфликты"}
+	ErrGitNotFastForward        = DefinedError{Code: 10011, StatusCode: http.StatusConflict, Err: "fast-forward merge is not possible", RuErr: "Слияние fast-forward невозможно"}
+	ErrGitNothingToMerge        = DefinedError{Code: 10012, StatusCode: http.StatusConflict, Err: "source branch has no changes to merge", RuErr: "В исходной ветке нет изменений для слияния"}
+	ErrGitSelfApproval          = DefinedError{Code: 10013, StatusCode: http.StatusForbidden, Err: "author cannot approve own merge request", RuErr: "Автор не может одобрить свой merge request"}
+	ErrGitRefNotFound           = DefinedError{Code: 10014, StatusCode: http.StatusNotFound, Err: "branch or revision not found", RuErr: "Ветка или ревизия не найдена"}
+	ErrGitSameBranches          = DefinedError{Code: 10015, StatusCode: http.StatusBadRequest, Err: "source and target branches must differ", RuErr: "Исходная и целевая ветки должны различаться"}
+	ErrGitMergeRequestExists    = DefinedError{Code: 10016, StatusCode: http.StatusConflict, Err: "open merge request for these branches already exists", RuErr: "Открытый merge request для этих веток уже существует"}
+	ErrGitMRCommentNotFound     = DefinedError{Code: 10017, StatusCode: http.StatusNotFound, Err: "merge request comment not found", RuErr: "Комментарий merge request не найден"}
+	ErrGitInvalidMergeMethod    = DefinedError{Code: 10018, StatusCode: http.StatusBadRequest, Err: "invalid merge method", RuErr: "Некорректный способ слияния"}
+	ErrGitBranchUpdated         = DefinedError{Code: 10019, StatusCode: http.StatusConflict, Err: "target branch was updated during merge, retry", RuErr: "Целевая ветка изменилась во время слияния, повторите попытку"}
+	ErrGitMirrorReadOnly        = DefinedError{Code: 10020, StatusCode: http.StatusForbidden, Err: "repository is a read-only mirror", RuErr: "Репозиторий является зеркалом и доступен только для чтения"}
+	ErrGitInvalidMirrorURL      = DefinedError{Code: 10021, StatusCode: http.StatusBadRequest, Err: "invalid mirror URL: only http(s) URLs without credentials are allowed", RuErr: "Некорректный адрес зеркала: допустимы только http(s) адреса без учетных данных"}
+	ErrGitNotMirror             = DefinedError{Code: 10022, StatusCode: http.StatusBadRequest, Err: "repository is not a mirror", RuErr: "Репозиторий не является зеркалом"}
+	ErrGitMirrorSyncInProgress  = DefinedError{Code: 10023, StatusCode: http.StatusConflict, Err: "mirror sync is already in progress", RuErr: "Синхронизация зеркала уже выполняется"}
 
 	// 11*** - SSH errors
 	ErrSSHKeyInvalidData    = DefinedError{Code: 11001, StatusCode: http.StatusBadRequest, Err: "invalid SSH key data", RuErr: "Некорректные данные SSH ключа"}
@@ -339,6 +400,8 @@ var (
 	ErrSSHAccessDenied      = DefinedError{Code: 11005, StatusCode: http.StatusForbidden, Err: "SSH access denied", RuErr: "SSH доступ запрещен"}
 	ErrSSHDisabled          = DefinedError{Code: 11006, StatusCode: http.StatusForbidden, Err: "SSH access is disabled", RuErr: "SSH доступ отключен"}
 	ErrSSHRateLimitExceeded = DefinedError{Code: 11007, StatusCode: http.StatusTooManyRequests, Err: "SSH rate limit exceeded", RuErr: "Превышен лимит SSH запросов"}
+	ErrSSHDeployKeyNotFound = DefinedError{Code: 11008, StatusCode: http.StatusNotFound, Err: "deploy key not found", RuErr: "Deploy ключ не найден"}
 )
 
 var ErrFormAnswerDependOn = errors.New("depend_on field has invalid value")
+var ErrFormAnswerSectionSkipped = errors.New("form section is not on the answer path")
@@ -19,6 +19,8 @@ type LdapProvider struct {
 
 	baseDN string
 	filter string
+	// атрибут пользователя с DN его групп
+	groupAttribute string
 }
 
 func InitLDAP(
@@ -26,13 +28,15 @@ func InitLDAP(
 	adminUsr string,
 	adminPwd string,
 	baseDN string,
-	filter string) (*LdapProvider, error) {
+	filter string,
+	groupAttribute string) (*LdapProvider, error) {
 	lp := &LdapProvider{
-		serverAdr: serverAdr,
-		adminUsr:  adminUsr,
-		adminPwd:  adminPwd,
-		baseDN:    baseDN,
-		filter:    filter,
+		serverAdr:      serverAdr,
+		adminUsr:       adminUsr,
+		adminPwd:       adminPwd,
+		baseDN:         baseDN,
+		filter:         filter,
+		groupAttribute: groupAttribute,
 	}
 	return lp, lp.check()
 }
@@ -149,9 +153,78 @@ func (lp *LdapProvider) Sync(users []dao.User) error {
 			attributes[attr.Name] = attr.Values
 		}
 
-		idx := userMap[attributes["mail"][0]]
+		if len(attributes["mail"]) == 0 || len(attributes["aiplan"]) == 0 || len(attributes["aiplanadmin"]) == 0 {
+			slog.Warn("LDAP user without aiplan attributes", "dn", entry.DN)
+			continue
+		}
+		idx, ok := userMap[attributes["mail"][0]]
+		if !ok {
+			continue
+		}
 		users[idx].IsActive = strings.ToLower(attributes["aiplan"][0]) == "true"
 		users[idx].IsSuperuser = strings.ToLower(attributes["aiplanadmin"][0]) == "true"
 	}
 	return nil
 }
+
+// UserGroups возвращает DN групп пользователей по email. Пользователи, не найденные в LDAP, в результат не попадают
+func (lp *LdapProvider) UserGroups(emails []string) (map[string][]string, error) {
+	l, err := ldap.DialURL(lp.serverAdr.String())
+	if err != nil {
+		return nil, err
+	}
+	defer l.Close()
+
+	if err := l.StartTLS(&tls.Config{InsecureSkipVerify: true}); err != nil {
+		slog.Debug("Start LDAP TLS", "err", err)
+	}
+
+	if err := l.Bind(lp.adminUsr, lp.adminPwd); err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]string, len(emails))
+	for _, email := range emails {
+		searchRequest := ldap.NewSearchRequest(
+			lp.baseDN,
+			ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+			strings.ReplaceAll(lp.filter, "{email}", ldap.EscapeFilter(email)),
+			[]string{lp.groupAttribute},
+			nil,
+		)
+
+		sr, err := l.Search(searchRequest)
+		if err != nil {
+			var ldapErr *ldap.Error
+			if errors.As(err, &ldapErr) && ldapErr.ResultCode == ldap.LDAPResultNoSuchObject {
+				continue
+			}
+			return nil, fmt.Errorf("LDAP search %s: %w", searchRequest.Filter, err)
+		}
+		if len(sr.Entries) == 0 {
+			continue
+		}
+		groups[email] = sr.Entries[0].GetEqualFoldAttributeValues(lp.groupAttribute)
+	}
+	return groups, nil
+}
+
+// GroupMatches проверяет, входит ли группа из сопоставления в список DN групп пользователя.
+// Группа сопоставления задается полным DN или CN группы, регистр не учитывается
+func GroupMatches(group string, userGroups []string) bool {
+	group = strings.TrimSpace(group)
+	for _, dn := range userGroups {
+		if strings.EqualFold(dn, group) {
+			return true
+		}
+		parsed, err := ldap.ParseDN(dn)
+		if err != nil || len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) == 0 {
+			continue
+		}
+		rdn := parsed.RDNs[0].Attributes[0]
+		if strings.EqualFold(rdn.Type, "cn") && strings.EqualFold(rdn.Value, group) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package authprovider
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/auth-provider/ldaptest"
+)
+
+func newTestLdap(t *testing.T) *LdapProvider {
+	srv := ldaptest.NewServer(t,
+		ldaptest.Entry{DN: "cn=admin,dc=example,dc=com", Password: "admin"},
+		ldaptest.Entry{
+			DN:       "uid=alice,ou=users,dc=example,dc=com",
+			Password: "alice-password",
+			Attributes: map[string][]string{
+				"uniqueIdentifier": {"alice@example.com"},
+				"memberOf":         {"cn=devs,ou=groups,dc=example,dc=com", "cn=Admins,ou=groups,dc=example,dc=com"},
+			},
+		},
+		ldaptest.Entry{
+			DN:         "uid=bob,ou=users,dc=example,dc=com",
+			Attributes: map[string][]string{"uniqueIdentifier": {"bob@example.com"}},
+		},
+	)
+	lp, err := InitLDAP(srv.URL, "cn=admin,dc=example,dc=com", "admin", "dc=example,dc=com", "(&(uniqueIdentifier={email}))", "memberOf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lp
+}
+
+func TestLdapUserGroups(t *testing.T) {
+	lp := newTestLdap(t)
+
+	if !lp.AuthUser("alice@example.com", "alice-password") || lp.AuthUser("alice@example.com", "wrong") {
+		t.Error("Unexpected auth result")
+	}
+
+	groups, err := lp.UserGroups([]string{"alice@example.com", "bob@example.com", "missing@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups["alice@example.com"]) != 2 || len(groups["bob@example.com"]) != 0 {
+		t.Errorf("Unexpected groups: %v", groups)
+	}
+	if _, ok := groups["bob@example.com"]; !ok {
+		t.Error("User without groups not found")
+	}
+	if _, ok := groups["missing@example.com"]; ok {
+		t.Error("Missing user found")
+	}
+}
+
+func TestGroupMatches(t *testing.T) {
+	groups := []string{"cn=devs,ou=groups,dc=example,dc=com", "CN=Admins,OU=Groups,DC=example,DC=com"}
+	for _, group := range []string{"devs", "admins", "cn=admins,ou=groups,dc=example,dc=com", " DEVS "} {
+		if !GroupMatches(group, groups) {
+			t.Errorf("%q not matched", group)
+		}
+	}
+	for _, group := range []string{"groups", "dev", "cn=devs,dc=example,dc=com"} {
+		if GroupMatches(group, groups) {
+			t.Errorf("%q matched", group)
+		}
+	}
+	if slices.ContainsFunc([]string{"devs"}, func(g string) bool { return GroupMatches(g, nil) }) {
+		t.Error("Matched without groups")
+	}
+}
@@ -15,7 +15,7 @@ func TestMain(t *testing.T) {
 }
 
 func TestSync(t *testing.T) {
-	lp, err := InitLDAP(cfg.LDAPServerURL.URL, cfg.LDAPBindUser, cfg.LDAPBindPassword, cfg.LDAPBaseDN, "(&(uniqueIdentifier={email}))")
+	lp, err := InitLDAP(cfg.LDAPServerURL.URL, cfg.LDAPBindUser, cfg.LDAPBindPassword, cfg.LDAPBaseDN, "(&(uniqueIdentifier={email}))", "memberOf")
 	require.NoError(t, err)
 	users := []dao.User{
 		{
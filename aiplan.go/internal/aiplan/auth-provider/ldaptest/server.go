@@ -0,0 +1,302 @@
+// Package ldaptest - LDAP сервер в памяти для тестов синхронизации с LDAP.
+//
+// Поддерживает простой bind, поиск с фильтрами равенства, присутствия атрибута, and, or и not
+// по всем записям сервера без учета базового DN и области поиска. StartTLS выполняется с самоподписанным сертификатом
+package ldaptest
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+)
+
+const (
+	appBindRequest       = 0
+	appBindResponse      = 1
+	appUnbindRequest     = 2
+	appSearchRequest     = 3
+	appSearchResultEntry = 4
+	appSearchResultDone  = 5
+	appExtendedRequest   = 23
+	appExtendedResponse  = 24
+	resultSuccess        = 0
+	resultProtocolError  = 2
+	resultInvalidCreds   = 49
+
+	filterAnd      = 0
+	filterOr       = 1
+	filterNot      = 2
+	filterEquality = 3
+	filterPresent  = 7
+)
+
+// Entry - запись каталога. Имена атрибутов сравниваются без учета регистра
+type Entry struct {
+	DN         string
+	Password   string
+	Attributes map[string][]string
+}
+
+type Server struct {
+	URL *url.URL
+
+	listener  net.Listener
+	tlsConfig *tls.Config
+	mu        sync.RWMutex
+	entries   []Entry
+	wg        sync.WaitGroup
+}
+
+// NewServer запускает сервер с записями на локальном порту и останавливает его по завершении теста
+func NewServer(t testing.TB, entries ...Entry) *Server {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := selfSignedCert()
+	if err != nil {
+		l.Close()
+		t.Fatal(err)
+	}
+	s := &Server{
+		URL:       &url.URL{Scheme: "ldap", Host: l.Addr().String()},
+		listener:  l,
+		tlsConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+		entries:   entries,
+	}
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(s.Close)
+	return s
+}
+
+// SetEntries заменяет записи каталога
+func (s *Server) SetEntries(entries ...Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = entries
+}
+
+func (s *Server) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer func() { conn.Close() }()
+	r := bufio.NewReader(conn)
+	for {
+		packet, err := ber.ReadPacket(r)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageId, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		var responses []*ber.Packet
+		startTLS := false
+		switch op.Tag {
+		case appBindRequest:
+			responses = append(responses, result(appBindResponse, s.bind(op)))
+		case appUnbindRequest:
+			return
+		case appSearchRequest:
+			responses = append(s.search(op), result(appSearchResultDone, resultSuccess))
+		case appExtendedRequest:
+			// единственная поддерживаемая расширенная операция - StartTLS
+			responses = append(responses, result(appExtendedResponse, resultSuccess))
+			startTLS = true
+		default:
+			return
+		}
+
+		for _, response := range responses {
+			envelope := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+			envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageId, "MessageID"))
+			envelope.AppendChild(response)
+			if _, err := conn.Write(envelope.Bytes()); err != nil {
+				return
+			}
+		}
+
+		if startTLS {
+			conn = tls.Server(conn, s.tlsConfig)
+			r = bufio.NewReader(conn)
+		}
+	}
+}
+
+func selfSignedCert() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ldaptest"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+func (s *Server) bind(op *ber.Packet) int {
+	if len(op.Children) < 3 {
+		return resultProtocolError
+	}
+	dn := packetString(op.Children[1])
+	password := packetString(op.Children[2])
+	if dn == "" && password == "" {
+		return resultSuccess
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, entry := range s.entries {
+		if strings.EqualFold(entry.DN, dn) && entry.Password != "" && entry.Password == password {
+			return resultSuccess
+		}
+	}
+	return resultInvalidCreds
+}
+
+func (s *Server) search(op *ber.Packet) []*ber.Packet {
+	if len(op.Children) < 8 {
+		return nil
+	}
+	filter := op.Children[6]
+	var attributes []string
+	for _, attr := range op.Children[7].Children {
+		attributes = append(attributes, packetString(attr))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var res []*ber.Packet
+	for _, entry := range s.entries {
+		if !matches(entry, filter) {
+			continue
+		}
+		packet := ber.Encode(ber.ClassApplication, ber.TypeConstructed, appSearchResultEntry, nil, "Search Result Entry")
+		packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, "DN"))
+		attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+		for _, name := range attributes {
+			values, ok := entry.attribute(name)
+			if !ok {
+				continue
+			}
+			attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+			attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+			set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+			for _, v := range values {
+				set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "Value"))
+			}
+			attr.AppendChild(set)
+			attrs.AppendChild(attr)
+		}
+		packet.AppendChild(attrs)
+		res = append(res, packet)
+	}
+	return res
+}
+
+func (e Entry) attribute(name string) ([]string, bool) {
+	for k, v := range e.Attributes {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func matches(entry Entry, filter *ber.Packet) bool {
+	switch filter.Tag {
+	case filterAnd:
+		for _, child := range filter.Children {
+			if !matches(entry, child) {
+				return false
+			}
+		}
+		return true
+	case filterOr:
+		for _, child := range filter.Children {
+			if matches(entry, child) {
+				return true
+			}
+		}
+		return false
+	case filterNot:
+		return len(filter.Children) == 1 && !matches(entry, filter.Children[0])
+	case filterEquality:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		values, _ := entry.attribute(packetString(filter.Children[0]))
+		value := packetString(filter.Children[1])
+		for _, v := range values {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+		return false
+	case filterPresent:
+		_, ok := entry.attribute(packetString(filter))
+		return ok
+	}
+	return false
+}
+
+func result(tag ber.Tag, code int) *ber.Packet {
+	packet := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), "Result Code"))
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	return packet
+}
+
+// packetString возвращает строковое значение примитива, в том числе контекстного, для которого Value не заполняется
+func packetString(p *ber.Packet) string {
+	if v, ok := p.Value.(string); ok {
+		return v
+	}
+	if p.Data != nil {
+		return p.Data.String()
+	}
+	return ""
+}
@@ -0,0 +1,434 @@
+package authprovider
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrOIDCStateMismatch = errors.New("oidc state mismatch")
+	ErrOIDCNonceMismatch = errors.New("oidc nonce mismatch")
+	ErrOIDCEmailRequired = errors.New("oidc id token has no verified email")
+)
+
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	// Claim со списком групп пользователя для сопоставления с ролями в пространствах
+	GroupsClaim string
+}
+
+// OIDCProvider - вход через OpenID Connect провайдер по authorization code flow с PKCE
+type OIDCProvider struct {
+	cfg       OIDCConfig
+	client    *http.Client
+	discovery oidcDiscovery
+
+	keysMu      sync.RWMutex
+	keys        map[string]any
+	keysFetched time.Time
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+// OIDCAuthRequest - параметры запроса авторизации, сохраняемые до возврата пользователя от провайдера
+type OIDCAuthRequest struct {
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// OIDCClaims - проверенные данные пользователя из id token
+type OIDCClaims struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	GivenName     string
+	FamilyName    string
+	Groups        []string
+}
+
+// oidcKeysRefreshInterval - минимальный интервал повторной загрузки ключей провайдера при неизвестном kid
+const oidcKeysRefreshInterval = time.Minute
+
+func InitOIDC(ctx context.Context, cfg OIDCConfig, client *http.Client) (*OIDCProvider, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	op := &OIDCProvider{cfg: cfg, client: client}
+
+	issuer := strings.TrimSuffix(cfg.IssuerURL, "/")
+	if err := op.getJSON(ctx, issuer+"/.well-known/openid-configuration", &op.discovery); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+	if strings.TrimSuffix(op.discovery.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("oidc discovery: issuer %s does not match %s", op.discovery.Issuer, cfg.IssuerURL)
+	}
+	if op.discovery.AuthorizationEndpoint == "" || op.discovery.TokenEndpoint == "" || op.discovery.JwksURI == "" {
+		return nil, errors.New("oidc discovery: incomplete provider metadata")
+	}
+	return op, op.refreshKeys(ctx)
+}
+
+// NewAuthRequest генерирует state, nonce и code verifier для нового входа
+func (op *OIDCProvider) NewAuthRequest() (OIDCAuthRequest, error) {
+	var values [3]string
+	for i := range values {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return OIDCAuthRequest{}, err
+		}
+		values[i] = base64.RawURLEncoding.EncodeToString(b)
+	}
+	return OIDCAuthRequest{State: values[0], Nonce: values[1], CodeVerifier: values[2]}, nil
+}
+
+// AuthCodeURL возвращает адрес страницы входа провайдера
+func (op *OIDCProvider) AuthCodeURL(req OIDCAuthRequest) string {
+	challenge := sha256.Sum256([]byte(req.CodeVerifier))
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {op.cfg.ClientID},
+		"redirect_uri":          {op.cfg.RedirectURL},
+		"scope":                 {strings.Join(op.cfg.Scopes, " ")},
+		"state":                 {req.State},
+		"nonce":                 {req.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(op.discovery.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return op.discovery.AuthorizationEndpoint + sep + q.Encode()
+}
+
+// Exchange обменивает код авторизации на id token и возвращает проверенные данные пользователя
+func (op *OIDCProvider) Exchange(ctx context.Context, req OIDCAuthRequest, state, code string) (*OIDCClaims, error) {
+	if state == "" || state != req.State {
+		return nil, ErrOIDCStateMismatch
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {op.cfg.RedirectURL},
+		"client_id":     {op.cfg.ClientID},
+		"code_verifier": {req.CodeVerifier},
+	}
+	if op.cfg.ClientSecret != "" {
+		form.Set("client_secret", op.cfg.ClientSecret)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, op.discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Accept", "application/json")
+
+	resp, err := op.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("oidc token endpoint: %s: %s", resp.Status, body)
+	}
+
+	var tokenResp struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil {
+		return nil, err
+	}
+	if tokenResp.IDToken == "" {
+		return nil, errors.New("oidc token endpoint: no id_token in response")
+	}
+	return op.verifyIDToken(ctx, tokenResp.IDToken, req.Nonce)
+}
+
+func (op *OIDCProvider) verifyIDToken(ctx context.Context, raw string, nonce string) (*OIDCClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims,
+		func(t *jwt.Token) (any, error) {
+			kid, _ := t.Header["kid"].(string)
+			return op.key(ctx, kid)
+		},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(op.discovery.Issuer),
+		jwt.WithAudience(op.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := claims["nonce"].(string); n == "" || n != nonce {
+		return nil, ErrOIDCNonceMismatch
+	}
+
+	res := &OIDCClaims{
+		Groups: claimStrings(claims[op.cfg.GroupsClaim]),
+	}
+	res.Subject, _ = claims["sub"].(string)
+	res.Email, _ = claims["email"].(string)
+	res.Name, _ = claims["name"].(string)
+	res.GivenName, _ = claims["given_name"].(string)
+	res.FamilyName, _ = claims["family_name"].(string)
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		res.EmailVerified = v
+	case string:
+		res.EmailVerified, _ = strconv.ParseBool(v)
+	}
+	// без claim email_verified адрес считается неподтвержденным: по нему нельзя связать учетную запись
+	res.Email = strings.ToLower(strings.TrimSpace(res.Email))
+	if res.Subject == "" || res.Email == "" || !res.EmailVerified {
+		return nil, ErrOIDCEmailRequired
+	}
+	return res, nil
+}
+
+// key возвращает ключ провайдера по kid, при неизвестном kid ключи загружаются заново
+func (op *OIDCProvider) key(ctx context.Context, kid string) (any, error) {
+	lookup := func() (any, bool) {
+		op.keysMu.RLock()
+		defer op.keysMu.RUnlock()
+		if kid == "" && len(op.keys) == 1 {
+			for _, k := range op.keys {
+				return k, true
+			}
+		}
+		k, ok := op.keys[kid]
+		return k, ok
+	}
+	if k, ok := lookup(); ok {
+		return k, nil
+	}
+
+	op.keysMu.RLock()
+	fetched := op.keysFetched
+	op.keysMu.RUnlock()
+	if time.Since(fetched) > oidcKeysRefreshInterval {
+		if err := op.refreshKeys(ctx); err != nil {
+			return nil, err
+		}
+		if k, ok := lookup(); ok {
+			return k, nil
+		}
+	}
+	return nil, fmt.Errorf("oidc: unknown signing key %q", kid)
+}
+
+func (op *OIDCProvider) refreshKeys(ctx context.Context) error {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := op.getJSON(ctx, op.discovery.JwksURI, &set); err != nil {
+		return fmt.Errorf("oidc jwks: %w", err)
+	}
+	keys := make(map[string]any, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+	if len(keys) == 0 {
+		return errors.New("oidc jwks: no usable signing keys")
+	}
+
+	op.keysMu.Lock()
+	op.keys = keys
+	op.keysFetched = time.Now()
+	op.keysMu.Unlock()
+	return nil
+}
+
+func (op *OIDCProvider) getJSON(ctx context.Context, u string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := op.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", u, resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (any, error) {
+	decode := func(s string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
+
+func claimStrings(v any) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		res := make([]string, 0, len(v))
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	}
+	return nil
+}
+
+// RoleMapping - роль пользователя в пространстве для значения группы провайдера
+type RoleMapping struct {
+	Group         string
+	WorkspaceSlug string
+	Role          int
+}
+
+// ParseRoleMapping разбирает сопоставление групп с ролями вида
+// "group=workspace-slug:role;other=workspace:role", где role - admin, member, guest или числовое значение роли
+func ParseRoleMapping(s string) ([]RoleMapping, error) {
+	var res []RoleMapping
+	for _, rule := range strings.Split(s, ";") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		group, target, ok := strings.Cut(rule, "=")
+		if !ok {
+			return nil, fmt.Errorf("role mapping %q: expected group=workspace:role", rule)
+		}
+		slug, roleName, ok := strings.Cut(target, ":")
+		if !ok || strings.TrimSpace(group) == "" || strings.TrimSpace(slug) == "" {
+			return nil, fmt.Errorf("role mapping %q: expected group=workspace:role", rule)
+		}
+
+		var role int
+		switch strings.ToLower(strings.TrimSpace(roleName)) {
+		case "admin":
+			role = types.AdminRole
+		case "member":
+			role = types.MemberRole
+		case "guest":
+			role = types.GuestRole
+		default:
+			r, err := strconv.Atoi(strings.TrimSpace(roleName))
+			if err != nil || (r != types.AdminRole && r != types.MemberRole && r != types.GuestRole) {
+				return nil, fmt.Errorf("role mapping %q: unknown role %s", rule, roleName)
+			}
+			role = r
+		}
+		res = append(res, RoleMapping{Group: strings.TrimSpace(group), WorkspaceSlug: strings.TrimSpace(slug), Role: role})
+	}
+	return res, nil
+}
+
+// WorkspaceRoles возвращает наибольшую роль в каждом пространстве по группам пользователя
+func WorkspaceRoles(mapping []RoleMapping, groups []string) map[string]int {
+	res := make(map[string]int)
+	for _, m := range mapping {
+		for _, g := range groups {
+			if g == m.Group && m.Role > res[m.WorkspaceSlug] {
+				res[m.WorkspaceSlug] = m.Role
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,180 @@
+package authprovider
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockIdP - минимальный OIDC провайдер для тестов
+type mockIdP struct {
+	srv    *httptest.Server
+	key    *rsa.PrivateKey
+	claims jwt.MapClaims
+	// challenge из запроса авторизации по коду
+	challenges map[string]string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &mockIdP{key: key, challenges: map[string]string{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.srv.URL,
+			"authorization_endpoint": idp.srv.URL + "/authorize",
+			"token_endpoint":         idp.srv.URL + "/token",
+			"jwks_uri":               idp.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if idp.challenges[r.Form.Get("code")] != base64.RawURLEncoding.EncodeToString(sum[:]) {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		tkn := jwt.NewWithClaims(jwt.SigningMethodRS256, idp.claims)
+		tkn.Header["kid"] = "test"
+		signed, err := tkn.SignedString(key)
+		if err != nil {
+			t.Error(err)
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": signed, "token_type": "Bearer"})
+	})
+	idp.srv = httptest.NewServer(mux)
+	t.Cleanup(idp.srv.Close)
+	return idp
+}
+
+// authorize имитирует вход пользователя на странице провайдера и возвращает код авторизации
+func (idp *mockIdP) authorize(t *testing.T, authURL string) string {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" {
+		t.Fatalf("PKCE not used: %s", authURL)
+	}
+	code := "code-" + q.Get("state")
+	idp.challenges[code] = q.Get("code_challenge")
+	idp.claims["nonce"] = q.Get("nonce")
+	return code
+}
+
+func TestOIDCProvider(t *testing.T) {
+	idp := newMockIdP(t)
+	ctx := context.Background()
+
+	op, err := InitOIDC(ctx, OIDCConfig{
+		IssuerURL:   idp.srv.URL,
+		ClientID:    "aiplan",
+		RedirectURL: "http://localhost/api/sso/oidc/callback/",
+	}, idp.srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	login := func(claims jwt.MapClaims) (*OIDCClaims, error) {
+		idp.claims = claims
+		req, err := op.NewAuthRequest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		code := idp.authorize(t, op.AuthCodeURL(req))
+		return op.Exchange(ctx, req, req.State, code)
+	}
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":            idp.srv.URL,
+			"aud":            "aiplan",
+			"sub":            "user-1",
+			"exp":            time.Now().Add(time.Minute).Unix(),
+			"email":          "User@Example.com",
+			"email_verified": true,
+			"groups":         []string{"devs", "admins"},
+		}
+	}
+
+	claims, err := login(valid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject != "user-1" || claims.Email != "user@example.com" || len(claims.Groups) != 2 {
+		t.Errorf("Unexpected claims: %+v", claims)
+	}
+
+	cases := map[string]func(jwt.MapClaims){
+		"audience":    func(c jwt.MapClaims) { c["aud"] = "other" },
+		"issuer":      func(c jwt.MapClaims) { c["iss"] = "http://evil" },
+		"expired":     func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() },
+		"unverified":  func(c jwt.MapClaims) { c["email_verified"] = false },
+		"no verified": func(c jwt.MapClaims) { delete(c, "email_verified") },
+	}
+	for name, modify := range cases {
+		c := valid()
+		modify(c)
+		if _, err := login(c); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	req, _ := op.NewAuthRequest()
+	idp.claims = valid()
+	code := idp.authorize(t, op.AuthCodeURL(req))
+	if _, err := op.Exchange(ctx, req, "other", code); !errors.Is(err, ErrOIDCStateMismatch) {
+		t.Errorf("State not checked: %v", err)
+	}
+	idp.claims["nonce"] = "other"
+	if _, err := op.Exchange(ctx, req, req.State, code); !errors.Is(err, ErrOIDCNonceMismatch) {
+		t.Errorf("Nonce not checked: %v", err)
+	}
+	other, _ := op.NewAuthRequest()
+	if _, err := op.Exchange(ctx, OIDCAuthRequest{State: req.State, Nonce: req.Nonce, CodeVerifier: other.CodeVerifier}, req.State, code); err == nil {
+		t.Error("Code verifier not checked")
+	}
+}
+
+func TestParseRoleMapping(t *testing.T) {
+	mapping, err := ParseRoleMapping("devs=main:member; admins=main:admin;guests=ext:5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	roles := WorkspaceRoles(mapping, []string{"devs", "admins", "other"})
+	if len(roles) != 1 || roles["main"] != types.AdminRole {
+		t.Errorf("Unexpected roles: %v", roles)
+	}
+
+	for _, s := range []string{"devs", "devs=main", "devs=main:owner", "=main:admin"} {
+		if _, err := ParseRoleMapping(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
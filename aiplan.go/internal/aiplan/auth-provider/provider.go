@@ -6,9 +6,11 @@
 //
 // Реализации:
 //   - LDAPProvider (ldap.go) — аутентификация через LDAP/AD
+//   - OIDCProvider (oidc.go) — вход через OpenID Connect (authorization code + PKCE)
 //
 // При успешной LDAP-аутентификации пользователь автоматически создаётся
-// в локальной БД, если его там ещё нет.
+// в локальной БД, если его там ещё нет. Также создаются пользователи при первом
+// входе через OpenID Connect, роли в пространствах назначаются по группам из id token.
 package authprovider
 
 type AuthProvider interface {
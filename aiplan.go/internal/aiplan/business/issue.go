@@ -2,15 +2,22 @@ package business
 
 import (
 	"errors"
+	"log/slog"
+	"slices"
 	"strings"
 	"time"
 
 	tracker "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/activity-tracker"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/rules"
 	errStack "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/stack-error"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types/activities"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/utils"
 	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // CreateIssueComment создает новый комментарий к задаче. Метод принимает задачу, пользователя, текст комментария, комментарий для базы данных, ID для ответа на комментарий и дополнительные метаданные. Возвращает ошибку, если произошла ошибка.
@@ -77,3 +84,142 @@ func (b *Business) CreateIssueComment(issue dao.Issue, user dao.User, text strin
 
 	return err
 }
+
+// CreateIssue создает задачу в проекте вместе с исполнителями и фиксирует создание в activity проекта.
+// Для не-администраторов проекта проверяется, что выбранный статус допускает создание задачи (FromStates).
+// Если статус не указан, используется статус проекта по умолчанию. После создания задача загружается
+// вместе со статусом и исполнителями.
+func (b *Business) CreateIssue(issue *dao.Issue, project dao.Project, user dao.User, isAdmin bool, assigneeIds []uuid.UUID) error {
+	if !isAdmin && !issue.StateId.IsNil() {
+		var state dao.State
+		if err := b.db.Select("from_states").Where("id = ?", issue.StateId).Where("project_id = ?", project.ID).First(&state).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apierrors.ErrProjectStateNotFound
+			}
+			return err
+		}
+		if len(state.FromStates.Array) > 0 && !slices.Contains(state.FromStates.Array, uuid.Nil) {
+			return apierrors.ErrForbiddenState
+		}
+	}
+
+	userID := uuid.NullUUID{UUID: user.ID, Valid: true}
+	issue.ProjectId = project.ID
+	issue.WorkspaceId = project.WorkspaceId
+	issue.CreatedById = user.ID
+	issue.UpdatedById = userID
+
+	if err := b.db.Transaction(func(tx *gorm.DB) error {
+		if err := dao.CreateIssue(tx, issue); err != nil {
+			return err
+		}
+
+		assigneeIds = utils.SetToSlice(utils.SliceToSet(assigneeIds))
+		if len(assigneeIds) == 0 {
+			return nil
+		}
+		newAssignees := make([]dao.IssueAssignee, len(assigneeIds))
+		for i, assignee := range assigneeIds {
+			newAssignees[i] = dao.IssueAssignee{
+				Id:          dao.GenUUID(),
+				AssigneeId:  assignee,
+				IssueId:     issue.ID,
+				ProjectId:   project.ID,
+				WorkspaceId: project.WorkspaceId,
+				CreatedById: userID,
+				UpdatedById: userID,
+			}
+		}
+		return tx.CreateInBatches(&newAssignees, 10).Error
+	}); err != nil {
+		return err
+	}
+
+	if err := b.db.
+		Preload("Assignees").
+		Preload("State").
+		Where("id = ?", issue.ID).
+		First(issue).Error; err != nil {
+		return err
+	}
+	issue.Project = &project
+	issue.Workspace = project.Workspace
+
+	if err := b.st.TrackChanges(types.LayerProject, nil, tracker.IssueToSnapshot(*issue), &project, &user); err != nil {
+		errStack.GetError(nil, err)
+	}
+	return nil
+}
+
+// ChangeIssueState переводит задачу в новый статус с учетом бизнес-процесса проекта.
+// Задача должна быть загружена вместе со статусом (State). Для не-администраторов проекта
+// выполняются правила BeforeStatusChange и проверка допустимых предыдущих статусов (FromStates).
+// Даты начала и завершения обновляются так же, как при изменении статуса через API задачи.
+// Изменение фиксируется в activity. Возвращает apierrors.ErrForbiddenState, если переход запрещен.
+func (b *Business) ChangeIssueState(issue *dao.Issue, newState dao.State, user dao.User, isAdmin bool) error {
+	if newState.ProjectId != issue.ProjectId {
+		return apierrors.ErrForbiddenState
+	}
+	if issue.StateId == newState.ID {
+		return nil
+	}
+
+	oldIssue := *issue
+
+	if !isAdmin {
+		var rulesLog []dao.RulesLog
+		res, msg, err := rules.BeforeStatusChange(user, oldIssue, newState)
+		rules.AppendMsg(oldIssue, user, msg, &rulesLog)
+		rules.AppendError(oldIssue, user, err, &rulesLog)
+		rules.ResultToLog(oldIssue, user, res, err, &rulesLog)
+		if logErr := rules.AddLog(b.db, rulesLog); logErr != nil {
+			slog.Error("Create rules log", "err", logErr)
+		}
+		if !res.ClientResult {
+			return err.ClientError()
+		}
+
+		if len(newState.FromStates.Array) > 0 && !slices.Contains(newState.FromStates.Array, oldIssue.StateId) {
+			return apierrors.ErrForbiddenState
+		}
+	}
+
+	oldGroup := ""
+	if oldIssue.State != nil {
+		oldGroup = oldIssue.State.Group
+	}
+
+	now := &types.TargetDateTimeZ{Time: time.Now()}
+	switch {
+	case newState.Group == "started":
+		issue.StartDate = now
+		issue.CompletedAt = nil
+	case newState.Group == "backlog" || newState.Group == "unstarted":
+		if oldGroup == "started" {
+			issue.StartDate = nil
+		}
+		issue.CompletedAt = nil
+	case newState.Group == "completed" || newState.Group == "cancelled":
+		if oldGroup != "completed" && oldGroup != "cancelled" || newState.Group == "completed" {
+			issue.CompletedAt = now
+		}
+	}
+
+	issue.StateId = newState.ID
+	issue.State = &newState
+	issue.UpdatedById = uuid.NullUUID{UUID: user.ID, Valid: true}
+	issue.UpdatedAt = time.Now()
+
+	if err := b.db.Omit(clause.Associations).
+		Select("state_id", "start_date", "completed_at", "updated_by_id", "updated_at").
+		Updates(issue).Error; err != nil {
+		return err
+	}
+
+	if err := b.st.TrackChanges(types.LayerIssue,
+		tracker.IssueToSnapshot(oldIssue),
+		tracker.IssueToSnapshot(*issue), issue, &user); err != nil {
+		errStack.GetError(nil, err)
+	}
+	return nil
+}
@@ -0,0 +1,450 @@
+package business
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	tracker "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/activity-tracker"
+	authprovider "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/auth-provider"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
+	errStack "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/stack-error"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	LdapMembershipAdd    = "add"
+	LdapMembershipRole   = "role"
+	LdapMembershipRemove = "remove"
+)
+
+// MemberKey - участие пользователя в пространстве или проекте
+type MemberKey struct {
+	TargetId uuid.UUID
+	UserId   uuid.UUID
+}
+
+// LdapMembershipState - текущие роли пользователей в пространствах и проектах из сопоставлений групп LDAP
+type LdapMembershipState struct {
+	WorkspaceRoles map[MemberKey]int
+	ProjectRoles   map[MemberKey]int
+	// Владельцы пространств и руководители проектов, их участие не удаляется. Руководитель проекта
+	// защищен и от удаления из пространства проекта
+	Protected map[MemberKey]bool
+}
+
+// PlanLdapMemberships рассчитывает изменения участия пользователей по их группам LDAP (email -> DN групп).
+// Роли только повышаются. Для участия в проекте пользователь добавляется в пространство гостем, если он еще не участник.
+// Удаляются только пользователи, не входящие ни в одну сопоставленную группу пространства или проекта
+func PlanLdapMemberships(mappings []dao.LdapGroupMapping, users []dao.User, userGroups map[string][]string, state LdapMembershipState) []dto.LdapMembershipChange {
+	var changes []dto.LdapMembershipChange
+	for _, user := range users {
+		groups := userGroups[user.Email]
+
+		var workspaces, projects, removeWorkspaces, removeProjects []uuid.UUID
+		wantWorkspace := map[uuid.UUID]int{}
+		wantProject := map[uuid.UUID]int{}
+		projectWorkspace := map[uuid.UUID]uuid.UUID{}
+		for _, m := range mappings {
+			matches := authprovider.GroupMatches(m.Group, groups)
+			if m.ProjectId.Valid {
+				projectWorkspace[m.ProjectId.UUID] = m.WorkspaceId
+				if m.RemoveMembers && !slices.Contains(removeProjects, m.ProjectId.UUID) {
+					removeProjects = append(removeProjects, m.ProjectId.UUID)
+				}
+				if !matches {
+					continue
+				}
+				if !slices.Contains(projects, m.ProjectId.UUID) {
+					projects = append(projects, m.ProjectId.UUID)
+				}
+				wantProject[m.ProjectId.UUID] = max(wantProject[m.ProjectId.UUID], m.Role)
+				// участие в проекте требует участия в пространстве
+				if !slices.Contains(workspaces, m.WorkspaceId) {
+					workspaces = append(workspaces, m.WorkspaceId)
+				}
+				wantWorkspace[m.WorkspaceId] = max(wantWorkspace[m.WorkspaceId], types.GuestRole)
+				continue
+			}
+
+			if m.RemoveMembers && !slices.Contains(removeWorkspaces, m.WorkspaceId) {
+				removeWorkspaces = append(removeWorkspaces, m.WorkspaceId)
+			}
+			if !matches {
+				continue
+			}
+			if !slices.Contains(workspaces, m.WorkspaceId) {
+				workspaces = append(workspaces, m.WorkspaceId)
+			}
+			wantWorkspace[m.WorkspaceId] = max(wantWorkspace[m.WorkspaceId], m.Role)
+		}
+
+		change := func(workspaceId uuid.UUID, projectId uuid.UUID, action string, oldRole, role int) {
+			changes = append(changes, dto.LdapMembershipChange{
+				UserId:      user.ID,
+				Email:       user.Email,
+				WorkspaceId: workspaceId,
+				ProjectId:   uuid.NullUUID{UUID: projectId, Valid: !projectId.IsNil()},
+				Action:      action,
+				OldRole:     oldRole,
+				Role:        role,
+			})
+		}
+
+		// роль в пространстве после изменений
+		workspaceRole := map[uuid.UUID]int{}
+		for _, workspaceId := range workspaces {
+			role := wantWorkspace[workspaceId]
+			current, ok := state.WorkspaceRoles[MemberKey{workspaceId, user.ID}]
+			switch {
+			case !ok:
+				change(workspaceId, uuid.Nil, LdapMembershipAdd, 0, role)
+				workspaceRole[workspaceId] = role
+			case current < role:
+				change(workspaceId, uuid.Nil, LdapMembershipRole, current, role)
+				workspaceRole[workspaceId] = role
+			default:
+				workspaceRole[workspaceId] = current
+			}
+		}
+
+		for _, projectId := range projects {
+			// администраторы пространства участвуют во всех его проектах администраторами
+			if workspaceRole[projectWorkspace[projectId]] == types.AdminRole {
+				continue
+			}
+			role := wantProject[projectId]
+			current, ok := state.ProjectRoles[MemberKey{projectId, user.ID}]
+			switch {
+			case !ok:
+				change(projectWorkspace[projectId], projectId, LdapMembershipAdd, 0, role)
+			case current < role:
+				change(projectWorkspace[projectId], projectId, LdapMembershipRole, current, role)
+			}
+		}
+
+		var removedWorkspaces []uuid.UUID
+		for _, workspaceId := range removeWorkspaces {
+			key := MemberKey{workspaceId, user.ID}
+			current, ok := state.WorkspaceRoles[key]
+			if !ok || slices.Contains(workspaces, workspaceId) || state.Protected[key] {
+				continue
+			}
+			change(workspaceId, uuid.Nil, LdapMembershipRemove, current, 0)
+			removedWorkspaces = append(removedWorkspaces, workspaceId)
+		}
+
+		for _, projectId := range removeProjects {
+			key := MemberKey{projectId, user.ID}
+			current, ok := state.ProjectRoles[key]
+			workspaceId := projectWorkspace[projectId]
+			if !ok || slices.Contains(projects, projectId) || state.Protected[key] ||
+				slices.Contains(removedWorkspaces, workspaceId) {
+				continue
+			}
+			if role, ok := workspaceRole[workspaceId]; ok && role == types.AdminRole {
+				continue
+			}
+			if role, ok := state.WorkspaceRoles[MemberKey{workspaceId, user.ID}]; ok && role == types.AdminRole {
+				continue
+			}
+			change(workspaceId, projectId, LdapMembershipRemove, current, 0)
+		}
+	}
+	return changes
+}
+
+// GetLdapMembershipState загружает текущие роли пользователей в пространствах и проектах сопоставлений
+func (b *Business) GetLdapMembershipState(users []dao.User, mappings []dao.LdapGroupMapping) (LdapMembershipState, error) {
+	state := LdapMembershipState{
+		WorkspaceRoles: map[MemberKey]int{},
+		ProjectRoles:   map[MemberKey]int{},
+		Protected:      map[MemberKey]bool{},
+	}
+	if len(users) == 0 || len(mappings) == 0 {
+		return state, nil
+	}
+
+	userIds := make([]uuid.UUID, len(users))
+	for i, user := range users {
+		userIds[i] = user.ID
+	}
+	var workspaceIds, projectIds []uuid.UUID
+	for _, m := range mappings {
+		workspaceIds = append(workspaceIds, m.WorkspaceId)
+		if m.ProjectId.Valid {
+			projectIds = append(projectIds, m.ProjectId.UUID)
+		}
+	}
+
+	var workspaceMembers []dao.WorkspaceMember
+	if err := b.db.
+		Joins("Workspace").
+		Where("workspace_members.workspace_id IN (?)", workspaceIds).
+		Where("workspace_members.member_id IN (?)", userIds).
+		Find(&workspaceMembers).Error; err != nil {
+		return state, err
+	}
+	for _, m := range workspaceMembers {
+		key := MemberKey{m.WorkspaceId, m.MemberId}
+		state.WorkspaceRoles[key] = m.Role
+		if m.Workspace != nil && m.Workspace.OwnerId == m.MemberId {
+			state.Protected[key] = true
+		}
+	}
+
+	var leads []dao.Project
+	if err := b.db.
+		Select("workspace_id", "project_lead_id").
+		Where("workspace_id IN (?)", workspaceIds).
+		Where("project_lead_id IN (?)", userIds).
+		Find(&leads).Error; err != nil {
+		return state, err
+	}
+	for _, p := range leads {
+		state.Protected[MemberKey{p.WorkspaceId, p.ProjectLeadId}] = true
+	}
+
+	if len(projectIds) == 0 {
+		return state, nil
+	}
+	var projectMembers []dao.ProjectMember
+	if err := b.db.
+		Joins("Project").
+		Where("project_members.project_id IN (?)", projectIds).
+		Where("project_members.member_id IN (?)", userIds).
+		Find(&projectMembers).Error; err != nil {
+		return state, err
+	}
+	for _, m := range projectMembers {
+		key := MemberKey{m.ProjectId, m.MemberId}
+		state.ProjectRoles[key] = m.Role
+		if m.Project != nil && m.Project.ProjectLeadId == m.MemberId {
+			state.Protected[key] = true
+		}
+	}
+	return state, nil
+}
+
+// ApplyLdapMembershipChanges применяет изменения участия от имени системного пользователя и записывает их в активности
+func (b *Business) ApplyLdapMembershipChanges(changes []dto.LdapMembershipChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	actor := dao.GetSystemUser(b.db)
+	if actor == nil {
+		return fmt.Errorf("system user not found")
+	}
+
+	workspaces := map[uuid.UUID]*dao.Workspace{}
+	projects := map[uuid.UUID]*dao.Project{}
+	for _, change := range changes {
+		workspace, ok := workspaces[change.WorkspaceId]
+		if !ok {
+			workspace = new(dao.Workspace)
+			if err := b.db.Where("id = ?", change.WorkspaceId).First(workspace).Error; err != nil {
+				return err
+			}
+			workspaces[change.WorkspaceId] = workspace
+		}
+
+		if change.ProjectId.Valid {
+			project, ok := projects[change.ProjectId.UUID]
+			if !ok {
+				project = new(dao.Project)
+				if err := b.db.Where("id = ?", change.ProjectId.UUID).First(project).Error; err != nil {
+					return err
+				}
+				project.Workspace = workspace
+				projects[change.ProjectId.UUID] = project
+			}
+			if err := b.applyLdapProjectChange(actor, project, change); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := b.applyLdapWorkspaceChange(actor, workspace, change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Business) applyLdapWorkspaceChange(actor *dao.User, workspace *dao.Workspace, change dto.LdapMembershipChange) error {
+	actorId := uuid.NullUUID{UUID: actor.ID, Valid: true}
+	var member dao.WorkspaceMember
+	if change.Action != LdapMembershipAdd {
+		if err := b.db.
+			Where("workspace_id = ?", workspace.ID).
+			Where("member_id = ?", change.UserId).
+			First(&member).Error; err != nil {
+			return err
+		}
+	}
+	oldMember := member
+
+	if change.Action == LdapMembershipRemove {
+		return b.removeLdapWorkspaceMember(actor, workspace, &member, change.Email)
+	}
+
+	if err := b.db.Transaction(func(tx *gorm.DB) error {
+		switch change.Action {
+		case LdapMembershipAdd:
+			member = dao.WorkspaceMember{
+				ID:                              dao.GenUUID(),
+				CreatedAt:                       time.Now(),
+				CreatedById:                     actorId,
+				WorkspaceId:                     workspace.ID,
+				MemberId:                        change.UserId,
+				Role:                            change.Role,
+				NotificationAuthorSettingsEmail: types.DefaultWorkspaceMemberNS,
+				NotificationAuthorSettingsApp:   types.DefaultWorkspaceMemberNS,
+				NotificationAuthorSettingsTG:    types.DefaultWorkspaceMemberNS,
+				NotificationSettingsEmail:       types.DefaultWorkspaceMemberNS,
+				NotificationSettingsApp:         types.DefaultWorkspaceMemberNS,
+				NotificationSettingsTG:          types.DefaultWorkspaceMemberNS,
+			}
+			if err := tx.Omit(clause.Associations).Create(&member).Error; err != nil {
+				return err
+			}
+		case LdapMembershipRole:
+			member.Role = change.Role
+			if err := tx.Model(&member).Updates(map[string]any{"role": change.Role, "updated_at": time.Now(), "updated_by_id": actorId}).Error; err != nil {
+				return err
+			}
+		}
+
+		if change.Role != types.AdminRole {
+			return nil
+		}
+		// администратор пространства - администратор всех его проектов
+		var projects []dao.Project
+		if err := tx.Where("workspace_id = ?", workspace.ID).Find(&projects).Error; err != nil {
+			return err
+		}
+		for _, project := range projects {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "project_id"}, {Name: "member_id"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{"role": types.AdminRole, "updated_at": time.Now(), "updated_by_id": actorId}),
+			}).Create(&dao.ProjectMember{
+				ID:                              dao.GenUUID(),
+				CreatedAt:                       time.Now(),
+				CreatedById:                     actorId,
+				WorkspaceId:                     workspace.ID,
+				ProjectId:                       project.ID,
+				Role:                            types.AdminRole,
+				MemberId:                        change.UserId,
+				ViewProps:                       types.DefaultViewProps,
+				NotificationAuthorSettingsEmail: types.DefaultProjectMemberNS,
+				NotificationAuthorSettingsApp:   types.DefaultProjectMemberNS,
+				NotificationAuthorSettingsTG:    types.DefaultProjectMemberNS,
+				NotificationSettingsEmail:       types.DefaultProjectMemberNS,
+				NotificationSettingsApp:         types.DefaultProjectMemberNS,
+				NotificationSettingsTG:          types.DefaultProjectMemberNS,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	var err error
+	switch change.Action {
+	case LdapMembershipAdd:
+		err = b.st.TrackChanges(types.LayerWorkspace, tracker.WorkspaceToSnapshot(workspace),
+			tracker.WorkspaceToSnapshot(workspace, tracker.WithWorkspaceMembers([]dao.WorkspaceMember{member}, func(m dao.WorkspaceMember) string { return fmt.Sprint(m.Role) })),
+			workspace, actor)
+	case LdapMembershipRole:
+		err = b.st.TrackChanges(types.LayerWorkspace, tracker.MemberToSnapshot(&oldMember), tracker.MemberToSnapshot(&member), workspace, actor)
+	}
+	if err != nil {
+		errStack.GetError(nil, err)
+	}
+	return nil
+}
+
+// removeLdapWorkspaceMember удаляет пользователя из проектов пространства и из самого пространства тем же путем,
+// что и удаление участника администратором: с очисткой назначений и наблюдения и записью активностей
+func (b *Business) removeLdapWorkspaceMember(actor *dao.User, workspace *dao.Workspace, member *dao.WorkspaceMember, email string) error {
+	var projectMembers []dao.ProjectMember
+	if err := b.db.
+		Joins("Project").
+		Where("project_members.workspace_id = ?", workspace.ID).
+		Where("project_members.member_id = ?", member.MemberId).
+		Find(&projectMembers).Error; err != nil {
+		return err
+	}
+	for _, pm := range projectMembers {
+		pm.Project.Workspace = workspace
+		if err := b.removeProjectMember(actor, pm.Project, &pm, email); err != nil {
+			return err
+		}
+	}
+
+	member.Workspace = workspace
+	return b.removeWorkspaceMember(actor, member, email)
+}
+
+func (b *Business) applyLdapProjectChange(actor *dao.User, project *dao.Project, change dto.LdapMembershipChange) error {
+	actorId := uuid.NullUUID{UUID: actor.ID, Valid: true}
+	var member dao.ProjectMember
+	if change.Action != LdapMembershipAdd {
+		if err := b.db.
+			Where("project_id = ?", project.ID).
+			Where("member_id = ?", change.UserId).
+			First(&member).Error; err != nil {
+			return err
+		}
+	}
+	oldMember := member
+
+	switch change.Action {
+	case LdapMembershipAdd:
+		member = dao.ProjectMember{
+			ID:                              dao.GenUUID(),
+			CreatedAt:                       time.Now(),
+			CreatedById:                     actorId,
+			WorkspaceId:                     project.WorkspaceId,
+			ProjectId:                       project.ID,
+			Role:                            change.Role,
+			MemberId:                        change.UserId,
+			ViewProps:                       types.DefaultViewProps,
+			NotificationAuthorSettingsEmail: types.DefaultProjectMemberNS,
+			NotificationAuthorSettingsApp:   types.DefaultProjectMemberNS,
+			NotificationAuthorSettingsTG:    types.DefaultProjectMemberNS,
+			NotificationSettingsEmail:       types.DefaultProjectMemberNS,
+			NotificationSettingsApp:         types.DefaultProjectMemberNS,
+			NotificationSettingsTG:          types.DefaultProjectMemberNS,
+		}
+		if err := b.db.Omit(clause.Associations).Create(&member).Error; err != nil {
+			return err
+		}
+	case LdapMembershipRole:
+		member.Role = change.Role
+		if err := b.db.Model(&member).Updates(map[string]any{"role": change.Role, "updated_at": time.Now(), "updated_by_id": actorId}).Error; err != nil {
+			return err
+		}
+	case LdapMembershipRemove:
+		return b.removeProjectMember(actor, project, &member, change.Email)
+	}
+
+	var err error
+	switch change.Action {
+	case LdapMembershipAdd:
+		err = b.st.TrackChanges(types.LayerProject, tracker.ProjectToSnapshot(project),
+			tracker.ProjectToSnapshot(project, tracker.WithProjectMembers([]dao.ProjectMember{member}, func(m dao.ProjectMember) string { return fmt.Sprint(m.Role) })),
+			project, actor)
+	case LdapMembershipRole:
+		err = b.st.TrackChanges(types.LayerProject, tracker.MemberToSnapshot(&oldMember), tracker.MemberToSnapshot(&member), project, actor, member.ID)
+	}
+	if err != nil {
+		errStack.GetError(nil, err)
+	}
+	return nil
+}
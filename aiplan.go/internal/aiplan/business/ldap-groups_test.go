@@ -0,0 +1,111 @@
+package business
+
+import (
+	"testing"
+
+	authprovider "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/auth-provider"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/auth-provider/ldaptest"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+	"github.com/gofrs/uuid"
+)
+
+func TestPlanLdapMemberships(t *testing.T) {
+	srv := ldaptest.NewServer(t,
+		ldaptest.Entry{DN: "cn=admin,dc=example,dc=com", Password: "admin"},
+		ldaptest.Entry{
+			DN: "uid=alice,ou=users,dc=example,dc=com",
+			Attributes: map[string][]string{
+				"mail":     {"alice@example.com"},
+				"memberOf": {"cn=devs,ou=groups,dc=example,dc=com"},
+			},
+		},
+		ldaptest.Entry{
+			DN: "uid=bob,ou=users,dc=example,dc=com",
+			Attributes: map[string][]string{
+				"mail":     {"bob@example.com"},
+				"memberOf": {"cn=leads,ou=groups,dc=example,dc=com"},
+			},
+		},
+		ldaptest.Entry{
+			DN:         "uid=carol,ou=users,dc=example,dc=com",
+			Attributes: map[string][]string{"mail": {"carol@example.com"}},
+		},
+		ldaptest.Entry{
+			DN:         "uid=dave,ou=users,dc=example,dc=com",
+			Attributes: map[string][]string{"mail": {"dave@example.com"}},
+		},
+	)
+	lp, err := authprovider.InitLDAP(srv.URL, "cn=admin,dc=example,dc=com", "admin", "dc=example,dc=com", "(mail={email})", "memberOf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alice := dao.User{ID: dao.GenUUID(), Email: "alice@example.com"}
+	bob := dao.User{ID: dao.GenUUID(), Email: "bob@example.com"}
+	carol := dao.User{ID: dao.GenUUID(), Email: "carol@example.com"}
+	dave := dao.User{ID: dao.GenUUID(), Email: "dave@example.com"}
+	users := []dao.User{alice, bob, carol, dave}
+
+	groups, err := lp.UserGroups([]string{alice.Email, bob.Email, carol.Email, dave.Email})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workspaceId := dao.GenUUID()
+	projectId := dao.GenUUID()
+	mappings := []dao.LdapGroupMapping{
+		{Group: "devs", WorkspaceId: workspaceId, ProjectId: uuid.NullUUID{UUID: projectId, Valid: true}, Role: types.MemberRole, RemoveMembers: true},
+		{Group: "cn=leads,ou=groups,dc=example,dc=com", WorkspaceId: workspaceId, Role: types.AdminRole, RemoveMembers: true},
+	}
+
+	state := LdapMembershipState{
+		WorkspaceRoles: map[MemberKey]int{
+			// bob участник пространства, его роль повышается до администратора
+			{workspaceId, bob.ID}: types.MemberRole,
+			// carol владелец пространства
+			{workspaceId, carol.ID}: types.AdminRole,
+			// dave не входит в группы и удаляется
+			{workspaceId, dave.ID}: types.MemberRole,
+		},
+		ProjectRoles: map[MemberKey]int{
+			{projectId, carol.ID}: types.AdminRole,
+			{projectId, dave.ID}:  types.MemberRole,
+		},
+		Protected: map[MemberKey]bool{
+			{workspaceId, carol.ID}: true,
+		},
+	}
+
+	changes := PlanLdapMemberships(mappings, users, groups, state)
+
+	expected := []dto.LdapMembershipChange{
+		// alice добавляется гостем в пространство и участником в проект
+		{UserId: alice.ID, Email: alice.Email, WorkspaceId: workspaceId, Action: LdapMembershipAdd, Role: types.GuestRole},
+		{UserId: alice.ID, Email: alice.Email, WorkspaceId: workspaceId, ProjectId: uuid.NullUUID{UUID: projectId, Valid: true}, Action: LdapMembershipAdd, Role: types.MemberRole},
+		// bob становится администратором пространства и не добавляется в проект отдельно
+		{UserId: bob.ID, Email: bob.Email, WorkspaceId: workspaceId, Action: LdapMembershipRole, OldRole: types.MemberRole, Role: types.AdminRole},
+		// carol защищена как владелец и администратор пространства
+		// dave удаляется из пространства вместе с проектом
+		{UserId: dave.ID, Email: dave.Email, WorkspaceId: workspaceId, Action: LdapMembershipRemove, OldRole: types.MemberRole},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %d changes, got %d: %+v", len(expected), len(changes), changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("Change %d: expected %+v, got %+v", i, expected[i], changes[i])
+		}
+	}
+
+	// повторная синхронизация после применения изменений ничего не меняет
+	state.WorkspaceRoles[MemberKey{workspaceId, alice.ID}] = types.GuestRole
+	state.ProjectRoles[MemberKey{projectId, alice.ID}] = types.MemberRole
+	state.WorkspaceRoles[MemberKey{workspaceId, bob.ID}] = types.AdminRole
+	delete(state.WorkspaceRoles, MemberKey{workspaceId, dave.ID})
+	delete(state.ProjectRoles, MemberKey{projectId, dave.ID})
+	if changes := PlanLdapMemberships(mappings, users, groups, state); len(changes) != 0 {
+		t.Errorf("Unexpected changes: %+v", changes)
+	}
+}
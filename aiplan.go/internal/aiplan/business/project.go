@@ -136,6 +136,12 @@ func (b *Business) DeleteProjectMember(actor *dao.ProjectMember, requestedMember
 		return apierrors.ErrDeleteSuperUser
 	}
 
+	return b.removeProjectMember(actor.Member, project, requestedMember, requestedMember.Member.Email)
+}
+
+// removeProjectMember удаляет участника проекта вместе с его избранным, назначениями и наблюдением за задачами
+// и записывает активность проекта. Проверки прав выполняются вызывающей стороной
+func (b *Business) removeProjectMember(actor *dao.User, project *dao.Project, requestedMember *dao.ProjectMember, email string) error {
 	// Remove all favorites
 	if err := b.db.Exec("delete from project_favorites where user_id = ? and project_id = ?",
 		requestedMember.MemberId, requestedMember.ProjectId).Error; err != nil {
@@ -154,14 +160,14 @@ func (b *Business) DeleteProjectMember(actor *dao.ProjectMember, requestedMember
 		return err
 	}
 
-	oldSnapshot := tracker.ProjectToSnapshot(project, tracker.WithProjectMembers([]dao.ProjectMember{*requestedMember}, func(m dao.ProjectMember) string { return m.Member.Email }))
+	oldSnapshot := tracker.ProjectToSnapshot(project, tracker.WithProjectMembers([]dao.ProjectMember{*requestedMember}, func(m dao.ProjectMember) string { return email }))
 	newSnapshot := tracker.ProjectToSnapshot(project)
 
-	if err := b.db.Delete(&requestedMember).Error; err != nil {
+	if err := b.db.Delete(requestedMember).Error; err != nil {
 		return err
 	}
 
-	if err := b.st.TrackChanges(types.LayerProject, oldSnapshot, newSnapshot, project, actor.Member); err != nil {
+	if err := b.st.TrackChanges(types.LayerProject, oldSnapshot, newSnapshot, project, actor); err != nil {
 		errStack.GetError(nil, err)
 	}
 	return nil
@@ -115,13 +115,19 @@ func (b *Business) DeleteWorkspaceMember(actor *dao.WorkspaceMember, requestedMe
 		}
 	}
 
-	oldSnapshot := tracker.WorkspaceToSnapshot(requestedMember.Workspace, tracker.WithWorkspaceMembers([]dao.WorkspaceMember{*requestedMember}, func(m dao.WorkspaceMember) string { return m.Member.Email }))
+	return b.removeWorkspaceMember(actor.Member, requestedMember, requestedMember.Member.Email)
+}
+
+// removeWorkspaceMember удаляет участника пространства и записывает активность пространства.
+// Участие в проектах пространства должно быть удалено заранее
+func (b *Business) removeWorkspaceMember(actor *dao.User, requestedMember *dao.WorkspaceMember, email string) error {
+	oldSnapshot := tracker.WorkspaceToSnapshot(requestedMember.Workspace, tracker.WithWorkspaceMembers([]dao.WorkspaceMember{*requestedMember}, func(m dao.WorkspaceMember) string { return email }))
 	newSnapshot := tracker.WorkspaceToSnapshot(requestedMember.Workspace)
 
 	if err := b.db.Delete(requestedMember).Error; err != nil {
 		return err
 	}
-	if err := b.st.TrackChanges(types.LayerWorkspace, oldSnapshot, newSnapshot, requestedMember.Workspace, actor.Member); err != nil {
+	if err := b.st.TrackChanges(types.LayerWorkspace, oldSnapshot, newSnapshot, requestedMember.Workspace, actor); err != nil {
 		errStack.GetError(nil, err)
 	}
 	return nil
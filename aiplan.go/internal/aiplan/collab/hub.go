@@ -0,0 +1,258 @@
+package collab
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	pingPeriod   = time.Second * 20
+	writeTimeout = time.Second * 10
+	saveTimeout  = time.Second * 30
+
+	// Максимальный размер сообщения клиента (снимок большого документа)
+	maxMessageSize = 8 << 20
+	// Размер очереди исходящих сообщений клиента, при переполнении клиент отключается
+	sendQueueSize = 256
+	// Количество шагов без снимка, после которого у клиента запрашивается снимок
+	snapshotEvery = 100
+	// Количество шагов до последнего снимка, которые хранятся для клиентов с устаревшей версией
+	keepSteps = 200
+)
+
+// Hub хранит активные сессии совместного редактирования
+type Hub struct {
+	PersistInterval time.Duration
+	HistoryInterval time.Duration
+
+	// Разрешенные хосты Origin помимо хоста запроса. Соединение авторизуется cookie,
+	// поэтому подключение со сторонних страниц запрещено
+	originPatterns []string
+
+	mu       sync.Mutex
+	sessions map[string]*session
+}
+
+// NewHub создает хаб, принимающий подключения со страниц хостов originPatterns (шаблоны websocket.AcceptOptions)
+func NewHub(originPatterns ...string) *Hub {
+	return &Hub{
+		PersistInterval: time.Second * 5,
+		HistoryInterval: time.Minute * 10,
+		originPatterns:  originPatterns,
+		sessions:        make(map[string]*session),
+	}
+}
+
+// DocKey - ключ сессии документа
+func DocKey(id uuid.UUID) string {
+	return "doc:" + id.String()
+}
+
+// IssueKey - ключ сессии описания задачи
+func IssueKey(id uuid.UUID) string {
+	return "issue:" + id.String()
+}
+
+// HasEditors возвращает true, если в сессии есть участники с правом редактирования.
+// Пока они есть, содержимое нельзя менять в обход сессии
+func (h *Hub) HasEditors(key string) bool {
+	h.mu.Lock()
+	s, ok := h.sessions[key]
+	h.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range s.clients {
+		if c.canEdit {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle подключает пользователя к сессии key по WebSocket и обслуживает соединение до его закрытия.
+// Сессия создается при первом подключении с помощью open и завершается после отключения последнего участника
+func (h *Hub) Handle(w http.ResponseWriter, r *http.Request, key string, user *dao.User, canEdit bool, open Opener) error {
+	s, err := h.join(key, open)
+	if err != nil {
+		return err
+	}
+
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+		OriginPatterns: h.originPatterns,
+	})
+	if err != nil {
+		h.leave(s, nil)
+		return err
+	}
+	defer conn.CloseNow()
+	conn.SetReadLimit(maxMessageSize)
+
+	c := &client{
+		id:      uuid.Must(uuid.NewV4()).String(),
+		user:    user,
+		canEdit: canEdit,
+		conn:    conn,
+		send:    make(chan ServerMessage, sendQueueSize),
+		done:    make(chan struct{}),
+	}
+	go c.writeLoop()
+
+	s.addClient(c)
+	defer h.leave(s, c)
+
+	ctx := r.Context()
+	for {
+		var msg ClientMessage
+		if err := wsjson.Read(ctx, conn, &msg); err != nil {
+			if websocket.CloseStatus(err) == -1 && !errors.Is(err, context.Canceled) {
+				slog.Debug("Read collab message", "key", key, "err", err)
+			}
+			return nil
+		}
+		s.handle(c, msg)
+	}
+}
+
+// Shutdown сохраняет содержимое всех сессий и закрывает соединения
+func (h *Hub) Shutdown(ctx context.Context) {
+	h.mu.Lock()
+	sessions := make([]*session, 0, len(h.sessions))
+	for _, s := range h.sessions {
+		sessions = append(sessions, s)
+	}
+	h.mu.Unlock()
+
+	for _, s := range sessions {
+		s.persist(ctx)
+		s.commit(ctx)
+
+		s.mu.Lock()
+		for _, c := range s.clients {
+			c.conn.Close(websocket.StatusGoingAway, "server shutdown")
+		}
+		s.mu.Unlock()
+	}
+}
+
+// join возвращает сессию key, создавая ее при необходимости.
+// Если сессия завершается, дожидается сохранения, чтобы новая сессия загрузила актуальное содержимое
+func (h *Hub) join(key string, open Opener) (*session, error) {
+	for {
+		h.mu.Lock()
+		s, ok := h.sessions[key]
+		if !ok {
+			break
+		}
+		if !s.closing {
+			s.members++
+			h.mu.Unlock()
+			return s, nil
+		}
+		h.mu.Unlock()
+		<-s.done
+	}
+	defer h.mu.Unlock()
+
+	store, content, err := open()
+	if err != nil {
+		return nil, err
+	}
+
+	s := newSession(h, key, store, content)
+	s.members++
+	h.sessions[key] = s
+	go s.run()
+	return s, nil
+}
+
+// leave отключает клиента и завершает сессию, если участников не осталось
+func (h *Hub) leave(s *session, c *client) {
+	if c != nil {
+		s.removeClient(c)
+		close(c.done)
+	}
+
+	h.mu.Lock()
+	s.members--
+	last := s.members == 0
+	if last {
+		s.closing = true
+	}
+	h.mu.Unlock()
+
+	if !last {
+		return
+	}
+
+	close(s.stop)
+	ctx, cancel := context.WithTimeout(context.Background(), saveTimeout)
+	s.persist(ctx)
+	s.commit(ctx)
+	cancel()
+
+	h.mu.Lock()
+	delete(h.sessions, s.key)
+	h.mu.Unlock()
+	close(s.done)
+}
+
+type client struct {
+	id      string
+	user    *dao.User
+	canEdit bool
+	conn    *websocket.Conn
+	send    chan ServerMessage
+	done    chan struct{}
+	cursor  *Cursor
+}
+
+func (c *client) writeLoop() {
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case msg := <-c.send:
+			ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+			err := wsjson.Write(ctx, c.conn, msg)
+			cancel()
+			if err != nil {
+				c.conn.CloseNow()
+				return
+			}
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+			err := c.conn.Ping(ctx)
+			cancel()
+			if err != nil {
+				c.conn.CloseNow()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// enqueue ставит сообщение в очередь отправки. Медленный клиент отключается, он переподключится с актуальным снимком
+func (c *client) enqueue(msg ServerMessage) {
+	select {
+	case c.send <- msg:
+	default:
+		slog.Warn("Collab client send queue overflow", "userId", c.user.ID, "clientId", c.id)
+		go c.conn.Close(websocket.StatusPolicyViolation, "send queue overflow")
+	}
+}
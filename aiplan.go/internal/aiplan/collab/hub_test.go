@@ -0,0 +1,241 @@
+package collab
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/gofrs/uuid"
+)
+
+type memStore struct {
+	mu      sync.Mutex
+	saved   []Content
+	commits int
+}
+
+func (m *memStore) Save(_ context.Context, content Content, _ *dao.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.saved = append(m.saved, content)
+	return nil
+}
+
+func (m *memStore) Commit(_ context.Context, _ *dao.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.commits++
+	return nil
+}
+
+func (m *memStore) state() ([]Content, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Content(nil), m.saved...), m.commits
+}
+
+func newTestServer(t *testing.T, store *memStore) (*Hub, *httptest.Server) {
+	t.Helper()
+	hub := NewHub("aiplan.example.com")
+	hub.PersistInterval = time.Millisecond * 20
+	hub.HistoryInterval = time.Hour
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username := r.URL.Query().Get("user")
+		user := &dao.User{ID: uuid.Must(uuid.NewV4()), Username: &username}
+		canEdit := r.URL.Query().Get("ro") == ""
+		err := hub.Handle(w, r, "doc:test", user, canEdit, func() (Store, Content, error) {
+			return store, Content{HTML: "<p>start</p>"}, nil
+		})
+		if err != nil && r.URL.Query().Get("foreign") == "" {
+			t.Errorf("Handle failed: %v", err)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return hub, srv
+}
+
+type testClient struct {
+	t    *testing.T
+	conn *websocket.Conn
+	init ServerMessage
+}
+
+func dial(t *testing.T, srv *httptest.Server, query string) *testClient {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http")+"/?"+query, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	c := &testClient{t: t, conn: conn}
+	c.init = c.next(MsgInit)
+	return c
+}
+
+func (c *testClient) send(msg ClientMessage) {
+	c.t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	if err := wsjson.Write(ctx, c.conn, msg); err != nil {
+		c.t.Fatalf("Write failed: %v", err)
+	}
+}
+
+// next возвращает следующее сообщение типа msgType, пропуская остальные
+func (c *testClient) next(msgType string) ServerMessage {
+	c.t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	for {
+		var msg ServerMessage
+		if err := wsjson.Read(ctx, c.conn, &msg); err != nil {
+			c.t.Fatalf("Waiting for %q: %v", msgType, err)
+		}
+		if msg.Type == msgType {
+			return msg
+		}
+	}
+}
+
+func steps(s ...string) []json.RawMessage {
+	res := make([]json.RawMessage, len(s))
+	for i, step := range s {
+		res[i] = json.RawMessage(`"` + step + `"`)
+	}
+	return res
+}
+
+func TestCollabSteps(t *testing.T) {
+	_, srv := newTestServer(t, &memStore{})
+
+	a := dial(t, srv, "user=alice")
+	if a.init.HTML != "<p>start</p>" || a.init.Version != 0 || !a.init.CanEdit {
+		t.Fatalf("Unexpected init: %+v", a.init)
+	}
+	b := dial(t, srv, "user=bob")
+	if presence := a.next(MsgPresence); len(presence.Participants) != 2 {
+		t.Errorf("Expected 2 participants, got %+v", presence.Participants)
+	}
+
+	a.send(ClientMessage{Type: MsgSteps, Version: 0, Steps: steps("a1", "a2")})
+	for _, c := range []*testClient{a, b} {
+		msg := c.next(MsgSteps)
+		if msg.Version != 0 || len(msg.Steps) != 2 || msg.ClientIDs[0] != a.init.ClientID {
+			t.Errorf("Unexpected steps: %+v", msg)
+		}
+	}
+
+	// b отправляет шаги на устаревшей версии и получает недостающие
+	b.send(ClientMessage{Type: MsgSteps, Version: 0, Steps: steps("b1")})
+	if msg := b.next(MsgSteps); msg.Version != 0 || len(msg.Steps) != 2 {
+		t.Errorf("Expected missing steps, got %+v", msg)
+	}
+
+	b.send(ClientMessage{Type: MsgSteps, Version: 2, Steps: steps("b1")})
+	if msg := a.next(MsgSteps); msg.Version != 2 || string(msg.Steps[0]) != `"b1"` || msg.ClientIDs[0] != b.init.ClientID {
+		t.Errorf("Unexpected rebased steps: %+v", msg)
+	}
+
+	// Новый участник получает снимок и все шаги после него
+	c := dial(t, srv, "user=carol")
+	if c.init.Version != 0 || len(c.init.Steps) != 3 || len(c.init.Participants) != 3 {
+		t.Errorf("Unexpected init for late client: %+v", c.init)
+	}
+}
+
+func TestCollabReadOnly(t *testing.T) {
+	hub, srv := newTestServer(t, &memStore{})
+
+	r := dial(t, srv, "user=reader&ro=1")
+	if r.init.CanEdit {
+		t.Error("Expected read only client")
+	}
+	if hub.HasEditors("doc:test") {
+		t.Error("Read only client must not be counted as editor")
+	}
+
+	r.send(ClientMessage{Type: MsgSteps, Version: 0, Steps: steps("r1")})
+	if msg := r.next(MsgError); msg.Error != "read only" {
+		t.Errorf("Unexpected error: %+v", msg)
+	}
+
+	w := dial(t, srv, "user=writer")
+	if !hub.HasEditors("doc:test") || w.init.Version != 0 || len(w.init.Steps) != 0 {
+		t.Errorf("Unexpected state after writer joined: %+v", w.init)
+	}
+}
+
+func TestCollabPersist(t *testing.T) {
+	store := &memStore{}
+	hub, srv := newTestServer(t, store)
+
+	a := dial(t, srv, "user=alice")
+	a.send(ClientMessage{Type: MsgSteps, Version: 0, Steps: steps("a1")})
+	a.next(MsgSteps)
+
+	// Снимок не текущей версии игнорируется
+	a.send(ClientMessage{Type: MsgSnapshot, Version: 0, Doc: json.RawMessage(`{"type":"doc"}`), HTML: "<p>old</p>"})
+	a.send(ClientMessage{Type: MsgSnapshot, Version: 1, Doc: json.RawMessage(`{"type":"doc"}`), HTML: `<p>new</p><script>alert(1)</script>`})
+	if msg := a.next(MsgSaved); msg.Version != 1 {
+		t.Errorf("Unexpected saved version: %+v", msg)
+	}
+
+	saved, commits := store.state()
+	if len(saved) != 1 || saved[0].HTML != "<p>new</p>" || commits != 0 {
+		t.Fatalf("Unexpected saved content: %+v, commits %d", saved, commits)
+	}
+
+	a.conn.Close(websocket.StatusNormalClosure, "")
+	deadline := time.Now().Add(time.Second * 5)
+	for hub.HasEditors("doc:test") || commits == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Session was not committed after last client left")
+		}
+		time.Sleep(time.Millisecond * 10)
+		_, commits = store.state()
+	}
+
+	// Следующая сессия создается заново
+	b := dial(t, srv, "user=bob")
+	if b.init.Version != 0 || b.init.HTML != "<p>start</p>" {
+		t.Errorf("Expected new session, got %+v", b.init)
+	}
+}
+
+func TestCollabOrigin(t *testing.T) {
+	hub, srv := newTestServer(t, &memStore{})
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/?user=writer"
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	// Страница стороннего сайта не может подключиться с cookie пользователя
+	_, resp, err := websocket.Dial(ctx, url+"&foreign=1", &websocket.DialOptions{
+		HTTPHeader: http.Header{"Origin": {"https://evil.example.com"}},
+	})
+	if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected foreign origin to be rejected, got %v", err)
+	}
+	if hub.HasEditors("doc:test") {
+		t.Error("Rejected client must not join the session")
+	}
+
+	conn, _, err := websocket.Dial(ctx, url, &websocket.DialOptions{
+		HTTPHeader: http.Header{"Origin": {"https://aiplan.example.com"}},
+	})
+	if err != nil {
+		t.Fatalf("Expected configured origin to be accepted: %v", err)
+	}
+	conn.CloseNow()
+}
@@ -0,0 +1,290 @@
+package collab
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	policy "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/redactor-policy"
+)
+
+type stepRecord struct {
+	step     json.RawMessage
+	clientID string
+}
+
+// session - сессия совместного редактирования одного документа
+type session struct {
+	hub   *Hub
+	key   string
+	store Store
+
+	// Защищены hub.mu
+	members int
+	closing bool
+
+	stop chan struct{}
+	done chan struct{}
+
+	// Сохранение и фиксация в истории выполняются последовательно
+	saveMu sync.Mutex
+
+	mu      sync.Mutex
+	clients map[string]*client
+	// Текущая версия документа
+	version int
+	// Последний снимок документа, snapshot.Version <= version
+	snapshot Content
+	// Шаги с версии base до version
+	base  int
+	steps []stepRecord
+	// Версия последнего сохраненного и зафиксированного в истории снимка
+	saved     int
+	committed int
+	// Последний редактировавший пользователь
+	editor *dao.User
+}
+
+func newSession(h *Hub, key string, store Store, content Content) *session {
+	return &session{
+		hub:       h,
+		key:       key,
+		store:     store,
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+		clients:   make(map[string]*client),
+		version:   content.Version,
+		snapshot:  content,
+		base:      content.Version,
+		saved:     content.Version,
+		committed: content.Version,
+	}
+}
+
+// run периодически сохраняет снимки и фиксирует их в истории до завершения сессии
+func (s *session) run() {
+	persistTicker := time.NewTicker(s.hub.PersistInterval)
+	historyTicker := time.NewTicker(s.hub.HistoryInterval)
+	defer persistTicker.Stop()
+	defer historyTicker.Stop()
+
+	for {
+		select {
+		case <-persistTicker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), saveTimeout)
+			s.persist(ctx)
+			cancel()
+		case <-historyTicker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), saveTimeout)
+			s.persist(ctx)
+			s.commit(ctx)
+			cancel()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *session) addClient(c *client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clients[c.id] = c
+
+	from := s.snapshot.Version - s.base
+	steps, clientIDs := s.stepsFrom(from)
+	c.enqueue(ServerMessage{
+		Type:         MsgInit,
+		Version:      s.snapshot.Version,
+		ClientID:     c.id,
+		CanEdit:      c.canEdit,
+		Doc:          s.snapshot.Doc,
+		HTML:         s.snapshot.HTML,
+		Steps:        steps,
+		ClientIDs:    clientIDs,
+		Participants: s.participants(),
+	})
+	s.broadcastPresence(c.id)
+}
+
+func (s *session) removeClient(c *client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.clients, c.id)
+	s.broadcastPresence("")
+}
+
+func (s *session) handle(c *client, msg ClientMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch msg.Type {
+	case MsgSteps:
+		s.receiveSteps(c, msg)
+	case MsgSnapshot:
+		s.receiveSnapshot(c, msg)
+	case MsgCursor:
+		c.cursor = &Cursor{Version: msg.Version, Anchor: msg.Anchor, Head: msg.Head}
+		s.broadcastPresence(c.id)
+	default:
+		c.enqueue(ServerMessage{Type: MsgError, Version: s.version, Error: "unknown message type"})
+	}
+}
+
+func (s *session) receiveSteps(c *client, msg ClientMessage) {
+	if !c.canEdit {
+		c.enqueue(ServerMessage{Type: MsgError, Version: s.version, Error: "read only"})
+		return
+	}
+
+	if msg.Version != s.version {
+		// Клиент не видел часть шагов: отправляем недостающие, после перебазирования он повторит отправку.
+		// Если шаги уже удалены из журнала, клиент начинает заново со снимка
+		if msg.Version < s.base || msg.Version > s.version {
+			steps, clientIDs := s.stepsFrom(s.snapshot.Version - s.base)
+			c.enqueue(ServerMessage{
+				Type:      MsgInit,
+				Version:   s.snapshot.Version,
+				ClientID:  c.id,
+				CanEdit:   c.canEdit,
+				Doc:       s.snapshot.Doc,
+				HTML:      s.snapshot.HTML,
+				Steps:     steps,
+				ClientIDs: clientIDs,
+			})
+			return
+		}
+		steps, clientIDs := s.stepsFrom(msg.Version - s.base)
+		c.enqueue(ServerMessage{Type: MsgSteps, Version: msg.Version, Steps: steps, ClientIDs: clientIDs})
+		return
+	}
+
+	if len(msg.Steps) == 0 {
+		return
+	}
+
+	from := s.version
+	clientIDs := make([]string, len(msg.Steps))
+	for i, step := range msg.Steps {
+		s.steps = append(s.steps, stepRecord{step: step, clientID: c.id})
+		clientIDs[i] = c.id
+	}
+	s.version += len(msg.Steps)
+	s.editor = c.user
+
+	for _, cl := range s.clients {
+		cl.enqueue(ServerMessage{Type: MsgSteps, Version: from, Steps: msg.Steps, ClientIDs: clientIDs})
+	}
+
+	if s.version-s.snapshot.Version >= snapshotEvery {
+		c.enqueue(ServerMessage{Type: MsgSnapshotRequest, Version: s.version})
+	}
+}
+
+// receiveSnapshot принимает снимок, только если он соответствует текущей версии
+func (s *session) receiveSnapshot(c *client, msg ClientMessage) {
+	if !c.canEdit || msg.Version != s.version || msg.Version <= s.snapshot.Version || len(msg.Doc) == 0 {
+		return
+	}
+
+	// HTML снимка отдается другим участникам при подключении, поэтому очищается сразу
+	s.snapshot = Content{Version: msg.Version, Doc: msg.Doc, HTML: policy.UgcPolicy.Sanitize(msg.HTML)}
+
+	// Шаги до снимка нужны только клиентам с немного устаревшей версией
+	if trim := s.snapshot.Version - keepSteps - s.base; trim > 0 {
+		s.steps = append([]stepRecord(nil), s.steps[trim:]...)
+		s.base += trim
+	}
+}
+
+// persist сохраняет последний снимок, если он изменился
+func (s *session) persist(ctx context.Context) {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	s.mu.Lock()
+	content, editor := s.snapshot, s.editor
+	changed := content.Version > s.saved && editor != nil
+	s.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	if err := s.store.Save(ctx, content, editor); err != nil {
+		slog.Error("Save collab session content", "key", s.key, "version", content.Version, "err", err)
+		return
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	s.saved = content.Version
+	for _, c := range s.clients {
+		c.enqueue(ServerMessage{Type: MsgSaved, Version: content.Version, SavedAt: &now})
+	}
+	s.mu.Unlock()
+}
+
+// commit фиксирует сохраненные изменения в истории
+func (s *session) commit(ctx context.Context) {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	s.mu.Lock()
+	saved, editor := s.saved, s.editor
+	changed := saved > s.committed && editor != nil
+	s.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	if err := s.store.Commit(ctx, editor); err != nil {
+		slog.Error("Commit collab session history", "key", s.key, "version", saved, "err", err)
+		return
+	}
+
+	s.mu.Lock()
+	s.committed = saved
+	s.mu.Unlock()
+}
+
+// stepsFrom возвращает шаги журнала начиная с индекса from
+func (s *session) stepsFrom(from int) ([]json.RawMessage, []string) {
+	if from < 0 || from >= len(s.steps) {
+		return nil, nil
+	}
+	steps := make([]json.RawMessage, 0, len(s.steps)-from)
+	clientIDs := make([]string, 0, len(s.steps)-from)
+	for _, r := range s.steps[from:] {
+		steps = append(steps, r.step)
+		clientIDs = append(clientIDs, r.clientID)
+	}
+	return steps, clientIDs
+}
+
+func (s *session) participants() []Participant {
+	res := make([]Participant, 0, len(s.clients))
+	for _, c := range s.clients {
+		res = append(res, Participant{
+			ClientID: c.id,
+			User:     c.user.ToLightDTO(),
+			CanEdit:  c.canEdit,
+			Cursor:   c.cursor,
+		})
+	}
+	return res
+}
+
+// broadcastPresence рассылает список участников всем, кроме except
+func (s *session) broadcastPresence(except string) {
+	participants := s.participants()
+	for id, c := range s.clients {
+		if id == except {
+			continue
+		}
+		c.enqueue(ServerMessage{Type: MsgPresence, Version: s.version, Participants: participants})
+	}
+}
@@ -0,0 +1,107 @@
+// Пакет collab реализует совместное редактирование документов и описаний задач через WebSocket.
+//
+// Сервер выступает центральным арбитром по схеме prosemirror-collab: хранит журнал шагов (steps)
+// редактора TipTap с номером версии, принимает шаги только от клиента с актуальной версией
+// и рассылает их всем участникам сессии. Клиент с устаревшей версией получает недостающие шаги,
+// перебазирует на них свои изменения и отправляет их повторно. Содержимое шагов сервер не разбирает.
+//
+// Протокол (JSON-сообщения):
+//   - init (сервер) - при подключении: снимок документа на версии version и шаги после нее;
+//   - steps (клиент) - новые шаги, начиная с версии version;
+//   - steps (сервер) - принятые шаги, начиная с версии version, и id клиентов-авторов;
+//   - snapshot (клиент) - содержимое документа (TipTap JSON и HTML) на текущей версии.
+//     Клиент отправляет снимок после паузы в редактировании и по запросу snapshot_request;
+//   - cursor (клиент) и presence (сервер) - участники сессии и позиции их курсоров;
+//   - saved (сервер) - снимок версии version сохранен.
+//
+// Снимки сохраняются в хранилище (Store) раз в PersistInterval, а в историю изменений
+// фиксируются раз в HistoryInterval и при завершении сессии.
+package collab
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
+)
+
+// Типы сообщений
+const (
+	MsgInit            = "init"
+	MsgSteps           = "steps"
+	MsgSnapshot        = "snapshot"
+	MsgSnapshotRequest = "snapshot_request"
+	MsgCursor          = "cursor"
+	MsgPresence        = "presence"
+	MsgSaved           = "saved"
+	MsgError           = "error"
+)
+
+// Content - содержимое документа на версии Version
+type Content struct {
+	Version int
+	// Doc - документ в формате TipTap JSON, может быть пустым до первого снимка от клиента
+	Doc  json.RawMessage
+	HTML string
+}
+
+// Store сохраняет содержимое сессии в сущность (документ, описание задачи)
+type Store interface {
+	// Save сохраняет содержимое, actor - последний редактировавший пользователь
+	Save(ctx context.Context, content Content, actor *dao.User) error
+	// Commit записывает в историю изменения, сохраненные с момента прошлой фиксации
+	Commit(ctx context.Context, actor *dao.User) error
+}
+
+// Opener загружает текущее содержимое и хранилище при создании сессии
+type Opener func() (Store, Content, error)
+
+// ClientMessage - сообщение клиента
+type ClientMessage struct {
+	Type    string            `json:"type"`
+	Version int               `json:"version"`
+	Steps   []json.RawMessage `json:"steps,omitempty"`
+	Doc     json.RawMessage   `json:"doc,omitempty"`
+	HTML    string            `json:"html,omitempty"`
+	Anchor  int               `json:"anchor,omitempty"`
+	Head    int               `json:"head,omitempty"`
+}
+
+// ServerMessage - сообщение сервера
+type ServerMessage struct {
+	Type    string `json:"type"`
+	Version int    `json:"version"`
+
+	// init
+	ClientID string          `json:"client_id,omitempty"`
+	CanEdit  bool            `json:"can_edit,omitempty"`
+	Doc      json.RawMessage `json:"doc,omitempty"`
+	HTML     string          `json:"html,omitempty"`
+
+	// init, steps
+	Steps     []json.RawMessage `json:"steps,omitempty"`
+	ClientIDs []string          `json:"client_ids,omitempty"`
+
+	// init, presence
+	Participants []Participant `json:"participants,omitempty"`
+
+	SavedAt *time.Time `json:"saved_at,omitempty"`
+	Error   string     `json:"error,omitempty"`
+}
+
+// Participant - участник сессии
+type Participant struct {
+	ClientID string         `json:"client_id"`
+	User     *dto.UserLight `json:"user"`
+	CanEdit  bool           `json:"can_edit"`
+	Cursor   *Cursor        `json:"cursor,omitempty"`
+}
+
+// Cursor - выделение участника, позиции относятся к версии документа Version
+type Cursor struct {
+	Version int `json:"version"`
+	Anchor  int `json:"anchor"`
+	Head    int `json:"head"`
+}
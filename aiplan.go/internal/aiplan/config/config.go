@@ -78,6 +78,8 @@ type Config struct {
 
 	GitEnabled          bool   `env:"GIT_ENABLED"`
 	GitRepositoriesPath string `env:"GIT_REPOSITORIES_PATH"`
+	// Хосты во внутренней сети, с которых разрешено зеркалирование репозиториев, через запятую
+	GitMirrorAllowedHosts string `env:"GIT_MIRROR_ALLOWED_HOSTS"`
 
 	// SSH Git server configuration
 	SSHEnabled          bool   `env:"SSH_ENABLED"`
@@ -93,6 +95,24 @@ type Config struct {
 	LDAPBindPassword string        `env:"LDAP_BIND_PASSWORD"`
 	LDAPFilter       string        `env:"LDAP_FILTER"`
 	LDAPForce        bool          `env:"LDAP_FORCE"`
+	// Атрибут пользователя со списком его групп для синхронизации участников пространств и проектов
+	LDAPGroupAttribute string `env:"LDAP_GROUP_ATTRIBUTE"`
+	// Расписание синхронизации участия в пространствах и проектах по группам LDAP в формате cron
+	LDAPSyncSchedule string `env:"LDAP_SYNC_SCHEDULE"`
+
+	// OpenID Connect configuration
+	OIDCIssuerURL    string `env:"OIDC_ISSUER_URL"`
+	OIDCClientID     string `env:"OIDC_CLIENT_ID"`
+	OIDCClientSecret string `env:"OIDC_CLIENT_SECRET"`
+	OIDCScopes       string `env:"OIDC_SCOPES"`
+	OIDCGroupsClaim  string `env:"OIDC_GROUPS_CLAIM"`
+	OIDCRoleMapping  string `env:"OIDC_ROLE_MAPPING"`
+	OIDCProviderName string `env:"OIDC_PROVIDER_NAME"`
+	// Вход только через SSO, вход по паролю доступен только суперпользователям
+	SSOForce bool `env:"SSO_FORCE"`
+
+	// Двухфакторная аутентификация обязательна для всех пользователей
+	TwoFactorRequired bool `env:"TWO_FACTOR_REQUIRED"`
 
 	MCPEnabled bool `env:"MCP_ENABLED"`
 
@@ -126,6 +146,16 @@ func ReadConfig(configPath string) *Config {
 	if config.LDAPServerURL.URL != nil && config.LDAPFilter == "" {
 		config.LDAPFilter = "(&(uniqueIdentifier={email}))"
 	}
+	if config.LDAPServerURL.URL != nil && config.LDAPGroupAttribute == "" {
+		config.LDAPGroupAttribute = "memberOf"
+	}
+	if config.LDAPServerURL.URL != nil && config.LDAPSyncSchedule == "" {
+		config.LDAPSyncSchedule = "0 * * * *"
+	}
+
+	if config.OIDCIssuerURL != "" && config.OIDCProviderName == "" {
+		config.OIDCProviderName = "SSO"
+	}
 
 	if config.OTELSampleRate == 0 {
 		config.OTELSampleRate = 0.1
@@ -0,0 +1,68 @@
+package dao
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
+	"github.com/gofrs/uuid"
+)
+
+// AccessTokenPrefix - префикс персональных токенов доступа, по нему они отличаются от токена авторизации пользователя
+const AccessTokenPrefix = "aipat_"
+
+// Персональные токены доступа пользователя. Хранится только хеш токена, сам токен показывается один раз при создании
+type PersonalAccessToken struct {
+	ID        uuid.UUID `gorm:"column:id;primaryKey;type:uuid" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UserId    uuid.UUID `json:"user_id" gorm:"type:uuid;index"`
+	Name      string    `json:"name"`
+	TokenHash string    `json:"-" gorm:"uniqueIndex"`
+	// Начало токена для отображения в списке
+	Prefix string   `json:"prefix"`
+	Scopes []string `json:"scopes" gorm:"serializer:json"`
+	// Токен действует только в указанном пространстве
+	WorkspaceId uuid.NullUUID `json:"workspace_id" gorm:"type:uuid" extensions:"x-nullable"`
+	ExpiresAt   *time.Time    `json:"expires_at" extensions:"x-nullable"`
+	LastUsedAt  *time.Time    `json:"last_used_at" extensions:"x-nullable"`
+	LastUsedIp  string        `json:"-"`
+	RevokedAt   *time.Time    `json:"revoked_at" gorm:"index" extensions:"x-nullable"`
+
+	User      *User      `json:"-" gorm:"foreignKey:UserId" extensions:"x-nullable"`
+	Workspace *Workspace `json:"-" gorm:"foreignKey:WorkspaceId" extensions:"x-nullable"`
+}
+
+func (PersonalAccessToken) TableName() string { return "personal_access_tokens" }
+
+// HashAccessToken возвращает хеш токена для поиска в БД
+func HashAccessToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Active проверяет, что токен не отозван и не истек
+func (t *PersonalAccessToken) Active(now time.Time) bool {
+	return t.RevokedAt == nil && (t.ExpiresAt == nil || t.ExpiresAt.After(now))
+}
+
+func (t *PersonalAccessToken) ToDTO() *dto.PersonalAccessToken {
+	if t == nil {
+		return nil
+	}
+	res := &dto.PersonalAccessToken{
+		ID:          t.ID,
+		CreatedAt:   t.CreatedAt,
+		Name:        t.Name,
+		Prefix:      t.Prefix,
+		Scopes:      t.Scopes,
+		WorkspaceId: t.WorkspaceId,
+		ExpiresAt:   t.ExpiresAt,
+		LastUsedAt:  t.LastUsedAt,
+		RevokedAt:   t.RevokedAt,
+	}
+	if t.Workspace != nil {
+		res.Workspace = t.Workspace.ToLightDTO()
+	}
+	return res
+}
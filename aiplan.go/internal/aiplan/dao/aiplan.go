@@ -56,7 +56,7 @@ type SessionsReset struct {
 // Возвращает имя таблицы для данного типа структуры.
 func (SessionsReset) TableName() string { return "sessions_resets" }
 
-// Сбрасывает сессии пользователя, создавая запись о сбросе в базе данных.
+// Сбрасывает сессии пользователя, отзывая его сессии на устройствах и создавая запись о сбросе в базе данных.
 //
 // Параметры:
 //   - db: экземпляр gorm.DB для взаимодействия с базой данных.
@@ -65,6 +65,9 @@ func (SessionsReset) TableName() string { return "sessions_resets" }
 // Возвращает:
 //   - error: ошибка, если при создании записи произошла ошибка.
 func ResetUserSessions(db *gorm.DB, user *User) error {
+	if err := RevokeUserSessions(db, user.ID); err != nil {
+		return err
+	}
 	return db.Create(&SessionsReset{
 		Id:        GenUUID(),
 		UserId:    user.ID,
@@ -10,9 +10,13 @@
 package dao
 
 import (
+	"database/sql"
 	"fmt"
+	"html"
 	"net/url"
+	"regexp"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
@@ -259,6 +263,17 @@ func (d *Doc) SetUrl() {
 	}
 }
 
+// Обновляет ссылки на задачи и документы из содержимого документа, если содержимое было записано.
+func (d *Doc) AfterSave(tx *gorm.DB) error {
+	if d.ID.IsNil() || !savesColumn(tx, "content") {
+		return nil
+	}
+	if err := SyncReferences(tx, ReferenceSourceDoc, d.ID); err != nil {
+		return err
+	}
+	return RelocateDocAnchors(tx, d.ID)
+}
+
 // Удаляет активность, связанную с документом перед его удалением из базы данных.  Параметр tx - это объект базы данных GORM, используемый для выполнения операций с базой данных. Функция возвращает ошибку, если при выполнении каких-либо операций с базой данных возникает ошибка.
 func (d *Doc) BeforeDelete(tx *gorm.DB) error {
 	var childDocs []Doc
@@ -290,10 +305,18 @@ func (d *Doc) BeforeDelete(tx *gorm.DB) error {
 		return err
 	}
 
+	if err := deleteReferences(tx, "source_doc_id = ? OR target_doc_id = ?", d.ID, d.ID); err != nil {
+		return err
+	}
+
 	if err := tx.Where("doc_id = ?", d.ID).Delete(&DocAccessRules{}).Error; err != nil {
 		return err
 	}
 
+	if err := tx.Where("doc_id = ?", d.ID).Delete(&DocShareLink{}).Error; err != nil {
+		return err
+	}
+
 	// Delete comments, reaction
 	var comments []DocComment
 	if err := tx.Where("doc_id = ?", d.ID).Preload("Attachments").Find(&comments).Error; err != nil {
@@ -420,11 +443,21 @@ type DocComment struct {
 	ReplyToCommentId uuid.NullUUID `json:"reply_to_comment_id"`
 	OriginalComment  *DocComment   `json:"original_comment,omitempty" gorm:"foreignKey:ReplyToCommentId" extensions:"x-nullable"`
 
-	Workspace *Workspace `json:"-" gorm:"foreignKey:WorkspaceId" extensions:"x-nullable"`
-	Doc       *Doc       `json:"-" gorm:"foreignKey:DocId" extensions:"x-nullable"`
-	Actor     *User      `json:"actor_detail" gorm:"foreignKey:ActorId" extensions:"x-nullable"`
-	CreatedBy *User      `json:"created_by_detail" gorm:"foreignKey:CreatedById;references:ID" extensions:"x-nullable"`
-	UpdatedBy *User      `json:"updated_by_detail" gorm:"foreignKey:UpdatedById;references:ID;" extensions:"x-nullable"`
+	// Фрагмент текста документа, к которому привязано обсуждение. Задается только у первого комментария обсуждения
+	Anchor *DocCommentAnchor `json:"anchor,omitempty" gorm:"type:jsonb;serializer:json" extensions:"x-nullable"`
+	// Автор выделенного фрагмента, получает уведомления об обсуждении
+	AnchorAuthorId uuid.NullUUID `json:"anchor_author_id,omitempty" gorm:"type:uuid" extensions:"x-nullable"`
+	// Первый комментарий обсуждения, к которому относится ответ
+	ThreadId     uuid.NullUUID `json:"thread_id,omitempty" gorm:"type:uuid;index" extensions:"x-nullable"`
+	ResolvedAt   sql.NullTime  `json:"resolved_at" extensions:"x-nullable"`
+	ResolvedById uuid.NullUUID `json:"resolved_by_id,omitempty" gorm:"type:uuid" extensions:"x-nullable"`
+
+	Workspace  *Workspace `json:"-" gorm:"foreignKey:WorkspaceId" extensions:"x-nullable"`
+	Doc        *Doc       `json:"-" gorm:"foreignKey:DocId" extensions:"x-nullable"`
+	Actor      *User      `json:"actor_detail" gorm:"foreignKey:ActorId" extensions:"x-nullable"`
+	ResolvedBy *User      `json:"resolved_by_detail" gorm:"foreignKey:ResolvedById" extensions:"x-nullable"`
+	CreatedBy  *User      `json:"created_by_detail" gorm:"foreignKey:CreatedById;references:ID" extensions:"x-nullable"`
+	UpdatedBy  *User      `json:"updated_by_detail" gorm:"foreignKey:UpdatedById;references:ID;" extensions:"x-nullable"`
 
 	Attachments []FileAsset `json:"comment_attachments" gorm:"foreignKey:DocCommentId"`
 
@@ -483,6 +516,14 @@ func (dc *DocComment) AfterFind(tx *gorm.DB) error {
 	return nil
 }
 
+// Обновляет ссылки на задачи и документы из текста комментария, если текст был записан.
+func (dc *DocComment) AfterSave(tx *gorm.DB) error {
+	if dc.Id.IsNil() || !savesColumn(tx, "comment_html") {
+		return nil
+	}
+	return SyncReferences(tx, ReferenceSourceDocComment, dc.Id)
+}
+
 // Удаляет активность, связанную с документом перед его удалением из базы данных.
 //
 // Параметры:
@@ -505,6 +546,10 @@ func (dc *DocComment) BeforeDelete(tx *gorm.DB) error {
 		return err
 	}
 
+	if err := deleteReferences(tx, "source_id = ?", dc.Id); err != nil {
+		return err
+	}
+
 	for _, attach := range dc.Attachments {
 		if err := tx.Delete(&attach).Error; err != nil {
 			return err
@@ -518,6 +563,17 @@ func (dc *DocComment) BeforeDelete(tx *gorm.DB) error {
 	if err := tx.Model(&DocComment{}).Where("reply_to_comment_id = ?", dc.Id).Update("reply_to_comment_id", nil).Error; err != nil {
 		return err
 	}
+
+	// Ответы в обсуждении фрагмента удаляются вместе с ним
+	var replies []DocComment
+	if err := tx.Where("thread_id = ?", dc.Id).Preload("Attachments").Find(&replies).Error; err != nil {
+		return err
+	}
+	for _, reply := range replies {
+		if err := tx.Delete(&reply).Error; err != nil {
+			return err
+		}
+	}
 	return nil
 }
 func (dc *DocComment) ToLightDTO() *dto.DocCommentLight {
@@ -551,6 +607,14 @@ func (dc *DocComment) ToDTO() *dto.DocComment {
 		Attachments:     utils.SliceToSlice(&dc.Attachments, func(fa *FileAsset) dto.FileAsset { return *fa.ToDTO() }),
 		Reactions:       utils.SliceToSlice(&dc.Reactions, func(cr *DocCommentReaction) *dto.CommentReaction { return cr.ToDTO() }),
 		ReactionSummary: dc.ReactionSummary,
+
+		Anchor:         dc.Anchor.ToDTO(),
+		AnchorAuthorId: dc.AnchorAuthorId,
+		ThreadId:       dc.ThreadId,
+		ResolvedBy:     dc.ResolvedBy.ToLightDTO(),
+	}
+	if dc.ResolvedAt.Valid {
+		comment.ResolvedAt = &dc.ResolvedAt.Time
 	}
 
 	if dc.ReplyToCommentId.Valid {
@@ -841,6 +905,242 @@ func (df *DocFavorites) ToDTO() *dto.DocFavorites {
 	}
 }
 
+// DocShareLink - публичная ссылка на документ только для чтения. Документ (с WithChildren - вместе с дочерними,
+// доступными создателю ссылки) открывается по токену без авторизации до отзыва ссылки или истечения ExpiresAt
+type DocShareLink struct {
+	Id          uuid.UUID `json:"id" gorm:"primaryKey;type:uuid"`
+	CreatedAt   time.Time `json:"created_at"`
+	CreatedById uuid.UUID `json:"created_by_id" gorm:"type:uuid"`
+	DocId       uuid.UUID `json:"doc_id" gorm:"type:uuid;index"`
+	WorkspaceId uuid.UUID `json:"workspace_id" gorm:"type:uuid"`
+
+	Token        string       `json:"-" gorm:"uniqueIndex"`
+	PasswordHash string       `json:"-"`
+	WithChildren bool         `json:"with_children"`
+	ExpiresAt    sql.NullTime `json:"expires_at" extensions:"x-nullable"`
+
+	ViewCount    int          `json:"view_count" gorm:"default:0"`
+	LastViewedAt sql.NullTime `json:"last_viewed_at" extensions:"x-nullable"`
+
+	Workspace *Workspace `json:"-" gorm:"foreignKey:WorkspaceId" extensions:"x-nullable"`
+	Doc       *Doc       `json:"-" gorm:"foreignKey:DocId" extensions:"x-nullable"`
+	CreatedBy *User      `json:"-" gorm:"foreignKey:CreatedById;references:ID" extensions:"x-nullable"`
+}
+
+// Возвращает имя таблицы, соответствующей сущности DocShareLink.
+func (DocShareLink) TableName() string { return "doc_share_links" }
+
+func (dsl DocShareLink) GetWorkspaceId() uuid.UUID {
+	return dsl.WorkspaceId
+}
+
+// HasPassword сообщает, защищена ли ссылка паролем
+func (dsl *DocShareLink) HasPassword() bool {
+	return dsl.PasswordHash != ""
+}
+
+// Expired сообщает, истек ли срок действия ссылки
+func (dsl *DocShareLink) Expired() bool {
+	return dsl.ExpiresAt.Valid && !dsl.ExpiresAt.Time.After(time.Now())
+}
+
+// URL возвращает адрес публичной страницы документа
+func (dsl *DocShareLink) URL() *url.URL {
+	ref, _ := url.Parse("/share/docs/" + dsl.Token)
+	return Config.WebURL.URL.ResolveReference(ref)
+}
+
+// Description возвращает описание параметров ссылки для истории документа. Токен в историю не попадает
+func (dsl *DocShareLink) Description() string {
+	desc := "публичная ссылка"
+	if dsl.WithChildren {
+		desc += ", с дочерними документами"
+	}
+	if dsl.HasPassword() {
+		desc += ", с паролем"
+	}
+	if dsl.ExpiresAt.Valid {
+		desc += ", до " + dsl.ExpiresAt.Time.Format("02.01.2006 15:04")
+	}
+	return desc
+}
+
+func (dsl *DocShareLink) ToDTO() *dto.DocShareLink {
+	if dsl == nil {
+		return nil
+	}
+	return &dto.DocShareLink{
+		Id:           dsl.Id,
+		CreatedAt:    dsl.CreatedAt,
+		CreatedBy:    dsl.CreatedBy.ToLightDTO(),
+		Url:          types.JsonURL{URL: dsl.URL()},
+		Token:        dsl.Token,
+		WithChildren: dsl.WithChildren,
+		HasPassword:  dsl.HasPassword(),
+		ExpiresAt:    utils.SqlNullTimeToPointerTime(dsl.ExpiresAt),
+		ViewCount:    dsl.ViewCount,
+		LastViewedAt: utils.SqlNullTimeToPointerTime(dsl.LastViewedAt),
+	}
+}
+
+// DocTemplate - шаблон документа пространства. Title и Template могут содержать переменные вида {{date}},
+// которые подставляются при создании документа из шаблона, список переменных - DocTemplateVariables
+type DocTemplate struct {
+	Id          uuid.UUID `json:"id" gorm:"primaryKey;type:uuid"`
+	CreatedAt   time.Time `json:"created_at"`
+	CreatedById uuid.UUID `json:"created_by_id" gorm:"type:uuid"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	UpdatedById uuid.UUID `json:"updated_by_id" gorm:"type:uuid"`
+
+	WorkspaceId uuid.UUID `json:"workspace_id" gorm:"type:uuid;uniqueIndex:doc_template_name_idx,priority:1"`
+
+	Name        string             `json:"name" gorm:"uniqueIndex:doc_template_name_idx,priority:2"`
+	Description string             `json:"description"`
+	Title       string             `json:"title"`
+	Template    types.RedactorHTML `json:"template"`
+
+	Workspace *Workspace `json:"-" gorm:"foreignKey:WorkspaceId" extensions:"x-nullable"`
+	CreatedBy *User      `json:"-" gorm:"foreignKey:CreatedById;references:ID" extensions:"x-nullable"`
+	UpdatedBy *User      `json:"-" gorm:"foreignKey:UpdatedById;references:ID" extensions:"x-nullable"`
+}
+
+// Возвращает имя таблицы, соответствующей сущности DocTemplate.
+func (DocTemplate) TableName() string { return "doc_templates" }
+
+func (dt DocTemplate) GetId() uuid.UUID {
+	return dt.Id
+}
+
+func (dt DocTemplate) GetString() string {
+	return dt.Name
+}
+
+func (dt DocTemplate) GetEntityType() actField.ActivityField {
+	return actField.DocTemplate.Field
+}
+
+func (dt DocTemplate) GetWorkspaceId() uuid.UUID {
+	return dt.WorkspaceId
+}
+
+func (dt *DocTemplate) ToLightDTO() *dto.DocTemplateLight {
+	if dt == nil {
+		return nil
+	}
+	return &dto.DocTemplateLight{
+		Id:          dt.Id,
+		Name:        dt.Name,
+		Description: dt.Description,
+		Title:       dt.Title,
+	}
+}
+
+func (dt *DocTemplate) ToDTO() *dto.DocTemplate {
+	if dt == nil {
+		return nil
+	}
+	return &dto.DocTemplate{
+		DocTemplateLight: *dt.ToLightDTO(),
+		Template:         dt.Template,
+		WorkspaceId:      dt.WorkspaceId,
+		CreatedAt:        dt.CreatedAt,
+		CreatedBy:        dt.CreatedBy.ToLightDTO(),
+		UpdatedAt:        dt.UpdatedAt,
+		UpdatedBy:        dt.UpdatedBy.ToLightDTO(),
+	}
+}
+
+// DocTemplateVariables - переменные, доступные в шаблонах документов
+var DocTemplateVariables = []dto.DocTemplateVariable{
+	{Name: "date", Description: "Дата создания документа, 02.01.2006"},
+	{Name: "time", Description: "Время создания документа, 15:04"},
+	{Name: "datetime", Description: "Дата и время создания документа"},
+	{Name: "author", Description: "Имя автора документа"},
+	{Name: "author_email", Description: "Email автора документа"},
+	{Name: "workspace", Description: "Название пространства"},
+	{Name: "parent", Description: "Название родительского документа"},
+	{Name: "sprint", Description: "Название связанного спринта с датами"},
+	{Name: "sprint_start", Description: "Дата начала связанного спринта"},
+	{Name: "sprint_end", Description: "Дата окончания связанного спринта"},
+	{Name: "sprint_url", Description: "Ссылка на связанный спринт"},
+}
+
+var docTemplateVarRe = regexp.MustCompile(`\{\{\s*([a-z_]+)\s*\}\}`)
+
+// DocTemplateVars - значения переменных шаблона документа. Дата и время выводятся в часовом поясе автора,
+// переменные родительского документа и спринта пустые, если они не заданы
+// -migration
+type DocTemplateVars struct {
+	Now       time.Time
+	Author    *User
+	Workspace *Workspace
+	Parent    *Doc
+	Sprint    *Sprint
+}
+
+func (v DocTemplateVars) values() map[string]string {
+	now := v.Now
+	if v.Author != nil {
+		now = now.In((*time.Location)(&v.Author.UserTimezone))
+	}
+
+	values := map[string]string{
+		"date":     now.Format("02.01.2006"),
+		"time":     now.Format("15:04"),
+		"datetime": now.Format("02.01.2006 15:04"),
+	}
+	if v.Author != nil {
+		values["author"] = v.Author.GetName()
+		values["author_email"] = v.Author.Email
+	}
+	if v.Workspace != nil {
+		values["workspace"] = v.Workspace.Name
+	}
+	if v.Parent != nil {
+		values["parent"] = v.Parent.Title
+	}
+	if v.Sprint != nil {
+		values["sprint"] = v.Sprint.GetFullName()
+		if v.Sprint.StartDate.Valid {
+			values["sprint_start"] = v.Sprint.StartDate.Time.Format("02.01.2006")
+		}
+		if v.Sprint.EndDate.Valid {
+			values["sprint_end"] = v.Sprint.EndDate.Time.Format("02.01.2006")
+		}
+		if v.Sprint.URL != nil {
+			values["sprint_url"] = v.Sprint.URL.String()
+		}
+	}
+	return values
+}
+
+// Render подставляет переменные в название и содержимое шаблона. Неизвестные переменные остаются без изменений,
+// значения в содержимом экранируются. Если у шаблона нет названия, используется имя шаблона
+func (dt *DocTemplate) Render(vars DocTemplateVars) (string, types.RedactorHTML) {
+	values := vars.values()
+	replace := func(s string, escape bool) string {
+		return docTemplateVarRe.ReplaceAllStringFunc(s, func(m string) string {
+			name := docTemplateVarRe.FindStringSubmatch(m)[1]
+			val, ok := values[name]
+			if !ok {
+				if !slices.ContainsFunc(DocTemplateVariables, func(v dto.DocTemplateVariable) bool { return v.Name == name }) {
+					return m
+				}
+			}
+			if escape {
+				return html.EscapeString(val)
+			}
+			return val
+		})
+	}
+
+	title := dt.Title
+	if title == "" {
+		title = dt.Name
+	}
+	return strings.TrimSpace(replace(title, false)), types.RedactorHTML{Body: replace(dt.Template.Body, true)}
+}
+
 // GetDoc получает документ по ID и другим параметрам, используя базу данных GORM.
 //
 // Параметры:
@@ -0,0 +1,108 @@
+package dao
+
+import (
+	"strings"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor/diff"
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// maxBlameVersions - сколько последних версий документа просматривается при поиске автора фрагмента
+const maxBlameVersions = 200
+
+// DocCommentAnchor - фрагмент текста документа, к которому привязано обсуждение
+// -migration
+type DocCommentAnchor struct {
+	diff.Anchor
+	// Фрагмент удален из документа, обсуждение показывается отдельно от текста
+	Detached bool `json:"detached"`
+}
+
+// ToDTO преобразует фрагмент в DTO без текста блока
+func (a *DocCommentAnchor) ToDTO() *dto.DocCommentAnchor {
+	if a == nil {
+		return nil
+	}
+	return &dto.DocCommentAnchor{
+		Block:    a.Block,
+		Start:    a.Start,
+		End:      a.End,
+		Text:     a.Text,
+		Detached: a.Detached,
+	}
+}
+
+// RelocateDocAnchors переносит фрагменты обсуждений документа в текущую версию содержимого.
+// Вызывается после каждого сохранения содержимого документа
+func RelocateDocAnchors(tx *gorm.DB, docId uuid.UUID) error {
+	var comments []DocComment
+	if err := tx.
+		Select("id", "anchor").
+		Where("doc_id = ?", docId).
+		Where("anchor IS NOT NULL").
+		Find(&comments).Error; err != nil {
+		return err
+	}
+	if len(comments) == 0 {
+		return nil
+	}
+
+	var content string
+	if err := tx.Model(&Doc{}).Select("content").Where("id = ?", docId).Scan(&content).Error; err != nil {
+		return err
+	}
+	doc, err := editor.ParseDocument(strings.NewReader(content))
+	if err != nil {
+		return err
+	}
+
+	for _, comment := range comments {
+		if comment.Anchor == nil {
+			continue
+		}
+		anchor, ok := diff.RelocateAnchor(doc, comment.Anchor.Anchor)
+		relocated := DocCommentAnchor{Anchor: anchor, Detached: !ok}
+		if relocated == *comment.Anchor {
+			continue
+		}
+		if err := tx.Model(&DocComment{Id: comment.Id}).
+			Select("anchor").
+			UpdateColumns(&DocComment{Anchor: &relocated}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DocTextAuthor возвращает пользователя, добавившего текст в документ: автора самого раннего изменения,
+// после которого текст непрерывно присутствует в документе. Если текст был в документе при создании - автора документа
+func DocTextAuthor(tx *gorm.DB, doc *Doc, text string) (uuid.UUID, error) {
+	var versions []struct {
+		OldValue string
+		ActorId  uuid.UUID
+	}
+	if err := tx.Model(&ActivityEvent{}).
+		Select("old_value", "actor_id").
+		Where("workspace_id = ?", doc.WorkspaceId).
+		Where("doc_id = ?", doc.ID).
+		Where("field = ?", "description").
+		Order("created_at DESC").
+		Limit(maxBlameVersions).
+		Scan(&versions).Error; err != nil {
+		return uuid.Nil, err
+	}
+
+	for _, v := range versions {
+		old, err := editor.ParseDocument(strings.NewReader(v.OldValue))
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if !strings.Contains(diff.Text(old), text) {
+			return v.ActorId, nil
+		}
+	}
+	return doc.CreatedById, nil
+}
@@ -34,6 +34,8 @@ type Form struct {
 	Title       string             `json:"title" validate:"required"`
 	Description types.RedactorHTML `json:"description"`
 	AuthRequire bool               `json:"auth_require"`
+	// Пользователь может изменять свой ответ, пока форма открыта
+	AllowEdit bool `json:"allow_edit"`
 
 	TargetProjectId uuid.NullUUID `gorm:"type:uuid"`
 	TargetProject   *Project      `gorm:"foreignKey:TargetProjectId" extensions:"x-nullable"`
@@ -43,6 +45,8 @@ type Form struct {
 	Workspace   *Workspace        `json:"workspace_detail" gorm:"foreignKey:WorkspaceId" extensions:"x-nullable"`
 
 	Fields                 types.FormFieldsSlice  `json:"fields" gorm:"type:jsonb"`
+	Sections               types.FormSections     `json:"sections" gorm:"type:jsonb"`
+	Protection             types.FormProtection   `json:"protection" gorm:"type:jsonb"`
 	Active                 bool                   `json:"active" gorm:"-"`
 	NotificationChannels   types.FormAnswerNotify `json:"notification_channels" gorm:"type:jsonb"`
 	Hash                   []byte                 `json:"-" gorm:"->;-:migration"`
@@ -82,10 +86,13 @@ func (f *Form) ToLightDTO() *dto.FormLight {
 		Title:           f.Title,
 		Description:     f.Description,
 		AuthRequire:     f.AuthRequire,
+		AllowEdit:       f.AllowEdit,
 		EndDate:         f.EndDate,
 		TargetProjectId: f.TargetProjectId,
 		WorkspaceId:     f.WorkspaceId,
 		Fields:          f.Fields,
+		Sections:        f.Sections,
+		Protection:      f.Protection,
 		Active:          f.Active,
 		Url:             types.JsonURL{f.URL},
 	}
@@ -176,6 +183,10 @@ func (form *Form) BeforeSave(tx *gorm.DB) error {
 	for i, fields := range form.Fields {
 		form.Fields[i].Label = policy.StripTagsPolicy.Sanitize(fields.Label)
 	}
+	for i, section := range form.Sections {
+		form.Sections[i].Title = policy.StripTagsPolicy.Sanitize(section.Title)
+		form.Sections[i].Description = policy.StripTagsPolicy.Sanitize(section.Description)
+	}
 	return nil
 }
 
@@ -243,6 +254,10 @@ type FormAnswer struct {
 	Attachment   *FormAttachment `json:"attachment_detail" gorm:"foreignKey:AttachmentId" extensions:"x-nullable"`
 
 	Attachments []FormAttachment `json:"attachments" gorm:"foreignKey:AnswerId;references:ID"`
+
+	// Задача, созданная по ответу или дополненная ответом
+	IssueId  uuid.NullUUID `json:"issue_id" gorm:"type:uuid;index" extensions:"x-nullable"`
+	EditedAt *time.Time    `json:"edited_at" extensions:"x-nullable"`
 }
 
 // TableName возвращает имя таблицы для сущности Form. Используется GORM для определения имени таблицы в базе данных.
@@ -286,6 +301,8 @@ func (fa *FormAnswer) ToDTO() *dto.FormAnswer {
 		ID:          fa.ID,
 		SeqId:       fa.SeqId,
 		CreatedAt:   fa.CreatedAt,
+		EditedAt:    fa.EditedAt,
+		IssueId:     fa.IssueId,
 		Responder:   fa.Responder.ToLightDTO(),
 		Form:        fa.Form.ToDTO(),
 		Fields:      fa.Fields,
@@ -303,7 +320,7 @@ func (fa *FormAnswer) ToDTO() *dto.FormAnswer {
 func (answer *FormAnswer) BeforeSave(tx *gorm.DB) error {
 	for i, fields := range answer.Fields {
 		switch fields.Type {
-		case "textarea", "input":
+		case "textarea", "input", "hidden":
 			if answer.Fields[i].Val != nil {
 				answer.Fields[i].Val = policy.UgcPolicy.Sanitize(fields.Val.(string))
 			}
@@ -321,7 +338,7 @@ func (answer *FormAnswer) BeforeSave(tx *gorm.DB) error {
 //   - error: Возвращает ошибку, если при выполнении каких-либо операций произошла ошибка.
 func (answer *FormAnswer) AfterFind(tx *gorm.DB) error {
 	for i, field := range answer.Fields {
-		if field.Type == "input" || field.Type == "textarea" {
+		if field.Type == "input" || field.Type == "textarea" || field.Type == "hidden" {
 			if answer.Fields[i].Val != nil {
 				answer.Fields[i].Val = html.UnescapeString(field.Val.(string))
 			}
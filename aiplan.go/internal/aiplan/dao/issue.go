@@ -736,6 +736,15 @@ func (issue *Issue) BeforeDelete(tx *gorm.DB) error {
 		return err
 	}
 
+	// Delete references from issue and its comments, references to issue are kept until permanent delete
+	if permanentDelete {
+		if err := deleteReferences(tx, "source_issue_id = ? OR target_issue_id = ?", issue.ID, issue.ID); err != nil {
+			return err
+		}
+	} else if err := deleteReferences(tx, "source_issue_id = ?", issue.ID); err != nil {
+		return err
+	}
+
 	// Delete deferredNotification
 	if err := tx.Where("issue_id = ?", issue.ID).Delete(&DeferredNotifications{}).Error; err != nil {
 		return err
@@ -948,6 +957,20 @@ func (issue *Issue) BeforeSave(tx *gorm.DB) error {
 	return nil
 }
 
+// AfterSave - обновляет ссылки на задачи и документы из описания задачи, если описание было записано.
+//
+// Параметры:
+//   - tx: объект базы данных GORM для выполнения операций.
+//
+// Возвращает:
+//   - error: ошибка, если не удалось обновить ссылки.
+func (issue *Issue) AfterSave(tx *gorm.DB) error {
+	if issue.ID.IsNil() || !savesColumn(tx, "description_html") {
+		return nil
+	}
+	return SyncReferences(tx, ReferenceSourceIssue, issue.ID)
+}
+
 // IsAssignee проверяет, назначен ли пользователь на данную задачу.
 //
 // Параметры:
@@ -1708,6 +1731,20 @@ func (ic *IssueComment) BeforeUpdate(tx *gorm.DB) error {
 	return ic.BeforeSave(tx)
 }
 
+// AfterSave - обновляет ссылки на задачи и документы из текста комментария, если текст был записан.
+//
+// Параметры:
+//   - tx: объект базы данных GORM для выполнения операций.
+//
+// Возвращает:
+//   - error: ошибка, если не удалось обновить ссылки.
+func (ic *IssueComment) AfterSave(tx *gorm.DB) error {
+	if ic.Id.IsNil() || !savesColumn(tx, "comment_html") {
+		return nil
+	}
+	return SyncReferences(tx, ReferenceSourceIssueComment, ic.Id)
+}
+
 // AfterFind - выполняется после извлечения объекта из базы данных.  Функция обновляет URL, собирает информацию о реакциях и возвращает ошибку, если при поиске произошла ошибка.
 //
 // Параметры:
@@ -1743,6 +1780,10 @@ func (ic *IssueComment) BeforeDelete(tx *gorm.DB) error {
 		return err
 	}
 
+	if err := deleteReferences(tx, "source_id = ?", ic.Id); err != nil {
+		return err
+	}
+
 	tx.
 		Where("entity_type = ?", types.LayerIssue).
 		Where("new_identifier = ? AND verb = ? AND field = ?", ic.Id, actField.VerbCreated, actField.Comment.Field.String()).
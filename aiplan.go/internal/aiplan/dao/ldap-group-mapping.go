@@ -0,0 +1,47 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
+	"github.com/gofrs/uuid"
+)
+
+// Сопоставление группы LDAP участию в пространстве или проекте. Пользователи группы добавляются в пространство
+// (или проект, если он указан) с заданной ролью при синхронизации и входе через LDAP. С RemoveMembers пользователи
+// LDAP, не входящие ни в одну сопоставленную группу, удаляются из пространства или проекта
+type LdapGroupMapping struct {
+	ID          uuid.UUID     `gorm:"column:id;primaryKey;type:uuid" json:"id"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+	CreatedById uuid.NullUUID `json:"created_by_id" gorm:"type:uuid" extensions:"x-nullable"`
+	// DN или CN группы
+	Group         string        `json:"group"`
+	WorkspaceId   uuid.UUID     `json:"workspace_id" gorm:"type:uuid;index"`
+	ProjectId     uuid.NullUUID `json:"project_id" gorm:"type:uuid" extensions:"x-nullable"`
+	Role          int           `json:"role"`
+	RemoveMembers bool          `json:"remove_members"`
+
+	Workspace *Workspace `json:"-" gorm:"foreignKey:WorkspaceId" extensions:"x-nullable"`
+	Project   *Project   `json:"-" gorm:"foreignKey:ProjectId" extensions:"x-nullable"`
+}
+
+func (LdapGroupMapping) TableName() string { return "ldap_group_mappings" }
+
+func (m *LdapGroupMapping) ToDTO() *dto.LdapGroupMapping {
+	if m == nil {
+		return nil
+	}
+	return &dto.LdapGroupMapping{
+		ID:            m.ID,
+		CreatedAt:     m.CreatedAt,
+		UpdatedAt:     m.UpdatedAt,
+		Group:         m.Group,
+		WorkspaceId:   m.WorkspaceId,
+		ProjectId:     m.ProjectId,
+		Role:          m.Role,
+		RemoveMembers: m.RemoveMembers,
+		Workspace:     m.Workspace.ToLightDTO(),
+		Project:       m.Project.ToLightDTO(),
+	}
+}
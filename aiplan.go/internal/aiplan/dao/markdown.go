@@ -0,0 +1,96 @@
+package dao
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor/markdown"
+	"gorm.io/gorm"
+)
+
+// markdownParseOptions возвращает параметры разбора Markdown в рамках пространства:
+// упоминания остаются только для участников пространства, ссылки PROJ-12 - только на существующие задачи
+func markdownParseOptions(tx *gorm.DB, workspace *Workspace) *markdown.ParseOptions {
+	members := make(map[string]bool)
+	issues := make(map[string]*editor.IssueLinkMention)
+
+	return &markdown.ParseOptions{
+		ResolveMention: func(username string) bool {
+			if exist, ok := members[username]; ok {
+				return exist
+			}
+			var exist bool
+			if err := tx.Model(&User{}).
+				Select("EXISTS(?)",
+					tx.Model(&User{}).
+						Select("1").
+						Joins("JOIN workspace_members wm ON wm.member_id = users.id").
+						Where("wm.workspace_id = ?", workspace.ID).
+						Where("users.username = ?", username),
+				).
+				Find(&exist).Error; err != nil {
+				return false
+			}
+			members[username] = exist
+			return exist
+		},
+		ResolveIssue: func(projectIdentifier string, sequenceId int) (*editor.IssueLinkMention, bool) {
+			key := fmt.Sprintf("%s-%d", projectIdentifier, sequenceId)
+			if link, ok := issues[key]; ok {
+				return link, link != nil
+			}
+
+			var exist bool
+			if err := tx.Model(&Issue{}).
+				Select("EXISTS(?)",
+					tx.Model(&Issue{}).
+						Select("1").
+						Joins("JOIN projects p ON p.id = issues.project_id AND p.deleted_at IS NULL").
+						Where("issues.workspace_id = ?", workspace.ID).
+						Where("p.identifier = ?", projectIdentifier).
+						Where("issues.sequence_id = ?", sequenceId),
+				).
+				Find(&exist).Error; err != nil || !exist {
+				issues[key] = nil
+				return nil, false
+			}
+
+			ref, _ := url.Parse(fmt.Sprintf("/%s/projects/%s/issues/%d", workspace.Slug, projectIdentifier, sequenceId))
+			link := &editor.IssueLinkMention{
+				Slug:              workspace.Slug,
+				ProjectIdentifier: projectIdentifier,
+				CurrentIssueId:    strconv.Itoa(sequenceId),
+				OriginalUrl:       Config.WebURL.URL.ResolveReference(ref).String(),
+			}
+			issues[key] = link
+			return link, true
+		},
+	}
+}
+
+// MarkdownToHTML преобразует Markdown в HTML редактора и структуру документа.
+// Упоминания и ссылки на задачи проверяются в рамках пространства workspace
+func MarkdownToHTML(tx *gorm.DB, workspace *Workspace, md string) (string, *editor.Document, error) {
+	doc, err := markdown.ParseString(md, markdownParseOptions(tx, workspace))
+	if err != nil {
+		return "", nil, err
+	}
+	return editor.RenderHTML(doc), doc, nil
+}
+
+// HTMLToMarkdown преобразует HTML редактора в Markdown.
+// Источник - всегда HTML: сохраненный JSON документа может отставать от него
+func HTMLToMarkdown(body string) (string, error) {
+	doc, err := editor.ParseDocument(strings.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	md, err := markdown.Serialize(doc)
+	if err != nil {
+		return "", err
+	}
+	return string(md), nil
+}
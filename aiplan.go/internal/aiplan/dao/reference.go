@@ -0,0 +1,233 @@
+package dao
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor/refs"
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// Типы источников ссылок
+const (
+	ReferenceSourceIssue        = "issue"
+	ReferenceSourceIssueComment = "issue_comment"
+	ReferenceSourceDoc          = "doc"
+	ReferenceSourceDocComment   = "doc_comment"
+)
+
+// EntityReference - ссылка из описания задачи, комментария или документа на задачу или документ того же пространства.
+// SourceIssueId/SourceDocId - задача или документ, которому принадлежит источник (для комментариев - родительская сущность)
+type EntityReference struct {
+	Id          uuid.UUID `json:"id" gorm:"primaryKey;type:uuid"`
+	CreatedAt   time.Time `json:"created_at"`
+	WorkspaceId uuid.UUID `json:"workspace_id" gorm:"type:uuid;index"`
+
+	SourceType    string        `json:"source_type"`
+	SourceId      uuid.UUID     `json:"source_id" gorm:"type:uuid;index"`
+	SourceIssueId uuid.NullUUID `json:"source_issue_id" gorm:"type:uuid;index" extensions:"x-nullable"`
+	SourceDocId   uuid.NullUUID `json:"source_doc_id" gorm:"type:uuid;index" extensions:"x-nullable"`
+
+	TargetIssueId uuid.NullUUID `json:"target_issue_id" gorm:"type:uuid;index" extensions:"x-nullable"`
+	TargetDocId   uuid.NullUUID `json:"target_doc_id" gorm:"type:uuid;index" extensions:"x-nullable"`
+
+	SourceIssue *Issue `json:"-" gorm:"foreignKey:SourceIssueId" extensions:"x-nullable"`
+	SourceDoc   *Doc   `json:"-" gorm:"foreignKey:SourceDocId" extensions:"x-nullable"`
+}
+
+// Возвращает имя таблицы, соответствующей сущности EntityReference.
+func (EntityReference) TableName() string { return "entity_references" }
+
+// ToDTO преобразует ссылку в DTO. Родительская задача или документ источника должны быть предзагружены
+func (r *EntityReference) ToDTO() *dto.EntityReference {
+	if r == nil {
+		return nil
+	}
+	return &dto.EntityReference{
+		SourceType: r.SourceType,
+		SourceId:   r.SourceId,
+		CreatedAt:  r.CreatedAt,
+		Issue:      r.SourceIssue.ToLightDTO(),
+		Doc:        r.SourceDoc.ToLightDTO(),
+	}
+}
+
+// referenceSource - текст задачи или документа, из которого извлекаются ссылки
+// -migration
+type referenceSource struct {
+	WorkspaceId uuid.UUID
+	IssueId     uuid.NullUUID
+	DocId       uuid.NullUUID
+	Body        string
+}
+
+// referenceTarget - задача или документ, на который указывает ссылка
+// -migration
+type referenceTarget struct {
+	issueId uuid.UUID
+	docId   uuid.UUID
+}
+
+// SyncReferences перечитывает текст источника и приводит таблицу ссылок в соответствие с ним:
+// исчезнувшие ссылки удаляются, новые добавляются. Если источник не найден, все его ссылки удаляются.
+// Учитываются только ссылки на существующие задачи и документы того же пространства, ссылки на самого себя пропускаются
+func SyncReferences(tx *gorm.DB, sourceType string, sourceId uuid.UUID) error {
+	tx = tx.Session(&gorm.Session{NewDB: true, SkipHooks: true})
+
+	var src referenceSource
+	var query *gorm.DB
+	switch sourceType {
+	case ReferenceSourceIssue:
+		query = tx.Model(&Issue{}).Select("workspace_id, id as issue_id, description_html as body")
+	case ReferenceSourceIssueComment:
+		query = tx.Model(&IssueComment{}).Select("workspace_id, issue_id, comment_html as body")
+	case ReferenceSourceDoc:
+		query = tx.Model(&Doc{}).Select("workspace_id, id as doc_id, content as body")
+	case ReferenceSourceDocComment:
+		query = tx.Model(&DocComment{}).Select("workspace_id, doc_id, comment_html as body")
+	default:
+		return nil
+	}
+	res := query.Where("id = ?", sourceId).Limit(1).Find(&src)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return tx.Where("source_id = ?", sourceId).Delete(&EntityReference{}).Error
+	}
+
+	targets, err := resolveReferences(tx, src, refs.Extract(src.Body, Config.WebURL.URL))
+	if err != nil {
+		return err
+	}
+
+	var existing []EntityReference
+	if err := tx.Where("source_id = ?", sourceId).Find(&existing).Error; err != nil {
+		return err
+	}
+
+	var outdated []uuid.UUID
+	for _, ref := range existing {
+		target := referenceTarget{issueId: ref.TargetIssueId.UUID, docId: ref.TargetDocId.UUID}
+		if _, ok := targets[target]; ok {
+			delete(targets, target)
+		} else {
+			outdated = append(outdated, ref.Id)
+		}
+	}
+
+	if len(outdated) > 0 {
+		if err := tx.Where("id in (?)", outdated).Delete(&EntityReference{}).Error; err != nil {
+			return err
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil
+	}
+
+	newRefs := make([]EntityReference, 0, len(targets))
+	for target := range targets {
+		newRefs = append(newRefs, EntityReference{
+			Id:            GenUUID(),
+			WorkspaceId:   src.WorkspaceId,
+			SourceType:    sourceType,
+			SourceId:      sourceId,
+			SourceIssueId: src.IssueId,
+			SourceDocId:   src.DocId,
+			TargetIssueId: uuid.NullUUID{UUID: target.issueId, Valid: !target.issueId.IsNil()},
+			TargetDocId:   uuid.NullUUID{UUID: target.docId, Valid: !target.docId.IsNil()},
+		})
+	}
+	return tx.CreateInBatches(&newRefs, 100).Error
+}
+
+// resolveReferences находит идентификаторы задач и документов пространства источника по ссылкам из текста
+func resolveReferences(tx *gorm.DB, src referenceSource, found refs.Refs) (map[referenceTarget]struct{}, error) {
+	targets := make(map[referenceTarget]struct{})
+	if found.Empty() {
+		return targets, nil
+	}
+
+	var slug string
+	if err := tx.Model(&Workspace{}).Select("slug").Where("id = ?", src.WorkspaceId).Find(&slug).Error; err != nil {
+		return nil, err
+	}
+
+	for _, ref := range found.Issues {
+		if ref.Workspace != "" && ref.Workspace != slug && !strings.EqualFold(ref.Workspace, src.WorkspaceId.String()) {
+			continue
+		}
+
+		var ids []uuid.UUID
+		if err := tx.Model(&Issue{}).
+			Joins("JOIN projects p ON p.id = issues.project_id").
+			Where("issues.workspace_id = ?", src.WorkspaceId).
+			Where("issues.sequence_id = ?", ref.Seq).
+			Where("p.identifier = upper(?) OR p.id::text = lower(?)", ref.Project, ref.Project).
+			Limit(1).
+			Pluck("issues.id", &ids).Error; err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			if src.IssueId.Valid && src.IssueId.UUID == id {
+				continue
+			}
+			targets[referenceTarget{issueId: id}] = struct{}{}
+		}
+	}
+
+	if len(found.Docs) > 0 {
+		var ids []uuid.UUID
+		if err := tx.Model(&Doc{}).
+			Where("workspace_id = ?", src.WorkspaceId).
+			Where("id in (?)", found.Docs).
+			Pluck("id", &ids).Error; err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			if src.DocId.Valid && src.DocId.UUID == id {
+				continue
+			}
+			targets[referenceTarget{docId: id}] = struct{}{}
+		}
+	}
+
+	return targets, nil
+}
+
+// savesColumn сообщает, записывает ли текущая операция сохранения колонку column.
+// Пакетные сохранения срезов пропускаются, как и в BeforeSave задачи
+func savesColumn(tx *gorm.DB, column string) bool {
+	destValue := reflect.ValueOf(tx.Statement.Dest)
+	if destValue.Kind() == reflect.Ptr && destValue.Elem().Kind() == reflect.Slice {
+		return false
+	}
+	if m, ok := tx.Statement.Dest.(map[string]interface{}); ok {
+		_, ok := m[column]
+		return ok
+	}
+	if len(tx.Statement.Selects) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(tx.Statement.Selects, func(s string) bool {
+		if s == "*" || s == column {
+			return true
+		}
+		if tx.Statement.Schema != nil {
+			if f := tx.Statement.Schema.LookUpField(s); f != nil {
+				return f.DBName == column
+			}
+		}
+		return false
+	})
+}
+
+// deleteReferences удаляет ссылки, источником или целью которых является сущность
+func deleteReferences(tx *gorm.DB, query string, args ...any) error {
+	return tx.Session(&gorm.Session{NewDB: true}).Where(query, args...).Delete(&EntityReference{}).Error
+}
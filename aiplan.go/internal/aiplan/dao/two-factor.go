@@ -0,0 +1,20 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// Коды восстановления двухфакторной аутентификации. Хранится только хеш кода, каждый код действует один раз
+type UserRecoveryCode struct {
+	ID        uuid.UUID  `gorm:"column:id;primaryKey;type:uuid" json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UserId    uuid.UUID  `json:"user_id" gorm:"type:uuid;index"`
+	CodeHash  string     `json:"-"`
+	UsedAt    *time.Time `json:"used_at" extensions:"x-nullable"`
+
+	User *User `json:"-" gorm:"foreignKey:UserId" extensions:"x-nullable"`
+}
+
+func (UserRecoveryCode) TableName() string { return "user_recovery_codes" }
@@ -0,0 +1,63 @@
+package dao
+
+import (
+	"time"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// Сессия пользователя на устройстве. Создается при входе, ID сессии передается в токенах доступа (claim sid),
+// при обновлении токенов запоминается идентификатор актуального refresh токена
+type UserSession struct {
+	ID         uuid.UUID `gorm:"column:id;primaryKey;type:uuid" json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UserId     uuid.UUID `json:"user_id" gorm:"type:uuid;index"`
+	UserAgent  string    `json:"user_agent"`
+	Ip         string    `json:"ip"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// jti актуального refresh токена сессии
+	RefreshJti string     `json:"-"`
+	RevokedAt  *time.Time `json:"revoked_at" gorm:"index" extensions:"x-nullable"`
+
+	User *User `json:"-" gorm:"foreignKey:UserId" extensions:"x-nullable"`
+}
+
+func (UserSession) TableName() string { return "user_sessions" }
+
+// Active проверяет, что сессия не отозвана и не истекла
+func (s *UserSession) Active(now time.Time) bool {
+	return s.RevokedAt == nil && s.ExpiresAt.After(now)
+}
+
+// ActiveUserSessions возвращает запрос активных сессий пользователя
+func ActiveUserSessions(db *gorm.DB, userId uuid.UUID) *gorm.DB {
+	return db.Where("user_id = ?", userId).
+		Where("revoked_at IS NULL").
+		Where("expires_at > ?", time.Now())
+}
+
+// RevokeUserSessions отзывает все активные сессии пользователя
+func RevokeUserSessions(db *gorm.DB, userId uuid.UUID) error {
+	return db.Model(&UserSession{}).
+		Where("user_id = ?", userId).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now()).Error
+}
+
+func (s *UserSession) ToDTO(currentId uuid.UUID) *dto.UserSession {
+	if s == nil {
+		return nil
+	}
+	return &dto.UserSession{
+		ID:         s.ID,
+		CreatedAt:  s.CreatedAt,
+		UserAgent:  s.UserAgent,
+		Ip:         s.Ip,
+		LastSeenAt: s.LastSeenAt,
+		ExpiresAt:  s.ExpiresAt,
+		Current:    s.ID == currentId,
+	}
+}
@@ -73,6 +73,13 @@ type User struct {
 	TokenUpdatedAt  *time.Time `json:"-" extensions:"x-nullable"`
 
 	AuthProvider string `json:"-" gorm:"default:'local'"`
+	// Идентификатор пользователя у OpenID Connect провайдера (claim sub), задается при первом входе через SSO
+	OIDCSubject *string `json:"-" gorm:"column:oidc_subject;uniqueIndex" extensions:"x-nullable"`
+
+	// Двухфакторная аутентификация по TOTP. Секрет сохраняется при подключении, вход с кодом требуется после подтверждения
+	TOTPSecret   string `json:"-" gorm:"column:totp_secret"`
+	TOTPEnabled  bool   `json:"totp_enabled" gorm:"column:totp_enabled"`
+	TOTPLastStep int64  `json:"-" gorm:"column:totp_last_step"`
 
 	LastWorkspaceId uuid.NullUUID `json:"-" gorm:"type:uuid;index" extensions:"x-nullable"`
 
@@ -168,6 +175,7 @@ func (u *User) ToDTO() *dto.User {
 		Settings:          u.Settings,
 		Tutorial:          u.Tutorial,
 		LastWorkspaceId:   u.LastWorkspaceId,
+		TOTPEnabled:       u.TOTPEnabled,
 		NotificationCount: 0,
 		AttachmentsAllow:  nil,
 	}
@@ -52,6 +52,8 @@ type Workspace struct {
 	// Note: type:text используется потому что в существующей БД это поле имеет тип text, а не uuid
 	UpdatedById      uuid.NullUUID `json:"updated_by_id" gorm:"type:uuid" extensions:"x-nullable"`
 	IntegrationToken string        `json:"-"`
+	// Участники пространства должны входить с двухфакторной аутентификацией
+	TwoFactorRequired bool `json:"two_factor_required"`
 
 	Hash []byte `json:"-" gorm:"->;-:migration"`
 
@@ -117,6 +119,7 @@ func (w *Workspace) ToDTO() *dto.Workspace {
 		UpdatedAt:             w.UpdatedAt,
 		Owner:                 w.Owner.ToLightDTO(),
 		Description:           w.Description,
+		TwoFactorRequired:     w.TwoFactorRequired,
 		CurrentUserMembership: w.CurrentUserMembership.ToDTO(),
 		IsFavorite:            false,
 	}
@@ -175,6 +178,10 @@ func (workspace *Workspace) BeforeDelete(tx *gorm.DB) error {
 		return err
 	}
 
+	if err := deleteReferences(tx, "workspace_id = ?", workspace.ID); err != nil {
+		return err
+	}
+
 	//delete asset
 	if workspace.LogoId.Valid {
 		if err := tx.Exec("UPDATE workspaces SET logo_id = NULL WHERE id = ?", workspace.ID).Error; err != nil {
@@ -253,6 +260,11 @@ func (workspace *Workspace) BeforeDelete(tx *gorm.DB) error {
 		return err
 	}
 
+	// delete doc templates
+	if err := tx.Where("workspace_id = ?", workspace.ID).Delete(&DocTemplate{}).Error; err != nil {
+		return err
+	}
+
 	// delete DeferredNotifications
 	if err := tx.Unscoped().Where("workspace_id = ?", workspace.ID).Delete(&DeferredNotifications{}).Error; err != nil {
 		return err
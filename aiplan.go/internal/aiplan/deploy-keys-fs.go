@@ -0,0 +1,263 @@
+// Пакет aiplan предоставляет функциональность для работы с deploy ключами
+// репозиториев через файловую систему без использования базы данных.
+//
+// Deploy ключ - это SSH ключ, привязанный к одному репозиторию, а не к пользователю.
+// Он используется CI и другими автоматизированными системами: по умолчанию дает
+// доступ только на чтение, опционально - на запись. Ключи хранятся рядом с
+// репозиторием в файле {repoPath}/aiplan-deploy-keys.json и удаляются вместе с ним.
+//
+// Fingerprint ключа уникален среди всех пользовательских и deploy ключей,
+// иначе SSH сервер не сможет однозначно определить, кто подключается.
+package aiplan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// DeployKeyMetadata представляет метаданные deploy ключа репозитория
+type DeployKeyMetadata struct {
+	SSHKeyMetadata
+
+	// ReadOnly - ключ дает доступ только на чтение (clone, fetch)
+	ReadOnly bool `json:"read_only"`
+
+	// CreatedBy - UUID администратора, добавившего ключ
+	CreatedBy string `json:"created_by"`
+}
+
+// RepositoryDeployKeys представляет все deploy ключи репозитория
+type RepositoryDeployKeys struct {
+	// Keys - массив deploy ключей репозитория
+	Keys []DeployKeyMetadata `json:"keys"`
+
+	// CreatedAt - время создания файла ключей
+	CreatedAt time.Time `json:"created_at"`
+
+	// UpdatedAt - время последнего обновления файла
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GetDeployKeysFilePath возвращает путь к файлу deploy ключей репозитория
+// Формат: {repoPath}/aiplan-deploy-keys.json
+func GetDeployKeysFilePath(repoPath string) string {
+	return filepath.Join(repoPath, "aiplan-deploy-keys.json")
+}
+
+// LoadDeployKeys загружает deploy ключи репозитория из файла
+// Если файл не существует, возвращается ошибка, которую можно проверить через os.IsNotExist
+func LoadDeployKeys(repoPath string) (*RepositoryDeployKeys, error) {
+	data, err := os.ReadFile(GetDeployKeysFilePath(repoPath))
+	if err != nil {
+		// Возвращаем оригинальную ошибку для корректной работы os.IsNotExist
+		return nil, err
+	}
+
+	var keys RepositoryDeployKeys
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal deploy keys: %w", err)
+	}
+
+	return &keys, nil
+}
+
+// saveDeployKeysUnsafe сохраняет deploy ключи репозитория в файл без блокировки
+// Использует атомарную запись (temp file + rename) для безопасности
+// ВАЖНО: Вызывающий код должен держать sshKeysMutex
+func saveDeployKeysUnsafe(keys *RepositoryDeployKeys, repoPath string) error {
+	keys.UpdatedAt = time.Now()
+
+	data, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal deploy keys: %w", err)
+	}
+
+	filePath := GetDeployKeysFilePath(repoPath)
+	tempPath := filePath + ".tmp"
+
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write temp deploy keys file: %w", err)
+	}
+
+	if err := os.Rename(tempPath, filePath); err != nil {
+		os.Remove(tempPath) // Cleanup temp file
+		return fmt.Errorf("failed to rename temp deploy keys file: %w", err)
+	}
+
+	return nil
+}
+
+// AddDeployKey добавляет deploy ключ репозиторию
+// Выполняет парсинг, валидацию и проверку на дубликаты среди всех SSH ключей
+func AddDeployKey(workspaceSlug, repoName, name, publicKey string, readOnly bool, createdBy, gitReposPath string) (*DeployKeyMetadata, error) {
+	// Используем общий mutex с пользовательскими ключами, чтобы проверка уникальности fingerprint была атомарной
+	sshKeysMutex.Lock()
+	defer sshKeysMutex.Unlock()
+
+	if !ValidateSSHKeyName(name) {
+		return nil, fmt.Errorf("invalid SSH key name: must be 1-255 characters")
+	}
+
+	keyType, fingerprint, comment, err := ParseSSHPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ключ не должен совпадать ни с пользовательским ключом, ни с deploy ключом другого репозитория
+	if existingKey, existingUserId, err := findSSHKeyByFingerprintUnsafe(fingerprint, gitReposPath); err == nil && existingKey != nil {
+		return nil, fmt.Errorf("SSH key with fingerprint %s already exists for user %s", fingerprint, existingUserId)
+	}
+	if existingKey, ws, repo, err := findDeployKeyByFingerprintUnsafe(fingerprint, gitReposPath); err == nil && existingKey != nil {
+		return nil, fmt.Errorf("SSH key with fingerprint %s already exists as deploy key of %s/%s", fingerprint, ws, repo)
+	}
+
+	repoPath := GetRepositoryPath(workspaceSlug, repoName, gitReposPath)
+	repoKeys, err := LoadDeployKeys(repoPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to load deploy keys: %w", err)
+		}
+		repoKeys = &RepositoryDeployKeys{
+			Keys:      []DeployKeyMetadata{},
+			CreatedAt: time.Now(),
+		}
+	}
+
+	keyId, err := uuid.NewV4()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key ID: %w", err)
+	}
+
+	newKey := DeployKeyMetadata{
+		SSHKeyMetadata: SSHKeyMetadata{
+			ID:          keyId.String(),
+			Name:        name,
+			PublicKey:   strings.TrimSpace(publicKey),
+			Fingerprint: fingerprint,
+			KeyType:     keyType,
+			CreatedAt:   time.Now(),
+			Comment:     comment,
+		},
+		ReadOnly:  readOnly,
+		CreatedBy: createdBy,
+	}
+
+	repoKeys.Keys = append(repoKeys.Keys, newKey)
+
+	if err := saveDeployKeysUnsafe(repoKeys, repoPath); err != nil {
+		return nil, fmt.Errorf("failed to save deploy keys: %w", err)
+	}
+
+	return &newKey, nil
+}
+
+// DeleteDeployKey удаляет deploy ключ репозитория по ID
+func DeleteDeployKey(workspaceSlug, repoName, keyId, gitReposPath string) error {
+	sshKeysMutex.Lock()
+	defer sshKeysMutex.Unlock()
+
+	repoPath := GetRepositoryPath(workspaceSlug, repoName, gitReposPath)
+	repoKeys, err := LoadDeployKeys(repoPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("deploy key not found")
+		}
+		return fmt.Errorf("failed to load deploy keys: %w", err)
+	}
+
+	keyFound := false
+	newKeys := []DeployKeyMetadata{}
+	for _, key := range repoKeys.Keys {
+		if key.ID == keyId {
+			keyFound = true
+			continue
+		}
+		newKeys = append(newKeys, key)
+	}
+
+	if !keyFound {
+		return fmt.Errorf("deploy key not found")
+	}
+
+	repoKeys.Keys = newKeys
+	if err := saveDeployKeysUnsafe(repoKeys, repoPath); err != nil {
+		return fmt.Errorf("failed to save deploy keys: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateDeployKeyLastUsed обновляет время последнего использования deploy ключа
+func UpdateDeployKeyLastUsed(workspaceSlug, repoName, keyId, gitReposPath string) error {
+	sshKeysMutex.Lock()
+	defer sshKeysMutex.Unlock()
+
+	repoPath := GetRepositoryPath(workspaceSlug, repoName, gitReposPath)
+	repoKeys, err := LoadDeployKeys(repoPath)
+	if err != nil {
+		return fmt.Errorf("failed to load deploy keys: %w", err)
+	}
+
+	keyFound := false
+	now := time.Now()
+	for i := range repoKeys.Keys {
+		if repoKeys.Keys[i].ID == keyId {
+			repoKeys.Keys[i].LastUsedAt = &now
+			keyFound = true
+			break
+		}
+	}
+
+	if !keyFound {
+		return fmt.Errorf("deploy key not found")
+	}
+
+	if err := saveDeployKeysUnsafe(repoKeys, repoPath); err != nil {
+		return fmt.Errorf("failed to save deploy keys: %w", err)
+	}
+
+	return nil
+}
+
+// FindDeployKeyByFingerprint ищет deploy ключ по fingerprint среди всех репозиториев
+// Возвращает: (key, workspaceSlug, repoName, error)
+func FindDeployKeyByFingerprint(fingerprint, gitReposPath string) (*DeployKeyMetadata, string, string, error) {
+	sshKeysMutex.Lock()
+	defer sshKeysMutex.Unlock()
+
+	return findDeployKeyByFingerprintUnsafe(fingerprint, gitReposPath)
+}
+
+// findDeployKeyByFingerprintUnsafe - внутренняя версия без mutex (для использования внутри других функций)
+func findDeployKeyByFingerprintUnsafe(fingerprint, gitReposPath string) (*DeployKeyMetadata, string, string, error) {
+	// Файлы ключей лежат внутри репозиториев: {gitReposPath}/{workspace}/{repo}.git/aiplan-deploy-keys.json
+	files, err := filepath.Glob(filepath.Join(gitReposPath, "*", "*.git", "aiplan-deploy-keys.json"))
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to list deploy keys: %w", err)
+	}
+
+	for _, file := range files {
+		repoPath := filepath.Dir(file)
+		repoKeys, err := LoadDeployKeys(repoPath)
+		if err != nil {
+			// Пропускаем поврежденные файлы
+			continue
+		}
+
+		for i := range repoKeys.Keys {
+			if repoKeys.Keys[i].Fingerprint == fingerprint {
+				workspaceSlug := filepath.Base(filepath.Dir(repoPath))
+				repoName := strings.TrimSuffix(filepath.Base(repoPath), ".git")
+				return &repoKeys.Keys[i], workspaceSlug, repoName, nil
+			}
+		}
+	}
+
+	return nil, "", "", fmt.Errorf("deploy key not found")
+}
@@ -0,0 +1,68 @@
+package aiplan
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// TestDeployKeys проверяет добавление, поиск, обновление и удаление deploy ключей
+func TestDeployKeys(t *testing.T) {
+	tempDir := t.TempDir()
+	publicKey := "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl ci@example.com"
+
+	repoPath := GetRepositoryPath("ws", "repo", tempDir)
+	if err := os.MkdirAll(repoPath, 0755); err != nil {
+		t.Fatalf("Failed to create repo dir: %v", err)
+	}
+
+	key, err := AddDeployKey("ws", "repo", "CI", publicKey, true, "admin-id", tempDir)
+	if err != nil {
+		t.Fatalf("Failed to add deploy key: %v", err)
+	}
+	if !key.ReadOnly || key.KeyType != "ssh-ed25519" || key.CreatedBy != "admin-id" {
+		t.Errorf("Unexpected deploy key: %+v", key)
+	}
+
+	// Тот же ключ нельзя добавить ни пользователю, ни другому репозиторию
+	if _, err := AddSSHKey("user-id", "user@example.com", "Laptop", publicKey, tempDir); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected 'already exists' error for user key, got: %v", err)
+	}
+	if _, err := AddDeployKey("ws", "other", "CI", publicKey, false, "admin-id", tempDir); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("Expected 'already exists' error for deploy key, got: %v", err)
+	}
+
+	found, ws, repo, err := FindDeployKeyByFingerprint(key.Fingerprint, tempDir)
+	if err != nil {
+		t.Fatalf("Failed to find deploy key: %v", err)
+	}
+	if found.ID != key.ID || ws != "ws" || repo != "repo" {
+		t.Errorf("Unexpected lookup result: %s %s/%s", found.ID, ws, repo)
+	}
+
+	if err := UpdateDeployKeyLastUsed("ws", "repo", key.ID, tempDir); err != nil {
+		t.Fatalf("Failed to update last used: %v", err)
+	}
+	repoKeys, err := LoadDeployKeys(repoPath)
+	if err != nil {
+		t.Fatalf("Failed to load deploy keys: %v", err)
+	}
+	if len(repoKeys.Keys) != 1 || repoKeys.Keys[0].LastUsedAt == nil {
+		t.Errorf("Expected last_used_at to be set: %+v", repoKeys.Keys)
+	}
+
+	if err := DeleteDeployKey("ws", "repo", key.ID, tempDir); err != nil {
+		t.Fatalf("Failed to delete deploy key: %v", err)
+	}
+	if _, _, _, err := FindDeployKeyByFingerprint(key.Fingerprint, tempDir); err == nil {
+		t.Errorf("Expected deploy key to be deleted")
+	}
+	if err := DeleteDeployKey("ws", "repo", key.ID, tempDir); err == nil {
+		t.Errorf("Expected error when deleting non-existent deploy key")
+	}
+
+	// После удаления ключ снова можно использовать как пользовательский
+	if _, err := AddSSHKey("user-id", "user@example.com", "Laptop", publicKey, tempDir); err != nil {
+		t.Errorf("Failed to add user key after deploy key removal: %v", err)
+	}
+}
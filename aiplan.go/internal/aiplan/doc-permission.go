@@ -64,6 +64,11 @@ func (s *Services) hasDocPermissions(c echo.Context) (bool, error) {
 	editorSet := utils.SliceToSet(doc.EditorsIDs)
 	watcherSet := utils.SliceToSet(doc.WatcherIDs)
 
+	// Публичные ссылки на документ доступны только редакторам, в том числе на чтение
+	if strings.Contains(c.Path(), "/share-links/") {
+		return hasEditAccess(user.ID, workspaceMember.Role, &doc, editorSet), nil
+	}
+
 	if onlyReadMethod(c) {
 		if hasReadAccess(user.ID, workspaceMember.Role, &doc, readerSet, editorSet, watcherSet) {
 			return true, nil
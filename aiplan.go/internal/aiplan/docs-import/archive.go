@@ -0,0 +1,421 @@
+// Пакет для импорта документов из zip-архива.
+//
+// Поддерживаются архивы с файлами Markdown или HTML, где структура каталогов задает вложенность документов,
+// и HTML-экспорт пространства Confluence, где вложенность берется из навигационной цепочки страниц.
+// Изображения и файлы из архива загружаются в файловое хранилище, ссылки между страницами заменяются ссылками на документы.
+package docs_import
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Format - формат страниц архива
+type Format string
+
+const (
+	FormatAuto       Format = "auto"
+	FormatMarkdown   Format = "markdown"
+	FormatHTML       Format = "html"
+	FormatConfluence Format = "confluence"
+)
+
+// maxTitleLength - максимальная длина названия документа
+const maxTitleLength = 150
+
+// maxPageSize - максимальный размер файла страницы, страницы большего размера пропускаются
+const maxPageSize = 10 << 20
+
+var (
+	ErrInvalidArchive = errors.New("invalid archive")
+	ErrNoPages        = errors.New("no pages in archive")
+	ErrInvalidFormat  = errors.New("invalid format")
+)
+
+// ParseFormat разбирает формат из запроса, пустое значение означает автоопределение
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case "":
+		return FormatAuto, nil
+	case FormatAuto, FormatMarkdown, FormatHTML, FormatConfluence:
+		return f, nil
+	}
+	return "", ErrInvalidFormat
+}
+
+// Page - страница архива, из которой создается документ
+type Page struct {
+	// Путь файла страницы в архиве. Пустой для каталога без собственной страницы
+	Path     string
+	Title    string
+	Markdown bool
+	Body     string
+	Children []*Page
+
+	key string
+}
+
+// Archive - прочитанный архив: дерево страниц и файлы для вложений
+type Archive struct {
+	Format Format
+	Pages  []*Page
+
+	files  map[string]*zip.File
+	byPath map[string]*Page
+}
+
+// ReadArchive читает страницы архива и строит их дерево
+func ReadArchive(zr *zip.Reader, format Format) (*Archive, error) {
+	a := &Archive{
+		files:  make(map[string]*zip.File),
+		byPath: make(map[string]*Page),
+	}
+
+	var names []string
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		name := path.Clean(strings.TrimPrefix(strings.ReplaceAll(f.Name, "\\", "/"), "/"))
+		if name == "." || strings.HasPrefix(name, "../") || skipFile(name) {
+			continue
+		}
+		a.files[name] = f
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, ErrNoPages
+	}
+
+	// Архив с одним каталогом в корне - каталог не становится документом
+	if root := commonRoot(names); root != "" {
+		files := make(map[string]*zip.File, len(a.files))
+		for i, name := range names {
+			names[i] = strings.TrimPrefix(name, root+"/")
+			files[names[i]] = a.files[name]
+		}
+		a.files = files
+	}
+	slices.Sort(names)
+
+	if format == FormatAuto {
+		format = detectFormat(a, names)
+	}
+	a.Format = format
+
+	var err error
+	if format == FormatConfluence {
+		err = a.readConfluence(names)
+	} else {
+		err = a.readTree(names, format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(a.Pages) == 0 {
+		return nil, ErrNoPages
+	}
+	sortPages(a.Pages)
+	return a, nil
+}
+
+// Walk обходит страницы так, что родитель всегда обрабатывается раньше дочерних
+func (a *Archive) Walk(fn func(page *Page, parent *Page, index int)) {
+	var walk func(pages []*Page, parent *Page)
+	walk = func(pages []*Page, parent *Page) {
+		for i, p := range pages {
+			fn(p, parent, i)
+			walk(p.Children, p)
+		}
+	}
+	walk(a.Pages, nil)
+}
+
+// Count возвращает количество страниц в архиве
+func (a *Archive) Count() int {
+	n := 0
+	a.Walk(func(*Page, *Page, int) { n++ })
+	return n
+}
+
+// PageByPath возвращает страницу по пути в архиве. Для ссылок без расширения проверяются файлы Markdown и HTML
+func (a *Archive) PageByPath(p string) *Page {
+	p = strings.TrimSuffix(p, "/")
+	for _, candidate := range []string{p, p + ".md", p + ".html"} {
+		if page, ok := a.byPath[candidate]; ok {
+			return page
+		}
+	}
+	return nil
+}
+
+// File возвращает файл архива по пути
+func (a *Archive) File(p string) *zip.File {
+	return a.files[p]
+}
+
+// readTree строит дерево страниц по каталогам архива. Файл с именем каталога (docs.md для docs/)
+// или index/README внутри каталога становятся содержимым документа каталога
+func (a *Archive) readTree(names []string, format Format) error {
+	nodes := make(map[string]*Page)
+
+	var ensureDir func(dir string) *Page
+	ensureDir = func(dir string) *Page {
+		if dir == "." || dir == "" {
+			return nil
+		}
+		if node, ok := nodes[dir]; ok {
+			return node
+		}
+		node := &Page{Title: cutTitle(path.Base(dir)), key: dir}
+		nodes[dir] = node
+		a.byPath[dir] = node
+		a.attach(ensureDir(path.Dir(dir)), node)
+		return node
+	}
+
+	for _, name := range names {
+		markdown := isMarkdown(name)
+		if !markdown && !isHTML(name) {
+			continue
+		}
+		if format == FormatMarkdown && !markdown || format == FormatHTML && markdown {
+			continue
+		}
+
+		body, err := readFile(a.files[name])
+		if err != nil {
+			continue
+		}
+
+		key := strings.TrimSuffix(name, path.Ext(name))
+		dir := path.Dir(name)
+
+		var node *Page
+		if isIndexName(path.Base(key)) && dir != "." {
+			if n := ensureDir(dir); n.Path == "" {
+				node = n
+			}
+		} else if n, ok := nodes[key]; ok && n.Path == "" {
+			node = n
+		}
+		if node == nil {
+			node = &Page{key: key}
+			nodes[key] = node
+			a.attach(ensureDir(dir), node)
+		}
+
+		node.Path = name
+		node.Markdown = markdown
+		node.Title, node.Body = pageTitle(path.Base(key), body, markdown)
+		a.byPath[name] = node
+	}
+	return nil
+}
+
+// readConfluence строит дерево страниц HTML-экспорта Confluence. Родитель страницы - последняя ссылка в навигационной цепочке
+func (a *Archive) readConfluence(names []string) error {
+	parents := make(map[*Page]string)
+
+	for _, name := range names {
+		if !isHTML(name) || path.Base(name) == "index.html" {
+			continue
+		}
+		body, err := readFile(a.files[name])
+		if err != nil {
+			continue
+		}
+		root, err := html.Parse(strings.NewReader(body))
+		if err != nil {
+			continue
+		}
+
+		content := findNode(root, func(n *html.Node) bool { return attr(n, "id") == "main-content" })
+		if content == nil {
+			continue
+		}
+
+		title := ""
+		if t := findNode(root, func(n *html.Node) bool { return attr(n, "id") == "title-text" }); t != nil {
+			title = textContent(t)
+			// Заголовок страницы в экспорте имеет вид "Пространство : Страница"
+			if _, after, ok := strings.Cut(title, " : "); ok {
+				title = after
+			}
+		}
+		if strings.TrimSpace(title) == "" {
+			title = strings.TrimSuffix(path.Base(name), path.Ext(name))
+		}
+
+		page := &Page{
+			Path:  name,
+			Title: cutTitle(title),
+			Body:  innerHTML(content),
+			key:   name,
+		}
+		a.byPath[name] = page
+
+		if crumbs := findNode(root, func(n *html.Node) bool { return attr(n, "id") == "breadcrumbs" }); crumbs != nil {
+			var last string
+			walkNodes(crumbs, func(n *html.Node) {
+				if n.Type == html.ElementNode && n.Data == "a" {
+					if href := attr(n, "href"); href != "" && href != "index.html" {
+						last = path.Join(path.Dir(name), href)
+					}
+				}
+			})
+			parents[page] = last
+		}
+	}
+
+	for _, name := range names {
+		page, ok := a.byPath[name]
+		if !ok {
+			continue
+		}
+		parent := a.byPath[parents[page]]
+		// Страница без родителя или с циклической цепочкой попадает в корень
+		for p := parent; p != nil; p = a.byPath[parents[p]] {
+			if p == page {
+				parent = nil
+				break
+			}
+		}
+		a.attach(parent, page)
+	}
+	return nil
+}
+
+func (a *Archive) attach(parent *Page, page *Page) {
+	if parent == nil {
+		a.Pages = append(a.Pages, page)
+	} else {
+		parent.Children = append(parent.Children, page)
+	}
+}
+
+func detectFormat(a *Archive, names []string) Format {
+	markdown, htmlPages := 0, 0
+	for _, name := range names {
+		switch {
+		case isMarkdown(name):
+			markdown++
+		case isHTML(name):
+			htmlPages++
+		}
+	}
+	if htmlPages > 0 && a.files["index.html"] != nil {
+		for _, name := range names {
+			if !isHTML(name) || name == "index.html" {
+				continue
+			}
+			if body, err := readFile(a.files[name]); err == nil && strings.Contains(body, `id="main-content"`) {
+				return FormatConfluence
+			}
+			break
+		}
+	}
+	if htmlPages > markdown {
+		return FormatHTML
+	}
+	return FormatMarkdown
+}
+
+// pageTitle возвращает название страницы и содержимое без заголовка, ставшего названием
+func pageTitle(fileName string, body string, markdown bool) (string, string) {
+	title := fileName
+	if markdown {
+		trimmed := strings.TrimLeft(body, "\ufeff \t\r\n")
+		if rest, ok := strings.CutPrefix(trimmed, "# "); ok {
+			line, after, _ := strings.Cut(rest, "\n")
+			if line = strings.TrimSpace(line); line != "" {
+				title, body = line, after
+			}
+		}
+	} else if root, err := html.Parse(strings.NewReader(body)); err == nil {
+		if t := findNode(root, func(n *html.Node) bool { return n.Type == html.ElementNode && n.Data == "title" }); t != nil {
+			if text := strings.TrimSpace(textContent(t)); text != "" {
+				title = text
+			}
+		}
+	}
+	return cutTitle(title), body
+}
+
+func cutTitle(title string) string {
+	title = strings.Join(strings.Fields(title), " ")
+	if r := []rune(title); len(r) > maxTitleLength {
+		title = string(r[:maxTitleLength])
+	}
+	return title
+}
+
+func sortPages(pages []*Page) {
+	slices.SortStableFunc(pages, func(a, b *Page) int { return strings.Compare(a.key, b.key) })
+	for _, p := range pages {
+		sortPages(p.Children)
+	}
+}
+
+func commonRoot(names []string) string {
+	root, _, ok := strings.Cut(names[0], "/")
+	if !ok {
+		return ""
+	}
+	for _, name := range names[1:] {
+		if !strings.HasPrefix(name, root+"/") {
+			return ""
+		}
+	}
+	return root
+}
+
+func skipFile(name string) bool {
+	if strings.HasPrefix(name, "__MACOSX/") {
+		return true
+	}
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+func isMarkdown(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".md" || ext == ".markdown"
+}
+
+func isHTML(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".html" || ext == ".htm"
+}
+
+func isIndexName(base string) bool {
+	base = strings.ToLower(base)
+	return base == "index" || base == "readme"
+}
+
+func readFile(f *zip.File) (string, error) {
+	if f.UncompressedSize64 > maxPageSize {
+		return "", errors.New("page is too large")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxPageSize))
+	if err != nil {
+		return "", err
+	}
+	return string(bytes.TrimPrefix(data, []byte("\ufeff"))), nil
+}
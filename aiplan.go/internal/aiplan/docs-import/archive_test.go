@@ -0,0 +1,149 @@
+package docs_import
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func makeZip(t *testing.T, files map[string]string) *zip.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zr
+}
+
+func titles(pages []*Page) []string {
+	var res []string
+	for _, p := range pages {
+		res = append(res, p.Title)
+	}
+	return res
+}
+
+func TestReadMarkdownTree(t *testing.T) {
+	zr := makeZip(t, map[string]string{
+		"wiki/intro.md":          "# Введение\n\nТекст ![схема](img/scheme.png)",
+		"wiki/guides.md":         "Раздел руководств",
+		"wiki/guides/setup.md":   "Установка, см. [введение](../intro.md)",
+		"wiki/api/README.md":     "# API\n\nОписание",
+		"wiki/api/methods.md":    "Методы",
+		"wiki/img/scheme.png":    "png",
+		"wiki/.hidden/skip.md":   "skip",
+		"__MACOSX/wiki/intro.md": "skip",
+	})
+
+	a, err := ReadArchive(zr, FormatAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Format != FormatMarkdown {
+		t.Errorf("Format = %s, want markdown", a.Format)
+	}
+	if got := strings.Join(titles(a.Pages), ","); got != "API,guides,Введение" {
+		t.Fatalf("Root pages = %s", got)
+	}
+	if a.Count() != 5 {
+		t.Errorf("Count = %d, want 5", a.Count())
+	}
+
+	api := a.Pages[0]
+	if api.Path != "api/README.md" || strings.Contains(api.Body, "# API") {
+		t.Errorf("Index page not merged into dir: %+v", api)
+	}
+	guides := a.Pages[1]
+	if guides.Body != "Раздел руководств" || len(guides.Children) != 1 || guides.Children[0].Title != "setup" {
+		t.Errorf("Sibling file not merged into dir: %+v", guides)
+	}
+
+	setup := guides.Children[0]
+	got := a.ConvertContent(setup, `<p>Установка, см. <a href="../intro.md#start">введение</a></p>`, LinkResolver{
+		Page: func(p *Page) (string, bool) { return "/ws/aidoc/" + p.Title, true },
+	})
+	if !strings.Contains(got, `href="/ws/aidoc/Введение#start"`) {
+		t.Errorf("Page link not rewritten: %s", got)
+	}
+
+	intro := a.Pages[2]
+	got = a.ConvertContent(intro, `<p>Текст <img src="img/scheme.png" class="x"><img src="https://example.com/a.png"></p><script>alert(1)</script>`, LinkResolver{
+		File: func(p string, inline bool) (string, bool) {
+			if !inline {
+				t.Errorf("Image must be inline")
+			}
+			return "/api/auth/file/" + p, true
+		},
+	})
+	if !strings.Contains(got, `src="/api/auth/file/img/scheme.png"`) || !strings.Contains(got, `src="https://example.com/a.png"`) || strings.Contains(got, "script") || strings.Contains(got, "class") {
+		t.Errorf("Unexpected content: %s", got)
+	}
+}
+
+func TestReadConfluence(t *testing.T) {
+	page := func(title string, crumbs string, content string) string {
+		return `<html><head><title>SPACE : ` + title + `</title></head><body>` +
+			`<div id="breadcrumb-section"><ol id="breadcrumbs"><li><a href="index.html">Space</a></li>` + crumbs + `</ol></div>` +
+			`<h1 id="title-heading"><span id="title-text"> SPACE : ` + title + ` </span></h1>` +
+			`<div id="main-content" class="wiki-content">` + content + `</div>` +
+			`<div class="pageSection">Attachments</div></body></html>`
+	}
+
+	zr := makeZip(t, map[string]string{
+		"SPACE/index.html":              `<html><body>Space index</body></html>`,
+		"SPACE/Home_1.html":             page("Home", "", `<p>Домашняя</p>`),
+		"SPACE/Child_2.html":            page("Child", `<li><a href="Home_1.html">Home</a></li>`, `<pre class="syntaxhighlighter-pre" data-syntaxhighlighter-params="brush: go; gutter: false">fmt.Println()</pre>`),
+		"SPACE/attachments/2/3.png":     "png",
+		"SPACE/styles/site.css":         "body{}",
+		"SPACE/Orphan_4.html":           page("Orphan", `<li><a href="Missing_9.html">Missing</a></li>`, `<p>Сирота</p>`),
+		"SPACE/images/icons/bullet.gif": "gif",
+	})
+
+	a, err := ReadArchive(zr, FormatAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Format != FormatConfluence {
+		t.Fatalf("Format = %s, want confluence", a.Format)
+	}
+	if got := strings.Join(titles(a.Pages), ","); got != "Home,Orphan" {
+		t.Fatalf("Root pages = %s", got)
+	}
+	home := a.Pages[0]
+	if len(home.Children) != 1 || home.Children[0].Title != "Child" {
+		t.Fatalf("Child not attached to Home: %+v", home.Children)
+	}
+	if strings.Contains(home.Body, "Attachments") || !strings.Contains(home.Body, "Домашняя") {
+		t.Errorf("Unexpected Home body: %s", home.Body)
+	}
+
+	child := home.Children[0]
+	got := a.ConvertContent(child, child.Body, LinkResolver{})
+	if got != `<pre><code class="language-go">fmt.Println()</code></pre>` {
+		t.Errorf("Code block not converted: %s", got)
+	}
+}
+
+func TestReadArchiveErrors(t *testing.T) {
+	if _, err := ReadArchive(makeZip(t, map[string]string{"a.png": "png"}), FormatAuto); err != ErrNoPages {
+		t.Errorf("Expected ErrNoPages, got %v", err)
+	}
+	if _, err := ParseFormat("docx"); err != ErrInvalidFormat {
+		t.Errorf("Expected ErrInvalidFormat, got %v", err)
+	}
+}
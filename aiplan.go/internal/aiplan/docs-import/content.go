@@ -0,0 +1,183 @@
+package docs_import
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// LinkResolver заменяет ссылки страницы на страницы и файлы архива.
+// Page возвращает адрес документа страницы, File - адрес загруженного файла (inline - изображение в тексте).
+// false означает, что ссылку нужно оставить как есть
+type LinkResolver struct {
+	Page func(page *Page) (string, bool)
+	File func(p string, inline bool) (string, bool)
+}
+
+// ConvertContent приводит HTML страницы к формату редактора: удаляет служебные элементы, преобразует блоки кода
+// экспорта Confluence и заменяет ссылки на страницы и файлы архива через resolver
+func (a *Archive) ConvertContent(page *Page, body string, resolver LinkResolver) string {
+	root, err := html.Parse(strings.NewReader(body))
+	if err != nil {
+		return body
+	}
+	content := findNode(root, func(n *html.Node) bool { return n.Type == html.ElementNode && n.DataAtom == atom.Body })
+	if content == nil {
+		return body
+	}
+
+	var remove []*html.Node
+	walkNodes(content, func(n *html.Node) {
+		if n.Type != html.ElementNode {
+			return
+		}
+		switch n.DataAtom {
+		case atom.Script, atom.Style, atom.Link, atom.Meta, atom.Noscript:
+			remove = append(remove, n)
+		case atom.Pre:
+			convertCodeBlock(n)
+		case atom.Img:
+			src := attr(n, "src")
+			if p, ok := resolvePath(page.Path, src); ok && a.File(p) != nil && resolver.File != nil {
+				if link, ok := resolver.File(p, true); ok {
+					src = link
+				}
+			}
+			n.Attr = filterAttrs(n.Attr, "alt", "title", "width", "height")
+			n.Attr = append(n.Attr, html.Attribute{Key: "src", Val: src})
+		case atom.A:
+			href := attr(n, "href")
+			u, _ := url.Parse(href)
+			if p, ok := resolvePath(page.Path, href); ok {
+				if target := a.PageByPath(p); target != nil && resolver.Page != nil {
+					if link, ok := resolver.Page(target); ok {
+						href = link
+						if u != nil && u.Fragment != "" {
+							href += "#" + u.Fragment
+						}
+					}
+				} else if a.File(p) != nil && resolver.File != nil {
+					if link, ok := resolver.File(p, false); ok {
+						href = link
+					}
+				}
+			}
+			n.Attr = filterAttrs(n.Attr, "title")
+			n.Attr = append(n.Attr, html.Attribute{Key: "href", Val: href})
+		}
+	})
+	for _, n := range remove {
+		n.Parent.RemoveChild(n)
+	}
+
+	return strings.TrimSpace(innerHTML(content))
+}
+
+// convertCodeBlock переносит язык подсветки блока кода Confluence (data-syntaxhighlighter-params="brush: java")
+// в формат редактора <pre><code class="language-java">
+func convertCodeBlock(pre *html.Node) {
+	if findNode(pre, func(n *html.Node) bool { return n != pre && n.DataAtom == atom.Code }) != nil {
+		return
+	}
+
+	var lang string
+	for _, param := range strings.Split(attr(pre, "data-syntaxhighlighter-params"), ";") {
+		if key, val, ok := strings.Cut(param, ":"); ok && strings.TrimSpace(key) == "brush" {
+			lang = strings.TrimSpace(val)
+		}
+	}
+
+	code := &html.Node{Type: html.ElementNode, Data: "code", DataAtom: atom.Code}
+	if lang != "" {
+		code.Attr = []html.Attribute{{Key: "class", Val: "language-" + lang}}
+	}
+	code.AppendChild(&html.Node{Type: html.TextNode, Data: textContent(pre)})
+
+	for c := pre.FirstChild; c != nil; c = pre.FirstChild {
+		pre.RemoveChild(c)
+	}
+	pre.Attr = nil
+	pre.AppendChild(code)
+}
+
+// resolvePath возвращает путь в архиве для относительной ссылки страницы base.
+// Внешние ссылки, якоря и выход за пределы архива не разрешаются
+func resolvePath(base string, ref string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+		return "", false
+	}
+
+	p := u.Path
+	if strings.HasPrefix(p, "/") {
+		p = strings.TrimPrefix(p, "/")
+	} else {
+		p = path.Join(path.Dir(base), p)
+	}
+	p = path.Clean(p)
+	if p == "." || p == ".." || strings.HasPrefix(p, "../") {
+		return "", false
+	}
+	return p, true
+}
+
+func filterAttrs(attrs []html.Attribute, keep ...string) []html.Attribute {
+	res := make([]html.Attribute, 0, len(attrs))
+	for _, a := range attrs {
+		for _, k := range keep {
+			if a.Key == k {
+				res = append(res, a)
+			}
+		}
+	}
+	return res
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func walkNodes(n *html.Node, fn func(n *html.Node)) {
+	fn(n)
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walkNodes(c, fn)
+	}
+}
+
+func findNode(n *html.Node, match func(n *html.Node) bool) *html.Node {
+	if match(n) {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findNode(c, match); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func textContent(n *html.Node) string {
+	var sb strings.Builder
+	walkNodes(n, func(c *html.Node) {
+		if c.Type == html.TextNode {
+			sb.WriteString(c.Data)
+		}
+	})
+	return sb.String()
+}
+
+func innerHTML(n *html.Node) string {
+	var sb strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		_ = html.Render(&sb, c)
+	}
+	return sb.String()
+}
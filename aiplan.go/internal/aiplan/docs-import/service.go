@@ -0,0 +1,567 @@
+package docs_import
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	filestorage "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/file-storage"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/utils"
+	"github.com/aisa-it/aiplan/aiplan.go/pkg/limiter"
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// Этапы импорта
+const (
+	StageParse  = "parse"
+	StageAssets = "assets"
+	StageDocs   = "docs"
+)
+
+var (
+	ErrCanceled       = errors.New("импорт отменен")
+	ErrSaveDB         = errors.New("ошибка при сохранении документов в БД")
+	ErrAssetsLimit    = errors.New("превышен лимит хранилища пространства")
+	ErrAlreadyRunning = errors.New("импорт документов уже выполняется")
+)
+
+// finishedTTL - сколько хранится статус завершенного импорта
+const finishedTTL = time.Hour * 24
+
+// DocImportService запускает импорты документов и хранит их статусы в памяти
+type DocImportService struct {
+	db      *gorm.DB
+	storage filestorage.FileStorage
+
+	mutex   sync.RWMutex
+	imports map[uuid.UUID]*Import
+}
+
+// Import - выполняемый или завершенный импорт документов
+type Import struct {
+	ID          uuid.UUID
+	ActorID     uuid.UUID
+	WorkspaceID uuid.UUID
+	FileName    string
+	Format      Format
+	StartAt     time.Time
+
+	TotalDocs      int
+	ImportedDocs   atomic.Int32
+	TotalAssets    int
+	ImportedAssets atomic.Int32
+
+	mutex        sync.RWMutex
+	stage        string
+	finished     bool
+	endAt        time.Time
+	err          error
+	failedAssets []string
+	rootDocIds   []uuid.UUID
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// ImportStatus - статус импорта документов
+type ImportStatus struct {
+	ID                uuid.UUID   `json:"id"`
+	ActorID           uuid.UUID   `json:"actor_id"`
+	TargetWorkspaceId uuid.UUID   `json:"target_workspace_id"`
+	FileName          string      `json:"file_name"`
+	Format            Format      `json:"format" enums:"markdown,html,confluence"`
+	Stage             string      `json:"stage" enums:"parse,assets,docs"`
+	TotalDocs         int         `json:"total_docs"`
+	DoneDocs          int32       `json:"done_docs"`
+	TotalAssets       int         `json:"total_assets,omitempty"`
+	ImportedAssets    int32       `json:"imported_assets,omitempty"`
+	FailedAssets      []string    `json:"failed_assets,omitempty"`
+	RootDocIds        []uuid.UUID `json:"root_doc_ids,omitempty"`
+	StartAt           time.Time   `json:"start_at"`
+	EndAt             *time.Time  `json:"end_at,omitempty"`
+
+	Progress       int `json:"progress"`
+	GlobalProgress int `json:"global_progress"`
+
+	Finished bool   `json:"finished"`
+	Error    string `json:"error,omitempty"`
+}
+
+// pendingAsset - файл архива, который нужно загрузить в хранилище
+type pendingAsset struct {
+	path   string
+	docId  uuid.UUID
+	asset  dao.FileAsset
+	inline bool
+	failed bool
+}
+
+func NewDocImportService(db *gorm.DB, storage filestorage.FileStorage) *DocImportService {
+	return &DocImportService{
+		db:      db,
+		storage: storage,
+		imports: make(map[uuid.UUID]*Import),
+	}
+}
+
+// GetStatus возвращает текущий статус импорта
+func (i *Import) GetStatus() ImportStatus {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	status := ImportStatus{
+		ID:                i.ID,
+		ActorID:           i.ActorID,
+		TargetWorkspaceId: i.WorkspaceID,
+		FileName:          i.FileName,
+		Format:            i.Format,
+		Stage:             i.stage,
+		TotalDocs:         i.TotalDocs,
+		DoneDocs:          i.ImportedDocs.Load(),
+		TotalAssets:       i.TotalAssets,
+		ImportedAssets:    i.ImportedAssets.Load(),
+		FailedAssets:      slices.Clone(i.failedAssets),
+		RootDocIds:        slices.Clone(i.rootDocIds),
+		StartAt:           i.StartAt,
+		Finished:          i.finished,
+	}
+
+	switch i.stage {
+	case StageParse:
+		status.GlobalProgress = 0
+	case StageAssets:
+		status.Progress = percent(int(status.ImportedAssets), i.TotalAssets)
+		status.GlobalProgress = int(float32(status.Progress)*0.5) + 10
+	case StageDocs:
+		status.Progress = percent(int(status.DoneDocs), i.TotalDocs)
+		status.GlobalProgress = int(float32(status.Progress)*0.4) + 60
+	}
+
+	if i.finished {
+		status.Progress = 100
+		status.GlobalProgress = 100
+		status.EndAt = &i.endAt
+	}
+	if i.err != nil {
+		status.Error = i.err.Error()
+	}
+	return status
+}
+
+func (i *Import) setStage(stage string) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.stage = stage
+}
+
+func (i *Import) finish(err error) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	i.finished = true
+	i.endAt = time.Now()
+	i.err = err
+}
+
+func (i *Import) isFinished() bool {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+	return i.finished
+}
+
+// CanStartImport сообщает, что у пользователя нет незавершенных импортов документов.
+// Окончательная проверка выполняется в StartImport вместе с регистрацией импорта
+func (s *DocImportService) CanStartImport(actorId uuid.UUID) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.canStartImport(actorId)
+}
+
+// canStartImport выполняется под s.mutex
+func (s *DocImportService) canStartImport(actorId uuid.UUID) bool {
+	for _, i := range s.imports {
+		if i.ActorID == actorId && !i.isFinished() {
+			return false
+		}
+	}
+	return true
+}
+
+// StartImport читает архив и запускает импорт в фоне. Файл архива закрывается и удаляется по окончании импорта.
+// Документы верхнего уровня создаются внутри parentDocId или в корне пространства.
+// Если у пользователя уже есть незавершенный импорт, возвращает ErrAlreadyRunning
+func (s *DocImportService) StartImport(file *os.File, fileName string, format Format, user dao.User, workspace dao.Workspace, parentDocId uuid.NullUUID) (*Import, error) {
+	cleanup := func() {
+		file.Close()
+		os.Remove(file.Name())
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	zr, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		cleanup()
+		return nil, ErrInvalidArchive
+	}
+	archive, err := ReadArchive(zr, format)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	imp := &Import{
+		ID:          dao.GenUUID(),
+		ActorID:     user.ID,
+		WorkspaceID: workspace.ID,
+		FileName:    fileName,
+		Format:      archive.Format,
+		StartAt:     time.Now(),
+		TotalDocs:   archive.Count(),
+		stage:       StageParse,
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+
+	// Проверка и регистрация под одной блокировкой: параллельные запросы не запустят два импорта
+	s.mutex.Lock()
+	if !s.canStartImport(user.ID) {
+		s.mutex.Unlock()
+		cancel()
+		cleanup()
+		return nil, ErrAlreadyRunning
+	}
+	s.cleanFinished()
+	s.imports[imp.ID] = imp
+	s.mutex.Unlock()
+
+	go func() {
+		defer cleanup()
+		defer cancel()
+
+		log := slog.With("importId", imp.ID, "workspaceId", workspace.ID, "file", fileName)
+		log.Info("Start docs import", "format", archive.Format, "docs", imp.TotalDocs)
+
+		err := s.runImport(imp, archive, &user, &workspace, parentDocId)
+		if err != nil {
+			log.Error("Docs import failed", "err", err)
+		} else {
+			log.Info("Docs import finished")
+		}
+		imp.finish(err)
+	}()
+
+	return imp, nil
+}
+
+// runImport преобразует страницы, загружает файлы и создает документы в одной транзакции.
+// При ошибке сохранения загруженные файлы удаляются из хранилища
+func (s *DocImportService) runImport(imp *Import, archive *Archive, user *dao.User, workspace *dao.Workspace, parentDocId uuid.NullUUID) error {
+	docs := make(map[*Page]*dao.Doc)
+	archive.Walk(func(page *Page, parent *Page, index int) {
+		docs[page] = &dao.Doc{
+			ID:          dao.GenUUID(),
+			CreatedById: user.ID,
+			Author:      user,
+			Title:       page.Title,
+			WorkspaceId: workspace.ID,
+			Workspace:   workspace,
+			SeqId:       index,
+		}
+		if parent != nil {
+			docs[page].ParentDocID = uuid.NullUUID{UUID: docs[parent].ID, Valid: true}
+		} else {
+			docs[page].ParentDocID = parentDocId
+		}
+	})
+
+	// Преобразование содержимого, файлы только резервируются и загружаются на следующем этапе
+	assets := make(map[string]*pendingAsset)
+	var assetOrder []*pendingAsset
+	var convertErr error
+	archive.Walk(func(page *Page, _ *Page, _ int) {
+		if convertErr != nil || page.Path == "" {
+			return
+		}
+		doc := docs[page]
+
+		body := page.Body
+		if page.Markdown {
+			html, _, err := dao.MarkdownToHTML(s.db, workspace, body)
+			if err != nil {
+				convertErr = err
+				return
+			}
+			body = html
+		}
+
+		doc.Content = types.RedactorHTML{Body: archive.ConvertContent(page, body, LinkResolver{
+			Page: func(target *Page) (string, bool) {
+				d := dao.Doc{ID: docs[target].ID, WorkspaceId: workspace.ID, Workspace: workspace}
+				d.SetUrl()
+				return d.URL.String(), true
+			},
+			File: func(p string, inline bool) (string, bool) {
+				pa, ok := assets[p]
+				if !ok {
+					f := archive.File(p)
+					name := path.Base(p)
+					pa = &pendingAsset{
+						path:   p,
+						docId:  doc.ID,
+						inline: inline,
+						asset: dao.FileAsset{
+							Id:          dao.GenUUID(),
+							CreatedAt:   time.Now(),
+							CreatedById: uuid.NullUUID{UUID: user.ID, Valid: true},
+							WorkspaceId: uuid.NullUUID{UUID: workspace.ID, Valid: true},
+							Name:        name,
+							FileSize:    int(f.UncompressedSize64),
+							ContentType: utils.ResolveContentType(name, ""),
+						},
+					}
+					// Изображения в тексте привязываются к документу, остальные файлы становятся вложениями документа
+					if inline {
+						pa.asset.DocId = uuid.NullUUID{UUID: doc.ID, Valid: true}
+					}
+					assets[p] = pa
+					assetOrder = append(assetOrder, pa)
+				}
+				return "/api/auth/file/" + pa.asset.Id.String(), true
+			},
+		})}
+	})
+	if convertErr != nil {
+		return convertErr
+	}
+
+	imp.mutex.Lock()
+	imp.TotalAssets = len(assetOrder)
+	imp.mutex.Unlock()
+	imp.setStage(StageAssets)
+
+	var uploaded []uuid.UUID
+	removeUploaded := func() {
+		for _, id := range uploaded {
+			if err := s.storage.Delete(id); err != nil {
+				slog.Error("Delete imported doc asset", "id", id, "err", err)
+			}
+		}
+	}
+
+	// Файлы учитываются в хранилище только после сохранения импорта, поэтому остаток уменьшается по мере загрузки.
+	// Отрицательный остаток означает, что лимитер его не сообщил, тогда проверяется только CanAddAttachment
+	remaining := int64(limiter.Limiter.GetRemainingAttachments(workspace.ID))
+	for _, pa := range assetOrder {
+		if imp.ctx.Err() != nil {
+			removeUploaded()
+			return ErrCanceled
+		}
+		size := int64(pa.asset.FileSize)
+		if (remaining >= 0 && size > remaining) || !limiter.Limiter.CanAddAttachment(workspace.ID) {
+			removeUploaded()
+			return ErrAssetsLimit
+		}
+		if err := s.uploadAsset(archive.File(pa.path), pa, workspace); err != nil {
+			slog.Error("Upload imported doc asset", "path", pa.path, "err", err)
+			pa.failed = true
+			imp.mutex.Lock()
+			imp.failedAssets = append(imp.failedAssets, pa.path)
+			imp.mutex.Unlock()
+		} else {
+			uploaded = append(uploaded, pa.asset.Id)
+			if remaining >= 0 {
+				remaining -= size
+			}
+		}
+		imp.ImportedAssets.Add(1)
+	}
+
+	imp.setStage(StageDocs)
+
+	var rootDocIds []uuid.UUID
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Новые документы верхнего уровня встают после существующих
+		var nextSeq int
+		query := tx.Model(&dao.Doc{}).Select("coalesce(max(seq_id) + 1, 0)").Where("workspace_id = ?", workspace.ID)
+		if parentDocId.Valid {
+			query = query.Where("parent_doc_id = ?", parentDocId.UUID)
+		} else {
+			query = query.Where("parent_doc_id IS NULL")
+		}
+		if err := query.Scan(&nextSeq).Error; err != nil {
+			return err
+		}
+
+		var createErr error
+		archive.Walk(func(page *Page, parent *Page, _ int) {
+			if createErr != nil {
+				return
+			}
+			if imp.ctx.Err() != nil {
+				createErr = ErrCanceled
+				return
+			}
+
+			doc := docs[page]
+			if parent == nil {
+				doc.SeqId += nextSeq
+				rootDocIds = append(rootDocIds, doc.ID)
+			}
+			if err := dao.CreateDoc(tx, doc, user); err != nil {
+				createErr = err
+				return
+			}
+			imp.ImportedDocs.Add(1)
+		})
+		if createErr != nil {
+			return createErr
+		}
+
+		for _, pa := range assetOrder {
+			if pa.failed {
+				continue
+			}
+			if err := tx.Create(&pa.asset).Error; err != nil {
+				return err
+			}
+			if pa.inline {
+				continue
+			}
+			if err := tx.Create(&dao.DocAttachment{
+				Id:          dao.GenUUID(),
+				CreatedAt:   time.Now(),
+				UpdatedAt:   time.Now(),
+				CreatedById: pa.asset.CreatedById,
+				UpdatedById: pa.asset.CreatedById,
+				AssetId:     pa.asset.Id,
+				DocId:       pa.docId,
+				WorkspaceId: workspace.ID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		// Ссылки на страницы, созданные позже ссылающейся, разрешаются только после создания всех документов
+		for _, doc := range docs {
+			if err := dao.SyncReferences(tx, dao.ReferenceSourceDoc, doc.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		removeUploaded()
+		if errors.Is(err, ErrCanceled) {
+			return ErrCanceled
+		}
+		slog.Error("Save imported docs", "err", err)
+		return ErrSaveDB
+	}
+
+	imp.mutex.Lock()
+	imp.rootDocIds = rootDocIds
+	imp.mutex.Unlock()
+	return nil
+}
+
+func (s *DocImportService) uploadAsset(f *zip.File, pa *pendingAsset, workspace *dao.Workspace) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	metadata := filestorage.Metadata{WorkspaceId: workspace.ID.String(), DocId: pa.docId.String()}
+	return s.storage.SaveReader(rc, int64(f.UncompressedSize64), pa.asset.Id, pa.asset.ContentType, &metadata)
+}
+
+// GetUserImports возвращает импорты пользователя, незавершенные первыми
+func (s *DocImportService) GetUserImports(actorId uuid.UUID) []ImportStatus {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	res := make([]ImportStatus, 0)
+	for _, i := range s.imports {
+		if i.ActorID == actorId {
+			res = append(res, i.GetStatus())
+		}
+	}
+	slices.SortFunc(res, func(a, b ImportStatus) int {
+		if a.Finished != b.Finished {
+			if a.Finished {
+				return 1
+			}
+			return -1
+		}
+		return b.StartAt.Compare(a.StartAt)
+	})
+	return res
+}
+
+// GetUserImportStatus возвращает статус импорта пользователя, false - импорт не найден
+func (s *DocImportService) GetUserImportStatus(id uuid.UUID, actorId uuid.UUID) (ImportStatus, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	i, ok := s.imports[id]
+	if !ok || i.ActorID != actorId {
+		return ImportStatus{}, false
+	}
+	return i.GetStatus(), true
+}
+
+// CancelImport отменяет незавершенный импорт пользователя, false - импорт не найден
+func (s *DocImportService) CancelImport(id uuid.UUID, actorId uuid.UUID) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	i, ok := s.imports[id]
+	if !ok || i.ActorID != actorId || i.isFinished() {
+		return false
+	}
+	i.cancel()
+	return true
+}
+
+// CancelWorkspaceImports отменяет импорты в пространство
+func (s *DocImportService) CancelWorkspaceImports(workspaceId uuid.UUID) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, i := range s.imports {
+		if i.WorkspaceID == workspaceId && !i.isFinished() {
+			i.cancel()
+		}
+	}
+}
+
+// cleanFinished удаляет старые завершенные импорты. Вызывается под блокировкой
+func (s *DocImportService) cleanFinished() {
+	for id, i := range s.imports {
+		i.mutex.RLock()
+		old := i.finished && time.Since(i.endAt) > finishedTTL
+		i.mutex.RUnlock()
+		if old {
+			delete(s.imports, id)
+		}
+	}
+}
+
+func percent(done, total int) int {
+	if total == 0 {
+		return 100
+	}
+	return int(float64(done) / float64(total) * 100)
+}
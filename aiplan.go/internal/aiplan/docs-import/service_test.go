@@ -0,0 +1,42 @@
+package docs_import
+
+import (
+	"archive/zip"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/gofrs/uuid"
+)
+
+func TestStartImportAlreadyRunning(t *testing.T) {
+	s := NewDocImportService(nil, nil)
+	user := dao.User{ID: dao.GenUUID()}
+	running := &Import{ID: dao.GenUUID(), ActorID: user.ID}
+	s.imports[running.ID] = running
+
+	f, err := os.CreateTemp(t.TempDir(), "doc-import-*.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	page, err := w.Create("intro.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page.Write([]byte("# Введение"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.StartImport(f, "docs.zip", FormatAuto, user, dao.Workspace{ID: dao.GenUUID()}, uuid.NullUUID{}); !errors.Is(err, ErrAlreadyRunning) {
+		t.Fatalf("Expected ErrAlreadyRunning, got %v", err)
+	}
+	if _, err := os.Stat(f.Name()); !os.IsNotExist(err) {
+		t.Error("Archive must be removed when import is rejected")
+	}
+	if len(s.imports) != 1 {
+		t.Errorf("Rejected import must not be registered, got %d imports", len(s.imports))
+	}
+}
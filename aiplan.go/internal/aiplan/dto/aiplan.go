@@ -5,13 +5,14 @@
 //   - Описание файлов (FileAsset).
 //   - Определение состояний (StateLight).
 //   - Определение тарифных планов (Tariffication).
-//   - Хранение истории изменений (HistoryBodyLight, HistoryBody).
+//   - Хранение истории изменений (HistoryBodyLight, HistoryBody, HistoryDiff).
 //   - Работа с вложениями (Attachment).
 package dto
 
 import (
 	"time"
 
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor/diff"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
 	actField "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types/activities"
 	"github.com/gofrs/uuid"
@@ -71,6 +72,15 @@ type HistoryBody struct {
 	CurrentInlineAttachment []FileAsset `json:"current_inline_attachment"`
 }
 
+// HistoryDiff - структурные изменения между версиями. To равно nil, если сравнение с текущей версией
+type HistoryDiff struct {
+	From *HistoryBodyLight `json:"from"`
+	To   *HistoryBodyLight `json:"to" extensions:"x-nullable"`
+
+	Blocks []diff.BlockChange `json:"blocks"`
+	Stats  diff.Stats         `json:"stats"`
+}
+
 type Attachment struct {
 	Id        uuid.UUID  `json:"id"`
 	CreatedAt time.Time  `json:"created_at"`
@@ -16,3 +16,13 @@ type CommentHistory struct {
 	CreatedAt       time.Time          `json:"created_at"`
 	Attachments     []FileAsset        `json:"comment_attachments,omitempty" `
 }
+
+// EntityReference - упоминание задачи или документа в описании задачи, документе или комментарии.
+// Issue или Doc - задача или документ, которым принадлежит источник ссылки
+type EntityReference struct {
+	SourceType string      `json:"source_type" enums:"issue,issue_comment,doc,doc_comment"`
+	SourceId   uuid.UUID   `json:"source_id"`
+	CreatedAt  time.Time   `json:"created_at"`
+	Issue      *IssueLight `json:"issue,omitempty" extensions:"x-nullable"`
+	Doc        *DocLight   `json:"doc,omitempty" extensions:"x-nullable"`
+}
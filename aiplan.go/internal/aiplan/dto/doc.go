@@ -25,6 +25,9 @@ type Doc struct {
 	Content    types.RedactorHTML `json:"content" swaggertype:"string"`
 	LLMContent bool               `json:"llm_content"`
 
+	// Содержимое в формате Markdown, заполняется по запросу ?format=markdown
+	ContentMarkdown string `json:"content_markdown,omitempty"`
+
 	ParentDoc uuid.NullUUID `json:"parent_doc,omitempty"`
 
 	InlineAttachments []FileAsset `json:"doc_inline_attachments"`
@@ -78,6 +81,28 @@ type DocComment struct {
 	Attachments     []FileAsset        `json:"comment_attachments"`
 	ReactionSummary map[string]int     `json:"reaction_summary,omitempty"`
 	Reactions       []*CommentReaction `json:"reactions,omitempty"`
+
+	Anchor         *DocCommentAnchor `json:"anchor,omitempty" extensions:"x-nullable"`
+	AnchorAuthorId uuid.NullUUID     `json:"anchor_author_id,omitempty" extensions:"x-nullable"`
+	ThreadId       uuid.NullUUID     `json:"thread_id,omitempty" extensions:"x-nullable"`
+	ResolvedAt     *time.Time        `json:"resolved_at,omitempty" extensions:"x-nullable"`
+	ResolvedBy     *UserLight        `json:"resolved_by_detail,omitempty" extensions:"x-nullable"`
+}
+
+// DocCommentAnchor - фрагмент текста документа, к которому привязано обсуждение.
+// Block - индекс блока верхнего уровня, Start и End - смещения в символах текста блока
+type DocCommentAnchor struct {
+	Block    int    `json:"block"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	Text     string `json:"text"`
+	Detached bool   `json:"detached"`
+}
+
+// DocCommentThread - обсуждение фрагмента документа: первый комментарий и ответы в порядке создания
+type DocCommentThread struct {
+	DocComment
+	Replies []DocComment `json:"replies"`
 }
 
 type CommentReaction struct {
@@ -94,3 +119,72 @@ type DocFavorites struct {
 	DocId uuid.UUID `json:"doc_id"`
 	Doc   *DocLight `json:"doc"`
 }
+
+// DocShareLink - публичная ссылка на документ только для чтения
+type DocShareLink struct {
+	Id           uuid.UUID     `json:"id"`
+	CreatedAt    time.Time     `json:"created_at"`
+	CreatedBy    *UserLight    `json:"created_by,omitempty" extensions:"x-nullable"`
+	Url          types.JsonURL `json:"url"`
+	Token        string        `json:"token"`
+	WithChildren bool          `json:"with_children"`
+	HasPassword  bool          `json:"has_password"`
+	ExpiresAt    *time.Time    `json:"expires_at" extensions:"x-nullable"`
+	ViewCount    int           `json:"view_count"`
+	LastViewedAt *time.Time    `json:"last_viewed_at" extensions:"x-nullable"`
+}
+
+// SharedDocLight - документ в дереве документов публичной ссылки
+type SharedDocLight struct {
+	Id       uuid.UUID        `json:"id"`
+	Title    string           `json:"title"`
+	Children []SharedDocLight `json:"children,omitempty"`
+}
+
+// SharedDocAttachment - вложение документа, открытого по публичной ссылке. Url ведет на загрузку через ссылку
+type SharedDocAttachment struct {
+	Name        string        `json:"name"`
+	Size        int           `json:"size"`
+	ContentType string        `json:"content_type"`
+	Url         types.JsonURL `json:"url"`
+}
+
+// SharedDoc - документ, открытый по публичной ссылке. Content - очищенный HTML, адреса изображений заменены
+// на загрузку через ссылку. Tree заполняется для ссылок с дочерними документами
+type SharedDoc struct {
+	Id        uuid.UUID `json:"id"`
+	Title     string    `json:"title"`
+	Author    string    `json:"author"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Content   string    `json:"content"`
+
+	Attachments []SharedDocAttachment `json:"attachments"`
+
+	Tree      *SharedDocLight `json:"tree,omitempty" extensions:"x-nullable"`
+	ExpiresAt *time.Time      `json:"expires_at" extensions:"x-nullable"`
+}
+
+// DocTemplateLight - шаблон документа пространства без содержимого
+type DocTemplateLight struct {
+	Id          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Title       string    `json:"title"`
+}
+
+// DocTemplate - шаблон документа пространства. Title и Template могут содержать переменные вида {{date}}
+type DocTemplate struct {
+	DocTemplateLight
+	Template    types.RedactorHTML `json:"template" swaggertype:"string"`
+	WorkspaceId uuid.UUID          `json:"workspace_id"`
+	CreatedAt   time.Time          `json:"created_at"`
+	CreatedBy   *UserLight         `json:"created_by,omitempty" extensions:"x-nullable"`
+	UpdatedAt   time.Time          `json:"updated_at"`
+	UpdatedBy   *UserLight         `json:"updated_by,omitempty" extensions:"x-nullable"`
+}
+
+// DocTemplateVariable - переменная, доступная в шаблонах документов
+type DocTemplateVariable struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
@@ -19,10 +19,13 @@ type FormLight struct {
 	Title           string                `json:"title" validate:"required"`
 	Description     types.RedactorHTML    `json:"description" swaggertype:"string"`
 	AuthRequire     bool                  `json:"auth_require"`
+	AllowEdit       bool                  `json:"allow_edit"`
 	EndDate         *types.TargetDate     `json:"end_date" extensions:"x-nullable" swaggertype:"string"`
 	TargetProjectId uuid.NullUUID         `json:"target_project_id,omitempty"  extensions:"x-nullable"`
 	WorkspaceId     uuid.UUID             `json:"workspace" `
 	Fields          types.FormFieldsSlice `json:"fields"`
+	Sections        types.FormSections    `json:"sections,omitempty"`
+	Protection      types.FormProtection  `json:"protection"`
 	Active          bool                  `json:"active"`
 	Url             types.JsonURL         `json:"url,omitempty"`
 }
@@ -36,12 +39,14 @@ type Form struct {
 }
 
 type FormAnswer struct {
-	ID        uuid.UUID `json:"id"`
-	SeqId     int       `json:"seq_id"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        uuid.UUID  `json:"id"`
+	SeqId     int        `json:"seq_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	EditedAt  *time.Time `json:"edited_at,omitempty" extensions:"x-nullable"`
 
-	Responder *UserLight `json:"responder" extensions:"x-nullable"`
-	Form      *Form      `json:"form" extensions:"x-nullable"`
+	IssueId   uuid.NullUUID `json:"issue_id" extensions:"x-nullable"`
+	Responder *UserLight    `json:"responder" extensions:"x-nullable"`
+	Form      *Form         `json:"form" extensions:"x-nullable"`
 
 	Fields types.FormFieldsSlice `json:"fields"`
 
@@ -49,6 +54,12 @@ type FormAnswer struct {
 	Attachments []FormAttachmentLight `json:"attachments,omitempty" extensions:"x-nullable"`
 }
 
+// FormIssueLink - подписанная ссылка на задачу для поля формы с атрибутом issue
+type FormIssueLink struct {
+	Ref string `json:"ref"`
+	URL string `json:"url"`
+}
+
 type FormAttachmentLight struct {
 	Id        uuid.UUID  `json:"id"`
 	CreatedAt time.Time  `json:"created_at"`
@@ -64,8 +75,50 @@ type RequestAnswer struct {
 
 //  **RESPONSE**
 
+// ResponseAnswerSection следующий раздел формы после проверенного, -1 - форму можно отправлять
+type ResponseAnswerSection struct {
+	NextSection int `json:"next_section"`
+}
+
 // ResponseAnswers ответы на поля формы
 type ResponseAnswers struct {
 	Form   FormLight             `json:"form"`
 	Fields types.FormFieldsSlice `json:"fields"`
 }
+
+// FormAnswerStats сводка ответов на форму по полям
+type FormAnswerStats struct {
+	Total    int                 `json:"total"`
+	Fields   []FormFieldStats    `json:"fields"`
+	Timeline []FormAnswersPerDay `json:"timeline"`
+}
+
+// FormFieldStats сводка ответов на поле формы. Shown - сколько раз поле показывалось с учетом depend_on,
+// Answered - сколько раз на него ответили
+type FormFieldStats struct {
+	Index        int     `json:"index"`
+	Label        string  `json:"label"`
+	Type         string  `json:"type"`
+	Shown        int     `json:"shown"`
+	Answered     int     `json:"answered"`
+	ResponseRate float64 `json:"response_rate"`
+
+	Options []FormOptionStats `json:"options,omitempty"`
+
+	Min *float64 `json:"min,omitempty" extensions:"x-nullable"`
+	Max *float64 `json:"max,omitempty" extensions:"x-nullable"`
+	Avg *float64 `json:"avg,omitempty" extensions:"x-nullable"`
+}
+
+// FormOptionStats количество ответов с вариантом. Percent - доля от ответивших на поле
+type FormOptionStats struct {
+	Value   string  `json:"value"`
+	Count   int     `json:"count"`
+	Percent float64 `json:"percent"`
+}
+
+// FormAnswersPerDay количество ответов за день
+type FormAnswersPerDay struct {
+	Date  string `json:"date"`
+	Count int    `json:"count"`
+}
@@ -4,7 +4,11 @@
 // Важно: Git репозитории НЕ хранятся в базе данных, вся информация находится в файловой системе.
 package dto
 
-import "time"
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
 
 // GitRepositoryLight - облегченная структура для представления Git репозитория
 type GitRepositoryLight struct {
@@ -17,6 +21,7 @@ type GitRepositoryLight struct {
 	CloneURL    string    `json:"clone_url"`
 	CreatedAt   time.Time `json:"created_at"`
 	CreatedBy   string    `json:"created_by"` // UUID пользователя
+	IsMirror    bool      `json:"is_mirror"`
 }
 
 // CreateGitRepositoryRequest - структура запроса на создание Git репозитория
@@ -33,19 +38,45 @@ type CreateGitRepositoryRequest struct {
 
 	// Description - описание репозитория (необязательное поле)
 	Description string `json:"description,omitempty"`
+
+	// Mirror - создать pull-зеркало внешнего репозитория (необязательное поле)
+	Mirror *CreateGitMirrorRequest `json:"mirror,omitempty"`
+}
+
+// CreateGitMirrorRequest - параметры pull-зеркала при создании репозитория
+type CreateGitMirrorRequest struct {
+	// URL - http(s) адрес удаленного репозитория без учетных данных
+	URL string `json:"url" validate:"required"`
+
+	// Username - имя пользователя (необязательное поле, для токенов можно не указывать)
+	Username string `json:"username,omitempty"`
+
+	// Password - пароль или токен доступа (необязательное поле)
+	Password string `json:"password,omitempty"`
+}
+
+// GitMirrorInfo - состояние pull-зеркала (без учетных данных)
+type GitMirrorInfo struct {
+	URL            string     `json:"url"`
+	HasCredentials bool       `json:"has_credentials"`
+	LastSyncAt     *time.Time `json:"last_sync_at,omitempty"`
+	LastSuccessAt  *time.Time `json:"last_success_at,omitempty"`
+	LastSyncStatus string     `json:"last_sync_status"`
+	LastSyncError  string     `json:"last_sync_error,omitempty"`
 }
 
 // CreateGitRepositoryResponse - структура ответа при создании Git репозитория
 type CreateGitRepositoryResponse struct {
-	Workspace   string     `json:"workspace"`
-	Name        string     `json:"name"`
-	Path        string     `json:"path"`
-	Branch      string     `json:"branch"`
-	Private     bool       `json:"private"`
-	Description string     `json:"description"`
-	CloneURL    string     `json:"clone_url"`
-	CreatedAt   time.Time  `json:"created_at"`
-	CreatedBy   *UserLight `json:"created_by,omitempty"`
+	Workspace   string         `json:"workspace"`
+	Name        string         `json:"name"`
+	Path        string         `json:"path"`
+	Branch      string         `json:"branch"`
+	Private     bool           `json:"private"`
+	Description string         `json:"description"`
+	CloneURL    string         `json:"clone_url"`
+	CreatedAt   time.Time      `json:"created_at"`
+	CreatedBy   *UserLight     `json:"created_by,omitempty"`
+	Mirror      *GitMirrorInfo `json:"mirror,omitempty"`
 }
 
 // ListGitRepositoriesResponse - структура ответа со списком репозиториев
@@ -97,9 +128,139 @@ type DeleteSSHKeyRequest struct {
 	KeyId string `json:"key_id" validate:"required"`
 }
 
+// DeployKeyDTO - deploy ключ репозитория для ответов API (без публичного ключа)
+type DeployKeyDTO struct {
+	SSHKeyDTO
+	ReadOnly  bool       `json:"read_only"`
+	CreatedBy *UserLight `json:"created_by,omitempty"`
+}
+
+// AddDeployKeyRequest - запрос на добавление deploy ключа репозиторию
+type AddDeployKeyRequest struct {
+	Name      string `json:"name" validate:"required,min=1,max=255"`
+	PublicKey string `json:"public_key" validate:"required"`
+	// ReadOnly - доступ только на чтение (по умолчанию true)
+	ReadOnly *bool `json:"read_only,omitempty"`
+}
+
+// ListDeployKeysResponse - список deploy ключей репозитория
+type ListDeployKeysResponse struct {
+	Keys  []DeployKeyDTO `json:"keys"`
+	Total int            `json:"total"`
+}
+
 // SSHConfigResponse - конфигурация SSH сервера
 type SSHConfigResponse struct {
 	SSHEnabled bool   `json:"ssh_enabled"`
 	SSHHost    string `json:"ssh_host"`
 	SSHPort    int    `json:"ssh_port"`
 }
+
+// ========================================
+// Merge Requests DTOs
+// ========================================
+
+// MergeRequestLight - облегченная структура merge request'а для списков
+type MergeRequestLight struct {
+	Workspace      string     `json:"workspace"`
+	Repository     string     `json:"repository"`
+	Number         int        `json:"number"`
+	Title          string     `json:"title"`
+	SourceBranch   string     `json:"source_branch"`
+	TargetBranch   string     `json:"target_branch"`
+	State          string     `json:"state"`
+	Author         *UserLight `json:"author,omitempty" extensions:"x-nullable"`
+	ApprovalsCount int        `json:"approvals_count"`
+	CommentsCount  int        `json:"comments_count"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	MergedAt       *time.Time `json:"merged_at,omitempty" extensions:"x-nullable"`
+	ClosedAt       *time.Time `json:"closed_at,omitempty" extensions:"x-nullable"`
+}
+
+// MergeRequest - полная структура merge request'а
+type MergeRequest struct {
+	MergeRequestLight
+
+	Description    string                 `json:"description"`
+	Issues         []IssueLight           `json:"issues"`
+	Approvals      []MergeRequestApproval `json:"approvals"`
+	MergedBy       *UserLight             `json:"merged_by,omitempty" extensions:"x-nullable"`
+	MergeMethod    string                 `json:"merge_method,omitempty"`
+	MergeCommitSHA string                 `json:"merge_commit_sha,omitempty"`
+}
+
+// MergeRequestApproval - одобрение merge request'а
+type MergeRequestApproval struct {
+	User      *UserLight `json:"user,omitempty" extensions:"x-nullable"`
+	CommitSHA string     `json:"commit_sha"`
+	// Outdated - одобрение дано для устаревшей версии исходной ветки
+	Outdated  bool      `json:"outdated"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MergeRequestComment - комментарий к merge request'у
+type MergeRequestComment struct {
+	Id               uuid.UUID          `json:"id"`
+	Actor            *UserLight         `json:"actor,omitempty" extensions:"x-nullable"`
+	CommentHtml      string             `json:"comment_html"`
+	ReplyToCommentId uuid.NullUUID      `json:"reply_to_comment_id" extensions:"x-nullable" swaggertype:"string"`
+	Path             string             `json:"path,omitempty"`
+	Line             int                `json:"line,omitempty"`
+	Side             string             `json:"side,omitempty"`
+	CommitSHA        string             `json:"commit_sha,omitempty"`
+	Reactions        []*CommentReaction `json:"reactions"`
+	ReactionSummary  map[string]int     `json:"reaction_summary,omitempty"`
+	CreatedAt        time.Time          `json:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at"`
+}
+
+// ListMergeRequestsResponse - список merge request'ов
+type ListMergeRequestsResponse struct {
+	MergeRequests []MergeRequestLight `json:"merge_requests"`
+	Total         int                 `json:"total"`
+}
+
+// CreateMergeRequestRequest - запрос на создание merge request'а
+type CreateMergeRequestRequest struct {
+	Title        string `json:"title" validate:"required,min=1,max=255"`
+	Description  string `json:"description,omitempty"`
+	SourceBranch string `json:"source_branch" validate:"required"`
+	TargetBranch string `json:"target_branch" validate:"required"`
+	// Issues - связанные задачи: UUID или идентификатор вида PROJ-12
+	Issues []string `json:"issues,omitempty"`
+}
+
+// UpdateMergeRequestRequest - запрос на изменение merge request'а (передаются только изменяемые поля)
+type UpdateMergeRequestRequest struct {
+	Title        *string `json:"title,omitempty" extensions:"x-nullable"`
+	Description  *string `json:"description,omitempty" extensions:"x-nullable"`
+	TargetBranch *string `json:"target_branch,omitempty" extensions:"x-nullable"`
+	// Issues - полная замена связанных задач: UUID или идентификатор вида PROJ-12
+	Issues *[]string `json:"issues,omitempty" extensions:"x-nullable"`
+	// State - open (переоткрыть) или closed (закрыть без слияния)
+	State *string `json:"state,omitempty" extensions:"x-nullable"`
+}
+
+// MergeMergeRequestRequest - запрос на слияние merge request'а
+type MergeMergeRequestRequest struct {
+	// Method - способ слияния: merge (merge коммит, по умолчанию) или fast-forward
+	Method string `json:"method,omitempty"`
+	// Message - сообщение merge коммита (по умолчанию формируется автоматически)
+	Message string `json:"message,omitempty"`
+	// IssueStateId - статус, в который перевести связанные задачи после слияния.
+	// Для задач других проектов используется первый статус группы completed.
+	IssueStateId *uuid.UUID `json:"issue_state_id,omitempty" extensions:"x-nullable" swaggertype:"string"`
+	// CompleteIssues - перевести связанные задачи в первый статус группы completed их проекта
+	CompleteIssues bool `json:"complete_issues,omitempty"`
+}
+
+// CreateMergeRequestCommentRequest - запрос на создание комментария к merge request'у
+type CreateMergeRequestCommentRequest struct {
+	CommentHtml      string        `json:"comment_html" validate:"required"`
+	ReplyToCommentId uuid.NullUUID `json:"reply_to_comment_id,omitempty" swaggertype:"string"`
+	// Path, Line, Side - привязка к строке файла в diff (необязательно)
+	Path string `json:"path,omitempty"`
+	Line int    `json:"line,omitempty"`
+	Side string `json:"side,omitempty"`
+}
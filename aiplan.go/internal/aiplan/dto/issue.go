@@ -74,6 +74,9 @@ type Issue struct {
 	Pinned              bool            `json:"pinned"`
 	LLMContent          bool            `json:"llm_content"`
 
+	// Описание в формате Markdown: заполняется по запросу ?format=markdown, при создании задачи заменяет description_html
+	DescriptionMarkdown string `json:"description_markdown,omitempty"`
+
 	Parent    *IssueLight      `json:"parent_detail"  extensions:"x-nullable"`
 	Workspace *WorkspaceLight  `json:"workspace_detail"  extensions:"x-nullable"`
 	Project   *ProjectLight    `json:"project_detail"  extensions:"x-nullable"`
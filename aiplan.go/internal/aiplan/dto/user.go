@@ -90,6 +90,8 @@ type User struct {
 	Settings  types.UserSettings `json:"settings"`
 	Tutorial  int                `json:"tutorial"`
 
+	TOTPEnabled bool `json:"totp_enabled"`
+
 	LastWorkspaceId   uuid.NullUUID `json:"last_workspace_id"  extensions:"x-nullable"`
 	LastWorkspaceSlug *string       `json:"last_workspace_slug"  extensions:"x-nullable"`
 	NotificationCount int           `json:"notification_count,omitempty"`
@@ -158,3 +160,34 @@ type PasswordResponse struct {
 type NotificationIdResponse struct {
 	Count int `json:"count"`
 }
+
+type PersonalAccessToken struct {
+	ID          uuid.UUID       `json:"id"`
+	CreatedAt   time.Time       `json:"created_at"`
+	Name        string          `json:"name"`
+	Prefix      string          `json:"prefix"`
+	Scopes      []string        `json:"scopes"`
+	WorkspaceId uuid.NullUUID   `json:"workspace_id" extensions:"x-nullable" swaggertype:"string"`
+	Workspace   *WorkspaceLight `json:"workspace,omitempty" extensions:"x-nullable"`
+	ExpiresAt   *time.Time      `json:"expires_at" extensions:"x-nullable"`
+	LastUsedAt  *time.Time      `json:"last_used_at" extensions:"x-nullable"`
+	RevokedAt   *time.Time      `json:"revoked_at" extensions:"x-nullable"`
+}
+
+// PersonalAccessTokenCreated - созданный токен. Значение токена возвращается только при создании
+type PersonalAccessTokenCreated struct {
+	PersonalAccessToken
+	Token string `json:"token"`
+}
+
+// UserSession - активная сессия пользователя на устройстве
+type UserSession struct {
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	UserAgent  string    `json:"user_agent"`
+	Ip         string    `json:"ip"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Сессия, из которой выполнен запрос
+	Current bool `json:"current"`
+}
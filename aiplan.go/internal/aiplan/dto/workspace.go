@@ -31,6 +31,7 @@ type Workspace struct {
 	Owner *UserLight `json:"owner,omitempty" extensions:"x-nullable"`
 
 	Description           types.RedactorHTML `json:"description"`
+	TwoFactorRequired     bool               `json:"two_factor_required"`
 	CurrentUserMembership *WorkspaceMember   `json:"current_user_membership,omitempty" extensions:"x-nullable"`
 	IsFavorite            bool               `json:"is_favorite"`
 }
@@ -85,3 +86,29 @@ type WorkspaceSummaryResponse struct {
 	Forms    []FormLight    `json:"forms,omitempty"`
 	Hash     []byte         `json:"-"`
 }
+
+// LdapGroupMapping - сопоставление группы LDAP участию в пространстве или проекте
+type LdapGroupMapping struct {
+	ID            uuid.UUID       `json:"id"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	Group         string          `json:"group"`
+	WorkspaceId   uuid.UUID       `json:"workspace_id"`
+	ProjectId     uuid.NullUUID   `json:"project_id" extensions:"x-nullable" swaggertype:"string"`
+	Role          int             `json:"role"`
+	RemoveMembers bool            `json:"remove_members"`
+	Workspace     *WorkspaceLight `json:"workspace,omitempty" extensions:"x-nullable"`
+	Project       *ProjectLight   `json:"project,omitempty" extensions:"x-nullable"`
+}
+
+// LdapMembershipChange - изменение участия пользователя в пространстве или проекте по группам LDAP
+type LdapMembershipChange struct {
+	UserId      uuid.UUID     `json:"user_id"`
+	Email       string        `json:"email"`
+	WorkspaceId uuid.UUID     `json:"workspace_id"`
+	ProjectId   uuid.NullUUID `json:"project_id" extensions:"x-nullable" swaggertype:"string"`
+	// add - добавление, role - повышение роли, remove - удаление
+	Action  string `json:"action"`
+	OldRole int    `json:"old_role,omitempty"`
+	Role    int    `json:"role,omitempty"`
+}
@@ -0,0 +1,165 @@
+package diff
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor"
+)
+
+// Anchor - фрагмент текста документа, к которому привязан комментарий.
+// Block - индекс блока верхнего уровня, Start и End - смещения в символах текста блока (как в сравнении версий).
+// Context - текст блока целиком, по нему блок находится после изменений документа
+type Anchor struct {
+	Block   int    `json:"block"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
+	Text    string `json:"text"`
+	Context string `json:"context"`
+}
+
+// NewAnchor привязывает фрагмент text к блоку block документа. Текст блока в редакторе может немного отличаться
+// от текста сравнения (разделители пунктов списка, ячеек таблицы), поэтому start используется как подсказка:
+// выбирается вхождение text, ближайшее к start. false - фрагмент в блоке не найден
+func NewAnchor(doc *editor.Document, block int, start int, text string) (Anchor, bool) {
+	if doc == nil || block < 0 || block >= len(doc.Elements) || strings.TrimSpace(text) == "" {
+		return Anchor{}, false
+	}
+	context := blockText(doc.Elements[block])
+	pos, ok := nearestIndex(context, text, start)
+	if !ok {
+		return Anchor{}, false
+	}
+	return Anchor{
+		Block:   block,
+		Start:   pos,
+		End:     pos + utf8.RuneCountInString(text),
+		Text:    text,
+		Context: context,
+	}, true
+}
+
+// RelocateAnchor находит фрагмент в новой версии документа. Блок ищется по тексту Context:
+// неизмененный блок - по совпадению, измененный - по наибольшей доле общего текста, смещения переносятся
+// через пословные изменения. Если блок не найден или фрагмент из него удален, фрагмент ищется по тексту Text.
+// false - фрагмент удален из документа
+func RelocateAnchor(doc *editor.Document, a Anchor) (Anchor, bool) {
+	if doc == nil {
+		return a, false
+	}
+	texts := make([]string, len(doc.Elements))
+	for i, elem := range doc.Elements {
+		texts[i] = blockText(elem)
+	}
+
+	if a.Block >= 0 && a.Block < len(texts) && texts[a.Block] == a.Context {
+		return a, true
+	}
+
+	if i := nearestBlock(texts, a.Block, func(text string) bool { return text == a.Context }); i >= 0 {
+		a.Block = i
+		return a, true
+	}
+
+	best, bestSimilarity := -1, 0.0
+	var bestChanges []TextChange
+	oldWords := splitWords(a.Context)
+	for i, text := range texts {
+		changes, similarity := compareWords(oldWords, splitWords(text))
+		if similarity < minSimilarity {
+			continue
+		}
+		if similarity > bestSimilarity || similarity == bestSimilarity && abs(i-a.Block) < abs(best-a.Block) {
+			best, bestSimilarity, bestChanges = i, similarity, changes
+		}
+	}
+	if best >= 0 {
+		start, end := mapOffset(bestChanges, a.Start), mapOffset(bestChanges, a.End)
+		if runes := []rune(texts[best]); start < end && end <= len(runes) {
+			return Anchor{Block: best, Start: start, End: end, Text: string(runes[start:end]), Context: texts[best]}, true
+		}
+	}
+
+	if a.Text == "" {
+		return a, false
+	}
+	i := nearestBlock(texts, a.Block, func(text string) bool { return strings.Contains(text, a.Text) })
+	if i < 0 {
+		return a, false
+	}
+	start, _ := nearestIndex(texts[i], a.Text, a.Start)
+	return Anchor{Block: i, Start: start, End: start + utf8.RuneCountInString(a.Text), Text: a.Text, Context: texts[i]}, true
+}
+
+// Text возвращает текст документа: блоки разделяются переводом строки
+func Text(doc *editor.Document) string {
+	if doc == nil {
+		return ""
+	}
+	texts := make([]string, len(doc.Elements))
+	for i, elem := range doc.Elements {
+		texts[i] = blockText(elem)
+	}
+	return strings.Join(texts, "\n")
+}
+
+// mapOffset переносит смещение старого текста в новый. Смещение внутри удаленного фрагмента
+// переносится в место удаления, вставки на границе фрагмента в него не включаются
+func mapOffset(changes []TextChange, pos int) int {
+	oldPos, newPos := 0, 0
+	for _, ch := range changes {
+		n := utf8.RuneCountInString(ch.Text)
+		switch ch.Op {
+		case OpEqual:
+			if pos <= oldPos+n {
+				return newPos + pos - oldPos
+			}
+			oldPos += n
+			newPos += n
+		case OpDelete:
+			if pos < oldPos+n {
+				return newPos
+			}
+			oldPos += n
+		case OpInsert:
+			newPos += n
+		}
+	}
+	return newPos
+}
+
+// nearestIndex возвращает смещение в символах вхождения substr, ближайшего к hint
+func nearestIndex(s string, substr string, hint int) (int, bool) {
+	best := -1
+	for from := 0; from <= len(s); {
+		i := strings.Index(s[from:], substr)
+		if i < 0 {
+			break
+		}
+		pos := utf8.RuneCountInString(s[:from+i])
+		if best < 0 || abs(pos-hint) < abs(best-hint) {
+			best = pos
+		}
+		_, size := utf8.DecodeRuneInString(s[from+i:])
+		from += i + max(size, 1)
+	}
+	return best, best >= 0
+}
+
+// nearestBlock возвращает индекс подходящего блока, ближайшего к from, или -1
+func nearestBlock(texts []string, from int, match func(text string) bool) int {
+	best := -1
+	for i, text := range texts {
+		if match(text) && (best < 0 || abs(i-from) < abs(best-from)) {
+			best = i
+		}
+	}
+	return best
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
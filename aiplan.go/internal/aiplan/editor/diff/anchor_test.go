@@ -0,0 +1,44 @@
+package diff
+
+import "testing"
+
+func TestRelocateAnchor(t *testing.T) {
+	doc := parse(t, `<p>Первый абзац</p><p>Срок сдачи отчета - пятница, ответственный - отдел аналитики</p><p>Последний</p>`)
+
+	a, ok := NewAnchor(doc, 1, 0, "пятница")
+	if !ok {
+		t.Fatal("Anchor not created")
+	}
+	if a.Start != 20 || a.End != 27 {
+		t.Fatalf("Anchor offsets = %d, %d", a.Start, a.End)
+	}
+	if _, ok := NewAnchor(doc, 0, 0, "пятница"); ok {
+		t.Error("Anchor created for missing text")
+	}
+
+	// Блок не изменился, но сдвинулся
+	moved, ok := RelocateAnchor(parse(t, `<p>Новый</p><p>Первый абзац</p><p>Срок сдачи отчета - пятница, ответственный - отдел аналитики</p>`), a)
+	if !ok || moved.Block != 2 || moved.Start != a.Start {
+		t.Errorf("Moved block: %+v, %v", moved, ok)
+	}
+
+	// Изменился текст перед фрагментом или сам фрагмент
+	edited, ok := RelocateAnchor(parse(t, `<p>Первый абзац</p><p>Срок сдачи годового отчета - пятница, ответственный - отдел аналитики</p>`), a)
+	if !ok || edited.Block != 1 || edited.Text != "пятница" || edited.Start != 29 {
+		t.Errorf("Edited block: %+v, %v", edited, ok)
+	}
+	replaced, ok := RelocateAnchor(parse(t, `<p>Первый абзац</p><p>Срок сдачи отчета - четверг, ответственный - отдел аналитики</p>`), a)
+	if !ok || replaced.Text != "четверг" {
+		t.Errorf("Replaced text: %+v, %v", replaced, ok)
+	}
+
+	// Блок переписан, но фрагмент остался в другом блоке
+	found, ok := RelocateAnchor(parse(t, `<p>Первый абзац</p><p>Все сдаем в пятница утром</p>`), a)
+	if !ok || found.Block != 1 || found.Text != "пятница" {
+		t.Errorf("Found by text: %+v, %v", found, ok)
+	}
+
+	if lost, ok := RelocateAnchor(parse(t, `<p>Первый абзац</p><p>Последний</p>`), a); ok {
+		t.Errorf("Deleted fragment relocated: %+v", lost)
+	}
+}
@@ -0,0 +1,507 @@
+// Структурное сравнение документов редактора.
+//
+// Документы сравниваются поблочно: совпадающие блоки находятся как наибольшая общая подпоследовательность,
+// среди оставшихся похожие блоки одного типа считаются измененными и для них строится пословное сравнение текста.
+// Результат подходит для отображения версий рядом и для уведомлений об изменениях.
+package diff
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor"
+)
+
+// Op - вид изменения блока или фрагмента текста
+type Op string
+
+const (
+	OpEqual  Op = "equal"
+	OpInsert Op = "insert"
+	OpDelete Op = "delete"
+	OpModify Op = "modify"
+)
+
+const (
+	// minSimilarity - минимальная доля общего текста, при которой удаленный и добавленный блоки считаются одним измененным
+	minSimilarity = 0.5
+	// pairWindow - сколько добавленных блоков просматривается в поиске пары для удаленного
+	pairWindow = 20
+	// maxLCSCells - ограничение размера таблицы поиска общей подпоследовательности.
+	// При превышении блоки или тексты целиком считаются удаленными и добавленными
+	maxLCSCells = 4_000_000
+)
+
+// TextChange - фрагмент текста измененного блока
+type TextChange struct {
+	Op   Op     `json:"op" enums:"equal,insert,delete"`
+	Text string `json:"text"`
+}
+
+// BlockChange - изменение блока документа. Индексы указывают на позицию блока в старой и новой версиях,
+// HTML - содержимое блока в соответствующей версии
+type BlockChange struct {
+	Op       Op     `json:"op" enums:"equal,insert,delete,modify"`
+	Type     string `json:"type"`
+	OldIndex *int   `json:"old_index,omitempty" extensions:"x-nullable"`
+	NewIndex *int   `json:"new_index,omitempty" extensions:"x-nullable"`
+	OldHTML  string `json:"old_html,omitempty"`
+	NewHTML  string `json:"new_html,omitempty"`
+
+	// Пословные изменения текста, только для измененных блоков
+	Text []TextChange `json:"text,omitempty"`
+
+	// Текст блока в соответствующей версии, для краткого описания изменений
+	oldText string
+	newText string
+}
+
+// Stats - количество блоков по видам изменений
+type Stats struct {
+	Inserted  int `json:"inserted"`
+	Deleted   int `json:"deleted"`
+	Modified  int `json:"modified"`
+	Unchanged int `json:"unchanged"`
+}
+
+// Diff - изменения между двумя версиями документа в порядке следования блоков
+type Diff struct {
+	Blocks []BlockChange `json:"blocks"`
+	Stats  Stats         `json:"stats"`
+}
+
+// HasChanges сообщает, отличаются ли версии
+func (d *Diff) HasChanges() bool {
+	return d.Stats.Inserted+d.Stats.Deleted+d.Stats.Modified > 0
+}
+
+type block struct {
+	typ   string
+	html  string
+	text  string
+	words []string
+}
+
+func newBlocks(doc *editor.Document) []block {
+	if doc == nil {
+		return nil
+	}
+	blocks := make([]block, 0, len(doc.Elements))
+	for _, elem := range doc.Elements {
+		b := block{
+			typ:  blockType(elem),
+			html: editor.RenderHTML(&editor.Document{Elements: []any{elem}}),
+		}
+		b.text = blockText(elem)
+		if b.typ == "code" {
+			b.words = splitLines(b.text)
+		} else {
+			b.words = splitWords(b.text)
+		}
+		blocks = append(blocks, b)
+	}
+	return blocks
+}
+
+// Compare сравнивает две версии документа. Пустой документ (nil) считается документом без блоков
+func Compare(oldDoc, newDoc *editor.Document) *Diff {
+	oldBlocks, newBlocks := newBlocks(oldDoc), newBlocks(newDoc)
+
+	oldKeys := make([]string, len(oldBlocks))
+	for i, b := range oldBlocks {
+		oldKeys[i] = b.html
+	}
+	newKeys := make([]string, len(newBlocks))
+	for i, b := range newBlocks {
+		newKeys[i] = b.html
+	}
+	pairs, _ := matchPairs(oldKeys, newKeys)
+
+	d := &Diff{Blocks: []BlockChange{}}
+	i, j := 0, 0
+	for _, p := range append(pairs, [2]int{len(oldBlocks), len(newBlocks)}) {
+		d.addGap(oldBlocks, newBlocks, i, p[0], j, p[1])
+		if p[0] < len(oldBlocks) {
+			d.add(BlockChange{
+				Op:       OpEqual,
+				Type:     oldBlocks[p[0]].typ,
+				OldIndex: ptr(p[0]),
+				NewIndex: ptr(p[1]),
+				OldHTML:  oldBlocks[p[0]].html,
+				NewHTML:  newBlocks[p[1]].html,
+			})
+		}
+		i, j = p[0]+1, p[1]+1
+	}
+	return d
+}
+
+// addGap добавляет изменения блоков между совпадениями: old[i0:i1] удалены, new[j0:j1] добавлены.
+// Похожие блоки одного типа объединяются в изменение блока с сохранением порядка
+func (d *Diff) addGap(oldBlocks, newBlocks []block, i0, i1, j0, j1 int) {
+	j := j0
+	for i := i0; i < i1; i++ {
+		match := -1
+		var text []TextChange
+		for k := j; k < j1 && k < j+pairWindow; k++ {
+			if oldBlocks[i].typ != newBlocks[k].typ {
+				continue
+			}
+			if changes, similarity := compareWords(oldBlocks[i].words, newBlocks[k].words); similarity >= minSimilarity {
+				match, text = k, changes
+				break
+			}
+		}
+		if match < 0 {
+			d.add(BlockChange{Op: OpDelete, Type: oldBlocks[i].typ, OldIndex: ptr(i), OldHTML: oldBlocks[i].html, oldText: oldBlocks[i].text})
+			continue
+		}
+
+		for ; j < match; j++ {
+			d.add(BlockChange{Op: OpInsert, Type: newBlocks[j].typ, NewIndex: ptr(j), NewHTML: newBlocks[j].html, newText: newBlocks[j].text})
+		}
+		d.add(BlockChange{
+			Op:       OpModify,
+			Type:     oldBlocks[i].typ,
+			OldIndex: ptr(i),
+			NewIndex: ptr(match),
+			OldHTML:  oldBlocks[i].html,
+			NewHTML:  newBlocks[match].html,
+			Text:     text,
+		})
+		j = match + 1
+	}
+	for ; j < j1; j++ {
+		d.add(BlockChange{Op: OpInsert, Type: newBlocks[j].typ, NewIndex: ptr(j), NewHTML: newBlocks[j].html, newText: newBlocks[j].text})
+	}
+}
+
+func (d *Diff) add(change BlockChange) {
+	switch change.Op {
+	case OpEqual:
+		d.Stats.Unchanged++
+	case OpInsert:
+		d.Stats.Inserted++
+	case OpDelete:
+		d.Stats.Deleted++
+	case OpModify:
+		d.Stats.Modified++
+	}
+	d.Blocks = append(d.Blocks, change)
+}
+
+// compareWords возвращает пословные изменения и долю общего текста от 0 до 1
+func compareWords(a, b []string) ([]TextChange, float64) {
+	if len(a)+len(b) == 0 {
+		return nil, 1
+	}
+
+	pairs, ok := matchPairs(a, b)
+	if !ok {
+		return []TextChange{
+			{Op: OpDelete, Text: strings.Join(a, "")},
+			{Op: OpInsert, Text: strings.Join(b, "")},
+		}, 0
+	}
+
+	var changes []TextChange
+	add := func(op Op, words []string) {
+		if len(words) == 0 {
+			return
+		}
+		text := strings.Join(words, "")
+		if n := len(changes); n > 0 && changes[n-1].Op == op {
+			changes[n-1].Text += text
+			return
+		}
+		changes = append(changes, TextChange{Op: op, Text: text})
+	}
+
+	i, j := 0, 0
+	for _, p := range pairs {
+		add(OpDelete, a[i:p[0]])
+		add(OpInsert, b[j:p[1]])
+		add(OpEqual, a[p[0]:p[0]+1])
+		i, j = p[0]+1, p[1]+1
+	}
+	add(OpDelete, a[i:])
+	add(OpInsert, b[j:])
+
+	var common, total int
+	for _, w := range a {
+		total += len(w)
+	}
+	for _, w := range b {
+		total += len(w)
+	}
+	for _, p := range pairs {
+		common += len(a[p[0]])
+	}
+	if total == 0 {
+		return changes, 1
+	}
+	return changes, float64(2*common) / float64(total)
+}
+
+// matchPairs находит наибольшую общую подпоследовательность и возвращает пары индексов совпавших элементов.
+// Общие начало и конец отбрасываются до построения таблицы; если оставшаяся таблица слишком велика,
+// середина считается несовпадающей и возвращается false
+func matchPairs[T comparable](a, b []T) ([][2]int, bool) {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	pairs := make([][2]int, 0, prefix+suffix)
+	for i := 0; i < prefix; i++ {
+		pairs = append(pairs, [2]int{i, i})
+	}
+
+	ok := true
+	ma, mb := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	n, m := len(ma), len(mb)
+	if n > 0 && m > 0 {
+		if (n+1)*(m+1) > maxLCSCells {
+			ok = false
+		} else {
+			// lcs[i*(m+1)+j] - длина общей подпоследовательности ma[i:] и mb[j:]
+			lcs := make([]int32, (n+1)*(m+1))
+			for i := n - 1; i >= 0; i-- {
+				for j := m - 1; j >= 0; j-- {
+					if ma[i] == mb[j] {
+						lcs[i*(m+1)+j] = lcs[(i+1)*(m+1)+j+1] + 1
+					} else {
+						lcs[i*(m+1)+j] = max(lcs[(i+1)*(m+1)+j], lcs[i*(m+1)+j+1])
+					}
+				}
+			}
+			for i, j := 0, 0; i < n && j < m; {
+				switch {
+				case ma[i] == mb[j]:
+					pairs = append(pairs, [2]int{prefix + i, prefix + j})
+					i++
+					j++
+				case lcs[(i+1)*(m+1)+j] >= lcs[i*(m+1)+j+1]:
+					i++
+				default:
+					j++
+				}
+			}
+		}
+	}
+
+	for k := 0; k < suffix; k++ {
+		pairs = append(pairs, [2]int{len(a) - suffix + k, len(b) - suffix + k})
+	}
+	return pairs, ok
+}
+
+// splitWords разбивает текст на слова, пробельные промежутки и отдельные знаки препинания
+func splitWords(s string) []string {
+	var words []string
+	start := 0
+	kind := -1
+	for i, r := range s {
+		var k int
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			k = 0
+		case unicode.IsSpace(r):
+			k = 1
+		default:
+			k = 2
+		}
+		if i > start && (k != kind || k == 2) {
+			words = append(words, s[start:i])
+			start = i
+		}
+		kind = k
+	}
+	if start < len(s) {
+		words = append(words, s[start:])
+	}
+	return words
+}
+
+// splitLines разбивает текст на строки вместе с переводами строк
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.SplitAfter(s, "\n")
+}
+
+func ptr(i int) *int {
+	return &i
+}
+
+// blockType возвращает название типа блока документа
+func blockType(elem any) string {
+	switch elem.(type) {
+	case editor.Paragraph, *editor.Paragraph:
+		return "paragraph"
+	case editor.List, *editor.List:
+		return "list"
+	case editor.Quote, *editor.Quote:
+		return "quote"
+	case editor.Code, *editor.Code:
+		return "code"
+	case editor.Table, *editor.Table:
+		return "table"
+	case editor.Spoiler, *editor.Spoiler:
+		return "spoiler"
+	case editor.InfoBlock, *editor.InfoBlock:
+		return "info_block"
+	case editor.Image, *editor.Image, editor.Drawio, *editor.Drawio:
+		return "image"
+	default:
+		return "unknown"
+	}
+}
+
+// blockText возвращает текст блока: параграфы разделяются переводом строки, ячейки таблицы - табуляцией
+func blockText(elem any) string {
+	var sb strings.Builder
+	writeBlockText(&sb, elem)
+	return sb.String()
+}
+
+func writeBlockText(sb *strings.Builder, elem any) {
+	switch e := elem.(type) {
+	case editor.Paragraph:
+		writeInlineText(sb, e.Content)
+	case *editor.Paragraph:
+		writeInlineText(sb, e.Content)
+	case editor.List:
+		writeListText(sb, &e)
+	case *editor.List:
+		writeListText(sb, e)
+	case editor.Quote:
+		writeParagraphsText(sb, e.Content)
+	case *editor.Quote:
+		writeParagraphsText(sb, e.Content)
+	case editor.Code:
+		sb.WriteString(e.Content)
+	case *editor.Code:
+		sb.WriteString(e.Content)
+	case editor.Table:
+		writeTableText(sb, &e)
+	case *editor.Table:
+		writeTableText(sb, e)
+	case editor.Spoiler:
+		writeTitledText(sb, e.Title, e.Content)
+	case *editor.Spoiler:
+		writeTitledText(sb, e.Title, e.Content)
+	case editor.InfoBlock:
+		writeTitledText(sb, e.Title, e.Content)
+	case *editor.InfoBlock:
+		writeTitledText(sb, e.Title, e.Content)
+	default:
+		writeInlineText(sb, []any{elem})
+	}
+}
+
+func writeInlineText(sb *strings.Builder, content []any) {
+	for _, item := range content {
+		switch n := item.(type) {
+		case editor.Text:
+			sb.WriteString(n.Content)
+		case *editor.Text:
+			sb.WriteString(n.Content)
+		case editor.HardBreak, *editor.HardBreak:
+			sb.WriteString("\n")
+		case editor.Mention:
+			writeMentionText(sb, &n)
+		case *editor.Mention:
+			writeMentionText(sb, n)
+		case editor.IssueLinkMention:
+			fmt.Fprintf(sb, "%s-%s", n.ProjectIdentifier, n.CurrentIssueId)
+		case *editor.IssueLinkMention:
+			fmt.Fprintf(sb, "%s-%s", n.ProjectIdentifier, n.CurrentIssueId)
+		case editor.DateNode:
+			sb.WriteString(n.Date)
+		case *editor.DateNode:
+			sb.WriteString(n.Date)
+		case editor.Image:
+			writeImageText(sb, n.Src)
+		case *editor.Image:
+			writeImageText(sb, n.Src)
+		case editor.Drawio:
+			writeImageText(sb, n.Src)
+		case *editor.Drawio:
+			writeImageText(sb, n.Src)
+		}
+	}
+}
+
+func writeMentionText(sb *strings.Builder, m *editor.Mention) {
+	if m.Label != "" {
+		sb.WriteString("@" + m.Label)
+	} else {
+		sb.WriteString("@" + m.ID)
+	}
+}
+
+// writeImageText добавляет изображение как отдельное слово, чтобы замена изображения была видна в тексте
+func writeImageText(sb *strings.Builder, src *url.URL) {
+	if src != nil {
+		sb.WriteString("[" + src.String() + "]")
+	}
+}
+
+func writeParagraphsText(sb *strings.Builder, paragraphs []editor.Paragraph) {
+	for i, p := range paragraphs {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		writeInlineText(sb, p.Content)
+	}
+}
+
+func writeListText(sb *strings.Builder, l *editor.List) {
+	for i, item := range l.Elements {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		if l.TaskList {
+			if item.Checked {
+				sb.WriteString("[x] ")
+			} else {
+				sb.WriteString("[ ] ")
+			}
+		}
+		writeParagraphsText(sb, item.Content)
+		for j := range item.Lists {
+			sb.WriteString("\n")
+			writeListText(sb, &item.Lists[j])
+		}
+	}
+}
+
+func writeTableText(sb *strings.Builder, t *editor.Table) {
+	for i, row := range t.Rows {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		for j, cell := range row {
+			if j > 0 {
+				sb.WriteString("\t")
+			}
+			writeParagraphsText(sb, cell.Content)
+		}
+	}
+}
+
+func writeTitledText(sb *strings.Builder, title string, content []editor.Paragraph) {
+	if title != "" {
+		sb.WriteString(title)
+		sb.WriteString("\n")
+	}
+	writeParagraphsText(sb, content)
+}
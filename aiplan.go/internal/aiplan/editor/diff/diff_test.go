@@ -0,0 +1,119 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor"
+)
+
+func parse(t *testing.T, body string) *editor.Document {
+	t.Helper()
+	doc, err := editor.ParseDocument(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return doc
+}
+
+func ops(d *Diff) string {
+	var res []string
+	for _, b := range d.Blocks {
+		res = append(res, string(b.Op))
+	}
+	return strings.Join(res, ",")
+}
+
+func TestCompare(t *testing.T) {
+	oldDoc := parse(t, `<p>Первый абзац</p><p>Второй абзац с длинным текстом</p><pre><code>a := 1
+b := 2</code></pre><p>Удаляемый</p>`)
+	newDoc := parse(t, `<p>Первый абзац</p><p>Новый</p><p>Второй абзац с очень длинным текстом</p><pre><code>a := 1
+b := 3</code></pre>`)
+
+	d := Compare(oldDoc, newDoc)
+	if got, want := ops(d), "equal,insert,modify,modify,delete"; got != want {
+		t.Fatalf("Ops = %s, want %s", got, want)
+	}
+	if d.Stats != (Stats{Inserted: 1, Deleted: 1, Modified: 2, Unchanged: 1}) {
+		t.Errorf("Unexpected stats: %+v", d.Stats)
+	}
+
+	modified := d.Blocks[2]
+	if *modified.OldIndex != 1 || *modified.NewIndex != 2 {
+		t.Errorf("Modified block indexes = %d, %d", *modified.OldIndex, *modified.NewIndex)
+	}
+	want := []TextChange{
+		{Op: OpEqual, Text: "Второй абзац с "},
+		{Op: OpInsert, Text: "очень "},
+		{Op: OpEqual, Text: "длинным текстом"},
+	}
+	if len(modified.Text) != len(want) {
+		t.Fatalf("Text changes = %+v", modified.Text)
+	}
+	for i := range want {
+		if modified.Text[i] != want[i] {
+			t.Errorf("Text change %d = %+v, want %+v", i, modified.Text[i], want[i])
+		}
+	}
+
+	code := d.Blocks[3]
+	if code.Type != "code" || len(code.Text) != 3 || code.Text[1].Text != "b := 2" || code.Text[2].Text != "b := 3" {
+		t.Errorf("Unexpected code changes: %+v", code.Text)
+	}
+}
+
+func TestCompareEqual(t *testing.T) {
+	body := `<p>Текст</p><ul><li><p>пункт</p></li></ul>`
+	d := Compare(parse(t, body), parse(t, body))
+	if d.HasChanges() || d.Stats.Unchanged != 2 {
+		t.Errorf("Unexpected diff: %+v", d.Stats)
+	}
+}
+
+func TestCompareEmpty(t *testing.T) {
+	d := Compare(nil, parse(t, `<p>Текст</p>`))
+	if got := ops(d); got != "insert" {
+		t.Errorf("Ops = %s", got)
+	}
+	d = Compare(parse(t, `<p>Текст</p>`), nil)
+	if got := ops(d); got != "delete" {
+		t.Errorf("Ops = %s", got)
+	}
+}
+
+func TestSplitWords(t *testing.T) {
+	got := splitWords("Привет,  мир_1!")
+	want := []string{"Привет", ",", "  ", "мир_1", "!"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("splitWords = %q", got)
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	long := strings.Repeat("слово ", 20)
+	oldDoc := parse(t, `<p>`+long+`срок пятница `+long+`</p><p>Удаляемый</p><p>Без изменений</p><img src="a.png">`)
+	newDoc := parse(t, `<p>`+long+`срок четверг `+long+`</p><p>Без изменений</p><p>Новый</p>`)
+
+	e := Compare(oldDoc, newDoc).Excerpt(2)
+	if len(e.Blocks) != 2 || e.Skipped != 1 {
+		t.Fatalf("Unexpected excerpt: %+v", e)
+	}
+
+	modified := e.Blocks[0]
+	if modified.Op != OpModify || len(modified.Text) != 4 {
+		t.Fatalf("Unexpected modified block: %+v", modified.Text)
+	}
+	if first := modified.Text[0].Text; !strings.HasPrefix(first, "…") || !strings.HasSuffix(first, "срок ") || len([]rune(first)) > excerptContext+1 {
+		t.Errorf("Context before change = %q", first)
+	}
+	if modified.Text[1] != (TextChange{Op: OpDelete, Text: "пятница"}) || modified.Text[2] != (TextChange{Op: OpInsert, Text: "четверг"}) {
+		t.Errorf("Unexpected changes: %+v", modified.Text[1:3])
+	}
+	if last := modified.Text[3].Text; !strings.HasSuffix(last, "…") {
+		t.Errorf("Context after change = %q", last)
+	}
+
+	if deleted := e.Blocks[1]; deleted.Op != OpDelete || deleted.Text[0].Text != "Удаляемый" {
+		t.Errorf("Unexpected deleted block: %+v", deleted)
+	}
+}
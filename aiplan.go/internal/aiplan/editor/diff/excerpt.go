@@ -0,0 +1,119 @@
+package diff
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	// excerptContext - сколько символов неизмененного текста оставляется рядом с изменением
+	excerptContext = 40
+	// excerptMaxText - максимальная длина текста добавленного или удаленного фрагмента
+	excerptMaxText = 300
+	// excerptEllipsis - обозначение пропущенного текста
+	excerptEllipsis = "…"
+)
+
+// Excerpt - краткое описание изменений между версиями для уведомлений. Blocks - измененные блоки
+// в порядке следования, Skipped - сколько измененных блоков не вошло в описание
+type Excerpt struct {
+	Blocks  []ExcerptBlock
+	Skipped int
+}
+
+// ExcerptBlock - измененный блок. Для добавленного и удаленного блока Text содержит один фрагмент с текстом блока,
+// для измененного - пословные изменения, в которых длинный неизмененный текст сокращен до окружения изменений
+type ExcerptBlock struct {
+	Op   Op
+	Text []TextChange
+}
+
+// Excerpt возвращает краткое описание не более maxBlocks измененных блоков.
+// Блоки без текста (изображения) пропускаются
+func (d *Diff) Excerpt(maxBlocks int) Excerpt {
+	var res Excerpt
+	for _, change := range d.Blocks {
+		var text []TextChange
+		switch change.Op {
+		case OpInsert:
+			text = textOnly(OpInsert, change.newText)
+		case OpDelete:
+			text = textOnly(OpDelete, change.oldText)
+		case OpModify:
+			text = shortenChanges(change.Text)
+		}
+		if len(text) == 0 {
+			continue
+		}
+		if len(res.Blocks) >= maxBlocks {
+			res.Skipped++
+			continue
+		}
+		res.Blocks = append(res.Blocks, ExcerptBlock{Op: change.Op, Text: text})
+	}
+	return res
+}
+
+func textOnly(op Op, text string) []TextChange {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	return []TextChange{{Op: op, Text: truncateEnd(text, excerptMaxText)}}
+}
+
+// shortenChanges сокращает неизмененный текст между изменениями: в начале блока остается конец фрагмента,
+// в конце - начало, в середине - начало и конец
+func shortenChanges(changes []TextChange) []TextChange {
+	res := make([]TextChange, 0, len(changes))
+	for i, ch := range changes {
+		switch {
+		case ch.Op != OpEqual:
+			ch.Text = truncateEnd(ch.Text, excerptMaxText)
+		case len(changes) == 1:
+			continue
+		case i == 0:
+			ch.Text = truncateStart(ch.Text, excerptContext)
+		case i == len(changes)-1:
+			ch.Text = truncateEnd(ch.Text, excerptContext)
+		default:
+			if len([]rune(ch.Text)) > 2*excerptContext {
+				ch.Text = truncateEnd(ch.Text, excerptContext) + " " + strings.TrimPrefix(truncateStart(ch.Text, excerptContext), excerptEllipsis)
+			}
+		}
+		res = append(res, ch)
+	}
+	return res
+}
+
+// truncateEnd оставляет начало текста не длиннее n символов, обрезая по границе слова
+func truncateEnd(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	cut := n
+	for i := n; i > n/2; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+	return strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace) + excerptEllipsis
+}
+
+// truncateStart оставляет конец текста не длиннее n символов, обрезая по границе слова
+func truncateStart(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	cut := len(runes) - n
+	for i := cut; i < len(runes)-n/2; i++ {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+	return excerptEllipsis + strings.TrimLeftFunc(string(runes[cut:]), unicode.IsSpace)
+}
@@ -92,6 +92,8 @@ func parseParagraph(root *html.Node) *Paragraph {
 		image := getImage(el)
 		if image != nil {
 			p.Content = append(p.Content, image)
+		} else if node := getInlineNode(el); node != nil {
+			p.Content = append(p.Content, node)
 		} else {
 			p.Content = append(p.Content, getText(el))
 		}
@@ -127,6 +129,12 @@ func parseListElement(li *html.Node) *ListElement {
 	listElement.Checked = getAttrValue("data-checked", li.Attr) == "true"
 
 	iterNodes(li, func(p *html.Node) bool {
+		if p != li && p.Type == html.ElementNode && (p.Data == "ul" || p.Data == "ol") {
+			if list := parseList(p); list != nil {
+				listElement.Lists = append(listElement.Lists, *list)
+			}
+			return true
+		}
 		paragraph := parseParagraph(p)
 		if paragraph != nil {
 			listElement.Content = append(listElement.Content, *paragraph)
@@ -138,15 +146,23 @@ func parseListElement(li *html.Node) *ListElement {
 }
 
 func parseCode(root *html.Node) Code {
-	var text string
+	var code Code
 	iterNodes(root, func(child *html.Node) bool {
+		if child.Type == html.ElementNode && child.Data == "code" && code.Language == "" {
+			for _, class := range strings.Fields(getAttrValue("class", child.Attr)) {
+				if lang, ok := strings.CutPrefix(class, "language-"); ok {
+					code.Language = lang
+					break
+				}
+			}
+		}
 		if child.Type != html.TextNode {
 			return false
 		}
-		text += child.Data
+		code.Content += child.Data
 		return false
 	})
-	return Code{text}
+	return code
 }
 
 func getText(root *html.Node) Text {
@@ -170,6 +186,8 @@ func getText(root *html.Node) Text {
 			text.Sub = true
 		case "sup":
 			text.Sup = true
+		case "code":
+			text.Code = true
 		case "span", "mark":
 			parseTextStyles(el, &text)
 		case "a":
@@ -184,6 +202,39 @@ func getText(root *html.Node) Text {
 	return text
 }
 
+// getInlineNode возвращает упоминание, ссылку на задачу или дату, если элемент (или обертка со стилями) является таким узлом
+func getInlineNode(root *html.Node) any {
+	var node any
+	iterNodes(root, func(el *html.Node) bool {
+		if node != nil {
+			return true
+		}
+		if el.Type != html.ElementNode {
+			return false
+		}
+		switch getAttrValue("data-type", el.Attr) {
+		case "mention":
+			node = &Mention{
+				ID:    getAttrValue("data-id", el.Attr),
+				Label: getAttrValue("data-label", el.Attr),
+			}
+		case "issueLinkMention":
+			node = &IssueLinkMention{
+				Slug:              getAttrValue("data-slug", el.Attr),
+				ProjectIdentifier: getAttrValue("data-project-identifier", el.Attr),
+				CurrentIssueId:    getAttrValue("data-current-issue-id", el.Attr),
+				OriginalUrl:       getAttrValue("data-original-url", el.Attr),
+			}
+		case "date-node":
+			node = &DateNode{Date: getAttrValue("data-date", el.Attr)}
+		default:
+			return false
+		}
+		return true
+	})
+	return node
+}
+
 func parseTextStyles(node *html.Node, text *Text) {
 	for _, attr := range node.Attr {
 		if attr.Key == "style" {
@@ -391,6 +442,10 @@ func parseTable(root *html.Node) *Table {
 }
 
 func parseColGroup(root *html.Node) []int {
+	// Таблица без colgroup (например, вставленная из другого редактора)
+	if root == nil {
+		return nil
+	}
 	var res []int
 	iterNodes(root, func(child *html.Node) bool {
 		if child.Type != html.ElementNode || child.Data != "col" {
@@ -41,6 +41,9 @@ func TestParsing(t *testing.T) {
 	gob.Register(Spoiler{})
 	gob.Register(InfoBlock{})
 	gob.Register(Color{})
+	gob.Register(&DateNode{})
+	gob.Register(&Mention{})
+	gob.Register(&IssueLinkMention{})
 
 	var buf bytes.Buffer
 	gz := gzip.NewWriter(&buf)
@@ -79,3 +82,63 @@ func TestColorJSON(t *testing.T) {
 		t.Fatal("Not equal", c, newC)
 	}
 }
+
+func TestRenderHTML(t *testing.T) {
+	f, _ := os.Open("example2.html")
+	d, err := ParseDocument(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rendered := RenderHTML(d)
+	d2, err := ParseDocument(bytes.NewBufferString(rendered))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(d2.Elements) != len(d.Elements) {
+		t.Fatalf("Expected %d elements after render, got %d\n%s", len(d.Elements), len(d2.Elements), rendered)
+	}
+	for i := range d.Elements {
+		want, _ := json.Marshal(d.Elements[i])
+		got, _ := json.Marshal(d2.Elements[i])
+		if !bytes.Equal(want, got) {
+			t.Errorf("Element %d differs:\nwant %s\ngot  %s", i, want, got)
+		}
+	}
+}
+
+func TestNestedListAndCodeLanguage(t *testing.T) {
+	src := `<ul data-type="taskList"><li data-checked="true" data-type="taskItem"><label><input type="checkbox" checked="checked"><span></span></label>` +
+		`<div><p>parent</p><ul><li><p>child</p><ol><li><p>deep</p></li></ol></li></ul></div></li></ul>` +
+		`<pre><code class="language-go">x := 1</code></pre>`
+	d, err := ParseDocument(bytes.NewBufferString(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Elements) != 2 {
+		t.Fatalf("Expected 2 elements, got %d", len(d.Elements))
+	}
+
+	list := d.Elements[0].(List)
+	if len(list.Elements) != 1 || len(list.Elements[0].Content) != 1 || len(list.Elements[0].Lists) != 1 {
+		t.Fatalf("Expected task item with nested list, got %+v", list)
+	}
+	nested := list.Elements[0].Lists[0]
+	if nested.TaskList || len(nested.Elements) != 1 || len(nested.Elements[0].Lists) != 1 || !nested.Elements[0].Lists[0].Numbered {
+		t.Errorf("Unexpected nested list: %+v", nested)
+	}
+	if code := d.Elements[1].(Code); code.Language != "go" || code.Content != "x := 1" {
+		t.Errorf("Unexpected code: %+v", code)
+	}
+
+	d2, err := ParseDocument(bytes.NewBufferString(RenderHTML(d)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := json.Marshal(d.Elements)
+	got, _ := json.Marshal(d2.Elements)
+	if !bytes.Equal(want, got) {
+		t.Errorf("Document changed after render:\nwant %s\ngot  %s", want, got)
+	}
+}
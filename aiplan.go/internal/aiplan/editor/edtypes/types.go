@@ -115,6 +115,7 @@ type Text struct {
 	Strikethrough bool
 	Sup           bool
 	Sub           bool
+	Code          bool // inline код (`code`)
 
 	Color   *Color
 	BgColor *Color
@@ -126,6 +127,8 @@ type Text struct {
 type ListElement struct {
 	Content []Paragraph
 	Checked bool
+	// Вложенные списки, следуют за параграфами элемента
+	Lists []List
 }
 
 type List struct {
@@ -140,6 +143,8 @@ type Quote struct {
 
 type Code struct {
 	Content string
+	// Язык подсветки кода, пустой - без подсветки
+	Language string
 }
 
 type Image struct {
@@ -0,0 +1,364 @@
+package editor
+
+import (
+	"fmt"
+	"html"
+	"log/slog"
+	"strings"
+)
+
+// RenderHTML преобразует документ в HTML в формате редактора TipTap.
+// Результат можно сохранить как описание задачи или содержимое документа,
+// ParseDocument восстанавливает из него тот же документ.
+func RenderHTML(doc *Document) string {
+	if doc == nil {
+		return "<p></p>"
+	}
+
+	var sb strings.Builder
+	for _, elem := range doc.Elements {
+		renderElement(&sb, elem)
+	}
+	if sb.Len() == 0 {
+		return "<p></p>"
+	}
+	return sb.String()
+}
+
+func renderElement(sb *strings.Builder, elem any) {
+	switch e := elem.(type) {
+	case Paragraph:
+		renderParagraph(sb, &e)
+	case *Paragraph:
+		renderParagraph(sb, e)
+	case Code:
+		renderCode(sb, &e)
+	case *Code:
+		renderCode(sb, e)
+	case Quote:
+		renderQuote(sb, &e)
+	case *Quote:
+		renderQuote(sb, e)
+	case List:
+		renderList(sb, &e)
+	case *List:
+		renderList(sb, e)
+	case Table:
+		renderTable(sb, &e)
+	case *Table:
+		renderTable(sb, e)
+	case Spoiler:
+		renderSpoiler(sb, &e)
+	case *Spoiler:
+		renderSpoiler(sb, e)
+	case InfoBlock:
+		renderInfoBlock(sb, &e)
+	case *InfoBlock:
+		renderInfoBlock(sb, e)
+	case nil:
+	case Image, *Image, Drawio, *Drawio, DateNode, *DateNode, IssueLinkMention, *IssueLinkMention, Mention, *Mention:
+		renderParagraph(sb, &Paragraph{Content: []any{e}})
+	default:
+		slog.Debug("Unknown element type for HTML rendering", "type", fmt.Sprintf("%T", e))
+	}
+}
+
+func renderParagraph(sb *strings.Builder, p *Paragraph) {
+	var styles []string
+	if p.Align != LeftAlign {
+		styles = append(styles, "text-align: "+textAlignName(p.Align))
+	}
+	sb.WriteString("<p")
+	if p.Indent > 0 {
+		fmt.Fprintf(sb, ` class="tt-indent-%d"`, p.Indent)
+	}
+	writeStyle(sb, styles)
+	sb.WriteString(">")
+	for _, item := range p.Content {
+		renderInline(sb, item)
+	}
+	sb.WriteString("</p>")
+}
+
+func renderParagraphs(sb *strings.Builder, paragraphs []Paragraph) {
+	if len(paragraphs) == 0 {
+		sb.WriteString("<p></p>")
+		return
+	}
+	for i := range paragraphs {
+		renderParagraph(sb, &paragraphs[i])
+	}
+}
+
+func renderInline(sb *strings.Builder, item any) {
+	switch n := item.(type) {
+	case Text:
+		renderText(sb, &n)
+	case *Text:
+		renderText(sb, n)
+	case HardBreak, *HardBreak:
+		sb.WriteString("<br>")
+	case Image:
+		renderImage(sb, &n)
+	case *Image:
+		renderImage(sb, n)
+	case Drawio:
+		renderDrawio(sb, &n)
+	case *Drawio:
+		renderDrawio(sb, n)
+	case DateNode:
+		renderDateNode(sb, &n)
+	case *DateNode:
+		renderDateNode(sb, n)
+	case Mention:
+		renderMention(sb, &n)
+	case *Mention:
+		renderMention(sb, n)
+	case IssueLinkMention:
+		renderIssueLinkMention(sb, &n)
+	case *IssueLinkMention:
+		renderIssueLinkMention(sb, n)
+	default:
+		slog.Debug("Unknown paragraph content type for HTML rendering", "type", fmt.Sprintf("%T", n))
+	}
+}
+
+// renderText оборачивает текст в теги отметок в порядке, который понимает getText
+func renderText(sb *strings.Builder, t *Text) {
+	if t.Content == "" {
+		return
+	}
+
+	var open, closing []string
+	wrap := func(openTag, tag string) {
+		open = append(open, openTag)
+		closing = append([]string{"</" + tag + ">"}, closing...)
+	}
+
+	if t.URL != nil {
+		wrap(fmt.Sprintf(`<a target="_blank" rel="noopener noreferrer nofollow" href="%s">`, html.EscapeString(t.URL.String())), "a")
+	}
+	var styles []string
+	if t.Size > 0 {
+		styles = append(styles, fmt.Sprintf("font-size: %dpx", t.Size))
+	}
+	if t.Color != nil {
+		styles = append(styles, "color: "+cssColor(*t.Color))
+	}
+	if len(styles) > 0 {
+		wrap(`<span style="`+strings.Join(styles, "; ")+`">`, "span")
+	}
+	if t.BgColor != nil {
+		c := cssColor(*t.BgColor)
+		wrap(fmt.Sprintf(`<mark data-color="%s" style="background-color: %s; color: inherit">`, c, c), "mark")
+	}
+	for _, mark := range []struct {
+		on  bool
+		tag string
+	}{
+		{t.Strong, "strong"},
+		{t.Italic, "em"},
+		{t.Underlined, "u"},
+		{t.Strikethrough, "s"},
+		{t.Sup, "sup"},
+		{t.Sub, "sub"},
+		{t.Code, "code"},
+	} {
+		if mark.on {
+			wrap("<"+mark.tag+">", mark.tag)
+		}
+	}
+
+	sb.WriteString(strings.Join(open, ""))
+	sb.WriteString(html.EscapeString(t.Content))
+	sb.WriteString(strings.Join(closing, ""))
+}
+
+func renderImage(sb *strings.Builder, img *Image) {
+	if img.Src == nil {
+		return
+	}
+	fmt.Fprintf(sb, `<img src="%s"`, html.EscapeString(img.Src.String()))
+	if img.Width > 0 {
+		fmt.Fprintf(sb, ` width="%d" style="width: %dpx"`, img.Width, img.Width)
+	}
+	sb.WriteString(">")
+}
+
+func renderDrawio(sb *strings.Builder, d *Drawio) {
+	if d.Src == nil {
+		return
+	}
+	class := d.Class
+	if class == "" {
+		class = "drawio"
+	}
+	fmt.Fprintf(sb, `<img src="%s" class="%s"`, html.EscapeString(d.Src.String()), html.EscapeString(class))
+	if d.Width > 0 {
+		fmt.Fprintf(sb, ` width="%d"`, d.Width)
+	}
+	sb.WriteString(">")
+}
+
+func renderDateNode(sb *strings.Builder, d *DateNode) {
+	date := html.EscapeString(d.Date)
+	fmt.Fprintf(sb, `<span data-type="date-node" data-date="%s" class="date-node">%s</span>`, date, date)
+}
+
+// renderMention записывает упоминание. ID упоминания - username, по тексту @username находятся упомянутые пользователи
+func renderMention(sb *strings.Builder, m *Mention) {
+	name := m.ID
+	if name == "" {
+		name = m.Label
+	}
+	fmt.Fprintf(sb, `<span class="mention" data-type="mention" data-id="%s" data-label="%s">@%s</span>`,
+		html.EscapeString(m.ID), html.EscapeString(m.Label), html.EscapeString(name))
+}
+
+func renderIssueLinkMention(sb *strings.Builder, ilm *IssueLinkMention) {
+	fmt.Fprintf(sb, `<span data-type="issueLinkMention" data-slug="%s" data-original-url="%s" data-project-identifier="%s" data-current-issue-id="%s" class="special-link-mention issue-link">%s-%s</span>`,
+		html.EscapeString(ilm.Slug),
+		html.EscapeString(ilm.OriginalUrl),
+		html.EscapeString(ilm.ProjectIdentifier),
+		html.EscapeString(ilm.CurrentIssueId),
+		html.EscapeString(ilm.ProjectIdentifier),
+		html.EscapeString(ilm.CurrentIssueId))
+}
+
+func renderCode(sb *strings.Builder, c *Code) {
+	if c.Language != "" {
+		fmt.Fprintf(sb, `<pre><code class="language-%s">`, html.EscapeString(c.Language))
+	} else {
+		sb.WriteString("<pre><code>")
+	}
+	sb.WriteString(html.EscapeString(c.Content))
+	sb.WriteString("</code></pre>")
+}
+
+func renderQuote(sb *strings.Builder, q *Quote) {
+	sb.WriteString("<blockquote>")
+	renderParagraphs(sb, q.Content)
+	sb.WriteString("</blockquote>")
+}
+
+func renderList(sb *strings.Builder, l *List) {
+	switch {
+	case l.TaskList:
+		sb.WriteString(`<ul data-type="taskList">`)
+	case l.Numbered:
+		sb.WriteString("<ol>")
+	default:
+		sb.WriteString("<ul>")
+	}
+
+	for _, elem := range l.Elements {
+		if l.TaskList {
+			checked, input := "false", `<input type="checkbox">`
+			if elem.Checked {
+				checked, input = "true", `<input type="checkbox" checked="checked">`
+			}
+			fmt.Fprintf(sb, `<li data-checked="%s" data-type="taskItem"><label>%s<span></span></label><div>`, checked, input)
+			renderParagraphs(sb, elem.Content)
+			for i := range elem.Lists {
+				renderList(sb, &elem.Lists[i])
+			}
+			sb.WriteString("</div></li>")
+			continue
+		}
+		sb.WriteString("<li>")
+		renderParagraphs(sb, elem.Content)
+		for i := range elem.Lists {
+			renderList(sb, &elem.Lists[i])
+		}
+		sb.WriteString("</li>")
+	}
+
+	if l.Numbered && !l.TaskList {
+		sb.WriteString("</ol>")
+	} else {
+		sb.WriteString("</ul>")
+	}
+}
+
+func renderTable(sb *strings.Builder, t *Table) {
+	sb.WriteString("<table")
+	if t.MinWidth > 0 {
+		fmt.Fprintf(sb, ` style="min-width: %dpx"`, t.MinWidth)
+	}
+	sb.WriteString("><colgroup>")
+	cols := len(t.ColWidth)
+	for _, row := range t.Rows {
+		cols = max(cols, len(row))
+	}
+	for i := range cols {
+		if i < len(t.ColWidth) && t.ColWidth[i] > 0 {
+			fmt.Fprintf(sb, `<col style="width: %dpx">`, t.ColWidth[i])
+		} else {
+			sb.WriteString("<col>")
+		}
+	}
+	sb.WriteString("</colgroup><tbody>")
+	for _, row := range t.Rows {
+		sb.WriteString("<tr>")
+		for _, cell := range row {
+			tag := "td"
+			if cell.Header {
+				tag = "th"
+			}
+			fmt.Fprintf(sb, `<%s colspan="%d" rowspan="%d">`, tag, max(cell.ColSpan, 1), max(cell.RowSpan, 1))
+			renderParagraphs(sb, cell.Content)
+			sb.WriteString("</" + tag + ">")
+		}
+		sb.WriteString("</tr>")
+	}
+	sb.WriteString("</tbody></table>")
+}
+
+func renderSpoiler(sb *strings.Builder, s *Spoiler) {
+	fmt.Fprintf(sb, `<div data-spoiler="" data-title="%s" data-collapsed="%t"`, html.EscapeString(s.Title), s.Collapsed)
+	var styles []string
+	if s.BgColor != (Color{}) {
+		styles = append(styles, "background-color: "+cssColor(s.BgColor))
+	}
+	if s.Color != (Color{}) {
+		styles = append(styles, "color: "+cssColor(s.Color))
+	}
+	writeStyle(sb, styles)
+	sb.WriteString(">")
+	renderParagraphs(sb, s.Content)
+	sb.WriteString("</div>")
+}
+
+func renderInfoBlock(sb *strings.Builder, ib *InfoBlock) {
+	fmt.Fprintf(sb, `<div data-info-block="" data-title="%s"`, html.EscapeString(ib.Title))
+	if ib.Color != (Color{}) {
+		fmt.Fprintf(sb, ` data-icon-color="%s"`, cssColor(ib.Color))
+	}
+	sb.WriteString(">")
+	renderParagraphs(sb, ib.Content)
+	sb.WriteString("</div>")
+}
+
+func writeStyle(sb *strings.Builder, styles []string) {
+	if len(styles) > 0 {
+		fmt.Fprintf(sb, ` style="%s"`, strings.Join(styles, "; "))
+	}
+}
+
+// cssColor возвращает цвет в формате, который понимает ParseColor: rgb() или #RRGGBBAA для полупрозрачных цветов
+func cssColor(c Color) string {
+	if c.A != 0 {
+		return fmt.Sprintf("#%02X%02X%02X%02X", c.R, c.G, c.B, c.A)
+	}
+	return fmt.Sprintf("rgb(%d, %d, %d)", c.R, c.G, c.B)
+}
+
+func textAlignName(align TextAlign) string {
+	switch align {
+	case CenterAlign:
+		return "center"
+	case RightAlign:
+		return "right"
+	}
+	return "left"
+}
@@ -0,0 +1,567 @@
+package markdown
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor/edtypes"
+)
+
+var (
+	mentionRe  = regexp.MustCompile(`^@(\w+)`)
+	issueRefRe = regexp.MustCompile(`^([A-Z][A-Z0-9]{2,14})-([0-9]+)`)
+	bareURLRe  = regexp.MustCompile(`^https?://[^\s<>]+`)
+	autolinkRe = regexp.MustCompile(`^<([A-Za-z][A-Za-z0-9+.-]{1,31}:[^\s<>]*)>`)
+	breakTagRe = regexp.MustCompile(`^<br\s*/?>`)
+)
+
+// inlineTags - поддерживаемые HTML теги выделения и соответствующие им отметки текста
+var inlineTags = map[string]func(t *edtypes.Text){
+	"u":      func(t *edtypes.Text) { t.Underlined = true },
+	"sup":    func(t *edtypes.Text) { t.Sup = true },
+	"sub":    func(t *edtypes.Text) { t.Sub = true },
+	"s":      func(t *edtypes.Text) { t.Strikethrough = true },
+	"del":    func(t *edtypes.Text) { t.Strikethrough = true },
+	"b":      func(t *edtypes.Text) { t.Strong = true },
+	"strong": func(t *edtypes.Text) { t.Strong = true },
+	"i":      func(t *edtypes.Text) { t.Italic = true },
+	"em":     func(t *edtypes.Text) { t.Italic = true },
+}
+
+// inlineParser разбирает inline разметку параграфа, ячейки таблицы или заголовка
+type inlineParser struct {
+	opts *ParseOptions
+}
+
+// parse разбирает строку s, применяя к тексту отметки шаблона tpl
+func (p *inlineParser) parse(s string, tpl edtypes.Text) []any {
+	var out []any
+	var buf strings.Builder
+
+	flush := func() {
+		if buf.Len() > 0 {
+			t := tpl
+			t.Content = buf.String()
+			out = appendInline(out, t)
+			buf.Reset()
+		}
+	}
+	emit := func(items ...any) {
+		flush()
+		for _, item := range items {
+			out = appendInline(out, item)
+		}
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			if s[i+1] == '\n' {
+				emit(&edtypes.HardBreak{})
+				i = skipSpaces(s, i+2)
+				continue
+			}
+			if isASCIIPunct(s[i+1]) {
+				buf.WriteByte(s[i+1])
+				i += 2
+				continue
+			}
+
+		case c == '\n':
+			// Два пробела в конце строки - жесткий перенос, иначе перенос строки - это пробел
+			str := buf.String()
+			trimmed := strings.TrimRight(str, " ")
+			buf.Reset()
+			buf.WriteString(trimmed)
+			if len(str)-len(trimmed) >= 2 {
+				emit(&edtypes.HardBreak{})
+			} else {
+				buf.WriteByte(' ')
+			}
+			i = skipSpaces(s, i+1)
+			continue
+
+		case c == '`':
+			if content, end, ok := parseCodeSpan(s, i); ok {
+				t := tpl
+				t.Code = true
+				t.Content = content
+				emit(t)
+				i = end
+				continue
+			}
+			n := runLen(s, i, '`')
+			buf.WriteString(s[i : i+n])
+			i += n
+			continue
+
+		case c == '!' && strings.HasPrefix(s[i+1:], "["):
+			if _, dest, end, ok := parseLink(s, i+1); ok {
+				if u, err := url.Parse(dest); err == nil {
+					emit(&edtypes.Image{Src: u})
+					i = end
+					continue
+				}
+			}
+
+		case c == '[' && tpl.URL == nil:
+			if text, dest, end, ok := parseLink(s, i); ok {
+				if u, err := url.Parse(dest); err == nil {
+					lt := tpl
+					lt.URL = u
+					emit(p.parse(text, lt)...)
+					i = end
+					continue
+				}
+			}
+
+		case c == '<':
+			if m := breakTagRe.FindString(s[i:]); m != "" {
+				emit(&edtypes.HardBreak{})
+				i += len(m)
+				continue
+			}
+			if m := autolinkRe.FindStringSubmatch(s[i:]); m != nil && tpl.URL == nil {
+				if u, err := url.Parse(m[1]); err == nil {
+					lt := tpl
+					lt.URL = u
+					lt.Content = m[1]
+					emit(lt)
+					i += len(m[0])
+					continue
+				}
+			}
+			if inner, end, apply, ok := parseInlineTag(s, i); ok {
+				t := tpl
+				apply(&t)
+				emit(p.parse(inner, t)...)
+				i = end
+				continue
+			}
+
+		case c == '*' || c == '_':
+			if inner, k, end, ok := parseEmphasis(s, i); ok {
+				t := tpl
+				if k != 2 {
+					t.Italic = true
+				}
+				if k >= 2 {
+					t.Strong = true
+				}
+				emit(p.parse(inner, t)...)
+				i = end
+				continue
+			}
+			n := runLen(s, i, c)
+			if !isLeftFlanking(s, i, n, c) {
+				buf.WriteString(s[i : i+n])
+				i += n
+				continue
+			}
+
+		case c == '~':
+			n := runLen(s, i, '~')
+			if n == 2 && isLeftFlanking(s, i, n, c) {
+				if q := findCloser(s, i+2, '~', 2); q >= 0 {
+					t := tpl
+					t.Strikethrough = true
+					emit(p.parse(s[i+2:q], t)...)
+					i = q + 2
+					continue
+				}
+			}
+			buf.WriteString(s[i : i+n])
+			i += n
+			continue
+
+		case c == '@' && tpl.URL == nil && !isWordBefore(s, i):
+			if m := mentionRe.FindStringSubmatch(s[i:]); m != nil {
+				if mention := p.mention(m[1]); mention != nil {
+					emit(mention)
+					i += len(m[0])
+					continue
+				}
+			}
+
+		case c >= 'A' && c <= 'Z' && tpl.URL == nil && !isWordBefore(s, i) && !strings.HasSuffix(s[:i], "-"):
+			if m := issueRefRe.FindStringSubmatch(s[i:]); m != nil && !isWordAt(s, i+len(m[0])) {
+				if issue := p.issueLink(m[1], m[2]); issue != nil {
+					emit(issue)
+					i += len(m[0])
+					continue
+				}
+			}
+
+		case c == 'h' && tpl.URL == nil && !isWordBefore(s, i):
+			if m := bareURLRe.FindString(s[i:]); m != "" {
+				m = trimURLPunct(m)
+				if u, err := url.Parse(m); err == nil {
+					lt := tpl
+					lt.URL = u
+					lt.Content = m
+					emit(lt)
+					i += len(m)
+					continue
+				}
+			}
+		}
+
+		buf.WriteByte(c)
+		i++
+	}
+	flush()
+
+	return out
+}
+
+// mention создает упоминание пользователя или nil, если пользователь не найден
+func (p *inlineParser) mention(username string) *edtypes.Mention {
+	if p.opts != nil && p.opts.ResolveMention != nil && !p.opts.ResolveMention(username) {
+		return nil
+	}
+	return &edtypes.Mention{ID: username, Label: username}
+}
+
+// issueLink создает ссылку на задачу или nil, если задача не найдена
+func (p *inlineParser) issueLink(projectIdentifier, seq string) *edtypes.IssueLinkMention {
+	sequenceId, err := strconv.Atoi(seq)
+	if err != nil || sequenceId <= 0 {
+		return nil
+	}
+	if p.opts == nil || p.opts.ResolveIssue == nil {
+		return &edtypes.IssueLinkMention{ProjectIdentifier: projectIdentifier, CurrentIssueId: strconv.Itoa(sequenceId)}
+	}
+	issue, ok := p.opts.ResolveIssue(projectIdentifier, sequenceId)
+	if !ok {
+		return nil
+	}
+	return issue
+}
+
+// appendInline добавляет элемент в содержимое параграфа, объединяя соседние тексты с одинаковым форматированием
+func appendInline(out []any, item any) []any {
+	t, ok := item.(edtypes.Text)
+	if !ok {
+		return append(out, item)
+	}
+	if t.Content == "" {
+		return out
+	}
+	if len(out) > 0 {
+		if last, ok := out[len(out)-1].(edtypes.Text); ok && sameMarks(last, t) {
+			last.Content += t.Content
+			out[len(out)-1] = last
+			return out
+		}
+	}
+	return append(out, t)
+}
+
+// sameMarks сравнивает форматирование двух текстов
+func sameMarks(a, b edtypes.Text) bool {
+	return a.Size == b.Size &&
+		a.Strong == b.Strong &&
+		a.Italic == b.Italic &&
+		a.Underlined == b.Underlined &&
+		a.Strikethrough == b.Strikethrough &&
+		a.Sup == b.Sup &&
+		a.Sub == b.Sub &&
+		a.Code == b.Code &&
+		a.Align == b.Align &&
+		sameColor(a.Color, b.Color) &&
+		sameColor(a.BgColor, b.BgColor) &&
+		linkString(a.URL) == linkString(b.URL)
+}
+
+func sameColor(a, b *edtypes.Color) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func linkString(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	return u.String()
+}
+
+// parseCodeSpan разбирает `code` начиная с позиции i
+func parseCodeSpan(s string, i int) (string, int, bool) {
+	n := runLen(s, i, '`')
+	for j := i + n; j < len(s); {
+		if s[j] != '`' {
+			j++
+			continue
+		}
+		m := runLen(s, j, '`')
+		if m == n {
+			content := strings.ReplaceAll(s[i+n:j], "\n", " ")
+			if len(content) >= 2 && content[0] == ' ' && content[len(content)-1] == ' ' && strings.Trim(content, " ") != "" {
+				content = content[1 : len(content)-1]
+			}
+			return content, j + m, true
+		}
+		j += m
+	}
+	return "", 0, false
+}
+
+// parseLink разбирает [текст](адрес "заголовок") начиная с позиции открывающей скобки
+func parseLink(s string, i int) (text, dest string, end int, ok bool) {
+	depth := 0
+	k := -1
+	for j := i; j < len(s) && k < 0; j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '`':
+			if _, e, ok := parseCodeSpan(s, j); ok {
+				j = e - 1
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				k = j
+			}
+		}
+	}
+	if k < 0 || k+1 >= len(s) || s[k+1] != '(' {
+		return "", "", 0, false
+	}
+
+	j := skipSpaces(s, k+2)
+	var raw strings.Builder
+	if j < len(s) && s[j] == '<' {
+		e := strings.IndexByte(s[j:], '>')
+		if e < 0 {
+			return "", "", 0, false
+		}
+		raw.WriteString(s[j+1 : j+e])
+		j += e + 1
+	} else {
+		parens := 0
+	loop:
+		for ; j < len(s); j++ {
+			switch c := s[j]; {
+			case c == '\\' && j+1 < len(s) && isASCIIPunct(s[j+1]):
+				raw.WriteByte(s[j+1])
+				j++
+			case c == ' ' || c == '\n':
+				break loop
+			case c == '(':
+				parens++
+				raw.WriteByte(c)
+			case c == ')':
+				if parens == 0 {
+					break loop
+				}
+				parens--
+				raw.WriteByte(c)
+			default:
+				raw.WriteByte(c)
+			}
+		}
+	}
+
+	// Заголовок ссылки в редакторе не хранится - пропускаем
+	j = skipSpaces(s, j)
+	if j < len(s) && (s[j] == '"' || s[j] == '\'') {
+		e := strings.IndexByte(s[j+1:], s[j])
+		if e < 0 {
+			return "", "", 0, false
+		}
+		j = skipSpaces(s, j+e+2)
+	}
+	if j >= len(s) || s[j] != ')' {
+		return "", "", 0, false
+	}
+	return s[i+1 : k], raw.String(), j + 1, true
+}
+
+// parseInlineTag разбирает поддерживаемый HTML тег выделения (<u>текст</u>)
+func parseInlineTag(s string, i int) (inner string, end int, apply func(t *edtypes.Text), ok bool) {
+	e := strings.IndexByte(s[i:], '>')
+	if e < 0 {
+		return "", 0, nil, false
+	}
+	name := strings.ToLower(s[i+1 : i+e])
+	apply, ok = inlineTags[name]
+	if !ok {
+		return "", 0, nil, false
+	}
+	closeTag := "</" + name + ">"
+	c := strings.Index(strings.ToLower(s[i+e+1:]), closeTag)
+	if c < 0 {
+		return "", 0, nil, false
+	}
+	start := i + e + 1
+	return s[start : start+c], start + c + len(closeTag), apply, true
+}
+
+// parseEmphasis разбирает выделение *курсив*, **жирный**, ***жирный курсив*** (и аналоги с _).
+// Возвращает содержимое, длину разделителя (1 - курсив, 2 - жирный, 3 - оба) и конец выделения.
+func parseEmphasis(s string, i int) (string, int, int, bool) {
+	c := s[i]
+	n := runLen(s, i, c)
+	if !isLeftFlanking(s, i, n, c) {
+		return "", 0, 0, false
+	}
+
+	var sizes []int
+	switch {
+	case n >= 3:
+		// Порядок вложенности определяется первым закрывающим разделителем:
+		// ***a*b** - жирный снаружи, ***a**b* - курсив снаружи
+		switch firstCloserLen(s, i+3, c) {
+		case 1:
+			sizes = []int{2, 1, 3}
+		case 2:
+			sizes = []int{1, 2, 3}
+		default:
+			sizes = []int{3, 2, 1}
+		}
+	case n == 2:
+		sizes = []int{2, 1}
+	default:
+		sizes = []int{1}
+	}
+
+	for _, k := range sizes {
+		if q := findCloser(s, i+k, c, k); q >= 0 {
+			return s[i+k : q], k, q + k, true
+		}
+	}
+	return "", 0, 0, false
+}
+
+// findCloser ищет закрывающий разделитель длины k символа c начиная с позиции start.
+// Серия из трех символов может закрыть одно- и двухсимвольное выделение (вложенное в нее).
+func findCloser(s string, start int, c byte, k int) int {
+	for j := start; j < len(s); {
+		switch {
+		case s[j] == '\\':
+			j += 2
+			continue
+		case s[j] == '`':
+			if _, e, ok := parseCodeSpan(s, j); ok {
+				j = e
+				continue
+			}
+		case s[j] == c:
+			m := runLen(s, j, c)
+			if (m == k || m == 3) && j > start && isRightFlanking(s, j, m, c) {
+				return j + m - k
+			}
+			j += m
+			continue
+		}
+		j++
+	}
+	return -1
+}
+
+// firstCloserLen возвращает длину первой закрывающей серии символа c (0 - не найдена)
+func firstCloserLen(s string, start int, c byte) int {
+	for j := start; j < len(s); {
+		switch {
+		case s[j] == '\\':
+			j += 2
+			continue
+		case s[j] == c:
+			m := runLen(s, j, c)
+			if j > start && isRightFlanking(s, j, m, c) {
+				return m
+			}
+			j += m
+			continue
+		}
+		j++
+	}
+	return 0
+}
+
+// isLeftFlanking проверяет, может ли серия разделителей открывать выделение
+func isLeftFlanking(s string, i, n int, c byte) bool {
+	if i+n >= len(s) {
+		return false
+	}
+	next, _ := utf8.DecodeRuneInString(s[i+n:])
+	if unicode.IsSpace(next) {
+		return false
+	}
+	// _ внутри слова (snake_case) не является выделением
+	return c != '_' || !isWordBefore(s, i)
+}
+
+// isRightFlanking проверяет, может ли серия разделителей закрывать выделение
+func isRightFlanking(s string, j, m int, c byte) bool {
+	prev, _ := utf8.DecodeLastRuneInString(s[:j])
+	if unicode.IsSpace(prev) {
+		return false
+	}
+	return c != '_' || !isWordAt(s, j+m)
+}
+
+// isWordBefore проверяет, что перед позицией i стоит буква, цифра или _
+func isWordBefore(s string, i int) bool {
+	if i == 0 {
+		return false
+	}
+	r, _ := utf8.DecodeLastRuneInString(s[:i])
+	return isWordRune(r)
+}
+
+// isWordAt проверяет, что в позиции i стоит буква, цифра или _
+func isWordAt(s string, i int) bool {
+	if i >= len(s) {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	return isWordRune(r)
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isASCIIPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+// trimURLPunct убирает из автоматически найденной ссылки завершающую пунктуацию предложения
+func trimURLPunct(u string) string {
+	for len(u) > 0 {
+		last := u[len(u)-1]
+		if strings.IndexByte(".,:;!?'\"", last) >= 0 ||
+			(last == ')' && strings.Count(u, "(") < strings.Count(u, ")")) {
+			u = u[:len(u)-1]
+			continue
+		}
+		break
+	}
+	return u
+}
+
+func runLen(s string, i int, c byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == c {
+		n++
+	}
+	return n
+}
+
+func skipSpaces(s string, i int) int {
+	for i < len(s) && (s[i] == ' ' || s[i] == '\t') {
+		i++
+	}
+	return i
+}
@@ -0,0 +1,504 @@
+package markdown
+
+import (
+	"html"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor/edtypes"
+)
+
+var (
+	fenceRe     = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})(.*)$")
+	headingRe   = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	thematicRe  = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	setextRe    = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	quoteRe     = regexp.MustCompile(`^ {0,3}> ?(.*)$`)
+	calloutRe   = regexp.MustCompile(`^\[!(\w+)\][ \t]*(.*)$`)
+	detailsRe   = regexp.MustCompile(`(?i)^\s*<details(\s+open)?\s*>\s*$`)
+	detailsEnd  = regexp.MustCompile(`(?i)^\s*</details>\s*$`)
+	summaryRe   = regexp.MustCompile(`(?i)^\s*<summary>(.*)</summary>\s*$`)
+	delimRowRe  = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	listMarkRe  = regexp.MustCompile(`^( *)([-*+]|\d{1,9}[.)])([ \t]+|$)`)
+	taskMarkRe  = regexp.MustCompile(`^\[([ xX])\](?:[ \t]+|$)`)
+	orderedOne  = regexp.MustCompile(`^ {0,3}1[.)]`)
+	leadingTabs = regexp.MustCompile(`^[ \t]+`)
+)
+
+// ParseMarkdown разбирает Markdown в документ редактора.
+// opts может быть nil - тогда все упоминания и ссылки на задачи преобразуются без проверки.
+func ParseMarkdown(r io.Reader, opts *ParseOptions) (*edtypes.Document, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+	lines := strings.Split(text, "\n")
+
+	// Табуляция в отступах заменяется пробелами, кроме строк внутри блоков кода
+	fence := ""
+	for i, line := range lines {
+		if m := fenceRe.FindStringSubmatch(line); m != nil {
+			switch {
+			case fence == "" && isFenceOpen(line):
+				fence = m[2]
+			case fence != "" && m[2][0] == fence[0] && len(m[2]) >= len(fence) && strings.TrimSpace(m[3]) == "":
+				fence = ""
+			}
+			continue
+		}
+		if fence != "" {
+			continue
+		}
+		lines[i] = leadingTabs.ReplaceAllStringFunc(line, func(s string) string {
+			return strings.ReplaceAll(s, "\t", "    ")
+		})
+	}
+
+	p := &blockParser{inline: &inlineParser{opts: opts}}
+	return &edtypes.Document{Elements: p.parseBlocks(lines)}, nil
+}
+
+// ParseString разбирает Markdown строку в документ редактора
+func ParseString(s string, opts *ParseOptions) (*edtypes.Document, error) {
+	return ParseMarkdown(strings.NewReader(s), opts)
+}
+
+// blockParser разбирает блочную структуру Markdown
+type blockParser struct {
+	inline *inlineParser
+}
+
+// parseBlocks разбирает строки в список элементов документа
+func (p *blockParser) parseBlocks(lines []string) []any {
+	elements := make([]any, 0)
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		var elem any
+
+		switch {
+		case isBlank(line):
+			i++
+			continue
+		case fenceRe.MatchString(line) && isFenceOpen(line):
+			elem, i = p.parseFencedCode(lines, i)
+		case headingRe.MatchString(line):
+			m := headingRe.FindStringSubmatch(line)
+			elem = p.heading(m[2], len(m[1]))
+			i++
+		case thematicRe.MatchString(line):
+			// Горизонтальной линии в редакторе нет
+			i++
+			continue
+		case quoteRe.MatchString(line):
+			elem, i = p.parseQuote(lines, i)
+		case detailsRe.MatchString(line):
+			elem, i = p.parseDetails(lines, i)
+		case isTableStart(lines, i):
+			elem, i = p.parseTable(lines, i)
+		case isListStart(line):
+			elem, i = p.parseList(lines, i)
+		case indentOf(line) >= 4:
+			elem, i = p.parseIndentedCode(lines, i)
+		default:
+			elem, i = p.parseParagraph(lines, i)
+		}
+
+		if elem != nil {
+			elements = append(elements, elem)
+		}
+	}
+	return elements
+}
+
+// heading создает параграф-заголовок: жирный текст размера, соответствующего уровню
+func (p *blockParser) heading(text string, level int) any {
+	content := p.inline.parse(strings.TrimSpace(text), edtypes.Text{Strong: true, Size: headingSizes[level]})
+	if len(content) == 0 {
+		return nil
+	}
+	return &edtypes.Paragraph{Content: content}
+}
+
+// paragraph разбирает текст параграфа. Параграф из одного изображения становится изображением
+func (p *blockParser) paragraph(text string) any {
+	content := p.inline.parse(text, edtypes.Text{})
+	if len(content) == 0 {
+		return nil
+	}
+	if len(content) == 1 {
+		if img, ok := content[0].(*edtypes.Image); ok {
+			return img
+		}
+	}
+	return &edtypes.Paragraph{Content: content}
+}
+
+// parseParagraph собирает строки параграфа до пустой строки или начала другого блока
+func (p *blockParser) parseParagraph(lines []string, i int) (any, int) {
+	var buf []string
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if isBlank(line) {
+			break
+		}
+		if len(buf) > 0 {
+			if m := setextRe.FindStringSubmatch(line); m != nil {
+				level := 1
+				if m[1][0] == '-' {
+					level = 2
+				}
+				return p.heading(strings.Join(buf, "\n"), level), i + 1
+			}
+			if interruptsParagraph(lines, i) {
+				break
+			}
+		}
+		buf = append(buf, strings.TrimLeft(line, " "))
+	}
+
+	text := strings.TrimRight(strings.Join(buf, "\n"), " ")
+	return p.paragraph(text), i
+}
+
+// parseFencedCode разбирает блок кода в ```
+func (p *blockParser) parseFencedCode(lines []string, i int) (any, int) {
+	m := fenceRe.FindStringSubmatch(lines[i])
+	indent, fence := len(m[1]), m[2]
+	// Язык - первое слово информационной строки
+	language := ""
+	if info := strings.Fields(html.UnescapeString(m[3])); len(info) > 0 {
+		language = info[0]
+	}
+
+	var code []string
+	for i++; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if indentOf(line) < 4 && strings.HasPrefix(trimmed, fence[:1]) &&
+			runLen(trimmed, 0, fence[0]) >= len(fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			i++
+			break
+		}
+		// Отступ открывающего разделителя снимается со строк кода
+		code = append(code, strings.TrimPrefix(line, strings.Repeat(" ", min(indent, indentOf(line)))))
+	}
+	return &edtypes.Code{Content: strings.Join(code, "\n"), Language: language}, i
+}
+
+// parseIndentedCode разбирает блок кода с отступом в 4 пробела
+func (p *blockParser) parseIndentedCode(lines []string, i int) (any, int) {
+	var code []string
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if !isBlank(line) && indentOf(line) < 4 {
+			break
+		}
+		if len(line) >= 4 {
+			line = line[4:]
+		} else {
+			line = ""
+		}
+		code = append(code, line)
+	}
+	for len(code) > 0 && strings.TrimSpace(code[len(code)-1]) == "" {
+		code = code[:len(code)-1]
+	}
+	return &edtypes.Code{Content: strings.Join(code, "\n")}, i
+}
+
+// parseQuote разбирает цитату. Цитата с маркером [!INFO] становится информационным блоком
+func (p *blockParser) parseQuote(lines []string, i int) (any, int) {
+	var inner []string
+	for ; i < len(lines); i++ {
+		m := quoteRe.FindStringSubmatch(lines[i])
+		if m == nil {
+			break
+		}
+		inner = append(inner, m[1])
+	}
+
+	if m := calloutRe.FindStringSubmatch(strings.TrimSpace(inner[0])); m != nil {
+		return &edtypes.InfoBlock{
+			Title:   strings.TrimSpace(m[2]),
+			Content: flattenParagraphs(p.parseBlocks(inner[1:])),
+		}, i
+	}
+	return &edtypes.Quote{Content: flattenParagraphs(p.parseBlocks(inner))}, i
+}
+
+// parseDetails разбирает <details><summary>Заголовок</summary>...</details> в спойлер
+func (p *blockParser) parseDetails(lines []string, i int) (any, int) {
+	spoiler := &edtypes.Spoiler{Collapsed: detailsRe.FindStringSubmatch(lines[i])[1] == ""}
+
+	var inner []string
+	depth := 1
+	for i++; i < len(lines); i++ {
+		line := lines[i]
+		if detailsRe.MatchString(line) {
+			depth++
+		} else if detailsEnd.MatchString(line) {
+			depth--
+			if depth == 0 {
+				i++
+				break
+			}
+		}
+		if m := summaryRe.FindStringSubmatch(line); m != nil && depth == 1 && spoiler.Title == "" && len(inner) == 0 {
+			spoiler.Title = html.UnescapeString(strings.TrimSpace(m[1]))
+			continue
+		}
+		inner = append(inner, line)
+	}
+
+	spoiler.Content = flattenParagraphs(p.parseBlocks(inner))
+	return spoiler, i
+}
+
+// parseTable разбирает GFM таблицу. Первая строка - заголовок
+func (p *blockParser) parseTable(lines []string, i int) (any, int) {
+	header := splitTableRow(lines[i])
+	table := &edtypes.Table{}
+	table.Rows = append(table.Rows, p.tableRow(header, len(header), true))
+
+	for i += 2; i < len(lines); i++ {
+		line := lines[i]
+		if isBlank(line) || !strings.Contains(line, "|") || interruptsParagraph(lines, i) {
+			break
+		}
+		table.Rows = append(table.Rows, p.tableRow(splitTableRow(line), len(header), false))
+	}
+	return table, i
+}
+
+// tableRow создает строку таблицы из width ячеек
+func (p *blockParser) tableRow(cells []string, width int, header bool) []edtypes.TableCell {
+	row := make([]edtypes.TableCell, width)
+	for j := range row {
+		row[j] = edtypes.TableCell{ColSpan: 1, RowSpan: 1, Header: header, Content: []edtypes.Paragraph{{}}}
+		if j >= len(cells) {
+			continue
+		}
+		if content := p.inline.parse(strings.TrimSpace(cells[j]), edtypes.Text{}); len(content) > 0 {
+			row[j].Content = []edtypes.Paragraph{{Content: content}}
+		}
+	}
+	return row
+}
+
+// listItem - элемент списка в процессе разбора
+type listItem struct {
+	// строки элемента без маркера и отступа его содержимого
+	lines []string
+}
+
+// parseList разбирает маркированный, нумерованный список или чек-лист.
+// Строки элемента с отступом его содержимого разбираются как блоки: вложенные списки сохраняются
+// в элементе, остальные блоки преобразуются в его параграфы
+func (p *blockParser) parseList(lines []string, i int) (any, int) {
+	first := listMarkRe.FindStringSubmatch(lines[i])
+	ordered := first[2][0] >= '0' && first[2][0] <= '9'
+	bullet := first[2][len(first[2])-1]
+
+	var items []*listItem
+	var cur *listItem
+	contentIndent := 0
+	blank := false
+
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if isBlank(line) {
+			if cur != nil {
+				cur.lines = append(cur.lines, "")
+			}
+			blank = true
+			continue
+		}
+
+		indent := indentOf(line)
+		if m := listMarkRe.FindStringSubmatch(line); m != nil && !thematicRe.MatchString(line) && (cur == nil || indent < contentIndent) {
+			if cur != nil {
+				// Маркер другого типа на том же уровне начинает новый список
+				if m[2][len(m[2])-1] != bullet {
+					break
+				}
+			} else if indent > 3 {
+				break
+			}
+			offset := len(m[0])
+			if m[3] == "" || len(m[3]) > 4 {
+				offset = len(m[1]) + len(m[2]) + 1
+			}
+			contentIndent = offset
+			cur = &listItem{}
+			items = append(items, cur)
+			content := ""
+			if offset < len(line) {
+				content = strings.TrimLeft(line[offset:], " ")
+			}
+			cur.lines = append(cur.lines, content)
+			blank = false
+			continue
+		}
+
+		switch {
+		case indent >= contentIndent:
+			cur.lines = append(cur.lines, line[contentIndent:])
+		case blank || interruptsParagraph(lines, i):
+			return p.listFromItems(items, ordered), i
+		default:
+			// Ленивое продолжение параграфа без отступа
+			cur.lines = append(cur.lines, strings.TrimLeft(line, " "))
+		}
+		blank = false
+	}
+	return p.listFromItems(items, ordered), i
+}
+
+// listFromItems создает список из разобранных элементов. Чек-лист определяется по первому элементу
+func (p *blockParser) listFromItems(items []*listItem, ordered bool) *edtypes.List {
+	list := &edtypes.List{Numbered: ordered}
+	for n, item := range items {
+		elem := edtypes.ListElement{}
+		if m := taskMarkRe.FindStringSubmatch(item.lines[0]); m != nil {
+			if n == 0 {
+				list.TaskList = true
+				list.Numbered = false
+			}
+			if list.TaskList {
+				elem.Checked = m[1] != " "
+				item.lines[0] = item.lines[0][len(m[0]):]
+			}
+		}
+		for _, block := range p.parseBlocks(item.lines) {
+			switch b := block.(type) {
+			case *edtypes.Paragraph:
+				elem.Content = append(elem.Content, *b)
+			case *edtypes.List:
+				elem.Lists = append(elem.Lists, *b)
+			default:
+				elem.Content = append(elem.Content, flattenParagraphs([]any{b})...)
+			}
+		}
+		if len(elem.Content) == 0 {
+			elem.Content = []edtypes.Paragraph{{}}
+		}
+		list.Elements = append(list.Elements, elem)
+	}
+	return list
+}
+
+// flattenParagraphs преобразует блоки в параграфы для элементов, которые могут содержать только параграфы
+// (цитата, спойлер, информационный блок)
+func flattenParagraphs(elements []any) []edtypes.Paragraph {
+	var paragraphs []edtypes.Paragraph
+	for _, elem := range elements {
+		switch e := elem.(type) {
+		case *edtypes.Paragraph:
+			paragraphs = append(paragraphs, *e)
+		case *edtypes.Image:
+			paragraphs = append(paragraphs, edtypes.Paragraph{Content: []any{e}})
+		case *edtypes.Code:
+			paragraph := edtypes.Paragraph{}
+			for n, line := range strings.Split(e.Content, "\n") {
+				if n > 0 {
+					paragraph.Content = append(paragraph.Content, &edtypes.HardBreak{})
+				}
+				paragraph.Content = appendInline(paragraph.Content, edtypes.Text{Content: line, Code: true})
+			}
+			paragraphs = append(paragraphs, paragraph)
+		case *edtypes.List:
+			for _, item := range e.Elements {
+				paragraphs = append(paragraphs, item.Content...)
+				for i := range item.Lists {
+					paragraphs = append(paragraphs, flattenParagraphs([]any{&item.Lists[i]})...)
+				}
+			}
+		case *edtypes.Table:
+			for _, row := range e.Rows {
+				for _, cell := range row {
+					paragraphs = append(paragraphs, cell.Content...)
+				}
+			}
+		case *edtypes.Quote:
+			paragraphs = append(paragraphs, e.Content...)
+		case *edtypes.Spoiler:
+			paragraphs = append(paragraphs, e.Content...)
+		case *edtypes.InfoBlock:
+			paragraphs = append(paragraphs, e.Content...)
+		}
+	}
+	return paragraphs
+}
+
+// splitTableRow разбивает строку таблицы на ячейки по неэкранированным |
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, "\\|") {
+		line = line[:len(line)-1]
+	}
+
+	var cells []string
+	start := 0
+	for j := 0; j < len(line); j++ {
+		switch line[j] {
+		case '\\':
+			j++
+		case '|':
+			cells = append(cells, line[start:j])
+			start = j + 1
+		}
+	}
+	return append(cells, line[start:])
+}
+
+// interruptsParagraph проверяет, начинает ли строка новый блок, прерывающий параграф
+func interruptsParagraph(lines []string, i int) bool {
+	line := lines[i]
+	switch {
+	case fenceRe.MatchString(line) && isFenceOpen(line),
+		headingRe.MatchString(line),
+		thematicRe.MatchString(line),
+		quoteRe.MatchString(line),
+		detailsRe.MatchString(line),
+		isTableStart(lines, i):
+		return true
+	case isListStart(line):
+		// Нумерованный список прерывает параграф, только если начинается с 1
+		m := listMarkRe.FindStringSubmatch(line)
+		if m[3] == "" {
+			return false
+		}
+		return !(m[2][0] >= '0' && m[2][0] <= '9') || orderedOne.MatchString(line)
+	}
+	return false
+}
+
+// isFenceOpen проверяет, что строка открывает блок кода (info-строка ``` не может содержать `)
+func isFenceOpen(line string) bool {
+	m := fenceRe.FindStringSubmatch(line)
+	return m[2][0] != '`' || !strings.Contains(m[3], "`")
+}
+
+func isTableStart(lines []string, i int) bool {
+	return i+1 < len(lines) && strings.Contains(lines[i], "|") &&
+		delimRowRe.MatchString(lines[i+1]) && strings.Contains(lines[i+1]+lines[i], "|") &&
+		strings.Contains(lines[i+1], "-")
+}
+
+func isListStart(line string) bool {
+	m := listMarkRe.FindStringSubmatch(line)
+	return m != nil && len(m[1]) <= 3 && !thematicRe.MatchString(line)
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
@@ -0,0 +1,209 @@
+package markdown
+
+import (
+	"testing"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor/edtypes"
+)
+
+func mustParse(t *testing.T, md string, opts *ParseOptions) *edtypes.Document {
+	t.Helper()
+	doc, err := ParseString(md, opts)
+	if err != nil {
+		t.Fatalf("ParseString failed: %v", err)
+	}
+	return doc
+}
+
+func TestParseHeadingAndParagraph(t *testing.T) {
+	doc := mustParse(t, "# Title\n\nSome *italic* and **bold** text\nsecond line\n\nSetext\n---\n", nil)
+	if len(doc.Elements) != 3 {
+		t.Fatalf("Expected 3 elements, got %d", len(doc.Elements))
+	}
+
+	heading := doc.Elements[0].(*edtypes.Paragraph)
+	text := heading.Content[0].(edtypes.Text)
+	if text.Content != "Title" || !text.Strong || text.Size != 24 {
+		t.Errorf("Unexpected heading: %+v", text)
+	}
+
+	p := doc.Elements[1].(*edtypes.Paragraph)
+	want := []edtypes.Text{
+		{Content: "Some "},
+		{Content: "italic", Italic: true},
+		{Content: " and "},
+		{Content: "bold", Strong: true},
+		{Content: " text second line"},
+	}
+	if len(p.Content) != len(want) {
+		t.Fatalf("Expected %d runs, got %d: %+v", len(want), len(p.Content), p.Content)
+	}
+	for i, w := range want {
+		if got := p.Content[i].(edtypes.Text); !sameMarks(got, w) || got.Content != w.Content {
+			t.Errorf("Run %d: expected %+v, got %+v", i, w, got)
+		}
+	}
+
+	if text := doc.Elements[2].(*edtypes.Paragraph).Content[0].(edtypes.Text); text.Size != 20 || text.Content != "Setext" {
+		t.Errorf("Unexpected setext heading: %+v", text)
+	}
+}
+
+func TestParseInlineMarks(t *testing.T) {
+	tests := []struct {
+		md   string
+		want edtypes.Text
+	}{
+		{"`a*b`", edtypes.Text{Content: "a*b", Code: true}},
+		{"~~del~~", edtypes.Text{Content: "del", Strikethrough: true}},
+		{"***both***", edtypes.Text{Content: "both", Strong: true, Italic: true}},
+		{"__bold__", edtypes.Text{Content: "bold", Strong: true}},
+		{"<u>under</u>", edtypes.Text{Content: "under", Underlined: true}},
+		{"<sup>2</sup>", edtypes.Text{Content: "2", Sup: true}},
+		{"snake_case_name", edtypes.Text{Content: "snake_case_name"}},
+		{"2 * 3 * 4", edtypes.Text{Content: "2 * 3 * 4"}},
+		{`\*not\*`, edtypes.Text{Content: "*not*"}},
+	}
+
+	for _, tt := range tests {
+		doc := mustParse(t, tt.md, nil)
+		p := doc.Elements[0].(*edtypes.Paragraph)
+		if len(p.Content) != 1 {
+			t.Errorf("%q: expected single run, got %+v", tt.md, p.Content)
+			continue
+		}
+		if got := p.Content[0].(edtypes.Text); !sameMarks(got, tt.want) || got.Content != tt.want.Content {
+			t.Errorf("%q: expected %+v, got %+v", tt.md, tt.want, got)
+		}
+	}
+}
+
+func TestParseLinks(t *testing.T) {
+	doc := mustParse(t, "See [the **docs**](https://example.com/a_(b)) and https://aiplan.ru/path.\n\n![](https://example.com/img.png)", nil)
+
+	p := doc.Elements[0].(*edtypes.Paragraph)
+	var links []string
+	for _, item := range p.Content {
+		if text := item.(edtypes.Text); text.URL != nil {
+			links = append(links, text.Content+"|"+text.URL.String())
+		}
+	}
+	want := []string{"the |https://example.com/a_(b)", "docs|https://example.com/a_(b)", "https://aiplan.ru/path|https://aiplan.ru/path"}
+	if len(links) != len(want) {
+		t.Fatalf("Expected links %v, got %v", want, links)
+	}
+	for i := range want {
+		if links[i] != want[i] {
+			t.Errorf("Link %d: expected %q, got %q", i, want[i], links[i])
+		}
+	}
+
+	img, ok := doc.Elements[1].(*edtypes.Image)
+	if !ok || img.Src.String() != "https://example.com/img.png" {
+		t.Errorf("Expected top-level image, got %#v", doc.Elements[1])
+	}
+}
+
+func TestParseMentionsAndIssueLinks(t *testing.T) {
+	opts := &ParseOptions{
+		ResolveMention: func(username string) bool {
+			return username == "alice"
+		},
+		ResolveIssue: func(projectIdentifier string, sequenceId int) (*edtypes.IssueLinkMention, bool) {
+			if projectIdentifier != "PROJ" {
+				return nil, false
+			}
+			return &edtypes.IssueLinkMention{Slug: "ws", ProjectIdentifier: projectIdentifier, CurrentIssueId: "12"}, true
+		},
+	}
+	doc := mustParse(t, "@alice @bob mail@alice.ru PROJ-12 UTF-8 `PROJ-12`", opts)
+	p := doc.Elements[0].(*edtypes.Paragraph)
+
+	var mentions, issues int
+	for _, item := range p.Content {
+		switch n := item.(type) {
+		case *edtypes.Mention:
+			mentions++
+			if n.ID != "alice" || n.Label != "alice" {
+				t.Errorf("Unexpected mention: %+v", n)
+			}
+		case *edtypes.IssueLinkMention:
+			issues++
+			if n.Slug != "ws" || n.CurrentIssueId != "12" {
+				t.Errorf("Unexpected issue link: %+v", n)
+			}
+		}
+	}
+	if mentions != 1 || issues != 1 {
+		t.Errorf("Expected 1 mention and 1 issue link, got %d and %d: %+v", mentions, issues, p.Content)
+	}
+}
+
+func TestParseLists(t *testing.T) {
+	doc := mustParse(t, "- [x] done\n- [ ] todo\n  continued\n\n1. first\n2. second\n   - nested\n\n* a\n\n  second paragraph\n* b\n", nil)
+	if len(doc.Elements) != 3 {
+		t.Fatalf("Expected 3 lists, got %d: %#v", len(doc.Elements), doc.Elements)
+	}
+
+	tasks := doc.Elements[0].(*edtypes.List)
+	if !tasks.TaskList || len(tasks.Elements) != 2 || !tasks.Elements[0].Checked || tasks.Elements[1].Checked {
+		t.Errorf("Unexpected task list: %+v", tasks)
+	}
+	if text := tasks.Elements[1].Content[0].Content[0].(edtypes.Text); text.Content != "todo continued" {
+		t.Errorf("Unexpected task item text: %q", text.Content)
+	}
+
+	ordered := doc.Elements[1].(*edtypes.List)
+	if !ordered.Numbered || len(ordered.Elements) != 2 || len(ordered.Elements[1].Lists) != 1 {
+		t.Fatalf("Expected numbered list with nested list in second item, got %+v", ordered)
+	}
+	if nested := ordered.Elements[1].Lists[0]; nested.Numbered || len(nested.Elements) != 1 {
+		t.Errorf("Unexpected nested list: %+v", nested)
+	}
+
+	loose := doc.Elements[2].(*edtypes.List)
+	if len(loose.Elements) != 2 || len(loose.Elements[0].Content) != 2 {
+		t.Errorf("Expected item with two paragraphs, got %+v", loose)
+	}
+}
+
+func TestParseBlocks(t *testing.T) {
+	md := "```go\nfunc main() {}\n\n```\n\n" +
+		"> quote\n> line\n\n" +
+		"> [!INFO] Note\n> body\n\n" +
+		"<details>\n<summary>Spoiler &amp; title</summary>\n\nhidden\n\n</details>\n\n" +
+		"| A | B \\| C |\n|---|:-:|\n| 1 | 2<br>3 |\n| 4 |\n\n" +
+		"    indented code\n"
+	doc := mustParse(t, md, nil)
+	if len(doc.Elements) != 6 {
+		t.Fatalf("Expected 6 elements, got %d: %#v", len(doc.Elements), doc.Elements)
+	}
+
+	if code := doc.Elements[0].(*edtypes.Code); code.Content != "func main() {}\n" || code.Language != "go" {
+		t.Errorf("Unexpected code: %q (%q)", code.Content, code.Language)
+	}
+	if quote := doc.Elements[1].(*edtypes.Quote); len(quote.Content) != 1 {
+		t.Errorf("Unexpected quote: %+v", quote)
+	}
+	if info := doc.Elements[2].(*edtypes.InfoBlock); info.Title != "Note" || len(info.Content) != 1 {
+		t.Errorf("Unexpected info block: %+v", info)
+	}
+	if spoiler := doc.Elements[3].(*edtypes.Spoiler); spoiler.Title != "Spoiler & title" || !spoiler.Collapsed || len(spoiler.Content) != 1 {
+		t.Errorf("Unexpected spoiler: %+v", spoiler)
+	}
+
+	table := doc.Elements[4].(*edtypes.Table)
+	if len(table.Rows) != 3 || len(table.Rows[2]) != 2 || !table.Rows[0][0].Header || table.Rows[1][0].Header {
+		t.Fatalf("Unexpected table: %+v", table)
+	}
+	if text := table.Rows[0][1].Content[0].Content[0].(edtypes.Text); text.Content != "B | C" {
+		t.Errorf("Unexpected header cell: %q", text.Content)
+	}
+	if _, ok := table.Rows[1][1].Content[0].Content[1].(*edtypes.HardBreak); !ok {
+		t.Errorf("Expected <br> in cell, got %+v", table.Rows[1][1].Content[0].Content)
+	}
+
+	if code := doc.Elements[5].(*edtypes.Code); code.Content != "indented code" {
+		t.Errorf("Unexpected indented code: %q", code.Content)
+	}
+}
@@ -0,0 +1,229 @@
+package markdown
+
+import (
+	"net/url"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor/edtypes"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor/tiptap"
+)
+
+func mustSerialize(t *testing.T, doc *edtypes.Document) string {
+	t.Helper()
+	b, err := Serialize(doc)
+	if err != nil {
+		t.Fatalf("Serialize failed: %v", err)
+	}
+	return string(b)
+}
+
+// TestMarkdownRoundTrip проверяет, что Markdown не меняется после разбора и сериализации
+func TestMarkdownRoundTrip(t *testing.T) {
+	md := `# Release notes
+
+Plain *italic* **bold** ***both*** ~~strike~~ <u>under</u> x<sup>2</sup> ` + "`code`" + ` text.
+
+Links: [docs](https://example.com/docs) and [**bold link**](https://example.com) @alice PROJ-12
+
+Escaped \*stars\* \@nobody ABC\-1 https\://not.link
+
+Line with\
+hard break
+
+## Checklist
+
+- [x] done
+- [ ] todo
+
+1. first
+2. second
+
+- bullet
+
+  second paragraph
+- next
+
+Nested lists:
+
+- [ ] task
+  - plain child
+    1. deep child
+- [x] other
+
+1. step
+   - [x] checked child
+
+> quote
+>
+> second
+
+> [!INFO] Note
+> info body
+
+<details open>
+<summary>Spoiler</summary>
+
+hidden text
+
+</details>
+
+| Name | Value |
+| --- | --- |
+| a \| b | 1<br>2 |
+| **bold** |  |
+
+` + "```" + `
+func main() {
+	println("` + "``" + `")
+}
+` + "```" + `
+
+` + "```" + `go
+x := 1
+` + "```" + `
+
+![](https://example.com/image.png)
+`
+
+	doc := mustParse(t, md, nil)
+	got := mustSerialize(t, doc)
+	if got != md {
+		t.Errorf("Round trip changed markdown:\n--- want\n%s\n--- got\n%s", md, got)
+	}
+}
+
+// TestDocumentRoundTrip проверяет, что документ не меняется после сериализации в Markdown и разбора
+func TestDocumentRoundTrip(t *testing.T) {
+	link, _ := url.Parse("https://example.com/a b")
+	img, _ := url.Parse("https://example.com/img.png")
+	doc := &edtypes.Document{Elements: []any{
+		&edtypes.Paragraph{Content: []any{edtypes.Text{Content: "Heading", Strong: true, Size: 20}}},
+		&edtypes.Paragraph{Content: []any{
+			edtypes.Text{Content: "a "},
+			edtypes.Text{Content: "bold ", Strong: true},
+			edtypes.Text{Content: "bold italic", Strong: true, Italic: true},
+			edtypes.Text{Content: " "},
+			edtypes.Text{Content: "italic", Italic: true},
+			edtypes.Text{Content: " "},
+			edtypes.Text{Content: "link", URL: link, Underlined: true},
+			edtypes.Text{Content: " 2*2=4 [x] <tag> "},
+			edtypes.Text{Content: "a`b", Code: true},
+			&edtypes.HardBreak{},
+			&edtypes.Mention{ID: "bob", Label: "bob"},
+			edtypes.Text{Content: " "},
+			&edtypes.IssueLinkMention{ProjectIdentifier: "PROJ", CurrentIssueId: "7"},
+		}},
+		&edtypes.Paragraph{Content: []any{edtypes.Text{Content: "# not heading"}}},
+		&edtypes.Paragraph{Content: []any{edtypes.Text{Content: "1. not list"}}},
+		&edtypes.Paragraph{Content: []any{edtypes.Text{Content: "- not list 22.08.2025"}}},
+		&edtypes.Code{Content: "```\ncode\n"},
+		&edtypes.Code{Content: "SELECT 1", Language: "sql"},
+		&edtypes.Quote{Content: []edtypes.Paragraph{
+			{Content: []any{edtypes.Text{Content: "one"}}},
+			{Content: []any{edtypes.Text{Content: "two"}}},
+		}},
+		&edtypes.List{TaskList: true, Elements: []edtypes.ListElement{
+			{Checked: true, Content: []edtypes.Paragraph{{Content: []any{edtypes.Text{Content: "done"}}}}},
+			{Content: []edtypes.Paragraph{{Content: []any{edtypes.Text{Content: "todo"}}}}},
+		}},
+		&edtypes.List{Numbered: true, Elements: []edtypes.ListElement{
+			{Content: []edtypes.Paragraph{{Content: []any{edtypes.Text{Content: "first"}}}, {Content: []any{edtypes.Text{Content: "more"}}}}},
+			{Content: []edtypes.Paragraph{{Content: []any{edtypes.Text{Content: "second"}}}}},
+		}},
+		&edtypes.List{TaskList: true, Elements: []edtypes.ListElement{
+			{Content: []edtypes.Paragraph{{Content: []any{edtypes.Text{Content: "parent"}}}}, Lists: []edtypes.List{
+				{Elements: []edtypes.ListElement{
+					{Content: []edtypes.Paragraph{{Content: []any{edtypes.Text{Content: "child"}}}}, Lists: []edtypes.List{
+						{Numbered: true, Elements: []edtypes.ListElement{
+							{Content: []edtypes.Paragraph{{Content: []any{edtypes.Text{Content: "deep"}}}}},
+						}},
+					}},
+				}},
+			}},
+			{Checked: true, Content: []edtypes.Paragraph{{Content: []any{edtypes.Text{Content: "other"}}}}, Lists: []edtypes.List{
+				{TaskList: true, Elements: []edtypes.ListElement{
+					{Checked: true, Content: []edtypes.Paragraph{{Content: []any{edtypes.Text{Content: "subtask"}}}}},
+				}},
+			}},
+		}},
+		&edtypes.Table{Rows: [][]edtypes.TableCell{
+			{
+				{ColSpan: 1, RowSpan: 1, Header: true, Content: []edtypes.Paragraph{{Content: []any{edtypes.Text{Content: "a|b"}}}}},
+				{ColSpan: 1, RowSpan: 1, Header: true, Content: []edtypes.Paragraph{{}}},
+			},
+			{
+				{ColSpan: 1, RowSpan: 1, Content: []edtypes.Paragraph{{Content: []any{edtypes.Text{Content: "x", Strong: true}}}}},
+				{ColSpan: 1, RowSpan: 1, Content: []edtypes.Paragraph{{Content: []any{edtypes.Text{Content: "y"}, &edtypes.HardBreak{}, edtypes.Text{Content: "z"}}}}},
+			},
+		}},
+		&edtypes.Spoiler{Title: "<Title>", Collapsed: true, Content: []edtypes.Paragraph{{Content: []any{edtypes.Text{Content: "hidden"}}}}},
+		&edtypes.InfoBlock{Title: "Info", Content: []edtypes.Paragraph{{Content: []any{edtypes.Text{Content: "body"}}}}},
+		&edtypes.Image{Src: img},
+	}}
+
+	md := mustSerialize(t, doc)
+	parsed := mustParse(t, md, nil)
+
+	if len(parsed.Elements) != len(doc.Elements) {
+		t.Fatalf("Expected %d elements, got %d\n%s", len(doc.Elements), len(parsed.Elements), md)
+	}
+	for i := range doc.Elements {
+		if !reflect.DeepEqual(normalize(doc.Elements[i]), normalize(parsed.Elements[i])) {
+			t.Errorf("Element %d differs:\nwant %#v\ngot  %#v\nmarkdown:\n%s", i, doc.Elements[i], parsed.Elements[i], md)
+		}
+	}
+}
+
+// TestTipTapRoundTrip проверяет, что документ из TipTap JSON стабилен после сериализации в Markdown
+func TestTipTapRoundTrip(t *testing.T) {
+	f, err := os.Open("../tiptap.json")
+	if err != nil {
+		t.Fatalf("Failed to open tiptap.json: %v", err)
+	}
+	defer f.Close()
+
+	doc, err := tiptap.ParseJSON(f)
+	if err != nil {
+		t.Fatalf("ParseJSON failed: %v", err)
+	}
+
+	first := mustSerialize(t, doc)
+	if first == "" {
+		t.Fatal("Serialized markdown is empty")
+	}
+	second := mustSerialize(t, mustParse(t, first, nil))
+	if first != second {
+		t.Errorf("Markdown is not stable after round trip:\n--- first\n%s\n--- second\n%s", first, second)
+	}
+
+	// Результат разбора Markdown сериализуется в TipTap JSON
+	if _, err := tiptap.Serialize(mustParse(t, first, nil)); err != nil {
+		t.Errorf("tiptap.Serialize failed: %v", err)
+	}
+}
+
+// normalize приводит URL к строке для сравнения элементов через reflect.DeepEqual
+func normalize(elem any) any {
+	switch e := elem.(type) {
+	case *edtypes.Paragraph:
+		p := *e
+		p.Content = make([]any, len(e.Content))
+		for i, item := range e.Content {
+			switch n := item.(type) {
+			case edtypes.Text:
+				if n.URL != nil {
+					n.URL, _ = url.Parse(n.URL.String())
+				}
+				p.Content[i] = n
+			default:
+				p.Content[i] = n
+			}
+		}
+		return p
+	case *edtypes.Image:
+		return e.Src.String()
+	}
+	return elem
+}
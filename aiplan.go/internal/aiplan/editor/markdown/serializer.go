@@ -0,0 +1,569 @@
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor/edtypes"
+)
+
+var (
+	blockStartRe = regexp.MustCompile(`^(?:#{1,6}(?:\s|$)|[-+](?:\s|$)|=+\s*$|-[-\s]*$)`)
+	orderedRe    = regexp.MustCompile(`^(\d{1,9})[.)](?:\s|$)`)
+	usernameRe   = regexp.MustCompile(`^\w+$`)
+)
+
+// Serialize сериализует документ редактора в Markdown.
+// Поддерживаются элементы в виде значений и указателей (парсеры HTML и TipTap JSON создают разные варианты).
+func Serialize(doc *edtypes.Document) ([]byte, error) {
+	if doc == nil {
+		return []byte{}, nil
+	}
+
+	blocks := make([]string, 0, len(doc.Elements))
+	for _, elem := range doc.Elements {
+		if block := serializeBlock(elem); block != "" {
+			blocks = append(blocks, block)
+		}
+	}
+	if len(blocks) == 0 {
+		return []byte{}, nil
+	}
+	return []byte(strings.Join(blocks, "\n\n") + "\n"), nil
+}
+
+// serializeBlock сериализует элемент верхнего уровня документа
+func serializeBlock(elem any) string {
+	switch e := elem.(type) {
+	case edtypes.Paragraph:
+		return serializeParagraph(&e)
+	case *edtypes.Paragraph:
+		return serializeParagraph(e)
+	case edtypes.Code:
+		return serializeCode(&e)
+	case *edtypes.Code:
+		return serializeCode(e)
+	case edtypes.Quote:
+		return serializeQuote(&e)
+	case *edtypes.Quote:
+		return serializeQuote(e)
+	case edtypes.List:
+		return serializeList(&e)
+	case *edtypes.List:
+		return serializeList(e)
+	case edtypes.Table:
+		return serializeTable(&e)
+	case *edtypes.Table:
+		return serializeTable(e)
+	case edtypes.Spoiler:
+		return serializeSpoiler(&e)
+	case *edtypes.Spoiler:
+		return serializeSpoiler(e)
+	case edtypes.InfoBlock:
+		return serializeInfoBlock(&e)
+	case *edtypes.InfoBlock:
+		return serializeInfoBlock(e)
+	case nil:
+		return ""
+	case edtypes.Image, *edtypes.Image, edtypes.Drawio, *edtypes.Drawio,
+		edtypes.DateNode, *edtypes.DateNode, edtypes.IssueLinkMention, *edtypes.IssueLinkMention,
+		edtypes.Mention, *edtypes.Mention, edtypes.Text, *edtypes.Text:
+		return serializeParagraph(&edtypes.Paragraph{Content: []any{e}})
+	default:
+		slog.Debug("Unknown element type for markdown serialization", "type", fmt.Sprintf("%T", e))
+		return ""
+	}
+}
+
+// serializeParagraph сериализует параграф. Параграф из жирного текста увеличенного размера становится заголовком
+func serializeParagraph(p *edtypes.Paragraph) string {
+	if level := headingOf(p); level > 0 {
+		content := make([]any, len(p.Content))
+		for i, item := range p.Content {
+			if t, ok := textOf(item); ok {
+				t.Strong = false
+				t.Size = 0
+				item = t
+			}
+			content[i] = item
+		}
+		text := strings.TrimSpace(serializeInline(content, true))
+		if text == "" {
+			return ""
+		}
+		return strings.Repeat("#", level) + " " + text
+	}
+
+	return escapeLineStarts(serializeInline(p.Content, false))
+}
+
+// serializeParagraphs сериализует параграфы блока, разделяя их пустой строкой
+func serializeParagraphs(paragraphs []edtypes.Paragraph) string {
+	blocks := make([]string, 0, len(paragraphs))
+	for i := range paragraphs {
+		if block := serializeParagraph(&paragraphs[i]); block != "" {
+			blocks = append(blocks, block)
+		}
+	}
+	return strings.Join(blocks, "\n\n")
+}
+
+// serializeCode сериализует блок кода. Разделитель длиннее любой последовательности ` в коде
+func serializeCode(c *edtypes.Code) string {
+	fence := "```"
+	for strings.Contains(c.Content, fence) {
+		fence += "`"
+	}
+	return fence + c.Language + "\n" + c.Content + "\n" + fence
+}
+
+// serializeQuote сериализует цитату
+func serializeQuote(q *edtypes.Quote) string {
+	content := serializeParagraphs(q.Content)
+	if content == "" {
+		return ""
+	}
+	return prefixLines(content, "> ")
+}
+
+// serializeInfoBlock сериализует информационный блок как цитату с маркером [!INFO]
+func serializeInfoBlock(ib *edtypes.InfoBlock) string {
+	block := "[!INFO]"
+	if title := strings.Join(strings.Fields(ib.Title), " "); title != "" {
+		block += " " + title
+	}
+	if content := serializeParagraphs(ib.Content); content != "" {
+		block += "\n" + content
+	}
+	return prefixLines(block, "> ")
+}
+
+// serializeSpoiler сериализует спойлер как <details>
+func serializeSpoiler(s *edtypes.Spoiler) string {
+	var sb strings.Builder
+	if s.Collapsed {
+		sb.WriteString("<details>\n")
+	} else {
+		sb.WriteString("<details open>\n")
+	}
+	fmt.Fprintf(&sb, "<summary>%s</summary>\n\n", html.EscapeString(s.Title))
+	if content := serializeParagraphs(s.Content); content != "" {
+		sb.WriteString(content)
+		sb.WriteString("\n\n")
+	}
+	sb.WriteString("</details>")
+	return sb.String()
+}
+
+// serializeList сериализует список. Вложенные параграфы элемента отделяются пустой строкой с отступом,
+// вложенные списки записываются с отступом содержимого элемента
+func serializeList(l *edtypes.List) string {
+	items := make([]string, 0, len(l.Elements))
+	for i, elem := range l.Elements {
+		marker := "- "
+		switch {
+		case l.TaskList && elem.Checked:
+			marker = "- [x] "
+		case l.TaskList:
+			marker = "- [ ] "
+		case l.Numbered:
+			marker = fmt.Sprintf("%d. ", i+1)
+		}
+		indent := len(marker)
+		if l.TaskList {
+			indent = 2
+		}
+
+		paragraphs := make([]string, 0, len(elem.Content))
+		for _, p := range elem.Content {
+			if text := escapeLineStarts(serializeInline(p.Content, false)); text != "" {
+				paragraphs = append(paragraphs, text)
+			}
+		}
+		content := strings.Join(paragraphs, "\n\n")
+		// Вложенные списки следуют за параграфами элемента без пустой строки
+		for i := range elem.Lists {
+			if nested := serializeList(&elem.Lists[i]); nested != "" {
+				content += "\n" + nested
+			}
+		}
+		content = strings.ReplaceAll(content, "\n", "\n"+strings.Repeat(" ", indent))
+		content = strings.ReplaceAll(content, "\n"+strings.Repeat(" ", indent)+"\n", "\n\n")
+
+		items = append(items, strings.TrimRight(marker+content, " "))
+	}
+	return strings.Join(items, "\n")
+}
+
+// serializeTable сериализует таблицу в GFM. Первая строка всегда становится заголовком,
+// несколько параграфов ячейки объединяются через <br>
+func serializeTable(t *edtypes.Table) string {
+	width := 0
+	for _, row := range t.Rows {
+		width = max(width, len(row))
+	}
+	if width == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	for i, row := range t.Rows {
+		sb.WriteString("|")
+		for j := range width {
+			cell := ""
+			if j < len(row) {
+				parts := make([]string, 0, len(row[j].Content))
+				for _, p := range row[j].Content {
+					parts = append(parts, strings.TrimSpace(serializeInline(p.Content, true)))
+				}
+				cell = strings.Join(parts, "<br>")
+			}
+			sb.WriteString(" " + cell + " |")
+		}
+		sb.WriteString("\n")
+
+		if i == 0 {
+			sb.WriteString("|" + strings.Repeat(" --- |", width) + "\n")
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// headingOf возвращает уровень заголовка, если параграф состоит из жирного текста увеличенного размера
+func headingOf(p *edtypes.Paragraph) int {
+	size := 0
+	for _, item := range p.Content {
+		switch item.(type) {
+		case edtypes.HardBreak, *edtypes.HardBreak, edtypes.Image, *edtypes.Image, edtypes.Drawio, *edtypes.Drawio:
+			return 0
+		}
+		t, ok := textOf(item)
+		if !ok {
+			continue
+		}
+		if !t.Strong || t.Size < headingSizes[len(headingSizes)-1] {
+			return 0
+		}
+		if size == 0 || t.Size < size {
+			size = t.Size
+		}
+	}
+	return headingLevel(size)
+}
+
+// mdMark - отметка форматирования текста в Markdown
+type mdMark struct {
+	kind int
+	url  string
+}
+
+// Виды отметок в порядке вложенности по умолчанию (ссылка - внешняя)
+const (
+	markLink = iota
+	markStrike
+	markStrong
+	markItalic
+	markUnderline
+	markSup
+	markSub
+)
+
+func (m mdMark) open() string {
+	return [...]string{"[", "~~", "**", "*", "<u>", "<sup>", "<sub>"}[m.kind]
+}
+
+func (m mdMark) close() string {
+	if m.kind == markLink {
+		return "](" + escapeURL(m.url) + ")"
+	}
+	return [...]string{"", "~~", "**", "*", "</u>", "</sup>", "</sub>"}[m.kind]
+}
+
+// textMarks возвращает отметки текста (inline код обрабатывается отдельно)
+func textMarks(t edtypes.Text) []mdMark {
+	var marks []mdMark
+	if t.URL != nil {
+		marks = append(marks, mdMark{kind: markLink, url: t.URL.String()})
+	}
+	for kind, on := range [...]bool{markStrike: t.Strikethrough, markStrong: t.Strong, markItalic: t.Italic,
+		markUnderline: t.Underlined, markSup: t.Sup, markSub: t.Sub} {
+		if on {
+			marks = append(marks, mdMark{kind: kind})
+		}
+	}
+	return marks
+}
+
+// inlineWriter сериализует содержимое параграфа, отслеживая открытые отметки форматирования
+type inlineWriter struct {
+	sb         strings.Builder
+	open       []mdMark
+	pending    string // пробелы, вынесенные за пределы разделителей выделения
+	singleLine bool   // перенос строки как <br> (ячейки таблиц, заголовки)
+}
+
+// serializeInline сериализует содержимое параграфа
+func serializeInline(content []any, singleLine bool) string {
+	w := &inlineWriter{singleLine: singleLine}
+	for i, item := range content {
+		if t, ok := textOf(item); ok {
+			w.writeText(t, content[i:])
+			continue
+		}
+		w.writeNode(item)
+	}
+	w.closeTo(0)
+	return w.sb.String()
+}
+
+// writeText записывает текст, открывая и закрывая отметки. rest - содержимое параграфа начиная с текущего текста,
+// по нему определяется порядок вложенности: дольше действующая отметка открывается раньше
+func (w *inlineWriter) writeText(t edtypes.Text, rest []any) {
+	if t.Content == "" {
+		return
+	}
+	lines := strings.Split(t.Content, "\n")
+	if len(lines) > 1 {
+		for i, line := range lines {
+			if i > 0 {
+				w.writeNode(&edtypes.HardBreak{})
+			}
+			lt := t
+			lt.Content = line
+			w.writeText(lt, rest)
+		}
+		return
+	}
+
+	core := t.Content
+	leading, trailing := "", ""
+	if !t.Code {
+		core = strings.TrimLeft(t.Content, " \t")
+		leading = t.Content[:len(t.Content)-len(core)]
+		trimmed := strings.TrimRight(core, " \t")
+		trailing = core[len(trimmed):]
+		core = trimmed
+	}
+
+	// Отметки, которых нет у текста, закрываются даже для текста из одних пробелов
+	want := textMarks(t)
+	keep := 0
+	for keep < len(w.open) && slices.Contains(want, w.open[keep]) {
+		keep++
+	}
+	w.closeTo(keep)
+	if core == "" {
+		w.pending += leading
+		return
+	}
+	w.sb.WriteString(w.pending + leading)
+	w.pending = ""
+
+	var opening []mdMark
+	for _, m := range want {
+		if !slices.Contains(w.open, m) {
+			opening = append(opening, m)
+		}
+	}
+	extent := func(m mdMark) int {
+		n := 0
+		for _, item := range rest {
+			it, ok := textOf(item)
+			if !ok || !slices.Contains(textMarks(it), m) {
+				break
+			}
+			n++
+		}
+		return n
+	}
+	slices.SortStableFunc(opening, func(a, b mdMark) int { return extent(b) - extent(a) })
+	for _, m := range opening {
+		w.sb.WriteString(m.open())
+		w.open = append(w.open, m)
+	}
+
+	if t.Code {
+		w.sb.WriteString(codeSpan(core))
+	} else {
+		w.sb.WriteString(escapeText(core, w.singleLine))
+	}
+	w.pending = trailing
+}
+
+// writeNode записывает узел параграфа, не являющийся текстом
+func (w *inlineWriter) writeNode(item any) {
+	var s string
+	switch n := item.(type) {
+	case edtypes.HardBreak, *edtypes.HardBreak:
+		w.closeTo(0)
+		w.pending = ""
+		if w.singleLine {
+			w.sb.WriteString("<br>")
+		} else {
+			w.sb.WriteString("\\\n")
+		}
+		return
+	case edtypes.Mention:
+		s = serializeMention(&n)
+	case *edtypes.Mention:
+		s = serializeMention(n)
+	case edtypes.IssueLinkMention:
+		s = serializeIssueLink(&n)
+	case *edtypes.IssueLinkMention:
+		s = serializeIssueLink(n)
+	case edtypes.DateNode:
+		s = escapeText(n.Date, w.singleLine)
+	case *edtypes.DateNode:
+		s = escapeText(n.Date, w.singleLine)
+	case edtypes.Image:
+		s = serializeImage("", n.Src)
+	case *edtypes.Image:
+		s = serializeImage("", n.Src)
+	case edtypes.Drawio:
+		s = serializeImage("drawio", n.Src)
+	case *edtypes.Drawio:
+		s = serializeImage("drawio", n.Src)
+	default:
+		slog.Debug("Unknown paragraph content type for markdown serialization", "type", fmt.Sprintf("%T", n))
+		return
+	}
+	if s == "" {
+		return
+	}
+	w.closeTo(0)
+	w.sb.WriteString(w.pending + s)
+	w.pending = ""
+}
+
+// closeTo закрывает отметки выше уровня n
+func (w *inlineWriter) closeTo(n int) {
+	for len(w.open) > n {
+		w.sb.WriteString(w.open[len(w.open)-1].close())
+		w.open = w.open[:len(w.open)-1]
+	}
+}
+
+// serializeMention записывает упоминание как @username (ID упоминания в редакторе - username)
+func serializeMention(m *edtypes.Mention) string {
+	switch {
+	case usernameRe.MatchString(m.ID):
+		return "@" + m.ID
+	case usernameRe.MatchString(m.Label):
+		return "@" + m.Label
+	case m.Label != "":
+		return escapeText("@"+m.Label, false)
+	}
+	return ""
+}
+
+func serializeIssueLink(ilm *edtypes.IssueLinkMention) string {
+	if ilm.ProjectIdentifier != "" && ilm.CurrentIssueId != "" {
+		return ilm.ProjectIdentifier + "-" + ilm.CurrentIssueId
+	}
+	if ilm.OriginalUrl != "" {
+		return "<" + ilm.OriginalUrl + ">"
+	}
+	return ""
+}
+
+func serializeImage(alt string, src *url.URL) string {
+	if src == nil {
+		return ""
+	}
+	return "![" + alt + "](" + escapeURL(src.String()) + ")"
+}
+
+// textOf возвращает текст, если элемент параграфа является текстом
+func textOf(item any) (edtypes.Text, bool) {
+	switch t := item.(type) {
+	case edtypes.Text:
+		return t, true
+	case *edtypes.Text:
+		if t != nil {
+			return *t, true
+		}
+	}
+	return edtypes.Text{}, false
+}
+
+// codeSpan оборачивает inline код в разделитель длиннее любой последовательности ` внутри
+func codeSpan(code string) string {
+	longest := 0
+	for i := 0; i < len(code); {
+		if code[i] == '`' {
+			n := runLen(code, i, '`')
+			longest = max(longest, n)
+			i += n
+			continue
+		}
+		i++
+	}
+	fence := strings.Repeat("`", longest+1)
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") ||
+		(strings.HasPrefix(code, " ") && strings.HasSuffix(code, " ") && strings.Trim(code, " ") != "") {
+		code = " " + code + " "
+	}
+	return fence + code + fence
+}
+
+// escapeText экранирует символы, которые парсер принял бы за разметку
+func escapeText(s string, singleLine bool) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case strings.IndexByte("\\`*_[]<>~", c) >= 0,
+			c == '|' && singleLine,
+			// @username - упоминание
+			c == '@' && isWordAt(s, i+1),
+			// PROJ-12 - ссылка на задачу
+			c == '-' && i > 0 && s[i-1] >= 'A' && s[i-1] <= 'Z' && i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9',
+			// http://... - автоматическая ссылка
+			c == ':' && strings.HasPrefix(s[i:], "://") && (strings.HasSuffix(s[:i], "http") || strings.HasSuffix(s[:i], "https")):
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+// escapeLineStarts экранирует начало строк параграфа, которое парсер принял бы за начало блока
+func escapeLineStarts(s string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		line = strings.TrimLeft(line, " \t")
+		if blockStartRe.MatchString(line) {
+			line = "\\" + line
+		} else if m := orderedRe.FindStringSubmatch(line); m != nil {
+			line = m[1] + "\\" + line[len(m[1]):]
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// escapeURL подготавливает адрес ссылки или изображения для записи в (...)
+func escapeURL(u string) string {
+	if strings.ContainsAny(u, " ()<>") {
+		return "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(u) + ">"
+	}
+	return u
+}
+
+// prefixLines добавляет префикс к каждой строке (пустые строки получают префикс без завершающих пробелов)
+func prefixLines(s, prefix string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = strings.TrimRight(prefix, " ")
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,47 @@
+// Пакет markdown преобразует Markdown в структуры пакета edtypes и обратно.
+//
+// Поддерживается подмножество CommonMark/GFM, которое имеет соответствие в редакторе:
+// параграфы, заголовки, выделение текста, ссылки, изображения, блоки кода, цитаты,
+// списки (в том числе чек-листы), таблицы, упоминания @username и ссылки на задачи PROJ-12.
+//
+// В редакторе нет отдельного узла заголовка: заголовок - это параграф с жирным текстом
+// увеличенного размера (как его создает TipTap), поэтому "# Заголовок" превращается в
+// текст размером 24px, а такой параграф при сериализации снова становится заголовком.
+//
+// Элементы редактора без аналога в Markdown сериализуются так, чтобы парсер мог их восстановить:
+// спойлер - <details>/<summary>, информационный блок - цитата с маркером [!INFO],
+// подчеркивание и индексы - теги <u>, <sup>, <sub>. Цвета, размеры изображений,
+// отступы и объединение ячеек таблиц в Markdown не сохраняются.
+package markdown
+
+import "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor/edtypes"
+
+// ParseOptions - параметры разбора Markdown
+type ParseOptions struct {
+	// ResolveMention проверяет, что пользователь из упоминания @username существует.
+	// Если функция не задана, упоминание создается без проверки; если возвращает false, остается текстом.
+	// Как и в редакторе, ID упоминания - username пользователя.
+	ResolveMention func(username string) bool
+
+	// ResolveIssue возвращает ссылку на задачу по идентификатору проекта и номеру (PROJ-12).
+	// Если функция не задана, ссылка создается без проверки; если возвращает false, остается текстом.
+	ResolveIssue func(projectIdentifier string, sequenceId int) (*edtypes.IssueLinkMention, bool)
+}
+
+// headingSizes - размер шрифта заголовков Markdown по уровню (1-6)
+var headingSizes = [...]int{0, 24, 20, 18, 16, 16, 16}
+
+// headingLevel возвращает уровень заголовка по размеру шрифта (0 - не заголовок)
+func headingLevel(size int) int {
+	switch {
+	case size >= 24:
+		return 1
+	case size >= 20:
+		return 2
+	case size >= 18:
+		return 3
+	case size >= 16:
+		return 4
+	}
+	return 0
+}
@@ -0,0 +1,140 @@
+// Извлечение ссылок на задачи и документы из HTML редактора.
+//
+// Ссылки на задачи берутся из упоминаний задач (issueLinkMention) и из обычных ссылок на страницы задач,
+// ссылки на документы - из ссылок на страницы документов. Внешние ссылки игнорируются.
+package refs
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gofrs/uuid"
+	"golang.org/x/net/html"
+)
+
+// IssueRef - ссылка на задачу. Пространство и проект могут быть указаны как идентификатором, так и slug/кодом проекта
+type IssueRef struct {
+	Workspace string
+	Project   string
+	Seq       int
+}
+
+// Refs - ссылки, найденные в тексте, без повторов
+type Refs struct {
+	Issues []IssueRef
+	Docs   []uuid.UUID
+}
+
+// Empty сообщает, что ссылок не найдено
+func (r Refs) Empty() bool {
+	return len(r.Issues) == 0 && len(r.Docs) == 0
+}
+
+// Extract находит ссылки на задачи и документы в HTML. Абсолютные ссылки учитываются только при совпадении хоста с webURL
+func Extract(body string, webURL *url.URL) Refs {
+	var res Refs
+	if strings.TrimSpace(body) == "" {
+		return res
+	}
+
+	root, err := html.Parse(strings.NewReader(body))
+	if err != nil {
+		return res
+	}
+
+	issues := make(map[IssueRef]struct{})
+	docs := make(map[uuid.UUID]struct{})
+
+	addIssue := func(ref IssueRef) {
+		if _, ok := issues[ref]; ok {
+			return
+		}
+		issues[ref] = struct{}{}
+		res.Issues = append(res.Issues, ref)
+	}
+
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch {
+			case attr(n, "data-type") == "issueLinkMention":
+				seq, err := strconv.Atoi(attr(n, "data-current-issue-id"))
+				if err == nil && attr(n, "data-project-identifier") != "" {
+					addIssue(IssueRef{
+						Workspace: attr(n, "data-slug"),
+						Project:   attr(n, "data-project-identifier"),
+						Seq:       seq,
+					})
+				}
+				// Внутри упоминания только текст со ссылкой на ту же задачу
+				return
+			case n.Data == "a":
+				issue, docId, ok := parseLink(attr(n, "href"), webURL)
+				if ok && !docId.IsNil() {
+					if _, exists := docs[docId]; !exists {
+						docs[docId] = struct{}{}
+						res.Docs = append(res.Docs, docId)
+					}
+				} else if ok {
+					addIssue(issue)
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+
+	return res
+}
+
+// parseLink разбирает ссылку на страницу задачи или документа:
+//   - /{workspace}/projects/{project}/issues/{seq}
+//   - /i/{workspace}/{project}/{seq}
+//   - /{workspace}/aidoc/{docId}
+//   - /d/{workspace}/{docId}
+func parseLink(href string, webURL *url.URL) (IssueRef, uuid.UUID, bool) {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil || href == "" {
+		return IssueRef{}, uuid.Nil, false
+	}
+	if u.Host != "" && (webURL == nil || !strings.EqualFold(u.Host, webURL.Host)) {
+		return IssueRef{}, uuid.Nil, false
+	}
+	if u.Host == "" && u.Scheme != "" {
+		return IssueRef{}, uuid.Nil, false
+	}
+
+	parts := strings.FieldsFunc(u.Path, func(r rune) bool { return r == '/' })
+
+	switch {
+	case len(parts) >= 4 && parts[0] == "i":
+		if seq, err := strconv.Atoi(parts[3]); err == nil {
+			return IssueRef{Workspace: parts[1], Project: parts[2], Seq: seq}, uuid.Nil, true
+		}
+	case len(parts) >= 5 && parts[1] == "projects" && parts[3] == "issues":
+		if seq, err := strconv.Atoi(parts[4]); err == nil {
+			return IssueRef{Workspace: parts[0], Project: parts[2], Seq: seq}, uuid.Nil, true
+		}
+	case len(parts) >= 3 && parts[0] == "d":
+		if id, err := uuid.FromString(parts[2]); err == nil && !id.IsNil() {
+			return IssueRef{}, id, true
+		}
+	case len(parts) >= 3 && parts[1] == "aidoc":
+		if id, err := uuid.FromString(parts[2]); err == nil && !id.IsNil() {
+			return IssueRef{}, id, true
+		}
+	}
+	return IssueRef{}, uuid.Nil, false
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,40 @@
+package refs
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/gofrs/uuid"
+)
+
+func TestExtract(t *testing.T) {
+	webURL, _ := url.Parse("https://plan.example.com")
+	docId := uuid.Must(uuid.FromString("7b5d0d3a-0c5b-4bb2-9a0e-6d0f2c4b1a11"))
+
+	body := `<p>См. <span data-type="issueLinkMention" data-slug="team" data-project-identifier="PRJ" data-current-issue-id="12">` +
+		`<a href="https://plan.example.com/i/team/PRJ/12">PRJ-12</a></span></p>` +
+		`<p><a href="/i/team/PRJ/12">еще раз</a> и <a href="https://plan.example.com/team/projects/5c1f/issues/7">задача</a></p>` +
+		`<p><a href="https://plan.example.com/team/aidoc/7b5d0d3a-0c5b-4bb2-9a0e-6d0f2c4b1a11">документ</a>` +
+		`<a href="/team/aidoc/7b5d0d3a-0c5b-4bb2-9a0e-6d0f2c4b1a11/">снова</a>` +
+		`<a href="https://plan.example.com/d/team/7b5d0d3a-0c5b-4bb2-9a0e-6d0f2c4b1a11">коротко</a>` +
+		`<a href="https://other.example.com/i/team/PRJ/1">чужая</a>` +
+		`<a href="mailto:someone@example.com">почта</a>` +
+		`<a href="/team/aidoc/not-a-uuid">битая</a></p>`
+
+	got := Extract(body, webURL)
+	want := Refs{
+		Issues: []IssueRef{
+			{Workspace: "team", Project: "PRJ", Seq: 12},
+			{Workspace: "team", Project: "5c1f", Seq: 7},
+		},
+		Docs: []uuid.UUID{docId},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Extract() = %+v, want %+v", got, want)
+	}
+
+	if !Extract("<p>текст</p>", webURL).Empty() {
+		t.Error("Expected no refs in plain text")
+	}
+}
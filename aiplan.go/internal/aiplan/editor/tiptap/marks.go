@@ -25,6 +25,8 @@ func applyMarks(text *edtypes.Text, marks []TipTapMark) {
 			text.Sup = true
 		case "subscript":
 			text.Sub = true
+		case "code":
+			text.Code = true
 		case "textStyle":
 			applyTextStyle(text, mark.Attrs)
 		case "link":
@@ -84,7 +84,8 @@ func parseCodeBlock(node TipTapNode) *edtypes.Code {
 	}
 
 	return &edtypes.Code{
-		Content: text,
+		Content:  text,
+		Language: getAttrString(node.Attrs, "language"),
 	}
 }
 
@@ -348,10 +349,15 @@ func parseListItem(node TipTapNode) *edtypes.ListElement {
 
 	// Обработать содержимое элемента списка
 	for _, child := range node.Content {
-		if child.Type == "paragraph" {
+		switch child.Type {
+		case "paragraph":
 			if p := parseParagraph(child); p != nil {
 				elem.Content = append(elem.Content, *p)
 			}
+		case "bulletList", "orderedList", "taskList":
+			if list := parseList(child); list != nil {
+				elem.Lists = append(elem.Lists, *list)
+			}
 		}
 	}
 
@@ -137,6 +137,9 @@ func serializeText(t *edtypes.Text) *TipTapNode {
 	if t.Sub {
 		marks = append(marks, TipTapMark{Type: "subscript"})
 	}
+	if t.Code {
+		marks = append(marks, TipTapMark{Type: "code"})
+	}
 
 	// Цвет текста
 	if t.Color != nil {
@@ -188,6 +191,9 @@ func serializeCode(c *edtypes.Code) *TipTapNode {
 		Type: "text",
 		Text: c.Content,
 	}
+	if c.Language != "" {
+		node.Attrs = map[string]interface{}{"language": c.Language}
+	}
 
 	return node
 }
@@ -200,7 +206,7 @@ func serializeQuote(q *edtypes.Quote) *TipTapNode {
 	}
 
 	for _, elem := range q.Content {
-		if childNode := serializeElement(elem); childNode != nil {
+		if childNode := serializeParagraph(&elem); childNode != nil {
 			node.Content = append(node.Content, *childNode)
 		}
 	}
@@ -229,7 +235,9 @@ func serializeList(l *edtypes.List) *TipTapNode {
 			Content: make([]TipTapNode, 0, len(item.Content)),
 		}
 
+		// Элементы чек-листа - taskItem, иначе парсер теряет отметку checked
 		if l.TaskList {
+			itemNode.Type = "taskItem"
 			itemNode.Attrs = map[string]interface{}{
 				"checked": item.Checked,
 			}
@@ -240,6 +248,9 @@ func serializeList(l *edtypes.List) *TipTapNode {
 				itemNode.Content = append(itemNode.Content, *childNode)
 			}
 		}
+		for i := range item.Lists {
+			itemNode.Content = append(itemNode.Content, *serializeList(&item.Lists[i]))
+		}
 
 		node.Content = append(node.Content, itemNode)
 	}
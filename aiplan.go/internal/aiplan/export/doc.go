@@ -0,0 +1,139 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor"
+)
+
+// DocPage - документ для экспорта. При экспорте книгой документы идут в порядке обхода дерева,
+// Level - глубина вложенности относительно корневого документа
+type DocPage struct {
+	Doc   *dao.Doc
+	Level int
+}
+
+// ImageLoader загружает изображение по ссылке из содержимого документа (обычно из файлового хранилища).
+// Возвращает содержимое и MIME-тип
+type ImageLoader func(src *url.URL) (io.ReadCloser, string, error)
+
+// parseDocContent разбирает HTML-содержимое документа
+func parseDocContent(doc *dao.Doc) (*editor.Document, error) {
+	return editor.ParseDocument(strings.NewReader(doc.Content.Body))
+}
+
+// openImage открывает изображение через loadImage, а если он не задан - по ссылке относительно webURL
+func openImage(src *url.URL, webURL *url.URL, loadImage ImageLoader) (io.ReadCloser, string, error) {
+	if loadImage != nil {
+		return loadImage(src)
+	}
+
+	u := src
+	if src.Host == "" && webURL != nil {
+		u = webURL.ResolveReference(src)
+	}
+	resp, err := http.Get(u.String())
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, "", fmt.Errorf("load image %s: %s", u, resp.Status)
+	}
+	return resp.Body, resp.Header.Get("Content-Type"), nil
+}
+
+// forEachParagraph вызывает fn для каждого параграфа документа, включая параграфы списков, таблиц и блоков
+func forEachParagraph(doc *editor.Document, fn func(p editor.Paragraph)) {
+	paragraphs := func(ps []editor.Paragraph) {
+		for _, p := range ps {
+			fn(p)
+		}
+	}
+	var list func(l editor.List)
+	list = func(l editor.List) {
+		for _, e := range l.Elements {
+			paragraphs(e.Content)
+			for _, nested := range e.Lists {
+				list(nested)
+			}
+		}
+	}
+	table := func(t editor.Table) {
+		for _, row := range t.Rows {
+			for _, cell := range row {
+				paragraphs(cell.Content)
+			}
+		}
+	}
+
+	for _, rawElement := range doc.Elements {
+		switch el := rawElement.(type) {
+		case editor.Paragraph:
+			fn(el)
+		case *editor.Paragraph:
+			fn(*el)
+		case editor.Quote:
+			paragraphs(el.Content)
+		case *editor.Quote:
+			paragraphs(el.Content)
+		case editor.List:
+			list(el)
+		case *editor.List:
+			list(*el)
+		case editor.Table:
+			table(el)
+		case *editor.Table:
+			table(*el)
+		case editor.InfoBlock:
+			paragraphs(el.Content)
+		case *editor.InfoBlock:
+			paragraphs(el.Content)
+		case editor.Spoiler:
+			paragraphs(el.Content)
+		case *editor.Spoiler:
+			paragraphs(el.Content)
+		}
+	}
+}
+
+// resolveLinks делает относительные ссылки и адреса изображений абсолютными,
+// чтобы экспортированный файл можно было открыть вне AIPlan
+func resolveLinks(doc *editor.Document, webURL *url.URL) {
+	if webURL == nil {
+		return
+	}
+	forEachParagraph(doc, func(p editor.Paragraph) {
+		// Content разделяет память с исходным параграфом, поэтому изменения сохраняются в документе
+		for i, item := range p.Content {
+			switch el := item.(type) {
+			case editor.Text:
+				if el.URL != nil && el.URL.Host == "" && !strings.HasPrefix(el.URL.String(), "#") {
+					el.URL = webURL.ResolveReference(el.URL)
+					p.Content[i] = el
+				}
+			case *editor.Image:
+				if el.Src != nil && el.Src.Host == "" && el.Src.Scheme == "" {
+					el.Src = webURL.ResolveReference(el.Src)
+				}
+			}
+		}
+	})
+}
+
+// docAuthorLine возвращает строку с автором и датой изменения документа
+func docAuthorLine(doc *dao.Doc) string {
+	var parts []string
+	if doc.Author != nil {
+		parts = append(parts, "Автор: "+doc.Author.GetName())
+	}
+	if !doc.UpdatedAt.IsZero() {
+		parts = append(parts, "Изменен: "+doc.UpdatedAt.Format("02.01.2006 15:04"))
+	}
+	return strings.Join(parts, " · ")
+}
@@ -0,0 +1,91 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"unicode"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor/markdown"
+)
+
+// markdownTitleSizes - размер шрифта заголовка документа по глубине вложенности (# - ####)
+var markdownTitleSizes = []int{24, 20, 18, 16}
+
+// DocsToMarkdown экспортирует документы в Markdown. Если документов несколько, в начале файла
+// добавляется оглавление со ссылками на заголовки документов.
+// Относительные ссылки и адреса изображений становятся абсолютными относительно webURL
+func DocsToMarkdown(pages []DocPage, webURL *url.URL, out io.Writer) error {
+	if len(pages) > 1 {
+		if _, err := io.WriteString(out, markdownTableOfContents(pages)); err != nil {
+			return err
+		}
+	}
+
+	for i, page := range pages {
+		doc, err := parseDocContent(page.Doc)
+		if err != nil {
+			return err
+		}
+		resolveLinks(doc, webURL)
+
+		// Заголовок документа - параграф из жирного текста увеличенного размера, как в редакторе
+		size := markdownTitleSizes[min(page.Level, len(markdownTitleSizes)-1)]
+		title := editor.Paragraph{Content: []any{editor.Text{Content: page.Doc.Title, Strong: true, Size: size}}}
+		doc.Elements = append([]any{title}, doc.Elements...)
+
+		md, err := markdown.Serialize(doc)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			md = append([]byte("\n"), md...)
+		}
+		if _, err := out.Write(md); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// markdownTableOfContents формирует оглавление со ссылками на якоря заголовков в формате GitHub
+func markdownTableOfContents(pages []DocPage) string {
+	var b strings.Builder
+	b.WriteString("## Содержание\n\n")
+
+	used := map[string]int{"содержание": 1}
+	for _, page := range pages {
+		title := strings.TrimSpace(page.Doc.Title)
+		fmt.Fprintf(&b, "%s- [%s](#%s)\n", strings.Repeat("  ", page.Level), escapeLinkText(title), markdownAnchor(title, used))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// markdownAnchor возвращает якорь заголовка: строчные буквы, цифры, дефисы и подчеркивания,
+// пробелы заменяются дефисами, повторяющиеся якоря получают суффикс -1, -2 и т.д.
+func markdownAnchor(title string, used map[string]int) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteRune('-')
+		}
+	}
+
+	anchor := b.String()
+	if n, ok := used[anchor]; ok {
+		used[anchor] = n + 1
+		return fmt.Sprintf("%s-%d", anchor, n)
+	}
+	used[anchor] = 1
+	return anchor
+}
+
+func escapeLinkText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `[`, `\[`, `]`, `\]`).Replace(s)
+}
@@ -0,0 +1,141 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+
+	"codeberg.org/go-pdf/fpdf"
+)
+
+// DocsToFPDF экспортирует документы в PDF. Каждый документ начинается с новой страницы.
+// Если документов несколько (документ с дочерними), они собираются в книгу с оглавлением
+// и закладками по дереву документов
+func DocsToFPDF(pages []DocPage, webURL *url.URL, loadImage ImageLoader, out io.Writer) error {
+	pdf := fpdf.New("P", "mm", "A4", "Rubik/static") // 210*297 mm
+
+	w := pdfWriter{
+		pdf:       pdf,
+		webURL:    webURL,
+		loadImage: loadImage,
+	}
+
+	w.defaultMargins.GetMargins(w.pdf)
+
+	registerFonts(pdf)
+	// Закладки пишутся в UTF-16, только если текущий шрифт - UTF-8
+	pdf.SetFont("Rubik", "", 12)
+	pdf.RegisterImageOptionsReader("logo.png", fpdf.ImageOptions{ImageType: "png"}, bytes.NewReader(aiplanLogo))
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("Rubik", "", 9)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(0, 5, strconv.Itoa(pdf.PageNo()), "", 0, "C", false, 0, "")
+		pdf.SetTextColor(0, 0, 0)
+	})
+
+	book := len(pages) > 1
+	links := make([]int, len(pages))
+	if book {
+		w.writeTableOfContents(pages, links)
+	}
+
+	for i, page := range pages {
+		doc, err := parseDocContent(page.Doc)
+		if err != nil {
+			return err
+		}
+
+		pdf.AddPage()
+		if book {
+			// Номер страницы в оглавлении подставляется при сохранении файла
+			pdf.SetLink(links[i], -1, -1)
+			pdf.RegisterAlias(tocPageAlias(i), strconv.Itoa(pdf.PageNo()))
+		}
+		pdf.Bookmark(page.Doc.Title, page.Level, -1)
+
+		w.writeDocHeader(page)
+		w.writeDescription(doc)
+	}
+
+	return pdf.Output(out)
+}
+
+func tocPageAlias(i int) string {
+	return fmt.Sprintf("{toc%d}", i)
+}
+
+// writeTableOfContents записывает оглавление книги со ссылками на страницы документов
+func (w *pdfWriter) writeTableOfContents(pages []DocPage, links []int) {
+	w.pdf.AddPage()
+	w.pdf.Bookmark("Содержание", 0, -1)
+
+	w.pdf.SetFont("Rubik", "B", 20)
+	w.write("Содержание")
+	w.pdf.Ln(14)
+
+	const (
+		lineHeight  = 7.0
+		indent      = 6.0
+		numberWidth = 15.0
+	)
+
+	maxWidth := w.getMaxContentWidth()
+	for i, page := range pages {
+		links[i] = w.pdf.AddLink()
+
+		if page.Level == 0 {
+			w.pdf.SetFont("Rubik", "B", 12)
+		} else {
+			w.pdf.SetFont("Rubik", "", 12)
+		}
+
+		offset := indent * float64(page.Level)
+		w.pdf.SetX(w.defaultMargins.Left + offset)
+		title := cleanUnsupportedSymbols(page.Doc.Title)
+		titleWidth := maxWidth - offset - numberWidth
+		if w.pdf.GetStringWidth(title) > titleWidth {
+			runes := []rune(title)
+			for len(runes) > 1 && w.pdf.GetStringWidth(string(runes)+"…") > titleWidth {
+				runes = runes[:len(runes)-1]
+			}
+			title = string(runes) + "…"
+		}
+		w.pdf.CellFormat(titleWidth, lineHeight, title, "", 0, "L", false, links[i], "")
+		w.pdf.CellFormat(numberWidth, lineHeight, tocPageAlias(i), "", 1, "R", false, links[i], "")
+	}
+	w.pdf.SetFont("Rubik", "", 12)
+}
+
+// writeDocHeader записывает заголовок документа, автора и дату изменения
+func (w *pdfWriter) writeDocHeader(page DocPage) {
+	if page.Level == 0 {
+		w.pdf.ImageOptions("logo.png", 179, 5, 25, 25, false, fpdf.ImageOptions{ReadDpi: true}, 0, "")
+	}
+
+	w.pdf.SetRightMargin(30)
+	w.pdf.SetTextColor(0, 0, 0)
+	w.pdf.SetFont("Rubik", "B", 20)
+	w.write(cleanUnsupportedSymbols(page.Doc.Title))
+	w.pdf.Ln(10)
+
+	if info := docAuthorLine(page.Doc); info != "" {
+		w.pdf.SetFont("Rubik", "", 10)
+		w.pdf.SetTextColor(120, 120, 120)
+		w.write(cleanUnsupportedSymbols(info))
+		w.pdf.SetTextColor(0, 0, 0)
+		w.pdf.Ln(6)
+	}
+
+	if w.pdf.GetY() < 30 {
+		w.pdf.SetY(30)
+	}
+	w.pdf.SetLineWidth(0.2)
+	w.pdf.Line(w.pdf.GetX(), w.pdf.GetY(), 200, w.pdf.GetY())
+	w.pdf.SetY(w.pdf.GetY() + 5)
+	w.resetMargins()
+	w.pdf.SetFont("Rubik", "", 12)
+}
@@ -0,0 +1,150 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+)
+
+const testDocContent = `<p>Текст со <strong>жирным</strong> и <a href="/docs/1">ссылкой</a>, ` +
+	`<span data-type="mention" data-id="ivanov" data-label="Иван Иванов">@Иван Иванов</span></p>` +
+	`<pre><code>func main() {
+	fmt.Println("hi")
+}</code></pre>` +
+	`<div data-info-block="" data-title="Важно" data-icon-color="#ff0000"><p>Информация</p></div>` +
+	`<table><tbody><tr><th><p>Заголовок</p></th><th><p>Столбец</p></th></tr><tr><td><p>ячейка</p></td><td><p><img src="/api/file/image.png"></p></td></tr></tbody></table>` +
+	`<ol><li><p>первый</p></li><li><p>второй</p></li></ol>`
+
+func testPages() []DocPage {
+	author := &dao.User{FirstName: "Иван", LastName: "Иванов"}
+	return []DocPage{
+		{Doc: &dao.Doc{Title: "Руководство", Author: author, UpdatedAt: time.Now(), Content: types.RedactorHTML{Body: testDocContent}}},
+		{Doc: &dao.Doc{Title: "Установка", Content: types.RedactorHTML{Body: `<p>Шаги установки</p>`}}, Level: 1},
+		{Doc: &dao.Doc{Title: "Установка", Content: types.RedactorHTML{Body: `<p>Повтор</p>`}}, Level: 1},
+	}
+}
+
+func testImageLoader(t *testing.T) ImageLoader {
+	return func(src *url.URL) (io.ReadCloser, string, error) {
+		if src.Path != "/api/file/image.png" {
+			return nil, "", errors.New("not found")
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1000, 500))); err != nil {
+			t.Fatal(err)
+		}
+		return io.NopCloser(&buf), "image/png", nil
+	}
+}
+
+func TestDocsToMarkdown(t *testing.T) {
+	u, _ := url.Parse("https://plan.example.com")
+
+	var buf bytes.Buffer
+	if err := DocsToMarkdown(testPages(), u, &buf); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	md := buf.String()
+
+	for _, want := range []string{
+		"## Содержание\n\n- [Руководство](#руководство)\n  - [Установка](#установка)\n  - [Установка](#установка-1)\n",
+		"# Руководство\n",
+		"## Установка\n\nШаги установки\n",
+		"[ссылкой](https://plan.example.com/docs/1)",
+		"@ivanov",
+		"![](https://plan.example.com/api/file/image.png)",
+		"```\nfunc main() {",
+		"> [!INFO] Важно",
+		"| Заголовок | Столбец |",
+		"1. первый",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Markdown does not contain %q:\n%s", want, md)
+		}
+	}
+}
+
+func TestDocsToDOCX(t *testing.T) {
+	u, _ := url.Parse("https://plan.example.com")
+
+	var buf bytes.Buffer
+	if err := DocsToDOCX(testPages(), u, testImageLoader(t), &buf); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Invalid zip: %v", err)
+	}
+
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(r)
+		r.Close()
+		files[f.Name] = string(data)
+
+		if strings.HasSuffix(f.Name, ".xml") || strings.HasSuffix(f.Name, ".rels") {
+			d := xml.NewDecoder(bytes.NewReader(data))
+			for {
+				if _, err := d.Token(); err == io.EOF {
+					break
+				} else if err != nil {
+					t.Fatalf("Malformed %s: %v", f.Name, err)
+				}
+			}
+		}
+	}
+
+	if _, ok := files["word/media/image1.png"]; !ok {
+		t.Error("Image is not embedded")
+	}
+
+	document := files["word/document.xml"]
+	for _, want := range []string{
+		`TOC \o "1-3"`,
+		`<w:hyperlink w:anchor="_doc1"`,
+		`<w:pStyle w:val="Heading1"/>`,
+		`<w:pStyle w:val="Heading2"/>`,
+		`<w:pStyle w:val="Code"/>`,
+		`<w:tbl>`,
+		`@Иван Иванов`,
+		`Важно`,
+		`<w:numId w:val="1"/>`,
+		`<a:ext cx="6115050" cy="3057525"/>`,
+	} {
+		if !strings.Contains(document, want) {
+			t.Errorf("document.xml does not contain %q", want)
+		}
+	}
+
+	if !strings.Contains(files["word/_rels/document.xml.rels"], `Target="https://plan.example.com/docs/1" TargetMode="External"`) {
+		t.Error("Relative link is not resolved")
+	}
+}
+
+func TestDocsToFPDF(t *testing.T) {
+	u, _ := url.Parse("https://plan.example.com")
+
+	var buf bytes.Buffer
+	if err := DocsToFPDF(testPages(), u, testImageLoader(t), &buf); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+		t.Fatal("Output is not a PDF")
+	}
+}
@@ -0,0 +1,773 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor"
+)
+
+const (
+	// Ширина области текста страницы A4 с полями 2 см в twips и в пикселях (96 dpi)
+	docxContentWidth   = 9638
+	docxContentWidthPx = 642
+	// EMU (единица DrawingML) в одном пикселе
+	docxEMUPerPx = 9525
+
+	docxNumBullet  = 1
+	docxNumDecimal = 2
+)
+
+// DocsToDOCX экспортирует документы в DOCX (Office Open XML).
+// Документы идут в порядке pages, каждый следующий - с новой страницы, заголовки документов
+// оформляются стилями "Заголовок 1-3" по глубине вложенности. Если документов несколько,
+// в начало добавляется оглавление (поле TOC, номера страниц проставляются при обновлении поля).
+// Изображения загружаются через loadImage и встраиваются в файл
+func DocsToDOCX(pages []DocPage, webURL *url.URL, loadImage ImageLoader, out io.Writer) error {
+	w := docxWriter{
+		webURL:    webURL,
+		loadImage: loadImage,
+		images:    make(map[string]*docxMedia),
+	}
+
+	if len(pages) > 1 {
+		w.writeTableOfContents(pages)
+	}
+
+	for i, page := range pages {
+		doc, err := parseDocContent(page.Doc)
+		if err != nil {
+			return err
+		}
+
+		w.writeDocTitle(i, page, i > 0 || len(pages) > 1)
+		w.writeDocument(doc)
+	}
+
+	return w.save(out)
+}
+
+type docxRel struct {
+	id       string
+	relType  string
+	target   string
+	external bool
+}
+
+type docxMedia struct {
+	name   string
+	relID  string
+	data   []byte
+	width  int
+	height int
+}
+
+type docxWriter struct {
+	webURL    *url.URL
+	loadImage ImageLoader
+
+	body  strings.Builder
+	rels  []docxRel
+	media []*docxMedia
+	// Изображения по адресу, nil - изображение не удалось загрузить
+	images map[string]*docxMedia
+	// Экземпляры нумерации списков: для каждого списка свой, чтобы нумерация начиналась с 1
+	nums    []int
+	drawing int
+}
+
+// docxParagraphProps - свойства параграфа, записываются в порядке схемы WordprocessingML
+type docxParagraphProps struct {
+	style     string
+	pageBreak bool
+	numID     int
+	border    string
+	fill      string
+	indent    int
+	align     editor.TextAlign
+}
+
+func (p docxParagraphProps) xml() string {
+	var b strings.Builder
+	if p.style != "" {
+		fmt.Fprintf(&b, `<w:pStyle w:val="%s"/>`, p.style)
+	}
+	if p.pageBreak {
+		b.WriteString(`<w:pageBreakBefore/>`)
+	}
+	if p.numID > 0 {
+		fmt.Fprintf(&b, `<w:numPr><w:ilvl w:val="0"/><w:numId w:val="%d"/></w:numPr>`, p.numID)
+	}
+	if p.border != "" {
+		fmt.Fprintf(&b, `<w:pBdr><w:left w:val="single" w:sz="18" w:space="8" w:color="%s"/></w:pBdr>`, p.border)
+	}
+	if p.fill != "" {
+		fmt.Fprintf(&b, `<w:shd w:val="clear" w:color="auto" w:fill="%s"/>`, p.fill)
+	}
+	if p.indent > 0 {
+		fmt.Fprintf(&b, `<w:ind w:left="%d"/>`, p.indent)
+	}
+	switch p.align {
+	case editor.CenterAlign:
+		b.WriteString(`<w:jc w:val="center"/>`)
+	case editor.RightAlign:
+		b.WriteString(`<w:jc w:val="right"/>`)
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "<w:pPr>" + b.String() + "</w:pPr>"
+}
+
+// docxRunProps - свойства фрагмента текста, записываются в порядке схемы WordprocessingML
+type docxRunProps struct {
+	style     string
+	mono      bool
+	strong    bool
+	italic    bool
+	strike    bool
+	color     string
+	size      int
+	underline bool
+	fill      string
+	vertAlign string
+}
+
+func (r docxRunProps) xml() string {
+	var b strings.Builder
+	if r.style != "" {
+		fmt.Fprintf(&b, `<w:rStyle w:val="%s"/>`, r.style)
+	}
+	if r.mono {
+		b.WriteString(`<w:rFonts w:ascii="Courier New" w:hAnsi="Courier New" w:cs="Courier New"/>`)
+	}
+	if r.strong {
+		b.WriteString(`<w:b/>`)
+	}
+	if r.italic {
+		b.WriteString(`<w:i/>`)
+	}
+	if r.strike {
+		b.WriteString(`<w:strike/>`)
+	}
+	if r.color != "" {
+		fmt.Fprintf(&b, `<w:color w:val="%s"/>`, r.color)
+	}
+	if r.size > 0 {
+		fmt.Fprintf(&b, `<w:sz w:val="%d"/><w:szCs w:val="%d"/>`, r.size, r.size)
+	}
+	if r.underline {
+		b.WriteString(`<w:u w:val="single"/>`)
+	}
+	if r.fill != "" {
+		fmt.Fprintf(&b, `<w:shd w:val="clear" w:color="auto" w:fill="%s"/>`, r.fill)
+	}
+	if r.vertAlign != "" {
+		fmt.Fprintf(&b, `<w:vertAlign w:val="%s"/>`, r.vertAlign)
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "<w:rPr>" + b.String() + "</w:rPr>"
+}
+
+func hexColor(c editor.Color) string {
+	return fmt.Sprintf("%02X%02X%02X", c.R, c.G, c.B)
+}
+
+func escapeXML(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// writeRun записывает фрагмент текста, переводы строк становятся разрывами строки
+func (w *docxWriter) writeRun(text string, props docxRunProps) {
+	w.body.WriteString("<w:r>")
+	w.body.WriteString(props.xml())
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			w.body.WriteString("<w:br/>")
+		}
+		if line != "" {
+			fmt.Fprintf(&w.body, `<w:t xml:space="preserve">%s</w:t>`, escapeXML(line))
+		}
+	}
+	w.body.WriteString("</w:r>")
+}
+
+func (w *docxWriter) addRel(relType, target string, external bool) string {
+	id := fmt.Sprintf("rId%d", len(w.rels)+10)
+	w.rels = append(w.rels, docxRel{id: id, relType: relType, target: target, external: external})
+	return id
+}
+
+func (w *docxWriter) writeLink(text, link string, props docxRunProps) {
+	id := w.addRel("http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink", link, true)
+	props.style = "Hyperlink"
+	fmt.Fprintf(&w.body, `<w:hyperlink r:id="%s" w:history="1">`, id)
+	w.writeRun(text, props)
+	w.body.WriteString("</w:hyperlink>")
+}
+
+func (w *docxWriter) textProps(t editor.Text) docxRunProps {
+	props := docxRunProps{
+		mono:      t.Code,
+		strong:    t.Strong,
+		italic:    t.Italic,
+		strike:    t.Strikethrough,
+		underline: t.Underlined,
+	}
+	if t.Size > 0 {
+		// Размер в половинах пункта: px * 0.75 * 2
+		props.size = t.Size * 3 / 2
+	}
+	if t.Color != nil {
+		props.color = hexColor(*t.Color)
+	}
+	if t.BgColor != nil {
+		props.fill = hexColor(*t.BgColor)
+	} else if t.Code {
+		props.fill = "EFEFF6"
+	}
+	if t.Sup {
+		props.vertAlign = "superscript"
+	} else if t.Sub {
+		props.vertAlign = "subscript"
+	}
+	return props
+}
+
+func (w *docxWriter) writeParagraph(p editor.Paragraph, props docxParagraphProps) {
+	w.writeParagraphWithPrefix(p, props, "")
+}
+
+// writeParagraphWithPrefix записывает параграф, prefix - текст перед содержимым (отметка чек-листа)
+func (w *docxWriter) writeParagraphWithPrefix(p editor.Paragraph, props docxParagraphProps, prefix string) {
+	if props.align == editor.LeftAlign {
+		props.align = p.Align
+	}
+	if p.Indent > 0 {
+		props.indent += p.Indent * 360
+	}
+
+	w.body.WriteString("<w:p>")
+	w.body.WriteString(props.xml())
+	if prefix != "" {
+		w.writeRun(prefix, docxRunProps{})
+	}
+	for _, item := range p.Content {
+		switch el := item.(type) {
+		case editor.Text:
+			if el.URL != nil {
+				w.writeLink(el.Content, w.absoluteURL(el.URL), w.textProps(el))
+			} else {
+				w.writeRun(el.Content, w.textProps(el))
+			}
+		case *editor.Image:
+			w.writeImage(el)
+		case *editor.HardBreak:
+			w.body.WriteString("<w:r><w:br/></w:r>")
+		case *editor.DateNode:
+			w.writeRun(" "+formatDate(el.Date)+" ", docxRunProps{fill: "CCCCCC"})
+		case *editor.Mention:
+			w.writeRun(" @"+el.Label+" ", docxRunProps{color: "474A52", fill: "BDBDBD"})
+		case *editor.IssueLinkMention:
+			text := el.ProjectIdentifier + "-" + el.CurrentIssueId
+			if el.OriginalUrl != "" {
+				w.writeLink(text, el.OriginalUrl, docxRunProps{})
+			} else {
+				w.writeRun(text, docxRunProps{color: "0066CC"})
+			}
+		}
+	}
+	w.body.WriteString("</w:p>")
+}
+
+func (w *docxWriter) absoluteURL(u *url.URL) string {
+	if u.Host == "" && w.webURL != nil {
+		return w.webURL.ResolveReference(u).String()
+	}
+	return u.String()
+}
+
+// loadMedia загружает изображение и добавляет его в файл, повторные ссылки используют тот же файл
+func (w *docxWriter) loadMedia(src *url.URL) *docxMedia {
+	key := src.String()
+	if m, ok := w.images[key]; ok {
+		return m
+	}
+	w.images[key] = nil
+
+	r, _, err := openImage(src, w.webURL, w.loadImage)
+	if err != nil {
+		slog.Warn("Load image for docx export", "src", key, "err", err)
+		return nil
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		slog.Warn("Read image for docx export", "src", key, "err", err)
+		return nil
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		// Неподдерживаемый формат (например, SVG), изображение останется ссылкой
+		return nil
+	}
+	if format == "jpeg" {
+		format = "jpg"
+	}
+
+	m := &docxMedia{
+		name:   fmt.Sprintf("image%d.%s", len(w.media)+1, format),
+		data:   data,
+		width:  cfg.Width,
+		height: cfg.Height,
+	}
+	m.relID = w.addRel("http://schemas.openxmlformats.org/officeDocument/2006/relationships/image", "media/"+m.name, false)
+	w.media = append(w.media, m)
+	w.images[key] = m
+	return m
+}
+
+func (w *docxWriter) writeImage(img *editor.Image) {
+	if img.Src == nil {
+		return
+	}
+	m := w.loadMedia(img.Src)
+	if m == nil {
+		w.writeLink("Изображение", w.absoluteURL(img.Src), docxRunProps{})
+		return
+	}
+
+	width, height := m.width, m.height
+	if img.Width > 0 && width > 0 {
+		height = height * img.Width / width
+		width = img.Width
+	}
+	if width > docxContentWidthPx {
+		height = height * docxContentWidthPx / width
+		width = docxContentWidthPx
+	}
+	cx, cy := width*docxEMUPerPx, height*docxEMUPerPx
+
+	w.drawing++
+	fmt.Fprintf(&w.body, `<w:r><w:drawing><wp:inline distT="0" distB="0" distL="0" distR="0">`+
+		`<wp:extent cx="%d" cy="%d"/><wp:docPr id="%d" name="Picture %d"/>`+
+		`<a:graphic xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main">`+
+		`<a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/picture">`+
+		`<pic:pic xmlns:pic="http://schemas.openxmlformats.org/drawingml/2006/picture">`+
+		`<pic:nvPicPr><pic:cNvPr id="%d" name="%s"/><pic:cNvPicPr/></pic:nvPicPr>`+
+		`<pic:blipFill><a:blip r:embed="%s"/><a:stretch><a:fillRect/></a:stretch></pic:blipFill>`+
+		`<pic:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="%d" cy="%d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></pic:spPr>`+
+		`</pic:pic></a:graphicData></a:graphic></wp:inline></w:drawing></w:r>`,
+		cx, cy, w.drawing, w.drawing, w.drawing, m.name, m.relID, cx, cy)
+}
+
+// writeTableOfContents записывает оглавление: поле TOC с уже заполненными ссылками на документы
+func (w *docxWriter) writeTableOfContents(pages []DocPage) {
+	w.body.WriteString(`<w:p><w:pPr><w:pStyle w:val="TOCHeading"/></w:pPr>`)
+	w.writeRun("Содержание", docxRunProps{})
+	w.body.WriteString(`</w:p>`)
+
+	for i, page := range pages {
+		fmt.Fprintf(&w.body, `<w:p><w:pPr><w:pStyle w:val="TOC%d"/></w:pPr>`, min(page.Level, 2)+1)
+		if i == 0 {
+			w.body.WriteString(`<w:r><w:fldChar w:fldCharType="begin" w:dirty="true"/></w:r>` +
+				`<w:r><w:instrText xml:space="preserve"> TOC \o "1-3" \h \z \u </w:instrText></w:r>` +
+				`<w:r><w:fldChar w:fldCharType="separate"/></w:r>`)
+		}
+		fmt.Fprintf(&w.body, `<w:hyperlink w:anchor="%s" w:history="1">`, docxBookmark(i))
+		w.writeRun(page.Doc.Title, docxRunProps{})
+		w.body.WriteString(`</w:hyperlink>`)
+		if i == len(pages)-1 {
+			w.body.WriteString(`<w:r><w:fldChar w:fldCharType="end"/></w:r>`)
+		}
+		w.body.WriteString(`</w:p>`)
+	}
+}
+
+func docxBookmark(i int) string {
+	return fmt.Sprintf("_doc%d", i)
+}
+
+// writeDocTitle записывает заголовок документа с закладкой для оглавления, автора и дату изменения
+func (w *docxWriter) writeDocTitle(i int, page DocPage, pageBreak bool) {
+	props := docxParagraphProps{
+		style:     fmt.Sprintf("Heading%d", min(page.Level, 2)+1),
+		pageBreak: pageBreak,
+	}
+	w.body.WriteString("<w:p>")
+	w.body.WriteString(props.xml())
+	fmt.Fprintf(&w.body, `<w:bookmarkStart w:id="%d" w:name="%s"/>`, i, docxBookmark(i))
+	w.writeRun(page.Doc.Title, docxRunProps{})
+	fmt.Fprintf(&w.body, `<w:bookmarkEnd w:id="%d"/>`, i)
+	w.body.WriteString("</w:p>")
+
+	if info := docAuthorLine(page.Doc); info != "" {
+		w.body.WriteString("<w:p>")
+		w.writeRun(info, docxRunProps{color: "787878", size: 18})
+		w.body.WriteString("</w:p>")
+	}
+}
+
+func (w *docxWriter) writeDocument(doc *editor.Document) {
+	for _, rawElement := range doc.Elements {
+		switch el := rawElement.(type) {
+		case editor.Paragraph:
+			w.writeParagraph(el, docxParagraphProps{})
+		case *editor.Paragraph:
+			w.writeParagraph(*el, docxParagraphProps{})
+		case editor.Quote:
+			w.writeQuote(el)
+		case *editor.Quote:
+			w.writeQuote(*el)
+		case editor.List:
+			w.writeList(el)
+		case *editor.List:
+			w.writeList(*el)
+		case editor.Table:
+			w.writeTable(el)
+		case *editor.Table:
+			w.writeTable(*el)
+		case editor.Code:
+			w.writeCodeBlock(el)
+		case *editor.Code:
+			w.writeCodeBlock(*el)
+		case editor.InfoBlock:
+			w.writeInfoBlock(el)
+		case *editor.InfoBlock:
+			w.writeInfoBlock(*el)
+		case editor.Spoiler:
+			w.writeSpoiler(el)
+		case *editor.Spoiler:
+			w.writeSpoiler(*el)
+		}
+	}
+}
+
+func (w *docxWriter) writeQuote(q editor.Quote) {
+	for _, p := range q.Content {
+		w.writeParagraph(p, docxParagraphProps{border: "4A4752", indent: 284})
+	}
+}
+
+func (w *docxWriter) writeList(list editor.List) {
+	w.writeNestedList(list, 0)
+}
+
+// writeNestedList записывает список уровня вложенности level, вложенные списки смещаются вправо
+func (w *docxWriter) writeNestedList(list editor.List, level int) {
+	numID := 0
+	if !list.TaskList {
+		abstract := docxNumBullet
+		if list.Numbered {
+			abstract = docxNumDecimal
+		}
+		w.nums = append(w.nums, abstract)
+		numID = len(w.nums)
+	}
+
+	indent := 720 * (level + 1)
+	for _, e := range list.Elements {
+		for i, p := range e.Content {
+			props := docxParagraphProps{indent: indent}
+			prefix := ""
+			if i == 0 {
+				if list.TaskList {
+					prefix = "☐ "
+					if e.Checked {
+						prefix = "☒ "
+					}
+				} else {
+					props = docxParagraphProps{numID: numID}
+					if level > 0 {
+						props.indent = indent
+					}
+				}
+			}
+			w.writeParagraphWithPrefix(p, props, prefix)
+		}
+		for _, nested := range e.Lists {
+			w.writeNestedList(nested, level+1)
+		}
+	}
+}
+
+func (w *docxWriter) writeCodeBlock(code editor.Code) {
+	w.body.WriteString(`<w:p><w:pPr><w:pStyle w:val="Code"/></w:pPr>`)
+	w.writeRun(code.Content, docxRunProps{})
+	w.body.WriteString(`</w:p>`)
+}
+
+// writeInfoBlock записывает информационный блок таблицей из одной ячейки с цветной левой границей
+func (w *docxWriter) writeInfoBlock(ib editor.InfoBlock) {
+	r, g, b := lightenColor(ib.Color, 0.85)
+	color := hexColor(ib.Color)
+
+	fmt.Fprintf(&w.body, `<w:tbl><w:tblPr><w:tblW w:w="%d" w:type="dxa"/>`+
+		`<w:tblBorders><w:left w:val="single" w:sz="24" w:space="0" w:color="%s"/></w:tblBorders></w:tblPr>`+
+		`<w:tblGrid><w:gridCol w:w="%d"/></w:tblGrid><w:tr><w:tc>`+
+		`<w:tcPr><w:tcW w:w="%d" w:type="dxa"/><w:shd w:val="clear" w:color="auto" w:fill="%02X%02X%02X"/></w:tcPr>`,
+		docxContentWidth, color, docxContentWidth, docxContentWidth, r, g, b)
+
+	w.body.WriteString("<w:p>")
+	w.writeRun(ib.Title, docxRunProps{strong: true, color: color})
+	w.body.WriteString("</w:p>")
+	for _, p := range ib.Content {
+		w.writeParagraph(p, docxParagraphProps{})
+	}
+	w.body.WriteString(`</w:tc></w:tr></w:tbl><w:p/>`)
+}
+
+// writeSpoiler записывает спойлер развернутым: заголовок на цветном фоне и содержимое с отступом
+func (w *docxWriter) writeSpoiler(s editor.Spoiler) {
+	w.body.WriteString("<w:p>")
+	w.body.WriteString(docxParagraphProps{fill: hexColor(s.BgColor)}.xml())
+	w.writeRun("▾ "+s.Title, docxRunProps{strong: true, color: hexColor(s.Color)})
+	w.body.WriteString("</w:p>")
+	for _, p := range s.Content {
+		w.writeParagraph(p, docxParagraphProps{indent: 397})
+	}
+}
+
+func (w *docxWriter) writeTable(table editor.Table) {
+	cols := 0
+	for _, row := range table.Rows {
+		n := 0
+		for _, cell := range row {
+			n += max(cell.ColSpan, 1)
+		}
+		cols = max(cols, n)
+	}
+	if cols == 0 {
+		return
+	}
+
+	widths := make([]int, cols)
+	if len(table.ColWidth) == cols && table.MinWidth > 0 {
+		sum := 0
+		for _, cw := range table.ColWidth {
+			sum += cw
+		}
+		auto := (table.MinWidth - sum) / max(cols, 1)
+		total := 0
+		for i, cw := range table.ColWidth {
+			if cw == 0 {
+				cw = max(auto, 1)
+			}
+			widths[i] = cw
+			total += cw
+		}
+		for i := range widths {
+			widths[i] = widths[i] * docxContentWidth / total
+		}
+	} else {
+		for i := range widths {
+			widths[i] = docxContentWidth / cols
+		}
+	}
+
+	fmt.Fprintf(&w.body, `<w:tbl><w:tblPr><w:tblStyle w:val="TableGrid"/><w:tblW w:w="%d" w:type="dxa"/></w:tblPr><w:tblGrid>`, docxContentWidth)
+	for _, cw := range widths {
+		fmt.Fprintf(&w.body, `<w:gridCol w:w="%d"/>`, cw)
+	}
+	w.body.WriteString(`</w:tblGrid>`)
+
+	for _, row := range table.Rows {
+		w.body.WriteString("<w:tr>")
+		col := 0
+		for _, cell := range row {
+			span := max(cell.ColSpan, 1)
+			width := 0
+			for i := col; i < col+span && i < cols; i++ {
+				width += widths[i]
+			}
+			col += span
+
+			fmt.Fprintf(&w.body, `<w:tc><w:tcPr><w:tcW w:w="%d" w:type="dxa"/>`, width)
+			if span > 1 {
+				fmt.Fprintf(&w.body, `<w:gridSpan w:val="%d"/>`, span)
+			}
+			if cell.Header {
+				w.body.WriteString(`<w:shd w:val="clear" w:color="auto" w:fill="E5EDFA"/>`)
+			}
+			w.body.WriteString(`</w:tcPr>`)
+
+			// Ячейка должна содержать хотя бы один параграф
+			if len(cell.Content) == 0 {
+				w.body.WriteString("<w:p/>")
+			}
+			for _, p := range cell.Content {
+				if cell.Header {
+					p = boldParagraph(p)
+				}
+				w.writeParagraph(p, docxParagraphProps{})
+			}
+			w.body.WriteString("</w:tc>")
+		}
+		w.body.WriteString("</w:tr>")
+	}
+	w.body.WriteString(`</w:tbl><w:p/>`)
+}
+
+// boldParagraph возвращает копию параграфа с жирным текстом
+func boldParagraph(p editor.Paragraph) editor.Paragraph {
+	content := make([]any, len(p.Content))
+	for i, item := range p.Content {
+		if t, ok := item.(editor.Text); ok {
+			t.Strong = true
+			item = t
+		}
+		content[i] = item
+	}
+	p.Content = content
+	return p
+}
+
+// save записывает пакет DOCX
+func (w *docxWriter) save(out io.Writer) error {
+	zw := zip.NewWriter(out)
+
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", docxContentTypes},
+		{"_rels/.rels", docxPackageRels},
+		{"word/document.xml", docxDocumentHeader + w.body.String() + docxDocumentFooter},
+		{"word/styles.xml", docxStyles},
+		{"word/settings.xml", docxSettings},
+		{"word/numbering.xml", w.numberingXML()},
+		{"word/_rels/document.xml.rels", w.relsXML()},
+	}
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.content); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range w.media {
+		fw, err := zw.Create("word/media/" + m.name)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(m.data); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func (w *docxWriter) relsXML() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	b.WriteString(`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`)
+	b.WriteString(`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/settings" Target="settings.xml"/>`)
+	b.WriteString(`<Relationship Id="rId3" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/numbering" Target="numbering.xml"/>`)
+	for _, rel := range w.rels {
+		mode := ""
+		if rel.external {
+			mode = ` TargetMode="External"`
+		}
+		fmt.Fprintf(&b, `<Relationship Id="%s" Type="%s" Target="%s"%s/>`, rel.id, rel.relType, escapeXML(rel.target), mode)
+	}
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+func (w *docxWriter) numberingXML() string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<w:numbering xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">`)
+	fmt.Fprintf(&b, `<w:abstractNum w:abstractNumId="%d"><w:multiLevelType w:val="singleLevel"/>`+
+		`<w:lvl w:ilvl="0"><w:start w:val="1"/><w:numFmt w:val="bullet"/><w:lvlText w:val="•"/><w:lvlJc w:val="left"/>`+
+		`<w:pPr><w:ind w:left="720" w:hanging="360"/></w:pPr></w:lvl></w:abstractNum>`, docxNumBullet)
+	fmt.Fprintf(&b, `<w:abstractNum w:abstractNumId="%d"><w:multiLevelType w:val="singleLevel"/>`+
+		`<w:lvl w:ilvl="0"><w:start w:val="1"/><w:numFmt w:val="decimal"/><w:lvlText w:val="%%1."/><w:lvlJc w:val="left"/>`+
+		`<w:pPr><w:ind w:left="720" w:hanging="360"/></w:pPr></w:lvl></w:abstractNum>`, docxNumDecimal)
+	for i, abstract := range w.nums {
+		fmt.Fprintf(&b, `<w:num w:numId="%d"><w:abstractNumId w:val="%d"/>`+
+			`<w:lvlOverride w:ilvl="0"><w:startOverride w:val="1"/></w:lvlOverride></w:num>`, i+1, abstract)
+	}
+	b.WriteString(`</w:numbering>`)
+	return b.String()
+}
+
+const docxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Default Extension="png" ContentType="image/png"/>` +
+	`<Default Extension="jpg" ContentType="image/jpeg"/>` +
+	`<Default Extension="gif" ContentType="image/gif"/>` +
+	`<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>` +
+	`<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>` +
+	`<Override PartName="/word/settings.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.settings+xml"/>` +
+	`<Override PartName="/word/numbering.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.numbering+xml"/>` +
+	`</Types>`
+
+const docxPackageRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>` +
+	`</Relationships>`
+
+const docxDocumentHeader = xml.Header + `<w:document` +
+	` xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"` +
+	` xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"` +
+	` xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing"` +
+	` xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"` +
+	` xmlns:pic="http://schemas.openxmlformats.org/drawingml/2006/picture"><w:body>`
+
+const docxDocumentFooter = `<w:sectPr><w:pgSz w:w="11906" w:h="16838"/>` +
+	`<w:pgMar w:top="1134" w:right="1134" w:bottom="1134" w:left="1134" w:header="709" w:footer="709" w:gutter="0"/>` +
+	`</w:sectPr></w:body></w:document>`
+
+// Поля в документе (оглавление) обновляются при открытии
+const docxSettings = xml.Header + `<w:settings xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` +
+	`<w:updateFields w:val="true"/></w:settings>`
+
+const docxStyles = xml.Header + `<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` +
+	`<w:docDefaults><w:rPrDefault><w:rPr><w:rFonts w:ascii="Arial" w:hAnsi="Arial" w:cs="Arial" w:eastAsia="Arial"/>` +
+	`<w:sz w:val="22"/><w:szCs w:val="22"/><w:lang w:val="ru-RU"/></w:rPr></w:rPrDefault>` +
+	`<w:pPrDefault><w:pPr><w:spacing w:after="120" w:line="276" w:lineRule="auto"/></w:pPr></w:pPrDefault></w:docDefaults>` +
+	`<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/>` +
+	`<w:pPr><w:keepNext/><w:spacing w:before="240" w:after="120"/><w:outlineLvl w:val="0"/></w:pPr><w:rPr><w:b/><w:sz w:val="36"/><w:szCs w:val="36"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading2"><w:name w:val="heading 2"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/>` +
+	`<w:pPr><w:keepNext/><w:spacing w:before="240" w:after="120"/><w:outlineLvl w:val="1"/></w:pPr><w:rPr><w:b/><w:sz w:val="30"/><w:szCs w:val="30"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Heading3"><w:name w:val="heading 3"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/>` +
+	`<w:pPr><w:keepNext/><w:spacing w:before="200" w:after="100"/><w:outlineLvl w:val="2"/></w:pPr><w:rPr><w:b/><w:sz w:val="26"/><w:szCs w:val="26"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="TOCHeading"><w:name w:val="TOC Heading"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/>` +
+	`<w:pPr><w:keepNext/><w:spacing w:before="240" w:after="240"/></w:pPr><w:rPr><w:b/><w:sz w:val="36"/><w:szCs w:val="36"/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="TOC1"><w:name w:val="toc 1"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:spacing w:after="60"/></w:pPr><w:rPr><w:b/></w:rPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="TOC2"><w:name w:val="toc 2"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:spacing w:after="60"/><w:ind w:left="340"/></w:pPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="TOC3"><w:name w:val="toc 3"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:spacing w:after="60"/><w:ind w:left="680"/></w:pPr></w:style>` +
+	`<w:style w:type="paragraph" w:styleId="Code"><w:name w:val="Code"/><w:basedOn w:val="Normal"/>` +
+	`<w:pPr><w:pBdr><w:top w:val="single" w:sz="4" w:space="4" w:color="CCCCCC"/><w:left w:val="single" w:sz="4" w:space="4" w:color="CCCCCC"/>` +
+	`<w:bottom w:val="single" w:sz="4" w:space="4" w:color="CCCCCC"/><w:right w:val="single" w:sz="4" w:space="4" w:color="CCCCCC"/></w:pBdr>` +
+	`<w:shd w:val="clear" w:color="auto" w:fill="EFEFF6"/><w:spacing w:after="120" w:line="240" w:lineRule="auto"/></w:pPr>` +
+	`<w:rPr><w:rFonts w:ascii="Courier New" w:hAnsi="Courier New" w:cs="Courier New"/><w:sz w:val="18"/><w:szCs w:val="18"/></w:rPr></w:style>` +
+	`<w:style w:type="character" w:styleId="Hyperlink"><w:name w:val="Hyperlink"/><w:rPr><w:color w:val="0563C1"/><w:u w:val="single"/></w:rPr></w:style>` +
+	`<w:style w:type="table" w:styleId="TableGrid"><w:name w:val="Table Grid"/><w:tblPr>` +
+	`<w:tblBorders><w:top w:val="single" w:sz="4" w:space="0" w:color="auto"/><w:left w:val="single" w:sz="4" w:space="0" w:color="auto"/>` +
+	`<w:bottom w:val="single" w:sz="4" w:space="0" w:color="auto"/><w:right w:val="single" w:sz="4" w:space="0" w:color="auto"/>` +
+	`<w:insideH w:val="single" w:sz="4" w:space="0" w:color="auto"/><w:insideV w:val="single" w:sz="4" w:space="0" w:color="auto"/></w:tblBorders>` +
+	`<w:tblCellMar><w:left w:w="108" w:type="dxa"/><w:right w:w="108" w:type="dxa"/></w:tblCellMar></w:tblPr></w:style>` +
+	`</w:styles>`
@@ -16,6 +16,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"log/slog"
 	"math"
 	"net/http"
 	"net/url"
@@ -52,6 +53,9 @@ type pdfWriter struct {
 	comments []dao.IssueComment
 	webURL   *url.URL
 
+	// Загрузка изображений из файлового хранилища, если не задана - по ссылке
+	loadImage ImageLoader
+
 	defaultMargins Margins
 }
 
@@ -78,10 +82,7 @@ func IssueToFPDF(issue *dao.Issue, webURL *url.URL, out io.Writer, comments ...d
 
 	w.defaultMargins.GetMargins(w.pdf)
 
-	pdf.AddUTF8FontFromBytes("Rubik", "", regularFont)
-	pdf.AddUTF8FontFromBytes("Rubik", "I", italicFont)
-	pdf.AddUTF8FontFromBytes("Rubik", "B", boldFont)
-	pdf.AddUTF8FontFromBytes("Rubik", "BI", boldItalicFont)
+	registerFonts(pdf)
 
 	// Register embed images
 	pdf.RegisterImageOptionsReader("logo.png", fpdf.ImageOptions{ImageType: "png"}, bytes.NewReader(aiplanLogo))
@@ -142,6 +143,13 @@ func IssueToFPDF(issue *dao.Issue, webURL *url.URL, out io.Writer, comments ...d
 	return pdf.Output(out)
 }
 
+func registerFonts(pdf *fpdf.Fpdf) {
+	pdf.AddUTF8FontFromBytes("Rubik", "", regularFont)
+	pdf.AddUTF8FontFromBytes("Rubik", "I", italicFont)
+	pdf.AddUTF8FontFromBytes("Rubik", "B", boldFont)
+	pdf.AddUTF8FontFromBytes("Rubik", "BI", boldItalicFont)
+}
+
 func (w *pdfWriter) writeDescription(doc *editor.Document) error {
 	for _, rawElement := range doc.Elements {
 		switch el := rawElement.(type) {
@@ -206,8 +214,15 @@ func (w *pdfWriter) writeDescription(doc *editor.Document) error {
 }
 
 func (w *pdfWriter) writeList(list editor.List) {
-	w.pdf.SetLeftMargin(13)
+	w.writeNestedList(list, 0)
+	w.pdf.SetLeftMargin(10)
+}
+
+// writeNestedList выводит список уровня вложенности level, вложенные списки смещаются вправо
+func (w *pdfWriter) writeNestedList(list editor.List, level int) {
+	offset := float64(level) * 5
 	for i, e := range list.Elements {
+		w.pdf.SetLeftMargin(13 + offset)
 		if list.TaskList {
 			w.writeTaskListMarker(e.Checked)
 		} else if list.Numbered {
@@ -217,11 +232,13 @@ func (w *pdfWriter) writeList(list editor.List) {
 		}
 
 		for _, p := range e.Content {
-			w.pdf.SetX(18)
+			w.pdf.SetX(18 + offset)
 			w.writeParagraph(p)
 		}
+		for _, nested := range e.Lists {
+			w.writeNestedList(nested, level+1)
+		}
 	}
-	w.pdf.SetLeftMargin(10)
 }
 
 func (w *pdfWriter) writeTaskListMarker(checked bool) {
@@ -422,6 +439,21 @@ func cleanUnsupportedSymbols(text string) string {
 
 func (w *pdfWriter) getEditorImageInfo(img *editor.Image) *fpdf.ImageInfoType {
 	info := w.pdf.GetImageInfo(img.Src.Path)
+	if info == nil && w.loadImage != nil {
+		r, contentType, err := w.loadImage(img.Src)
+		if err != nil {
+			slog.Warn("Load doc image", "src", img.Src.String(), "err", err)
+			return nil
+		}
+		defer r.Close()
+
+		options := fpdf.ImageOptions{ImageType: w.pdf.ImageTypeFromMime(contentType), ReadDpi: true}
+		if options.ImageType == "" {
+			w.pdf.ClearError()
+			return nil
+		}
+		return w.pdf.RegisterImageOptionsReader(img.Src.Path, options, r)
+	}
 	if info == nil {
 		u := img.Src
 		if img.Src.Host == "" && img.Src.Scheme != "file" {
@@ -0,0 +1,217 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Table - таблица для выгрузки в CSV и XLSX. Значения ячеек: string, float64, int, bool, time.Time, TableLink или nil
+type Table struct {
+	Header []string
+	Rows   [][]any
+}
+
+// TableLink - ссылка в ячейке таблицы. В XLSX записывается формулой HYPERLINK, в CSV - адресом
+type TableLink struct {
+	URL  string
+	Text string
+}
+
+const tableDateFormat = "02.01.2006 15:04"
+
+// xlsxMaxCellText - ограничение длины текста ячейки XLSX
+const xlsxMaxCellText = 32767
+
+// WriteCSV записывает таблицу в CSV. Файл начинается с BOM, чтобы Excel определял кодировку UTF-8
+func (t *Table) WriteCSV(out io.Writer) error {
+	if _, err := io.WriteString(out, "\ufeff"); err != nil {
+		return err
+	}
+	w := csv.NewWriter(out)
+	header := make([]string, len(t.Header))
+	for i, h := range t.Header {
+		header[i] = csvText(h)
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+	record := make([]string, len(t.Header))
+	for _, row := range t.Rows {
+		record = record[:0]
+		for _, v := range row {
+			record = append(record, csvValue(v))
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func csvValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return csvText(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return "Да"
+		}
+		return "Нет"
+	case time.Time:
+		return v.Format(tableDateFormat)
+	case TableLink:
+		return csvText(v.URL)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// csvText экранирует текст, который табличный редактор выполнил бы как формулу
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// WriteXLSX записывает таблицу в XLSX (Office Open XML) с одним листом sheet.
+// Строка заголовка закрепляется и выделяется жирным, числа записываются числовыми ячейками
+func (t *Table) WriteXLSX(sheet string, out io.Writer) error {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	b.WriteString(`<sheetData>`)
+
+	b.WriteString(`<row r="1">`)
+	for i, h := range t.Header {
+		fmt.Fprintf(&b, `<c r="%s1" t="inlineStr" s="1"><is><t>%s</t></is></c>`, xlsxColumn(i), escapeXML(xlsxText(h)))
+	}
+	b.WriteString(`</row>`)
+
+	for r, row := range t.Rows {
+		fmt.Fprintf(&b, `<row r="%d">`, r+2)
+		for i, v := range row {
+			ref := xlsxColumn(i) + strconv.Itoa(r+2)
+			switch v := v.(type) {
+			case nil:
+				continue
+			case float64:
+				fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'f', -1, 64))
+			case int:
+				fmt.Fprintf(&b, `<c r="%s"><v>%d</v></c>`, ref, v)
+			case TableLink:
+				text := v.Text
+				if text == "" {
+					text = v.URL
+				}
+				formula := fmt.Sprintf(`HYPERLINK("%s","%s")`, strings.ReplaceAll(v.URL, `"`, `""`), strings.ReplaceAll(text, `"`, `""`))
+				fmt.Fprintf(&b, `<c r="%s" t="str" s="2"><f>%s</f><v>%s</v></c>`, ref, escapeXML(formula), escapeXML(xlsxText(text)))
+			default:
+				fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, escapeXML(xlsxText(csvValue(v))))
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+
+	zw := zip.NewWriter(out)
+	files := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxPackageRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, escapeXML(xlsxSheetName(sheet)))},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+		{"xl/worksheets/sheet1.xml", b.String()},
+	}
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.content); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// xlsxColumn возвращает буквенное обозначение столбца по индексу: 0 - A, 26 - AA
+func xlsxColumn(i int) string {
+	var res []byte
+	for i++; i > 0; i = (i - 1) / 26 {
+		res = append([]byte{byte('A' + (i-1)%26)}, res...)
+	}
+	return string(res)
+}
+
+func xlsxText(s string) string {
+	if runes := []rune(s); len(runes) > xlsxMaxCellText {
+		return string(runes[:xlsxMaxCellText])
+	}
+	return s
+}
+
+// xlsxSheetName убирает из названия листа недопустимые символы и ограничивает длину 31 символом
+func xlsxSheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return ' '
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	if name == "" {
+		return "Sheet1"
+	}
+	return name
+}
+
+const xlsxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const xlsxPackageRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbook = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>` +
+	`</workbook>`
+
+const xlsxWorkbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+// xlsxStyles - стили ячеек: 0 - обычный, 1 - заголовок (жирный), 2 - ссылка (синий подчеркнутый)
+const xlsxStyles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="3"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font>` +
+	`<font><u/><sz val="11"/><color rgb="FF0563C1"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="3"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="0" fontId="2" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>` +
+	`</styleSheet>`
@@ -0,0 +1,84 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestTableCSV(t *testing.T) {
+	table := Table{
+		Header: []string{"№", "Ответ", "Файл"},
+		Rows: [][]any{
+			{1, "текст, с запятой", TableLink{URL: "https://example.com/f", Text: "f.png"}},
+			{2.5, true, nil},
+			{-3, "=HYPERLINK(\"https://evil.example.com\")", "@SUM(A1)"},
+			{4, "+1", "-1"},
+			{5, "\tcmd", "\rcmd"},
+		},
+	}
+	var buf bytes.Buffer
+	if err := table.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "\ufeff№,Ответ,Файл\n1,\"текст, с запятой\",https://example.com/f\n2.5,Да,\n" +
+		"-3,\"'=HYPERLINK(\"\"https://evil.example.com\"\")\",'@SUM(A1)\n" +
+		"4,'+1,'-1\n" +
+		"5,'\tcmd,\"'\rcmd\"\n"
+	if buf.String() != want {
+		t.Errorf("CSV = %q", buf.String())
+	}
+}
+
+func TestTableXLSX(t *testing.T) {
+	table := Table{
+		Header: []string{"№", "Ответ"},
+		Rows:   [][]any{{1, `<a & "b">`}, {2, TableLink{URL: "https://example.com/?a=1&b=2", Text: "файл"}}},
+	}
+	var buf bytes.Buffer
+	if err := table.WriteXLSX("Ответы: форма", &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(r)
+		r.Close()
+		files[f.Name] = string(data)
+
+		// Все части должны быть корректным XML
+		d := xml.NewDecoder(bytes.NewReader(data))
+		for {
+			if _, err := d.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s: invalid XML: %v", f.Name, err)
+			}
+		}
+	}
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	if !strings.Contains(sheet, `<c r="A2"><v>1</v></c>`) || !strings.Contains(sheet, "&lt;a &amp; &#34;b&#34;&gt;") {
+		t.Errorf("Unexpected cells: %s", sheet)
+	}
+	if !strings.Contains(sheet, `HYPERLINK(&#34;https://example.com/?a=1&amp;b=2&#34;,&#34;файл&#34;)`) {
+		t.Errorf("Link not written: %s", sheet)
+	}
+	if !strings.Contains(files["xl/workbook.xml"], `name="Ответы  форма"`) {
+		t.Errorf("Unexpected sheet name: %s", files["xl/workbook.xml"])
+	}
+	if xlsxColumn(0) != "A" || xlsxColumn(25) != "Z" || xlsxColumn(26) != "AA" || xlsxColumn(701) != "ZZ" {
+		t.Error("Wrong column names")
+	}
+}
@@ -0,0 +1,358 @@
+// Сопоставление полей формы атрибутам задачи.
+//
+// Поле формы с issue_field заполняет атрибут задачи, создаваемой по ответу в проекте формы: приоритет, теги,
+// исполнителей, срок, спринт или свойство проекта. Соответствие типов полей и атрибутов проверяется при сохранении
+// формы (checkFormFields), ссылки на теги, участников, спринты и шаблоны свойств - в checkFormIssueFields.
+// При создании задачи ссылки, удаленные после сохранения формы, пропускаются. Скрытое поле с атрибутом issue содержит
+// подписанную ссылку на задачу проекта формы, к которой ответ добавляется комментарием
+package aiplan
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	types2 "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/utils"
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	formIssuePriority   = "priority"
+	formIssueLabels     = "labels"
+	formIssueAssignees  = "assignees"
+	formIssueTargetDate = "target_date"
+	formIssueSprint     = "sprint"
+	formIssueProperty   = "property"
+	formIssueTarget     = "issue"
+)
+
+var (
+	issuePriorities = []string{"urgent", "high", "medium", "low"}
+
+	// formIssueFieldTypes - типы полей формы, допустимые для атрибута задачи
+	formIssueFieldTypes = map[string][]string{
+		formIssuePriority:   {formFieldSelect},
+		formIssueLabels:     {formFieldSelect, formFieldMultiselect},
+		formIssueAssignees:  {formFieldSelect, formFieldMultiselect},
+		formIssueTargetDate: {formFieldDate},
+		formIssueSprint:     {formFieldSelect},
+		formIssueProperty:   {formFieldInput, formFieldTextarea, formFieldCheckbox, formFieldSelect, formFieldHidden},
+		formIssueTarget:     {formFieldHidden},
+	}
+
+	// formPropertyFieldTypes - типы полей формы, допустимые для типа свойства проекта
+	formPropertyFieldTypes = map[string][]string{
+		"string":  {formFieldInput, formFieldTextarea, formFieldSelect, formFieldHidden},
+		"boolean": {formFieldCheckbox},
+		"select":  {formFieldSelect},
+		"link":    {formFieldInput},
+	}
+)
+
+// checkFormIssueField проверяет соответствие типа поля атрибуту задачи и значения вариантов ответа.
+// mapped - уже сопоставленные атрибуты с единственным значением, для поиска повторов
+func checkFormIssueField(field *types2.FormFields, mapped map[string]struct{}) error {
+	m := field.IssueField
+	if field.IssueNameField {
+		return fmt.Errorf("issue_name_field can't be mapped to issue_field")
+	}
+	fieldTypes, ok := formIssueFieldTypes[m.Attribute]
+	if !ok {
+		return fmt.Errorf("unknown issue_field attribute")
+	}
+	if !slices.Contains(fieldTypes, field.Type) {
+		return fmt.Errorf("issue_field %s not supported for %s field", m.Attribute, field.Type)
+	}
+
+	key := m.Attribute
+	if m.Attribute == formIssueProperty {
+		if m.PropertyId == nil {
+			return fmt.Errorf("issue_field property_id required")
+		}
+		key += m.PropertyId.String()
+	} else {
+		m.PropertyId = nil
+	}
+	if m.Attribute != formIssueLabels && m.Attribute != formIssueAssignees {
+		if _, ok := mapped[key]; ok {
+			return fmt.Errorf("issue_field %s duplicate", m.Attribute)
+		}
+		mapped[key] = struct{}{}
+	}
+
+	if field.Type != formFieldSelect && field.Type != formFieldMultiselect {
+		m.Values = nil
+		return nil
+	}
+
+	var options []interface{}
+	if field.Validate != nil {
+		options = field.Validate.Opt
+	}
+	switch {
+	case len(m.Values) == 0 && (m.Attribute == formIssuePriority || m.Attribute == formIssueProperty):
+		// варианты ответа используются как значения атрибута
+		if m.Attribute == formIssuePriority {
+			for _, opt := range options {
+				if !slices.Contains(issuePriorities, fmt.Sprint(opt)) {
+					return fmt.Errorf("issue_field unknown priority %v", opt)
+				}
+			}
+		}
+		return nil
+	case len(m.Values) != len(options):
+		return fmt.Errorf("issue_field values count must match options count")
+	}
+
+	for _, v := range m.Values {
+		if v == "" {
+			continue
+		}
+		switch m.Attribute {
+		case formIssuePriority:
+			if !slices.Contains(issuePriorities, v) {
+				return fmt.Errorf("issue_field unknown priority %s", v)
+			}
+		case formIssueLabels, formIssueAssignees, formIssueSprint:
+			if _, err := uuid.FromString(v); err != nil {
+				return fmt.Errorf("issue_field wrong id %s", v)
+			}
+		}
+	}
+	return nil
+}
+
+// checkFormIssueFields проверяет, что теги, участники, спринты и шаблоны свойств, указанные в полях формы,
+// существуют в проекте формы
+func checkFormIssueFields(tx *gorm.DB, form *dao.Form) error {
+	refs := make(map[string][]string)
+	var properties []uuid.UUID
+	for _, field := range form.Fields {
+		if field.IssueField == nil {
+			continue
+		}
+		if !form.TargetProjectId.Valid {
+			return fmt.Errorf("issue_field requires target_project_id")
+		}
+		for _, v := range field.IssueField.Values {
+			if v != "" {
+				refs[field.IssueField.Attribute] = append(refs[field.IssueField.Attribute], v)
+			}
+		}
+		if field.IssueField.PropertyId != nil {
+			properties = append(properties, *field.IssueField.PropertyId)
+		}
+	}
+	projectId := form.TargetProjectId.UUID
+
+	check := func(attribute string, query *gorm.DB) error {
+		ids := utils.MergeUniqueSlices(refs[attribute])
+		if len(ids) == 0 {
+			return nil
+		}
+		var count int64
+		if err := query.Where("id IN ?", ids).Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) != len(ids) {
+			return fmt.Errorf("issue_field %s not found", attribute)
+		}
+		return nil
+	}
+	if err := check(formIssueLabels, tx.Model(&dao.Label{}).Where("project_id = ?", projectId)); err != nil {
+		return err
+	}
+	if err := check(formIssueSprint, tx.Model(&dao.Sprint{}).Where("workspace_id = ?", form.WorkspaceId)); err != nil {
+		return err
+	}
+	if ids := utils.MergeUniqueSlices(refs[formIssueAssignees]); len(ids) > 0 {
+		var count int64
+		if err := tx.Model(&dao.ProjectMember{}).
+			Where("project_id = ?", projectId).
+			Where("member_id IN ?", ids).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) != len(ids) {
+			return fmt.Errorf("issue_field assignees not found")
+		}
+	}
+
+	if len(properties) == 0 {
+		return nil
+	}
+	var templates []dao.ProjectPropertyTemplate
+	if err := tx.Where("project_id = ?", projectId).Where("id IN ?", properties).Find(&templates).Error; err != nil {
+		return err
+	}
+	byId := make(map[uuid.UUID]dao.ProjectPropertyTemplate, len(templates))
+	for _, t := range templates {
+		byId[t.Id] = t
+	}
+	for _, field := range form.Fields {
+		if field.IssueField == nil || field.IssueField.PropertyId == nil {
+			continue
+		}
+		template, ok := byId[*field.IssueField.PropertyId]
+		if !ok {
+			return fmt.Errorf("issue_field property not found")
+		}
+		if !slices.Contains(formPropertyFieldTypes[template.Type], field.Type) {
+			return fmt.Errorf("issue_field property %s not supported for %s field", template.Name, field.Type)
+		}
+		if template.Type != "select" || len(template.Options) == 0 {
+			continue
+		}
+		values := field.IssueField.Values
+		if len(values) == 0 && field.Validate != nil {
+			for _, opt := range field.Validate.Opt {
+				values = append(values, fmt.Sprint(opt))
+			}
+		}
+		for _, v := range values {
+			if v != "" && !slices.Contains(template.Options, v) {
+				return fmt.Errorf("issue_field property %s has no option %s", template.Name, v)
+			}
+		}
+	}
+	return nil
+}
+
+// formIssueValues - значения атрибутов задачи из ответа на форму
+type formIssueValues struct {
+	Priority   *string
+	TargetDate *types2.TargetDateTimeZ
+	Labels     []uuid.UUID
+	Assignees  []uuid.UUID
+	Sprints    []uuid.UUID
+	Properties []dao.IssueProperty
+	// Ссылка на задачу, к которой добавляется ответ вместо создания новой
+	Issue string
+}
+
+// getFormIssueValues собирает значения атрибутов задачи из ответа. Теги, участники, спринты и шаблоны свойств,
+// удаленные из проекта после сохранения формы, пропускаются
+func getFormIssueValues(ctx context.Context, tx *gorm.DB, form *dao.Form, answer *dao.FormAnswer) (formIssueValues, error) {
+	var res formIssueValues
+	values := make(map[string][]string)
+	propertyValues := make(map[uuid.UUID]any)
+	for i, field := range form.Fields {
+		m := field.IssueField
+		if m == nil || i >= len(answer.Fields) || answer.Fields[i].Val == nil {
+			continue
+		}
+		val := answer.Fields[i].Val
+
+		switch field.Type {
+		case formFieldDate:
+			if ms, ok := val.(float64); ok {
+				res.TargetDate = &types2.TargetDateTimeZ{Time: time.UnixMilli(int64(ms)).UTC()}
+			}
+			continue
+		case formFieldCheckbox, formFieldInput, formFieldTextarea, formFieldHidden:
+			if m.Attribute == formIssueTarget {
+				res.Issue = fmt.Sprint(val)
+			}
+			if m.PropertyId != nil {
+				propertyValues[*m.PropertyId] = val
+			}
+			continue
+		}
+
+		var selected []string
+		for _, v := range answerOptions(field, val) {
+			if v != "" {
+				selected = append(selected, v)
+			}
+		}
+		if m.PropertyId != nil {
+			if len(selected) > 0 {
+				propertyValues[*m.PropertyId] = selected[0]
+			}
+			continue
+		}
+		values[m.Attribute] = append(values[m.Attribute], selected...)
+	}
+
+	if v := values[formIssuePriority]; len(v) > 0 {
+		res.Priority = &v[0]
+	}
+
+	projectId := form.TargetProjectId.UUID
+	existing := func(query *gorm.DB, column string, ids []string) ([]uuid.UUID, error) {
+		ids = utils.MergeUniqueSlices(ids)
+		if len(ids) == 0 {
+			return nil, nil
+		}
+		var res []uuid.UUID
+		err := query.Where(column+" IN ?", ids).Pluck(column, &res).Error
+		return res, err
+	}
+	var err error
+	if res.Labels, err = existing(tx.Model(&dao.Label{}).Where("project_id = ?", projectId), "id", values[formIssueLabels]); err != nil {
+		return res, err
+	}
+	if res.Assignees, err = existing(tx.Model(&dao.ProjectMember{}).Where("project_id = ?", projectId), "member_id", values[formIssueAssignees]); err != nil {
+		return res, err
+	}
+	if res.Sprints, err = existing(tx.Model(&dao.Sprint{}).Where("workspace_id = ?", form.WorkspaceId), "id", values[formIssueSprint]); err != nil {
+		return res, err
+	}
+
+	if len(propertyValues) == 0 {
+		return res, nil
+	}
+	var templates []dao.ProjectPropertyTemplate
+	if err := tx.Where("project_id = ?", projectId).
+		Where("id IN ?", slices.Collect(maps.Keys(propertyValues))).
+		Find(&templates).Error; err != nil {
+		return res, err
+	}
+	for _, template := range templates {
+		value := propertyValues[template.Id]
+		if template.Type == "link" {
+			value = map[string]any{"name": value, "url": value}
+		}
+		if err := validatePropertyValue(ctx, template, value); err != nil {
+			continue
+		}
+		res.Properties = append(res.Properties, dao.IssueProperty{
+			TemplateId: template.Id,
+			Value:      serializePropertyValue(value),
+		})
+	}
+	return res, nil
+}
+
+// answerOptions возвращает значения атрибута для выбранных вариантов ответа select/multiselect
+func answerOptions(field types2.FormFields, val interface{}) []string {
+	var selected []interface{}
+	switch v := val.(type) {
+	case []interface{}:
+		selected = v
+	default:
+		selected = []interface{}{v}
+	}
+	if field.Validate == nil {
+		return nil
+	}
+
+	var res []string
+	for _, s := range selected {
+		idx := slices.Index(field.Validate.Opt, s)
+		switch {
+		case idx < 0:
+			continue
+		case len(field.IssueField.Values) == 0:
+			res = append(res, fmt.Sprint(s))
+		case idx < len(field.IssueField.Values):
+			res = append(res, field.IssueField.Values[idx])
+		}
+	}
+	return res
+}
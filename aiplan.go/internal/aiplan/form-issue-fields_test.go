@@ -0,0 +1,79 @@
+package aiplan
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/config"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	types2 "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+	"github.com/gofrs/uuid"
+)
+
+func TestCheckFormIssueFields(t *testing.T) {
+	labelId := uuid.Must(uuid.NewV4()).String()
+	selectField := func(m *types2.FormIssueField, opts ...interface{}) types2.FormFields {
+		return types2.FormFields{Type: formFieldSelect, Validate: &types2.ValidationRule{Opt: opts}, IssueField: m}
+	}
+
+	valid := types2.FormFieldsSlice{
+		selectField(&types2.FormIssueField{Attribute: formIssuePriority}, "high", "low"),
+		selectField(&types2.FormIssueField{Attribute: formIssueLabels, Values: []string{labelId, ""}}, "Ошибка", "Другое"),
+		{Type: formFieldDate, IssueField: &types2.FormIssueField{Attribute: formIssueTargetDate, Values: []string{"x"}}},
+		{Type: formFieldHidden, IssueField: &types2.FormIssueField{Attribute: formIssueTarget}},
+	}
+	if err := checkFormFields(&valid); err != nil {
+		t.Fatalf("Valid fields rejected: %v", err)
+	}
+	if valid[2].IssueField.Values != nil {
+		t.Errorf("Values not cleared for date field: %v", valid[2].IssueField.Values)
+	}
+
+	propertyId := uuid.Must(uuid.NewV4())
+	cases := map[string]types2.FormFieldsSlice{
+		"wrong field type": {{Type: formFieldInput, IssueField: &types2.FormIssueField{Attribute: formIssueAssignees}}},
+		"unknown priority": {selectField(&types2.FormIssueField{Attribute: formIssuePriority}, "Срочно")},
+		"values count":     {selectField(&types2.FormIssueField{Attribute: formIssueLabels, Values: []string{labelId}}, "a", "b")},
+		"wrong id":         {selectField(&types2.FormIssueField{Attribute: formIssueSprint, Values: []string{"sprint"}}, "a")},
+		"no property id":   {{Type: formFieldCheckbox, IssueField: &types2.FormIssueField{Attribute: formIssueProperty}}},
+		"duplicate": {
+			{Type: formFieldInput, IssueField: &types2.FormIssueField{Attribute: formIssueProperty, PropertyId: &propertyId}},
+			{Type: formFieldTextarea, IssueField: &types2.FormIssueField{Attribute: formIssueProperty, PropertyId: &propertyId}},
+		},
+		"issue target type": {{Type: formFieldInput, IssueField: &types2.FormIssueField{Attribute: formIssueTarget}}},
+		"issue name":        {{Type: formFieldInput, IssueNameField: true, IssueField: &types2.FormIssueField{Attribute: formIssueProperty, PropertyId: &propertyId}}},
+	}
+	for name, fields := range cases {
+		if err := checkFormFields(&fields); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestFormIssueRef(t *testing.T) {
+	oldCfg := cfg
+	cfg = &config.Config{SecretKey: "secret"}
+	defer func() { cfg = oldCfg }()
+
+	form := &dao.Form{ID: uuid.Must(uuid.NewV4())}
+	issueId := uuid.Must(uuid.NewV4())
+	ref := formIssueRef(form, issueId)
+
+	if id, ok := parseFormIssueRef(form, ref); !ok || id != issueId {
+		t.Fatalf("Signed ref rejected: %s", ref)
+	}
+
+	otherForm := &dao.Form{ID: uuid.Must(uuid.NewV4())}
+	otherIssue := uuid.Must(uuid.NewV4())
+	_, signature, _ := strings.Cut(ref, ".")
+	for name, bad := range map[string]string{
+		"other form":   formIssueRef(otherForm, issueId),
+		"other issue":  otherIssue.String() + "." + signature,
+		"unsigned id":  issueId.String(),
+		"issue number": "PROJ-12",
+	} {
+		if _, ok := parseFormIssueRef(form, bad); ok {
+			t.Errorf("%s: expected ref to be rejected", name)
+		}
+	}
+}
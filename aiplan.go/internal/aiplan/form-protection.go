@@ -0,0 +1,119 @@
+// Защита форм от спама.
+//
+// Настройки защиты хранятся в форме (protection): капча Altcha и скрытое поле-ловушка (honeypot) для ответов
+// без аутентификации, ограничение частоты ответов и загрузок вложений с одного IP адреса, максимальное количество
+// ответов на форму, а также размер и типы вложений
+package aiplan
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	types2 "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+	"github.com/labstack/echo/v4"
+)
+
+// reqFormAnswer - ответ на форму. Принимается как массив ответов на поля или как объект
+// с ответами, капчей и значением поля-ловушки
+type reqFormAnswer struct {
+	Fields         types2.FormFieldsSlice `json:"fields"`
+	CaptchaPayload string                 `json:"captcha_payload,omitempty"`
+	// Значение скрытого поля-ловушки, заполняется только ботами
+	Honeypot string `json:"honeypot,omitempty"`
+}
+
+func (r *reqFormAnswer) UnmarshalJSON(data []byte) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		return json.Unmarshal(trimmed, &r.Fields)
+	}
+	type plain reqFormAnswer
+	return json.Unmarshal(data, (*plain)(r))
+}
+
+// checkFormProtection проверяет и нормализует настройки защиты формы
+func checkFormProtection(p *types2.FormProtection) error {
+	if p.RateLimit < 0 || p.MaxAnswers < 0 || p.MaxAttachmentSize < 0 {
+		return fmt.Errorf("protection limits must not be negative")
+	}
+	var attachmentTypes []string
+	for _, t := range p.AttachmentTypes {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" {
+			continue
+		}
+		if !strings.HasPrefix(t, ".") && !strings.Contains(t, "/") {
+			return fmt.Errorf("unknown attachment type %s", t)
+		}
+		attachmentTypes = append(attachmentTypes, t)
+	}
+	p.AttachmentTypes = attachmentTypes
+	return nil
+}
+
+// formAnswerAllowed проверяет капчу и частоту ответов. Возвращает false без ошибки, если заполнено поле-ловушка:
+// такой ответ не сохраняется, но отправителю возвращается обычный результат
+func (s *Services) formAnswerAllowed(c echo.Context, form *dao.Form, user *dao.User, req *reqFormAnswer) (bool, error) {
+	p := form.Protection
+	if user == nil {
+		if p.Honeypot && req.Honeypot != "" {
+			return false, nil
+		}
+		if p.Captcha && !CaptchaService.Validate(req.CaptchaPayload) {
+			return false, apierrors.ErrCaptchaFail
+		}
+	}
+
+	if p.RateLimit > 0 && !s.formAnswerLimiter.CheckAndRecordLimit("answer/"+form.ID.String()+"/"+c.RealIP(), p.RateLimit) {
+		return false, apierrors.ErrFormRateLimit
+	}
+	return true, nil
+}
+
+// formAttachmentUploadAllowed проверяет частоту загрузки вложений. На один ответ допускается
+// столько загрузок, сколько в форме полей для вложений
+func (s *Services) formAttachmentUploadAllowed(c echo.Context, form *dao.Form) bool {
+	limit := form.Protection.RateLimit
+	if limit == 0 {
+		return true
+	}
+	attachmentFields := 0
+	for _, field := range form.Fields {
+		if field.Type == formFieldAttachment {
+			attachmentFields++
+		}
+	}
+	return s.formAnswerLimiter.CheckAndRecordLimit("attachment/"+form.ID.String()+"/"+c.RealIP(), limit*max(1, attachmentFields))
+}
+
+// formAttachmentTypeAllowed проверяет, что файл соответствует одному из допустимых типов вложений формы
+func formAttachmentTypeAllowed(p types2.FormProtection, fileName, contentType string) bool {
+	if len(p.AttachmentTypes) == 0 {
+		return true
+	}
+	ext := strings.ToLower(filepath.Ext(fileName))
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(contentType)
+	}
+	for _, t := range p.AttachmentTypes {
+		switch {
+		case strings.HasPrefix(t, "."):
+			if ext == t {
+				return true
+			}
+		case strings.HasSuffix(t, "/*"):
+			if strings.HasPrefix(mediaType, strings.TrimSuffix(t, "*")) {
+				return true
+			}
+		case mediaType == t:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package aiplan
+
+import (
+	"encoding/json"
+	"testing"
+
+	types2 "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+)
+
+func TestReqFormAnswer(t *testing.T) {
+	var req reqFormAnswer
+	if err := json.Unmarshal([]byte(`[{"value": "a"}, {"value": true}]`), &req); err != nil {
+		t.Fatal(err)
+	}
+	if len(req.Fields) != 2 || req.Fields[0].Val != "a" {
+		t.Errorf("Unexpected fields: %v", req.Fields)
+	}
+
+	req = reqFormAnswer{}
+	if err := json.Unmarshal([]byte(`{"fields": [{"value": 1}], "captcha_payload": "p", "honeypot": "spam"}`), &req); err != nil {
+		t.Fatal(err)
+	}
+	if len(req.Fields) != 1 || req.CaptchaPayload != "p" || req.Honeypot != "spam" {
+		t.Errorf("Unexpected request: %+v", req)
+	}
+}
+
+func TestFormAttachmentTypeAllowed(t *testing.T) {
+	p := types2.FormProtection{AttachmentTypes: []string{" Image/* ", ".PDF", "text/plain", ""}}
+	if err := checkFormProtection(&p); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name, contentType string
+		allowed           bool
+	}{
+		{"photo.png", "image/png", true},
+		{"doc.pdf", "application/octet-stream", true},
+		{"notes.txt", "text/plain; charset=utf-8", true},
+		{"script.exe", "application/x-msdownload", false},
+		{"page.html", "text/html", false},
+	}
+	for _, c := range cases {
+		if formAttachmentTypeAllowed(p, c.name, c.contentType) != c.allowed {
+			t.Errorf("%s: expected allowed=%v", c.name, c.allowed)
+		}
+	}
+	if !formAttachmentTypeAllowed(types2.FormProtection{}, "any.exe", "application/x-msdownload") {
+		t.Error("Types not restricted by default")
+	}
+
+	if err := checkFormProtection(&types2.FormProtection{AttachmentTypes: []string{"pdf"}}); err == nil {
+		t.Error("Type without dot or slash accepted")
+	}
+	if err := checkFormProtection(&types2.FormProtection{MaxAnswers: -1}); err == nil {
+		t.Error("Negative limit accepted")
+	}
+}
@@ -0,0 +1,172 @@
+// Разделы (страницы) формы с условными переходами.
+//
+// Поля формы упорядочены по разделам, раздел поля задается индексом section. После заполнения раздела переход
+// к следующему определяется условиями branches раздела по значениям уже заполненных полей. Переходы возможны только
+// вперед, поэтому путь по форме однозначно восстанавливается по ответу. Поля разделов, не вошедших в путь,
+// не проверяются, а их значения не сохраняются. Форма без разделов считается формой из одного раздела
+package aiplan
+
+import (
+	"fmt"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	types2 "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+)
+
+// formSectionsCount возвращает количество разделов формы
+func formSectionsCount(sections types2.FormSections) int {
+	return max(1, len(sections))
+}
+
+// checkFormSections проверяет порядок полей по разделам и условия переходов между разделами
+func checkFormSections(fields types2.FormFieldsSlice, sections types2.FormSections) error {
+	count := formSectionsCount(sections)
+
+	prev := 0
+	for _, field := range fields {
+		if field.Section < prev || field.Section >= count {
+			return fmt.Errorf("fields must be ordered by section")
+		}
+		if field.IssueNameField && field.Section != 0 {
+			return fmt.Errorf("issue_name_field must be in first section")
+		}
+		prev = field.Section
+	}
+
+	checkTarget := func(section, target int) error {
+		if target == types2.FormSectionEnd {
+			return nil
+		}
+		if target <= section || target >= count {
+			return fmt.Errorf("section %d: invalid transition to section %d", section, target)
+		}
+		return nil
+	}
+	for i, section := range sections {
+		for _, branch := range section.Branches {
+			if branch.FieldIndex < 0 || branch.FieldIndex >= len(fields) || fields[branch.FieldIndex].Section > i {
+				return fmt.Errorf("section %d: branch field must be in this or previous section", i)
+			}
+			if err := checkFormCondition(fields[branch.FieldIndex], &branch.FormFieldDependency); err != nil {
+				return fmt.Errorf("section %d branch: %w", i, err)
+			}
+			if err := checkTarget(i, branch.GoTo); err != nil {
+				return err
+			}
+		}
+		if section.Next != nil {
+			if err := checkTarget(i, *section.Next); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// nextFormSection возвращает раздел, следующий за section при ответах answers.
+// Окончанию формы соответствует количество разделов
+func nextFormSection(form *dao.Form, section int, answers types2.FormFieldsSlice) int {
+	if section >= len(form.Sections) {
+		return section + 1
+	}
+	count := formSectionsCount(form.Sections)
+	target := func(t int) int {
+		switch {
+		case t == types2.FormSectionEnd:
+			return count
+		case t <= section || t > count:
+			return section + 1
+		}
+		return t
+	}
+
+	s := form.Sections[section]
+	for _, branch := range s.Branches {
+		if branch.FieldIndex >= len(answers) || branch.FieldIndex >= len(form.Fields) {
+			continue
+		}
+		if isParentCondition(answers[branch.FieldIndex], form.Fields[branch.FieldIndex], &branch.FormFieldDependency) {
+			return target(branch.GoTo)
+		}
+	}
+	if s.Next != nil {
+		return target(*s.Next)
+	}
+	return section + 1
+}
+
+// formVisitedSections восстанавливает по сохраненному ответу, какие разделы формы были заполнены
+func formVisitedSections(form *dao.Form, answers types2.FormFieldsSlice) []bool {
+	visited := make([]bool, formSectionsCount(form.Sections))
+	next := 0
+	for s := range visited {
+		if s != next {
+			continue
+		}
+		visited[s] = true
+		next = nextFormSection(form, s, answers)
+	}
+	return visited
+}
+
+// formAnswerSections проверяет ответы на разделы формы по пути заполнения до раздела last включительно.
+// Возвращает ответы на все поля формы, где значения полей пропущенных разделов пустые, и раздел,
+// следующий за last, или types2.FormSectionEnd. Если раздел last не входит в путь, возвращается ErrFormAnswerSectionSkipped.
+// При last больше номера последнего раздела проверяется весь путь
+func formAnswerSections(answers types2.FormFieldsSlice, form *dao.Form, last int) (types2.FormFieldsSlice, int, error) {
+	validator := FormValidator()
+	count := formSectionsCount(form.Sections)
+	resultAnswer := make(types2.FormFieldsSlice, 0, len(form.Fields))
+
+	next := 0
+	lastVisited := false
+	for i, field := range form.Fields {
+		// завершенные разделы и разделы без полей проходятся до раздела текущего поля
+		for next < field.Section && next <= last {
+			if next == last {
+				lastVisited = true
+			}
+			next = nextFormSection(form, next, resultAnswer)
+		}
+
+		if field.Section != next || field.Section > last {
+			resultAnswer = append(resultAnswer, types2.FormFields{Type: field.Type, Label: field.Label})
+			continue
+		}
+
+		skip, err := ValidateFieldDependency(field, i, resultAnswer, form.Fields, answers[i].Val)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if !skip {
+			validFunc := validator[field.Type]
+			checkVal := validFunc(answers[i].Val, field.Required, field.Validate)
+			if !checkVal {
+				return nil, 0, fmt.Errorf("field missing or wrong type")
+			}
+		}
+
+		resultAnswer = append(resultAnswer,
+			types2.FormFields{
+				Type:  field.Type,
+				Label: field.Label,
+				Val:   answers[i].Val,
+			})
+	}
+	for next <= last && next < count {
+		if next == last {
+			lastVisited = true
+		}
+		next = nextFormSection(form, next, resultAnswer)
+	}
+
+	if !lastVisited && last < count {
+		return nil, 0, apierrors.ErrFormAnswerSectionSkipped
+	}
+	if next >= count {
+		next = types2.FormSectionEnd
+	}
+	return resultAnswer, next, nil
+}
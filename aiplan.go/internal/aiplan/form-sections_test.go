@@ -0,0 +1,84 @@
+package aiplan
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	types2 "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+)
+
+func TestFormAnswerSections(t *testing.T) {
+	end := types2.FormSectionEnd
+	form := &dao.Form{
+		Fields: types2.FormFieldsSlice{
+			{Type: formFieldCheckbox, Label: "Ошибка"},
+			{Type: formFieldInput, Label: "Пожелание", Required: true, Section: 1},
+			{Type: formFieldInput, Label: "Шаги воспроизведения", Required: true, Section: 2},
+		},
+		Sections: types2.FormSections{
+			{Branches: []types2.FormSectionBranch{{FormFieldDependency: types2.FormFieldDependency{FieldIndex: 0, ExpectedValue: true}, GoTo: 2}}},
+			{Next: &end},
+			{},
+		},
+	}
+	if err := checkFormFields(&form.Fields); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkFormSections(form.Fields, form.Sections); err != nil {
+		t.Fatal(err)
+	}
+	answers := func(vals ...interface{}) types2.FormFieldsSlice {
+		var res types2.FormFieldsSlice
+		for i, v := range vals {
+			res = append(res, types2.FormFields{Type: form.Fields[i].Type, Val: v})
+		}
+		return res
+	}
+
+	// ошибка: второй раздел пропускается, его значение не сохраняется
+	res, err := formAnswer(answers(true, "лишнее", "шаги"), form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res[1].Val != nil || res[2].Val != "шаги" {
+		t.Errorf("Unexpected answer: %v", res)
+	}
+	if _, err := formAnswer(answers(true, "пожелание", nil), form); err == nil {
+		t.Error("Required field of visited section not checked")
+	}
+
+	// пожелание: после второго раздела форма отправляется, третий раздел не обязателен
+	if _, err := formAnswer(answers(false, "пожелание", nil), form); err != nil {
+		t.Errorf("Skipped section checked: %v", err)
+	}
+
+	if _, next, err := formAnswerSections(answers(true, nil, nil), form, 0); err != nil || next != 2 {
+		t.Errorf("next = %d, err = %v", next, err)
+	}
+	if _, next, err := formAnswerSections(answers(false, "пожелание", nil), form, 1); err != nil || next != end {
+		t.Errorf("next = %d, err = %v", next, err)
+	}
+	if _, _, err := formAnswerSections(answers(true, nil, nil), form, 1); !errors.Is(err, apierrors.ErrFormAnswerSectionSkipped) {
+		t.Errorf("Skipped section accepted: %v", err)
+	}
+
+	visited := formVisitedSections(form, answers(true, nil, "шаги"))
+	if !visited[0] || visited[1] || !visited[2] {
+		t.Errorf("visited = %v", visited)
+	}
+
+	back := 0
+	cases := map[string]types2.FormSections{
+		"backward transition": {{Next: &back}, {}, {}},
+		"unknown section":     {{}, {}},
+		"branch field ahead":  {{Branches: []types2.FormSectionBranch{{FormFieldDependency: types2.FormFieldDependency{FieldIndex: 1}, GoTo: 2}}}, {}, {}},
+		"branch field type":   {{}, {Branches: []types2.FormSectionBranch{{FormFieldDependency: types2.FormFieldDependency{FieldIndex: 1}, GoTo: 2}}}, {}},
+	}
+	for name, sections := range cases {
+		if err := checkFormSections(form.Fields, sections); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
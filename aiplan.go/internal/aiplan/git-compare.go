@@ -0,0 +1,404 @@
+// Пакет aiplan предоставляет движок сравнения и слияния веток Git репозиториев.
+//
+// Сравнение строится относительно merge-base двух ревизий (аналог "base...head"),
+// поэтому в diff попадают только изменения, сделанные в head после ответвления.
+// Слияние выполняется без рабочей копии: дерево результата строится через
+// git merge-tree, коммит создается через git commit-tree, а ветка обновляется
+// через git update-ref с проверкой старого значения.
+package aiplan
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ========================================
+// Git Compare DTO Structures
+// ========================================
+
+// CompareFileDTO представляет изменения одного файла
+type CompareFileDTO struct {
+	Path      string `json:"path"`
+	OldPath   string `json:"old_path,omitempty"`
+	Status    string `json:"status"` // "added", "modified", "deleted", "renamed", "copied", "type_changed"
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Binary    bool   `json:"binary"`
+	Patch     string `json:"patch,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+// CompareResponseDTO представляет результат сравнения двух ревизий
+type CompareResponseDTO struct {
+	Base      string           `json:"base"`
+	Head      string           `json:"head"`
+	BaseSHA   string           `json:"base_sha"`
+	HeadSHA   string           `json:"head_sha"`
+	MergeBase string           `json:"merge_base"`
+	Commits   []CommitDTO      `json:"commits"`
+	Files     []CompareFileDTO `json:"files"`
+	Additions int              `json:"additions"`
+	Deletions int              `json:"deletions"`
+}
+
+// MergeCheckDTO представляет результат проверки возможности слияния
+type MergeCheckDTO struct {
+	Mergeable      bool     `json:"mergeable"`
+	CanFastForward bool     `json:"can_fast_forward"`
+	UpToDate       bool     `json:"up_to_date"`
+	Conflicts      []string `json:"conflicts"`
+	TargetSHA      string   `json:"target_sha"`
+	SourceSHA      string   `json:"source_sha"`
+
+	// treeSHA - дерево результата слияния (используется при создании merge коммита)
+	treeSHA string
+}
+
+// Максимальный размер patch одного файла, отдаваемый в ответе
+const maxComparePatchSize = 512 * 1024
+
+var (
+	errGitRefNotFound     = errors.New("git ref not found")
+	errGitMergeConflict   = errors.New("merge has conflicts")
+	errGitNotFastForward  = errors.New("fast-forward is not possible")
+	errGitNothingToMerge  = errors.New("nothing to merge")
+	errGitRefUpdateFailed = errors.New("target branch was updated concurrently")
+)
+
+// gitIdentity представляет автора/коммиттера, от имени которого выполняются операции слияния
+type gitIdentity struct {
+	Name  string
+	Email string
+}
+
+// executeGitCommandStdout выполняет Git команду и возвращает только stdout без обрезки.
+// В отличие от executeGitCommand, stderr не смешивается с выводом, а ошибка
+// сохраняет *exec.ExitError для анализа кода возврата.
+func executeGitCommandStdout(repoPath string, env []string, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = repoPath
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return output, fmt.Errorf("git %s failed: %w, stderr: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
+
+// gitExitCode возвращает код возврата git команды из ошибки executeGitCommandStdout
+func gitExitCode(err error) int {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// resolveGitCommit возвращает SHA коммита для ветки/тега/коммита
+func resolveGitCommit(repoPath, ref string) (string, error) {
+	if ref == "" || strings.HasPrefix(ref, "-") {
+		return "", errGitRefNotFound
+	}
+	output, err := executeGitCommandStdout(repoPath, nil, "rev-parse", "--verify", "--quiet", ref+"^{commit}")
+	if err != nil {
+		return "", errGitRefNotFound
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// resolveGitBranch возвращает SHA головы ветки
+func resolveGitBranch(repoPath, branch string) (string, error) {
+	if !validateBranchName(branch) {
+		return "", errGitRefNotFound
+	}
+	return resolveGitCommit(repoPath, "refs/heads/"+branch)
+}
+
+// isGitAncestor проверяет, является ли ancestor предком commit
+func isGitAncestor(repoPath, ancestor, commit string) (bool, error) {
+	_, err := executeGitCommandStdout(repoPath, nil, "merge-base", "--is-ancestor", ancestor, commit)
+	if err == nil {
+		return true, nil
+	}
+	if gitExitCode(err) == 1 {
+		return false, nil
+	}
+	return false, err
+}
+
+// compareGitRefs сравнивает две ревизии репозитория относительно их merge-base.
+// Если withPatch = true, для каждого файла возвращается unified diff.
+func compareGitRefs(repoPath, base, head string, withPatch bool) (*CompareResponseDTO, error) {
+	baseSHA, err := resolveGitCommit(repoPath, base)
+	if err != nil {
+		return nil, err
+	}
+	headSHA, err := resolveGitCommit(repoPath, head)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &CompareResponseDTO{
+		Base:    base,
+		Head:    head,
+		BaseSHA: baseSHA,
+		HeadSHA: headSHA,
+		Commits: []CommitDTO{},
+		Files:   []CompareFileDTO{},
+	}
+
+	mergeBase, err := executeGitCommandStdout(repoPath, nil, "merge-base", baseSHA, headSHA)
+	if err != nil {
+		if gitExitCode(err) != 1 {
+			return nil, err
+		}
+		// Нет общего предка - сравниваем с пустым деревом
+		emptyTree, err := executeGitCommandStdout(repoPath, nil, "hash-object", "-t", "tree", "/dev/null")
+		if err != nil {
+			return nil, err
+		}
+		result.MergeBase = strings.TrimSpace(string(emptyTree))
+	} else {
+		result.MergeBase = strings.TrimSpace(string(mergeBase))
+	}
+
+	// Коммиты, которые есть в head, но отсутствуют в base
+	format := "%H|%an|%ae|%aI|%cn|%ce|%cI|%P|%s"
+	logOutput, err := executeGitCommandStdout(repoPath, nil, "log", "--pretty=format:"+format, baseSHA+".."+headSHA)
+	if err != nil {
+		return nil, err
+	}
+	if result.Commits, err = parseCommitLog(string(logOutput)); err != nil {
+		return nil, err
+	}
+
+	// Список файлов с количеством изменений
+	numstat, err := executeGitCommandStdout(repoPath, nil, "diff", "--numstat", "-z", "-M", result.MergeBase, headSHA)
+	if err != nil {
+		return nil, err
+	}
+	result.Files = parseDiffNumstat(numstat)
+
+	nameStatus, err := executeGitCommandStdout(repoPath, nil, "diff", "--name-status", "-z", "-M", result.MergeBase, headSHA)
+	if err != nil {
+		return nil, err
+	}
+	statuses := parseDiffNameStatus(nameStatus)
+	for i := range result.Files {
+		if i < len(statuses) {
+			result.Files[i].Status = statuses[i]
+		}
+		result.Additions += result.Files[i].Additions
+		result.Deletions += result.Files[i].Deletions
+	}
+
+	if withPatch && len(result.Files) > 0 {
+		patch, err := executeGitCommandStdout(repoPath, nil, "diff", "--no-color", "--no-ext-diff", "-M", result.MergeBase, headSHA)
+		if err != nil {
+			return nil, err
+		}
+		patches := splitDiffPatch(string(patch))
+		for i := range result.Files {
+			if i >= len(patches) {
+				break
+			}
+			if len(patches[i]) > maxComparePatchSize {
+				result.Files[i].Truncated = true
+				continue
+			}
+			result.Files[i].Patch = patches[i]
+		}
+	}
+
+	return result, nil
+}
+
+// parseDiffNumstat парсит вывод git diff --numstat -z
+// Формат: <add>\t<del>\t<path>\0 или <add>\t<del>\t\0<old>\0<new>\0 для переименований
+func parseDiffNumstat(output []byte) []CompareFileDTO {
+	files := []CompareFileDTO{}
+	parts := strings.Split(string(output), "\x00")
+	for i := 0; i < len(parts); i++ {
+		if parts[i] == "" {
+			continue
+		}
+		fields := strings.SplitN(parts[i], "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+
+		file := CompareFileDTO{}
+		if fields[0] == "-" && fields[1] == "-" {
+			file.Binary = true
+		} else {
+			file.Additions, _ = strconv.Atoi(fields[0])
+			file.Deletions, _ = strconv.Atoi(fields[1])
+		}
+
+		if fields[2] == "" && i+2 < len(parts) {
+			file.OldPath = parts[i+1]
+			file.Path = parts[i+2]
+			i += 2
+		} else {
+			file.Path = fields[2]
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+// parseDiffNameStatus парсит вывод git diff --name-status -z и возвращает статусы файлов в порядке вывода
+func parseDiffNameStatus(output []byte) []string {
+	statuses := []string{}
+	parts := strings.Split(string(output), "\x00")
+	for i := 0; i < len(parts); i++ {
+		if parts[i] == "" {
+			continue
+		}
+		code := parts[i][:1]
+		status := "modified"
+		switch code {
+		case "A":
+			status = "added"
+		case "D":
+			status = "deleted"
+		case "R":
+			status = "renamed"
+		case "C":
+			status = "copied"
+		case "T":
+			status = "type_changed"
+		}
+		// Переименования и копирования содержат два пути
+		if code == "R" || code == "C" {
+			i += 2
+		} else {
+			i++
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// splitDiffPatch разбивает общий unified diff на части по файлам
+func splitDiffPatch(patch string) []string {
+	if patch == "" {
+		return nil
+	}
+	chunks := strings.Split(patch, "\ndiff --git ")
+	result := make([]string, 0, len(chunks))
+	for i, chunk := range chunks {
+		if i > 0 {
+			chunk = "diff --git " + chunk
+		}
+		result = append(result, strings.TrimSuffix(chunk, "\n"))
+	}
+	return result
+}
+
+// checkGitMerge проверяет возможность слияния ветки source в target без изменения репозитория
+func checkGitMerge(repoPath, target, source string) (*MergeCheckDTO, error) {
+	targetSHA, err := resolveGitBranch(repoPath, target)
+	if err != nil {
+		return nil, err
+	}
+	sourceSHA, err := resolveGitBranch(repoPath, source)
+	if err != nil {
+		return nil, err
+	}
+
+	check := &MergeCheckDTO{
+		TargetSHA: targetSHA,
+		SourceSHA: sourceSHA,
+		Conflicts: []string{},
+	}
+
+	upToDate, err := isGitAncestor(repoPath, sourceSHA, targetSHA)
+	if err != nil {
+		return nil, err
+	}
+	if upToDate {
+		check.UpToDate = true
+		return check, nil
+	}
+
+	if check.CanFastForward, err = isGitAncestor(repoPath, targetSHA, sourceSHA); err != nil {
+		return nil, err
+	}
+
+	// Вывод: <tree>\n[<conflicted path>\n...]; код возврата 1 означает наличие конфликтов
+	output, err := executeGitCommandStdout(repoPath, nil, "merge-tree", "--write-tree", "--name-only", "--no-messages", targetSHA, sourceSHA)
+	if err != nil && gitExitCode(err) != 1 {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	if len(lines) == 0 || lines[0] == "" {
+		return nil, fmt.Errorf("unexpected merge-tree output: %q", string(output))
+	}
+	check.treeSHA = lines[0]
+	if err != nil {
+		for _, line := range lines[1:] {
+			if line != "" {
+				check.Conflicts = append(check.Conflicts, line)
+			}
+		}
+	}
+
+	check.Mergeable = len(check.Conflicts) == 0
+	return check, nil
+}
+
+// mergeGitBranches вливает source в target указанным способом и возвращает новый SHA target.
+// Ветка обновляется атомарно: если target изменилась во время слияния, возвращается errGitRefUpdateFailed.
+func mergeGitBranches(repoPath, target, source, method, message string, committer gitIdentity) (string, error) {
+	check, err := checkGitMerge(repoPath, target, source)
+	if err != nil {
+		return "", err
+	}
+	if check.UpToDate {
+		return "", errGitNothingToMerge
+	}
+
+	var newSHA string
+	switch method {
+	case MergeMethodFastForward:
+		if !check.CanFastForward {
+			return "", errGitNotFastForward
+		}
+		newSHA = check.SourceSHA
+	case MergeMethodMerge:
+		if !check.Mergeable {
+			return "", errGitMergeConflict
+		}
+		env := []string{
+			"GIT_AUTHOR_NAME=" + committer.Name,
+			"GIT_AUTHOR_EMAIL=" + committer.Email,
+			"GIT_COMMITTER_NAME=" + committer.Name,
+			"GIT_COMMITTER_EMAIL=" + committer.Email,
+		}
+		output, err := executeGitCommandStdout(repoPath, env, "commit-tree", check.treeSHA,
+			"-p", check.TargetSHA, "-p", check.SourceSHA, "-m", message)
+		if err != nil {
+			return "", err
+		}
+		newSHA = strings.TrimSpace(string(output))
+	default:
+		return "", fmt.Errorf("unknown merge method: %s", method)
+	}
+
+	if _, err := executeGitCommandStdout(repoPath, nil, "update-ref", "-m", "merge "+source+": "+method,
+		"refs/heads/"+target, newSHA, check.TargetSHA); err != nil {
+		return "", errGitRefUpdateFailed
+	}
+
+	return newSHA, nil
+}
@@ -40,11 +40,15 @@ type GitRepository struct {
 	// Branch - ветка по умолчанию
 	Branch string `json:"branch"`
 
+	// Mirror - настройки pull-зеркала (nil для обычного репозитория)
+	Mirror *GitMirror `json:"mirror,omitempty"`
+
 	// Path - полный путь к репозиторию на файловой системе (не сохраняется в JSON)
 	Path string `json:"-"`
 }
 
 // Save сохраняет метаданные репозитория в файл aiplan.json
+// Использует атомарную запись (temp file + rename): файл обновляется при синхронизации зеркал
 func (r *GitRepository) Save() error {
 	metadataPath := filepath.Join(r.Path, "aiplan.json")
 	data, err := json.MarshalIndent(r, "", "  ")
@@ -52,10 +56,22 @@ func (r *GitRepository) Save() error {
 		return fmt.Errorf("failed to marshal repository metadata: %w", err)
 	}
 
-	if err := os.WriteFile(metadataPath, data, 0644); err != nil {
+	// Учетные данные зеркала не должны быть доступны другим пользователям системы
+	perm := os.FileMode(0644)
+	if r.Mirror != nil && r.Mirror.Password != "" {
+		perm = 0600
+	}
+
+	tempPath := metadataPath + ".tmp"
+	if err := os.WriteFile(tempPath, data, perm); err != nil {
 		return fmt.Errorf("failed to write metadata file: %w", err)
 	}
 
+	if err := os.Rename(tempPath, metadataPath); err != nil {
+		os.Remove(tempPath) // Cleanup temp file
+		return fmt.Errorf("failed to rename metadata file: %w", err)
+	}
+
 	return nil
 }
 
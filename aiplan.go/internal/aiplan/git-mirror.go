@@ -0,0 +1,481 @@
+// Пакет aiplan предоставляет функциональность pull-зеркал Git репозиториев.
+//
+// Зеркало - это bare репозиторий, который периодически забирает ветки и теги
+// из внешнего Git сервера (cron задача git_mirrors_sync). Push в зеркало запрещен,
+// а слияние merge request'ов недоступно: единственный источник изменений - удаленный репозиторий.
+//
+// Настройки зеркала (URL, учетные данные) и состояние последней синхронизации
+// хранятся в aiplan.json вместе с остальными метаданными репозитория, пароль - в зашифрованном виде.
+// Учетные данные передаются git через переменные окружения (GIT_CONFIG_*)
+// и не попадают ни в конфигурацию репозитория, ни в аргументы процесса.
+// Git подключается к адресам, проверенным checkMirrorHost, и не следует перенаправлениям.
+package aiplan
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Статусы синхронизации зеркала
+const (
+	GitMirrorStatusPending = "pending"
+	GitMirrorStatusSuccess = "success"
+	GitMirrorStatusFailed  = "failed"
+)
+
+// gitMirrorSyncTimeout - максимальная длительность одной синхронизации
+const gitMirrorSyncTimeout = 10 * time.Minute
+
+// gitMirrorErrorMaxLen - максимальная длина сохраняемого текста ошибки синхронизации
+const gitMirrorErrorMaxLen = 2000
+
+// ErrGitMirrorSyncInProgress возвращается, если синхронизация зеркала уже выполняется
+var ErrGitMirrorSyncInProgress = errors.New("mirror sync is already in progress")
+
+var (
+	// gitMirrorLocks - блокировки синхронизации по пути репозитория (*sync.Mutex)
+	gitMirrorLocks sync.Map
+
+	// gitMetadataMutex защищает чтение-изменение-запись aiplan.json при обновлении статуса зеркала
+	gitMetadataMutex sync.Mutex
+)
+
+// GitMirror описывает настройки и состояние pull-зеркала
+type GitMirror struct {
+	// URL - адрес удаленного репозитория (без учетных данных)
+	URL string `json:"url"`
+
+	// Username - имя пользователя для HTTP аутентификации
+	Username string `json:"username,omitempty"`
+
+	// Password - пароль или токен доступа для HTTP аутентификации.
+	// В aiplan.json сохраняется зашифрованным (см. MarshalJSON)
+	Password string `json:"-"`
+
+	// LastSyncAt - время последней попытки синхронизации
+	LastSyncAt *time.Time `json:"last_sync_at,omitempty"`
+
+	// LastSuccessAt - время последней успешной синхронизации
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+
+	// LastSyncStatus - результат последней синхронизации: pending, success или failed
+	LastSyncStatus string `json:"last_sync_status"`
+
+	// LastSyncError - текст ошибки последней синхронизации
+	LastSyncError string `json:"last_sync_error,omitempty"`
+
+	// undecrypted - пароль, который не удалось расшифровать (изменен SECRET_KEY), сохраняется без изменений
+	undecrypted string
+}
+
+// gitMirrorJSON - представление зеркала в aiplan.json
+type gitMirrorJSON struct {
+	gitMirrorFields
+	// EncryptedPassword - пароль, зашифрованный ключом из SECRET_KEY
+	EncryptedPassword string `json:"password_enc,omitempty"`
+	// LegacyPassword - пароль в открытом виде из прежних версий, шифруется при следующем сохранении
+	LegacyPassword string `json:"password,omitempty"`
+}
+
+type gitMirrorFields GitMirror
+
+// MarshalJSON сохраняет зеркало с зашифрованным паролем
+func (m GitMirror) MarshalJSON() ([]byte, error) {
+	res := gitMirrorJSON{gitMirrorFields: gitMirrorFields(m), EncryptedPassword: m.undecrypted}
+	if m.Password != "" {
+		encrypted, err := encryptMirrorSecret(m.Password)
+		if err != nil {
+			return nil, err
+		}
+		res.EncryptedPassword = encrypted
+	}
+	return json.Marshal(res)
+}
+
+// UnmarshalJSON загружает зеркало и расшифровывает пароль
+func (m *GitMirror) UnmarshalJSON(data []byte) error {
+	var res gitMirrorJSON
+	if err := json.Unmarshal(data, &res); err != nil {
+		return err
+	}
+	*m = GitMirror(res.gitMirrorFields)
+	m.Password = res.LegacyPassword
+	if res.EncryptedPassword != "" {
+		password, err := decryptMirrorSecret(res.EncryptedPassword)
+		if err != nil {
+			slog.Warn("Decrypt git mirror password", "url", m.URL, "err", err)
+			m.undecrypted = res.EncryptedPassword
+			return nil
+		}
+		m.Password = password
+	}
+	return nil
+}
+
+// gitMirrorCipher возвращает AES-GCM с ключом, производным от SECRET_KEY
+func gitMirrorCipher() (cipher.AEAD, error) {
+	var secret string
+	if cfg != nil {
+		secret = cfg.SecretKey
+	}
+	key := sha256.Sum256([]byte("git-mirror/" + secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func encryptMirrorSecret(secret string) (string, error) {
+	gcm, err := gitMirrorCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(secret), nil)), nil
+}
+
+func decryptMirrorSecret(encrypted string) (string, error) {
+	gcm, err := gitMirrorCipher()
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// ValidateMirrorURL проверяет адрес удаленного репозитория для зеркала.
+// Допускаются только http(s) адреса без учетных данных: локальные пути и file://
+// дали бы доступ к файловой системе сервера, в том числе к чужим репозиториям.
+// Хост не должен указывать во внутреннюю сеть сервера (см. checkMirrorHost).
+func ValidateMirrorURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid mirror URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid mirror URL: only http and https are supported")
+	}
+	if u.Hostname() == "" {
+		return fmt.Errorf("invalid mirror URL: host is empty")
+	}
+	if u.User != nil {
+		return fmt.Errorf("invalid mirror URL: pass credentials separately")
+	}
+	_, err = checkMirrorHost(u.Hostname())
+	return err
+}
+
+// gitMirrorLookupIP разрешает имя хоста зеркала (подменяется в тестах)
+var gitMirrorLookupIP = net.LookupIP
+
+// gitMirrorSharedNet - адреса CGNAT (RFC 6598), не входящие в net.IP.IsPrivate
+var gitMirrorSharedNet = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// checkMirrorHost запрещает зеркала из внутренней сети сервера: loopback, частные, link-local и
+// служебные адреса. Иначе зеркало с сохраненными учетными данными давало бы доступ к внутренним сервисам.
+// Возвращает проверенные адреса хоста. Хосты из GIT_MIRROR_ALLOWED_HOSTS не проверяются, для них возвращается nil.
+func checkMirrorHost(host string) ([]net.IP, error) {
+	if mirrorHostAllowed(host) {
+		return nil, nil
+	}
+
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		var err error
+		if ips, err = gitMirrorLookupIP(host); err != nil {
+			return nil, fmt.Errorf("invalid mirror URL: resolve host: %w", err)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("invalid mirror URL: host %s has no addresses", host)
+	}
+	for _, ip := range ips {
+		if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+			ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+			gitMirrorSharedNet.Contains(ip) {
+			return nil, fmt.Errorf("invalid mirror URL: host %s resolves to internal address %s", host, ip)
+		}
+	}
+	return ips, nil
+}
+
+// mirrorResolve возвращает значение http.curloptResolve, закрепляющее за хостом зеркала проверенные адреса.
+// Без него git разрешал бы имя повторно и мог получить другой, внутренний адрес (DNS rebinding)
+func mirrorResolve(u *url.URL, ips []net.IP) string {
+	if len(ips) == 0 || net.ParseIP(u.Hostname()) != nil {
+		return ""
+	}
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	addrs := make([]string, len(ips))
+	for i, ip := range ips {
+		if ip.To4() == nil {
+			addrs[i] = "[" + ip.String() + "]"
+		} else {
+			addrs[i] = ip.String()
+		}
+	}
+	return u.Hostname() + ":" + port + ":" + strings.Join(addrs, ",")
+}
+
+// mirrorHostAllowed проверяет, что хост явно разрешен в GIT_MIRROR_ALLOWED_HOSTS
+func mirrorHostAllowed(host string) bool {
+	if cfg == nil {
+		return false
+	}
+	for _, allowed := range strings.Split(cfg.GitMirrorAllowedHosts, ",") {
+		if allowed = strings.TrimSpace(allowed); allowed != "" && strings.EqualFold(allowed, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// ConfigureGitMirrorRemote настраивает remote origin bare репозитория для зеркалирования веток и тегов
+func ConfigureGitMirrorRemote(repoPath, remoteURL string) error {
+	commands := [][]string{
+		{"config", "remote.origin.url", remoteURL},
+		{"config", "--add", "remote.origin.fetch", "+refs/heads/*:refs/heads/*"},
+		{"config", "--add", "remote.origin.fetch", "+refs/tags/*:refs/tags/*"},
+	}
+	for _, args := range commands {
+		if _, err := executeGitCommand(repoPath, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsGitMirror проверяет, является ли репозиторий pull-зеркалом
+func IsGitMirror(repoPath string) bool {
+	repo, err := LoadGitRepository(repoPath)
+	return err == nil && repo.Mirror != nil
+}
+
+// gitMirrorEnv возвращает переменные окружения для git команд зеркала.
+// Перенаправления запрещены, чтобы учетные данные и запросы не уходили на другой хост.
+// Учетные данные передаются заголовком Authorization только для адреса зеркала через GIT_CONFIG_*.
+// resolve - закрепленные адреса хоста (см. mirrorResolve).
+func gitMirrorEnv(mirror *GitMirror, resolve string) []string {
+	config := [][2]string{{"http.followRedirects", "false"}}
+	if resolve != "" {
+		config = append(config, [2]string{"http.curloptResolve", resolve})
+	}
+	if mirror.Password != "" {
+		username := mirror.Username
+		if username == "" {
+			// GitLab и GitHub принимают токен с произвольным именем пользователя
+			username = "oauth2"
+		}
+		credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + mirror.Password))
+		config = append(config, [2]string{"http." + mirror.URL + ".extraHeader", "Authorization: Basic " + credentials})
+	}
+
+	env := []string{"GIT_TERMINAL_PROMPT=0", "GIT_CONFIG_COUNT=" + strconv.Itoa(len(config))}
+	for i, kv := range config {
+		env = append(env,
+			fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", i, kv[0]),
+			fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", i, kv[1]),
+		)
+	}
+	return env
+}
+
+// runGitMirrorCommand выполняет git команду зеркала с таймаутом, учетными данными и закрепленными адресами
+func runGitMirrorCommand(ctx context.Context, repoPath string, mirror *GitMirror, resolve string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = repoPath
+	cmd.Env = append(os.Environ(), gitMirrorEnv(mirror, resolve)...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("git %s timed out", args[0])
+		}
+		return "", fmt.Errorf("git %s failed: %w, output: %s", args[0], err, strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
+
+// parseRemoteHead извлекает ветку по умолчанию из вывода git ls-remote --symref origin HEAD
+func parseRemoteHead(output string) string {
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "ref: ") {
+			continue
+		}
+		ref, target, _ := strings.Cut(strings.TrimPrefix(line, "ref: "), "\t")
+		if target == "HEAD" && strings.HasPrefix(ref, "refs/heads/") {
+			return strings.TrimPrefix(ref, "refs/heads/")
+		}
+	}
+	return ""
+}
+
+// fetchGitMirror забирает ветки и теги удаленного репозитория и возвращает его ветку по умолчанию
+func fetchGitMirror(repoPath string, mirror *GitMirror) (string, error) {
+	// Адрес хоста мог измениться после создания зеркала, проверяем его перед каждой синхронизацией
+	// и подключаемся именно к проверенным адресам
+	var resolve string
+	if u, err := url.Parse(mirror.URL); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		ips, err := checkMirrorHost(u.Hostname())
+		if err != nil {
+			return "", err
+		}
+		resolve = mirrorResolve(u, ips)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), gitMirrorSyncTimeout)
+	defer cancel()
+
+	if _, err := runGitMirrorCommand(ctx, repoPath, mirror, resolve, "fetch", "--prune", "--quiet", "origin"); err != nil {
+		return "", err
+	}
+
+	// Ветка по умолчанию может отсутствовать (пустой удаленный репозиторий) - это не ошибка
+	output, err := runGitMirrorCommand(ctx, repoPath, mirror, resolve, "ls-remote", "--symref", "origin", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	branch := parseRemoteHead(output)
+	if branch == "" {
+		return "", nil
+	}
+	if _, err := executeGitCommand(repoPath, "symbolic-ref", "HEAD", "refs/heads/"+branch); err != nil {
+		return "", err
+	}
+	return branch, nil
+}
+
+// truncateGitMirrorError обрезает текст ошибки и убирает из него учетные данные
+func truncateGitMirrorError(err error, mirror *GitMirror) string {
+	msg := err.Error()
+	if mirror.Password != "" {
+		msg = strings.ReplaceAll(msg, mirror.Password, "***")
+	}
+	if len(msg) > gitMirrorErrorMaxLen {
+		msg = msg[:gitMirrorErrorMaxLen] + "..."
+	}
+	return msg
+}
+
+// SyncGitMirror синхронизирует зеркало с удаленным репозиторием и сохраняет результат в aiplan.json.
+// Если синхронизация этого репозитория уже выполняется, возвращает ErrGitMirrorSyncInProgress.
+func SyncGitMirror(repoPath string) error {
+	lock, _ := gitMirrorLocks.LoadOrStore(repoPath, &sync.Mutex{})
+	if !lock.(*sync.Mutex).TryLock() {
+		return ErrGitMirrorSyncInProgress
+	}
+	defer lock.(*sync.Mutex).Unlock()
+
+	repo, err := LoadGitRepository(repoPath)
+	if err != nil {
+		return err
+	}
+	if repo.Mirror == nil {
+		return fmt.Errorf("repository %s/%s is not a mirror", repo.Workspace, repo.Name)
+	}
+
+	branch, syncErr := fetchGitMirror(repoPath, repo.Mirror)
+
+	// Перечитываем метаданные под блокировкой: за время fetch они могли измениться
+	gitMetadataMutex.Lock()
+	defer gitMetadataMutex.Unlock()
+
+	repo, err = LoadGitRepository(repoPath)
+	if err != nil {
+		return err
+	}
+	if repo.Mirror == nil {
+		return syncErr
+	}
+
+	now := time.Now()
+	repo.Mirror.LastSyncAt = &now
+	if syncErr != nil {
+		repo.Mirror.LastSyncStatus = GitMirrorStatusFailed
+		repo.Mirror.LastSyncError = truncateGitMirrorError(syncErr, repo.Mirror)
+	} else {
+		repo.Mirror.LastSyncStatus = GitMirrorStatusSuccess
+		repo.Mirror.LastSyncError = ""
+		repo.Mirror.LastSuccessAt = &now
+		if branch != "" {
+			repo.Branch = branch
+		}
+	}
+
+	if err := repo.Save(); err != nil {
+		return err
+	}
+	return syncErr
+}
+
+// GitMirrorSyncer периодически синхронизирует все зеркала (cron задача git_mirrors_sync)
+type GitMirrorSyncer struct {
+	gitReposPath string
+}
+
+// NewGitMirrorSyncer создает синхронизатор зеркал для директории репозиториев
+func NewGitMirrorSyncer(gitReposPath string) *GitMirrorSyncer {
+	return &GitMirrorSyncer{gitReposPath: gitReposPath}
+}
+
+// SyncAll последовательно синхронизирует все зеркала во всех workspace
+func (s *GitMirrorSyncer) SyncAll() {
+	files, err := filepath.Glob(filepath.Join(s.gitReposPath, "*", "*.git", "aiplan.json"))
+	if err != nil {
+		slog.Error("Failed to list git repositories for mirror sync", "err", err)
+		return
+	}
+
+	for _, file := range files {
+		repoPath := filepath.Dir(file)
+		if !IsGitMirror(repoPath) {
+			continue
+		}
+
+		if err := SyncGitMirror(repoPath); err != nil {
+			if errors.Is(err, ErrGitMirrorSyncInProgress) {
+				continue
+			}
+			slog.Warn("Git mirror sync failed", "path", repoPath, "err", err)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+// Персональные токены доступа.
+//
+// Пользователь создает несколько именованных токенов с областями действия (types.TokenScopes), сроком действия и,
+// при необходимости, ограничением одним пространством. Токен передается как Bearer/Basic, так же как токен авторизации
+// пользователя, и отличается от него префиксом dao.AccessTokenPrefix. Области действия и ограничение пространством
+// проверяются в AuthMiddleware, доступ к MCP серверу требует области mcp, а инструменты MCP, изменяющие задачи
+// и документы, - соответствующих областей записи
+package aiplan
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	apicontext "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/api-context"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/utils"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/sethvargo/go-password/password"
+	"gorm.io/gorm"
+)
+
+// accessTokenUsageInterval - минимальный интервал обновления времени последнего использования токена
+const accessTokenUsageInterval = time.Minute
+
+type CreateAccessTokenRequest struct {
+	Name        string        `json:"name"`
+	Scopes      []string      `json:"scopes"`
+	WorkspaceId uuid.NullUUID `json:"workspace_id" swaggertype:"string"`
+	ExpiresAt   *time.Time    `json:"expires_at"`
+}
+
+// authPersonalAccessToken находит пользователя по персональному токену доступа и обновляет время использования токена
+func (s *Services) authPersonalAccessToken(c echo.Context, db *gorm.DB, raw string) (*dao.User, *dao.PersonalAccessToken, error) {
+	var pat dao.PersonalAccessToken
+	if err := db.
+		Preload("Workspace").
+		Where("token_hash = ?", dao.HashAccessToken(raw)).
+		First(&pat).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, apierrors.ErrFailedLogin
+		}
+		return nil, nil, err
+	}
+	now := time.Now()
+	if !pat.Active(now) {
+		return nil, nil, apierrors.ErrTokenExpired
+	}
+
+	var user dao.User
+	if err := db.Joins("LastWorkspace").Where("users.id = ?", pat.UserId).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, apierrors.ErrFailedLogin
+		}
+		return nil, nil, err
+	}
+	if !user.IsActive {
+		return nil, nil, apierrors.ErrLoginTriesExceed
+	}
+
+	if pat.LastUsedAt == nil || now.Sub(*pat.LastUsedAt) > accessTokenUsageInterval || pat.LastUsedIp != c.RealIP() {
+		if err := db.Model(&pat).UpdateColumns(map[string]any{"last_used_at": now, "last_used_ip": c.RealIP()}).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+	return &user, &pat, nil
+}
+
+// checkAccessTokenScope проверяет, что запрос разрешен областями действия токена и пространством, которым он ограничен
+func checkAccessTokenScope(c echo.Context, pat *dao.PersonalAccessToken) error {
+	path := c.Path()
+	if !accessTokenAllows(pat.Scopes, c.Request().Method, path) {
+		return apierrors.ErrAccessTokenScope
+	}
+
+	if !pat.WorkspaceId.Valid {
+		return nil
+	}
+	// MCP инструменты работают со всеми пространствами пользователя
+	if strings.HasPrefix(path, "/mcp/") {
+		return apierrors.ErrAccessTokenWorkspace
+	}
+	slugOrId := c.Param("workspaceSlug")
+	if slugOrId == "" {
+		// вне пространств доступны только данные о пользователе и список пространств
+		if c.Request().Method == http.MethodGet && (path == "/api/auth/users/me/" || path == "/api/auth/workspaces/") {
+			return nil
+		}
+		return apierrors.ErrAccessTokenWorkspace
+	}
+	if slugOrId == pat.WorkspaceId.UUID.String() || (pat.Workspace != nil && slugOrId == pat.Workspace.Slug) {
+		return nil
+	}
+	return apierrors.ErrAccessTokenWorkspace
+}
+
+// accessTokenAllows проверяет метод и путь маршрута запроса по областям действия токена
+func accessTokenAllows(scopes []string, method, path string) bool {
+	if slices.Contains(scopes, types.TokenScopeAdmin) {
+		return true
+	}
+
+	if strings.HasPrefix(path, "/mcp/") {
+		return slices.Contains(scopes, types.TokenScopeMCP)
+	}
+
+	// администрирование и управление доступом к аккаунту только с полным доступом
+	for _, prefix := range []string{
+		"/api/auth/admin/",
+		"/api/auth/users/me/tokens/",
+		"/api/auth/users/me/token/",
+		"/api/auth/users/me/2fa/",
+		"/api/auth/users/me/sessions/",
+		"/api/auth/users/me/change-email/",
+		"/api/auth/change-my-password/",
+		"/api/auth/sign-out-everywhere/",
+	} {
+		if strings.HasPrefix(path, prefix) {
+			return false
+		}
+	}
+
+	canRead := slices.Contains(scopes, types.TokenScopeRead) ||
+		slices.Contains(scopes, types.TokenScopeIssuesWrite) ||
+		slices.Contains(scopes, types.TokenScopeDocsWrite)
+	// поиск выполняется POST запросами, но ничего не изменяет
+	if method == http.MethodGet || method == http.MethodHead || strings.Contains(path, "/search/") {
+		return canRead
+	}
+
+	switch {
+	case strings.HasPrefix(path, "/api/auth/workspaces/:workspaceSlug/projects/:projectId/issues/"),
+		strings.HasPrefix(path, "/api/auth/issues/"),
+		strings.HasPrefix(path, "/api/auth/issue-attachments/"):
+		return slices.Contains(scopes, types.TokenScopeIssuesWrite)
+	case strings.HasPrefix(path, "/api/auth/workspaces/:workspaceSlug/doc"):
+		return slices.Contains(scopes, types.TokenScopeDocsWrite)
+	}
+	return false
+}
+
+// getMyAccessTokens godoc
+// @id getMyAccessTokens
+// @Summary Пользователи (управление доступом): персональные токены доступа
+// @Description Возвращает персональные токены доступа текущего пользователя без значений токенов
+// @Tags Users
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {array} dto.PersonalAccessToken "Токены"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Router /api/auth/users/me/tokens/ [get]
+func (s *Services) getMyAccessTokens(c echo.Context) error {
+	user := apicontext.GetContext(c).GetUser()
+
+	var tokens []dao.PersonalAccessToken
+	if err := s.DB(c).
+		Preload("Workspace").
+		Where("user_id = ?", user.ID).
+		Order("created_at DESC").
+		Find(&tokens).Error; err != nil {
+		return EError(c, err)
+	}
+	return c.JSON(http.StatusOK, utils.SliceToSlice(&tokens, func(t *dao.PersonalAccessToken) dto.PersonalAccessToken { return *t.ToDTO() }))
+}
+
+// createMyAccessToken godoc
+// @id createMyAccessToken
+// @Summary Пользователи (управление доступом): создание персонального токена доступа
+// @Description Создает персональный токен доступа. Значение токена возвращается только в этом ответе
+// @Tags Users
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param data body CreateAccessTokenRequest true "Параметры токена"
+// @Success 201 {object} dto.PersonalAccessTokenCreated "Созданный токен"
+// @Failure 400 {object} apierrors.DefinedError "Некорректные параметры токена"
+// @Failure 403 {object} apierrors.DefinedError "Токены нельзя создавать по токену"
+// @Router /api/auth/users/me/tokens/ [post]
+func (s *Services) createMyAccessToken(c echo.Context) error {
+	apiContext := apicontext.GetContext(c)
+	user := apiContext.GetUser()
+	if apiContext.IsTokenAuth() {
+		return EErrorDefined(c, apierrors.ErrAccessTokenManagement)
+	}
+
+	var req CreateAccessTokenRequest
+	if err := c.Bind(&req); err != nil {
+		return EError(c, err)
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > 255 || len(req.Scopes) == 0 || (req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now())) {
+		return EErrorDefined(c, apierrors.ErrAccessTokenInvalid)
+	}
+	var scopes []string
+	for _, scope := range req.Scopes {
+		if !slices.Contains(types.TokenScopes, scope) {
+			return EErrorDefined(c, apierrors.ErrAccessTokenInvalid)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	pat := dao.PersonalAccessToken{
+		ID:          dao.GenUUID(),
+		UserId:      user.ID,
+		Name:        req.Name,
+		Scopes:      scopes,
+		WorkspaceId: req.WorkspaceId,
+		ExpiresAt:   req.ExpiresAt,
+	}
+	if req.WorkspaceId.Valid {
+		var workspace dao.Workspace
+		if err := s.DB(c).
+			Where("id = ?", req.WorkspaceId.UUID).
+			Where("exists (select 1 from workspace_members wm where wm.workspace_id = workspaces.id and wm.member_id = ?)", user.ID).
+			First(&workspace).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return EErrorDefined(c, apierrors.ErrWorkspaceNotFound)
+			}
+			return EError(c, err)
+		}
+		pat.Workspace = &workspace
+	}
+
+	raw := dao.AccessTokenPrefix + password.MustGenerate(40, 10, 0, false, true)
+	pat.TokenHash = dao.HashAccessToken(raw)
+	pat.Prefix = raw[:len(dao.AccessTokenPrefix)+6]
+	if err := s.DB(c).Omit("User", "Workspace").Create(&pat).Error; err != nil {
+		return EError(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, dto.PersonalAccessTokenCreated{
+		PersonalAccessToken: *pat.ToDTO(),
+		Token:               raw,
+	})
+}
+
+// revokeMyAccessToken godoc
+// @id revokeMyAccessToken
+// @Summary Пользователи (управление доступом): отзыв персонального токена доступа
+// @Description Отзывает персональный токен доступа, после чего запросы с ним отклоняются
+// @Tags Users
+// @Security ApiKeyAuth
+// @Param tokenId path string true "ID токена"
+// @Success 200 "Токен отозван"
+// @Failure 403 {object} apierrors.DefinedError "Токены нельзя отзывать по токену"
+// @Failure 404 {object} apierrors.DefinedError "Токен не найден"
+// @Router /api/auth/users/me/tokens/{tokenId}/ [delete]
+func (s *Services) revokeMyAccessToken(c echo.Context) error {
+	apiContext := apicontext.GetContext(c)
+	user := apiContext.GetUser()
+	if apiContext.IsTokenAuth() {
+		return EErrorDefined(c, apierrors.ErrAccessTokenManagement)
+	}
+
+	tokenId, err := uuid.FromString(c.Param("tokenId"))
+	if err != nil {
+		return EErrorDefined(c, apierrors.ErrAccessTokenNotFound)
+	}
+
+	res := s.DB(c).Model(&dao.PersonalAccessToken{}).
+		Where("id = ?", tokenId).
+		Where("user_id = ?", user.ID).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		return EError(c, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return EErrorDefined(c, apierrors.ErrAccessTokenNotFound)
+	}
+	return c.NoContent(http.StatusOK)
+}
@@ -0,0 +1,78 @@
+package aiplan
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/mcp/tools"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+)
+
+func TestAccessTokenAllows(t *testing.T) {
+	const (
+		issues = "/api/auth/workspaces/:workspaceSlug/projects/:projectId/issues/:issueIdOrSeq/"
+		docs   = "/api/auth/workspaces/:workspaceSlug/doc/:docId/"
+	)
+	read := []string{types.TokenScopeRead}
+	issuesWrite := []string{types.TokenScopeIssuesWrite}
+	docsWrite := []string{types.TokenScopeDocsWrite}
+	admin := []string{types.TokenScopeAdmin}
+
+	cases := []struct {
+		name   string
+		scopes []string
+		method string
+		path   string
+		want   bool
+	}{
+		{"read issue", read, http.MethodGet, issues, true},
+		{"read search", read, http.MethodPost, "/api/auth/issues/search/", true},
+		{"read export", read, http.MethodPost, "/api/auth/issues/search/export/", true},
+		{"read update issue", read, http.MethodPatch, issues, false},
+		{"issues update issue", issuesWrite, http.MethodPatch, issues, true},
+		{"issues update doc", issuesWrite, http.MethodPatch, docs, false},
+		{"docs update doc", docsWrite, http.MethodPatch, docs, true},
+		{"docs read issue", docsWrite, http.MethodGet, issues, true},
+		{"docs update issue", docsWrite, http.MethodPatch, issues, false},
+		{"issues workspace settings", issuesWrite, http.MethodPatch, "/api/auth/workspaces/:workspaceSlug/", false},
+		{"read admin", read, http.MethodGet, "/api/auth/admin/users/", false},
+		{"read tokens", read, http.MethodGet, "/api/auth/users/me/tokens/", false},
+		{"read auth token", read, http.MethodGet, "/api/auth/users/me/token/", false},
+		{"admin tokens", admin, http.MethodGet, "/api/auth/users/me/tokens/", true},
+		{"admin update", admin, http.MethodDelete, docs, true},
+		{"read mcp", read, http.MethodPost, "/mcp/*", false},
+		{"mcp", []string{types.TokenScopeMCP}, http.MethodPost, "/mcp/*", true},
+		{"mcp api", []string{types.TokenScopeMCP}, http.MethodGet, issues, false},
+	}
+	for _, tc := range cases {
+		if got := accessTokenAllows(tc.scopes, tc.method, tc.path); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestMCPToolAllowed(t *testing.T) {
+	mcpRead := []string{types.TokenScopeMCP, types.TokenScopeRead}
+	mcpIssues := []string{types.TokenScopeMCP, types.TokenScopeIssuesWrite}
+	mcpDocs := []string{types.TokenScopeMCP, types.TokenScopeDocsWrite}
+
+	cases := []struct {
+		scopes []string
+		scope  string
+		want   bool
+	}{
+		{mcpRead, types.TokenScopeRead, true},
+		{mcpRead, types.TokenScopeIssuesWrite, false},
+		{mcpIssues, types.TokenScopeIssuesWrite, true},
+		{mcpIssues, types.TokenScopeDocsWrite, false},
+		{mcpDocs, types.TokenScopeDocsWrite, true},
+		{mcpDocs, types.TokenScopeIssuesWrite, false},
+		{mcpDocs, "", false},
+		{[]string{types.TokenScopeAdmin}, types.TokenScopeIssuesWrite, true},
+	}
+	for _, tc := range cases {
+		if got := tools.ToolAllowed(tc.scopes, tc.scope); got != tc.want {
+			t.Errorf("%v %s: got %v, want %v", tc.scopes, tc.scope, got, tc.want)
+		}
+	}
+}
@@ -135,6 +135,9 @@ func (s *Services) AddAdminServices(g *echo.Group) {
 	usersGroup.GET(":userId/", s.getUserById)
 	usersGroup.PATCH(":userId/", s.updateUser)
 	usersGroup.DELETE(":userId/", s.deleteUser)
+	usersGroup.POST(":userId/2fa/reset/", s.resetUserTwoFactor)
+	usersGroup.GET(":userId/sessions/", s.getUserSessions)
+	usersGroup.DELETE(":userId/sessions/:sessionId/", s.revokeUserSession)
 	usersGroup.GET(":userId/feedback/", s.getUserFeedback)
 
 	usersGroup.GET(":userId/workspaces/", s.geWorkspaceListByUser)
@@ -162,6 +165,8 @@ func (s *Services) AddAdminServices(g *echo.Group) {
 	importsGroup.GET("", s.getRunningImportList)
 
 	staffPermissionGroup.GET("jitsi-token-logs/", s.getJitsiTokenLogList)
+
+	s.addLdapGroupServices(staffPermissionGroup.Group("ldap/"))
 }
 
 // getAllWorkspaceList godoc
@@ -310,6 +315,7 @@ func (s *Services) deleteWorkspaceByAdmin(c echo.Context) error {
 	if err := s.importService.CancelWorkspaceImports(workspace.ID); err != nil {
 		return EError(c, err)
 	}
+	s.docImportService.CancelWorkspaceImports(workspace.ID)
 
 	if err := s.business.DeleteWorkspace(user, &workspace); err != nil {
 		return EError(c, err)
@@ -704,6 +710,94 @@ func (s *Services) deleteUser(c echo.Context) error {
 	return c.NoContent(http.StatusOK)
 }
 
+// resetUserTwoFactor godoc
+// @id resetUserTwoFactor
+// @Summary Пользователи: сброс двухфакторной аутентификации
+// @Description Отключает 2FA пользователя и удаляет коды восстановления, например при потере приложения-аутентификатора. Если 2FA обязательна, пользователь подключит ее заново при следующем входе
+// @Tags AdminPanel
+// @Security ApiKeyAuth
+// @Param userId path string true "ID пользователя"
+// @Success 200 "2FA сброшена"
+// @Failure 403 {object} apierrors.DefinedError "Ошибка: доступ запрещен"
+// @Failure 404 {object} apierrors.DefinedError "Пользователь не найден"
+// @Failure 500 {object} apierrors.DefinedError "Внутренняя ошибка сервера"
+// @Router /api/auth/admin/users/{userId}/2fa/reset [post]
+func (s *Services) resetUserTwoFactor(c echo.Context) error {
+	userId, err := uuid.FromString(c.Param("userId"))
+	if err != nil {
+		return EErrorDefined(c, apierrors.ErrUserNotFound)
+	}
+
+	var user dao.User
+	if err := s.DB(c).Where("id = ?", userId).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return EErrorDefined(c, apierrors.ErrUserNotFound)
+		}
+		return EError(c, err)
+	}
+
+	if err := resetTwoFactor(s.DB(c), &user); err != nil {
+		return EError(c, err)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// getUserSessions godoc
+// @id getUserSessions
+// @Summary Пользователи: активные сессии пользователя
+// @Description Возвращает активные сессии пользователя на устройствах
+// @Tags AdminPanel
+// @Security ApiKeyAuth
+// @Produce json
+// @Param userId path string true "ID пользователя"
+// @Success 200 {array} dto.UserSession "Сессии"
+// @Failure 403 {object} apierrors.DefinedError "Ошибка: доступ запрещен"
+// @Failure 404 {object} apierrors.DefinedError "Пользователь не найден"
+// @Router /api/auth/admin/users/{userId}/sessions [get]
+func (s *Services) getUserSessions(c echo.Context) error {
+	userId, err := uuid.FromString(c.Param("userId"))
+	if err != nil {
+		return EErrorDefined(c, apierrors.ErrUserNotFound)
+	}
+
+	sessions, err := listUserSessions(s.DB(c), userId, currentSessionId(apicontext.GetContext(c).GetAuthInfo()))
+	if err != nil {
+		return EError(c, err)
+	}
+	return c.JSON(http.StatusOK, sessions)
+}
+
+// revokeUserSession godoc
+// @id revokeUserSession
+// @Summary Пользователи: завершение сессии пользователя
+// @Description Завершает сессию пользователя на устройстве
+// @Tags AdminPanel
+// @Security ApiKeyAuth
+// @Param userId path string true "ID пользователя"
+// @Param sessionId path string true "ID сессии"
+// @Success 200 "Сессия завершена"
+// @Failure 403 {object} apierrors.DefinedError "Ошибка: доступ запрещен"
+// @Failure 404 {object} apierrors.DefinedError "Сессия не найдена"
+// @Router /api/auth/admin/users/{userId}/sessions/{sessionId} [delete]
+func (s *Services) revokeUserSession(c echo.Context) error {
+	userId, err := uuid.FromString(c.Param("userId"))
+	if err != nil {
+		return EErrorDefined(c, apierrors.ErrUserNotFound)
+	}
+	sessionId, err := uuid.FromString(c.Param("sessionId"))
+	if err != nil {
+		return EErrorDefined(c, apierrors.ErrSessionNotFound)
+	}
+
+	if err := revokeSession(s.DB(c), userId, sessionId); err != nil {
+		if defined, ok := err.(apierrors.DefinedError); ok {
+			return EErrorDefined(c, defined)
+		}
+		return EError(c, err)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
 // getUserFeedback godoc
 // @id getUserFeedback
 // @Summary Пользователи: получение отзыва пользователя
@@ -107,7 +107,24 @@ func (s *Services) AuthMiddleware(secret []byte, skipper middleware.Skipper) ech
 			// Token auth
 			if schema == "Basic" || schema == "Bearer" {
 				var user dao.User
-				if err := s.db.WithContext(ctx).
+				var personalToken *dao.PersonalAccessToken
+				if strings.HasPrefix(accessToken.SignedString, dao.AccessTokenPrefix) {
+					patUser, pat, err := s.authPersonalAccessToken(c, s.db.WithContext(ctx), accessToken.SignedString)
+					if err != nil {
+						if defined, ok := err.(apierrors.DefinedError); ok {
+							return EErrorDefined(c, defined)
+						}
+						span.RecordError(err)
+						span.SetStatus(codes.Error, "personal access token lookup failed")
+						return EError(c, err)
+					}
+					if err := checkAccessTokenScope(c, pat); err != nil {
+						return EErrorDefined(c, err.(apierrors.DefinedError))
+					}
+					user = *patUser
+					personalToken = pat
+					c.Set("token_scopes", pat.Scopes)
+				} else if err := s.db.WithContext(ctx).
 					Joins("LastWorkspace").
 					Where("users.auth_token = ?", accessToken.SignedString).
 					First(&user).Error; err != nil {
@@ -125,10 +142,11 @@ func (s *Services) AuthMiddleware(secret []byte, skipper middleware.Skipper) ech
 				}
 				c.Set("user", &user)
 				apicontext.SetContext(c, s.db.WithContext(ctx), &apicontext.UserMeta{
-					User:         &user,
-					AccessToken:  accessToken,
-					RefreshToken: nil,
-					TokenAuth:    true,
+					User:          &user,
+					AccessToken:   accessToken,
+					RefreshToken:  nil,
+					TokenAuth:     true,
+					PersonalToken: personalToken,
 				})
 				return next(c)
 			}
@@ -215,6 +233,17 @@ func (s *Services) AuthMiddleware(secret []byte, skipper middleware.Skipper) ech
 				if reseted.Valid && reseted.Bool {
 					return EErrorDefined(c, apierrors.ErrSessionReset)
 				}
+
+				if sessionId := tokenSessionId(accessToken); !sessionId.IsNil() {
+					if err := s.checkSession(s.db.WithContext(ctx), user.ID, sessionId, c.RealIP()); err != nil {
+						if defined, ok := err.(apierrors.DefinedError); ok {
+							return EErrorDefined(c, defined)
+						}
+						span.RecordError(err)
+						span.SetStatus(codes.Error, "session check failed")
+						return EError(c, err)
+					}
+				}
 			}
 
 			if user == nil {
@@ -265,6 +294,8 @@ func (s *Services) AuthMiddleware(secret []byte, skipper middleware.Skipper) ech
 
 func (s *Services) AddAuthenticationServices(g *echo.Group, secret []byte) {
 	g.POST("sign-in/", s.emailLogin)
+	g.POST("sign-in/2fa/", s.twoFactorSignIn)
+	g.POST("sign-in/2fa/setup/", s.twoFactorSignInSetup)
 
 	g.GET("captcha/", s.requestCaptcha)
 }
@@ -350,6 +381,11 @@ func (s *Services) emailLogin(c echo.Context) error {
 		return EErrorDefined(c, apierrors.ErrIntegrationLogin)
 	}
 
+	// при входе только через SSO пароль остается у суперпользователей для восстановления доступа
+	if cfg.SSOForce && s.oidcProvider != nil && !user.IsSuperuser {
+		return EErrorDefined(c, apierrors.ErrSSOOnly)
+	}
+
 	sucessfullLogin := false
 	if s.authProvider != nil {
 		if s.authProvider.AuthUser(req.Email, req.Password) {
@@ -363,6 +399,7 @@ func (s *Services) emailLogin(c echo.Context) error {
 				}
 			}
 			sucessfullLogin = true
+			s.syncLdapGroups(&user)
 		}
 	}
 
@@ -371,29 +408,65 @@ func (s *Services) emailLogin(c echo.Context) error {
 	}
 
 	if !sucessfullLogin {
-		user.LoginAttempts++
-		time.Sleep(time.Second * time.Duration(user.LoginAttempts))
-
-		// block after 5 fails
-		if user.LoginAttempts >= 5 {
-			slog.InfoContext(c.Request().Context(), "Block user for more than 5 failed attempts", "user", user.String())
-			user.BlockedUntil = sql.NullTime{Valid: true, Time: time.Now().Add(time.Minute * 20)}
-			user.LoginAttempts = 0
-		}
+		return s.failedLogin(c, &user)
+	}
 
-		if err := s.DB(c).Model(&user).Select("LoginAttempts", "BlockedUntil").Updates(&user).Error; err != nil {
+	// при включенной или обязательной двухфакторной аутентификации токены выдаются после проверки кода
+	required, err := twoFactorRequired(s.DB(c), &user)
+	if err != nil {
+		return EError(c, err)
+	}
+	if user.TOTPEnabled || required {
+		challenge, err := newTwoFactorChallenge(&user)
+		if err != nil {
 			return EError(c, err)
 		}
+		return c.JSON(http.StatusOK, challenge)
+	}
 
-		if user.BlockedUntil.Valid && user.BlockedUntil.Time.After(time.Now()) {
-			s.notificationsService.Tg.UserBlockedUntil(user, user.BlockedUntil.Time)
-			s.emailService.UserBlockedUntil(user, user.BlockedUntil.Time)
-			return EErrorDefined(c, apierrors.ErrBlockedUntil.WithFormattedMessage(user.BlockedUntil.Time.Format("02.01.2006 15:04")))
-		}
+	return s.loginResponse(c, &user, nil)
+}
 
-		return EErrorDefined(c, apierrors.ErrFailedLogin)
+// failedLogin увеличивает счетчик неудачных попыток входа и блокирует пользователя после 5 неудачных попыток подряд
+func (s *Services) failedLogin(c echo.Context, user *dao.User) error {
+	if err := s.countFailedAttempt(c, user); err != nil {
+		return EError(c, err)
 	}
+	return EErrorDefined(c, apierrors.ErrFailedLogin)
+}
 
+// countFailedAttempt учитывает неудачную попытку подтверждения личности пользователя. После 5 неудачных попыток подряд
+// пользователь блокируется и возвращается ErrBlockedUntil
+func (s *Services) countFailedAttempt(c echo.Context, user *dao.User) error {
+	user.LoginAttempts++
+	time.Sleep(time.Second * time.Duration(user.LoginAttempts))
+
+	// block after 5 fails
+	if user.LoginAttempts >= 5 {
+		slog.InfoContext(c.Request().Context(), "Block user for more than 5 failed attempts", "user", user.String())
+		user.BlockedUntil = sql.NullTime{Valid: true, Time: time.Now().Add(time.Minute * 20)}
+		user.LoginAttempts = 0
+	}
+
+	if err := s.DB(c).Model(user).Select("LoginAttempts", "BlockedUntil").Updates(user).Error; err != nil {
+		return err
+	}
+
+	if userBlocked(user) {
+		s.notificationsService.Tg.UserBlockedUntil(*user, user.BlockedUntil.Time)
+		s.emailService.UserBlockedUntil(*user, user.BlockedUntil.Time)
+		return apierrors.ErrBlockedUntil.WithFormattedMessage(user.BlockedUntil.Time.Format("02.01.2006 15:04"))
+	}
+	return nil
+}
+
+// userBlocked сообщает, заблокирован ли пользователь после неудачных попыток входа
+func userBlocked(user *dao.User) bool {
+	return user.BlockedUntil.Valid && user.BlockedUntil.Time.After(time.Now())
+}
+
+// finishLogin обновляет данные последнего входа пользователя и выдает токены доступа
+func (s *Services) finishLogin(c echo.Context, user *dao.User) (*token.Token, *token.Token, error) {
 	tm := time.Now()
 
 	user.LastActive = &tm
@@ -404,22 +477,35 @@ func (s *Services) emailLogin(c echo.Context) error {
 	user.TokenUpdatedAt = &tm
 	user.LoginAttempts = 0
 	user.BlockedUntil = sql.NullTime{}
-	if err := s.DB(c).Model(&user).Select("LastActive", "LastLoginTime", "LastLoginIp", "LastLoginUagent", "TokenUpdatedAt", "LoginAttempts", "BlockedUntil").Updates(&user).Error; err != nil {
-		return EError(c, err)
+	if err := s.DB(c).Model(user).Select("LastActive", "LastLoginTime", "LastLoginIp", "LastLoginUagent", "TokenUpdatedAt", "LoginAttempts", "BlockedUntil").Updates(user).Error; err != nil {
+		return nil, nil, err
 	}
 
-	access_token, refresh_token, err := createAccessToken(user.ID)
+	access_token, refresh_token, err := s.createSession(c, user, true)
 	if err != nil {
-		return EError(c, err)
+		return nil, nil, err
 	}
 
 	setAuthCookies(c, access_token, refresh_token)
+	return access_token, refresh_token, nil
+}
+
+// loginResponse завершает вход и возвращает токены доступа и пользователя. extra добавляется в ответ
+func (s *Services) loginResponse(c echo.Context, user *dao.User, extra map[string]interface{}) error {
+	access_token, refresh_token, err := s.finishLogin(c, user)
+	if err != nil {
+		return EError(c, err)
+	}
 
-	return c.JSON(http.StatusOK, map[string]interface{}{
+	resp := map[string]interface{}{
 		"access_token":  access_token.SignedString,
 		"refresh_token": refresh_token.SignedString,
 		"user":          user,
-	})
+	}
+	for k, v := range extra {
+		resp[k] = v
+	}
+	return c.JSON(http.StatusOK, resp)
 }
 
 func (s *Services) tokenProlong(c echo.Context, tkn *token.Token) (*token.Token, *dao.User, error) {
@@ -483,8 +569,11 @@ func (s *Services) tokenProlong(c echo.Context, tkn *token.Token) (*token.Token,
 		return nil, nil, EErrorDefined(c, apierrors.ErrSessionReset)
 	}
 
-	accessToken, refreshToken, err := createAccessToken(user.ID)
+	accessToken, refreshToken, err := s.prolongSession(c, &user, tkn)
 	if err != nil {
+		if defined, ok := err.(apierrors.DefinedError); ok {
+			return nil, nil, EErrorDefined(c, defined)
+		}
 		return nil, nil, EError(c, err)
 	}
 
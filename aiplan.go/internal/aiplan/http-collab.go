@@ -0,0 +1,205 @@
+// Совместное редактирование документов и описаний задач через WebSocket.
+//
+// Подключение проходит через middleware прав доступа документа/задачи: участник с правом чтения
+// видит изменения и курсоры, участник с правом редактирования может вносить изменения.
+// Содержимое сессии периодически сохраняется в Doc.Content или DescriptionHtml/DescriptionJSON задачи,
+// а изменения фиксируются в истории через snapshotTracker.
+package aiplan
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"time"
+
+	tracker "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/activity-tracker"
+	apicontext "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/api-context"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/collab"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor/tiptap"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/opt"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/utils"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// docCollab godoc
+// @id docCollab
+// @Summary doc: совместное редактирование документа
+// @Description WebSocket-сессия совместного редактирования документа (шаги TipTap, снимки, курсоры участников). Пользователи без права редактирования подключаются только для просмотра
+// @Tags Docs
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug рабочего пространства"
+// @Param docId path string true "Id документа"
+// @Success 101 "Переключение на WebSocket"
+// @Failure 403 {object} apierrors.DefinedError "Доступ запрещен"
+// @Failure 404 {object} apierrors.DefinedError "Ошибка: не найдено"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/workspaces/{workspaceSlug}/doc/{docId}/collab/ [get]
+func (s *Services) docCollab(c echo.Context) error {
+	apiContext := apicontext.GetContext(c)
+	workspaceMember := apiContext.GetWorkspaceMember()
+	doc := apiContext.GetDoc()
+	if apiContext.Error() != nil {
+		return EError(c, apiContext.Error())
+	}
+	user := apiContext.GetUser()
+
+	canEdit := user.ID == doc.CreatedById ||
+		user.IsSuperuser ||
+		workspaceMember.Role == types.AdminRole ||
+		hasEditAccess(user.ID, workspaceMember.Role, doc, utils.SliceToSet(doc.EditorsIDs))
+
+	docId := doc.ID
+	if err := s.collabHub.Handle(c.Response(), c.Request(), collab.DocKey(docId), user, canEdit, func() (collab.Store, collab.Content, error) {
+		var content types.RedactorHTML
+		if err := s.db.Model(&dao.Doc{}).Select("content").Where("id = ?", docId).Scan(&content).Error; err != nil {
+			return nil, collab.Content{}, err
+		}
+		store := &docCollabStore{db: s.db, tracker: s.snapshotTracker, docId: docId, committed: content.Body}
+		return store, collab.Content{HTML: content.Body}, nil
+	}); err != nil {
+		return EError(c, err)
+	}
+	return nil
+}
+
+// issueDescriptionCollab godoc
+// @id issueDescriptionCollab
+// @Summary Задачи: совместное редактирование описания задачи
+// @Description WebSocket-сессия совместного редактирования описания задачи (шаги TipTap, снимки, курсоры участников). Редактировать описание могут автор задачи и администратор проекта, остальные подключаются только для просмотра
+// @Tags Issues
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug рабочего пространства"
+// @Param projectId path string true "ID проекта"
+// @Param issueIdOrSeq path string true "Идентификатор или последовательный номер задачи"
+// @Success 101 "Переключение на WebSocket"
+// @Failure 403 {object} apierrors.DefinedError "Доступ запрещен"
+// @Failure 404 {object} apierrors.DefinedError "Ошибка: не найдено"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/workspaces/{workspaceSlug}/projects/{projectId}/issues/{issueIdOrSeq}/description-collab/ [get]
+func (s *Services) issueDescriptionCollab(c echo.Context) error {
+	apiContext := apicontext.GetContext(c)
+	projectMember := apiContext.GetProjectMember()
+	issue := apiContext.GetIssue()
+	if apiContext.Error() != nil {
+		return EError(c, apiContext.Error())
+	}
+	user := apiContext.GetUser()
+
+	// Как и в updateIssue, описание меняют только автор и администратор проекта
+	canEdit := projectMember.Role == types.AdminRole || issue.CreatedById == user.ID
+
+	issueId := issue.ID
+	if err := s.collabHub.Handle(c.Response(), c.Request(), collab.IssueKey(issueId), user, canEdit, func() (collab.Store, collab.Content, error) {
+		var description string
+		if err := s.db.Model(&dao.Issue{}).Select("description_html").Where("id = ?", issueId).Scan(&description).Error; err != nil {
+			return nil, collab.Content{}, err
+		}
+		store := &issueCollabStore{db: s.db, tracker: s.snapshotTracker, issueId: issueId, committed: description}
+		return store, collab.Content{HTML: description}, nil
+	}); err != nil {
+		return EError(c, err)
+	}
+	return nil
+}
+
+// docCollabStore сохраняет сессию совместного редактирования в документ
+type docCollabStore struct {
+	db      *gorm.DB
+	tracker *tracker.SnapshotTracker
+	docId   uuid.UUID
+	// Содержимое на момент последней записи в историю
+	committed string
+}
+
+func (st *docCollabStore) Save(ctx context.Context, content collab.Content, actor *dao.User) error {
+	return st.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&dao.Doc{}).
+			Where("id = ?", st.docId).
+			UpdateColumns(map[string]interface{}{
+				"content":       types.RedactorHTML{Body: content.HTML},
+				"updated_at":    time.Now(),
+				"updated_by_id": actor.ID,
+			}).Error; err != nil {
+			return err
+		}
+		// UpdateColumns не вызывает хуки, ссылки и фрагменты обсуждений обновляются явно
+		if err := dao.SyncReferences(tx, dao.ReferenceSourceDoc, st.docId); err != nil {
+			return err
+		}
+		return dao.RelocateDocAnchors(tx, st.docId)
+	})
+}
+
+func (st *docCollabStore) Commit(ctx context.Context, actor *dao.User) error {
+	var doc dao.Doc
+	if err := st.db.WithContext(ctx).
+		Preload("Workspace").
+		Preload("Author").
+		Where("id = ?", st.docId).
+		First(&doc).Error; err != nil {
+		return err
+	}
+
+	oldSnapshot := tracker.DocSnapshot{ID: doc.ID, Title: opt.Some(doc.Title), Content: opt.Some(st.committed)}
+	newSnapshot := tracker.DocSnapshot{ID: doc.ID, Title: opt.Some(doc.Title), Content: opt.Some(doc.Content.String())}
+	if err := st.tracker.TrackChanges(types.LayerDoc, oldSnapshot, newSnapshot, &doc, actor); err != nil {
+		return err
+	}
+	st.committed = doc.Content.String()
+	return nil
+}
+
+// issueCollabStore сохраняет сессию совместного редактирования в описание задачи
+type issueCollabStore struct {
+	db      *gorm.DB
+	tracker *tracker.SnapshotTracker
+	issueId uuid.UUID
+	// Описание на момент последней записи в историю
+	committed string
+}
+
+func (st *issueCollabStore) Save(ctx context.Context, content collab.Content, actor *dao.User) error {
+	data := map[string]interface{}{
+		"description_html": content.HTML,
+		"updated_at":       time.Now(),
+		"updated_by_id":    uuid.NullUUID{UUID: actor.ID, Valid: true},
+	}
+	if doc, err := tiptap.ParseJSON(bytes.NewReader(content.Doc)); err == nil {
+		data["description_json"] = *doc
+	} else {
+		slog.Warn("Parse collab issue description", "issueId", st.issueId, "err", err)
+		data["description_json"] = editor.Document{}
+	}
+
+	// Updates по map вызывает BeforeSave задачи: описание очищается и пересчитывается description_stripped
+	return st.db.WithContext(ctx).
+		Model(&dao.Issue{ID: st.issueId}).
+		Select("description_html", "description_json", "updated_at", "updated_by_id").
+		Updates(data).Error
+}
+
+func (st *issueCollabStore) Commit(ctx context.Context, actor *dao.User) error {
+	var issue dao.Issue
+	if err := st.db.WithContext(ctx).
+		Joins("Workspace").
+		Joins("Project").
+		Joins("State").
+		Joins("Author").
+		Where("issues.id = ?", st.issueId).
+		First(&issue).Error; err != nil {
+		return err
+	}
+
+	oldSnapshot := tracker.IssueSnapshot{ID: issue.ID, Name: opt.Some(issue.Name), Description: opt.Some(st.committed)}
+	newSnapshot := tracker.IssueSnapshot{ID: issue.ID, Name: opt.Some(issue.Name), Description: opt.Some(issue.DescriptionHtml)}
+	if err := st.tracker.TrackChanges(types.LayerIssue, oldSnapshot, newSnapshot, &issue, actor); err != nil {
+		return err
+	}
+	st.committed = issue.DescriptionHtml
+	return nil
+}
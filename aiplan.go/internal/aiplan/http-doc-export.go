@@ -0,0 +1,161 @@
+// Экспорт документов в PDF, Markdown и DOCX.
+//
+// Документ экспортируется один или вместе со всеми доступными пользователю дочерними документами
+// как книга с оглавлением. Изображения загружаются напрямую из файлового хранилища пространства.
+package aiplan
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	apicontext "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/api-context"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/export"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// exportDoc godoc
+// @id exportDoc
+// @Summary doc: экспорт документа
+// @Description Экспортирует документ в PDF, Markdown или DOCX. С параметром children экспортируются также все доступные дочерние документы в виде книги с оглавлением
+// @Tags Docs
+// @Security ApiKeyAuth
+// @Produce application/pdf
+// @Produce text/markdown
+// @Produce application/vnd.openxmlformats-officedocument.wordprocessingml.document
+// @Param workspaceSlug path string true "Slug рабочего пространства"
+// @Param docId path string true "Id документа"
+// @Param format query string false "Формат файла: pdf, md, docx" default(pdf)
+// @Param children query bool false "Экспортировать вместе с дочерними документами" default(false)
+// @Success 200 {file} binary "Файл документа"
+// @Failure 400 {object} apierrors.DefinedError "Некорректные параметры запроса"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Доступ запрещен"
+// @Failure 404 {object} apierrors.DefinedError "Ошибка: не найдено"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/workspaces/{workspaceSlug}/doc/{docId}/export/ [get]
+func (s *Services) exportDoc(c echo.Context) error {
+	apiContext := apicontext.GetContext(c)
+	workspaceMember := apiContext.GetWorkspaceMember()
+	doc := apiContext.GetDoc(apicontext.WithDocAuthor())
+	if apiContext.Error() != nil {
+		return EError(c, apiContext.Error())
+	}
+
+	format := "pdf"
+	children := false
+	if err := echo.QueryParamsBinder(c).
+		String("format", &format).
+		Bool("children", &children).
+		BindError(); err != nil {
+		return EErrorDefined(c, apierrors.ErrDocBadRequest)
+	}
+
+	pages := []export.DocPage{{Doc: doc}}
+	if children {
+		var err error
+		pages, err = s.appendChildDocPages(s.DB(c), workspaceMember, doc.ID, 1, pages)
+		if err != nil {
+			return EError(c, err)
+		}
+	}
+
+	loadImage := s.docImageLoader(s.DB(c), doc.WorkspaceId)
+
+	var buf bytes.Buffer
+	var contentType string
+	switch strings.ToLower(format) {
+	case "pdf":
+		contentType = "application/pdf"
+		if err := export.DocsToFPDF(pages, cfg.WebURL.URL, loadImage, &buf); err != nil {
+			return EError(c, err)
+		}
+	case "md", "markdown":
+		format = "md"
+		contentType = "text/markdown; charset=utf-8"
+		if err := export.DocsToMarkdown(pages, cfg.WebURL.URL, &buf); err != nil {
+			return EError(c, err)
+		}
+	case "docx":
+		contentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+		if err := export.DocsToDOCX(pages, cfg.WebURL.URL, loadImage, &buf); err != nil {
+			return EError(c, err)
+		}
+	default:
+		return EErrorDefined(c, apierrors.ErrDocExportFormat)
+	}
+
+	c.Response().Header().Set("Content-Disposition",
+		"attachment; filename*=UTF-8''"+url.PathEscape(doc.Title+"."+strings.ToLower(format)))
+	return c.Blob(http.StatusOK, contentType, buf.Bytes())
+}
+
+// appendChildDocPages добавляет к pages доступные участнику дочерние документы parentId в порядке обхода дерева
+func (s *Services) appendChildDocPages(tx *gorm.DB, workspaceMember *dao.WorkspaceMember, parentId uuid.UUID, level int, pages []export.DocPage) ([]export.DocPage, error) {
+	var docs []dao.Doc
+	if err := visibleDocsQuery(tx, workspaceMember).
+		Preload("Author").
+		Where("docs.parent_doc_id = ?", parentId).
+		Order("seq_id ASC").
+		Find(&docs).Error; err != nil {
+		return nil, err
+	}
+
+	for i := range docs {
+		pages = append(pages, export.DocPage{Doc: &docs[i], Level: level})
+
+		var err error
+		pages, err = s.appendChildDocPages(tx, workspaceMember, docs[i].ID, level+1, pages)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return pages, nil
+}
+
+// visibleDocsQuery ограничивает выборку документами пространства, доступными участнику на чтение
+func visibleDocsQuery(tx *gorm.DB, workspaceMember *dao.WorkspaceMember) *gorm.DB {
+	q := tx.Where("docs.workspace_id = ?", workspaceMember.WorkspaceId)
+	if workspaceMember.Role != types.AdminRole {
+		q = q.Where("docs.reader_role <= ? OR docs.editor_role <= ? OR EXISTS (SELECT 1 FROM doc_access_rules dar WHERE dar.doc_id = docs.id AND dar.member_id = ?) OR docs.created_by_id = ?",
+			workspaceMember.Role, workspaceMember.Role, workspaceMember.MemberId, workspaceMember.MemberId)
+	}
+	return q
+}
+
+// docImageLoader загружает изображения документа из файлового хранилища.
+// Загружаются только файлы пространства, ссылки на внешние ресурсы не запрашиваются
+func (s *Services) docImageLoader(tx *gorm.DB, workspaceId uuid.UUID) export.ImageLoader {
+	return func(src *url.URL) (io.ReadCloser, string, error) {
+		name, ok := storageFileName(src)
+		if !ok {
+			return nil, "", errors.New("image is not stored in file storage")
+		}
+
+		q := tx.Where("workspace_id = ?", workspaceId)
+		if id, err := uuid.FromString(name); err == nil {
+			q = q.Where("id = ?", id)
+		} else {
+			q = q.Where("name = ?", name)
+		}
+
+		var asset dao.FileAsset
+		if err := q.First(&asset).Error; err != nil {
+			return nil, "", err
+		}
+
+		r, err := s.storage.LoadReader(asset.Id)
+		if err != nil {
+			return nil, "", err
+		}
+		return r, asset.ContentType, nil
+	}
+}
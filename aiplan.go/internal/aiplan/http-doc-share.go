@@ -0,0 +1,607 @@
+// Публичные ссылки на документы только для чтения.
+//
+// Редактор документа создает ссылку со случайным токеном, при необходимости с паролем, сроком действия
+// и дочерними документами. По ссылке документ открывается без авторизации: содержимое очищается политикой
+// редактора, изображения и вложения загружаются через ссылку с проверкой принадлежности документу.
+// Дочерние документы отбираются по правам создателя ссылки на момент просмотра.
+// Создание и отзыв ссылок записываются в историю документа.
+package aiplan
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	tracker "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/activity-tracker"
+	apicontext "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/api-context"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
+	policy "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/redactor-policy"
+	errStack "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/stack-error"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+	actField "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types/activities"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/utils"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/sethvargo/go-password/password"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	docShareLinkKey   = "doc_share.link"
+	docShareMemberKey = "doc_share.member"
+	docShareCookie    = "doc_share"
+)
+
+type DocShareLinkRequest struct {
+	WithChildren bool       `json:"with_children"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty" extensions:"x-nullable"`
+	Password     string     `json:"password,omitempty" validate:"max=128"`
+}
+
+type DocShareUnlockRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// AddDocShareWithoutAuthServices регистрирует публичный просмотр документов по ссылке
+func (s *Services) AddDocShareWithoutAuthServices(g *echo.Group) {
+	shareGroup := g.Group("share/docs/:token", s.DocShareLinkMiddleware)
+	shareGroup.GET("/", s.getSharedDoc)
+	shareGroup.POST("/unlock/", s.unlockSharedDoc)
+	shareGroup.GET("/docs/:docId/", s.getSharedChildDoc)
+	shareGroup.GET("/file/:fileName/", s.getSharedDocFile)
+}
+
+// DocShareLinkMiddleware проверяет ссылку: срок действия, членство создателя в пространстве, его доступ к документу и пароль.
+// Пароль подтверждается cookie, которую выдает unlockSharedDoc
+func (s *Services) DocShareLinkMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		var link dao.DocShareLink
+		if err := s.DB(c).
+			Preload("Doc").
+			Where("token = ?", c.Param("token")).
+			First(&link).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return EErrorDefined(c, apierrors.ErrDocShareLinkNotFound)
+			}
+			return EError(c, err)
+		}
+		if link.Doc == nil {
+			return EErrorDefined(c, apierrors.ErrDocShareLinkNotFound)
+		}
+		if link.Expired() {
+			return EErrorDefined(c, apierrors.ErrDocShareLinkExpired)
+		}
+
+		// Ссылка перестает работать, если создатель больше не состоит в пространстве
+		var member dao.WorkspaceMember
+		if err := s.DB(c).
+			Where("workspace_id = ?", link.WorkspaceId).
+			Where("member_id = ?", link.CreatedById).
+			First(&member).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return EErrorDefined(c, apierrors.ErrDocShareLinkNotFound)
+			}
+			return EError(c, err)
+		}
+
+		// Ссылка перестает работать, если создатель потерял доступ к документу
+		var visible int64
+		if err := visibleDocsQuery(s.DB(c), &member).
+			Model(&dao.Doc{}).
+			Where("docs.id = ?", link.DocId).
+			Count(&visible).Error; err != nil {
+			return EError(c, err)
+		}
+		if visible == 0 {
+			return EErrorDefined(c, apierrors.ErrDocShareLinkNotFound)
+		}
+
+		if link.HasPassword() && !strings.HasSuffix(c.Path(), "/unlock/") {
+			cookie, err := c.Cookie(docShareCookie)
+			if err != nil || !hmac.Equal([]byte(cookie.Value), []byte(docShareAccessKey(&link))) {
+				return EErrorDefined(c, apierrors.ErrDocSharePassword)
+			}
+		}
+
+		c.Set(docShareLinkKey, &link)
+		c.Set(docShareMemberKey, &member)
+		return next(c)
+	}
+}
+
+// docShareAccessKey возвращает значение cookie доступа к защищенной паролем ссылке.
+// При смене пароля или пересоздании ссылки ранее выданные cookie перестают действовать
+func docShareAccessKey(link *dao.DocShareLink) string {
+	mac := hmac.New(sha256.New, []byte(cfg.SecretKey))
+	mac.Write([]byte(link.Id.String()))
+	mac.Write([]byte(link.PasswordHash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func docShareBasePath(link *dao.DocShareLink) string {
+	return "/api/share/docs/" + link.Token + "/"
+}
+
+// getDocShareLinks godoc
+// @id getDocShareLinks
+// @Summary Doc: публичные ссылки документа
+// @Description Возвращает действующие публичные ссылки на документ. Доступно редакторам документа
+// @Tags Docs
+// @Security ApiKeyAuth
+// @Produce json
+// @Param workspaceSlug path string true "Slug рабочего пространства"
+// @Param docId path string true "Id документа"
+// @Success 200 {array} dto.DocShareLink "Публичные ссылки"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Доступ запрещен"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/workspaces/{workspaceSlug}/doc/{docId}/share-links/ [get]
+func (s *Services) getDocShareLinks(c echo.Context) error {
+	apiContext := apicontext.GetContext(c)
+	doc := apiContext.GetDoc()
+	if apiContext.Error() != nil {
+		return EError(c, apiContext.Error())
+	}
+
+	var links []dao.DocShareLink
+	if err := s.DB(c).
+		Preload("CreatedBy").
+		Where("doc_id = ?", doc.ID).
+		Order("created_at DESC").
+		Find(&links).Error; err != nil {
+		return EError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, utils.SliceToSlice(&links, func(l *dao.DocShareLink) dto.DocShareLink { return *l.ToDTO() }))
+}
+
+// createDocShareLink godoc
+// @id createDocShareLink
+// @Summary Doc: создание публичной ссылки
+// @Description Публикует документ по ссылке только для чтения, при необходимости с дочерними документами, паролем и сроком действия. Доступно редакторам документа
+// @Tags Docs
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param workspaceSlug path string true "Slug рабочего пространства"
+// @Param docId path string true "Id документа"
+// @Param data body DocShareLinkRequest true "Параметры ссылки"
+// @Success 201 {object} dto.DocShareLink "Созданная ссылка"
+// @Failure 400 {object} apierrors.DefinedError "Некорректные параметры запроса"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Доступ запрещен"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/workspaces/{workspaceSlug}/doc/{docId}/share-links/ [post]
+func (s *Services) createDocShareLink(c echo.Context) error {
+	apiContext := apicontext.GetContext(c)
+	user := apiContext.GetUser()
+	doc := apiContext.GetDoc()
+	if apiContext.Error() != nil {
+		return EError(c, apiContext.Error())
+	}
+
+	var req DocShareLinkRequest
+	if err := c.Bind(&req); err != nil {
+		return EErrorDefined(c, apierrors.ErrDocBadRequest)
+	}
+	if err := c.Validate(&req); err != nil {
+		return EErrorDefined(c, apierrors.ErrDocRequestValidate)
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return EErrorDefined(c, apierrors.ErrDocShareExpiresAt)
+	}
+
+	link := dao.DocShareLink{
+		Id:           dao.GenUUID(),
+		CreatedById:  user.ID,
+		DocId:        doc.ID,
+		WorkspaceId:  doc.WorkspaceId,
+		Token:        password.MustGenerate(48, 12, 0, false, true),
+		WithChildren: req.WithChildren,
+		CreatedBy:    user,
+	}
+	if req.ExpiresAt != nil {
+		link.ExpiresAt = sql.NullTime{Time: *req.ExpiresAt, Valid: true}
+	}
+	if req.Password != "" {
+		link.PasswordHash = dao.GenPasswordHash(req.Password)
+	}
+
+	if err := s.DB(c).Omit(clause.Associations).Create(&link).Error; err != nil {
+		return EError(c, err)
+	}
+
+	if err := s.snapshotTracker.TrackVerb(types.LayerDoc, actField.VerbCreated, doc, user,
+		tracker.WithField(actField.ShareLink.Field),
+		tracker.WithNewVal(link.Description()),
+		tracker.WithNewID(link.Id),
+	); err != nil {
+		errStack.GetError(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, link.ToDTO())
+}
+
+// deleteDocShareLink godoc
+// @id deleteDocShareLink
+// @Summary Doc: отзыв публичной ссылки
+// @Description Отзывает публичную ссылку на документ, документ по ней больше не открывается. Доступно редакторам документа
+// @Tags Docs
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug рабочего пространства"
+// @Param docId path string true "Id документа"
+// @Param linkId path string true "Id ссылки"
+// @Success 200 "Ссылка отозвана"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Доступ запрещен"
+// @Failure 404 {object} apierrors.DefinedError "Ссылка не найдена"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/workspaces/{workspaceSlug}/doc/{docId}/share-links/{linkId}/ [delete]
+func (s *Services) deleteDocShareLink(c echo.Context) error {
+	apiContext := apicontext.GetContext(c)
+	user := apiContext.GetUser()
+	doc := apiContext.GetDoc()
+	if apiContext.Error() != nil {
+		return EError(c, apiContext.Error())
+	}
+
+	var link dao.DocShareLink
+	if err := s.DB(c).
+		Where("doc_id = ?", doc.ID).
+		Where("id = ?", c.Param("linkId")).
+		First(&link).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return EErrorDefined(c, apierrors.ErrDocShareLinkNotFound)
+		}
+		return EError(c, err)
+	}
+
+	if err := s.DB(c).Delete(&link).Error; err != nil {
+		return EError(c, err)
+	}
+
+	if err := s.snapshotTracker.TrackVerb(types.LayerDoc, actField.VerbDeleted, doc, user,
+		tracker.WithField(actField.ShareLink.Field),
+		tracker.WithOldVal(link.Description()),
+		tracker.WithOldID(link.Id),
+	); err != nil {
+		errStack.GetError(c, err)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// getSharedDoc godoc
+// @id getSharedDoc
+// @Summary Doc: документ по публичной ссылке
+// @Description Возвращает документ, опубликованный по ссылке, и дерево дочерних документов, если они опубликованы вместе с ним
+// @Tags Docs
+// @Produce json
+// @Param token path string true "Токен ссылки"
+// @Success 200 {object} dto.SharedDoc "Документ"
+// @Failure 401 {object} apierrors.DefinedError "Необходим пароль"
+// @Failure 404 {object} apierrors.DefinedError "Ссылка не найдена или отозвана"
+// @Failure 410 {object} apierrors.DefinedError "Срок действия ссылки истек"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/share/docs/{token}/ [get]
+func (s *Services) getSharedDoc(c echo.Context) error {
+	link := c.Get(docShareLinkKey).(*dao.DocShareLink)
+	member := c.Get(docShareMemberKey).(*dao.WorkspaceMember)
+
+	resp, err := s.sharedDocDTO(s.DB(c), link, link.Doc)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	if link.WithChildren {
+		tree, err := s.sharedDocTree(s.DB(c), member, link.Doc.ID, link.Doc.Title)
+		if err != nil {
+			return EError(c, err)
+		}
+		resp.Tree = &tree
+	}
+
+	if err := s.DB(c).Model(link).UpdateColumns(map[string]any{
+		"view_count":     gorm.Expr("view_count + 1"),
+		"last_viewed_at": time.Now(),
+	}).Error; err != nil {
+		return EError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// getSharedChildDoc godoc
+// @id getSharedChildDoc
+// @Summary Doc: дочерний документ по публичной ссылке
+// @Description Возвращает дочерний документ из дерева документов, опубликованного по ссылке
+// @Tags Docs
+// @Produce json
+// @Param token path string true "Токен ссылки"
+// @Param docId path string true "Id дочернего документа"
+// @Success 200 {object} dto.SharedDoc "Документ"
+// @Failure 401 {object} apierrors.DefinedError "Необходим пароль"
+// @Failure 404 {object} apierrors.DefinedError "Документ не найден"
+// @Failure 410 {object} apierrors.DefinedError "Срок действия ссылки истек"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/share/docs/{token}/docs/{docId}/ [get]
+func (s *Services) getSharedChildDoc(c echo.Context) error {
+	link := c.Get(docShareLinkKey).(*dao.DocShareLink)
+	member := c.Get(docShareMemberKey).(*dao.WorkspaceMember)
+
+	docId, err := uuid.FromString(c.Param("docId"))
+	if err != nil {
+		return EErrorDefined(c, apierrors.ErrDocNotFound)
+	}
+
+	ids, err := s.sharedDocIds(s.DB(c), link, member)
+	if err != nil {
+		return EError(c, err)
+	}
+	if _, ok := ids[docId]; !ok {
+		return EErrorDefined(c, apierrors.ErrDocNotFound)
+	}
+
+	var doc dao.Doc
+	if err := s.DB(c).
+		Preload("Author").
+		Where("workspace_id = ?", link.WorkspaceId).
+		Where("id = ?", docId).
+		First(&doc).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return EErrorDefined(c, apierrors.ErrDocNotFound)
+		}
+		return EError(c, err)
+	}
+
+	resp, err := s.sharedDocDTO(s.DB(c), link, &doc)
+	if err != nil {
+		return EError(c, err)
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// unlockSharedDoc godoc
+// @id unlockSharedDoc
+// @Summary Doc: ввод пароля публичной ссылки
+// @Description Проверяет пароль ссылки и устанавливает cookie доступа к документу
+// @Tags Docs
+// @Accept json
+// @Param token path string true "Токен ссылки"
+// @Param data body DocShareUnlockRequest true "Пароль"
+// @Success 200 "Доступ открыт"
+// @Failure 400 {object} apierrors.DefinedError "Некорректные параметры запроса"
+// @Failure 403 {object} apierrors.DefinedError "Неверный пароль"
+// @Failure 404 {object} apierrors.DefinedError "Ссылка не найдена или отозвана"
+// @Failure 410 {object} apierrors.DefinedError "Срок действия ссылки истек"
+// @Failure 429 {object} apierrors.DefinedError "Слишком много попыток"
+// @Router /api/share/docs/{token}/unlock/ [post]
+func (s *Services) unlockSharedDoc(c echo.Context) error {
+	link := c.Get(docShareLinkKey).(*dao.DocShareLink)
+	if !link.HasPassword() {
+		return c.NoContent(http.StatusOK)
+	}
+
+	var req DocShareUnlockRequest
+	if err := c.Bind(&req); err != nil {
+		return EErrorDefined(c, apierrors.ErrDocBadRequest)
+	}
+	if err := c.Validate(&req); err != nil {
+		return EErrorDefined(c, apierrors.ErrDocRequestValidate)
+	}
+
+	if !s.docShareLimiter.CheckAndRecord(c.RealIP() + "/" + link.Token) {
+		return EErrorDefined(c, apierrors.ErrDocShareTooManyTries)
+	}
+	if !checkPassword(req.Password, link.PasswordHash) {
+		return EErrorDefined(c, apierrors.ErrDocSharePasswordWrong)
+	}
+
+	cookie := new(http.Cookie)
+	cookie.Name = docShareCookie
+	cookie.Value = docShareAccessKey(link)
+	cookie.HttpOnly = true
+	cookie.Secure = cfg.WebURL.URL.Scheme == "https"
+	cookie.Path = docShareBasePath(link)
+	cookie.SameSite = http.SameSiteLaxMode
+	cookie.Expires = time.Now().Add(24 * time.Hour)
+	if link.ExpiresAt.Valid && link.ExpiresAt.Time.Before(cookie.Expires) {
+		cookie.Expires = link.ExpiresAt.Time
+	}
+	c.SetCookie(cookie)
+
+	return c.NoContent(http.StatusOK)
+}
+
+// getSharedDocFile godoc
+// @id getSharedDocFile
+// @Summary Doc: файл документа по публичной ссылке
+// @Description Возвращает изображение или вложение документа, опубликованного по ссылке. Доступны только файлы опубликованных документов
+// @Tags Docs
+// @Produce */*
+// @Param token path string true "Токен ссылки"
+// @Param fileName path string true "Имя файла или ID файла"
+// @Success 200 "Содержимое файла"
+// @Failure 401 {object} apierrors.DefinedError "Необходим пароль"
+// @Failure 404 {object} apierrors.DefinedError "Файл не найден"
+// @Failure 410 {object} apierrors.DefinedError "Срок действия ссылки истек"
+// @Router /api/share/docs/{token}/file/{fileName}/ [get]
+func (s *Services) getSharedDocFile(c echo.Context) error {
+	link := c.Get(docShareLinkKey).(*dao.DocShareLink)
+	member := c.Get(docShareMemberKey).(*dao.WorkspaceMember)
+
+	ids, err := s.sharedDocIds(s.DB(c), link, member)
+	if err != nil {
+		return EError(c, err)
+	}
+	docIds := utils.MapToSlice(ids, func(k uuid.UUID, _ struct{}) uuid.UUID { return k })
+
+	name := c.Param("fileName")
+	q := s.DB(c).
+		Where("workspace_id = ?", link.WorkspaceId).
+		Where("doc_id IN (?) OR id IN (SELECT asset_id FROM doc_attachments WHERE doc_id IN (?))", docIds, docIds)
+	if id, err := uuid.FromString(name); err == nil {
+		q = q.Where("id = ?", id)
+	} else {
+		q = q.Where("name = ?", name)
+	}
+
+	var asset dao.FileAsset
+	if err := q.First(&asset).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+		return EError(c, err)
+	}
+
+	return s.streamAsset(c, asset)
+}
+
+// sharedDocDTO формирует документ для публичного просмотра: очищенное содержимое и вложения со ссылками на загрузку через ссылку
+func (s *Services) sharedDocDTO(tx *gorm.DB, link *dao.DocShareLink, doc *dao.Doc) (*dto.SharedDoc, error) {
+	base := cfg.WebURL.URL.ResolveReference(&url.URL{Path: docShareBasePath(link)})
+
+	var attachments []dao.DocAttachment
+	if err := tx.
+		Preload("Asset").
+		Where("doc_id = ?", doc.ID).
+		Order("created_at").
+		Find(&attachments).Error; err != nil {
+		return nil, err
+	}
+
+	resp := &dto.SharedDoc{
+		Id:          doc.ID,
+		Title:       doc.Title,
+		UpdatedAt:   doc.UpdatedAt,
+		Content:     sharedDocContent(doc.Content.Body, base),
+		Attachments: make([]dto.SharedDocAttachment, 0, len(attachments)),
+		ExpiresAt:   utils.SqlNullTimeToPointerTime(link.ExpiresAt),
+	}
+	if doc.Author != nil {
+		resp.Author = doc.Author.GetName()
+	}
+	for _, a := range attachments {
+		if a.Asset == nil {
+			continue
+		}
+		resp.Attachments = append(resp.Attachments, dto.SharedDocAttachment{
+			Name:        a.Asset.Name,
+			Size:        a.Asset.FileSize,
+			ContentType: a.Asset.ContentType,
+			Url:         types.JsonURL{URL: base.ResolveReference(&url.URL{Path: "file/" + a.Asset.Id.String() + "/"})},
+		})
+	}
+	return resp, nil
+}
+
+// sharedDocContent очищает HTML документа политикой редактора и заменяет адреса файлов из хранилища
+// на загрузку через ссылку
+func sharedDocContent(body string, base *url.URL) string {
+	sanitized := policy.UgcPolicy.Sanitize(body)
+
+	nodes, err := html.ParseFragment(strings.NewReader(sanitized), &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body})
+	if err != nil {
+		return sanitized
+	}
+
+	var rewrite func(n *html.Node)
+	rewrite = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			for i, attr := range n.Attr {
+				if (n.DataAtom == atom.Img && attr.Key == "src") || (n.DataAtom == atom.A && attr.Key == "href") {
+					if u, err := url.Parse(attr.Val); err == nil {
+						if name, ok := storageFileName(u); ok {
+							n.Attr[i].Val = base.ResolveReference(&url.URL{Path: "file/" + name + "/"}).String()
+						}
+					}
+				}
+			}
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			rewrite(child)
+		}
+	}
+
+	var sb strings.Builder
+	for _, n := range nodes {
+		rewrite(n)
+		if err := html.Render(&sb, n); err != nil {
+			return sanitized
+		}
+	}
+	return sb.String()
+}
+
+// storageFileName возвращает имя или id файла, если ссылка ведет в файловое хранилище приложения
+func storageFileName(src *url.URL) (string, bool) {
+	if (src.Host != "" && src.Host != cfg.WebURL.URL.Host) || !strings.Contains(src.Path, "/file/") {
+		return "", false
+	}
+	name := path.Base(strings.TrimSuffix(src.Path, "/"))
+	if name == "" || name == "/" || name == "." || name == "file" {
+		return "", false
+	}
+	return name, true
+}
+
+// sharedDocIds возвращает id документов, опубликованных по ссылке
+func (s *Services) sharedDocIds(tx *gorm.DB, link *dao.DocShareLink, member *dao.WorkspaceMember) (map[uuid.UUID]struct{}, error) {
+	ids := map[uuid.UUID]struct{}{link.DocId: {}}
+	if !link.WithChildren {
+		return ids, nil
+	}
+
+	tree, err := s.sharedDocTree(tx, member, link.DocId, "")
+	if err != nil {
+		return nil, err
+	}
+	var collect func(node dto.SharedDocLight)
+	collect = func(node dto.SharedDocLight) {
+		ids[node.Id] = struct{}{}
+		for _, child := range node.Children {
+			collect(child)
+		}
+	}
+	collect(tree)
+	return ids, nil
+}
+
+// sharedDocTree возвращает дерево из документа и доступных участнику дочерних документов
+func (s *Services) sharedDocTree(tx *gorm.DB, member *dao.WorkspaceMember, id uuid.UUID, title string) (dto.SharedDocLight, error) {
+	node := dto.SharedDocLight{Id: id, Title: title}
+
+	var children []struct {
+		ID    uuid.UUID
+		Title string
+	}
+	if err := visibleDocsQuery(tx, member).
+		Model(&dao.Doc{}).
+		Select("docs.id, docs.title").
+		Where("docs.parent_doc_id = ?", id).
+		Order("seq_id ASC").
+		Find(&children).Error; err != nil {
+		return node, err
+	}
+
+	for _, child := range children {
+		childNode, err := s.sharedDocTree(tx, member, child.ID, child.Title)
+		if err != nil {
+			return node, err
+		}
+		node.Children = append(node.Children, childNode)
+	}
+	return node, nil
+}
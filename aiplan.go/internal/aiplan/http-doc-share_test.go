@@ -0,0 +1,40 @@
+package aiplan
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/config"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+)
+
+func TestSharedDocContent(t *testing.T) {
+	webURL, _ := url.Parse("https://plan.example.com")
+	oldCfg := cfg
+	cfg = &config.Config{WebURL: types.JsonURL{URL: webURL}}
+	defer func() { cfg = oldCfg }()
+	base, _ := url.Parse("https://plan.example.com/api/share/docs/token/")
+
+	body := `<p>Текст<script>alert(1)</script></p>` +
+		`<img src="/api/auth/file/pic.png">` +
+		`<img src="https://plan.example.com/api/auth/file/7b5d0d3a-0c5b-4bb2-9a0e-6d0f2c4b1a11/">` +
+		`<img src="https://other.example.com/api/auth/file/pic.png">` +
+		`<a href="https://plan.example.com/api/auth/file/report.pdf">отчет</a>`
+
+	got := sharedDocContent(body, base)
+
+	if strings.Contains(got, "script") {
+		t.Errorf("Script not sanitized: %s", got)
+	}
+	for _, want := range []string{
+		`src="https://plan.example.com/api/share/docs/token/file/pic.png/"`,
+		`src="https://plan.example.com/api/share/docs/token/file/7b5d0d3a-0c5b-4bb2-9a0e-6d0f2c4b1a11/"`,
+		`src="https://other.example.com/api/auth/file/pic.png"`,
+		`href="https://plan.example.com/api/share/docs/token/file/report.pdf/"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Missing %s in %s", want, got)
+		}
+	}
+}
@@ -0,0 +1,384 @@
+// Шаблоны документов пространства.
+//
+// Шаблоны создают и изменяют администраторы пространства, использовать их могут все участники.
+// Документ создается из шаблона обычным запросом создания корневого или дочернего документа с template_id:
+// в название и содержимое шаблона подставляются переменные (дата, автор, родительский документ, связанный спринт).
+package aiplan
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	tracker "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/activity-tracker"
+	apicontext "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/api-context"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
+	errStack "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/stack-error"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+	actField "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types/activities"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/utils"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+type DocTemplateRequest struct {
+	Name        string             `json:"name" validate:"required,max=150"`
+	Description string             `json:"description,omitempty" validate:"max=500"`
+	Title       string             `json:"title,omitempty" validate:"max=150"`
+	Template    types.RedactorHTML `json:"template" swaggertype:"string"`
+}
+
+// getDocTemplateList godoc
+// @id getDocTemplateList
+// @Summary Doc (шаблоны): получение списка шаблонов документов
+// @Description Возвращает шаблоны документов пространства с пагинацией
+// @Tags Docs
+// @Security ApiKeyAuth
+// @Produce json
+// @Param workspaceSlug path string true "Slug рабочего пространства"
+// @Param offset query int false "Смещение для пагинации" default(0)
+// @Param limit query int false "Количество шаблонов на странице" default(100)
+// @Success 200 {object} dao.PaginationResponse{result=[]dto.DocTemplate} "Список шаблонов документов"
+// @Failure 400 {object} apierrors.DefinedError "Некорректные параметры запроса"
+// @Failure 403 {object} apierrors.DefinedError "Доступ запрещен"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/workspaces/{workspaceSlug}/doc-templates/ [get]
+func (s *Services) getDocTemplateList(c echo.Context) error {
+	apiContext := apicontext.GetContext(c)
+	workspace := apiContext.GetWorkspace()
+	if apiContext.Error() != nil {
+		return EError(c, apiContext.Error())
+	}
+
+	offset := 0
+	limit := 100
+	if err := echo.QueryParamsBinder(c).
+		Int("offset", &offset).
+		Int("limit", &limit).
+		BindError(); err != nil {
+		return EErrorDefined(c, apierrors.ErrDocBadRequest)
+	}
+
+	query := s.DB(c).
+		Preload("CreatedBy").
+		Preload("UpdatedBy").
+		Where("workspace_id = ?", workspace.ID).
+		Order("lower(name)")
+
+	var templates []dao.DocTemplate
+	resp, err := dao.PaginationRequest(offset, limit, query, &templates)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	resp.Result = utils.SliceToSlice(resp.Result.(*[]dao.DocTemplate), func(t *dao.DocTemplate) dto.DocTemplate {
+		return *t.ToDTO()
+	})
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// getDocTemplateVariables godoc
+// @id getDocTemplateVariables
+// @Summary Doc (шаблоны): переменные шаблонов документов
+// @Description Возвращает переменные, которые можно использовать в названии и содержимом шаблона в виде {{name}}
+// @Tags Docs
+// @Security ApiKeyAuth
+// @Produce json
+// @Param workspaceSlug path string true "Slug рабочего пространства"
+// @Success 200 {array} dto.DocTemplateVariable "Переменные шаблонов"
+// @Router /api/auth/workspaces/{workspaceSlug}/doc-templates/variables/ [get]
+func (s *Services) getDocTemplateVariables(c echo.Context) error {
+	return c.JSON(http.StatusOK, dao.DocTemplateVariables)
+}
+
+// getDocTemplate godoc
+// @id getDocTemplate
+// @Summary Doc (шаблоны): получение шаблона документа
+// @Description Возвращает шаблон документа по его ID
+// @Tags Docs
+// @Security ApiKeyAuth
+// @Produce json
+// @Param workspaceSlug path string true "Slug рабочего пространства"
+// @Param templateId path string true "ID шаблона"
+// @Success 200 {object} dto.DocTemplate "Шаблон документа"
+// @Failure 404 {object} apierrors.DefinedError "Шаблон не найден"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/workspaces/{workspaceSlug}/doc-templates/{templateId}/ [get]
+func (s *Services) getDocTemplate(c echo.Context) error {
+	apiContext := apicontext.GetContext(c)
+	workspace := apiContext.GetWorkspace()
+	if apiContext.Error() != nil {
+		return EError(c, apiContext.Error())
+	}
+
+	template, err := getDocTemplate(s.DB(c).Preload("CreatedBy").Preload("UpdatedBy"), workspace.ID, c.Param("templateId"))
+	if err != nil {
+		return EError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, template.ToDTO())
+}
+
+// createDocTemplate godoc
+// @id createDocTemplate
+// @Summary Doc (шаблоны): создание шаблона документа
+// @Description Создает шаблон документа пространства. Доступно администраторам пространства
+// @Tags Docs
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param workspaceSlug path string true "Slug рабочего пространства"
+// @Param data body DocTemplateRequest true "Данные шаблона"
+// @Success 201 {object} dto.DocTemplate "Шаблон создан"
+// @Failure 400 {object} apierrors.DefinedError "Некорректные данные шаблона"
+// @Failure 403 {object} apierrors.DefinedError "Доступ запрещен"
+// @Failure 409 {object} apierrors.DefinedError "Шаблон с таким именем уже существует"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/workspaces/{workspaceSlug}/doc-templates/ [post]
+func (s *Services) createDocTemplate(c echo.Context) error {
+	apiContext := apicontext.GetContext(c)
+	workspace := apiContext.GetWorkspace()
+	if apiContext.Error() != nil {
+		return EError(c, apiContext.Error())
+	}
+	user := apiContext.GetUser()
+
+	var req DocTemplateRequest
+	if err := c.Bind(&req); err != nil {
+		return EErrorDefined(c, apierrors.ErrDocBadRequest)
+	}
+	if err := c.Validate(&req); err != nil {
+		return EErrorDefined(c, apierrors.ErrDocRequestValidate)
+	}
+
+	template := dao.DocTemplate{
+		Id:          dao.GenUUID(),
+		CreatedAt:   time.Now(),
+		CreatedById: user.ID,
+		UpdatedAt:   time.Now(),
+		UpdatedById: user.ID,
+		WorkspaceId: workspace.ID,
+		Name:        req.Name,
+		Description: req.Description,
+		Title:       req.Title,
+		Template:    req.Template,
+		CreatedBy:   user,
+		UpdatedBy:   user,
+	}
+
+	if err := s.DB(c).Omit("CreatedBy", "UpdatedBy").Create(&template).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return EErrorDefined(c, apierrors.ErrDocTemplateDuplicated)
+		}
+		return EError(c, err)
+	}
+
+	if err := s.snapshotTracker.TrackVerb(types.LayerWorkspace, actField.VerbCreated, workspace, user,
+		tracker.WithField(actField.DocTemplate.Field),
+		tracker.WithNewVal(template.Name),
+		tracker.WithNewID(template.Id),
+	); err != nil {
+		errStack.GetError(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, template.ToDTO())
+}
+
+// updateDocTemplate godoc
+// @id updateDocTemplate
+// @Summary Doc (шаблоны): изменение шаблона документа
+// @Description Изменяет шаблон документа пространства. Доступно администраторам пространства
+// @Tags Docs
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param workspaceSlug path string true "Slug рабочего пространства"
+// @Param templateId path string true "ID шаблона"
+// @Param data body DocTemplateRequest true "Данные шаблона"
+// @Success 200 {object} dto.DocTemplate "Шаблон изменен"
+// @Failure 400 {object} apierrors.DefinedError "Некорректные данные шаблона"
+// @Failure 403 {object} apierrors.DefinedError "Доступ запрещен"
+// @Failure 404 {object} apierrors.DefinedError "Шаблон не найден"
+// @Failure 409 {object} apierrors.DefinedError "Шаблон с таким именем уже существует"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/workspaces/{workspaceSlug}/doc-templates/{templateId}/ [patch]
+func (s *Services) updateDocTemplate(c echo.Context) error {
+	apiContext := apicontext.GetContext(c)
+	workspace := apiContext.GetWorkspace()
+	if apiContext.Error() != nil {
+		return EError(c, apiContext.Error())
+	}
+	user := apiContext.GetUser()
+
+	template, err := getDocTemplate(s.DB(c).Preload("CreatedBy"), workspace.ID, c.Param("templateId"))
+	if err != nil {
+		return EError(c, err)
+	}
+
+	req := DocTemplateRequest{
+		Name:        template.Name,
+		Description: template.Description,
+		Title:       template.Title,
+		Template:    template.Template,
+	}
+	fields, err := BindData(c, "", &req)
+	if err != nil {
+		return EErrorDefined(c, apierrors.ErrDocBadRequest)
+	}
+	if err := c.Validate(&req); err != nil {
+		return EErrorDefined(c, apierrors.ErrDocRequestValidate)
+	}
+
+	oldName := template.Name
+	var updateFields []string
+	for _, field := range fields {
+		switch field {
+		case "name":
+			_ = CompareAndAddFields(&template.Name, &req.Name, field, &updateFields)
+		case "description":
+			_ = CompareAndAddFields(&template.Description, &req.Description, field, &updateFields)
+		case "title":
+			_ = CompareAndAddFields(&template.Title, &req.Title, field, &updateFields)
+		case "template":
+			if template.Template.Body != req.Template.Body {
+				template.Template = req.Template
+				updateFields = append(updateFields, field)
+			}
+		}
+	}
+
+	if len(updateFields) > 0 {
+		template.UpdatedById = user.ID
+		template.UpdatedBy = user
+		updateFields = append(updateFields, "updated_by_id", "updated_at")
+
+		if err := s.DB(c).Select(updateFields).Updates(template).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return EErrorDefined(c, apierrors.ErrDocTemplateDuplicated)
+			}
+			return EError(c, err)
+		}
+
+		if err := s.snapshotTracker.TrackVerb(types.LayerWorkspace, actField.VerbUpdated, workspace, user,
+			tracker.WithField(actField.DocTemplate.Field),
+			tracker.WithOldVal(oldName),
+			tracker.WithNewVal(template.Name),
+			tracker.WithOldID(template.Id),
+			tracker.WithNewID(template.Id),
+		); err != nil {
+			errStack.GetError(c, err)
+		}
+	}
+
+	return c.JSON(http.StatusOK, template.ToDTO())
+}
+
+// deleteDocTemplate godoc
+// @id deleteDocTemplate
+// @Summary Doc (шаблоны): удаление шаблона документа
+// @Description Удаляет шаблон документа пространства. Документы, созданные из шаблона, не изменяются. Доступно администраторам пространства
+// @Tags Docs
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug рабочего пространства"
+// @Param templateId path string true "ID шаблона"
+// @Success 200 "Шаблон удален"
+// @Failure 403 {object} apierrors.DefinedError "Доступ запрещен"
+// @Failure 404 {object} apierrors.DefinedError "Шаблон не найден"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/workspaces/{workspaceSlug}/doc-templates/{templateId}/ [delete]
+func (s *Services) deleteDocTemplate(c echo.Context) error {
+	apiContext := apicontext.GetContext(c)
+	workspace := apiContext.GetWorkspace()
+	if apiContext.Error() != nil {
+		return EError(c, apiContext.Error())
+	}
+	user := apiContext.GetUser()
+
+	template, err := getDocTemplate(s.DB(c), workspace.ID, c.Param("templateId"))
+	if err != nil {
+		return EError(c, err)
+	}
+
+	if err := s.DB(c).Delete(template).Error; err != nil {
+		return EError(c, err)
+	}
+
+	if err := s.snapshotTracker.TrackVerb(types.LayerWorkspace, actField.VerbDeleted, workspace, user,
+		tracker.WithField(actField.DocTemplate.Field),
+		tracker.WithOldVal(template.Name),
+		tracker.WithOldID(template.Id),
+	); err != nil {
+		errStack.GetError(c, err)
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+func getDocTemplate(tx *gorm.DB, workspaceId uuid.UUID, templateId string) (*dao.DocTemplate, error) {
+	id, err := uuid.FromString(templateId)
+	if err != nil {
+		return nil, apierrors.ErrDocTemplateNotFound
+	}
+
+	var template dao.DocTemplate
+	if err := tx.
+		Where("workspace_id = ?", workspaceId).
+		Where("id = ?", id).
+		First(&template).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apierrors.ErrDocTemplateNotFound
+		}
+		return nil, err
+	}
+	return &template, nil
+}
+
+// applyDocTemplate заполняет запрос создания документа из шаблона: название, если оно не задано,
+// и содержимое, если не передано ни content, ни content_markdown
+func applyDocTemplate(c echo.Context, tx *gorm.DB, req *DocRequest, fields []string, parent *dao.Doc) ([]string, error) {
+	apiContext := apicontext.GetContext(c)
+	workspace := apiContext.GetWorkspace()
+	if apiContext.Error() != nil {
+		return nil, apiContext.Error()
+	}
+
+	template, err := getDocTemplate(tx, workspace.ID, req.TemplateId.String())
+	if err != nil {
+		return nil, err
+	}
+
+	vars := dao.DocTemplateVars{
+		Now:       time.Now(),
+		Author:    apiContext.GetUser(),
+		Workspace: workspace,
+		Parent:    parent,
+	}
+	if req.SprintId != nil {
+		var sprint dao.Sprint
+		if err := tx.
+			Where("workspace_id = ?", workspace.ID).
+			Where("id = ?", *req.SprintId).
+			First(&sprint).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, apierrors.ErrSprintNotFound
+			}
+			return nil, err
+		}
+		vars.Sprint = &sprint
+	}
+
+	title, content := template.Render(vars)
+	if req.Title == "" {
+		req.Title = title
+	}
+	if !slices.Contains(fields, "content") {
+		req.Content = content
+		fields = append(fields, "content")
+	}
+	return fields, nil
+}
@@ -0,0 +1,207 @@
+// Обсуждения фрагментов документа.
+//
+// Комментарий с выделенным фрагментом (anchor) начинает обсуждение, ответы создаются с thread_id первого комментария.
+// Фрагмент хранится как индекс блока и смещения в его тексте и переносится при каждом сохранении содержимого
+// документа (см. dao.RelocateDocAnchors); если текст фрагмента удален, обсуждение помечается как отделенное от текста.
+// Обсуждение можно закрыть и открыть заново, ответ в закрытом обсуждении открывает его автоматически
+package aiplan
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	apicontext "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/api-context"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/utils"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// getDocCommentThreadList godoc
+// @id getDocCommentThreadList
+// @Summary doc: обсуждения фрагментов документа
+// @Description Возвращает обсуждения выделенных фрагментов документа с ответами в порядке расположения фрагментов в тексте. Отделенные от текста обсуждения возвращаются в конце
+// @Tags Docs
+// @Security ApiKeyAuth
+// @Produce json
+// @Param workspaceSlug path string true "Slug рабочего пространства"
+// @Param docId path string true "Id документа"
+// @Param resolved query bool false "Фильтр по состоянию: true - закрытые, false - открытые, без параметра - все"
+// @Success 200 {array} dto.DocCommentThread "обсуждения"
+// @Failure 400 {object} apierrors.DefinedError "Некорректные параметры запроса"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Доступ запрещен"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/workspaces/{workspaceSlug}/doc/{docId}/comment-threads/ [get]
+func (s *Services) getDocCommentThreadList(c echo.Context) error {
+	apiContext := apicontext.GetContext(c)
+	doc := apiContext.GetDoc()
+	if apiContext.Error() != nil {
+		return EError(c, apiContext.Error())
+	}
+
+	var resolved *bool
+	if v := c.QueryParam("resolved"); v != "" {
+		var b bool
+		if err := echo.QueryParamsBinder(c).Bool("resolved", &b).BindError(); err != nil {
+			return EErrorDefined(c, apierrors.ErrDocBadRequest)
+		}
+		resolved = &b
+	}
+
+	query := s.DB(c).
+		Joins("Actor").
+		Joins("ResolvedBy").
+		Preload("Reactions").
+		Where("doc_comments.doc_id = ?", doc.ID).
+		Where("doc_comments.anchor IS NOT NULL")
+	if resolved != nil {
+		if *resolved {
+			query = query.Where("doc_comments.resolved_at IS NOT NULL")
+		} else {
+			query = query.Where("doc_comments.resolved_at IS NULL")
+		}
+	}
+
+	var threads []dao.DocComment
+	if err := query.
+		Order("(doc_comments.anchor->>'detached')::boolean").
+		Order("(doc_comments.anchor->>'block')::int").
+		Order("(doc_comments.anchor->>'start')::int").
+		Order("doc_comments.created_at").
+		Find(&threads).Error; err != nil {
+		return EError(c, err)
+	}
+
+	resp := make([]dto.DocCommentThread, len(threads))
+	if len(threads) == 0 {
+		return c.JSON(http.StatusOK, resp)
+	}
+
+	var replies []dao.DocComment
+	if err := s.DB(c).
+		Joins("Actor").
+		Preload("Reactions").
+		Where("doc_comments.doc_id = ?", doc.ID).
+		Where("doc_comments.thread_id IN ?", utils.SliceToSlice(&threads, func(t *dao.DocComment) uuid.UUID { return t.Id })).
+		Order("doc_comments.created_at").
+		Find(&replies).Error; err != nil {
+		return EError(c, err)
+	}
+
+	byThread := make(map[uuid.UUID][]dto.DocComment, len(threads))
+	for i := range replies {
+		byThread[replies[i].ThreadId.UUID] = append(byThread[replies[i].ThreadId.UUID], *replies[i].ToDTO())
+	}
+	for i := range threads {
+		resp[i] = dto.DocCommentThread{
+			DocComment: *threads[i].ToDTO(),
+			Replies:    byThread[threads[i].Id],
+		}
+		if resp[i].Replies == nil {
+			resp[i].Replies = []dto.DocComment{}
+		}
+	}
+	return c.JSON(http.StatusOK, resp)
+}
+
+// resolveDocCommentThread godoc
+// @id resolveDocCommentThread
+// @Summary doc: закрытие обсуждения фрагмента
+// @Description Закрывает обсуждение выделенного фрагмента документа. Доступно автору обсуждения и пользователям с правом редактирования документа
+// @Tags Docs
+// @Security ApiKeyAuth
+// @Produce json
+// @Param workspaceSlug path string true "Slug рабочего пространства"
+// @Param docId path string true "Id документа"
+// @Param commentId path string true "Id первого комментария обсуждения"
+// @Success 200 {object} dto.DocComment "обсуждение"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Доступ запрещен"
+// @Failure 404 {object} apierrors.DefinedError "Обсуждение не найдено"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/workspaces/{workspaceSlug}/doc/{docId}/comments/{commentId}/resolve/ [post]
+func (s *Services) resolveDocCommentThread(c echo.Context) error {
+	return s.setDocCommentThreadResolved(c, true)
+}
+
+// reopenDocCommentThread godoc
+// @id reopenDocCommentThread
+// @Summary doc: повторное открытие обсуждения фрагмента
+// @Description Открывает закрытое обсуждение выделенного фрагмента документа. Доступно автору обсуждения и пользователям с правом редактирования документа
+// @Tags Docs
+// @Security ApiKeyAuth
+// @Produce json
+// @Param workspaceSlug path string true "Slug рабочего пространства"
+// @Param docId path string true "Id документа"
+// @Param commentId path string true "Id первого комментария обсуждения"
+// @Success 200 {object} dto.DocComment "обсуждение"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Доступ запрещен"
+// @Failure 404 {object} apierrors.DefinedError "Обсуждение не найдено"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/workspaces/{workspaceSlug}/doc/{docId}/comments/{commentId}/resolve/ [delete]
+func (s *Services) reopenDocCommentThread(c echo.Context) error {
+	return s.setDocCommentThreadResolved(c, false)
+}
+
+func (s *Services) setDocCommentThreadResolved(c echo.Context, resolved bool) error {
+	apiContext := apicontext.GetContext(c)
+	workspaceMember := apiContext.GetWorkspaceMember()
+	doc := apiContext.GetDoc()
+	if apiContext.Error() != nil {
+		return EError(c, apiContext.Error())
+	}
+	user := apiContext.GetUser()
+
+	commentId, err := uuid.FromString(c.Param("commentId"))
+	if err != nil {
+		return EErrorDefined(c, apierrors.ErrDocBadRequest)
+	}
+
+	var thread dao.DocComment
+	if err := s.DB(c).
+		Joins("Actor").
+		Preload("Reactions").
+		Where("doc_comments.doc_id = ?", doc.ID).
+		Where("doc_comments.id = ?", commentId).
+		Where("doc_comments.anchor IS NOT NULL").
+		First(&thread).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return EErrorDefined(c, apierrors.ErrDocCommentThreadNotFound)
+		}
+		return EError(c, err)
+	}
+
+	canEdit := user.ID == doc.CreatedById ||
+		user.IsSuperuser ||
+		workspaceMember.Role == types.AdminRole ||
+		hasEditAccess(user.ID, workspaceMember.Role, doc, utils.SliceToSet(doc.EditorsIDs))
+	if !canEdit && (!thread.ActorId.Valid || thread.ActorId.UUID != user.ID) {
+		return EErrorDefined(c, apierrors.ErrDocForbidden)
+	}
+
+	if thread.ResolvedAt.Valid != resolved {
+		data := map[string]interface{}{"resolved_at": nil, "resolved_by_id": nil}
+		thread.ResolvedAt, thread.ResolvedById, thread.ResolvedBy = sql.NullTime{}, uuid.NullUUID{}, nil
+		if resolved {
+			now := time.Now()
+			data = map[string]interface{}{"resolved_at": now, "resolved_by_id": user.ID}
+			thread.ResolvedAt = sql.NullTime{Time: now, Valid: true}
+			thread.ResolvedById = uuid.NullUUID{UUID: user.ID, Valid: true}
+			thread.ResolvedBy = user
+		}
+		if err := s.DB(c).Model(&dao.DocComment{}).
+			Where("id = ?", thread.Id).
+			Updates(data).Error; err != nil {
+			return EError(c, err)
+		}
+	}
+
+	return c.JSON(http.StatusOK, thread.ToDTO())
+}
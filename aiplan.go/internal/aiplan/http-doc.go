@@ -9,10 +9,14 @@ import (
 	"net/http"
 	"net/url"
 	"slices"
+	"strings"
 	"time"
 
 	apicontext "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/api-context"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/collab"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor/diff"
 	filestorage "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/file-storage"
 	errStack "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/stack-error"
 	actField "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types/activities"
@@ -41,6 +45,13 @@ func (s *Services) AddDocServices(g *echo.Group) {
 	workspaceGroup.GET("/doc/", s.getRootDocList)
 	workspaceGroup.POST("/doc/", s.createRootDoc)
 
+	workspaceGroup.GET("/doc-templates/", s.getDocTemplateList)
+	workspaceGroup.POST("/doc-templates/", s.createDocTemplate)
+	workspaceGroup.GET("/doc-templates/variables/", s.getDocTemplateVariables)
+	workspaceGroup.GET("/doc-templates/:templateId/", s.getDocTemplate)
+	workspaceGroup.PATCH("/doc-templates/:templateId/", s.updateDocTemplate)
+	workspaceGroup.DELETE("/doc-templates/:templateId/", s.deleteDocTemplate)
+
 	workspaceGroup.POST("/user-favorite-docs/", s.addDocToFavorites)
 	workspaceGroup.GET("/user-favorite-docs/", s.getFavoriteDocList)
 	workspaceGroup.DELETE("/user-favorite-docs/:docId/", s.removeDocFromFavorites)
@@ -53,8 +64,16 @@ func (s *Services) AddDocServices(g *echo.Group) {
 
 	docGroup.GET("/child/", s.getChildDocList)
 	docGroup.GET("/history/", s.getDocHistoryList)
+	docGroup.GET("/history/diff/", s.getDocHistoryDiff)
 	docGroup.GET("/history/:versionId/", s.getDocHistory)
 	docGroup.PATCH("/history/:versionId/", s.updateDocFromHistory)
+	docGroup.GET("/collab/", s.docCollab)
+	docGroup.GET("/export/", s.exportDoc)
+	docGroup.GET("/referenced-by/", s.getDocReferencedBy)
+
+	docGroup.GET("/share-links/", s.getDocShareLinks)
+	docGroup.POST("/share-links/", s.createDocShareLink)
+	docGroup.DELETE("/share-links/:linkId/", s.deleteDocShareLink)
 
 	docGroup.GET("/comments/", s.getDocCommentList)
 	docGroup.POST("/comments/", s.createDocComment)
@@ -66,6 +85,10 @@ func (s *Services) AddDocServices(g *echo.Group) {
 	docGroup.POST("/comments/:commentId/reactions/", s.addDocCommentReaction)
 	docGroup.DELETE("/comments/:commentId/reactions/:reaction/", s.removeDocCommentReaction)
 
+	docGroup.GET("/comment-threads/", s.getDocCommentThreadList)
+	docGroup.POST("/comments/:commentId/resolve/", s.resolveDocCommentThread)
+	docGroup.DELETE("/comments/:commentId/resolve/", s.reopenDocCommentThread)
+
 	docGroup.GET("/doc-attachments/", s.getDocAttachmentList)
 	docGroup.POST("/doc-attachments/", s.createDocAttachments)
 	docGroup.DELETE("/doc-attachments/:attachmentId/", s.deleteDocAttachment)
@@ -131,6 +154,7 @@ func (s *Services) getRootDocList(c echo.Context) error {
 // @Produce json
 // @Param workspaceSlug path string true "Slug рабочего пространства"
 // @Param docId path string true "Id документа"
+// @Param format query string false "markdown - дополнительно вернуть содержимое в формате Markdown (content_markdown)"
 // @Success 200 {object} dto.Doc "документ"
 // @Failure 400 {object} apierrors.DefinedError "Некорректные параметры запроса"
 // @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
@@ -144,13 +168,22 @@ func (s *Services) getDoc(c echo.Context) error {
 	if apiContext.Error() != nil {
 		return EError(c, apiContext.Error())
 	}
-	return c.JSON(http.StatusOK, doc.ToDTO())
+
+	res := doc.ToDTO()
+	if wantsMarkdown(c) {
+		md, err := dao.HTMLToMarkdown(doc.Content.Body)
+		if err != nil {
+			return EError(c, err)
+		}
+		res.ContentMarkdown = md
+	}
+	return c.JSON(http.StatusOK, res)
 }
 
 // createRootDoc godoc
 // @id createRootDoc
 // @Summary doc: добавление корневого документа
-// @Description добавление корневого документа
+// @Description добавление корневого документа. С template_id название (если не задано) и содержимое берутся из шаблона документа
 // @Tags Docs
 // @Security ApiKeyAuth
 // @Accept multipart/form-data
@@ -179,7 +212,7 @@ func (s *Services) createRootDoc(c echo.Context) error {
 		return EErrorDefined(c, apierrors.ErrDocForbidden)
 	}
 
-	doc, _, err := BindDoc(c, nil)
+	doc, _, err := BindDoc(c, s.DB(c), nil, nil)
 	if err != nil {
 		return EError(c, err)
 	}
@@ -228,7 +261,7 @@ func (s *Services) createRootDoc(c echo.Context) error {
 // createDoc godoc
 // @id createDoc
 // @Summary doc: добавление документа
-// @Description добавление документа
+// @Description добавление документа. С template_id название (если не задано) и содержимое берутся из шаблона документа
 // @Tags Docs
 // @Security ApiKeyAuth
 // @Accept multipart/form-data
@@ -255,7 +288,7 @@ func (s *Services) createDoc(c echo.Context) error {
 	parentDoc := *parentDocPtr
 	user := apiContext.GetUser()
 
-	doc, fields, err := BindDoc(c, nil)
+	doc, fields, err := BindDoc(c, s.DB(c), nil, &parentDoc)
 	if err != nil {
 		return EError(c, err)
 	}
@@ -359,12 +392,16 @@ func (s *Services) updateDoc(c echo.Context) error {
 
 	oldSnapshot := tracker.DocToSnapshot(&doc)
 
-	newDoc, fields, err := BindDoc(c, &doc)
+	newDoc, fields, err := BindDoc(c, s.DB(c), &doc, nil)
 	if err != nil {
 		return EError(c, err)
 	}
 	form, _ := c.MultipartForm()
 
+	if slices.Contains(fields, "content") && s.collabHub.HasEditors(collab.DocKey(doc.ID)) {
+		return EErrorDefined(c, apierrors.ErrDocCollabActive)
+	}
+
 	if utils.CheckInSet(utils.SliceToSet(fields), "editor_role", "reader_role", "editor_list", "reader_list", "watcher_list") {
 		if doc.CreatedById != user.ID && workspaceMember.Role != types.AdminRole {
 			return EErrorDefined(c, apierrors.ErrDocForbidden)
@@ -1065,6 +1102,7 @@ func (s *Services) getDocCommentList(c echo.Context) error {
 		Joins("Actor").
 		Joins("OriginalComment").
 		Joins("OriginalComment.Actor").
+		Joins("ResolvedBy").
 		Preload("Reactions").
 		Where("doc_comments.workspace_id = ?", workspace.ID).
 		Where("doc_comments.doc_id = ?", currentDoc.ID).
@@ -1100,7 +1138,7 @@ func (s *Services) getDocCommentList(c echo.Context) error {
 // @Produce json
 // @Param workspaceSlug path string true "Slug рабочего пространства"
 // @Param docId path string true "Id документа"
-// @Param comment formData string true "комментарий в формате JSON" example({"comment_html": "<p>HTML-контент</p>", "reply_to_comment_id": null})
+// @Param comment formData string true "комментарий в формате JSON. anchor начинает обсуждение выделенного фрагмента, thread_id добавляет ответ в обсуждение" example({"comment_html": "<p>HTML-контент</p>", "reply_to_comment_id": null, "anchor": {"block": 2, "start": 10, "text": "фрагмент"}, "thread_id": null})
 // @Param files formData file false "Вложения для документа"
 // @Success 200 {object} dto.DocComment "комментарий"
 // @Failure 400 {object} apierrors.DefinedError "Некорректные параметры запроса"
@@ -1152,6 +1190,36 @@ func (s *Services) createDocComment(c echo.Context) error {
 			}
 		}
 
+		if comment.Anchor != nil {
+			authorId, err := dao.DocTextAuthor(tx, doc, comment.Anchor.Text)
+			if err != nil {
+				return err
+			}
+			comment.AnchorAuthorId = uuid.NullUUID{UUID: authorId, Valid: true}
+		}
+
+		if comment.ThreadId.Valid {
+			var thread dao.DocComment
+			if err := tx.
+				Where("doc_id = ?", doc.ID).
+				Where("id = ?", comment.ThreadId.UUID).
+				Where("anchor IS NOT NULL").
+				First(&thread).Error; err != nil {
+				if err == gorm.ErrRecordNotFound {
+					return apierrors.ErrDocCommentThreadNotFound
+				}
+				return err
+			}
+			// Ответ в закрытом обсуждении открывает его заново
+			if thread.ResolvedAt.Valid {
+				if err := tx.Model(&thread).
+					Select("resolved_at", "resolved_by_id").
+					Updates(map[string]interface{}{"resolved_at": nil, "resolved_by_id": nil}).Error; err != nil {
+					return err
+				}
+			}
+		}
+
 		if err := tx.Omit(clause.Associations).Create(&comment).Error; err != nil {
 			return err
 		}
@@ -1221,6 +1289,7 @@ func (s *Services) getDocComment(c echo.Context) error {
 		Joins("Actor").
 		Joins("OriginalComment").
 		Joins("OriginalComment.Actor").
+		Joins("ResolvedBy").
 		Preload("Reactions").
 		Where("doc_comments.workspace_id = ?", workspace.ID).
 		Where("doc_comments.doc_id = ?", docId).
@@ -2113,6 +2182,97 @@ func (s *Services) getDocHistory(c echo.Context) error {
 	return c.JSON(http.StatusOK, resp)
 }
 
+// getDocHistoryDiff godoc
+// @id getDocHistoryDiff
+// @Summary Doc: Сравнение версий документа
+// @Description Возвращает добавленные, удаленные и измененные блоки между двумя версиями документа или версией и текущим содержимым. Для измененных блоков возвращаются пословные изменения текста
+// @Tags Docs
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param workspaceSlug path string true "Slug рабочего пространства"
+// @Param docId path string true "Id документа"
+// @Param from query string true "Id исходной версии"
+// @Param to query string false "Id версии для сравнения, по умолчанию текущая"
+// @Success 200 {object} dto.HistoryDiff "изменения между версиями"
+// @Failure 400 {object} apierrors.DefinedError "Некорректные параметры запроса"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Доступ запрещен"
+// @Failure 404 {object} apierrors.DefinedError "Версия не найдена"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/workspaces/{workspaceSlug}/doc/{docId}/history/diff/ [get]
+func (s *Services) getDocHistoryDiff(c echo.Context) error {
+	apiContext := apicontext.GetContext(c)
+	doc := apiContext.GetDoc()
+	if apiContext.Error() != nil {
+		return EError(c, apiContext.Error())
+	}
+
+	var fromId, toId uuid.UUID
+	if err := echo.QueryParamsBinder(c).
+		MustTextUnmarshaler("from", &fromId).
+		TextUnmarshaler("to", &toId).
+		BindError(); err != nil {
+		return EErrorDefined(c, apierrors.ErrDocBadRequest)
+	}
+
+	from, err := s.getDocVersion(s.DB(c), doc, fromId)
+	if err != nil {
+		return EError(c, err)
+	}
+	resp := dto.HistoryDiff{From: from.ToHistoryLightDTO()}
+	oldBody := from.OldValue
+
+	newBody := doc.Content.Body
+	if !toId.IsNil() {
+		to, err := s.getDocVersion(s.DB(c), doc, toId)
+		if err != nil {
+			return EError(c, err)
+		}
+		resp.To = to.ToHistoryLightDTO()
+		newBody = to.OldValue
+	}
+
+	d, err := compareBodies(oldBody, newBody)
+	if err != nil {
+		return EError(c, err)
+	}
+	resp.Blocks = d.Blocks
+	resp.Stats = d.Stats
+	return c.JSON(http.StatusOK, resp)
+}
+
+// compareBodies сравнивает две версии текста в формате редактора
+func compareBodies(oldBody, newBody string) (*diff.Diff, error) {
+	oldDoc, err := editor.ParseDocument(strings.NewReader(oldBody))
+	if err != nil {
+		return nil, err
+	}
+	newDoc, err := editor.ParseDocument(strings.NewReader(newBody))
+	if err != nil {
+		return nil, err
+	}
+	return diff.Compare(oldDoc, newDoc), nil
+}
+
+// getDocVersion возвращает версию документа - событие изменения содержимого, OldValue которого хранит текст версии
+func (s *Services) getDocVersion(tx *gorm.DB, doc *dao.Doc, versionId uuid.UUID) (*dao.ActivityEvent, error) {
+	var activity dao.ActivityEvent
+	if err := tx.
+		Joins("Actor").
+		Where("activity_events.workspace_id = ?", doc.WorkspaceId).
+		Where("activity_events.doc_id = ?", doc.ID).
+		Where("activity_events.field = ?", "description").
+		Where("activity_events.id = ?", versionId).
+		First(&activity).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apierrors.ErrDocVersionNotFound
+		}
+		return nil, err
+	}
+	return &activity, nil
+}
+
 // updateDocFromHistory godoc
 // @id updateDocFromHistory
 // @Summary Doc: Откат старой версии документа
@@ -2140,6 +2300,10 @@ func (s *Services) updateDocFromHistory(c echo.Context) error {
 	user := apiContext.GetUser()
 	versionId := c.Param("versionId")
 
+	if s.collabHub.HasEditors(collab.DocKey(doc.ID)) {
+		return EErrorDefined(c, apierrors.ErrDocCollabActive)
+	}
+
 	oldSnapshot := tracker.DocToSnapshot(&doc)
 
 	var activity dao.ActivityEvent
@@ -2183,11 +2347,31 @@ type DocRequest struct {
 	EditorList  []uuid.UUID        `json:"editor_list,omitempty"`
 	ReaderList  []uuid.UUID        `json:"reader_list,omitempty"`
 	WatcherList []uuid.UUID        `json:"watcher_list,omitempty"`
+
+	// Содержимое в формате Markdown, заменяет content
+	ContentMarkdown *string `json:"content_markdown,omitempty" example:"# Заголовок"`
+
+	// Шаблон, из которого создается документ, и спринт для переменных шаблона. Только при создании
+	TemplateId *uuid.UUID `json:"template_id,omitempty" extensions:"x-nullable"`
+	SprintId   *uuid.UUID `json:"sprint_id,omitempty" extensions:"x-nullable"`
 }
 
 type DocCommentRequest struct {
 	CommentHtml    types.RedactorHTML `json:"comment_html" swaggertype:"string" example:"<p>HTML-контент</p>"`
 	ReplyToComment uuid.NullUUID      `json:"reply_to_comment_id,omitempty"`
+
+	// Выделенный фрагмент документа, начинает новое обсуждение. Учитывается только при создании
+	Anchor *DocCommentAnchorRequest `json:"anchor,omitempty" extensions:"x-nullable"`
+	// Первый комментарий обсуждения, в которое добавляется ответ. Учитывается только при создании
+	ThreadId uuid.NullUUID `json:"thread_id,omitempty"`
+}
+
+// DocCommentAnchorRequest - выделенный фрагмент документа: индекс блока верхнего уровня, выделенный текст
+// и смещение начала выделения в тексте блока. Смещение используется для выбора вхождения, если текст встречается в блоке несколько раз
+type DocCommentAnchorRequest struct {
+	Block int    `json:"block"`
+	Start int    `json:"start"`
+	Text  string `json:"text"`
 }
 
 type DocMoveParams struct {
@@ -2200,7 +2384,9 @@ type AddDocToFavoritesRequest struct {
 	DocID uuid.UUID `json:"doc" validate:"required"`
 }
 
-func BindDoc(c echo.Context, doc *dao.Doc) (*dao.Doc, []string, error) {
+// BindDoc разбирает запрос создания (doc == nil) или изменения документа. parent - родительский документ
+// создаваемого документа, используется в переменных шаблона
+func BindDoc(c echo.Context, tx *gorm.DB, doc *dao.Doc, parent *dao.Doc) (*dao.Doc, []string, error) {
 	var req DocRequest
 	fields, err := BindData(c, "doc", &req)
 	if err != nil {
@@ -2210,6 +2396,30 @@ func BindDoc(c echo.Context, doc *dao.Doc) (*dao.Doc, []string, error) {
 	if doc != nil && req.Title == "" {
 		req.Title = doc.Title
 	}
+
+	if req.ContentMarkdown != nil {
+		apiContext := apicontext.GetContext(c)
+		workspace := apiContext.GetWorkspace()
+		if apiContext.Error() != nil {
+			return nil, nil, apiContext.Error()
+		}
+		body, _, err := dao.MarkdownToHTML(tx, workspace, *req.ContentMarkdown)
+		if err != nil {
+			return nil, nil, apierrors.ErrDocBadRequest
+		}
+		req.Content = types.RedactorHTML{Body: body}
+		if !slices.Contains(fields, "content") {
+			fields = append(fields, "content")
+		}
+	}
+
+	if doc == nil && req.TemplateId != nil {
+		fields, err = applyDocTemplate(c, tx, &req, fields, parent)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
 	if err := c.Validate(&req); err != nil {
 		return nil, nil, apierrors.ErrDocRequestValidate
 	}
@@ -2305,11 +2515,27 @@ func BindDocComment(c echo.Context, comment *dao.DocComment) (*dao.DocComment, [
 			Actor:            apiContext.GetUser(),
 			CommentHtml:      req.CommentHtml,
 			ReplyToCommentId: req.ReplyToComment,
+			ThreadId:         req.ThreadId,
 			CommentType:      1,
 			Attachments:      make([]dao.FileAsset, 0),
 		}
 		commentCreate.CommentStripped = commentCreate.CommentHtml.StripTags()
 
+		if req.Anchor != nil {
+			if req.ThreadId.Valid {
+				return nil, nil, apierrors.ErrDocCommentBadRequest
+			}
+			content, err := editor.ParseDocument(strings.NewReader(doc.Content.Body))
+			if err != nil {
+				return nil, nil, err
+			}
+			anchor, ok := diff.NewAnchor(content, req.Anchor.Block, req.Anchor.Start, req.Anchor.Text)
+			if !ok {
+				return nil, nil, apierrors.ErrDocCommentAnchorNotFound
+			}
+			commentCreate.Anchor = &dao.DocCommentAnchor{Anchor: anchor}
+		}
+
 		return commentCreate, fields, nil
 	} else {
 		var resFields []string
@@ -0,0 +1,382 @@
+// Сводка и выгрузка ответов на форму.
+//
+// Сводка считается по полям формы: для select, multiselect и checkbox - количество и доля ответов с каждым вариантом,
+// для numeric - минимум, максимум и среднее, а также доля ответивших среди тех, кому поле показывалось с учетом разделов
+// и depend_on.
+// Выгрузка в CSV или XLSX содержит по столбцу на поле, вложения выгружаются ссылками на файлы
+package aiplan
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	apicontext "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/api-context"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/export"
+	types2 "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// formAnswersBatch - размер пачки ответов при обходе всех ответов формы
+const formAnswersBatch = 500
+
+// getAnswersStats godoc
+// @id getAnswersStats
+// @Summary ответы: Сводка ответов
+// @Description Возвращает сводку ответов на форму по полям и количество ответов по дням
+// @Tags Forms
+// @Produce json
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug рабочего пространства"
+// @Param formSlug path string true "Slug формы"
+// @Success 200 {object} dto.FormAnswerStats "Сводка ответов"
+// @Failure 403 {object} apierrors.DefinedError "Ошибка: доступ запрещен"
+// @Failure 404 {object} apierrors.DefinedError "Форма не найдена"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/workspaces/{workspaceSlug}/forms/{formSlug}/answers/stats/ [get]
+func (s *Services) getAnswersStats(c echo.Context) error {
+	apiContext := apicontext.GetContext(c)
+	user := apiContext.GetUser()
+	form := apiContext.GetForm(apicontext.WithFormAll())
+	if apiContext.Error() != nil {
+		return EError(c, apiContext.Error())
+	}
+
+	stats := newFormStats(form, (*time.Location)(&user.UserTimezone))
+	if err := forEachFormAnswer(s.DB(c), form, false, stats.add); err != nil {
+		return EError(c, err)
+	}
+	return c.JSON(http.StatusOK, stats.result())
+}
+
+// exportAnswers godoc
+// @id exportAnswers
+// @Summary ответы: Выгрузка ответов
+// @Description Выгружает все ответы на форму в CSV или XLSX. Каждому полю формы соответствует столбец, поля пропущенных разделов и скрытые условием depend_on остаются пустыми, вложения выгружаются ссылками
+// @Tags Forms
+// @Security ApiKeyAuth
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param workspaceSlug path string true "Slug рабочего пространства"
+// @Param formSlug path string true "Slug формы"
+// @Param format query string false "Формат файла: csv, xlsx" default(xlsx)
+// @Success 200 {file} binary "Файл с ответами"
+// @Failure 400 {object} apierrors.DefinedError "Некорректные параметры запроса"
+// @Failure 403 {object} apierrors.DefinedError "Ошибка: доступ запрещен"
+// @Failure 404 {object} apierrors.DefinedError "Форма не найдена"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/workspaces/{workspaceSlug}/forms/{formSlug}/answers/export/ [get]
+func (s *Services) exportAnswers(c echo.Context) error {
+	apiContext := apicontext.GetContext(c)
+	user := apiContext.GetUser()
+	form := apiContext.GetForm(apicontext.WithFormAll())
+	if apiContext.Error() != nil {
+		return EError(c, apiContext.Error())
+	}
+
+	format := "xlsx"
+	if err := echo.QueryParamsBinder(c).
+		String("format", &format).
+		BindError(); err != nil {
+		return EErrorDefined(c, apierrors.ErrFormBadRequest)
+	}
+	format = strings.ToLower(format)
+	if format != "csv" && format != "xlsx" {
+		return EErrorDefined(c, apierrors.ErrFormExportFormat)
+	}
+
+	loc := (*time.Location)(&user.UserTimezone)
+	table := export.Table{Header: []string{"№", "Дата", "Пользователь", "Email"}}
+	for _, field := range form.Fields {
+		table.Header = append(table.Header, field.Label)
+	}
+	if err := forEachFormAnswer(s.DB(c), form, true, func(answer *dao.FormAnswer) {
+		table.Rows = append(table.Rows, formAnswerRow(form, answer, loc))
+	}); err != nil {
+		return EError(c, err)
+	}
+
+	var buf bytes.Buffer
+	var contentType string
+	switch format {
+	case "csv":
+		contentType = "text/csv; charset=utf-8"
+		if err := table.WriteCSV(&buf); err != nil {
+			return EError(c, err)
+		}
+	case "xlsx":
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+		if err := table.WriteXLSX(form.Title, &buf); err != nil {
+			return EError(c, err)
+		}
+	}
+
+	c.Response().Header().Set("Content-Disposition",
+		"attachment; filename*=UTF-8''"+url.PathEscape(form.Title+"."+format))
+	return c.Blob(http.StatusOK, contentType, buf.Bytes())
+}
+
+// forEachFormAnswer обходит все ответы на форму в порядке номеров пачками по formAnswersBatch.
+// С details загружаются авторы и вложения ответов
+func forEachFormAnswer(tx *gorm.DB, form *dao.Form, details bool, fn func(answer *dao.FormAnswer)) error {
+	lastSeq := -1
+	for {
+		query := tx.Where("form_answers.form_id = ?", form.ID).
+			Where("form_answers.seq_id > ?", lastSeq).
+			Order("form_answers.seq_id").
+			Limit(formAnswersBatch)
+		if details {
+			query = query.Joins("Responder").Preload("Attachments.Asset")
+		}
+
+		var answers []dao.FormAnswer
+		if err := query.Find(&answers).Error; err != nil {
+			return err
+		}
+		for i := range answers {
+			fn(&answers[i])
+		}
+		if len(answers) < formAnswersBatch {
+			return nil
+		}
+		lastSeq = answers[len(answers)-1].SeqId
+	}
+}
+
+// formFieldShown проверяет, показывалось ли поле idx при заполнении ответа, с учетом заполненных разделов visited
+// и всей цепочки depend_on
+func formFieldShown(fields types2.FormFieldsSlice, answer types2.FormFieldsSlice, visited []bool, idx int) bool {
+	if section := fields[idx].Section; section < 0 || section >= len(visited) || !visited[section] {
+		return false
+	}
+	dep := fields[idx].DependOn
+	if dep == nil {
+		return true
+	}
+	if dep.FieldIndex < 0 || dep.FieldIndex >= idx || dep.FieldIndex >= len(answer) {
+		return false
+	}
+	return formFieldShown(fields, answer, visited, dep.FieldIndex) &&
+		isParentCondition(answer[dep.FieldIndex], fields[dep.FieldIndex], dep)
+}
+
+// formFieldAnswered проверяет, что в ответе на поле есть значение
+func formFieldAnswered(val interface{}) bool {
+	switch v := val.(type) {
+	case nil:
+		return false
+	case string:
+		return strings.TrimSpace(v) != ""
+	case []interface{}:
+		return len(v) > 0
+	}
+	return true
+}
+
+// formAnswerRow формирует строку выгрузки ответа
+func formAnswerRow(form *dao.Form, answer *dao.FormAnswer, loc *time.Location) []any {
+	row := []any{answer.SeqId, answer.CreatedAt.In(loc), nil, nil}
+	if answer.Responder != nil {
+		row[2] = answer.Responder.GetName()
+		row[3] = answer.Responder.Email
+	}
+
+	visited := formVisitedSections(form, answer.Fields)
+	for i, field := range form.Fields {
+		if i >= len(answer.Fields) || !formFieldShown(form.Fields, answer.Fields, visited, i) {
+			row = append(row, nil)
+			continue
+		}
+		val := answer.Fields[i].Val
+		if val == nil {
+			row = append(row, nil)
+			continue
+		}
+
+		switch field.Type {
+		case formFieldNumeric:
+			row = append(row, val)
+		case formFieldCheckbox:
+			v, _ := val.(bool)
+			row = append(row, v)
+		case formFieldDate:
+			if ms, ok := val.(float64); ok {
+				row = append(row, time.UnixMilli(int64(ms)).In(loc).Format("02.01.2006"))
+			} else {
+				row = append(row, fmt.Sprint(val))
+			}
+		case formFieldMultiselect:
+			var values []string
+			if list, ok := val.([]interface{}); ok {
+				for _, v := range list {
+					values = append(values, fmt.Sprint(v))
+				}
+			}
+			row = append(row, strings.Join(values, "; "))
+		case formFieldAttachment:
+			row = append(row, formAttachmentLink(answer, fmt.Sprint(val)))
+		default:
+			row = append(row, fmt.Sprint(val))
+		}
+	}
+	return row
+}
+
+// formAttachmentLink возвращает ссылку на файл вложения ответа
+func formAttachmentLink(answer *dao.FormAnswer, attachmentId string) any {
+	for _, attachment := range answer.Attachments {
+		if attachment.Id.String() != attachmentId || attachment.Asset == nil {
+			continue
+		}
+		return export.TableLink{
+			URL:  cfg.WebURL.URL.ResolveReference(&url.URL{Path: "/api/auth/file/" + attachment.Asset.Id.String()}).String(),
+			Text: attachment.Asset.Name,
+		}
+	}
+	return attachmentId
+}
+
+// formStats накапливает сводку ответов на форму
+type formStats struct {
+	form   *dao.Form
+	loc    *time.Location
+	total  int
+	fields []dto.FormFieldStats
+	sums   []float64
+	counts []map[string]int
+	days   map[string]int
+}
+
+func newFormStats(form *dao.Form, loc *time.Location) *formStats {
+	s := &formStats{
+		form:   form,
+		loc:    loc,
+		fields: make([]dto.FormFieldStats, len(form.Fields)),
+		sums:   make([]float64, len(form.Fields)),
+		counts: make([]map[string]int, len(form.Fields)),
+		days:   make(map[string]int),
+	}
+	for i, field := range form.Fields {
+		s.fields[i] = dto.FormFieldStats{Index: i, Label: field.Label, Type: field.Type}
+		s.counts[i] = make(map[string]int)
+	}
+	return s
+}
+
+func (s *formStats) add(answer *dao.FormAnswer) {
+	s.total++
+	s.days[answer.CreatedAt.In(s.loc).Format("2006-01-02")]++
+
+	visited := formVisitedSections(s.form, answer.Fields)
+	for i, field := range s.form.Fields {
+		if i >= len(answer.Fields) || !formFieldShown(s.form.Fields, answer.Fields, visited, i) {
+			continue
+		}
+		st := &s.fields[i]
+		st.Shown++
+		val := answer.Fields[i].Val
+		if !formFieldAnswered(val) {
+			continue
+		}
+		st.Answered++
+
+		switch field.Type {
+		case formFieldSelect:
+			s.counts[i][fmt.Sprint(val)]++
+		case formFieldMultiselect:
+			if list, ok := val.([]interface{}); ok {
+				for _, v := range list {
+					s.counts[i][fmt.Sprint(v)]++
+				}
+			}
+		case formFieldCheckbox:
+			s.counts[i][fmt.Sprint(val)]++
+		case formFieldNumeric:
+			v, ok := val.(float64)
+			if !ok {
+				continue
+			}
+			s.sums[i] += v
+			if st.Min == nil || v < *st.Min {
+				st.Min = &v
+			}
+			if st.Max == nil || v > *st.Max {
+				st.Max = &v
+			}
+		}
+	}
+}
+
+func (s *formStats) result() dto.FormAnswerStats {
+	res := dto.FormAnswerStats{Total: s.total, Fields: s.fields, Timeline: []dto.FormAnswersPerDay{}}
+
+	for i, field := range s.form.Fields {
+		st := &res.Fields[i]
+		st.ResponseRate = percent(st.Answered, st.Shown)
+
+		var values []string
+		switch field.Type {
+		case formFieldSelect, formFieldMultiselect:
+			if field.Validate != nil {
+				for _, opt := range field.Validate.Opt {
+					values = append(values, fmt.Sprint(opt))
+				}
+			}
+		case formFieldCheckbox:
+			values = []string{"true", "false"}
+		case formFieldNumeric:
+			if st.Min != nil {
+				avg := math.Round(s.sums[i]/float64(st.Answered)*100) / 100
+				st.Avg = &avg
+			}
+			continue
+		default:
+			continue
+		}
+
+		// варианты, удаленные из формы после получения ответов, выводятся после актуальных
+		known := make(map[string]struct{}, len(values))
+		for _, v := range values {
+			known[v] = struct{}{}
+		}
+		actual := len(values)
+		for v := range s.counts[i] {
+			if _, ok := known[v]; !ok {
+				values = append(values, v)
+			}
+		}
+		slices.Sort(values[actual:])
+
+		st.Options = make([]dto.FormOptionStats, 0, len(values))
+		for _, v := range values {
+			st.Options = append(st.Options, dto.FormOptionStats{
+				Value:   v,
+				Count:   s.counts[i][v],
+				Percent: percent(s.counts[i][v], st.Answered),
+			})
+		}
+	}
+
+	for day, count := range s.days {
+		res.Timeline = append(res.Timeline, dto.FormAnswersPerDay{Date: day, Count: count})
+	}
+	slices.SortFunc(res.Timeline, func(a, b dto.FormAnswersPerDay) int { return strings.Compare(a.Date, b.Date) })
+	return res
+}
+
+// percent возвращает долю part от total в процентах с точностью до сотых
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(total)*10000) / 100
+}
@@ -0,0 +1,363 @@
+// Изменение ответов на форму и добавление ответов к существующим задачам.
+//
+// В форме с allow_edit аутентифицированный пользователь может изменять свои ответы, пока форма открыта.
+// Изменения записываются в activity формы, а в задачу, созданную по ответу, добавляется комментарий с новым ответом.
+// Если в форме есть поле с issue_field.attribute = issue, ответ добавляется комментарием к указанной в поле задаче
+// проекта формы вместо создания новой. Значение поля - подписанная ссылка на задачу (getFormIssueLink),
+// поэтому отправитель не может подставить другую задачу проекта
+package aiplan
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tracker "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/activity-tracker"
+	apicontext "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/api-context"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/business"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
+	errStack "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/stack-error"
+	types2 "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types/activities"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/utils"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// getMyAnswers godoc
+// @id getMyAnswers
+// @Summary ответы: Мои ответы
+// @Description Возвращает ответы текущего пользователя на форму, начиная с последнего
+// @Tags Forms
+// @Produce json
+// @Security ApiKeyAuth
+// @Param formSlug path string true "Slug формы"
+// @Success 200 {array} dto.FormAnswer "Ответы пользователя"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 404 {object} apierrors.DefinedError "Форма не найдена"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/forms/{formSlug}/my-answers/ [get]
+func (s *Services) getMyAnswers(c echo.Context) error {
+	apiContext := apicontext.GetContext(c)
+	user := apiContext.GetUser()
+	form := apiContext.GetForm()
+	if apiContext.Error() != nil {
+		return EError(c, apiContext.Error())
+	}
+
+	var answers []dao.FormAnswer
+	if err := s.DB(c).
+		Preload("Attachments.Asset").
+		Where("form_id = ?", form.ID).
+		Where("created_by_id = ?", user.ID).
+		Order("seq_id DESC").
+		Find(&answers).Error; err != nil {
+		return EError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, utils.SliceToSlice(&answers, func(fa *dao.FormAnswer) dto.FormAnswer { return *fa.ToDTO() }))
+}
+
+// updateMyAnswer godoc
+// @id updateMyAnswer
+// @Summary ответы: Изменить свой ответ
+// @Description Изменяет ответ текущего пользователя на форму, если форма разрешает изменение ответов и еще открыта. В задачу, связанную с ответом, добавляется комментарий с измененным ответом
+// @Tags Forms
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param formSlug path string true "Slug формы"
+// @Param answerSeq path int true "Порядковый номер ответа"
+// @Param answer body []dto.RequestAnswer true "Ответы на поля"
+// @Success 200 {object} dto.FormAnswer "Измененный ответ"
+// @Failure 400 {object} apierrors.DefinedError "Ошибка валидации ответа"
+// @Failure 403 {object} apierrors.DefinedError "Изменение ответов запрещено"
+// @Failure 404 {object} apierrors.DefinedError "Ответ не найден"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/forms/{formSlug}/my-answers/{answerSeq}/ [patch]
+func (s *Services) updateMyAnswer(c echo.Context) error {
+	apiContext := apicontext.GetContext(c)
+	user := apiContext.GetUser()
+	form := apiContext.GetForm(apicontext.WithFormAll())
+	if apiContext.Error() != nil {
+		return EError(c, apiContext.Error())
+	}
+
+	if !form.AllowEdit {
+		return EErrorDefined(c, apierrors.ErrFormAnswerEditForbidden)
+	}
+	if !form.Active {
+		return EErrorDefined(c, apierrors.ErrFormAnswerEnd)
+	}
+
+	answerSeq, err := strconv.Atoi(strings.TrimSuffix(c.Param("answerSeq"), "/"))
+	if err != nil {
+		return EErrorDefined(c, apierrors.ErrFormBadRequest)
+	}
+
+	var answer dao.FormAnswer
+	if err := s.DB(c).
+		Preload("Attachments.Asset").
+		Where("form_id = ?", form.ID).
+		Where("seq_id = ?", answerSeq).
+		Where("created_by_id = ?", user.ID).
+		First(&answer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return EErrorDefined(c, apierrors.ErrFormAnswerNotFound)
+		}
+		return EError(c, err)
+	}
+
+	var req reqFormAnswer
+	if err := c.Bind(&req); err != nil {
+		return EErrorDefined(c, apierrors.ErrFormBadRequest)
+	}
+	if len(form.Fields) != len(req.Fields) {
+		return EErrorDefined(c, apierrors.ErrLenAnswers)
+	}
+
+	resultAnswers, err := formAnswer(req.Fields, form)
+	if err != nil {
+		if errors.Is(err, apierrors.ErrFormAnswerDependOn) {
+			return EErrorDefined(c, apierrors.ErrFormDependOn)
+		}
+		return EErrorDefined(c, apierrors.ErrFormCheckAnswers)
+	}
+	if len(resultAnswers) == 0 {
+		return EErrorDefined(c, apierrors.ErrFormEmptyAnswers)
+	}
+
+	var attachmentUUIDs []string
+	for _, field := range resultAnswers {
+		if field.Type == formFieldAttachment && field.Val != nil {
+			attachmentUUIDs = append(attachmentUUIDs, fmt.Sprint(field.Val))
+		}
+	}
+
+	oldSnap := tracker.FormAnswerToSnapshot(&answer)
+
+	now := time.Now()
+	answer.Fields = resultAnswers
+	answer.EditedAt = &now
+	if err := s.DB(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("fields", "edited_at").Updates(&answer).Error; err != nil {
+			return err
+		}
+
+		// вложения, удаленные из ответа, освобождаются, новые привязываются к ответу
+		unlink := tx.Model(&dao.FormAttachment{}).Where("answer_id = ?", answer.ID)
+		if len(attachmentUUIDs) > 0 {
+			unlink = unlink.Where("id NOT IN ?", attachmentUUIDs)
+		}
+		if err := unlink.Update("answer_id", nil).Error; err != nil {
+			return err
+		}
+		if len(attachmentUUIDs) > 0 {
+			if err := tx.Model(&dao.FormAttachment{}).
+				Where("form_id = ?", form.ID).
+				Where("id IN ?", attachmentUUIDs).
+				Where("answer_id IS NULL").
+				Update("answer_id", answer.ID).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Preload("Asset").Where("answer_id = ?", answer.ID).Find(&answer.Attachments).Error
+	}); err != nil {
+		return EError(c, err)
+	}
+
+	newSnap := tracker.FormAnswerToSnapshot(&answer)
+	if err := s.snapshotTracker.TrackChanges(types2.LayerForm, &oldSnap, &newSnap, &answer, user); err != nil {
+		errStack.GetError(c, err)
+	}
+
+	if answer.IssueId.Valid {
+		go func(form *dao.Form, answer dao.FormAnswer, user *dao.User) {
+			var issue dao.Issue
+			if err := s.RawDB().Where("id = ?", answer.IssueId.UUID).First(&issue).Error; err != nil {
+				slog.Error("Get form answer issue", "answerId", answer.ID, "err", err)
+				return
+			}
+			if err := s.appendAnswerToIssue(form, &answer, &issue, user, true); err != nil {
+				slog.Error("Append edited answer to issue", "answerId", answer.ID, "err", err)
+			}
+		}(form, answer, user)
+	}
+
+	answer.Responder = user
+	return c.JSON(http.StatusOK, answer.ToDTO())
+}
+
+// formIssueRef возвращает подписанную ссылку на задачу для поля формы с атрибутом issue
+func formIssueRef(form *dao.Form, issueId uuid.UUID) string {
+	return issueId.String() + "." + formIssueSignature(form.ID, issueId)
+}
+
+// formIssueSignature подписывает пару форма-задача, подпись одной формы не подходит для другой
+func formIssueSignature(formId, issueId uuid.UUID) string {
+	mac := hmac.New(sha256.New, []byte(cfg.SecretKey))
+	mac.Write([]byte("form-issue/"))
+	mac.Write(formId.Bytes())
+	mac.Write(issueId.Bytes())
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// parseFormIssueRef проверяет подпись ссылки на задачу и возвращает id задачи
+func parseFormIssueRef(form *dao.Form, ref string) (uuid.UUID, bool) {
+	rawId, signature, ok := strings.Cut(strings.TrimSpace(ref), ".")
+	if !ok {
+		return uuid.Nil, false
+	}
+	id, err := uuid.FromString(rawId)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	if !hmac.Equal([]byte(signature), []byte(formIssueSignature(form.ID, id))) {
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// findFormTargetIssue ищет задачу проекта формы по подписанной ссылке из поля формы
+func findFormTargetIssue(tx *gorm.DB, form *dao.Form, ref string) (*dao.Issue, error) {
+	id, ok := parseFormIssueRef(form, ref)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var issue dao.Issue
+	if err := tx.Preload("Project").
+		Where("issues.project_id = ?", form.TargetProjectId.UUID).
+		Where("issues.id = ?", id).
+		First(&issue).Error; err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// getFormIssueLink godoc
+// @id getFormIssueLink
+// @Summary формы: ссылка на форму для задачи
+// @Description Возвращает подписанную ссылку на задачу проекта формы для поля с атрибутом issue и адрес формы с этой ссылкой в параметре issue. Ответы, отправленные по ссылке, добавляются комментариями к задаче
+// @Tags Forms
+// @Produce json
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug рабочего пространства"
+// @Param formSlug path string true "Slug формы"
+// @Param issueId path string true "Id задачи"
+// @Success 200 {object} dto.FormIssueLink "Ссылка на задачу"
+// @Failure 403 {object} apierrors.DefinedError "Ошибка: доступ запрещен"
+// @Failure 404 {object} apierrors.DefinedError "Задача не найдена"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/workspaces/{workspaceSlug}/forms/{formSlug}/issue-link/{issueId}/ [get]
+func (s *Services) getFormIssueLink(c echo.Context) error {
+	apiContext := apicontext.GetContext(c)
+	form := apiContext.GetForm(apicontext.WithFormAll())
+	if apiContext.Error() != nil {
+		return EError(c, apiContext.Error())
+	}
+
+	issueId, err := uuid.FromString(c.Param("issueId"))
+	if err != nil || !form.TargetProjectId.Valid {
+		return EErrorDefined(c, apierrors.ErrIssueNotFound)
+	}
+
+	var exists bool
+	if err := s.DB(c).Model(&dao.Issue{}).
+		Select("count(*) > 0").
+		Where("project_id = ?", form.TargetProjectId.UUID).
+		Where("id = ?", issueId).
+		Find(&exists).Error; err != nil {
+		return EError(c, err)
+	}
+	if !exists {
+		return EErrorDefined(c, apierrors.ErrIssueNotFound)
+	}
+
+	ref := formIssueRef(form, issueId)
+	u, _ := url.Parse(fmt.Sprintf("/f/%s/", form.Slug))
+	u = cfg.WebURL.URL.ResolveReference(u)
+	u.RawQuery = url.Values{"issue": {ref}}.Encode()
+	return c.JSON(http.StatusOK, dto.FormIssueLink{Ref: ref, URL: u.String()})
+}
+
+// appendAnswerToIssue добавляет ответ комментарием к задаче от имени системного пользователя и прикрепляет к задаче
+// вложения ответа. edited - ответ изменен пользователем после отправки
+func (s *Services) appendAnswerToIssue(form *dao.Form, answer *dao.FormAnswer, issue *dao.Issue, user *dao.User, edited bool) error {
+	body, err := business.GenBodyAnswer(answer, user)
+	if err != nil {
+		return err
+	}
+	title := fmt.Sprintf("Ответ №%d формы «%s»", answer.SeqId, form.Title)
+	if edited {
+		title = fmt.Sprintf("Ответ №%d формы «%s» изменен", answer.SeqId, form.Title)
+	}
+
+	systemUser := dao.GetSystemUser(s.db)
+	if systemUser == nil {
+		return errors.New("system user not found")
+	}
+	systemUserID := uuid.NullUUID{UUID: systemUser.ID, Valid: true}
+
+	comment := dao.IssueComment{
+		Id:          dao.GenUUID(),
+		WorkspaceId: issue.WorkspaceId,
+		ProjectId:   issue.ProjectId,
+		IssueId:     issue.ID,
+		ActorId:     systemUserID,
+		CommentHtml: types2.RedactorHTML{Body: "<p><strong>" + html.EscapeString(title) + "</strong></p>" + body},
+	}
+
+	if err := s.RawDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+
+		var attached []uuid.UUID
+		if err := tx.Model(&dao.IssueAttachment{}).Where("issue_id = ?", issue.ID).Pluck("asset_id", &attached).Error; err != nil {
+			return err
+		}
+		for _, attachment := range answer.Attachments {
+			if slices.Contains(attached, attachment.AssetId) {
+				continue
+			}
+			if err := tx.Create(&dao.IssueAttachment{
+				Id:          dao.GenUUID(),
+				AssetId:     attachment.AssetId,
+				IssueId:     issue.ID,
+				ProjectId:   issue.ProjectId,
+				WorkspaceId: issue.WorkspaceId,
+				CreatedById: systemUserID,
+				UpdatedById: systemUserID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&dao.FormAnswer{}).Where("id = ?", answer.ID).Update("issue_id", issue.ID).Error; err != nil {
+			return err
+		}
+		return tx.Model(&dao.Issue{}).Where("id = ?", issue.ID).Update("updated_at", time.Now()).Error
+	}); err != nil {
+		return err
+	}
+	answer.IssueId = uuid.NullUUID{UUID: issue.ID, Valid: true}
+
+	return s.snapshotTracker.TrackVerb(types2.LayerIssue, activities.VerbCreated, issue, systemUser,
+		tracker.WithNewVal(comment.CommentHtml.String()),
+		tracker.WithNewID(comment.Id),
+	)
+}
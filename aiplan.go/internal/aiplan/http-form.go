@@ -101,14 +101,20 @@ func (s *Services) AddFormServices(g *echo.Group) {
 
 	answerGroup.GET("/", s.getFormAuth)
 	answerGroup.POST("/answer/", s.createAnswerAuth)
+	answerGroup.POST("/sections/:section/check/", s.checkAnswerSectionAuth)
 	answerGroup.POST("/form-attachments/", s.createFormAttachments)
 	answerGroup.DELETE("/form-attachments/:attachmentId/", s.deleteFormAttachment)
+	answerGroup.GET("/my-answers/", s.getMyAnswers)
+	answerGroup.PATCH("/my-answers/:answerSeq/", s.updateMyAnswer)
 
 	formGroup.PATCH("/", s.updateForm)
 	formGroup.DELETE("/", s.deleteForm)
 
 	formGroup.GET("/answers/", s.getAnswers)
+	formGroup.GET("/answers/stats/", s.getAnswersStats)
+	formGroup.GET("/answers/export/", s.exportAnswers)
 	formGroup.GET("/answers/:answerSeq", s.getAnswer)
+	formGroup.GET("/issue-link/:issueId/", s.getFormIssueLink)
 
 }
 
@@ -116,6 +122,7 @@ func (s *Services) AddFormWithoutAuthServices(g *echo.Group) {
 	formNoAuthGroup := g.Group("forms/:formSlug", s.AnswerFormNoAuthMiddleware)
 	formNoAuthGroup.GET("/", s.getFormNoAuth)
 	formNoAuthGroup.POST("/answer/", s.createAnswerNoAuth)
+	formNoAuthGroup.POST("/sections/:section/check/", s.checkAnswerSectionNoAuth)
 	formNoAuthGroup.POST("/form-attachments/", s.createFormAttachmentsNoAuth)
 }
 
@@ -200,6 +207,18 @@ func (s *Services) createForm(c echo.Context) error {
 		return EErrorDefined(c, apierrors.ErrFormCheckFields.WithFormattedMessage(err.Error()))
 	}
 
+	if err := checkFormSections(form.Fields, form.Sections); err != nil {
+		return EErrorDefined(c, apierrors.ErrFormCheckFields.WithFormattedMessage(err.Error()))
+	}
+
+	if err := checkFormProtection(&form.Protection); err != nil {
+		return EErrorDefined(c, apierrors.ErrFormCheckFields.WithFormattedMessage(err.Error()))
+	}
+
+	if err := checkFormIssueFields(s.DB(c), form); err != nil {
+		return EErrorDefined(c, apierrors.ErrFormCheckFields.WithFormattedMessage(err.Error()))
+	}
+
 	if err := s.DB(c).Create(&form).Error; err != nil {
 		return EErrorDefined(c, apierrors.ErrGeneric)
 	}
@@ -266,6 +285,14 @@ func (s *Services) updateForm(c echo.Context) error {
 		requestMap["fields"] = req.Fields
 	}
 
+	if _, ok := requestMap["sections"]; ok {
+		requestMap["sections"] = req.Sections
+	}
+
+	if req.Protection != nil {
+		requestMap["protection"] = *req.Protection
+	}
+
 	newForm, err := req.toDao(&form, requestMap)
 	if err != nil {
 		return EErrorDefined(c, apierrors.ErrFormBadConvertRequest.WithFormattedMessage(err.Error()))
@@ -282,6 +309,18 @@ func (s *Services) updateForm(c echo.Context) error {
 		return EErrorDefined(c, apierrors.ErrFormCheckFields.WithFormattedMessage(err.Error()))
 	}
 
+	if err := checkFormSections(form.Fields, form.Sections); err != nil {
+		return EErrorDefined(c, apierrors.ErrFormCheckFields.WithFormattedMessage(err.Error()))
+	}
+
+	if err := checkFormProtection(&form.Protection); err != nil {
+		return EErrorDefined(c, apierrors.ErrFormCheckFields.WithFormattedMessage(err.Error()))
+	}
+
+	if err := checkFormIssueFields(s.DB(c), &form); err != nil {
+		return EErrorDefined(c, apierrors.ErrFormCheckFields.WithFormattedMessage(err.Error()))
+	}
+
 	updateFields := []string{"updated_by", "workspace_detail"}
 	for k := range requestMap {
 		updateFields = append(updateFields, k)
@@ -493,7 +532,7 @@ func (s *Services) getAnswer(c echo.Context) error {
 // @Produce json
 // @Security ApiKeyAuth
 // @Param formSlug path string true "Slug формы"
-// @Param answer body []dto.RequestAnswer true "Данные ответа"
+// @Param answer body []dto.RequestAnswer true "Ответы на поля. Для капчи и поля-ловушки передается объект {fields, captcha_payload, honeypot}"
 // @Success 201 {object} dto.ResponseAnswers "Отправленный ответ"
 // @Failure 400 {object} apierrors.DefinedError "Ошибка валидации ответа"
 // @Failure 403 {object} apierrors.DefinedError "Требуется аутентификация"
@@ -509,11 +548,11 @@ func (s *Services) createAnswerAuth(c echo.Context) error {
 	form := *formPtr
 	user := apiContext.GetUser()
 
-	var userAnswer types2.FormFieldsSlice
-
-	if err := c.Bind(&userAnswer); err != nil {
+	var req reqFormAnswer
+	if err := c.Bind(&req); err != nil {
 		return EErrorDefined(c, apierrors.ErrFormBadRequest)
 	}
+	userAnswer := req.Fields
 
 	if !form.Active {
 		return EErrorDefined(c, apierrors.ErrFormAnswerEnd)
@@ -534,6 +573,12 @@ func (s *Services) createAnswerAuth(c echo.Context) error {
 		return EErrorDefined(c, apierrors.ErrFormEmptyAnswers)
 	}
 
+	if ok, err := s.formAnswerAllowed(c, &form, user, &req); err != nil {
+		return EError(c, err)
+	} else if !ok {
+		return c.JSON(http.StatusOK, dto.ResponseAnswers{Form: *form.ToLightDTO(), Fields: resultAnswers})
+	}
+
 	var attachmentUUIDs []string
 	for _, field := range resultAnswers {
 		if field.Type == "attachment" && field.Val != nil {
@@ -543,6 +588,16 @@ func (s *Services) createAnswerAuth(c echo.Context) error {
 
 	var answer dao.FormAnswer
 	if err := s.DB(c).Transaction(func(tx *gorm.DB) error {
+		if form.Protection.MaxAnswers > 0 {
+			var count int64
+			if err := tx.Model(&dao.FormAnswer{}).Where("form_id = ?", form.ID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count >= int64(form.Protection.MaxAnswers) {
+				return apierrors.ErrFormAnswersLimit
+			}
+		}
+
 		var seqId int
 		// Calculate sequence id
 		var lastId sql.NullInt64
@@ -644,7 +699,7 @@ func (s *Services) createAnswerAuth(c echo.Context) error {
 // @Accept json
 // @Produce json
 // @Param formSlug path string true "Slug формы"
-// @Param answer body []dto.RequestAnswer true "Данные ответа"
+// @Param answer body []dto.RequestAnswer true "Ответы на поля. Для капчи и поля-ловушки передается объект {fields, captcha_payload, honeypot}"
 // @Success 201 {object} dto.ResponseAnswers "Отправленный ответ"
 // @Failure 400 {object} apierrors.DefinedError "Ошибка валидации ответа"
 // @Failure 403 {object} apierrors.DefinedError "Требуется аутентификация"
@@ -655,6 +710,83 @@ func (s *Services) createAnswerNoAuth(c echo.Context) error {
 	return s.createAnswerAuth(c)
 }
 
+// checkAnswerSectionAuth godoc
+// @id checkAnswerSectionAuth
+// @Summary ответы: Проверить раздел ответа (аутентифицированный)
+// @Description Проверяет ответы на разделы формы по пути заполнения до указанного раздела включительно и возвращает следующий раздел. Значение -1 означает, что форму можно отправлять
+// @Tags Forms
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param formSlug path string true "Slug формы"
+// @Param section path int true "Индекс раздела"
+// @Param answer body []dto.RequestAnswer true "Ответы на все поля формы"
+// @Success 200 {object} dto.ResponseAnswerSection "Следующий раздел"
+// @Failure 400 {object} apierrors.DefinedError "Ошибка валидации ответа"
+// @Failure 403 {object} apierrors.DefinedError "Требуется аутентификация"
+// @Failure 404 {object} apierrors.DefinedError "Форма не найдена"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/forms/{formSlug}/sections/{section}/check/ [post]
+func (s *Services) checkAnswerSectionAuth(c echo.Context) error {
+	apiContext := apicontext.GetContext(c)
+	form := apiContext.GetForm(apicontext.WithFormAll())
+	if apiContext.Error() != nil {
+		return EError(c, apiContext.Error())
+	}
+
+	section, err := strconv.Atoi(c.Param("section"))
+	if err != nil || section < 0 || section >= formSectionsCount(form.Sections) {
+		return EErrorDefined(c, apierrors.ErrFormBadRequest)
+	}
+
+	var req reqFormAnswer
+	if err := c.Bind(&req); err != nil {
+		return EErrorDefined(c, apierrors.ErrFormBadRequest)
+	}
+	userAnswer := req.Fields
+
+	if !form.Active {
+		return EErrorDefined(c, apierrors.ErrFormAnswerEnd)
+	}
+
+	if len(form.Fields) != len(userAnswer) {
+		return EErrorDefined(c, apierrors.ErrLenAnswers)
+	}
+
+	_, next, err := formAnswerSections(userAnswer, form, section)
+	if err != nil {
+		if errors.Is(err, apierrors.ErrFormAnswerDependOn) {
+			return EErrorDefined(c, apierrors.ErrFormDependOn)
+		}
+		if errors.Is(err, apierrors.ErrFormAnswerSectionSkipped) {
+			return EErrorDefined(c, apierrors.ErrFormSectionSkipped)
+		}
+		return EErrorDefined(c, apierrors.ErrFormCheckAnswers)
+	}
+
+	return c.JSON(http.StatusOK, dto.ResponseAnswerSection{NextSection: next})
+}
+
+// checkAnswerSectionNoAuth godoc
+// @id checkAnswerSectionNoAuth
+// @Summary ответы: Проверить раздел ответа
+// @Description Проверяет ответы на разделы формы по пути заполнения до указанного раздела включительно и возвращает следующий раздел. Значение -1 означает, что форму можно отправлять
+// @Tags Forms
+// @Accept json
+// @Produce json
+// @Param formSlug path string true "Slug формы"
+// @Param section path int true "Индекс раздела"
+// @Param answer body []dto.RequestAnswer true "Ответы на все поля формы"
+// @Success 200 {object} dto.ResponseAnswerSection "Следующий раздел"
+// @Failure 400 {object} apierrors.DefinedError "Ошибка валидации ответа"
+// @Failure 403 {object} apierrors.DefinedError "Требуется аутентификация"
+// @Failure 404 {object} apierrors.DefinedError "Форма не найдена"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/forms/{formSlug}/sections/{section}/check/ [post]
+func (s *Services) checkAnswerSectionNoAuth(c echo.Context) error {
+	return s.checkAnswerSectionAuth(c)
+}
+
 // createFormAttachmentsNoAuth godoc
 // @id createFormAttachmentsNoAuth
 // @Summary вложения: загрузка вложения в ответ формы (без аутентификации)
@@ -675,6 +807,22 @@ func (s *Services) createFormAttachmentsNoAuth(c echo.Context) error {
 }
 
 func (s *Services) createAnswerIssue(c echo.Context, form *dao.Form, answer *dao.FormAnswer, user *dao.User) error {
+	values, err := getFormIssueValues(c.Request().Context(), s.RawDB(), form, answer)
+	if err != nil {
+		return err
+	}
+
+	// ответ добавляется к указанной задаче, если она есть в проекте формы, иначе создается новая задача
+	if values.Issue != "" {
+		target, err := findFormTargetIssue(s.RawDB(), form, values.Issue)
+		if err == nil {
+			return s.appendAnswerToIssue(form, answer, target, user, false)
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	}
+
 	res, err := business.GenBodyAnswer(answer, user)
 	if err != nil {
 		return err
@@ -707,6 +855,10 @@ func (s *Services) createAnswerIssue(c echo.Context, form *dao.Form, answer *dao
 		}
 	}
 
+	issue.Priority = values.Priority
+	issue.TargetDate = values.TargetDate
+	defaultAssignees = utils.MergeUniqueSlices(defaultAssignees, values.Assignees)
+
 	var createWatcher bool
 	if user != nil {
 		if err := s.RawDB().Raw("select exists(select 1 from project_members where member_id = ? and project_id = ?)", user.ID, form.TargetProjectId).Find(&createWatcher).Error; err != nil {
@@ -753,6 +905,45 @@ func (s *Services) createAnswerIssue(c echo.Context, form *dao.Form, answer *dao
 			}
 		}
 
+		for _, labelId := range values.Labels {
+			if err := tx.Create(&dao.IssueLabel{
+				Id:          dao.GenUUID(),
+				LabelId:     labelId,
+				IssueId:     issue.ID,
+				ProjectId:   issue.ProjectId,
+				WorkspaceId: issue.WorkspaceId,
+				CreatedById: systemUserID,
+				UpdatedById: systemUserID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, sprintId := range values.Sprints {
+			if err := tx.Create(&dao.SprintIssue{
+				Id:          dao.GenUUID(),
+				SprintId:    sprintId,
+				IssueId:     issue.ID,
+				ProjectId:   issue.ProjectId,
+				WorkspaceId: issue.WorkspaceId,
+				CreatedById: systemUser.ID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, property := range values.Properties {
+			property.Id = dao.GenUUID()
+			property.IssueId = issue.ID
+			property.ProjectId = issue.ProjectId
+			property.WorkspaceId = issue.WorkspaceId
+			property.CreatedById = systemUserID
+			property.UpdatedById = systemUserID
+			if err := tx.Create(&property).Error; err != nil {
+				return err
+			}
+		}
+
 		for _, formAttachment := range formAttachments {
 			if err := tx.Create(&dao.IssueAttachment{
 				Id:          dao.GenUUID(),
@@ -767,10 +958,15 @@ func (s *Services) createAnswerIssue(c echo.Context, form *dao.Form, answer *dao
 			}
 		}
 
+		if err := tx.Model(&dao.FormAnswer{}).Where("id = ?", answer.ID).Update("issue_id", issue.ID).Error; err != nil {
+			return err
+		}
+
 		return tx.CreateInBatches(&newAssignees, 10).Error
 	}); err != nil {
 		return err
 	}
+	answer.IssueId = uuid.NullUUID{UUID: issue.ID, Valid: true}
 
 	err = s.snapshotTracker.TrackChanges(types2.LayerProject, nil, tracker.IssueToSnapshot(*issue), issue.Project, user)
 	if err != nil {
@@ -808,7 +1004,14 @@ func (s *Services) createFormAttachments(c echo.Context) error {
 	if !limiter.Limiter.CanAddAttachment(form.WorkspaceId) {
 		return EErrorDefined(c, apierrors.ErrAssetsLimitExceed)
 	}
-	const maxFileSize = 50 * 1024 * 1024 // 50 МБ
+	var maxFileSize int64 = 50 * 1024 * 1024 // 50 МБ
+	if size := form.Protection.MaxAttachmentSize; size > 0 && size < maxFileSize {
+		maxFileSize = size
+	}
+
+	if !s.formAttachmentUploadAllowed(c, form) {
+		return EErrorDefined(c, apierrors.ErrFormRateLimit)
+	}
 
 	asset, err := c.FormFile("asset")
 	if err != nil {
@@ -819,19 +1022,23 @@ func (s *Services) createFormAttachments(c echo.Context) error {
 		return EErrorDefined(c, apierrors.ErrFileTooLarge)
 	}
 
-	assetSrc, err := asset.Open()
-	if err != nil {
-		return EError(c, err)
-	}
-
 	fileName := asset.Filename
 
 	if decodedFilename, err := url.QueryUnescape(asset.Filename); err == nil {
 		fileName = decodedFilename
 	}
 
-	assetId := dao.GenUUID()
 	contentType := utils.ResolveContentType(fileName, asset.Header.Get("Content-Type"))
+	if !formAttachmentTypeAllowed(form.Protection, fileName, contentType) {
+		return EErrorDefined(c, apierrors.ErrFormAttachmentType)
+	}
+
+	assetSrc, err := asset.Open()
+	if err != nil {
+		return EError(c, err)
+	}
+
+	assetId := dao.GenUUID()
 
 	if err := s.storage.SaveReader(
 		assetSrc,
@@ -1057,38 +1264,16 @@ func isParentCondition(answer types2.FormFields, field types2.FormFields, dep *t
 	return false
 }
 
+// formAnswer проверяет ответы на все поля формы по пути заполнения разделов
 func formAnswer(answers types2.FormFieldsSlice, form *dao.Form) (types2.FormFieldsSlice, error) {
-
-	validator := FormValidator()
-	var resultAnswer types2.FormFieldsSlice
-
-	for i, field := range form.Fields {
-		skip, err := ValidateFieldDependency(field, i, resultAnswer, form.Fields, answers[i].Val)
-		if err != nil {
-			return nil, err
-		}
-
-		if !skip {
-			validFunc := validator[field.Type]
-			checkVal := validFunc(answers[i].Val, field.Required, field.Validate)
-			if !checkVal {
-				return nil, fmt.Errorf("field missing or wrong type")
-			}
-		}
-
-		resultAnswer = append(resultAnswer,
-			types2.FormFields{
-				Type:  field.Type,
-				Label: field.Label,
-				Val:   answers[i].Val,
-			})
-	}
-	return resultAnswer, nil
+	resultAnswer, _, err := formAnswerSections(answers, form, formSectionsCount(form.Sections))
+	return resultAnswer, err
 }
 
 func checkFormFields(fields *types2.FormFieldsSlice) error {
 	validator := FormValidator()
 	var checkIssueNameField bool
+	issueFields := make(map[string]struct{})
 	for i, field := range *fields {
 		if field.IssueNameField {
 			if field.Type != formFieldInput {
@@ -1160,7 +1345,7 @@ func checkFormFields(fields *types2.FormFieldsSlice) error {
 			(*fields)[i].Validate.ValueType = "numeric"
 		case formFieldCheckbox:
 			(*fields)[i].Validate.ValueType = "bool"
-		case formFieldInput:
+		case formFieldInput, formFieldHidden:
 			(*fields)[i].Validate.ValueType = "string"
 		case formFieldTextarea:
 			(*fields)[i].Validate.ValueType = "string"
@@ -1178,29 +1363,41 @@ func checkFormFields(fields *types2.FormFieldsSlice) error {
 			(*fields)[i].Validate.ValueType = "multiselect"
 		}
 
+		if field.IssueField != nil {
+			if err := checkFormIssueField(&(*fields)[i], issueFields); err != nil {
+				return err
+			}
+		}
+
 		if field.DependOn != nil { // проверка корректности конфигурации depend_on при создании/обновлении формы
-			if i <= field.DependOn.FieldIndex { // зависимое поле должно идти после поля, от которого оно зависит
+			if i <= field.DependOn.FieldIndex || field.DependOn.FieldIndex < 0 { // зависимое поле должно идти после поля, от которого оно зависит
 				return fmt.Errorf("invalid depend_on order: %d must be greater than %d", i, field.DependOn.FieldIndex)
 			}
-			f := (*fields)[field.DependOn.FieldIndex]
-			switch f.Type {
-			case formFieldCheckbox:
-				if field.DependOn.OptionIndex != nil {
-					return fmt.Errorf("invalid depend_on config")
-				}
-			case formFieldSelect, formFieldMultiselect:
-				if field.DependOn.OptionIndex == nil {
-					return fmt.Errorf("depend_on option index required")
-				}
-				if f.Validate == nil || f.Validate.Opt == nil ||
-					*field.DependOn.OptionIndex >= len(f.Validate.Opt) {
-					return fmt.Errorf("depend_on option index out of range")
-				}
-			default:
-				return fmt.Errorf("unsupported depend_on field type")
+			if err := checkFormCondition((*fields)[field.DependOn.FieldIndex], field.DependOn); err != nil {
+				return fmt.Errorf("depend_on: %w", err)
 			}
+		}
+	}
+	return nil
+}
 
+// checkFormCondition проверяет условие depend_on или перехода между разделами по значению поля parent
+func checkFormCondition(parent types2.FormFields, dep *types2.FormFieldDependency) error {
+	switch parent.Type {
+	case formFieldCheckbox:
+		if dep.OptionIndex != nil {
+			return fmt.Errorf("invalid config")
 		}
+	case formFieldSelect, formFieldMultiselect:
+		if dep.OptionIndex == nil {
+			return fmt.Errorf("option index required")
+		}
+		if parent.Validate == nil || parent.Validate.Opt == nil ||
+			*dep.OptionIndex < 0 || *dep.OptionIndex >= len(parent.Validate.Opt) {
+			return fmt.Errorf("option index out of range")
+		}
+	default:
+		return fmt.Errorf("unsupported field type")
 	}
 	return nil
 }
@@ -1211,14 +1408,17 @@ type reqForm struct {
 	Title                string                  `json:"title,omitempty" validate:"required"`
 	Description          types2.RedactorHTML     `json:"description,omitempty"`
 	AuthRequire          bool                    `json:"auth_require,omitempty"`
+	AllowEdit            bool                    `json:"allow_edit,omitempty"`
 	EndDate              *types2.TargetDate      `json:"end_date,omitempty" extensions:"x-nullable"`
 	TargetProjectId      *string                 `json:"target_project_id" extensions:"x-nullable"`
 	Fields               types2.FormFieldsSlice  `json:"fields,omitempty"`
+	Sections             types2.FormSections     `json:"sections,omitempty"`
+	Protection           *types2.FormProtection  `json:"protection,omitempty" extensions:"x-nullable"`
 	NotificationChannels types2.FormAnswerNotify `json:"notification_channels"`
 }
 
 func (rf *reqForm) toDao(form *dao.Form, updFields map[string]interface{}) (*dao.Form, error) {
-	allowedForm := []string{"title", "description", "auth_require", "end_date", "fields", "target_project_id", "notification_channels"}
+	allowedForm := []string{"title", "description", "auth_require", "allow_edit", "end_date", "fields", "sections", "protection", "target_project_id", "notification_channels"}
 
 	if form == nil {
 		form = &dao.Form{}
@@ -1227,6 +1427,7 @@ func (rf *reqForm) toDao(form *dao.Form, updFields map[string]interface{}) (*dao
 		form.Title = rf.Title
 		form.Description = rf.Description
 		form.AuthRequire = rf.AuthRequire
+		form.AllowEdit = rf.AllowEdit
 		if rf.EndDate != nil {
 			date, err := notifications.FormatDate(rf.EndDate.Time.String(), "2006-01-02", nil)
 			if err != nil {
@@ -1243,6 +1444,10 @@ func (rf *reqForm) toDao(form *dao.Form, updFields map[string]interface{}) (*dao
 			form.TargetProjectId = uuid.NullUUID{Valid: true, UUID: projectUUID}
 		}
 		form.Fields = rf.Fields
+		form.Sections = rf.Sections
+		if rf.Protection != nil {
+			form.Protection = *rf.Protection
+		}
 		form.NotificationChannels = rf.NotificationChannels
 	} else {
 		for _, field := range allowedForm {
@@ -1266,6 +1471,12 @@ func (rf *reqForm) toDao(form *dao.Form, updFields map[string]interface{}) (*dao
 					} else {
 						return nil, fmt.Errorf("auth_require")
 					}
+				case "allow_edit":
+					if allowEdit, ok := value.(bool); ok {
+						form.AllowEdit = allowEdit
+					} else {
+						return nil, fmt.Errorf("allow_edit")
+					}
 				case "end_date":
 					if rawValue, ok := value.(time.Time); ok {
 						endDate := &types2.TargetDate{}
@@ -1297,6 +1508,18 @@ func (rf *reqForm) toDao(form *dao.Form, updFields map[string]interface{}) (*dao
 					} else {
 						return nil, fmt.Errorf("fields")
 					}
+				case "protection":
+					if protection, ok := value.(types2.FormProtection); ok {
+						form.Protection = protection
+					} else {
+						return nil, fmt.Errorf("protection")
+					}
+				case "sections":
+					if sections, ok := value.(types2.FormSections); ok {
+						form.Sections = sections
+					} else {
+						return nil, fmt.Errorf("sections")
+					}
 				case "target_project_id":
 					if value == nil {
 						form.TargetProjectId = uuid.NullUUID{Valid: false}
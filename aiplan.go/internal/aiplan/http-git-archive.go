@@ -0,0 +1,345 @@
+// Пакет aiplan предоставляет скачивание файлов и архивов Git репозиториев.
+//
+// Содержимое отдается потоком напрямую из вывода git (cat-file/archive) без буферизации
+// всего файла в памяти. Доступ проверяется так же, как для остальных Browse эндпоинтов.
+package aiplan
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	apicontext "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/api-context"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
+	"github.com/labstack/echo/v4"
+)
+
+// Форматы архивов, поддерживаемые git archive
+var gitArchiveFormats = map[string]string{
+	"tar.gz": "application/gzip",
+	"zip":    "application/zip",
+}
+
+// Типы содержимого, которые могут исполняться браузером. Такие файлы отдаются как text/plain,
+// чтобы raw эндпоинт нельзя было использовать для XSS на домене приложения.
+var gitRawUnsafeContentTypes = []string{
+	"text/html",
+	"image/svg+xml",
+	"application/xhtml+xml",
+	"text/xml",
+	"application/xml",
+	"application/javascript",
+	"text/javascript",
+}
+
+// gitRawContentType определяет Content-Type файла по расширению, а при неудаче - по содержимому
+func gitRawContentType(path string, head []byte) string {
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		if isBinaryFile(head) {
+			contentType = http.DetectContentType(head)
+		} else {
+			contentType = "text/plain; charset=utf-8"
+		}
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	for _, unsafe := range gitRawUnsafeContentTypes {
+		if mediaType == unsafe {
+			return "text/plain; charset=utf-8"
+		}
+	}
+	return contentType
+}
+
+// parseByteRange разбирает заголовок Range с одним диапазоном байт.
+// Возвращает ok = false, если заголовок отсутствует или содержит несколько диапазонов
+// (в этом случае отдается весь файл), и err, если диапазон не удовлетворяет размеру файла.
+func parseByteRange(header string, size int64) (start, end int64, ok bool, err error) {
+	if header == "" || !strings.HasPrefix(header, "bytes=") {
+		return 0, 0, false, nil
+	}
+	spec := strings.TrimPrefix(header, "bytes=")
+	if strings.Contains(spec, ",") {
+		return 0, 0, false, nil
+	}
+
+	from, to, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, 0, false, fmt.Errorf("invalid range")
+	}
+
+	if from == "" {
+		// Суффиксный диапазон: последние N байт
+		n, err := strconv.ParseInt(to, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, false, fmt.Errorf("invalid range")
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, size - 1, true, nil
+	}
+
+	start, err = strconv.ParseInt(from, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, false, fmt.Errorf("invalid range")
+	}
+	end = size - 1
+	if to != "" {
+		end, err = strconv.ParseInt(to, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false, fmt.Errorf("invalid range")
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+	return start, end, true, nil
+}
+
+// sanitizeArchiveName заменяет в имени ревизии символы, недопустимые в имени файла
+func sanitizeArchiveName(ref string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '-'
+	}, ref)
+}
+
+// resolveGitRef возвращает ref из запроса или ветку по умолчанию
+func resolveGitRef(c echo.Context, repoPath string) string {
+	ref := c.QueryParam("ref")
+	if ref != "" {
+		return ref
+	}
+	defaultBranch, err := getDefaultBranch(repoPath)
+	if err != nil {
+		slog.WarnContext(c.Request().Context(), "Failed to get default branch, using 'main'", "err", err)
+		return "main"
+	}
+	return defaultBranch
+}
+
+// @Summary Browse: скачивание файла
+// @Description Возвращает содержимое файла как есть с определением Content-Type и поддержкой Range запросов.
+// @Description HTML, SVG и другие исполняемые браузером типы отдаются как text/plain.
+// @Tags GIT-BROWSE
+// @Produce octet-stream
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug workspace"
+// @Param repoName path string true "Имя репозитория"
+// @Param ref query string false "Ветка/тег/коммит (по умолчанию: ветка по умолчанию)"
+// @Param path query string true "Путь к файлу в репозитории"
+// @Param download query bool false "Отдать файл как вложение (Content-Disposition: attachment)"
+// @Success 200 {file} binary "Содержимое файла"
+// @Success 206 {file} binary "Запрошенный диапазон файла"
+// @Success 304 "Файл не изменился (If-None-Match)"
+// @Failure 400 {object} apierrors.DefinedError "Некорректный запрос"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Доступ запрещен"
+// @Failure 404 {object} apierrors.DefinedError "Репозиторий, ревизия или файл не найдены"
+// @Failure 416 "Запрошенный диапазон недопустим"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/git/{workspaceSlug}/repositories/{repoName}/raw [get]
+func (s *Services) getRepositoryRaw(c echo.Context) error {
+	user := apicontext.GetContext(c).GetUser()
+	scope, err := s.getGitRepositoryScope(c, false)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	path := strings.TrimPrefix(c.QueryParam("path"), "/")
+	if path == "" {
+		return EErrorDefined(c, apierrors.ErrGeneric)
+	}
+	ref := resolveGitRef(c, scope.repoPath)
+
+	commit, err := resolveGitCommit(scope.repoPath, ref)
+	if err != nil {
+		return EError(c, gitErrorToDefined(err))
+	}
+
+	// Определяем объект файла и проверяем, что это blob (а не директория или submodule)
+	objectOutput, err := executeGitCommandStdout(scope.repoPath, nil, "rev-parse", "--verify", "--quiet", commit+":"+path)
+	if err != nil {
+		return EErrorDefined(c, apierrors.ErrGitRefNotFound)
+	}
+	blobSHA := strings.TrimSpace(string(objectOutput))
+	objectType, err := executeGitCommandStdout(scope.repoPath, nil, "cat-file", "-t", blobSHA)
+	if err != nil || strings.TrimSpace(string(objectType)) != "blob" {
+		return EErrorDefined(c, apierrors.ErrGitRefNotFound)
+	}
+	sizeOutput, err := executeGitCommandStdout(scope.repoPath, nil, "cat-file", "-s", blobSHA)
+	if err != nil {
+		return EError(c, err)
+	}
+	size, _ := strconv.ParseInt(strings.TrimSpace(string(sizeOutput)), 10, 64)
+
+	// Содержимое blob неизменно, поэтому его SHA является надежным ETag
+	etag := `"` + blobSHA + `"`
+	header := c.Response().Header()
+	header.Set("ETag", etag)
+	header.Set("Accept-Ranges", "bytes")
+	header.Set("X-Content-Type-Options", "nosniff")
+	header.Set("Content-Security-Policy", "sandbox")
+	if match := c.Request().Header.Get("If-None-Match"); match != "" && strings.Contains(match, etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	start, end, partial, err := parseByteRange(c.Request().Header.Get("Range"), size)
+	if err != nil {
+		header.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		return c.NoContent(http.StatusRequestedRangeNotSatisfiable)
+	}
+	// If-Range с другим ETag означает, что клиенту нужен весь файл
+	if ifRange := c.Request().Header.Get("If-Range"); partial && ifRange != "" && ifRange != etag {
+		partial = false
+	}
+
+	cmd := exec.CommandContext(c.Request().Context(), "git", "cat-file", "blob", blobSHA)
+	cmd.Dir = scope.repoPath
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return EError(c, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return EError(c, err)
+	}
+	// Закрытие pipe завершает git, если файл был прочитан не полностью (Range)
+	defer func() {
+		stdout.Close()
+		cmd.Wait()
+	}()
+
+	reader := bufio.NewReaderSize(stdout, 8192)
+	head, _ := reader.Peek(512)
+
+	disposition := "inline"
+	if download, _ := strconv.ParseBool(c.QueryParam("download")); download {
+		disposition = "attachment"
+	}
+	header.Set(echo.HeaderContentType, gitRawContentType(path, head))
+	header.Set(echo.HeaderContentDisposition, mime.FormatMediaType(disposition, map[string]string{"filename": filepath.Base(path)}))
+
+	status := http.StatusOK
+	length := size
+	if partial {
+		if _, err := io.CopyN(io.Discard, reader, start); err != nil {
+			return EError(c, err)
+		}
+		length = end - start + 1
+		status = http.StatusPartialContent
+		header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+	}
+	header.Set(echo.HeaderContentLength, strconv.FormatInt(length, 10))
+	c.Response().WriteHeader(status)
+
+	if _, err := io.CopyN(c.Response(), reader, length); err != nil {
+		// Заголовки уже отправлены, поэтому ошибку можно только залогировать
+		slog.WarnContext(c.Request().Context(), "Failed to stream git blob", "repo", scope.repoPath, "path", path, "err", err)
+	}
+
+	slog.InfoContext(c.Request().Context(), "Git raw file served",
+		"workspace", scope.workspace.Slug,
+		"repo", scope.repoName,
+		"ref", ref,
+		"path", path,
+		"size", length,
+		"user", user.Email)
+
+	return nil
+}
+
+// @Summary Browse: скачивание архива
+// @Description Возвращает снимок ревизии репозитория в виде архива tar.gz или zip (git archive).
+// @Description Архив передается потоком по мере формирования.
+// @Tags GIT-BROWSE
+// @Produce octet-stream
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug workspace"
+// @Param repoName path string true "Имя репозитория"
+// @Param ref query string false "Ветка/тег/коммит (по умолчанию: ветка по умолчанию)"
+// @Param format query string false "Формат архива: tar.gz или zip" default(tar.gz)
+// @Success 200 {file} binary "Архив"
+// @Failure 400 {object} apierrors.DefinedError "Некорректный запрос"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Доступ запрещен"
+// @Failure 404 {object} apierrors.DefinedError "Репозиторий или ревизия не найдены"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/git/{workspaceSlug}/repositories/{repoName}/archive [get]
+func (s *Services) getRepositoryArchive(c echo.Context) error {
+	user := apicontext.GetContext(c).GetUser()
+	scope, err := s.getGitRepositoryScope(c, false)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	format := c.QueryParam("format")
+	if format == "" {
+		format = "tar.gz"
+	}
+	contentType, ok := gitArchiveFormats[format]
+	if !ok {
+		return EErrorDefined(c, apierrors.ErrGeneric.WithFormattedMessage("Unsupported archive format"))
+	}
+
+	ref := resolveGitRef(c, scope.repoPath)
+	commit, err := resolveGitCommit(scope.repoPath, ref)
+	if err != nil {
+		return EError(c, gitErrorToDefined(err))
+	}
+
+	// Все файлы архива помещаются в каталог {repo}-{ref}/
+	name := scope.repoName + "-" + sanitizeArchiveName(ref)
+
+	cmd := exec.CommandContext(c.Request().Context(), "git", "archive", "--format="+format, "--prefix="+name+"/", commit)
+	cmd.Dir = scope.repoPath
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return EError(c, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return EError(c, err)
+	}
+
+	// Дожидаемся первых байт, чтобы ошибка запуска git archive вернулась клиенту как JSON
+	reader := bufio.NewReader(stdout)
+	if _, err := reader.Peek(1); err != nil {
+		waitErr := cmd.Wait()
+		slog.ErrorContext(c.Request().Context(), "Failed to run git archive", "repo", scope.repoPath, "ref", ref, "err", err, "wait", waitErr)
+		return EErrorDefined(c, apierrors.ErrGitCommandFailed.WithFormattedMessage("Failed to create archive"))
+	}
+
+	header := c.Response().Header()
+	header.Set(echo.HeaderContentType, contentType)
+	header.Set(echo.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": name + "." + format}))
+	header.Set("X-Content-Type-Options", "nosniff")
+	c.Response().WriteHeader(http.StatusOK)
+
+	written, copyErr := io.Copy(c.Response(), reader)
+	stdout.Close()
+	if err := cmd.Wait(); err != nil || copyErr != nil {
+		// Заголовки уже отправлены, поэтому ошибку можно только залогировать
+		slog.WarnContext(c.Request().Context(), "Git archive streaming interrupted", "repo", scope.repoPath, "ref", ref, "err", err, "copy_err", copyErr)
+		return nil
+	}
+
+	slog.InfoContext(c.Request().Context(), "Git archive served",
+		"workspace", scope.workspace.Slug,
+		"repo", scope.repoName,
+		"ref", ref,
+		"format", format,
+		"size", written,
+		"user", user.Email)
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package aiplan
+
+import (
+	"testing"
+)
+
+// TestParseByteRange проверяет разбор заголовка Range
+func TestParseByteRange(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		size        int64
+		start, end  int64
+		ok          bool
+		expectError bool
+	}{
+		{"No header", "", 100, 0, 0, false, false},
+		{"Full range", "bytes=0-99", 100, 0, 99, true, false},
+		{"Open range", "bytes=10-", 100, 10, 99, true, false},
+		{"End beyond size", "bytes=90-200", 100, 90, 99, true, false},
+		{"Suffix range", "bytes=-10", 100, 90, 99, true, false},
+		{"Suffix larger than size", "bytes=-500", 100, 0, 99, true, false},
+		{"Multiple ranges ignored", "bytes=0-1,5-6", 100, 0, 0, false, false},
+		{"Other unit ignored", "items=0-1", 100, 0, 0, false, false},
+		{"Start beyond size", "bytes=100-", 100, 0, 0, false, true},
+		{"End before start", "bytes=50-10", 100, 0, 0, false, true},
+		{"Garbage", "bytes=abc", 100, 0, 0, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, ok, err := parseByteRange(tt.header, tt.size)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("Expected error=%v, got %v", tt.expectError, err)
+			}
+			if ok != tt.ok || start != tt.start || end != tt.end {
+				t.Errorf("Expected (%d, %d, %v), got (%d, %d, %v)", tt.start, tt.end, tt.ok, start, end, ok)
+			}
+		})
+	}
+}
+
+// TestGitRawContentType проверяет определение Content-Type и защиту от исполняемых типов
+func TestGitRawContentType(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		head     []byte
+		expected string
+	}{
+		{"PNG by extension", "img/logo.png", nil, "image/png"},
+		{"HTML served as text", "index.html", []byte("<html></html>"), "text/plain; charset=utf-8"},
+		{"SVG served as text", "icon.svg", []byte("<svg></svg>"), "text/plain; charset=utf-8"},
+		{"Unknown text", "Makefile", []byte("all:\n\tgo build\n"), "text/plain; charset=utf-8"},
+		{"Unknown binary", "data.bin1", []byte{0x00, 0x01, 0x02}, "application/octet-stream"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := gitRawContentType(tt.path, tt.head); result != tt.expected {
+				t.Errorf("Expected %s for %s, got %s", tt.expected, tt.path, result)
+			}
+		})
+	}
+}
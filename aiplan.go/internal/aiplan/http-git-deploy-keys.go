@@ -0,0 +1,218 @@
+package aiplan
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+
+	apicontext "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/api-context"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// getDeployKeysScope загружает репозиторий из URL и проверяет, что SSH включен,
+// а пользователь является администратором workspace
+func (s *Services) getDeployKeysScope(c echo.Context) (*gitRepositoryScope, error) {
+	user := apicontext.GetContext(c).GetUser()
+
+	if !cfg.SSHEnabled {
+		return nil, apierrors.ErrSSHDisabled
+	}
+
+	scope, err := s.getGitRepositoryScope(c, true)
+	if err != nil {
+		return nil, err
+	}
+	if !scope.isAdmin(user) {
+		return nil, apierrors.ErrWorkspaceAdminRoleRequired
+	}
+	return scope, nil
+}
+
+// deployKeyToDTO конвертирует deploy ключ в DTO (без публичного ключа!)
+func deployKeyToDTO(key *DeployKeyMetadata, users map[uuid.UUID]*dto.UserLight) dto.DeployKeyDTO {
+	result := dto.DeployKeyDTO{
+		SSHKeyDTO: dto.SSHKeyDTO{
+			ID:          key.ID,
+			Name:        key.Name,
+			KeyType:     key.KeyType,
+			Fingerprint: key.Fingerprint,
+			CreatedAt:   key.CreatedAt,
+			LastUsedAt:  key.LastUsedAt,
+			Comment:     key.Comment,
+		},
+		ReadOnly: key.ReadOnly,
+	}
+	if id, err := uuid.FromString(key.CreatedBy); err == nil {
+		result.CreatedBy = users[id]
+	}
+	return result
+}
+
+// @Summary Deploy Keys: список deploy ключей
+// @Description Возвращает список deploy ключей репозитория. Доступно только администраторам workspace
+// @Tags GIT-SSH
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug workspace"
+// @Param repoName path string true "Имя репозитория"
+// @Success 200 {object} dto.ListDeployKeysResponse "Список deploy ключей"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Недостаточно прав или SSH отключен"
+// @Failure 404 {object} apierrors.DefinedError "Репозиторий не найден"
+// @Router /api/auth/git/{workspaceSlug}/repositories/{repoName}/deploy-keys/ [get]
+func (s *Services) listDeployKeys(c echo.Context) error {
+	scope, err := s.getDeployKeysScope(c)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	repoKeys, err := LoadDeployKeys(scope.repoPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c.JSON(http.StatusOK, dto.ListDeployKeysResponse{
+				Keys:  []dto.DeployKeyDTO{},
+				Total: 0,
+			})
+		}
+		slog.ErrorContext(c.Request().Context(), "Failed to load deploy keys", "repo", scope.repoName, "err", err)
+		return EError(c, err)
+	}
+
+	var creatorIds []uuid.UUID
+	for _, key := range repoKeys.Keys {
+		if id, err := uuid.FromString(key.CreatedBy); err == nil {
+			creatorIds = append(creatorIds, id)
+		}
+	}
+	users, err := s.loadUsersByIds(c, creatorIds)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	keysDTO := make([]dto.DeployKeyDTO, 0, len(repoKeys.Keys))
+	for i := range repoKeys.Keys {
+		keysDTO = append(keysDTO, deployKeyToDTO(&repoKeys.Keys[i], users))
+	}
+
+	return c.JSON(http.StatusOK, dto.ListDeployKeysResponse{
+		Keys:  keysDTO,
+		Total: len(keysDTO),
+	})
+}
+
+// @Summary Deploy Keys: добавить deploy ключ
+// @Description Добавляет SSH ключ, дающий доступ только к этому репозиторию. По умолчанию ключ только для чтения. Доступно только администраторам workspace
+// @Tags GIT-SSH
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug workspace"
+// @Param repoName path string true "Имя репозитория"
+// @Param request body dto.AddDeployKeyRequest true "Deploy ключ"
+// @Success 201 {object} dto.DeployKeyDTO "Добавленный deploy ключ"
+// @Failure 400 {object} apierrors.DefinedError "Некорректные данные запроса"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Недостаточно прав или SSH отключен"
+// @Failure 404 {object} apierrors.DefinedError "Репозиторий не найден"
+// @Failure 409 {object} apierrors.DefinedError "SSH ключ с таким fingerprint уже существует"
+// @Router /api/auth/git/{workspaceSlug}/repositories/{repoName}/deploy-keys/ [post]
+func (s *Services) addDeployKey(c echo.Context) error {
+	user := apicontext.GetContext(c).GetUser()
+
+	scope, err := s.getDeployKeysScope(c)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	var req dto.AddDeployKeyRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to bind AddDeployKeyRequest", "err", err)
+		return EErrorDefined(c, apierrors.ErrGeneric)
+	}
+
+	if req.Name == "" || req.PublicKey == "" {
+		return EErrorDefined(c, apierrors.ErrSSHKeyInvalidData)
+	}
+
+	readOnly := true
+	if req.ReadOnly != nil {
+		readOnly = *req.ReadOnly
+	}
+
+	key, err := AddDeployKey(scope.workspace.Slug, scope.repoName, req.Name, req.PublicKey, readOnly, user.ID.String(), cfg.GitRepositoriesPath)
+	if err != nil {
+		errMsg := err.Error()
+		if strings.Contains(errMsg, "invalid SSH key name") {
+			return EErrorDefined(c, apierrors.ErrSSHKeyInvalidData)
+		}
+		if strings.Contains(errMsg, "invalid SSH public key format") {
+			return EErrorDefined(c, apierrors.ErrSSHInvalidPublicKey)
+		}
+		if strings.Contains(errMsg, "already exists") {
+			return EErrorDefined(c, apierrors.ErrSSHKeyAlreadyExists)
+		}
+
+		slog.ErrorContext(c.Request().Context(), "Failed to add deploy key", "repo", scope.repoName, "user", user.Email, "err", err)
+		return EError(c, err)
+	}
+
+	slog.InfoContext(c.Request().Context(), "Deploy key added",
+		"user", user.Email,
+		"workspace", scope.workspace.Slug,
+		"repo", scope.repoName,
+		"key_id", key.ID,
+		"key_name", key.Name,
+		"read_only", key.ReadOnly,
+		"fingerprint", key.Fingerprint)
+
+	return c.JSON(http.StatusCreated, deployKeyToDTO(key, map[uuid.UUID]*dto.UserLight{user.ID: user.ToLightDTO()}))
+}
+
+// @Summary Deploy Keys: удалить deploy ключ
+// @Description Удаляет deploy ключ репозитория по ID. Доступно только администраторам workspace
+// @Tags GIT-SSH
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug workspace"
+// @Param repoName path string true "Имя репозитория"
+// @Param keyId path string true "ID deploy ключа (UUID)"
+// @Success 204 "Deploy ключ успешно удален"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Недостаточно прав или SSH отключен"
+// @Failure 404 {object} apierrors.DefinedError "Deploy ключ не найден"
+// @Router /api/auth/git/{workspaceSlug}/repositories/{repoName}/deploy-keys/{keyId} [delete]
+func (s *Services) deleteDeployKey(c echo.Context) error {
+	user := apicontext.GetContext(c).GetUser()
+
+	scope, err := s.getDeployKeysScope(c)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	keyId := c.Param("keyId")
+	if _, err := uuid.FromString(keyId); err != nil {
+		return EErrorDefined(c, apierrors.ErrSSHDeployKeyNotFound)
+	}
+
+	if err := DeleteDeployKey(scope.workspace.Slug, scope.repoName, keyId, cfg.GitRepositoriesPath); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return EErrorDefined(c, apierrors.ErrSSHDeployKeyNotFound)
+		}
+		slog.ErrorContext(c.Request().Context(), "Failed to delete deploy key", "repo", scope.repoName, "key_id", keyId, "err", err)
+		return EError(c, err)
+	}
+
+	slog.InfoContext(c.Request().Context(), "Deploy key deleted",
+		"user", user.Email,
+		"workspace", scope.workspace.Slug,
+		"repo", scope.repoName,
+		"key_id", keyId)
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,1357 @@
+// Пакет aiplan предоставляет функциональность merge request'ов для Git репозиториев.
+//
+// Архитектурный принцип: merge request'ы, как и сами репозитории, НЕ хранятся в базе данных.
+// Они находятся в файловой системе рядом с репозиторием (см. merge-requests-fs.go).
+// База данных используется только для пользователей, workspace и связанных задач.
+package aiplan
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	apicontext "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/api-context"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
+	policy "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/redactor-policy"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/utils"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// ========================================
+// Merge Requests DTO Structures
+// ========================================
+
+// MergeRequestDetailsDTO представляет merge request вместе с текущим статусом слияния
+type MergeRequestDetailsDTO struct {
+	dto.MergeRequest
+
+	// MergeStatus - возможность слияния (только для открытых merge request'ов)
+	MergeStatus *MergeCheckDTO `json:"merge_status,omitempty" extensions:"x-nullable"`
+}
+
+// ========================================
+// Merge Requests Helper Functions
+// ========================================
+
+// parseMergeRequestNumber возвращает номер merge request'а из URL
+func parseMergeRequestNumber(c echo.Context) (int, error) {
+	number, err := strconv.Atoi(c.Param("number"))
+	if err != nil || number <= 0 {
+		return 0, apierrors.ErrGitMergeRequestNotFound
+	}
+	return number, nil
+}
+
+// loadMergeRequestFromURL загружает merge request по номеру из URL
+func loadMergeRequestFromURL(c echo.Context, scope *gitRepositoryScope) (*MergeRequest, error) {
+	number, err := parseMergeRequestNumber(c)
+	if err != nil {
+		return nil, err
+	}
+	mr, err := LoadMergeRequest(scope.repoPath, number)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, apierrors.ErrGitMergeRequestNotFound
+		}
+		return nil, err
+	}
+	return mr, nil
+}
+
+// updateMergeRequestFromURL изменяет merge request по номеру из URL под блокировкой
+func updateMergeRequestFromURL(c echo.Context, scope *gitRepositoryScope, fn func(mr *MergeRequest) error) (*MergeRequest, error) {
+	number, err := parseMergeRequestNumber(c)
+	if err != nil {
+		return nil, err
+	}
+	mr, err := UpdateMergeRequest(scope.repoPath, number, fn)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, apierrors.ErrGitMergeRequestNotFound
+		}
+		return nil, err
+	}
+	return mr, nil
+}
+
+// gitErrorToDefined преобразует ошибки движка сравнения и слияния в ошибки API
+func gitErrorToDefined(err error) error {
+	switch {
+	case errors.Is(err, errGitRefNotFound):
+		return apierrors.ErrGitRefNotFound
+	case errors.Is(err, errGitMergeConflict):
+		return apierrors.ErrGitMergeConflict
+	case errors.Is(err, errGitNotFastForward):
+		return apierrors.ErrGitNotFastForward
+	case errors.Is(err, errGitNothingToMerge):
+		return apierrors.ErrGitNothingToMerge
+	case errors.Is(err, errGitRefUpdateFailed):
+		return apierrors.ErrGitBranchUpdated
+	}
+	return err
+}
+
+// mergeRequestIssuesQuery ограничивает выборку задачами проектов, в которых состоит пользователь.
+// Так же права проверяются при переводе связанных задач в новый статус
+func mergeRequestIssuesQuery(tx *gorm.DB, user *dao.User) *gorm.DB {
+	if user.IsSuperuser {
+		return tx
+	}
+	return tx.Where("issues.project_id IN (?)", tx.Session(&gorm.Session{NewDB: true}).
+		Model(&dao.ProjectMember{}).
+		Select("project_id").
+		Where("member_id = ?", user.ID))
+}
+
+// visibleMergeRequestIssues возвращает задачи из ids, доступные пользователю
+func (s *Services) visibleMergeRequestIssues(c echo.Context, user *dao.User, ids []uuid.UUID) ([]uuid.UUID, error) {
+	visible := []uuid.UUID{}
+	if len(ids) == 0 {
+		return visible, nil
+	}
+	err := mergeRequestIssuesQuery(s.DB(c).Model(&dao.Issue{}), user).
+		Where("issues.id IN (?)", ids).
+		Pluck("issues.id", &visible).Error
+	return visible, err
+}
+
+// resolveMergeRequestIssues преобразует ссылки на задачи (UUID или PROJ-12) в UUID задач workspace.
+// Связать можно только задачи проектов, в которых состоит пользователь
+func (s *Services) resolveMergeRequestIssues(c echo.Context, user *dao.User, workspace dao.Workspace, refs []string) ([]uuid.UUID, error) {
+	ids := []uuid.UUID{}
+	for _, ref := range refs {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
+			continue
+		}
+
+		query := mergeRequestIssuesQuery(s.DB(c).Model(&dao.Issue{}), user).
+			Select("issues.id").
+			Where("issues.workspace_id = ?", workspace.ID)
+		if id, err := uuid.FromString(ref); err == nil {
+			query = query.Where("issues.id = ?", id)
+		} else {
+			sep := strings.LastIndex(ref, "-")
+			if sep <= 0 {
+				return nil, apierrors.ErrIssueNotFound
+			}
+			seq, err := strconv.Atoi(ref[sep+1:])
+			if err != nil {
+				return nil, apierrors.ErrIssueNotFound
+			}
+			query = query.Joins("Project").
+				Where(`"Project".identifier = ?`, strings.ToUpper(ref[:sep])).
+				Where("issues.sequence_id = ?", seq)
+		}
+
+		var issueId uuid.UUID
+		if err := query.Limit(1).Scan(&issueId).Error; err != nil {
+			return nil, err
+		}
+		if issueId.IsNil() {
+			return nil, apierrors.ErrIssueNotFound
+		}
+		if !slices.Contains(ids, issueId) {
+			ids = append(ids, issueId)
+		}
+	}
+	return ids, nil
+}
+
+// mergeRequestToLightDTO формирует облегченное представление merge request'а
+func mergeRequestToLightDTO(workspaceSlug, repoName string, mr *MergeRequest, users map[uuid.UUID]*dto.UserLight) dto.MergeRequestLight {
+	return dto.MergeRequestLight{
+		Workspace:      workspaceSlug,
+		Repository:     repoName,
+		Number:         mr.Number,
+		Title:          mr.Title,
+		SourceBranch:   mr.SourceBranch,
+		TargetBranch:   mr.TargetBranch,
+		State:          mr.State,
+		Author:         users[mr.AuthorID],
+		ApprovalsCount: len(mr.Approvals),
+		CommentsCount:  len(mr.Comments),
+		CreatedAt:      mr.CreatedAt,
+		UpdatedAt:      mr.UpdatedAt,
+		MergedAt:       mr.MergedAt,
+		ClosedAt:       mr.ClosedAt,
+	}
+}
+
+// mergeRequestCommentToDTO формирует представление комментария merge request'а
+func mergeRequestCommentToDTO(comment *MergeRequestComment, users map[uuid.UUID]*dto.UserLight) dto.MergeRequestComment {
+	result := dto.MergeRequestComment{
+		Id:               comment.Id,
+		Actor:            users[comment.ActorId],
+		CommentHtml:      comment.CommentHtml.String(),
+		ReplyToCommentId: comment.ReplyToCommentId,
+		Path:             comment.Path,
+		Line:             comment.Line,
+		Side:             comment.Side,
+		CommitSHA:        comment.CommitSHA,
+		Reactions:        make([]*dto.CommentReaction, 0, len(comment.Reactions)),
+		CreatedAt:        comment.CreatedAt,
+		UpdatedAt:        comment.UpdatedAt,
+	}
+	if len(comment.Reactions) > 0 {
+		result.ReactionSummary = make(map[string]int)
+	}
+	for i := range comment.Reactions {
+		result.Reactions = append(result.Reactions, &comment.Reactions[i])
+		result.ReactionSummary[comment.Reactions[i].Reaction]++
+	}
+	return result
+}
+
+// mergeRequestToDetailsDTO формирует полное представление merge request'а со связанными задачами.
+// Возвращаются только задачи проектов, в которых состоит текущий пользователь.
+// Для открытого merge request'а дополнительно вычисляется возможность слияния.
+func (s *Services) mergeRequestToDetailsDTO(c echo.Context, scope *gitRepositoryScope, mr *MergeRequest) (*MergeRequestDetailsDTO, error) {
+	userIds := []uuid.UUID{mr.AuthorID}
+	if mr.MergedBy != nil {
+		userIds = append(userIds, *mr.MergedBy)
+	}
+	for _, a := range mr.Approvals {
+		userIds = append(userIds, a.UserID)
+	}
+	users, err := s.loadUsersByIds(c, userIds)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &MergeRequestDetailsDTO{
+		MergeRequest: dto.MergeRequest{
+			MergeRequestLight: mergeRequestToLightDTO(scope.workspace.Slug, scope.repoName, mr, users),
+			Description:       mr.Description.String(),
+			Issues:            []dto.IssueLight{},
+			Approvals:         []dto.MergeRequestApproval{},
+			MergeMethod:       mr.MergeMethod,
+			MergeCommitSHA:    mr.MergeCommitSHA,
+		},
+	}
+	if mr.MergedBy != nil {
+		result.MergedBy = users[*mr.MergedBy]
+	}
+
+	if len(mr.IssueIDs) > 0 {
+		var issues []dao.Issue
+		if err := mergeRequestIssuesQuery(s.DB(c), apicontext.GetContext(c).GetUser()).
+			Joins("Workspace").
+			Joins("Project").
+			Joins("State").
+			Where("issues.id IN (?)", mr.IssueIDs).
+			Order("issues.sequence_id").
+			Find(&issues).Error; err != nil {
+			return nil, err
+		}
+		result.Issues = utils.SliceToSlice(&issues, func(i *dao.Issue) dto.IssueLight { return *i.ToLightDTO() })
+	}
+
+	// Текущая голова исходной ветки нужна для определения устаревших одобрений
+	headSHA := mr.MergedSourceSHA
+	if mr.State == MergeRequestStateOpen {
+		status, err := checkGitMerge(scope.repoPath, mr.TargetBranch, mr.SourceBranch)
+		if err != nil && !errors.Is(err, errGitRefNotFound) {
+			return nil, err
+		}
+		if status != nil {
+			result.MergeStatus = status
+			headSHA = status.SourceSHA
+		}
+	}
+
+	for _, a := range mr.Approvals {
+		result.Approvals = append(result.Approvals, dto.MergeRequestApproval{
+			User:      users[a.UserID],
+			CommitSHA: a.CommitSHA,
+			Outdated:  headSHA != "" && a.CommitSHA != headSHA,
+			CreatedAt: a.CreatedAt,
+		})
+	}
+
+	return result, nil
+}
+
+// ========================================
+// Git Compare Endpoint
+// ========================================
+
+// @Summary Browse: сравнение ревизий
+// @Description Возвращает коммиты и изменения файлов между двумя ревизиями относительно их общего предка
+// @Tags GIT-BROWSE
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug workspace"
+// @Param repoName path string true "Имя репозитория"
+// @Param base query string true "Базовая ревизия (ветка/тег/коммит)"
+// @Param head query string true "Сравниваемая ревизия (ветка/тег/коммит)"
+// @Param patch query bool false "Включить unified diff для каждого файла" default(true)
+// @Success 200 {object} CompareResponseDTO "Результат сравнения"
+// @Failure 400 {object} apierrors.DefinedError "Некорректный запрос"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Доступ запрещен"
+// @Failure 404 {object} apierrors.DefinedError "Репозиторий или ревизия не найдены"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/git/{workspaceSlug}/repositories/{repoName}/compare [get]
+func (s *Services) getRepositoryCompare(c echo.Context) error {
+	scope, err := s.getGitRepositoryScope(c, false)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	base := c.QueryParam("base")
+	head := c.QueryParam("head")
+	withPatch := true
+	if err := echo.QueryParamsBinder(c).Bool("patch", &withPatch).BindError(); err != nil {
+		return EErrorDefined(c, apierrors.ErrGeneric)
+	}
+	if base == "" || head == "" {
+		return EErrorDefined(c, apierrors.ErrGeneric)
+	}
+
+	result, err := compareGitRefs(scope.repoPath, base, head, withPatch)
+	if err != nil {
+		return EError(c, gitErrorToDefined(err))
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// ========================================
+// Merge Requests Endpoints
+// ========================================
+
+// @Summary Merge requests: список
+// @Description Возвращает список merge request'ов репозитория
+// @Tags GIT-MR
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug workspace"
+// @Param repoName path string true "Имя репозитория"
+// @Param state query string false "Фильтр по состоянию: open, closed, merged, all" default(open)
+// @Success 200 {object} dto.ListMergeRequestsResponse "Список merge request'ов"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Доступ запрещен"
+// @Failure 404 {object} apierrors.DefinedError "Репозиторий не найден"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/git/{workspaceSlug}/repositories/{repoName}/merge-requests [get]
+func (s *Services) listMergeRequests(c echo.Context) error {
+	scope, err := s.getGitRepositoryScope(c, false)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	state := c.QueryParam("state")
+	if state == "" {
+		state = MergeRequestStateOpen
+	}
+
+	mrs, err := ListMergeRequests(scope.repoPath)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	var filtered []*MergeRequest
+	var authorIds []uuid.UUID
+	for _, mr := range mrs {
+		if state != "all" && mr.State != state {
+			continue
+		}
+		filtered = append(filtered, mr)
+		authorIds = append(authorIds, mr.AuthorID)
+	}
+
+	users, err := s.loadUsersByIds(c, authorIds)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	response := dto.ListMergeRequestsResponse{MergeRequests: []dto.MergeRequestLight{}}
+	for _, mr := range filtered {
+		response.MergeRequests = append(response.MergeRequests, mergeRequestToLightDTO(scope.workspace.Slug, scope.repoName, mr, users))
+	}
+	response.Total = len(response.MergeRequests)
+
+	return c.JSON(http.StatusOK, response)
+}
+
+// @Summary Merge requests: создание
+// @Description Создает merge request из исходной ветки в целевую
+// @Tags GIT-MR
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug workspace"
+// @Param repoName path string true "Имя репозитория"
+// @Param request body dto.CreateMergeRequestRequest true "Параметры merge request'а"
+// @Success 201 {object} MergeRequestDetailsDTO "Созданный merge request"
+// @Failure 400 {object} apierrors.DefinedError "Некорректный запрос"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Недостаточно прав"
+// @Failure 404 {object} apierrors.DefinedError "Репозиторий, ветка или задача не найдены"
+// @Failure 409 {object} apierrors.DefinedError "Открытый merge request для этих веток уже существует"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/git/{workspaceSlug}/repositories/{repoName}/merge-requests [post]
+func (s *Services) createMergeRequest(c echo.Context) error {
+	user := apicontext.GetContext(c).GetUser()
+	scope, err := s.getGitRepositoryScope(c, true)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	var req dto.CreateMergeRequestRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to bind request", "err", err)
+		return EErrorDefined(c, apierrors.ErrGeneric)
+	}
+
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" || len(req.Title) > 255 {
+		return EErrorDefined(c, apierrors.ErrGeneric)
+	}
+	if req.SourceBranch == req.TargetBranch {
+		return EErrorDefined(c, apierrors.ErrGitSameBranches)
+	}
+	if !validateBranchName(req.SourceBranch) || !validateBranchName(req.TargetBranch) {
+		return EErrorDefined(c, apierrors.ErrGitInvalidBranch)
+	}
+	for _, branch := range []string{req.SourceBranch, req.TargetBranch} {
+		if _, err := resolveGitBranch(scope.repoPath, branch); err != nil {
+			return EError(c, gitErrorToDefined(err))
+		}
+	}
+
+	issueIds, err := s.resolveMergeRequestIssues(c, user, scope.workspace, req.Issues)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	mr := &MergeRequest{
+		Title:        req.Title,
+		Description:  types.RedactorHTML{Body: policy.UgcPolicy.Sanitize(req.Description)},
+		SourceBranch: req.SourceBranch,
+		TargetBranch: req.TargetBranch,
+		AuthorID:     user.ID,
+		IssueIDs:     issueIds,
+	}
+	if err := CreateMergeRequest(scope.repoPath, mr); err != nil {
+		if errors.Is(err, apierrors.ErrGitMergeRequestExists) {
+			return EErrorDefined(c, apierrors.ErrGitMergeRequestExists)
+		}
+		slog.ErrorContext(c.Request().Context(), "Failed to create merge request", "repo", scope.repoPath, "err", err)
+		return EError(c, err)
+	}
+
+	slog.InfoContext(c.Request().Context(), "Merge request created",
+		"workspace", scope.workspace.Slug,
+		"repo", scope.repoName,
+		"number", mr.Number,
+		"user", user.Email)
+
+	result, err := s.mergeRequestToDetailsDTO(c, scope, mr)
+	if err != nil {
+		return EError(c, err)
+	}
+	return c.JSON(http.StatusCreated, result)
+}
+
+// @Summary Merge requests: получение
+// @Description Возвращает merge request со связанными задачами, одобрениями и статусом слияния
+// @Tags GIT-MR
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug workspace"
+// @Param repoName path string true "Имя репозитория"
+// @Param number path int true "Номер merge request'а"
+// @Success 200 {object} MergeRequestDetailsDTO "Merge request"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Доступ запрещен"
+// @Failure 404 {object} apierrors.DefinedError "Репозиторий или merge request не найден"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/git/{workspaceSlug}/repositories/{repoName}/merge-requests/{number} [get]
+func (s *Services) getMergeRequest(c echo.Context) error {
+	scope, err := s.getGitRepositoryScope(c, false)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	mr, err := loadMergeRequestFromURL(c, scope)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	result, err := s.mergeRequestToDetailsDTO(c, scope, mr)
+	if err != nil {
+		return EError(c, err)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// @Summary Merge requests: изменение
+// @Description Изменяет заголовок, описание, целевую ветку и связанные задачи, закрывает или переоткрывает merge request.
+// @Description Изменять merge request может автор или администратор workspace.
+// @Tags GIT-MR
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug workspace"
+// @Param repoName path string true "Имя репозитория"
+// @Param number path int true "Номер merge request'а"
+// @Param request body dto.UpdateMergeRequestRequest true "Изменяемые поля"
+// @Success 200 {object} MergeRequestDetailsDTO "Измененный merge request"
+// @Failure 400 {object} apierrors.DefinedError "Некорректный запрос"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Недостаточно прав"
+// @Failure 404 {object} apierrors.DefinedError "Репозиторий, merge request, ветка или задача не найдены"
+// @Failure 409 {object} apierrors.DefinedError "Merge request уже слит"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/git/{workspaceSlug}/repositories/{repoName}/merge-requests/{number} [patch]
+func (s *Services) updateMergeRequest(c echo.Context) error {
+	user := apicontext.GetContext(c).GetUser()
+	scope, err := s.getGitRepositoryScope(c, true)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	var req dto.UpdateMergeRequestRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to bind request", "err", err)
+		return EErrorDefined(c, apierrors.ErrGeneric)
+	}
+
+	var issueIds []uuid.UUID
+	if req.Issues != nil {
+		if issueIds, err = s.resolveMergeRequestIssues(c, user, scope.workspace, *req.Issues); err != nil {
+			return EError(c, err)
+		}
+	}
+
+	mr, err := updateMergeRequestFromURL(c, scope, func(mr *MergeRequest) error {
+		if mr.AuthorID != user.ID && !scope.isAdmin(user) {
+			return apierrors.ErrWorkspaceForbidden
+		}
+		if mr.State == MergeRequestStateMerged {
+			return apierrors.ErrGitMergeRequestNotOpen
+		}
+
+		if req.Title != nil {
+			title := strings.TrimSpace(*req.Title)
+			if title == "" || len(title) > 255 {
+				return apierrors.ErrGeneric
+			}
+			mr.Title = title
+		}
+		if req.Description != nil {
+			mr.Description = types.RedactorHTML{Body: policy.UgcPolicy.Sanitize(*req.Description)}
+		}
+		if req.TargetBranch != nil && *req.TargetBranch != mr.TargetBranch {
+			if *req.TargetBranch == mr.SourceBranch {
+				return apierrors.ErrGitSameBranches
+			}
+			if _, err := resolveGitBranch(scope.repoPath, *req.TargetBranch); err != nil {
+				return gitErrorToDefined(err)
+			}
+			mr.TargetBranch = *req.TargetBranch
+			// Одобрения относятся к сравнению с прежней целевой веткой
+			mr.Approvals = []MergeRequestApproval{}
+		}
+		if req.Issues != nil {
+			// Связи с задачами недоступных пользователю проектов он не видит, они сохраняются
+			visible, err := s.visibleMergeRequestIssues(c, user, mr.IssueIDs)
+			if err != nil {
+				return err
+			}
+			for _, id := range mr.IssueIDs {
+				if !slices.Contains(visible, id) && !slices.Contains(issueIds, id) {
+					issueIds = append(issueIds, id)
+				}
+			}
+			mr.IssueIDs = issueIds
+		}
+		if req.State != nil && *req.State != mr.State {
+			switch *req.State {
+			case MergeRequestStateClosed:
+				now := time.Now()
+				mr.State = MergeRequestStateClosed
+				mr.ClosedAt = &now
+			case MergeRequestStateOpen:
+				if _, err := resolveGitBranch(scope.repoPath, mr.SourceBranch); err != nil {
+					return gitErrorToDefined(err)
+				}
+				mr.State = MergeRequestStateOpen
+				mr.ClosedAt = nil
+			default:
+				return apierrors.ErrGeneric
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return EError(c, err)
+	}
+
+	slog.InfoContext(c.Request().Context(), "Merge request updated",
+		"workspace", scope.workspace.Slug,
+		"repo", scope.repoName,
+		"number", mr.Number,
+		"state", mr.State,
+		"user", user.Email)
+
+	result, err := s.mergeRequestToDetailsDTO(c, scope, mr)
+	if err != nil {
+		return EError(c, err)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// @Summary Merge requests: изменения
+// @Description Возвращает коммиты и diff merge request'а. Для слитого merge request'а diff строится по состоянию веток на момент слияния.
+// @Tags GIT-MR
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug workspace"
+// @Param repoName path string true "Имя репозитория"
+// @Param number path int true "Номер merge request'а"
+// @Param patch query bool false "Включить unified diff для каждого файла" default(true)
+// @Success 200 {object} CompareResponseDTO "Изменения merge request'а"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Доступ запрещен"
+// @Failure 404 {object} apierrors.DefinedError "Репозиторий, merge request или ветка не найдены"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/git/{workspaceSlug}/repositories/{repoName}/merge-requests/{number}/diff [get]
+func (s *Services) getMergeRequestDiff(c echo.Context) error {
+	scope, err := s.getGitRepositoryScope(c, false)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	mr, err := loadMergeRequestFromURL(c, scope)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	withPatch := true
+	if err := echo.QueryParamsBinder(c).Bool("patch", &withPatch).BindError(); err != nil {
+		return EErrorDefined(c, apierrors.ErrGeneric)
+	}
+
+	base, head := "refs/heads/"+mr.TargetBranch, "refs/heads/"+mr.SourceBranch
+	if mr.State == MergeRequestStateMerged && mr.MergedTargetSHA != "" {
+		base, head = mr.MergedTargetSHA, mr.MergedSourceSHA
+	}
+
+	result, err := compareGitRefs(scope.repoPath, base, head, withPatch)
+	if err != nil {
+		return EError(c, gitErrorToDefined(err))
+	}
+	result.Base, result.Head = mr.TargetBranch, mr.SourceBranch
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// @Summary Merge requests: слияние
+// @Description Выполняет слияние на сервере: merge коммитом (по умолчанию) или fast-forward.
+// @Description При наличии конфликтов слияние не выполняется. Связанные задачи могут быть переведены в указанный статус.
+// @Tags GIT-MR
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug workspace"
+// @Param repoName path string true "Имя репозитория"
+// @Param number path int true "Номер merge request'а"
+// @Param request body dto.MergeMergeRequestRequest false "Параметры слияния"
+// @Success 200 {object} MergeRequestDetailsDTO "Слитый merge request"
+// @Failure 400 {object} apierrors.DefinedError "Некорректный запрос"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Недостаточно прав"
+// @Failure 404 {object} apierrors.DefinedError "Репозиторий, merge request или ветка не найдены"
+// @Failure 409 {object} apierrors.DefinedError "Конфликты, fast-forward невозможен или merge request не открыт"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/git/{workspaceSlug}/repositories/{repoName}/merge-requests/{number}/merge [post]
+func (s *Services) mergeMergeRequest(c echo.Context) error {
+	user := apicontext.GetContext(c).GetUser()
+	scope, err := s.getGitRepositoryScope(c, true)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	// Зеркало обновляется только из удаленного репозитория
+	if IsGitMirror(scope.repoPath) {
+		return EErrorDefined(c, apierrors.ErrGitMirrorReadOnly)
+	}
+
+	var req dto.MergeMergeRequestRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to bind request", "err", err)
+		return EErrorDefined(c, apierrors.ErrGeneric)
+	}
+	if req.Method == "" {
+		req.Method = MergeMethodMerge
+	}
+	if req.Method != MergeMethodMerge && req.Method != MergeMethodFastForward {
+		return EErrorDefined(c, apierrors.ErrGitInvalidMergeMethod)
+	}
+
+	committer := gitIdentity{Name: user.GetName(), Email: user.Email}
+
+	// Слияние выполняется под блокировкой merge request'ов репозитория, чтобы исключить повторное слияние.
+	// Блокировка общая только для merge request'ов этого репозитория
+	mr, err := updateMergeRequestFromURL(c, scope, func(mr *MergeRequest) error {
+		if mr.State != MergeRequestStateOpen {
+			return apierrors.ErrGitMergeRequestNotOpen
+		}
+
+		status, err := checkGitMerge(scope.repoPath, mr.TargetBranch, mr.SourceBranch)
+		if err != nil {
+			return gitErrorToDefined(err)
+		}
+
+		message := req.Message
+		if strings.TrimSpace(message) == "" {
+			message = fmt.Sprintf("Merge branch '%s' into %s\n\nMerge request !%d: %s", mr.SourceBranch, mr.TargetBranch, mr.Number, mr.Title)
+		}
+
+		sha, err := mergeGitBranches(scope.repoPath, mr.TargetBranch, mr.SourceBranch, req.Method, message, committer)
+		if err != nil {
+			return gitErrorToDefined(err)
+		}
+
+		now := time.Now()
+		mr.State = MergeRequestStateMerged
+		mr.MergedAt = &now
+		mr.MergedBy = &user.ID
+		mr.MergeMethod = req.Method
+		mr.MergeCommitSHA = sha
+		mr.MergedTargetSHA = status.TargetSHA
+		mr.MergedSourceSHA = status.SourceSHA
+		return nil
+	})
+	if err != nil {
+		return EError(c, err)
+	}
+
+	slog.InfoContext(c.Request().Context(), "Merge request merged",
+		"workspace", scope.workspace.Slug,
+		"repo", scope.repoName,
+		"number", mr.Number,
+		"method", mr.MergeMethod,
+		"sha", mr.MergeCommitSHA,
+		"user", user.Email)
+
+	if req.CompleteIssues || req.IssueStateId != nil {
+		s.transitionMergeRequestIssues(c, user, mr, req.IssueStateId)
+	}
+
+	result, err := s.mergeRequestToDetailsDTO(c, scope, mr)
+	if err != nil {
+		return EError(c, err)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// transitionMergeRequestIssues переводит связанные с merge request'ом задачи в новый статус.
+// Если stateId не указан или относится к другому проекту, используется первый статус группы completed проекта задачи.
+// Ошибки перевода отдельных задач не отменяют слияние и только логируются.
+func (s *Services) transitionMergeRequestIssues(c echo.Context, user *dao.User, mr *MergeRequest, stateId *uuid.UUID) {
+	for _, issueId := range mr.IssueIDs {
+		var issue dao.Issue
+		if err := s.DB(c).
+			Joins("Workspace").
+			Joins("Project").
+			Joins("State").
+			Where("issues.id = ?", issueId).
+			First(&issue).Error; err != nil {
+			slog.WarnContext(c.Request().Context(), "Merge request issue not found", "issue", issueId, "err", err)
+			continue
+		}
+
+		isAdmin := user.IsSuperuser
+		if !user.IsSuperuser {
+			var projectMember dao.ProjectMember
+			if err := s.DB(c).
+				Where("project_id = ? AND member_id = ?", issue.ProjectId, user.ID).
+				First(&projectMember).Error; err != nil || projectMember.Role < types.MemberRole {
+				slog.WarnContext(c.Request().Context(), "No rights to change merge request issue state", "issue", issueId, "user", user.Email)
+				continue
+			}
+			isAdmin = projectMember.Role == types.AdminRole
+		}
+
+		var newState dao.State
+		query := s.DB(c).Where("project_id = ?", issue.ProjectId)
+		if stateId != nil {
+			query = query.Where("id = ?", *stateId)
+		}
+		err := query.First(&newState).Error
+		if stateId == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+			err = s.DB(c).
+				Where("project_id = ?", issue.ProjectId).
+				Where(`"group" = ?`, "completed").
+				Order("sequence").
+				First(&newState).Error
+		}
+		if err != nil {
+			slog.WarnContext(c.Request().Context(), "Merge request issue target state not found", "issue", issueId, "err", err)
+			continue
+		}
+
+		if err := s.business.ChangeIssueState(&issue, newState, *user, isAdmin); err != nil {
+			slog.WarnContext(c.Request().Context(), "Failed to change merge request issue state", "issue", issueId, "err", err)
+		}
+	}
+}
+
+// @Summary Merge requests: одобрение
+// @Description Одобряет merge request от имени текущего пользователя. Автор не может одобрить свой merge request.
+// @Tags GIT-MR
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug workspace"
+// @Param repoName path string true "Имя репозитория"
+// @Param number path int true "Номер merge request'а"
+// @Success 200 {object} MergeRequestDetailsDTO "Merge request"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Недостаточно прав"
+// @Failure 404 {object} apierrors.DefinedError "Репозиторий или merge request не найден"
+// @Failure 409 {object} apierrors.DefinedError "Merge request не открыт"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/git/{workspaceSlug}/repositories/{repoName}/merge-requests/{number}/approvals [post]
+func (s *Services) approveMergeRequest(c echo.Context) error {
+	user := apicontext.GetContext(c).GetUser()
+	scope, err := s.getGitRepositoryScope(c, true)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	mr, err := updateMergeRequestFromURL(c, scope, func(mr *MergeRequest) error {
+		if mr.State != MergeRequestStateOpen {
+			return apierrors.ErrGitMergeRequestNotOpen
+		}
+		if mr.AuthorID == user.ID {
+			return apierrors.ErrGitSelfApproval
+		}
+		headSHA, err := resolveGitBranch(scope.repoPath, mr.SourceBranch)
+		if err != nil {
+			return gitErrorToDefined(err)
+		}
+
+		// Повторное одобрение обновляет коммит, к которому оно относится
+		mr.Approvals = slices.DeleteFunc(mr.Approvals, func(a MergeRequestApproval) bool { return a.UserID == user.ID })
+		mr.Approvals = append(mr.Approvals, MergeRequestApproval{
+			UserID:    user.ID,
+			CommitSHA: headSHA,
+			CreatedAt: time.Now(),
+		})
+		return nil
+	})
+	if err != nil {
+		return EError(c, err)
+	}
+
+	result, err := s.mergeRequestToDetailsDTO(c, scope, mr)
+	if err != nil {
+		return EError(c, err)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// @Summary Merge requests: отзыв одобрения
+// @Description Отзывает одобрение merge request'а текущим пользователем
+// @Tags GIT-MR
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug workspace"
+// @Param repoName path string true "Имя репозитория"
+// @Param number path int true "Номер merge request'а"
+// @Success 200 {object} MergeRequestDetailsDTO "Merge request"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Недостаточно прав"
+// @Failure 404 {object} apierrors.DefinedError "Репозиторий или merge request не найден"
+// @Failure 409 {object} apierrors.DefinedError "Merge request не открыт"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/git/{workspaceSlug}/repositories/{repoName}/merge-requests/{number}/approvals [delete]
+func (s *Services) unapproveMergeRequest(c echo.Context) error {
+	user := apicontext.GetContext(c).GetUser()
+	scope, err := s.getGitRepositoryScope(c, true)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	mr, err := updateMergeRequestFromURL(c, scope, func(mr *MergeRequest) error {
+		if mr.State != MergeRequestStateOpen {
+			return apierrors.ErrGitMergeRequestNotOpen
+		}
+		mr.Approvals = slices.DeleteFunc(mr.Approvals, func(a MergeRequestApproval) bool { return a.UserID == user.ID })
+		return nil
+	})
+	if err != nil {
+		return EError(c, err)
+	}
+
+	result, err := s.mergeRequestToDetailsDTO(c, scope, mr)
+	if err != nil {
+		return EError(c, err)
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// ========================================
+// Merge Request Comments Endpoints
+// ========================================
+
+// @Summary Merge requests (комментарии): список
+// @Description Возвращает общие и построчные комментарии merge request'а в порядке создания
+// @Tags GIT-MR
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug workspace"
+// @Param repoName path string true "Имя репозитория"
+// @Param number path int true "Номер merge request'а"
+// @Param path query string false "Только комментарии к указанному файлу"
+// @Success 200 {array} dto.MergeRequestComment "Комментарии"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Доступ запрещен"
+// @Failure 404 {object} apierrors.DefinedError "Репозиторий или merge request не найден"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/git/{workspaceSlug}/repositories/{repoName}/merge-requests/{number}/comments [get]
+func (s *Services) getMergeRequestCommentList(c echo.Context) error {
+	scope, err := s.getGitRepositoryScope(c, false)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	mr, err := loadMergeRequestFromURL(c, scope)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	path := c.QueryParam("path")
+	var actorIds []uuid.UUID
+	for _, comment := range mr.Comments {
+		actorIds = append(actorIds, comment.ActorId)
+	}
+	users, err := s.loadUsersByIds(c, actorIds)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	result := []dto.MergeRequestComment{}
+	for i := range mr.Comments {
+		if path != "" && mr.Comments[i].Path != path {
+			continue
+		}
+		result = append(result, mergeRequestCommentToDTO(&mr.Comments[i], users))
+	}
+	return c.JSON(http.StatusOK, result)
+}
+
+// @Summary Merge requests (комментарии): создание
+// @Description Создает общий комментарий или комментарий к строке файла (path, line, side)
+// @Tags GIT-MR
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug workspace"
+// @Param repoName path string true "Имя репозитория"
+// @Param number path int true "Номер merge request'а"
+// @Param request body dto.CreateMergeRequestCommentRequest true "Комментарий"
+// @Success 201 {object} dto.MergeRequestComment "Созданный комментарий"
+// @Failure 400 {object} apierrors.DefinedError "Некорректный запрос"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Недостаточно прав"
+// @Failure 404 {object} apierrors.DefinedError "Репозиторий, merge request или комментарий не найден"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/git/{workspaceSlug}/repositories/{repoName}/merge-requests/{number}/comments [post]
+func (s *Services) createMergeRequestComment(c echo.Context) error {
+	user := apicontext.GetContext(c).GetUser()
+	scope, err := s.getGitRepositoryScope(c, true)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	var req dto.CreateMergeRequestCommentRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to bind request", "err", err)
+		return EErrorDefined(c, apierrors.ErrGeneric)
+	}
+
+	comment := MergeRequestComment{
+		Id:               dao.GenUUID(),
+		ActorId:          user.ID,
+		CommentHtml:      types.RedactorHTML{Body: policy.UgcPolicy.Sanitize(req.CommentHtml)},
+		ReplyToCommentId: req.ReplyToCommentId,
+		Reactions:        []dto.CommentReaction{},
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+	if strings.TrimSpace(comment.CommentHtml.StripTags()) == "" {
+		return EErrorDefined(c, apierrors.ErrGeneric)
+	}
+
+	if req.Path != "" {
+		if req.Line <= 0 {
+			return EErrorDefined(c, apierrors.ErrGeneric)
+		}
+		if req.Side == "" {
+			req.Side = "new"
+		}
+		if req.Side != "old" && req.Side != "new" {
+			return EErrorDefined(c, apierrors.ErrGeneric)
+		}
+		comment.Path, comment.Line, comment.Side = req.Path, req.Line, req.Side
+	}
+
+	_, err = updateMergeRequestFromURL(c, scope, func(mr *MergeRequest) error {
+		if comment.ReplyToCommentId.Valid && mr.FindComment(comment.ReplyToCommentId.UUID) == nil {
+			return apierrors.ErrGitMRCommentNotFound
+		}
+		if comment.Path != "" {
+			sha := mr.MergedSourceSHA
+			if mr.State != MergeRequestStateMerged {
+				sha, _ = resolveGitBranch(scope.repoPath, mr.SourceBranch)
+			}
+			comment.CommitSHA = sha
+		}
+		mr.Comments = append(mr.Comments, comment)
+		return nil
+	})
+	if err != nil {
+		return EError(c, err)
+	}
+
+	users, err := s.loadUsersByIds(c, []uuid.UUID{user.ID})
+	if err != nil {
+		return EError(c, err)
+	}
+	return c.JSON(http.StatusCreated, mergeRequestCommentToDTO(&comment, users))
+}
+
+// @Summary Merge requests (комментарии): изменение
+// @Description Изменяет текст комментария. Изменять комментарий может только его автор.
+// @Tags GIT-MR
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug workspace"
+// @Param repoName path string true "Имя репозитория"
+// @Param number path int true "Номер merge request'а"
+// @Param commentId path string true "ID комментария"
+// @Param request body dto.CreateMergeRequestCommentRequest true "Комментарий (используется только comment_html)"
+// @Success 200 {object} dto.MergeRequestComment "Измененный комментарий"
+// @Failure 400 {object} apierrors.DefinedError "Некорректный запрос"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Недостаточно прав"
+// @Failure 404 {object} apierrors.DefinedError "Репозиторий, merge request или комментарий не найден"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/git/{workspaceSlug}/repositories/{repoName}/merge-requests/{number}/comments/{commentId} [patch]
+func (s *Services) updateMergeRequestComment(c echo.Context) error {
+	user := apicontext.GetContext(c).GetUser()
+	scope, err := s.getGitRepositoryScope(c, true)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	commentId, err := uuid.FromString(c.Param("commentId"))
+	if err != nil {
+		return EErrorDefined(c, apierrors.ErrGitMRCommentNotFound)
+	}
+
+	var req dto.CreateMergeRequestCommentRequest
+	if err := c.Bind(&req); err != nil {
+		slog.ErrorContext(c.Request().Context(), "Failed to bind request", "err", err)
+		return EErrorDefined(c, apierrors.ErrGeneric)
+	}
+	body := types.RedactorHTML{Body: policy.UgcPolicy.Sanitize(req.CommentHtml)}
+	if strings.TrimSpace(body.StripTags()) == "" {
+		return EErrorDefined(c, apierrors.ErrGeneric)
+	}
+
+	var updated MergeRequestComment
+	_, err = updateMergeRequestFromURL(c, scope, func(mr *MergeRequest) error {
+		comment := mr.FindComment(commentId)
+		if comment == nil {
+			return apierrors.ErrGitMRCommentNotFound
+		}
+		if comment.ActorId != user.ID {
+			return apierrors.ErrWorkspaceForbidden
+		}
+		comment.CommentHtml = body
+		comment.UpdatedAt = time.Now()
+		updated = *comment
+		return nil
+	})
+	if err != nil {
+		return EError(c, err)
+	}
+
+	users, err := s.loadUsersByIds(c, []uuid.UUID{user.ID})
+	if err != nil {
+		return EError(c, err)
+	}
+	return c.JSON(http.StatusOK, mergeRequestCommentToDTO(&updated, users))
+}
+
+// @Summary Merge requests (комментарии): удаление
+// @Description Удаляет комментарий. Удалять комментарий может автор или администратор workspace.
+// @Tags GIT-MR
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug workspace"
+// @Param repoName path string true "Имя репозитория"
+// @Param number path int true "Номер merge request'а"
+// @Param commentId path string true "ID комментария"
+// @Success 204 "Комментарий удален"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Недостаточно прав"
+// @Failure 404 {object} apierrors.DefinedError "Репозиторий, merge request или комментарий не найден"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/git/{workspaceSlug}/repositories/{repoName}/merge-requests/{number}/comments/{commentId} [delete]
+func (s *Services) deleteMergeRequestComment(c echo.Context) error {
+	user := apicontext.GetContext(c).GetUser()
+	scope, err := s.getGitRepositoryScope(c, true)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	commentId, err := uuid.FromString(c.Param("commentId"))
+	if err != nil {
+		return EErrorDefined(c, apierrors.ErrGitMRCommentNotFound)
+	}
+
+	_, err = updateMergeRequestFromURL(c, scope, func(mr *MergeRequest) error {
+		comment := mr.FindComment(commentId)
+		if comment == nil {
+			return apierrors.ErrGitMRCommentNotFound
+		}
+		if comment.ActorId != user.ID && !scope.isAdmin(user) {
+			return apierrors.ErrWorkspaceForbidden
+		}
+		mr.Comments = slices.DeleteFunc(mr.Comments, func(cm MergeRequestComment) bool { return cm.Id == commentId })
+		// Ответы на удаленный комментарий становятся самостоятельными комментариями
+		for i := range mr.Comments {
+			if mr.Comments[i].ReplyToCommentId.Valid && mr.Comments[i].ReplyToCommentId.UUID == commentId {
+				mr.Comments[i].ReplyToCommentId = uuid.NullUUID{}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return EError(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// @Summary Merge requests (комментарии): добавление реакции
+// @Description Добавляет реакцию пользователя к комментарию merge request'а
+// @Tags GIT-MR
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug workspace"
+// @Param repoName path string true "Имя репозитория"
+// @Param number path int true "Номер merge request'а"
+// @Param commentId path string true "ID комментария"
+// @Param data body map[string]string true "Реакция (пример: 👍, 👎, ❤️)"
+// @Success 201 {object} dto.CommentReaction "Созданная реакция"
+// @Failure 400 {object} apierrors.DefinedError "Некорректная реакция"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Недостаточно прав"
+// @Failure 404 {object} apierrors.DefinedError "Репозиторий, merge request или комментарий не найден"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/git/{workspaceSlug}/repositories/{repoName}/merge-requests/{number}/comments/{commentId}/reactions [post]
+func (s *Services) addMergeRequestCommentReaction(c echo.Context) error {
+	user := apicontext.GetContext(c).GetUser()
+	scope, err := s.getGitRepositoryScope(c, true)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	commentId, err := uuid.FromString(c.Param("commentId"))
+	if err != nil {
+		return EErrorDefined(c, apierrors.ErrGitMRCommentNotFound)
+	}
+
+	var reactionRequest ReactionRequest
+	if err := c.Bind(&reactionRequest); err != nil {
+		return EError(c, err)
+	}
+	if !validReactions[reactionRequest.Reaction] {
+		return EErrorDefined(c, apierrors.ErrInvalidReaction)
+	}
+
+	var reaction dto.CommentReaction
+	created := false
+	_, err = updateMergeRequestFromURL(c, scope, func(mr *MergeRequest) error {
+		comment := mr.FindComment(commentId)
+		if comment == nil {
+			return apierrors.ErrGitMRCommentNotFound
+		}
+		// Проверяем, есть ли уже такая реакция от пользователя
+		for _, r := range comment.Reactions {
+			if r.UserId == user.ID && r.Reaction == reactionRequest.Reaction {
+				reaction = r
+				return nil
+			}
+		}
+		reaction = dto.CommentReaction{
+			Id:        dao.GenUUID(),
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+			CommentId: commentId,
+			UserId:    user.ID,
+			Reaction:  reactionRequest.Reaction,
+		}
+		comment.Reactions = append(comment.Reactions, reaction)
+		created = true
+		return nil
+	})
+	if err != nil {
+		return EError(c, err)
+	}
+
+	if !created {
+		return c.JSON(http.StatusOK, reaction)
+	}
+	return c.JSON(http.StatusCreated, reaction)
+}
+
+// @Summary Merge requests (комментарии): удаление реакции
+// @Description Удаляет указанную реакцию пользователя с комментария merge request'а
+// @Tags GIT-MR
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug workspace"
+// @Param repoName path string true "Имя репозитория"
+// @Param number path int true "Номер merge request'а"
+// @Param commentId path string true "ID комментария"
+// @Param reaction path string true "Реакция для удаления (пример: 👍, 👎, ❤️)"
+// @Success 204 "Реакция успешно удалена"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Недостаточно прав"
+// @Failure 404 {object} apierrors.DefinedError "Репозиторий, merge request или комментарий не найден"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/git/{workspaceSlug}/repositories/{repoName}/merge-requests/{number}/comments/{commentId}/reactions/{reaction} [delete]
+func (s *Services) removeMergeRequestCommentReaction(c echo.Context) error {
+	user := apicontext.GetContext(c).GetUser()
+	scope, err := s.getGitRepositoryScope(c, true)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	commentId, err := uuid.FromString(c.Param("commentId"))
+	if err != nil {
+		return EErrorDefined(c, apierrors.ErrGitMRCommentNotFound)
+	}
+	reactionStr := strings.TrimSuffix(c.Param("reaction"), "/")
+
+	_, err = updateMergeRequestFromURL(c, scope, func(mr *MergeRequest) error {
+		comment := mr.FindComment(commentId)
+		if comment == nil {
+			return apierrors.ErrGitMRCommentNotFound
+		}
+		comment.Reactions = slices.DeleteFunc(comment.Reactions, func(r dto.CommentReaction) bool {
+			return r.UserId == user.ID && r.Reaction == reactionStr
+		})
+		return nil
+	})
+	if err != nil {
+		return EError(c, err)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ========================================
+// Issue Merge Requests Endpoint
+// ========================================
+
+// getIssueMergeRequestList godoc
+// @id getIssueMergeRequestList
+// @Summary Задачи: merge request'ы задачи
+// @Description Возвращает merge request'ы репозиториев рабочего пространства, связанные с задачей
+// @Tags Issues
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param workspaceSlug path string true "Slug рабочего пространства"
+// @Param projectId path string true "ID проекта"
+// @Param issueIdOrSeq path string true "Идентификатор или последовательный номер задачи"
+// @Param state query string false "Фильтр по состоянию: open, closed, merged, all" default(open)
+// @Success 200 {array} dto.MergeRequestLight "Список merge request'ов"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Доступ запрещен"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/workspaces/{workspaceSlug}/projects/{projectId}/issues/{issueIdOrSeq}/merge-requests [get]
+func (s *Services) getIssueMergeRequestList(c echo.Context) error {
+	apiContext := apicontext.GetContext(c)
+	workspace := apiContext.GetWorkspace()
+	issue := apiContext.GetIssue()
+	if apiContext.Error() != nil {
+		return EError(c, apiContext.Error())
+	}
+
+	result := []dto.MergeRequestLight{}
+	if !cfg.GitEnabled || cfg.GitRepositoriesPath == "" {
+		return c.JSON(http.StatusOK, result)
+	}
+
+	state := c.QueryParam("state")
+	if state == "" {
+		state = MergeRequestStateOpen
+	}
+
+	repos, err := ListGitRepositories(workspace.Slug, cfg.GitRepositoriesPath)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	var found []*MergeRequest
+	var repoNames []string
+	var authorIds []uuid.UUID
+	for _, repo := range repos {
+		mrs, err := ListMergeRequests(repo.Path)
+		if err != nil {
+			slog.WarnContext(c.Request().Context(), "Failed to list merge requests", "repo", repo.Path, "err", err)
+			continue
+		}
+		for _, mr := range mrs {
+			if !mr.LinksIssue(issue.ID) || (state != "all" && mr.State != state) {
+				continue
+			}
+			found = append(found, mr)
+			repoNames = append(repoNames, repo.Name)
+			authorIds = append(authorIds, mr.AuthorID)
+		}
+	}
+
+	users, err := s.loadUsersByIds(c, authorIds)
+	if err != nil {
+		return EError(c, err)
+	}
+	for i, mr := range found {
+		result = append(result, mergeRequestToLightDTO(workspace.Slug, repoNames[i], mr, users))
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
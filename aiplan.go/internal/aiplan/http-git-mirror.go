@@ -0,0 +1,73 @@
+package aiplan
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	apicontext "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/api-context"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
+	"github.com/labstack/echo/v4"
+)
+
+// gitMirrorToDTO конвертирует состояние зеркала в DTO (без учетных данных!)
+func gitMirrorToDTO(mirror *GitMirror) *dto.GitMirrorInfo {
+	if mirror == nil {
+		return nil
+	}
+	return &dto.GitMirrorInfo{
+		URL:            mirror.URL,
+		HasCredentials: mirror.Password != "",
+		LastSyncAt:     mirror.LastSyncAt,
+		LastSuccessAt:  mirror.LastSuccessAt,
+		LastSyncStatus: mirror.LastSyncStatus,
+		LastSyncError:  mirror.LastSyncError,
+	}
+}
+
+// @Summary Зеркала: синхронизировать зеркало
+// @Description Синхронно забирает изменения из удаленного репозитория pull-зеркала и возвращает состояние синхронизации. Доступно участникам workspace
+// @Tags GIT
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param workspaceSlug path string true "Slug workspace"
+// @Param repoName path string true "Имя репозитория"
+// @Success 200 {object} dto.GitMirrorInfo "Состояние зеркала после синхронизации"
+// @Failure 400 {object} apierrors.DefinedError "Репозиторий не является зеркалом"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Недостаточно прав"
+// @Failure 404 {object} apierrors.DefinedError "Репозиторий не найден"
+// @Failure 409 {object} apierrors.DefinedError "Синхронизация уже выполняется"
+// @Router /api/auth/git/{workspaceSlug}/repositories/{repoName}/mirror/sync/ [post]
+func (s *Services) syncGitMirror(c echo.Context) error {
+	user := apicontext.GetContext(c).GetUser()
+
+	scope, err := s.getGitRepositoryScope(c, true)
+	if err != nil {
+		return EError(c, err)
+	}
+	if !IsGitMirror(scope.repoPath) {
+		return EErrorDefined(c, apierrors.ErrGitNotMirror)
+	}
+
+	// Ошибка синхронизации сохраняется в метаданных и возвращается в состоянии зеркала
+	if err := SyncGitMirror(scope.repoPath); err != nil {
+		if errors.Is(err, ErrGitMirrorSyncInProgress) {
+			return EErrorDefined(c, apierrors.ErrGitMirrorSyncInProgress)
+		}
+		slog.WarnContext(c.Request().Context(), "Git mirror sync failed",
+			"workspace", scope.workspace.Slug,
+			"repo", scope.repoName,
+			"user", user.Email,
+			"err", err)
+	}
+
+	repo, err := LoadGitRepository(scope.repoPath)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, gitMirrorToDTO(repo.Mirror))
+}
@@ -7,6 +7,7 @@ package aiplan
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -22,6 +23,7 @@ import (
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+	"github.com/gofrs/uuid"
 	"github.com/labstack/echo/v4"
 	"gorm.io/gorm"
 )
@@ -95,13 +97,14 @@ type BranchesResponseDTO struct {
 
 // RepoInfoDTO представляет информацию о репозитории
 type RepoInfoDTO struct {
-	Name          string     `json:"name"`                  // Имя репозитория
-	Workspace     string     `json:"workspace"`             // Slug workspace
-	DefaultBranch string     `json:"default_branch"`        // Ветка по умолчанию
-	BranchesCount int        `json:"branches_count"`        // Количество веток
-	CommitsCount  int        `json:"commits_count"`         // Количество коммитов
-	Size          int64      `json:"size"`                  // Размер репозитория (байты)
-	LastCommit    *CommitDTO `json:"last_commit,omitempty"` // Последний коммит
+	Name          string             `json:"name"`                  // Имя репозитория
+	Workspace     string             `json:"workspace"`             // Slug workspace
+	DefaultBranch string             `json:"default_branch"`        // Ветка по умолчанию
+	BranchesCount int                `json:"branches_count"`        // Количество веток
+	CommitsCount  int                `json:"commits_count"`         // Количество коммитов
+	Size          int64              `json:"size"`                  // Размер репозитория (байты)
+	LastCommit    *CommitDTO         `json:"last_commit,omitempty"` // Последний коммит
+	Mirror        *dto.GitMirrorInfo `json:"mirror,omitempty"`      // Состояние pull-зеркала (только для зеркал)
 }
 
 // @Summary Конфигурация: получение Git настроек
@@ -127,7 +130,7 @@ func (s *Services) getGitConfig(c echo.Context) error {
 }
 
 // @Summary Репозиторий: создание Git репозитория
-// @Description Создает новый bare Git репозиторий в указанном рабочем пространстве. Метаданные хранятся в файловой системе.
+// @Description Создает новый bare Git репозиторий в указанном рабочем пространстве. Метаданные хранятся в файловой системе. Если указан mirror, создается pull-зеркало внешнего репозитория, первая синхронизация выполняется в фоне.
 // @Tags GIT
 // @Accept json
 // @Produce json
@@ -210,6 +213,14 @@ func (s *Services) createGitRepository(c echo.Context) error {
 		return EErrorDefined(c, apierrors.ErrGitInvalidBranch)
 	}
 
+	// Валидация адреса зеркала
+	if req.Mirror != nil {
+		req.Mirror.URL = strings.TrimSpace(req.Mirror.URL)
+		if err := ValidateMirrorURL(req.Mirror.URL); err != nil {
+			return EErrorDefined(c, apierrors.ErrGitInvalidMirrorURL)
+		}
+	}
+
 	// Создаем путь к репозиторию: {GitRepositoriesPath}/{workspace-slug}/{repo-name}.git
 	repoPath := GetRepositoryPath(workspace.Slug, req.Name, cfg.GitRepositoriesPath)
 
@@ -234,6 +245,22 @@ func (s *Services) createGitRepository(c echo.Context) error {
 		return EErrorDefined(c, apierrors.ErrGitCommandFailed.WithFormattedMessage(string(output)))
 	}
 
+	// Для зеркала настраиваем remote, из которого будут забираться ветки и теги
+	var mirror *GitMirror
+	if req.Mirror != nil {
+		if err := ConfigureGitMirrorRemote(repoPath, req.Mirror.URL); err != nil {
+			slog.ErrorContext(c.Request().Context(), "Failed to configure mirror remote", "path", repoPath, "err", err)
+			os.RemoveAll(repoPath) // Cleanup
+			return EErrorDefined(c, apierrors.ErrGitCommandFailed.WithFormattedMessage("Failed to configure mirror remote"))
+		}
+		mirror = &GitMirror{
+			URL:            req.Mirror.URL,
+			Username:       req.Mirror.Username,
+			Password:       req.Mirror.Password,
+			LastSyncStatus: GitMirrorStatusPending,
+		}
+	}
+
 	// UUID пользователя
 	userUUID := user.ID
 
@@ -246,6 +273,7 @@ func (s *Services) createGitRepository(c echo.Context) error {
 		CreatedAt:   time.Now(),
 		CreatedBy:   userUUID,
 		Branch:      branch,
+		Mirror:      mirror,
 		Path:        repoPath,
 	}
 
@@ -265,6 +293,15 @@ func (s *Services) createGitRepository(c echo.Context) error {
 		}
 	}
 
+	// Первая синхронизация зеркала может занять много времени, поэтому выполняется в фоне
+	if mirror != nil {
+		go func() {
+			if err := SyncGitMirror(repoPath); err != nil {
+				slog.Warn("Initial git mirror sync failed", "workspace", workspace.Slug, "repo", req.Name, "err", err)
+			}
+		}()
+	}
+
 	// Генерируем clone URL
 	cloneURL := fmt.Sprintf("git@%s:%s/%s.git", cfg.WebURL.URL.Host, workspace.Slug, req.Name)
 
@@ -291,6 +328,7 @@ func (s *Services) createGitRepository(c echo.Context) error {
 		CloneURL:    cloneURL,
 		CreatedAt:   gitRepo.CreatedAt,
 		CreatedBy:   creator.ToLightDTO(),
+		Mirror:      gitMirrorToDTO(gitRepo.Mirror),
 	}
 
 	return c.JSON(http.StatusCreated, response)
@@ -397,6 +435,7 @@ func (s *Services) listGitRepositories(c echo.Context) error {
 			CloneURL:    cloneURL,
 			CreatedAt:   repo.CreatedAt,
 			CreatedBy:   repo.CreatedBy.String(),
+			IsMirror:    repo.Mirror != nil,
 		})
 	}
 
@@ -936,6 +975,96 @@ func (s *Services) checkRepositoryAccess(user *dao.User, workspaceSlug, repoName
 	return true, nil
 }
 
+// gitRepositoryScope содержит репозиторий из URL и членство пользователя в его workspace
+type gitRepositoryScope struct {
+	workspace dao.Workspace
+	// member - членство пользователя в workspace (nil, если пользователь не состоит в workspace)
+	member   *dao.WorkspaceMember
+	repoName string
+	repoPath string
+}
+
+// isAdmin проверяет, является ли пользователь администратором workspace или superuser
+func (sc *gitRepositoryScope) isAdmin(user *dao.User) bool {
+	return user.IsSuperuser || (sc.member != nil && sc.member.Role == types.AdminRole)
+}
+
+// getGitRepositoryScope загружает workspace и репозиторий из URL и проверяет права пользователя.
+// Для write = true требуется роль не ниже участника workspace, иначе достаточно прав на чтение репозитория.
+func (s *Services) getGitRepositoryScope(c echo.Context, write bool) (*gitRepositoryScope, error) {
+	user := apicontext.GetContext(c).GetUser()
+	workspaceSlug := c.Param("workspaceSlug")
+	repoName := c.Param("repoName")
+
+	if cfg.GitRepositoriesPath == "" {
+		return nil, apierrors.ErrGitDisabled
+	}
+	if workspaceSlug == "" || repoName == "" {
+		return nil, apierrors.ErrGeneric
+	}
+
+	// Валидация имени репозитория (защита от path traversal)
+	if !ValidateRepositoryName(repoName) {
+		return nil, apierrors.ErrGitInvalidRepositoryName
+	}
+	if !GitRepositoryExists(workspaceSlug, repoName, cfg.GitRepositoriesPath) {
+		return nil, apierrors.ErrGitRepositoryNotFound
+	}
+
+	scope := &gitRepositoryScope{
+		repoName: repoName,
+		repoPath: GetRepositoryPath(workspaceSlug, repoName, cfg.GitRepositoriesPath),
+	}
+
+	if err := s.DB(c).Where("slug = ?", workspaceSlug).First(&scope.workspace).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apierrors.ErrWorkspaceNotFound
+		}
+		return nil, err
+	}
+
+	var member dao.WorkspaceMember
+	if err := s.DB(c).
+		Where("workspace_id = ? AND member_id = ?", scope.workspace.ID, user.ID).
+		First(&member).Error; err == nil {
+		scope.member = &member
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	if write {
+		if !user.IsSuperuser && (scope.member == nil || scope.member.Role < types.MemberRole) {
+			return nil, apierrors.ErrWorkspaceForbidden
+		}
+		return scope, nil
+	}
+
+	hasAccess, err := s.checkRepositoryAccess(user, workspaceSlug, repoName)
+	if err != nil {
+		return nil, err
+	}
+	if !hasAccess {
+		return nil, apierrors.ErrWorkspaceForbidden
+	}
+	return scope, nil
+}
+
+// loadUsersByIds загружает пользователей по UUID и возвращает их облегченные представления в виде map
+func (s *Services) loadUsersByIds(c echo.Context, ids []uuid.UUID) (map[uuid.UUID]*dto.UserLight, error) {
+	result := make(map[uuid.UUID]*dto.UserLight, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+	var users []dao.User
+	if err := s.DB(c).Where("id IN (?)", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for i := range users {
+		result[users[i].ID] = users[i].ToLightDTO()
+	}
+	return result, nil
+}
+
 // ========================================
 // Git Browse API Endpoints
 // ========================================
@@ -1521,6 +1650,11 @@ func (s *Services) getRepositoryInfo(c echo.Context) error {
 		LastCommit:    lastCommit,
 	}
 
+	// Для зеркала добавляем состояние последней синхронизации
+	if gitRepo, err := LoadGitRepository(repoPath); err == nil {
+		response.Mirror = gitMirrorToDTO(gitRepo.Mirror)
+	}
+
 	slog.InfoContext(c.Request().Context(), "Git repository info retrieved",
 		"workspace", workspaceSlug,
 		"repo", repoName,
@@ -1565,6 +1699,33 @@ func (s *Services) AddGitServices(g *echo.Group) {
 	gitEnabledGroup.GET(":workspaceSlug/repositories/:repoName/commits/", s.getRepositoryCommits)
 	gitEnabledGroup.GET(":workspaceSlug/repositories/:repoName/branches/", s.getRepositoryBranches)
 	gitEnabledGroup.GET(":workspaceSlug/repositories/:repoName/info/", s.getRepositoryInfo)
+	gitEnabledGroup.GET(":workspaceSlug/repositories/:repoName/compare/", s.getRepositoryCompare)
+	gitEnabledGroup.GET(":workspaceSlug/repositories/:repoName/raw/", s.getRepositoryRaw)
+	gitEnabledGroup.GET(":workspaceSlug/repositories/:repoName/archive/", s.getRepositoryArchive)
+
+	// Pull-зеркала
+	gitEnabledGroup.POST(":workspaceSlug/repositories/:repoName/mirror/sync/", s.syncGitMirror)
+
+	// Deploy keys endpoints (только для администраторов workspace)
+	gitEnabledGroup.GET(":workspaceSlug/repositories/:repoName/deploy-keys/", s.listDeployKeys)
+	gitEnabledGroup.POST(":workspaceSlug/repositories/:repoName/deploy-keys/", s.addDeployKey)
+	gitEnabledGroup.DELETE(":workspaceSlug/repositories/:repoName/deploy-keys/:keyId", s.deleteDeployKey)
+
+	// Merge requests endpoints
+	gitEnabledGroup.GET(":workspaceSlug/repositories/:repoName/merge-requests/", s.listMergeRequests)
+	gitEnabledGroup.POST(":workspaceSlug/repositories/:repoName/merge-requests/", s.createMergeRequest)
+	gitEnabledGroup.GET(":workspaceSlug/repositories/:repoName/merge-requests/:number/", s.getMergeRequest)
+	gitEnabledGroup.PATCH(":workspaceSlug/repositories/:repoName/merge-requests/:number/", s.updateMergeRequest)
+	gitEnabledGroup.GET(":workspaceSlug/repositories/:repoName/merge-requests/:number/diff/", s.getMergeRequestDiff)
+	gitEnabledGroup.POST(":workspaceSlug/repositories/:repoName/merge-requests/:number/merge/", s.mergeMergeRequest)
+	gitEnabledGroup.POST(":workspaceSlug/repositories/:repoName/merge-requests/:number/approvals/", s.approveMergeRequest)
+	gitEnabledGroup.DELETE(":workspaceSlug/repositories/:repoName/merge-requests/:number/approvals/", s.unapproveMergeRequest)
+	gitEnabledGroup.GET(":workspaceSlug/repositories/:repoName/merge-requests/:number/comments/", s.getMergeRequestCommentList)
+	gitEnabledGroup.POST(":workspaceSlug/repositories/:repoName/merge-requests/:number/comments/", s.createMergeRequestComment)
+	gitEnabledGroup.PATCH(":workspaceSlug/repositories/:repoName/merge-requests/:number/comments/:commentId/", s.updateMergeRequestComment)
+	gitEnabledGroup.DELETE(":workspaceSlug/repositories/:repoName/merge-requests/:number/comments/:commentId/", s.deleteMergeRequestComment)
+	gitEnabledGroup.POST(":workspaceSlug/repositories/:repoName/merge-requests/:number/comments/:commentId/reactions/", s.addMergeRequestCommentReaction)
+	gitEnabledGroup.DELETE(":workspaceSlug/repositories/:repoName/merge-requests/:number/comments/:commentId/reactions/:reaction", s.removeMergeRequestCommentReaction)
 
 	// Repository CRUD endpoints (general routes - MUST be after specific routes)
 	gitEnabledGroup.GET(":workspaceSlug/repositories/", s.listGitRepositories)
@@ -5,18 +5,27 @@
 //   - Получение статуса импорта Jira.
 //   - Отслеживание списка импортов, инициированных пользователем.
 //   - Отмена запущенного импорта Jira.
+//   - Импорт документов из архива Markdown, HTML или экспорта Confluence.
 package aiplan
 
 import (
+	"errors"
+	"io"
 	"net/http"
+	"os"
 
 	apicontext "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/api-context"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
 
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	docs_import "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/docs-import"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/issues-import/entity"
-	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/issues-import/errors"
+	importErrors "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/issues-import/errors"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+	"github.com/aisa-it/aiplan/aiplan.go/pkg/limiter"
+	"github.com/gofrs/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
 	"gorm.io/gorm"
 )
 
@@ -28,6 +37,13 @@ func (s *Services) AddImportServices(g *echo.Group) {
 	jiraGroup.GET("status/", s.getMyImportList)
 	jiraGroup.GET("status/:importId/", s.getJiraImportStatus)
 	jiraGroup.POST("cancel/:importId/", s.cancelJiraImport)
+
+	docsGroup := g.Group("import/docs/", DemoMiddleware)
+
+	docsGroup.POST("start/", s.startDocImport, middleware.BodyLimit("500M"))
+	docsGroup.GET("status/", s.getMyDocImportList)
+	docsGroup.GET("status/:importId/", s.getDocImportStatus)
+	docsGroup.POST("cancel/:importId/", s.cancelDocImport)
 }
 
 type JiraInfoRequest struct {
@@ -64,7 +80,7 @@ func (s *Services) getJiraInfo(c echo.Context) error {
 	info, err := s.importService.GetJiraInfo(req.Username, req.Token, req.JiraURL)
 	if err != nil {
 
-		if err == errors.ErrJiraUnauthorized {
+		if err == importErrors.ErrJiraUnauthorized {
 			return EErrorDefined(c, apierrors.ErrJiraInvalidCredentials)
 		}
 		return EError(c, err)
@@ -143,7 +159,7 @@ func (s *Services) startJiraImport(c echo.Context) error {
 		req.PrioritiesMapping,
 	)
 	if err != nil {
-		if err == errors.ErrJiraUnauthorized {
+		if err == importErrors.ErrJiraUnauthorized {
 			return EErrorDefined(c, apierrors.ErrJiraInvalidCredentials)
 		}
 		return EError(c, err)
@@ -245,3 +261,178 @@ func (s *Services) cancelJiraImport(c echo.Context) error {
 
 	return c.NoContent(http.StatusNoContent)
 }
+
+// startDocImport godoc
+// @id startDocImport
+// @Summary Интеграции (документы): импорт документов из архива
+// @Description Загружает zip-архив с файлами Markdown или HTML (каталоги задают вложенность документов) или HTML-экспорт пространства Confluence
+// @Description и запускает создание документов в рабочем пространстве. Изображения и файлы архива загружаются как вложения, ссылки между страницами заменяются ссылками на документы
+// @Tags Integrations
+// @Security ApiKeyAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param target_workspace_id formData string true "ID рабочего пространства"
+// @Param format formData string false "Формат архива: auto, markdown, html, confluence" default(auto)
+// @Param parent_doc_id formData string false "ID документа, внутри которого создаются документы"
+// @Param file formData file true "Zip-архив"
+// @Success 200 {object} docs_import.ImportStatus "Статус запущенного импорта"
+// @Failure 400 {object} apierrors.DefinedError "Некорректный архив или формат"
+// @Failure 401 {object} apierrors.DefinedError "Неавторизованный доступ"
+// @Failure 403 {object} apierrors.DefinedError "Отсутствие прав для выполнения импорта"
+// @Failure 409 {object} apierrors.DefinedError "Импорт документов уже выполняется"
+// @Failure 500 {object} apierrors.DefinedError "Внутренняя ошибка сервера"
+// @Router /api/auth/import/docs/start [post]
+func (s *Services) startDocImport(c echo.Context) error {
+	user := apicontext.GetContext(c).GetUser()
+
+	if !s.docImportService.CanStartImport(user.ID) {
+		return EErrorDefined(c, apierrors.ErrAlreadyImportingDocs)
+	}
+
+	var workspaceMember dao.WorkspaceMember
+	if err := s.DB(c).
+		Joins("Workspace").
+		Where("workspace_members.workspace_id = ?", c.FormValue("target_workspace_id")).
+		Where("workspace_members.member_id = ?", user.ID).
+		Where("workspace_members.role = ?", types.AdminRole).
+		First(&workspaceMember).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return EErrorDefined(c, apierrors.ErrTargetWorkspaceNotFoundOrNotAdmin)
+		}
+		return EError(c, err)
+	}
+	workspace := workspaceMember.Workspace
+
+	format, err := docs_import.ParseFormat(c.FormValue("format"))
+	if err != nil {
+		return EErrorDefined(c, apierrors.ErrDocImportInvalidFormat)
+	}
+
+	var parentDocId uuid.NullUUID
+	if v := c.FormValue("parent_doc_id"); v != "" {
+		id, err := uuid.FromString(v)
+		if err != nil {
+			return EErrorDefined(c, apierrors.ErrDocNotFound)
+		}
+		if _, err := dao.GetDoc(s.DB(c), workspace.ID, id, workspaceMember); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return EErrorDefined(c, apierrors.ErrDocNotFound)
+			}
+			return EError(c, err)
+		}
+		parentDocId = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	if !limiter.Limiter.CanAddAttachment(workspace.ID) {
+		return EErrorDefined(c, apierrors.ErrAssetsLimitExceed)
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return EErrorDefined(c, apierrors.ErrDocImportInvalidArchive)
+	}
+	src, err := fileHeader.Open()
+	if err != nil {
+		return EError(c, err)
+	}
+	defer src.Close()
+
+	// Архив сохраняется во временный файл: zip требует произвольного доступа, а импорт продолжается после ответа
+	tmp, err := os.CreateTemp("", "doc-import-*.zip")
+	if err != nil {
+		return EError(c, err)
+	}
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return EError(c, err)
+	}
+
+	imp, err := s.docImportService.StartImport(tmp, fileHeader.Filename, format, *user, *workspace, parentDocId)
+	if err != nil {
+		switch err {
+		case docs_import.ErrInvalidArchive:
+			return EErrorDefined(c, apierrors.ErrDocImportInvalidArchive)
+		case docs_import.ErrNoPages:
+			return EErrorDefined(c, apierrors.ErrDocImportNoPages)
+		case docs_import.ErrAlreadyRunning:
+			return EErrorDefined(c, apierrors.ErrAlreadyImportingDocs)
+		}
+		return EError(c, err)
+	}
+
+	return c.JSON(http.StatusOK, imp.GetStatus())
+}
+
+// getMyDocImportList godoc
+// @id getMyDocImportList
+// @Summary Интеграции (документы): получение моих импортов документов
+// @Description Возвращает список импортов документов, запущенных текущим пользователем
+// @Tags Integrations
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Список импортов пользователя"
+// @Failure 401 {object} apierrors.DefinedError "Неавторизованный доступ"
+// @Failure 500 {object} apierrors.DefinedError "Внутренняя ошибка сервера"
+// @Router /api/auth/import/docs/status [get]
+func (s *Services) getMyDocImportList(c echo.Context) error {
+	user := apicontext.GetContext(c).GetUser()
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"imports": s.docImportService.GetUserImports(user.ID),
+	})
+}
+
+// getDocImportStatus godoc
+// @id getDocImportStatus
+// @Summary Интеграции (документы): получение статуса импорта документов
+// @Description Возвращает статус импорта документов по его ID
+// @Tags Integrations
+// @Security ApiKeyAuth
+// @Produce json
+// @Param importId path string true "ID импорта"
+// @Success 200 {object} docs_import.ImportStatus "Статус импорта"
+// @Failure 400 {object} apierrors.DefinedError "Некорректный ID импорта"
+// @Failure 401 {object} apierrors.DefinedError "Неавторизованный доступ"
+// @Failure 404 {object} apierrors.DefinedError "Импорт не найден"
+// @Router /api/auth/import/docs/status/{importId} [get]
+func (s *Services) getDocImportStatus(c echo.Context) error {
+	user := apicontext.GetContext(c).GetUser()
+
+	importId, err := uuid.FromString(c.Param("importId"))
+	if err != nil {
+		return EErrorDefined(c, apierrors.ErrImportIDRequired)
+	}
+
+	status, ok := s.docImportService.GetUserImportStatus(importId, user.ID)
+	if !ok {
+		return c.NoContent(http.StatusNotFound)
+	}
+	return c.JSON(http.StatusOK, status)
+}
+
+// cancelDocImport godoc
+// @id cancelDocImport
+// @Summary Интеграции (документы): отмена импорта документов
+// @Description Отменяет запущенный импорт документов. Уже загруженные файлы удаляются, документы не создаются
+// @Tags Integrations
+// @Security ApiKeyAuth
+// @Param importId path string true "ID импорта"
+// @Success 204 {object} nil "Успешная отмена импорта"
+// @Failure 400 {object} apierrors.DefinedError "Некорректный ID импорта"
+// @Failure 401 {object} apierrors.DefinedError "Неавторизованный доступ"
+// @Failure 404 {object} apierrors.DefinedError "Импорт не найден"
+// @Router /api/auth/import/docs/cancel/{importId} [post]
+func (s *Services) cancelDocImport(c echo.Context) error {
+	user := apicontext.GetContext(c).GetUser()
+
+	importId, err := uuid.FromString(c.Param("importId"))
+	if err != nil {
+		return EErrorDefined(c, apierrors.ErrImportIDRequired)
+	}
+
+	if !s.docImportService.CancelImport(importId, user.ID) {
+		return c.NoContent(http.StatusNotFound)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
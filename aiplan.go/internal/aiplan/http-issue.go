@@ -30,6 +30,7 @@ import (
 
 	apicontext "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/api-context"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/collab"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/export"
 	errStack "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/stack-error"
 	actField "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types/activities"
@@ -97,12 +98,16 @@ func (s *Services) AddIssueServices(g *echo.Group) {
 	issueGroup.PATCH("/issue-links/:linkId/", s.updateIssueLink)
 	issueGroup.DELETE("/issue-links/:linkId/", s.deleteIssueLink)
 
+	issueGroup.GET("/referenced-by/", s.getIssueReferencedBy)
+
 	issueGroup.GET("/history/", s.getIssueHistoryList)
+	issueGroup.GET("/history/diff/", s.getIssueHistoryDiff)
 
 	issueGroup.GET("/comments/", s.getIssueCommentList)
 	issueGroup.POST("/comments/", s.createIssueComment)
 	issueGroup.GET("/comments/:commentId/", s.getIssueComment)
 	issueGroup.GET("/comments/:commentId/history/", s.getIssueCommentUpdateList)
+	issueGroup.GET("/comments/:commentId/history/diff/", s.getIssueCommentHistoryDiff)
 	issueGroup.PATCH("/comments/:commentId/", s.updateIssueComment)
 	issueGroup.DELETE("/comments/:commentId/", s.deleteIssueComment)
 
@@ -121,8 +126,11 @@ func (s *Services) AddIssueServices(g *echo.Group) {
 
 	issueGroup.GET("/pdf/", s.getIssuePdf)
 
+	issueGroup.GET("/merge-requests/", s.getIssueMergeRequestList)
+
 	issueGroup.POST("/description-lock/", s.issueDescriptionLock)
 	issueGroup.POST("/description-unlock/", s.issueDescriptionUnlock)
+	issueGroup.GET("/description-collab/", s.issueDescriptionCollab)
 
 	issueGroup.POST("/pin/", s.issuePin)
 	issueGroup.POST("/unpin/", s.issueUnpin)
@@ -568,6 +576,7 @@ func (s *Services) exportIssueList(c echo.Context) error {
 // @Param workspaceSlug path string true "Slug рабочего пространства"
 // @Param projectId path string true "ID проекта"
 // @Param issueIdOrSeq path string true "Идентификатор или номеру последовательности задачи"
+// @Param format query string false "markdown - дополнительно вернуть описание в формате Markdown (description_markdown)"
 // @Success 200 {object} dto.Issue "Детали задачи"
 // @Failure 204 "Нет контента (пользователь не имеет доступа)"
 // @Failure 400 {object} apierrors.DefinedError "Некорректные параметры запроса"
@@ -581,7 +590,16 @@ func (s *Services) getIssue(c echo.Context) error {
 	if apiContext.Error() != nil {
 		return EError(c, apiContext.Error())
 	}
-	return c.JSON(http.StatusOK, issue.ToDTO())
+
+	res := issue.ToDTO()
+	if wantsMarkdown(c) {
+		md, err := dao.HTMLToMarkdown(issue.DescriptionHtml)
+		if err != nil {
+			return EError(c, err)
+		}
+		res.DescriptionMarkdown = md
+	}
+	return c.JSON(http.StatusOK, res)
 }
 
 // updateIssue godoc
@@ -639,10 +657,27 @@ func (s *Services) updateIssue(c echo.Context) error {
 		}
 	}
 
+	// Описание в Markdown заменяет description_html
+	if val, ok := data["description_markdown"]; ok {
+		delete(data, "description_markdown")
+		if md, ok := val.(string); ok && md != "" {
+			body, doc, err := dao.MarkdownToHTML(s.DB(c), apiCtx.GetWorkspace(), md)
+			if err != nil {
+				return EError(c, err)
+			}
+			data["description_html"] = body
+			data["description_json"] = *doc
+		}
+	}
+
 	if val, ok := data["description_html"]; ok {
 		if val == "" {
 			delete(data, "description_html")
 		} else {
+			if s.collabHub.HasEditors(collab.IssueKey(issue.ID)) {
+				return EErrorDefined(c, apierrors.ErrIssueDescriptionCollabActive)
+			}
+
 			var locked bool
 			if err := s.DB(c).Model(&dao.IssueDescriptionLock{}).
 				Select("EXISTS(?)",
@@ -2231,6 +2266,40 @@ func (s *Services) getIssueHistoryList(c echo.Context) error {
 	return c.JSON(http.StatusOK, result)
 }
 
+// getIssueHistoryDiff godoc
+// @id getIssueHistoryDiff
+// @Summary Задачи: сравнение версий описания задачи
+// @Description Возвращает добавленные, удаленные и измененные блоки описания задачи между двумя изменениями. Исходная версия - описание до изменения from, версия для сравнения - описание после изменения to, по умолчанию текущее. При from = to возвращаются изменения одной правки. Для измененных блоков возвращаются пословные изменения текста
+// @Tags Issues
+// @Security ApiKeyAuth
+// @Produce json
+// @Param workspaceSlug path string true "Slug рабочего пространства"
+// @Param projectId path string true "ID проекта"
+// @Param issueIdOrSeq path string true "Идентификатор или последовательный номер задачи"
+// @Param from query string true "Id изменения описания, описание до которого сравнивается"
+// @Param to query string false "Id изменения описания, описание после которого сравнивается, по умолчанию текущее описание"
+// @Success 200 {object} dto.HistoryDiff "изменения между версиями"
+// @Failure 400 {object} apierrors.DefinedError "Некорректные параметры запроса"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Доступ запрещен"
+// @Failure 404 {object} apierrors.DefinedError "Версия не найдена"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/workspaces/{workspaceSlug}/projects/{projectId}/issues/{issueIdOrSeq}/history/diff/ [get]
+func (s *Services) getIssueHistoryDiff(c echo.Context) error {
+	apiCtx := apicontext.GetContext(c)
+	issue := apiCtx.GetIssue()
+	if apiCtx.Error() != nil {
+		return EError(c, apiCtx.Error())
+	}
+
+	query := s.DB(c).
+		Where("activity_events.project_id = ?", issue.ProjectId).
+		Where("activity_events.issue_id = ?", issue.ID).
+		Where("activity_events.entity_type = ?", types.LayerIssue).
+		Where("activity_events.field = ?", actField.Description.Field.String())
+	return s.issueVersionDiff(c, query, issue.DescriptionHtml)
+}
+
 // ############# Issue comments methods ###################
 
 // getIssueCommentList godoc
@@ -2630,6 +2699,106 @@ func (s *Services) getIssueCommentUpdateList(c echo.Context) error {
 	return c.JSON(http.StatusOK, resp)
 }
 
+// getIssueCommentHistoryDiff godoc
+// @id getIssueCommentHistoryDiff
+// @Summary Задачи (комментарии): сравнение версий комментария к задаче
+// @Description Возвращает добавленные, удаленные и измененные блоки комментария между двумя изменениями. Исходная версия - текст до изменения from, версия для сравнения - текст после изменения to, по умолчанию текущий. При from = to возвращаются изменения одной правки. Для измененных блоков возвращаются пословные изменения текста
+// @Tags Issues
+// @Security ApiKeyAuth
+// @Produce json
+// @Param workspaceSlug path string true "Slug рабочего пространства"
+// @Param projectId path string true "ID проекта"
+// @Param issueIdOrSeq path string true "Идентификатор или последовательный номер задачи"
+// @Param commentId path string true "ID комментария"
+// @Param from query string true "Id изменения комментария, текст до которого сравнивается"
+// @Param to query string false "Id изменения комментария, текст после которого сравнивается, по умолчанию текущий текст"
+// @Success 200 {object} dto.HistoryDiff "изменения между версиями"
+// @Failure 400 {object} apierrors.DefinedError "Некорректные параметры запроса"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Доступ запрещен"
+// @Failure 404 {object} apierrors.DefinedError "Комментарий или версия не найдены"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/workspaces/{workspaceSlug}/projects/{projectId}/issues/{issueIdOrSeq}/comments/{commentId}/history/diff/ [get]
+func (s *Services) getIssueCommentHistoryDiff(c echo.Context) error {
+	apiCtx := apicontext.GetContext(c)
+	issue := apiCtx.GetIssue()
+	if apiCtx.Error() != nil {
+		return EError(c, apiCtx.Error())
+	}
+
+	var comment dao.IssueComment
+	if err := s.DB(c).
+		Where("issue_id = ?", issue.ID).
+		Where("id = ?", c.Param("commentId")).
+		First(&comment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return EErrorDefined(c, apierrors.ErrIssueCommentNotFound)
+		}
+		return EError(c, err)
+	}
+
+	query := s.DB(c).
+		Where("activity_events.project_id = ?", issue.ProjectId).
+		Where("activity_events.issue_id = ?", issue.ID).
+		Where("activity_events.entity_type = ?", types.LayerIssue).
+		Where("activity_events.field = ?", actField.Comment.Field.String()).
+		Where("activity_events.new_identifier = ?", comment.Id)
+	return s.issueVersionDiff(c, query, comment.CommentHtml.Body)
+}
+
+// issueVersionDiff сравнивает версии текста задачи по параметрам from и to. query отбирает изменения текста,
+// current - текущий текст, с которым сравнивается при отсутствии to
+func (s *Services) issueVersionDiff(c echo.Context, query *gorm.DB, current string) error {
+	var fromId, toId uuid.UUID
+	if err := echo.QueryParamsBinder(c).
+		MustTextUnmarshaler("from", &fromId).
+		TextUnmarshaler("to", &toId).
+		BindError(); err != nil {
+		return EErrorDefined(c, apierrors.ErrIssueHistoryBadRequest)
+	}
+
+	getVersion := func(id uuid.UUID) (*dao.ActivityEvent, error) {
+		var activity dao.ActivityEvent
+		if err := query.Session(&gorm.Session{}).
+			Joins("Actor").
+			Where("activity_events.id = ?", id).
+			First(&activity).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, apierrors.ErrIssueVersionNotFound
+			}
+			return nil, err
+		}
+		return &activity, nil
+	}
+
+	from, err := getVersion(fromId)
+	if err != nil {
+		return EError(c, err)
+	}
+	resp := dto.HistoryDiff{From: from.ToHistoryLightDTO()}
+
+	newBody := current
+	if !toId.IsNil() {
+		to, err := getVersion(toId)
+		if err != nil {
+			return EError(c, err)
+		}
+		if to.CreatedAt.Before(from.CreatedAt) {
+			return EErrorDefined(c, apierrors.ErrIssueHistoryBadRequest)
+		}
+		resp.To = to.ToHistoryLightDTO()
+		newBody = to.NewValue
+	}
+
+	d, err := compareBodies(from.OldValue, newBody)
+	if err != nil {
+		return EError(c, err)
+	}
+	resp.Blocks = d.Blocks
+	resp.Stats = d.Stats
+	return c.JSON(http.StatusOK, resp)
+}
+
 // updateIssueComment godoc
 // @id updateIssueComment
 // @Summary Задачи (комментарии): изменение комментария к задаче
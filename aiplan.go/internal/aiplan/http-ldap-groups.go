@@ -0,0 +1,256 @@
+// Синхронизация участников пространств и проектов с группами LDAP.
+//
+// Администратор задает сопоставления групп LDAP пространствам и проектам с ролью. Сопоставления применяются
+// периодической синхронизацией LDAP (maintenance.LdapSynchronizer) и при каждом входе пользователя через LDAP.
+// Предпросмотр показывает изменения, которые внесет синхронизация, без их применения
+package aiplan
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	apicontext "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/api-context"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/utils"
+	"github.com/gofrs/uuid"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+type LdapGroupMappingRequest struct {
+	Group         string        `json:"group"`
+	WorkspaceId   uuid.UUID     `json:"workspace_id"`
+	ProjectId     uuid.NullUUID `json:"project_id" swaggertype:"string"`
+	Role          int           `json:"role"`
+	RemoveMembers bool          `json:"remove_members"`
+}
+
+func (s *Services) addLdapGroupServices(g *echo.Group) {
+	g.GET("group-mappings/", s.getLdapGroupMappings)
+	g.POST("group-mappings/", s.createLdapGroupMapping)
+	g.PATCH("group-mappings/:mappingId/", s.updateLdapGroupMapping)
+	g.DELETE("group-mappings/:mappingId/", s.deleteLdapGroupMapping)
+	g.GET("sync/preview/", s.previewLdapGroupSync)
+	g.POST("sync/", s.runLdapGroupSync)
+}
+
+// syncLdapGroups применяет сопоставления групп LDAP к пользователю при входе. Ошибки синхронизации не мешают входу
+func (s *Services) syncLdapGroups(user *dao.User) {
+	if s.ldapSynchronizer == nil {
+		return
+	}
+	if _, err := s.ldapSynchronizer.SyncGroups([]dao.User{*user}, false); err != nil {
+		slog.Error("Sync LDAP groups on login", "user", user.ID, "err", err)
+	}
+}
+
+// getLdapGroupMappings godoc
+// @id getLdapGroupMappings
+// @Summary LDAP: сопоставления групп
+// @Description Возвращает сопоставления групп LDAP пространствам и проектам
+// @Tags AdminPanel
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {array} dto.LdapGroupMapping "Сопоставления"
+// @Failure 403 {object} apierrors.DefinedError "Ошибка: доступ запрещен"
+// @Router /api/auth/admin/ldap/group-mappings/ [get]
+func (s *Services) getLdapGroupMappings(c echo.Context) error {
+	var mappings []dao.LdapGroupMapping
+	if err := s.DB(c).
+		Preload("Workspace").
+		Preload("Project").
+		Order("created_at").
+		Find(&mappings).Error; err != nil {
+		return EError(c, err)
+	}
+	return c.JSON(http.StatusOK, utils.SliceToSlice(&mappings, func(m *dao.LdapGroupMapping) dto.LdapGroupMapping { return *m.ToDTO() }))
+}
+
+// createLdapGroupMapping godoc
+// @id createLdapGroupMapping
+// @Summary LDAP: создание сопоставления группы
+// @Description Сопоставляет группу LDAP (DN или CN) пространству или проекту с ролью. Применяется при следующей синхронизации или входе пользователя
+// @Tags AdminPanel
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param data body LdapGroupMappingRequest true "Сопоставление"
+// @Success 201 {object} dto.LdapGroupMapping "Созданное сопоставление"
+// @Failure 400 {object} apierrors.DefinedError "Некорректное сопоставление"
+// @Failure 403 {object} apierrors.DefinedError "Ошибка: доступ запрещен"
+// @Router /api/auth/admin/ldap/group-mappings/ [post]
+func (s *Services) createLdapGroupMapping(c echo.Context) error {
+	user := apicontext.GetContext(c).GetUser()
+
+	var req LdapGroupMappingRequest
+	if err := c.Bind(&req); err != nil {
+		return EError(c, err)
+	}
+
+	mapping := dao.LdapGroupMapping{
+		ID:          dao.GenUUID(),
+		CreatedById: uuid.NullUUID{UUID: user.ID, Valid: true},
+	}
+	if err := s.fillLdapGroupMapping(c, &mapping, req); err != nil {
+		return err
+	}
+	if err := s.DB(c).Omit("Workspace", "Project").Create(&mapping).Error; err != nil {
+		return EError(c, err)
+	}
+	return c.JSON(http.StatusCreated, mapping.ToDTO())
+}
+
+// updateLdapGroupMapping godoc
+// @id updateLdapGroupMapping
+// @Summary LDAP: изменение сопоставления группы
+// @Description Изменяет сопоставление группы LDAP
+// @Tags AdminPanel
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param mappingId path string true "ID сопоставления"
+// @Param data body LdapGroupMappingRequest true "Сопоставление"
+// @Success 200 {object} dto.LdapGroupMapping "Сопоставление"
+// @Failure 400 {object} apierrors.DefinedError "Некорректное сопоставление"
+// @Failure 404 {object} apierrors.DefinedError "Сопоставление не найдено"
+// @Router /api/auth/admin/ldap/group-mappings/{mappingId}/ [patch]
+func (s *Services) updateLdapGroupMapping(c echo.Context) error {
+	mapping, err := s.getLdapGroupMapping(c)
+	if err != nil {
+		return err
+	}
+
+	var req LdapGroupMappingRequest
+	if err := c.Bind(&req); err != nil {
+		return EError(c, err)
+	}
+	if err := s.fillLdapGroupMapping(c, mapping, req); err != nil {
+		return err
+	}
+	mapping.UpdatedAt = time.Now()
+	if err := s.DB(c).Omit("Workspace", "Project").Save(mapping).Error; err != nil {
+		return EError(c, err)
+	}
+	return c.JSON(http.StatusOK, mapping.ToDTO())
+}
+
+// deleteLdapGroupMapping godoc
+// @id deleteLdapGroupMapping
+// @Summary LDAP: удаление сопоставления группы
+// @Description Удаляет сопоставление группы LDAP. Участие пользователей, добавленных по сопоставлению, сохраняется
+// @Tags AdminPanel
+// @Security ApiKeyAuth
+// @Param mappingId path string true "ID сопоставления"
+// @Success 200 "Сопоставление удалено"
+// @Failure 404 {object} apierrors.DefinedError "Сопоставление не найдено"
+// @Router /api/auth/admin/ldap/group-mappings/{mappingId}/ [delete]
+func (s *Services) deleteLdapGroupMapping(c echo.Context) error {
+	mapping, err := s.getLdapGroupMapping(c)
+	if err != nil {
+		return err
+	}
+	if err := s.DB(c).Delete(mapping).Error; err != nil {
+		return EError(c, err)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// previewLdapGroupSync godoc
+// @id previewLdapGroupSync
+// @Summary LDAP: предпросмотр синхронизации групп
+// @Description Возвращает изменения участия в пространствах и проектах, которые внесет синхронизация групп LDAP, без их применения
+// @Tags AdminPanel
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {array} dto.LdapMembershipChange "Изменения"
+// @Failure 400 {object} apierrors.DefinedError "LDAP не настроен"
+// @Failure 403 {object} apierrors.DefinedError "Ошибка: доступ запрещен"
+// @Router /api/auth/admin/ldap/sync/preview/ [get]
+func (s *Services) previewLdapGroupSync(c echo.Context) error {
+	return s.ldapGroupSync(c, true)
+}
+
+// runLdapGroupSync godoc
+// @id runLdapGroupSync
+// @Summary LDAP: синхронизация групп
+// @Description Синхронизирует участие пользователей LDAP в пространствах и проектах с их группами и возвращает внесенные изменения
+// @Tags AdminPanel
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {array} dto.LdapMembershipChange "Изменения"
+// @Failure 400 {object} apierrors.DefinedError "LDAP не настроен"
+// @Failure 403 {object} apierrors.DefinedError "Ошибка: доступ запрещен"
+// @Router /api/auth/admin/ldap/sync/ [post]
+func (s *Services) runLdapGroupSync(c echo.Context) error {
+	return s.ldapGroupSync(c, false)
+}
+
+func (s *Services) ldapGroupSync(c echo.Context, dryRun bool) error {
+	if s.ldapSynchronizer == nil {
+		return EErrorDefined(c, apierrors.ErrLdapNotConfigured)
+	}
+	changes, err := s.ldapSynchronizer.SyncAllGroups(dryRun)
+	if err != nil {
+		return EError(c, err)
+	}
+	if changes == nil {
+		changes = []dto.LdapMembershipChange{}
+	}
+	return c.JSON(http.StatusOK, changes)
+}
+
+func (s *Services) getLdapGroupMapping(c echo.Context) (*dao.LdapGroupMapping, error) {
+	mappingId, err := uuid.FromString(c.Param("mappingId"))
+	if err != nil {
+		return nil, EErrorDefined(c, apierrors.ErrLdapGroupMappingNotFound)
+	}
+	var mapping dao.LdapGroupMapping
+	if err := s.DB(c).Where("id = ?", mappingId).First(&mapping).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, EErrorDefined(c, apierrors.ErrLdapGroupMappingNotFound)
+		}
+		return nil, EError(c, err)
+	}
+	return &mapping, nil
+}
+
+// fillLdapGroupMapping проверяет запрос и переносит его в сопоставление
+func (s *Services) fillLdapGroupMapping(c echo.Context, mapping *dao.LdapGroupMapping, req LdapGroupMappingRequest) error {
+	req.Group = strings.TrimSpace(req.Group)
+	if req.Group == "" || req.WorkspaceId.IsNil() || !IsValidRole(req.Role) {
+		return EErrorDefined(c, apierrors.ErrLdapGroupMappingInvalid)
+	}
+
+	var workspace dao.Workspace
+	if err := s.DB(c).Where("id = ?", req.WorkspaceId).First(&workspace).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return EErrorDefined(c, apierrors.ErrWorkspaceNotFound)
+		}
+		return EError(c, err)
+	}
+	mapping.Workspace = &workspace
+	mapping.Project = nil
+
+	if req.ProjectId.Valid {
+		var project dao.Project
+		if err := s.DB(c).Where("id = ?", req.ProjectId.UUID).Where("workspace_id = ?", workspace.ID).First(&project).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return EErrorDefined(c, apierrors.ErrProjectNotFound)
+			}
+			return EError(c, err)
+		}
+		mapping.Project = &project
+	}
+
+	mapping.Group = req.Group
+	mapping.WorkspaceId = req.WorkspaceId
+	mapping.ProjectId = req.ProjectId
+	mapping.Role = req.Role
+	mapping.RemoveMembers = req.RemoveMembers
+	return nil
+}
@@ -114,6 +114,11 @@ func (s *Services) assetsHandler(c echo.Context) error {
 		return c.NoContent(http.StatusNotFound)
 	}
 
+	return s.streamAsset(c, asset.FileAsset)
+}
+
+// streamAsset отдает содержимое файла из хранилища с заголовками кэширования и безопасного отображения
+func (s *Services) streamAsset(c echo.Context, asset dao.FileAsset) error {
 	stats, err := s.storage.GetFileInfo(asset.Id)
 	if err != nil {
 		errResponse := minio.ToErrorResponse(err)
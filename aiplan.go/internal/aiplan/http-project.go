@@ -1818,6 +1818,16 @@ func (s *Services) createIssue(c echo.Context) error {
 		Draft:               issue.Draft,
 	}
 
+	// Описание в Markdown заменяет description_html
+	if issue.DescriptionMarkdown != "" {
+		body, doc, err := dao.MarkdownToHTML(s.DB(c), workspace, issue.DescriptionMarkdown)
+		if err != nil {
+			return EError(c, err)
+		}
+		issueNew.DescriptionHtml = body
+		issueNew.DescriptionJSON = *doc
+	}
+
 	// State flow check
 	if projectMember.Role != types.AdminRole {
 		var state dao.State
@@ -0,0 +1,105 @@
+// Обратные ссылки: где упоминаются задача или документ.
+//
+// Ссылки извлекаются при сохранении описаний задач, документов и комментариев (см. dao.SyncReferences),
+// здесь они отдаются с учетом прав пользователя на источник ссылки
+package aiplan
+
+import (
+	"net/http"
+
+	apicontext "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/api-context"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/utils"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// getIssueReferencedBy godoc
+// @id getIssueReferencedBy
+// @Summary Задачи (ссылки): где упоминается задача
+// @Description Возвращает задачи, документы и комментарии, в тексте которых есть ссылка на задачу. Источники, недоступные пользователю, не возвращаются
+// @Tags Issues
+// @Security ApiKeyAuth
+// @Produce json
+// @Param workspaceSlug path string true "Slug рабочего пространства"
+// @Param projectId path string true "ID проекта"
+// @Param issueIdOrSeq path string true "Идентификатор или последовательный номер задачи"
+// @Success 200 {array} dto.EntityReference "Обратные ссылки"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Доступ запрещен"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/workspaces/{workspaceSlug}/projects/{projectId}/issues/{issueIdOrSeq}/referenced-by/ [get]
+func (s *Services) getIssueReferencedBy(c echo.Context) error {
+	apiContext := apicontext.GetContext(c)
+	issue := apiContext.GetIssue()
+	if apiContext.Error() != nil {
+		return EError(c, apiContext.Error())
+	}
+
+	refs, err := s.referencedBy(c, s.DB(c).Where("target_issue_id = ?", issue.ID))
+	if err != nil {
+		return EError(c, err)
+	}
+	return c.JSON(http.StatusOK, refs)
+}
+
+// getDocReferencedBy godoc
+// @id getDocReferencedBy
+// @Summary Doc: где упоминается документ
+// @Description Возвращает задачи, документы и комментарии, в тексте которых есть ссылка на документ. Источники, недоступные пользователю, не возвращаются
+// @Tags Docs
+// @Security ApiKeyAuth
+// @Produce json
+// @Param workspaceSlug path string true "Slug рабочего пространства"
+// @Param docId path string true "Id документа"
+// @Success 200 {array} dto.EntityReference "Обратные ссылки"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Failure 403 {object} apierrors.DefinedError "Доступ запрещен"
+// @Failure 500 {object} apierrors.DefinedError "Ошибка сервера"
+// @Router /api/auth/workspaces/{workspaceSlug}/doc/{docId}/referenced-by/ [get]
+func (s *Services) getDocReferencedBy(c echo.Context) error {
+	apiContext := apicontext.GetContext(c)
+	doc := apiContext.GetDoc()
+	if apiContext.Error() != nil {
+		return EError(c, apiContext.Error())
+	}
+
+	refs, err := s.referencedBy(c, s.DB(c).Where("target_doc_id = ?", doc.ID))
+	if err != nil {
+		return EError(c, err)
+	}
+	return c.JSON(http.StatusOK, refs)
+}
+
+// referencedBy загружает ссылки по запросу query, оставляя только источники, доступные пользователю:
+// задачи проектов, в которых он состоит, и документы, которые он может читать
+func (s *Services) referencedBy(c echo.Context, query *gorm.DB) ([]dto.EntityReference, error) {
+	apiContext := apicontext.GetContext(c)
+	user := apiContext.GetUser()
+	workspaceMember := apiContext.GetWorkspaceMember()
+	if apiContext.Error() != nil {
+		return nil, apiContext.Error()
+	}
+
+	issues := s.DB(c).Model(&dao.Issue{}).Select("issues.id")
+	docs := s.DB(c).Model(&dao.Doc{}).Select("docs.id").Where("docs.workspace_id = ?", workspaceMember.WorkspaceId)
+	if !user.IsSuperuser {
+		issues = issues.Where("issues.project_id IN (?)",
+			s.DB(c).Model(&dao.ProjectMember{}).Select("project_id").Where("member_id = ?", user.ID))
+		docs = visibleDocsQuery(s.DB(c).Model(&dao.Doc{}).Select("docs.id"), workspaceMember)
+	}
+
+	var refs []dao.EntityReference
+	if err := query.
+		Where("workspace_id = ?", workspaceMember.WorkspaceId).
+		Where("(source_issue_id IS NOT NULL AND source_issue_id IN (?)) OR (source_doc_id IS NOT NULL AND source_doc_id IN (?))", issues, docs).
+		Preload("SourceIssue.State").
+		Preload("SourceDoc.Workspace").
+		Order("created_at desc").
+		Find(&refs).Error; err != nil {
+		return nil, err
+	}
+
+	return utils.SliceToSlice(&refs, func(r *dao.EntityReference) dto.EntityReference { return *r.ToDTO() }), nil
+}
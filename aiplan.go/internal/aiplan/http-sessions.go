@@ -0,0 +1,255 @@
+// Сессии пользователя на устройствах.
+//
+// При входе создается запись dao.UserSession, ее ID передается в access и refresh токенах (claim sid). При обновлении
+// токенов проверяется, что сессия не отозвана и refresh токен актуален для сессии, а время последней активности
+// сессии обновляется. Пользователь видит свои активные сессии и может отозвать любую из них, администратор - сессии
+// любого пользователя. При входе с нового устройства пользователь получает уведомление
+package aiplan
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	apicontext "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/api-context"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/token"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/utils"
+	"github.com/gofrs/uuid"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+// sessionActivityInterval - минимальный интервал обновления времени последней активности сессии
+const sessionActivityInterval = time.Minute
+
+// tokenSessionId возвращает ID сессии из токена. Для токенов, выданных до появления сессий, возвращает uuid.Nil
+func tokenSessionId(tkn *token.Token) uuid.UUID {
+	if tkn == nil || tkn.JWT == nil {
+		return uuid.Nil
+	}
+	claims, ok := tkn.JWT.Claims.(jwt.MapClaims)
+	if !ok {
+		return uuid.Nil
+	}
+	sid, _ := claims["sid"].(string)
+	return uuid.FromStringOrNil(sid)
+}
+
+// currentSessionId возвращает ID сессии, из которой выполнен запрос
+func currentSessionId(auth *apicontext.UserMeta) uuid.UUID {
+	if auth == nil {
+		return uuid.Nil
+	}
+	if id := tokenSessionId(auth.AccessToken); !id.IsNil() {
+		return id
+	}
+	return tokenSessionId(auth.RefreshToken)
+}
+
+// createSession выдает токены доступа для новой сессии пользователя и сохраняет сессию.
+// notify включает проверку устройства и уведомление о входе с нового устройства
+func (s *Services) createSession(c echo.Context, user *dao.User, notify bool) (*token.Token, *token.Token, error) {
+	now := time.Now()
+	session := dao.UserSession{
+		ID:         dao.GenUUID(),
+		CreatedAt:  now,
+		UserId:     user.ID,
+		UserAgent:  c.Request().UserAgent(),
+		Ip:         c.RealIP(),
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(types.RefreshTokenExpiresPeriod),
+	}
+
+	newDevice := false
+	if notify {
+		var err error
+		if newDevice, err = s.isNewDevice(c, user, session.UserAgent); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	accessToken, refreshToken, err := createAccessToken(user.ID, session.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	session.RefreshJti = tokenJti(refreshToken)
+
+	if err := s.DB(c).Omit("User").Create(&session).Error; err != nil {
+		return nil, nil, err
+	}
+
+	if newDevice {
+		s.notificationsService.Tg.NewDeviceLogin(*user, session)
+		if err := s.emailService.NewDeviceLogin(*user, session); err != nil {
+			slog.Error("Send new device login email", "user", user.ID, "err", err)
+		}
+	}
+	return accessToken, refreshToken, nil
+}
+
+// isNewDevice проверяет по сессиям пользователя, что он раньше не входил с этим браузером. Первая сессия
+// пользователя новым устройством не считается. Данные последнего входа пользователя к этому моменту уже
+// обновлены, поэтому для проверки не подходят
+func (s *Services) isNewDevice(c echo.Context, user *dao.User, userAgent string) (bool, error) {
+	var sessions struct {
+		Total int
+		Known int
+	}
+	if err := s.DB(c).Model(&dao.UserSession{}).
+		Select("COUNT(*) AS total, COUNT(CASE WHEN user_agent = ? THEN 1 END) AS known", userAgent).
+		Where("user_id = ?", user.ID).
+		Scan(&sessions).Error; err != nil {
+		return false, err
+	}
+	return sessions.Total > 0 && sessions.Known == 0, nil
+}
+
+// prolongSession выдает новые токены сессии по refresh токену. Токены без сессии получают новую сессию
+func (s *Services) prolongSession(c echo.Context, user *dao.User, refreshToken *token.Token) (*token.Token, *token.Token, error) {
+	sessionId := tokenSessionId(refreshToken)
+	if sessionId.IsNil() {
+		return s.createSession(c, user, false)
+	}
+
+	var session dao.UserSession
+	if err := s.DB(c).Where("id = ?", sessionId).Where("user_id = ?", user.ID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, apierrors.ErrSessionReset
+		}
+		return nil, nil, err
+	}
+	now := time.Now()
+	if !session.Active(now) || session.RefreshJti != tokenJti(refreshToken) {
+		return nil, nil, apierrors.ErrSessionReset
+	}
+
+	accessToken, newRefreshToken, err := createAccessToken(user.ID, session.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.DB(c).Model(&session).UpdateColumns(map[string]any{
+		"refresh_jti":  tokenJti(newRefreshToken),
+		"last_seen_at": now,
+		"ip":           c.RealIP(),
+		"expires_at":   now.Add(types.RefreshTokenExpiresPeriod),
+	}).Error; err != nil {
+		return nil, nil, err
+	}
+	return accessToken, newRefreshToken, nil
+}
+
+// checkSession проверяет, что сессия access токена не отозвана, и обновляет время последней активности сессии
+func (s *Services) checkSession(db *gorm.DB, userId uuid.UUID, sessionId uuid.UUID, ip string) error {
+	var session dao.UserSession
+	if err := db.Select("id", "revoked_at", "expires_at", "last_seen_at", "ip").
+		Where("id = ?", sessionId).
+		Where("user_id = ?", userId).
+		First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return apierrors.ErrSessionReset
+		}
+		return err
+	}
+	now := time.Now()
+	if !session.Active(now) {
+		return apierrors.ErrSessionReset
+	}
+	if now.Sub(session.LastSeenAt) > sessionActivityInterval || session.Ip != ip {
+		return db.Model(&session).UpdateColumns(map[string]any{"last_seen_at": now, "ip": ip}).Error
+	}
+	return nil
+}
+
+func tokenJti(tkn *token.Token) string {
+	if tkn == nil || tkn.JWT == nil {
+		return ""
+	}
+	claims, ok := tkn.JWT.Claims.(jwt.MapClaims)
+	if !ok {
+		return ""
+	}
+	jti, _ := claims["jti"].(string)
+	return jti
+}
+
+// revokeSession отзывает активную сессию пользователя
+func revokeSession(db *gorm.DB, userId uuid.UUID, sessionId uuid.UUID) error {
+	res := db.Model(&dao.UserSession{}).
+		Where("id = ?", sessionId).
+		Where("user_id = ?", userId).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return apierrors.ErrSessionNotFound
+	}
+	return nil
+}
+
+func listUserSessions(db *gorm.DB, userId uuid.UUID, currentId uuid.UUID) ([]dto.UserSession, error) {
+	var sessions []dao.UserSession
+	if err := dao.ActiveUserSessions(db, userId).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return utils.SliceToSlice(&sessions, func(s *dao.UserSession) dto.UserSession { return *s.ToDTO(currentId) }), nil
+}
+
+// getMySessions godoc
+// @id getMySessions
+// @Summary Пользователи (управление доступом): активные сессии
+// @Description Возвращает активные сессии текущего пользователя на устройствах. Текущая сессия отмечена полем current
+// @Tags Users
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {array} dto.UserSession "Сессии"
+// @Failure 401 {object} apierrors.DefinedError "Необходима авторизация"
+// @Router /api/auth/users/me/sessions/ [get]
+func (s *Services) getMySessions(c echo.Context) error {
+	apiContext := apicontext.GetContext(c)
+	sessions, err := listUserSessions(s.DB(c), apiContext.GetUser().ID, currentSessionId(apiContext.GetAuthInfo()))
+	if err != nil {
+		return EError(c, err)
+	}
+	return c.JSON(http.StatusOK, sessions)
+}
+
+// revokeMySession godoc
+// @id revokeMySession
+// @Summary Пользователи (управление доступом): завершение сессии
+// @Description Завершает сессию текущего пользователя на устройстве. Запросы с токенами этой сессии отклоняются
+// @Tags Users
+// @Security ApiKeyAuth
+// @Param sessionId path string true "ID сессии"
+// @Success 200 "Сессия завершена"
+// @Failure 404 {object} apierrors.DefinedError "Сессия не найдена"
+// @Router /api/auth/users/me/sessions/{sessionId}/ [delete]
+func (s *Services) revokeMySession(c echo.Context) error {
+	apiContext := apicontext.GetContext(c)
+	user := apiContext.GetUser()
+
+	sessionId, err := uuid.FromString(c.Param("sessionId"))
+	if err != nil {
+		return EErrorDefined(c, apierrors.ErrSessionNotFound)
+	}
+	if err := revokeSession(s.DB(c), user.ID, sessionId); err != nil {
+		if defined, ok := err.(apierrors.DefinedError); ok {
+			return EErrorDefined(c, defined)
+		}
+		return EError(c, err)
+	}
+
+	if sessionId == currentSessionId(apiContext.GetAuthInfo()) {
+		clearAuthCookies(c)
+	}
+	return c.NoContent(http.StatusOK)
+}
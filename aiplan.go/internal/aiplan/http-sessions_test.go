@@ -0,0 +1,108 @@
+package aiplan
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	apicontext "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/api-context"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/config"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/token"
+	"github.com/gofrs/uuid"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestSessionTokens(t *testing.T) {
+	oldCfg := cfg
+	cfg = &config.Config{SecretKey: "secret"}
+	defer func() { cfg = oldCfg }()
+
+	parse := func(tkn *token.Token) *token.Token {
+		parsed, err := jwt.Parse(tkn.SignedString, func(*jwt.Token) (interface{}, error) { return []byte(cfg.SecretKey), nil })
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &token.Token{JWT: parsed, SignedString: tkn.SignedString}
+	}
+
+	userId, sessionId := dao.GenUUID(), dao.GenUUID()
+	access, refresh, err := createAccessToken(userId, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	access, refresh = parse(access), parse(refresh)
+	if tokenSessionId(access) != sessionId || tokenSessionId(refresh) != sessionId {
+		t.Errorf("Session id not in tokens")
+	}
+	if tokenJti(access) == "" || tokenJti(access) == tokenJti(refresh) {
+		t.Errorf("Unexpected jti: %q %q", tokenJti(access), tokenJti(refresh))
+	}
+
+	// access токен из кеша обновления не разобран, сессия берется из refresh токена
+	meta := &apicontext.UserMeta{AccessToken: &token.Token{SignedString: access.SignedString}, RefreshToken: refresh}
+	if currentSessionId(meta) != sessionId {
+		t.Errorf("Current session not found")
+	}
+	if !currentSessionId(nil).IsNil() {
+		t.Errorf("Session without auth")
+	}
+
+	legacy, _, err := createAccessToken(userId, uuid.Nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id := tokenSessionId(parse(legacy)); !id.IsNil() {
+		t.Errorf("Unexpected session %s", id)
+	}
+}
+
+func TestNewDeviceLogin(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec(`CREATE TABLE user_sessions (
+		id TEXT PRIMARY KEY, created_at DATETIME, user_id TEXT, user_agent TEXT, ip TEXT,
+		last_seen_at DATETIME, expires_at DATETIME, refresh_jti TEXT, revoked_at DATETIME
+	)`).Error; err != nil {
+		t.Fatal(err)
+	}
+	s := &Services{db: db}
+
+	login := func(user *dao.User, userAgent string) bool {
+		req := httptest.NewRequest("POST", "/api/sign-in", nil)
+		req.Header.Set("User-Agent", userAgent)
+		c := echo.New().NewContext(req, httptest.NewRecorder())
+
+		// к проверке устройства finishLogin уже записал данные текущего входа
+		now := time.Now()
+		user.LastLoginTime = &now
+		user.LastLoginUagent = userAgent
+
+		newDevice, err := s.isNewDevice(c, user, userAgent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		session := dao.UserSession{ID: dao.GenUUID(), UserId: user.ID, UserAgent: userAgent, CreatedAt: now, LastSeenAt: now, ExpiresAt: now.Add(time.Hour)}
+		if err := db.Omit("User").Create(&session).Error; err != nil {
+			t.Fatal(err)
+		}
+		return newDevice
+	}
+
+	user := &dao.User{ID: dao.GenUUID()}
+	if login(user, "Firefox") {
+		t.Error("First login notified as new device")
+	}
+	if login(user, "Firefox") {
+		t.Error("Login from known device notified")
+	}
+	if !login(user, "Chrome") {
+		t.Error("Login from new device not notified")
+	}
+}
@@ -0,0 +1,358 @@
+// Вход через OpenID Connect (SSO).
+//
+// Вход выполняется по authorization code flow с PKCE: state, nonce и code verifier сохраняются в подписанной
+// куке на время входа. После проверки id token пользователь находится по email или создается при первом входе,
+// роли в пространствах назначаются по группам пользователя (OIDC_ROLE_MAPPING), после чего выдаются обычные
+// access и refresh токены. Роли по группам только повышаются и не снимаются при следующих входах.
+// При SSO_FORCE вход по паролю доступен только суперпользователям
+package aiplan
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
+	authprovider "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/auth-provider"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	oidcAuthCookie = "oidc_auth"
+	oidcAuthPath   = "/api/sso/oidc/"
+	oidcAuthExpire = 10 * time.Minute
+)
+
+// SSOInfo - доступные способы входа через SSO
+type SSOInfo struct {
+	OIDC    bool   `json:"oidc"`
+	Name    string `json:"name,omitempty"`
+	SSOOnly bool   `json:"sso_only"`
+}
+
+type oidcAuthClaims struct {
+	authprovider.OIDCAuthRequest
+	Next string `json:"next,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func (s *Services) AddSSOServices(g *echo.Group) {
+	g.GET("sso/", s.getSSOInfo)
+	g.GET("sso/oidc/login/", s.oidcLogin)
+	g.GET("sso/oidc/callback/", s.oidcCallback)
+}
+
+// initOIDC подключает OpenID Connect провайдер, если он настроен
+func initOIDC() (*authprovider.OIDCProvider, []authprovider.RoleMapping, error) {
+	if cfg.OIDCIssuerURL == "" {
+		return nil, nil, nil
+	}
+	mapping, err := authprovider.ParseRoleMapping(cfg.OIDCRoleMapping)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	provider, err := authprovider.InitOIDC(ctx, authprovider.OIDCConfig{
+		IssuerURL:    cfg.OIDCIssuerURL,
+		ClientID:     cfg.OIDCClientID,
+		ClientSecret: cfg.OIDCClientSecret,
+		RedirectURL:  cfg.WebURL.URL.ResolveReference(&url.URL{Path: oidcAuthPath + "callback/"}).String(),
+		Scopes:       strings.Fields(cfg.OIDCScopes),
+		GroupsClaim:  cfg.OIDCGroupsClaim,
+	}, nil)
+	return provider, mapping, err
+}
+
+// getSSOInfo godoc
+// @id getSSOInfo
+// @Summary Пользователи (управление доступом): способы входа через SSO
+// @Description Возвращает настроенные способы входа через SSO и признак запрета входа по паролю
+// @Tags Users
+// @Produce json
+// @Success 200 {object} SSOInfo "Способы входа"
+// @Router /api/sso [get]
+func (s *Services) getSSOInfo(c echo.Context) error {
+	info := SSOInfo{OIDC: s.oidcProvider != nil, SSOOnly: cfg.SSOForce && s.oidcProvider != nil}
+	if info.OIDC {
+		info.Name = cfg.OIDCProviderName
+	}
+	return c.JSON(http.StatusOK, info)
+}
+
+// oidcLogin godoc
+// @id oidcLogin
+// @Summary Пользователи (управление доступом): вход через OpenID Connect
+// @Description Перенаправляет пользователя на страницу входа OpenID Connect провайдера
+// @Tags Users
+// @Param next query string false "Путь в приложении для перехода после входа"
+// @Success 302 "Перенаправление к провайдеру"
+// @Failure 404 {object} apierrors.DefinedError "Вход через SSO не настроен"
+// @Router /api/sso/oidc/login [get]
+func (s *Services) oidcLogin(c echo.Context) error {
+	if s.oidcProvider == nil {
+		return EErrorDefined(c, apierrors.ErrSSODisabled)
+	}
+
+	req, err := s.oidcProvider.NewAuthRequest()
+	if err != nil {
+		return EError(c, err)
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, oidcAuthClaims{
+		OIDCAuthRequest: req,
+		Next:            oidcNextPath(c.QueryParam("next")),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(oidcAuthExpire)),
+		},
+	}).SignedString(purposeKey("oidc"))
+	if err != nil {
+		return EError(c, err)
+	}
+
+	setOIDCAuthCookie(c, signed, time.Now().Add(oidcAuthExpire))
+	return c.Redirect(http.StatusFound, s.oidcProvider.AuthCodeURL(req))
+}
+
+// oidcCallback godoc
+// @id oidcCallback
+// @Summary Пользователи (управление доступом): завершение входа через OpenID Connect
+// @Description Принимает код авторизации от провайдера, выдает токены доступа и перенаправляет в приложение. При ошибке перенаправляет на страницу входа с параметром sso_error
+// @Tags Users
+// @Param code query string true "Код авторизации"
+// @Param state query string true "State запроса авторизации"
+// @Success 302 "Перенаправление в приложение"
+// @Router /api/sso/oidc/callback [get]
+func (s *Services) oidcCallback(c echo.Context) error {
+	if s.oidcProvider == nil {
+		return EErrorDefined(c, apierrors.ErrSSODisabled)
+	}
+
+	fail := func(defErr apierrors.DefinedError, err error) error {
+		if err != nil {
+			slog.WarnContext(c.Request().Context(), "OIDC login failed", "err", err)
+		}
+		q := url.Values{"sso_error": {strconv.Itoa(defErr.Code)}}
+		return c.Redirect(http.StatusFound, cfg.WebURL.URL.ResolveReference(&url.URL{Path: "/signin/", RawQuery: q.Encode()}).String())
+	}
+
+	cookie, err := c.Cookie(oidcAuthCookie)
+	setOIDCAuthCookie(c, "", time.Unix(0, 0))
+	if err != nil {
+		return fail(apierrors.ErrSSOFailed, err)
+	}
+	var authClaims oidcAuthClaims
+	if _, err := jwt.ParseWithClaims(cookie.Value, &authClaims, func(t *jwt.Token) (any, error) {
+		return purposeKey("oidc"), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired()); err != nil {
+		return fail(apierrors.ErrSSOFailed, err)
+	}
+
+	if e := c.QueryParam("error"); e != "" {
+		return fail(apierrors.ErrSSOFailed, errors.New(e+": "+c.QueryParam("error_description")))
+	}
+
+	claims, err := s.oidcProvider.Exchange(c.Request().Context(), authClaims.OIDCAuthRequest, c.QueryParam("state"), c.QueryParam("code"))
+	if err != nil {
+		return fail(apierrors.ErrSSOFailed, err)
+	}
+
+	user, err := s.oidcUser(c, claims)
+	if err != nil {
+		var defErr apierrors.DefinedError
+		if errors.As(err, &defErr) {
+			return fail(defErr, nil)
+		}
+		return fail(apierrors.ErrSSOFailed, err)
+	}
+
+	if _, _, err := s.finishLogin(c, user); err != nil {
+		return fail(apierrors.ErrSSOFailed, err)
+	}
+
+	next, err := url.Parse(oidcNextPath(authClaims.Next))
+	if err != nil {
+		next = &url.URL{Path: "/"}
+	}
+	return c.Redirect(http.StatusFound, cfg.WebURL.URL.ResolveReference(next).String())
+}
+
+// oidcUser находит пользователя по sub из id token или создает его при первом входе,
+// затем назначает роли в пространствах по группам пользователя.
+// Существующая учетная запись связывается по подтвержденному email, если она еще не связана с другим sub
+// и не принадлежит суперпользователю
+func (s *Services) oidcUser(c echo.Context, claims *authprovider.OIDCClaims) (*dao.User, error) {
+	var user dao.User
+	err := s.DB(c).Where("oidc_subject = ?", claims.Subject).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = s.DB(c).Where("email = ?", claims.Email).First(&user).Error
+		if err == nil {
+			if user.OIDCSubject != nil || user.IsSuperuser {
+				slog.WarnContext(c.Request().Context(), "Refuse to link OIDC account", "email", claims.Email, "sub", claims.Subject)
+				return nil, apierrors.ErrSSOAccountLink
+			}
+			slog.InfoContext(c.Request().Context(), "Link user to OIDC", "email", claims.Email, "sub", claims.Subject)
+			if err := s.DB(c).Model(&user).Update("oidc_subject", claims.Subject).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		slog.InfoContext(c.Request().Context(), "Create new user from OIDC", "email", claims.Email)
+
+		user = dao.User{
+			ID:              dao.GenUUID(),
+			Email:           claims.Email,
+			Password:        dao.GenPasswordHash(dao.GenPassword()),
+			FirstName:       claims.GivenName,
+			LastName:        claims.FamilyName,
+			Theme:           types.DefaultTheme,
+			IsActive:        true,
+			IsEmailVerified: true,
+			IsOnboarded:     false,
+			AuthProvider:    "oidc",
+			OIDCSubject:     &claims.Subject,
+		}
+		if user.FirstName == "" && user.LastName == "" {
+			user.FirstName, user.LastName, _ = strings.Cut(claims.Name, " ")
+		}
+		if err := s.DB(c).Create(&user).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	if user.BlockedUntil.Valid && user.BlockedUntil.Time.After(time.Now()) {
+		return nil, apierrors.ErrBlockedUntil.WithFormattedMessage(user.BlockedUntil.Time.Format("02.01.2006 15:04"))
+	}
+	if !user.IsActive {
+		return nil, apierrors.ErrLoginTriesExceed
+	}
+	if user.IsIntegration {
+		return nil, apierrors.ErrIntegrationLogin
+	}
+
+	if user.AuthProvider != "oidc" {
+		if err := s.DB(c).Model(&user).Update("auth_provider", "oidc").Error; err != nil {
+			return nil, err
+		}
+	}
+
+	roles := authprovider.WorkspaceRoles(s.oidcRoleMapping, claims.Groups)
+	if len(roles) > 0 {
+		if err := s.DB(c).Transaction(func(tx *gorm.DB) error {
+			return applyWorkspaceRoles(tx, &user, roles)
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return &user, nil
+}
+
+// applyWorkspaceRoles добавляет пользователя в пространства по slug с указанными ролями.
+// Роль существующего участника только повышается, администраторы пространства становятся администраторами его проектов
+func applyWorkspaceRoles(tx *gorm.DB, user *dao.User, roles map[string]int) error {
+	for slug, role := range roles {
+		var workspace dao.Workspace
+		if err := tx.Where("slug = ?", slug).First(&workspace).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				slog.Warn("Workspace from SSO role mapping not found", "slug", slug)
+				continue
+			}
+			return err
+		}
+
+		var member dao.WorkspaceMember
+		err := tx.Where("workspace_id = ?", workspace.ID).Where("member_id = ?", user.ID).First(&member).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			member = dao.WorkspaceMember{
+				ID:                              dao.GenUUID(),
+				WorkspaceId:                     workspace.ID,
+				MemberId:                        user.ID,
+				Role:                            role,
+				NotificationAuthorSettingsEmail: types.DefaultWorkspaceMemberNS,
+				NotificationAuthorSettingsApp:   types.DefaultWorkspaceMemberNS,
+				NotificationAuthorSettingsTG:    types.DefaultWorkspaceMemberNS,
+				NotificationSettingsEmail:       types.DefaultWorkspaceMemberNS,
+				NotificationSettingsApp:         types.DefaultWorkspaceMemberNS,
+				NotificationSettingsTG:          types.DefaultWorkspaceMemberNS,
+			}
+			if err := tx.Omit(clause.Associations).Create(&member).Error; err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case member.Role >= role:
+			continue
+		default:
+			if err := tx.Model(&member).Updates(map[string]any{"role": role, "updated_at": time.Now()}).Error; err != nil {
+				return err
+			}
+		}
+
+		if role != types.AdminRole {
+			continue
+		}
+		var projects []dao.Project
+		if err := tx.Where("workspace_id = ?", workspace.ID).Find(&projects).Error; err != nil {
+			return err
+		}
+		for _, project := range projects {
+			if err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "project_id"}, {Name: "member_id"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{"role": types.AdminRole, "updated_at": time.Now()}),
+			}).Create(&dao.ProjectMember{
+				ID:                              dao.GenUUID(),
+				CreatedAt:                       time.Now(),
+				WorkspaceId:                     workspace.ID,
+				ProjectId:                       project.ID,
+				Role:                            types.AdminRole,
+				MemberId:                        user.ID,
+				ViewProps:                       types.DefaultViewProps,
+				NotificationAuthorSettingsEmail: types.DefaultProjectMemberNS,
+				NotificationAuthorSettingsApp:   types.DefaultProjectMemberNS,
+				NotificationAuthorSettingsTG:    types.DefaultProjectMemberNS,
+				NotificationSettingsEmail:       types.DefaultProjectMemberNS,
+				NotificationSettingsApp:         types.DefaultProjectMemberNS,
+				NotificationSettingsTG:          types.DefaultProjectMemberNS,
+			}).Error; err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// oidcNextPath оставляет только относительный путь внутри приложения, чтобы не допустить перенаправления на чужой сайт
+func oidcNextPath(next string) string {
+	u, err := url.Parse(next)
+	if err != nil || next == "" || u.IsAbs() || u.Host != "" || !strings.HasPrefix(u.Path, "/") || strings.HasPrefix(next, "//") || strings.Contains(next, "\\") {
+		return "/"
+	}
+	return (&url.URL{Path: u.Path, RawQuery: u.RawQuery, Fragment: u.Fragment}).String()
+}
+
+func setOIDCAuthCookie(c echo.Context, value string, expires time.Time) {
+	c.SetCookie(&http.Cookie{
+		Name:     oidcAuthCookie,
+		Value:    value,
+		Path:     oidcAuthPath,
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   cfg.WebURL.URL.Scheme == "https",
+		// Lax: кука отправляется при возврате пользователя от провайдера
+		SameSite: http.SameSiteLaxMode,
+	})
+}
@@ -0,0 +1,21 @@
+package aiplan
+
+import "testing"
+
+func TestOIDCNextPath(t *testing.T) {
+	cases := map[string]string{
+		"":                        "/",
+		"/workspace/issues?id=1":  "/workspace/issues?id=1",
+		"https://evil.com/path":   "/",
+		"//evil.com/path":         "/",
+		"/\\evil.com":             "/",
+		"relative/path":           "/",
+		"javascript:alert(1)":     "/",
+		"/profile/#notifications": "/profile/#notifications",
+	}
+	for next, expected := range cases {
+		if res := oidcNextPath(next); res != expected {
+			t.Errorf("oidcNextPath(%q) = %q, expected %q", next, res, expected)
+		}
+	}
+}
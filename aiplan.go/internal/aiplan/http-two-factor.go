@@ -0,0 +1,495 @@
+// Двухфакторная аутентификация по TOTP.
+//
+// Пользователь подключает приложение-аутентификатор по QR коду (otpauth:// URI) и подтверждает подключение кодом,
+// после чего получает одноразовые коды восстановления. При входе по паролю с включенной 2FA вместо токенов
+// возвращается короткоживущий two_factor_token, токены доступа выдаются после проверки кода или кода восстановления.
+// Если 2FA обязательна (TWO_FACTOR_REQUIRED или настройка пространства, в котором состоит пользователь), а приложение
+// не подключено, подключение выполняется в процессе входа. Вход через SSO не требует второго фактора: его проверяет провайдер
+package aiplan
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	apicontext "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/api-context"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/totp"
+	"github.com/gofrs/uuid"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+const (
+	twoFactorTokenExpire = 5 * time.Minute
+	recoveryCodesCount   = 10
+	totpIssuer           = "AIPlan"
+)
+
+// TwoFactorChallenge - ответ на вход по паролю, если требуется второй фактор
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool `json:"two_factor_required"`
+	// Приложение-аутентификатор не подключено, перед вводом кода нужно выполнить sign-in/2fa/setup/
+	EnrollmentRequired bool   `json:"enrollment_required"`
+	Token              string `json:"two_factor_token"`
+}
+
+// TwoFactorSetup - данные для подключения приложения-аутентификатора
+type TwoFactorSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// TwoFactorRecoveryCodes - новые коды восстановления, показываются пользователю один раз
+type TwoFactorRecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TwoFactorTokenRequest struct {
+	Token string `json:"two_factor_token"`
+}
+
+type TwoFactorLoginRequest struct {
+	Token        string `json:"two_factor_token"`
+	Code         string `json:"code,omitempty"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+type TwoFactorCodeRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recovery_code,omitempty"`
+}
+
+type twoFactorClaims struct {
+	UserId string `json:"user_id"`
+	jwt.RegisteredClaims
+}
+
+// purposeKey возвращает ключ подписи для служебных токенов. Токены, подписанные таким ключом,
+// не принимаются как токены доступа
+func purposeKey(purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(cfg.SecretKey))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
+// twoFactorRequired проверяет, обязательна ли двухфакторная аутентификация для пользователя
+func twoFactorRequired(tx *gorm.DB, user *dao.User) (bool, error) {
+	if cfg.TwoFactorRequired {
+		return true, nil
+	}
+	var required bool
+	err := tx.Raw(`select exists(select 1 from workspace_members wm
+		join workspaces w on w.id = wm.workspace_id
+		where wm.member_id = ? and w.two_factor_required and w.deleted_at is null)`, user.ID).
+		Scan(&required).Error
+	return required, err
+}
+
+func newTwoFactorChallenge(user *dao.User) (*TwoFactorChallenge, error) {
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, twoFactorClaims{
+		UserId: user.ID.String(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(twoFactorTokenExpire)),
+		},
+	}).SignedString(purposeKey("2fa"))
+	if err != nil {
+		return nil, err
+	}
+	return &TwoFactorChallenge{
+		TwoFactorRequired:  true,
+		EnrollmentRequired: !user.TOTPEnabled,
+		Token:              signed,
+	}, nil
+}
+
+// twoFactorTokenUser возвращает пользователя по two_factor_token
+func (s *Services) twoFactorTokenUser(c echo.Context, signed string) (*dao.User, error) {
+	var claims twoFactorClaims
+	if _, err := jwt.ParseWithClaims(signed, &claims, func(t *jwt.Token) (any, error) {
+		return purposeKey("2fa"), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired()); err != nil {
+		return nil, apierrors.ErrTwoFactorTokenInvalid
+	}
+	userId, err := uuid.FromString(claims.UserId)
+	if err != nil {
+		return nil, apierrors.ErrTwoFactorTokenInvalid
+	}
+
+	var user dao.User
+	if err := s.DB(c).Where("id = ?", userId).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apierrors.ErrTwoFactorTokenInvalid
+		}
+		return nil, err
+	}
+	if user.BlockedUntil.Valid && user.BlockedUntil.Time.After(time.Now()) {
+		return nil, apierrors.ErrBlockedUntil.WithFormattedMessage(user.BlockedUntil.Time.Format("02.01.2006 15:04"))
+	}
+	if !user.IsActive {
+		return nil, apierrors.ErrLoginTriesExceed
+	}
+	return &user, nil
+}
+
+// twoFactorSignInSetup godoc
+// @id twoFactorSignInSetup
+// @Summary Пользователи (управление доступом): подключение 2FA при входе
+// @Description Генерирует секрет приложения-аутентификатора для пользователя, которому 2FA обязательна, но еще не подключена
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param data body TwoFactorTokenRequest true "Токен второго шага входа"
+// @Success 200 {object} TwoFactorSetup "Данные для подключения"
+// @Failure 400 {object} apierrors.DefinedError "2FA уже подключена"
+// @Failure 401 {object} apierrors.DefinedError "Неверный токен второго шага входа"
+// @Router /api/sign-in/2fa/setup [post]
+func (s *Services) twoFactorSignInSetup(c echo.Context) error {
+	var req TwoFactorTokenRequest
+	if err := c.Bind(&req); err != nil {
+		return EError(c, err)
+	}
+	user, err := s.twoFactorTokenUser(c, req.Token)
+	if err != nil {
+		return EError(c, err)
+	}
+	if user.TOTPEnabled {
+		return EErrorDefined(c, apierrors.ErrTwoFactorAlreadyEnabled)
+	}
+
+	setup, err := s.newTwoFactorSecret(c, user)
+	if err != nil {
+		return EError(c, err)
+	}
+	return c.JSON(http.StatusOK, setup)
+}
+
+// twoFactorSignIn godoc
+// @id twoFactorSignIn
+// @Summary Пользователи (управление доступом): второй шаг входа
+// @Description Проверяет код приложения-аутентификатора или код восстановления и выдает токены доступа. При подключении 2FA в процессе входа в ответе возвращаются коды восстановления
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param data body TwoFactorLoginRequest true "Токен второго шага входа и код"
+// @Success 200 {object} map[string]interface{} "Токены доступа и информация о пользователе"
+// @Failure 400 {object} apierrors.DefinedError "Подключение 2FA не начато"
+// @Failure 401 {object} apierrors.DefinedError "Неверный код или токен второго шага входа"
+// @Router /api/sign-in/2fa [post]
+func (s *Services) twoFactorSignIn(c echo.Context) error {
+	var req TwoFactorLoginRequest
+	if err := c.Bind(&req); err != nil {
+		return EError(c, err)
+	}
+	user, err := s.twoFactorTokenUser(c, req.Token)
+	if err != nil {
+		return EError(c, err)
+	}
+
+	if !user.TOTPEnabled {
+		// подключение 2FA в процессе входа
+		if user.TOTPSecret == "" {
+			return EErrorDefined(c, apierrors.ErrTwoFactorSetupRequired)
+		}
+		codes, err := s.enableTwoFactor(c, user, req.Code)
+		if err != nil {
+			if errors.Is(err, apierrors.ErrTwoFactorCodeInvalid) {
+				return s.failedLogin(c, user)
+			}
+			return EError(c, err)
+		}
+		return s.loginResponse(c, user, map[string]interface{}{"recovery_codes": codes})
+	}
+
+	ok, err := s.checkTwoFactorCode(c, user, req.Code, req.RecoveryCode)
+	if err != nil {
+		return EError(c, err)
+	}
+	if !ok {
+		return s.failedLogin(c, user)
+	}
+	return s.loginResponse(c, user, nil)
+}
+
+// setupTwoFactor godoc
+// @id setupTwoFactor
+// @Summary Пользователи: подключение 2FA
+// @Description Генерирует новый секрет приложения-аутентификатора. 2FA включается после подтверждения кодом
+// @Tags Users
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} TwoFactorSetup "Данные для подключения"
+// @Failure 400 {object} apierrors.DefinedError "2FA уже подключена"
+// @Router /api/auth/users/me/2fa/setup [post]
+func (s *Services) setupTwoFactor(c echo.Context) error {
+	user := apicontext.GetContext(c).GetUser()
+	if user.TOTPEnabled {
+		return EErrorDefined(c, apierrors.ErrTwoFactorAlreadyEnabled)
+	}
+	setup, err := s.newTwoFactorSecret(c, user)
+	if err != nil {
+		return EError(c, err)
+	}
+	return c.JSON(http.StatusOK, setup)
+}
+
+// enableTwoFactorMe godoc
+// @id enableTwoFactorMe
+// @Summary Пользователи: включение 2FA
+// @Description Подтверждает подключение приложения-аутентификатора кодом и возвращает коды восстановления
+// @Tags Users
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param data body TwoFactorCodeRequest true "Код из приложения"
+// @Success 200 {object} TwoFactorRecoveryCodes "Коды восстановления"
+// @Failure 400 {object} apierrors.DefinedError "Подключение 2FA не начато или 2FA уже подключена"
+// @Failure 401 {object} apierrors.DefinedError "Неверный код"
+// @Router /api/auth/users/me/2fa/enable [post]
+func (s *Services) enableTwoFactorMe(c echo.Context) error {
+	user := apicontext.GetContext(c).GetUser()
+	var req TwoFactorCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return EError(c, err)
+	}
+	if user.TOTPEnabled {
+		return EErrorDefined(c, apierrors.ErrTwoFactorAlreadyEnabled)
+	}
+	if user.TOTPSecret == "" {
+		return EErrorDefined(c, apierrors.ErrTwoFactorSetupRequired)
+	}
+
+	codes, err := s.enableTwoFactor(c, user, req.Code)
+	if err != nil {
+		return EError(c, err)
+	}
+	return c.JSON(http.StatusOK, TwoFactorRecoveryCodes{RecoveryCodes: codes})
+}
+
+// disableTwoFactor godoc
+// @id disableTwoFactor
+// @Summary Пользователи: отключение 2FA
+// @Description Отключает 2FA после проверки кода приложения или кода восстановления. Недоступно, если 2FA обязательна для пользователя
+// @Tags Users
+// @Security ApiKeyAuth
+// @Accept json
+// @Param data body TwoFactorCodeRequest true "Код из приложения или код восстановления"
+// @Success 200 "2FA отключена"
+// @Failure 400 {object} apierrors.DefinedError "2FA не подключена"
+// @Failure 401 {object} apierrors.DefinedError "Неверный код или учетная запись заблокирована после неудачных попыток"
+// @Failure 403 {object} apierrors.DefinedError "2FA обязательна"
+// @Router /api/auth/users/me/2fa/disable [post]
+func (s *Services) disableTwoFactor(c echo.Context) error {
+	user := apicontext.GetContext(c).GetUser()
+	var req TwoFactorCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return EError(c, err)
+	}
+	if !user.TOTPEnabled {
+		return EErrorDefined(c, apierrors.ErrTwoFactorNotEnabled)
+	}
+	required, err := twoFactorRequired(s.DB(c), user)
+	if err != nil {
+		return EError(c, err)
+	}
+	if required {
+		return EErrorDefined(c, apierrors.ErrTwoFactorRequired)
+	}
+
+	if err := s.verifyTwoFactorCode(c, user, req.Code, req.RecoveryCode); err != nil {
+		return EError(c, err)
+	}
+
+	if err := resetTwoFactor(s.DB(c), user); err != nil {
+		return EError(c, err)
+	}
+	return c.NoContent(http.StatusOK)
+}
+
+// regenerateRecoveryCodes godoc
+// @id regenerateRecoveryCodes
+// @Summary Пользователи: новые коды восстановления 2FA
+// @Description Заменяет коды восстановления новыми после проверки кода приложения
+// @Tags Users
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param data body TwoFactorCodeRequest true "Код из приложения"
+// @Success 200 {object} TwoFactorRecoveryCodes "Коды восстановления"
+// @Failure 400 {object} apierrors.DefinedError "2FA не подключена"
+// @Failure 401 {object} apierrors.DefinedError "Неверный код или учетная запись заблокирована после неудачных попыток"
+// @Router /api/auth/users/me/2fa/recovery-codes [post]
+func (s *Services) regenerateRecoveryCodes(c echo.Context) error {
+	user := apicontext.GetContext(c).GetUser()
+	var req TwoFactorCodeRequest
+	if err := c.Bind(&req); err != nil {
+		return EError(c, err)
+	}
+	if !user.TOTPEnabled {
+		return EErrorDefined(c, apierrors.ErrTwoFactorNotEnabled)
+	}
+	if err := s.verifyTwoFactorCode(c, user, req.Code, ""); err != nil {
+		return EError(c, err)
+	}
+
+	var codes []string
+	if err := s.DB(c).Transaction(func(tx *gorm.DB) (err error) {
+		codes, err = replaceRecoveryCodes(tx, user)
+		return err
+	}); err != nil {
+		return EError(c, err)
+	}
+	return c.JSON(http.StatusOK, TwoFactorRecoveryCodes{RecoveryCodes: codes})
+}
+
+func (s *Services) newTwoFactorSecret(c echo.Context, user *dao.User) (*TwoFactorSetup, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.DB(c).Model(user).Updates(map[string]any{"totp_secret": secret, "totp_last_step": 0}).Error; err != nil {
+		return nil, err
+	}
+	user.TOTPSecret = secret
+	return &TwoFactorSetup{Secret: secret, URI: totp.ProvisioningURI(secret, totpIssuer, user.Email)}, nil
+}
+
+// enableTwoFactor проверяет код по новому секрету, включает 2FA и возвращает коды восстановления
+func (s *Services) enableTwoFactor(c echo.Context, user *dao.User, code string) ([]string, error) {
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+	if !ok {
+		return nil, apierrors.ErrTwoFactorCodeInvalid
+	}
+
+	var codes []string
+	if err := s.DB(c).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]any{"totp_enabled": true, "totp_last_step": step}).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, user)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	user.TOTPEnabled = true
+	user.TOTPLastStep = step
+	return codes, nil
+}
+
+// checkTwoFactorCode проверяет код приложения или код восстановления. Использованный код восстановления
+// больше не принимается, принятый код приложения нельзя использовать повторно
+func (s *Services) checkTwoFactorCode(c echo.Context, user *dao.User, code, recoveryCode string) (bool, error) {
+	if code != "" {
+		step, ok := totp.Validate(user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+		if !ok {
+			return false, nil
+		}
+		// шаг обновляется условно, чтобы один код не был принят двумя параллельными запросами
+		res := s.DB(c).Model(&dao.User{}).
+			Where("id = ?", user.ID).
+			Where("totp_last_step < ?", step).
+			Update("totp_last_step", step)
+		if res.Error != nil {
+			return false, res.Error
+		}
+		user.TOTPLastStep = step
+		return res.RowsAffected > 0, nil
+	}
+
+	if recoveryCode == "" {
+		return false, nil
+	}
+	res := s.DB(c).Model(&dao.UserRecoveryCode{}).
+		Where("user_id = ?", user.ID).
+		Where("code_hash = ?", hashRecoveryCode(recoveryCode)).
+		Where("used_at IS NULL").
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// verifyTwoFactorCode проверяет код для действий с 2FA в активной сессии. Неверный код учитывается в счетчике
+// неудачных попыток входа, чтобы код нельзя было подобрать из украденной сессии
+func (s *Services) verifyTwoFactorCode(c echo.Context, user *dao.User, code, recoveryCode string) error {
+	if userBlocked(user) {
+		return apierrors.ErrBlockedUntil.WithFormattedMessage(user.BlockedUntil.Time.Format("02.01.2006 15:04"))
+	}
+	ok, err := s.checkTwoFactorCode(c, user, code, recoveryCode)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if err := s.countFailedAttempt(c, user); err != nil {
+			return err
+		}
+		return apierrors.ErrTwoFactorCodeInvalid
+	}
+	if user.LoginAttempts > 0 {
+		user.LoginAttempts = 0
+		return s.DB(c).Model(user).Select("LoginAttempts").Updates(user).Error
+	}
+	return nil
+}
+
+// replaceRecoveryCodes удаляет старые коды восстановления пользователя и создает новые
+func replaceRecoveryCodes(tx *gorm.DB, user *dao.User) ([]string, error) {
+	if err := tx.Where("user_id = ?", user.ID).Delete(&dao.UserRecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodesCount)
+	records := make([]dao.UserRecoveryCode, 0, recoveryCodesCount)
+	for range recoveryCodesCount {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		records = append(records, dao.UserRecoveryCode{
+			ID:       dao.GenUUID(),
+			UserId:   user.ID,
+			CodeHash: hashRecoveryCode(code),
+		})
+	}
+	return codes, tx.Create(&records).Error
+}
+
+// resetTwoFactor отключает 2FA пользователя и удаляет коды восстановления
+func resetTwoFactor(tx *gorm.DB, user *dao.User) error {
+	return tx.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]any{"totp_enabled": false, "totp_secret": "", "totp_last_step": 0}).Error; err != nil {
+			return err
+		}
+		user.TOTPEnabled = false
+		user.TOTPSecret = ""
+		return tx.Where("user_id = ?", user.ID).Delete(&dao.UserRecoveryCode{}).Error
+	})
+}
+
+// generateRecoveryCode генерирует код восстановления вида xxxxx-xxxxx
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
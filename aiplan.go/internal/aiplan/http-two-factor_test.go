@@ -0,0 +1,66 @@
+package aiplan
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/config"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRecoveryCodes(t *testing.T) {
+	seen := map[string]bool{}
+	for range 20 {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(code) != 11 || code[5] != '-' || seen[code] {
+			t.Fatalf("Unexpected code %q", code)
+		}
+		seen[code] = true
+
+		// код принимается без дефиса и в любом регистре
+		if hashRecoveryCode(code) != hashRecoveryCode(" "+strings.ToUpper(strings.ReplaceAll(code, "-", ""))) {
+			t.Errorf("Code %q not normalized", code)
+		}
+	}
+}
+
+func TestTwoFactorChallenge(t *testing.T) {
+	oldCfg := cfg
+	cfg = &config.Config{SecretKey: "secret"}
+	defer func() { cfg = oldCfg }()
+
+	user := &dao.User{ID: dao.GenUUID()}
+	challenge, err := newTwoFactorChallenge(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !challenge.EnrollmentRequired {
+		t.Error("Enrollment not required for user without 2FA")
+	}
+
+	// токен второго шага не должен приниматься как токен доступа
+	if _, err := jwt.Parse(challenge.Token, func(*jwt.Token) (any, error) { return []byte(cfg.SecretKey), nil }); err == nil {
+		t.Error("Two-factor token signed with access token key")
+	}
+	if _, err := jwt.Parse(challenge.Token, func(*jwt.Token) (any, error) { return purposeKey("oidc"), nil }); err == nil {
+		t.Error("Two-factor token signed with OIDC key")
+	}
+}
+
+func TestVerifyTwoFactorCodeBlocked(t *testing.T) {
+	// заблокированный после подбора пользователь не может проверять коды даже из активной сессии
+	user := &dao.User{ID: dao.GenUUID(), TOTPEnabled: true, BlockedUntil: sql.NullTime{Valid: true, Time: time.Now().Add(time.Minute)}}
+	err := (&Services{}).verifyTwoFactorCode(nil, user, "123456", "")
+	var defined apierrors.DefinedError
+	if !errors.As(err, &defined) || defined.Code != apierrors.ErrBlockedUntil.Code {
+		t.Fatalf("Expected ErrBlockedUntil, got %v", err)
+	}
+}
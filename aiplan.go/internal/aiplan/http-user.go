@@ -78,8 +78,18 @@ func (s *Services) AddUserServices(g *echo.Group) {
 
 	g.GET("users/me/all/projects/", s.getCurrentUserAllProjectList)
 
+	g.POST("users/me/2fa/setup/", s.setupTwoFactor)
+	g.POST("users/me/2fa/enable/", s.enableTwoFactorMe)
+	g.POST("users/me/2fa/disable/", s.disableTwoFactor)
+	g.POST("users/me/2fa/recovery-codes/", s.regenerateRecoveryCodes)
+
 	g.GET("users/me/token/", s.getMyAuthToken)
 	g.POST("users/me/token/reset/", s.resetMyAuthToken)
+	g.GET("users/me/tokens/", s.getMyAccessTokens)
+	g.POST("users/me/tokens/", s.createMyAccessToken)
+	g.DELETE("users/me/tokens/:tokenId/", s.revokeMyAccessToken)
+	g.GET("users/me/sessions/", s.getMySessions)
+	g.DELETE("users/me/sessions/:sessionId/", s.revokeMySession)
 
 	g.POST("sign-out/", s.signOut)
 	g.POST("sign-out-everywhere/", s.signOutEverywhere)
@@ -1047,6 +1057,12 @@ func (s *Services) signOut(c echo.Context) error {
 			return EError(c, err)
 		}
 
+		if sessionId := currentSessionId(auth); !sessionId.IsNil() {
+			if err := revokeSession(s.DB(c), u.ID, sessionId); err != nil && err != apierrors.ErrSessionNotFound {
+				return EError(c, err)
+			}
+		}
+
 		if err := s.memDB.BlacklistToken(auth.AccessToken.JWT.Signature); err != nil {
 			return EError(c, err)
 		}
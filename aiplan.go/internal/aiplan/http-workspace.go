@@ -262,7 +262,7 @@ func (s *Services) updateWorkspace(c echo.Context) error {
 	}
 
 	if err := s.DB(c).Transaction(func(tx *gorm.DB) error {
-		if err := tx.Select([]string{"name", "description", "company_size", "owner_id"}).Updates(&workspace).Error; err != nil {
+		if err := tx.Select([]string{"name", "description", "company_size", "owner_id", "two_factor_required"}).Updates(&workspace).Error; err != nil {
 			return err
 		}
 		return nil
@@ -438,6 +438,7 @@ func (s *Services) deleteWorkspace(c echo.Context) error {
 	if err := s.importService.CancelWorkspaceImports(workspace.ID); err != nil {
 		return EError(c, err)
 	}
+	s.docImportService.CancelWorkspaceImports(workspace.ID)
 
 	if err := s.business.DeleteWorkspace(user, workspace); err != nil {
 		return EError(c, err)
@@ -45,6 +45,7 @@ import (
 	authprovider "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/auth-provider"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/business"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/cache"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/collab"
 	jitsi_token "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/jitsi-token"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/mcp"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/migration"
@@ -68,6 +69,7 @@ import (
 	tracker "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/activity-tracker"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/config"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	docs_import "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/docs-import"
 	filestorage "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/file-storage"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/integrations"
 	issues_import "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/issues-import"
@@ -103,13 +105,21 @@ type Services struct {
 	memDB               *mem.AIPlanMemAPI
 	integrationsService *integrations.IntegrationsService
 	importService       *issues_import.ImportService
+	docImportService    *docs_import.DocImportService
 	jitsiTokenIss       *jitsi_token.JitsiTokenIssuer
 	authProvider        *authprovider.LdapProvider
+	ldapSynchronizer    *maintenance.LdapSynchronizer
+	oidcProvider        *authprovider.OIDCProvider
+	oidcRoleMapping     []authprovider.RoleMapping
 
 	notificationsService *notifications.Notification
 
 	business    *business.Business
 	tokensCache *tokenscache.TokensCache
+	collabHub   *collab.Hub
+
+	docShareLimiter   *RateLimiter
+	formAnswerLimiter *RateLimiter
 }
 
 // DB возвращает *gorm.DB, привязанный к контексту HTTP-запроса.
@@ -253,6 +263,7 @@ func Server(db *gorm.DB, c *config.Config, version string) {
 			cfg.LDAPBindPassword,
 			cfg.LDAPBaseDN,
 			cfg.LDAPFilter,
+			cfg.LDAPGroupAttribute,
 		)
 		if err != nil {
 			slog.Error("Connect to LDAP server", "err", err)
@@ -260,6 +271,12 @@ func Server(db *gorm.DB, c *config.Config, version string) {
 		}
 	}
 
+	oidcProvider, oidcRoleMapping, err := initOIDC()
+	if err != nil {
+		slog.Error("Connect to OIDC provider", "err", err)
+		os.Exit(1)
+	}
+
 	migration.New(db).Run()
 
 	jobRegistry := cronmanager.JobRegistry{
@@ -294,6 +311,22 @@ func Server(db *gorm.DB, c *config.Config, version string) {
 		},
 	}
 
+	var ldapSynchronizer *maintenance.LdapSynchronizer
+	if ldapProvider != nil {
+		ldapSynchronizer = maintenance.NewLdapSynchronizer(db, ldapProvider, bl)
+		jobRegistry["ldap_groups_sync"] = cronmanager.Job{
+			Func:     ldapSynchronizer.SyncGroupsJob,
+			Schedule: cfg.LDAPSyncSchedule,
+		}
+	}
+
+	if cfg.GitEnabled && cfg.GitRepositoriesPath != "" {
+		jobRegistry["git_mirrors_sync"] = cronmanager.Job{
+			Func:     NewGitMirrorSyncer(cfg.GitRepositoriesPath).SyncAll,
+			Schedule: "*/10 * * * *", // every 10 minutes
+		}
+	}
+
 	// Create CronManager
 	cronManager := cronmanager.NewCronManager(jobRegistry)
 	if err := cronManager.LoadJobs(); err != nil {
@@ -308,11 +341,18 @@ func Server(db *gorm.DB, c *config.Config, version string) {
 		emailService:         es,
 		memDB:                memDB,
 		importService:        issues_import.NewImportService(db, storage, es),
+		docImportService:     docs_import.NewDocImportService(db, storage),
 		notificationsService: ns,
 		business:             bl,
 		jitsiTokenIss:        jitsi_token.NewJitsiTokenIssuer(cfg.JitsiJWTSecret, cfg.JitsiAppID),
 		authProvider:         ldapProvider,
+		ldapSynchronizer:     ldapSynchronizer,
+		oidcProvider:         oidcProvider,
+		oidcRoleMapping:      oidcRoleMapping,
 		tokensCache:          tokenscache.NewTokensCache(),
+		collabHub:            collab.NewHub(cfg.WebURL.URL.Host),
+		docShareLimiter:      NewRateLimiter(10, time.Minute),
+		formAnswerLimiter:    NewRateLimiter(0, time.Hour),
 	}
 
 	// Start cronManager
@@ -330,6 +370,7 @@ func Server(db *gorm.DB, c *config.Config, version string) {
 	if cfg.GitEnabled && cfg.SSHEnabled {
 		sshServerConfig := SSHServerConfig{
 			DB:               db,
+			Business:         bl,
 			GitReposPath:     cfg.GitRepositoriesPath,
 			Host:             cfg.SSHHost,
 			Port:             cfg.SSHPort,
@@ -376,6 +417,11 @@ func Server(db *gorm.DB, c *config.Config, version string) {
 			}
 		}
 
+		// Сохраняем сессии совместного редактирования до остановки уведомлений
+		s.collabHub.Shutdown(shutdownCtx)
+		s.docShareLimiter.Stop()
+		s.formAnswerLimiter.Stop()
+
 		cronManager.Stop()
 		np.Stop()
 		es.Stop()
@@ -405,6 +451,7 @@ func Server(db *gorm.DB, c *config.Config, version string) {
 				c.Path() == "/api/auth/workspaces/:workspaceSlug/projects/:projectId/issues/:issueIdOrSeq/comments/:commentId/" ||
 				c.Path() == "/api/auth/forms/:formSlug/form-attachments/" ||
 				c.Path() == "/api/auth/users/me/avatar/" ||
+				c.Path() == "/api/auth/import/docs/start/" ||
 				strings.Contains(c.Path(), "/api/auth/issue-attachments/tus/") ||
 				strings.Contains(c.Path(), "/api/auth/attachments/tus/")
 
@@ -498,6 +545,7 @@ func Server(db *gorm.DB, c *config.Config, version string) {
 	apiGroup.GET("docsIndex/", NewHelpIndex("aiplan-help/"))
 
 	s.AddAuthenticationServices(apiGroup, []byte(cfg.SecretKey))
+	s.AddSSOServices(apiGroup)
 	s.AddFormServices(authGroup)
 	s.AddProjectServices(authGroup)
 	s.AddWorkspaceServices(authGroup)
@@ -515,6 +563,7 @@ func Server(db *gorm.DB, c *config.Config, version string) {
 	// services without auth
 	s.AddUserWithoutAuthServices(apiGroup)
 	s.AddFormWithoutAuthServices(apiGroup)
+	s.AddDocShareWithoutAuthServices(apiGroup)
 
 	// Version endpoint
 	apiGroup.GET("version/", func(c echo.Context) error {
@@ -636,14 +685,14 @@ func checkPassword(password string, pass string) bool {
 	return false
 }
 
-// Генерация ключа доступа
-func createAccessToken(userId uuid.UUID) (*token.Token, *token.Token, error) {
-	ta, err := token.GenJwtToken([]byte(cfg.SecretKey), "access", userId)
+// Генерация ключа доступа для сессии пользователя
+func createAccessToken(userId uuid.UUID, sessionId uuid.UUID) (*token.Token, *token.Token, error) {
+	ta, err := token.GenJwtToken([]byte(cfg.SecretKey), "access", userId, sessionId)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	tr, err := token.GenJwtToken([]byte(cfg.SecretKey), "refresh", userId)
+	tr, err := token.GenJwtToken([]byte(cfg.SecretKey), "refresh", userId, sessionId)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -985,6 +1034,11 @@ func GetActivitiesTable(query *gorm.DB, from DayRequest, to DayRequest) (map[uui
 	return resp, nil
 }
 
+// wantsMarkdown - клиент запросил описание/содержимое в формате Markdown (?format=markdown)
+func wantsMarkdown(c echo.Context) bool {
+	return strings.EqualFold(c.QueryParam("format"), "markdown")
+}
+
 func BindData(c echo.Context, key string, target interface{}) ([]string, error) {
 	var fields []string
 	form, _ := c.MultipartForm()
@@ -4,17 +4,21 @@ import (
 	"log/slog"
 
 	authprovider "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/auth-provider"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/business"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/cache"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
 	"gorm.io/gorm"
 )
 
 type LdapSynchronizer struct {
 	db           *gorm.DB
 	ldapProvider *authprovider.LdapProvider
+	bl           *business.Business
 }
 
-func NewLdapSynchronizer(db *gorm.DB, ldapProvider *authprovider.LdapProvider) *LdapSynchronizer {
-	return &LdapSynchronizer{db, ldapProvider}
+func NewLdapSynchronizer(db *gorm.DB, ldapProvider *authprovider.LdapProvider, bl *business.Business) *LdapSynchronizer {
+	return &LdapSynchronizer{db, ldapProvider, bl}
 }
 
 func (ls *LdapSynchronizer) SyncJob() {
@@ -36,3 +40,66 @@ func (ls *LdapSynchronizer) SyncJob() {
 		slog.Error("Update users params from LDAP", "err", err)
 	}
 }
+
+// SyncGroupsJob - задача планировщика, синхронизирует только участие в пространствах и проектах по группам LDAP.
+// Атрибуты пользователей (is_active, is_superuser) не изменяются
+func (ls *LdapSynchronizer) SyncGroupsJob() {
+	slog.Info("Sync LDAP groups memberships")
+	if _, err := ls.SyncAllGroups(false); err != nil {
+		slog.Error("Sync memberships from LDAP groups", "err", err)
+	}
+}
+
+// SyncAllGroups синхронизирует участие в пространствах и проектах всех пользователей LDAP с их группами.
+// При dryRun изменения только рассчитываются
+func (ls *LdapSynchronizer) SyncAllGroups(dryRun bool) ([]dto.LdapMembershipChange, error) {
+	var changes []dto.LdapMembershipChange
+	var users []dao.User
+	err := ls.db.Where("auth_provider = ?", "ldap").FindInBatches(&users, 20, func(tx *gorm.DB, batch int) error {
+		batchChanges, err := ls.SyncGroups(users, dryRun)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, batchChanges...)
+		return nil
+	}).Error
+	return changes, err
+}
+
+// SyncGroups синхронизирует участие пользователей в пространствах и проектах с их группами LDAP по сопоставлениям групп.
+// При dryRun изменения только рассчитываются
+func (ls *LdapSynchronizer) SyncGroups(users []dao.User, dryRun bool) ([]dto.LdapMembershipChange, error) {
+	var mappings []dao.LdapGroupMapping
+	if err := ls.db.Order("created_at").Find(&mappings).Error; err != nil {
+		return nil, err
+	}
+	if len(mappings) == 0 || len(users) == 0 {
+		return nil, nil
+	}
+
+	emails := make([]string, len(users))
+	for i, user := range users {
+		emails[i] = user.Email
+	}
+	groups, err := ls.ldapProvider.UserGroups(emails)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := ls.bl.GetLdapMembershipState(users, mappings)
+	if err != nil {
+		return nil, err
+	}
+	changes := business.PlanLdapMemberships(mappings, users, groups, state)
+	if dryRun || len(changes) == 0 {
+		return changes, nil
+	}
+
+	if err := ls.bl.ApplyLdapMembershipChanges(changes); err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		cache.WorkspaceMembersCache.Expire(change.WorkspaceId)
+	}
+	return changes, nil
+}
@@ -85,6 +85,8 @@ const mcpInstructions = `MCP сервер для работы с системо
 - get_doc — получение документа по UUID
 - create_doc — создание документа (workspace_id, title, content в HTML/TipTap, parent_doc_id, роли доступа, draft)
 - update_doc — обновление документа (title, content, draft)
+- list_doc_templates — шаблоны документов пространства и доступные в них переменные
+- create_doc_from_template — создание документа из шаблона (workspace_id, template_id, parent_doc_id, sprint_id для переменных спринта)
 
 ## Ресурсы
 - aiplan://users/current — информация о текущем пользователе (имя, email, права, настройки)
@@ -125,6 +127,7 @@ const mcpInstructions = `MCP сервер для работы с системо
 1. Получи workspace_id через get_user_workspaces
 2. Для просмотра: get_workspace_docs → get_doc
 3. Для создания: create_doc с workspace_id, title и content (HTML формат TipTap)
+   или create_doc_from_template, если в пространстве есть подходящий шаблон (см. list_doc_templates)
 4. Для обновления: update_doc с doc_id и изменяемыми полями
 
 ### Анализ проекта
@@ -156,6 +159,9 @@ func NewMCPServer(db *gorm.DB, bl *business.Business, version string) echo.Handl
 	httpServer := server.NewStreamableHTTPServer(srv)
 	return func(c echo.Context) error {
 		sessionCtx := context.WithValue(c.Request().Context(), "user", c.Get("user"))
+		if scopes, ok := c.Get("token_scopes").([]string); ok {
+			sessionCtx = context.WithValue(sessionCtx, "token_scopes", scopes)
+		}
 		httpServer.ServeHTTP(c.Response(), c.Request().WithContext(sessionCtx))
 		return nil
 	}
@@ -6,12 +6,15 @@ import (
 	"context"
 	"errors"
 	"strings"
+	"time"
 
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/business"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/mcp/logger"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/utils"
 	"github.com/gofrs/uuid"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -35,6 +38,7 @@ var docsTools = []Tool{
 			),
 		),
 		DocPermissionsMiddleware(getDoc),
+		types.TokenScopeRead,
 	},
 	{
 		mcp.NewTool(
@@ -53,6 +57,9 @@ var docsTools = []Tool{
 			mcp.WithString("content",
 				mcp.Description("HTML-содержимое документа в формате TipTap (поддерживает <p>, <h1>-<h6>, <ul>, <ol>, <li>, <a>, <img>, <strong>, <em>, <code>, <pre>, <blockquote>, <table> и другие стандартные HTML-теги)"),
 			),
+			mcp.WithString("content_markdown",
+				mcp.Description("Содержимое документа в формате Markdown (используется вместо content). Поддерживает упоминания @username и ссылки на задачи PROJ-12"),
+			),
 			mcp.WithString("parent_doc_id",
 				mcp.Description("ID родительского документа (UUID) для создания вложенного"),
 			),
@@ -67,6 +74,57 @@ var docsTools = []Tool{
 			),
 		),
 		createDoc,
+		types.TokenScopeDocsWrite,
+	},
+	{
+		mcp.NewTool(
+			"list_doc_templates",
+			mcp.WithDescription("Список шаблонов документов пространства и переменных, доступных в шаблонах"),
+			mcp.WithIdempotentHintAnnotation(true),
+			mcp.WithDestructiveHintAnnotation(false),
+			mcp.WithString("workspace_id",
+				mcp.Required(),
+				mcp.Description("ID пространства (UUID или slug)"),
+			),
+		),
+		listDocTemplates,
+		types.TokenScopeRead,
+	},
+	{
+		mcp.NewTool(
+			"create_doc_from_template",
+			mcp.WithDescription("Создание документа из шаблона пространства. В название и содержимое шаблона подставляются переменные: дата, автор, родительский документ, связанный спринт"),
+			mcp.WithIdempotentHintAnnotation(false),
+			mcp.WithDestructiveHintAnnotation(true),
+			mcp.WithString("workspace_id",
+				mcp.Required(),
+				mcp.Description("ID пространства (UUID или slug)"),
+			),
+			mcp.WithString("template_id",
+				mcp.Required(),
+				mcp.Description("ID шаблона (UUID) или его имя, см. list_doc_templates"),
+			),
+			mcp.WithString("title",
+				mcp.Description("Название документа (макс. 150 символов). По умолчанию берется из шаблона"),
+			),
+			mcp.WithString("parent_doc_id",
+				mcp.Description("ID родительского документа (UUID) для создания вложенного"),
+			),
+			mcp.WithString("sprint_id",
+				mcp.Description("ID спринта пространства (UUID) для переменных {{sprint}}, {{sprint_start}}, {{sprint_end}}, {{sprint_url}}"),
+			),
+			mcp.WithNumber("reader_role",
+				mcp.Description("Минимальная роль для чтения (5=Guest, 10=Member, 15=Admin)"),
+			),
+			mcp.WithNumber("editor_role",
+				mcp.Description("Минимальная роль для редактирования (5=Guest, 10=Member, 15=Admin)"),
+			),
+			mcp.WithBoolean("draft",
+				mcp.Description("Создать как черновик (по умолчанию false)"),
+			),
+		),
+		createDocFromTemplate,
+		types.TokenScopeDocsWrite,
 	},
 	{
 		mcp.NewTool(
@@ -84,11 +142,15 @@ var docsTools = []Tool{
 			mcp.WithString("content",
 				mcp.Description("Новое HTML-содержимое документа в формате TipTap"),
 			),
+			mcp.WithString("content_markdown",
+				mcp.Description("Новое содержимое документа в формате Markdown (используется вместо content)"),
+			),
 			mcp.WithBoolean("draft",
 				mcp.Description("Статус черновика (true/false)"),
 			),
 		),
 		DocEditPermissionsMiddleware(updateDocTool),
+		types.TokenScopeDocsWrite,
 	},
 }
 
@@ -98,7 +160,7 @@ func GetDocsTools(db *gorm.DB, bl *business.Business) []server.ServerTool {
 	for _, t := range docsTools {
 		result = append(result, server.ServerTool{
 			Tool:    t.Tool,
-			Handler: WrapTool(db, bl, t.Handler),
+			Handler: WrapTool(db, bl, t),
 		})
 	}
 	return result
@@ -339,6 +401,7 @@ type createDocParams struct {
 	workspaceIdOrSlug string
 	title             string
 	content           string
+	contentMarkdown   string
 	parentDocIdStr    string
 	readerRole        int
 	editorRole        int
@@ -371,6 +434,7 @@ func parseCreateDocParams(args map[string]interface{}) (*createDocParams, *mcp.C
 	}
 
 	params.content, _ = args["content"].(string)
+	params.contentMarkdown, _ = args["content_markdown"].(string)
 	params.draft, _ = args["draft"].(bool)
 	params.parentDocIdStr, _ = args["parent_doc_id"].(string)
 
@@ -468,6 +532,14 @@ func createDoc(ctx context.Context, db *gorm.DB, bl *business.Business, user *da
 		return errResult, nil
 	}
 
+	if params.contentMarkdown != "" {
+		body, _, err := dao.MarkdownToHTML(db, workspace, params.contentMarkdown)
+		if err != nil {
+			return logger.Error(err), nil
+		}
+		params.content = body
+	}
+
 	doc := dao.Doc{
 		ID:          dao.GenUUID(),
 		Title:       params.title,
@@ -495,14 +567,170 @@ func createDoc(ctx context.Context, db *gorm.DB, bl *business.Business, user *da
 	return mcp.NewToolResultJSON(createdDoc.ToDTO())
 }
 
+// listDocTemplates возвращает шаблоны документов пространства.
+func listDocTemplates(ctx context.Context, db *gorm.DB, _ *business.Business, user *dao.User, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	workspaceIdOrSlug, _ := request.GetArguments()["workspace_id"].(string)
+	if workspaceIdOrSlug == "" {
+		return mcp.NewToolResultError("workspace_id обязателен"), nil
+	}
+
+	workspace, err := findWorkspaceByIdOrSlug(db, workspaceIdOrSlug)
+	if err != nil {
+		return mcp.NewToolResultError("workspace не найден"), nil
+	}
+	if _, err := getWorkspaceMemberOrSuperuser(db, workspace, user); err != nil {
+		return mcp.NewToolResultError("нет доступа к workspace"), nil
+	}
+
+	var templates []dao.DocTemplate
+	if err := db.Where("workspace_id = ?", workspace.ID).Order("lower(name)").Find(&templates).Error; err != nil {
+		return logger.Error(err), nil
+	}
+
+	return mcp.NewToolResultJSON(map[string]any{
+		"templates": utils.SliceToSlice(&templates, func(t *dao.DocTemplate) dto.DocTemplate { return *t.ToDTO() }),
+		"variables": dao.DocTemplateVariables,
+	})
+}
+
+// findDocTemplate ищет шаблон документа пространства по ID или имени.
+func findDocTemplate(db *gorm.DB, workspace *dao.Workspace, idOrName string) (*dao.DocTemplate, *mcp.CallToolResult) {
+	query := db.Where("workspace_id = ?", workspace.ID)
+	if id, err := uuid.FromString(idOrName); err == nil {
+		query = query.Where("id = ?", id)
+	} else {
+		query = query.Where("name = ?", idOrName)
+	}
+
+	var template dao.DocTemplate
+	if err := query.First(&template).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apierrors.ErrDocTemplateNotFound.MCPError()
+		}
+		return nil, logger.Error(err)
+	}
+	return &template, nil
+}
+
+// createDocFromTemplate создаёт документ из шаблона пространства.
+func createDocFromTemplate(ctx context.Context, db *gorm.DB, bl *business.Business, user *dao.User, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+
+	workspaceIdOrSlug, _ := args["workspace_id"].(string)
+	if workspaceIdOrSlug == "" {
+		return mcp.NewToolResultError("workspace_id обязателен"), nil
+	}
+	templateIdOrName, _ := args["template_id"].(string)
+	if templateIdOrName == "" {
+		return mcp.NewToolResultError("template_id обязателен"), nil
+	}
+
+	workspace, err := findWorkspaceByIdOrSlug(db, workspaceIdOrSlug)
+	if err != nil {
+		return mcp.NewToolResultError("workspace не найден"), nil
+	}
+
+	workspaceMember, err := getWorkspaceMemberOrSuperuser(db, workspace, user)
+	if err != nil {
+		return mcp.NewToolResultError("нет доступа к workspace"), nil
+	}
+
+	if workspaceMember.Role <= types.GuestRole {
+		return apierrors.ErrDocForbidden.MCPError(), nil
+	}
+
+	template, errResult := findDocTemplate(db, workspace, templateIdOrName)
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	var readerRole, editorRole int
+	if v, ok := args["reader_role"].(float64); ok {
+		readerRole = int(v)
+	}
+	if v, ok := args["editor_role"].(float64); ok {
+		editorRole = int(v)
+	}
+	parentDocIdStr, _ := args["parent_doc_id"].(string)
+
+	parentDocID, readerRole, editorRole, errResult := handleParentDoc(
+		db, parentDocIdStr, workspace, user, workspaceMember, readerRole, editorRole)
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	vars := dao.DocTemplateVars{
+		Now:       time.Now(),
+		Author:    user,
+		Workspace: workspace,
+	}
+	if parentDocID.Valid {
+		var parentDoc dao.Doc
+		if err := db.Select("id", "title").Where("id = ?", parentDocID.UUID).First(&parentDoc).Error; err != nil {
+			return logger.Error(err), nil
+		}
+		vars.Parent = &parentDoc
+	}
+
+	sprintId, err := GetUUIDArg(args, "sprint_id")
+	if err != nil {
+		return mcp.NewToolResultError("некорректный формат sprint_id (ожидается UUID)"), nil
+	}
+	if !sprintId.IsNil() {
+		var sprint dao.Sprint
+		if err := db.Where("workspace_id = ? AND id = ?", workspace.ID, sprintId).First(&sprint).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return apierrors.ErrSprintNotFound.MCPError(), nil
+			}
+			return logger.Error(err), nil
+		}
+		vars.Sprint = &sprint
+	}
+
+	title, content := template.Render(vars)
+	if v, ok := args["title"].(string); ok && strings.TrimSpace(v) != "" {
+		title = strings.TrimSpace(v)
+	}
+	if len(title) > 150 {
+		return mcp.NewToolResultError("title не должен превышать 150 символов"), nil
+	}
+	draft, _ := args["draft"].(bool)
+
+	doc := dao.Doc{
+		ID:          dao.GenUUID(),
+		Title:       title,
+		Content:     content,
+		WorkspaceId: workspace.ID,
+		Workspace:   workspace,
+		CreatedById: user.ID,
+		Author:      user,
+		ReaderRole:  readerRole,
+		EditorRole:  editorRole,
+		Draft:       draft,
+		ParentDocID: parentDocID,
+	}
+
+	if err := dao.CreateDoc(db, &doc, user); err != nil {
+		return logger.Error(err), nil
+	}
+
+	createdDoc, errResult := loadFullDoc(db, doc.ID, workspaceMember)
+	if errResult != nil {
+		return errResult, nil
+	}
+
+	return mcp.NewToolResultJSON(createdDoc.ToDTO())
+}
+
 // updateDocParams содержит параметры для обновления документа.
 type updateDocParams struct {
-	title      string
-	content    string
-	draft      *bool
-	hasTitle   bool
-	hasContent bool
-	hasDraft   bool
+	title           string
+	content         string
+	contentMarkdown string
+	draft           *bool
+	hasTitle        bool
+	hasContent      bool
+	hasDraft        bool
 }
 
 // parseUpdateDocParams извлекает и валидирует параметры обновления из запроса.
@@ -526,6 +754,11 @@ func parseUpdateDocParams(args map[string]interface{}) (*updateDocParams, *mcp.C
 		params.hasContent = true
 	}
 
+	if content, ok := args["content_markdown"].(string); ok {
+		params.contentMarkdown = content
+		params.hasContent = true
+	}
+
 	if draft, ok := args["draft"].(bool); ok {
 		params.draft = &draft
 		params.hasDraft = true
@@ -533,7 +766,7 @@ func parseUpdateDocParams(args map[string]interface{}) (*updateDocParams, *mcp.C
 
 	// Проверяем, что хотя бы одно поле указано для обновления
 	if !params.hasTitle && !params.hasContent && !params.hasDraft {
-		return nil, mcp.NewToolResultError("необходимо указать хотя бы одно поле для обновления (title, content или draft)")
+		return nil, mcp.NewToolResultError("необходимо указать хотя бы одно поле для обновления (title, content, content_markdown или draft)")
 	}
 
 	return params, nil
@@ -554,6 +787,13 @@ func updateDocTool(ctx context.Context, db *gorm.DB, _ *business.Business, user
 	if params.hasTitle {
 		updates["title"] = params.title
 	}
+	if params.contentMarkdown != "" {
+		body, _, err := dao.MarkdownToHTML(db, docCtx.Doc.Workspace, params.contentMarkdown)
+		if err != nil {
+			return logger.Error(err), nil
+		}
+		params.content = body
+	}
 	if params.hasContent {
 		updates["content"] = types.RedactorHTML{Body: params.content}
 	}
@@ -14,6 +14,7 @@ import (
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/business"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/mcp/logger"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/search"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
@@ -38,6 +39,7 @@ var issuesTools = []Tool{
 			),
 		),
 		getIssue,
+		types.TokenScopeRead,
 	},
 	{
 		mcp.NewTool(
@@ -112,6 +114,7 @@ var issuesTools = []Tool{
 			),
 		),
 		searchIssues,
+		types.TokenScopeRead,
 	},
 	{
 		mcp.NewTool(
@@ -130,6 +133,9 @@ var issuesTools = []Tool{
 			mcp.WithString("description_html",
 				mcp.Description("Описание задачи в HTML формате"),
 			),
+			mcp.WithString("description_markdown",
+				mcp.Description("Описание задачи в формате Markdown (используется вместо description_html). Поддерживает упоминания @username и ссылки на задачи PROJ-12"),
+			),
 			mcp.WithString("priority",
 				mcp.Description("Приоритет задачи"),
 				mcp.Enum("urgent", "high", "medium", "low"),
@@ -156,6 +162,7 @@ var issuesTools = []Tool{
 			),
 		),
 		createIssue,
+		types.TokenScopeIssuesWrite,
 	},
 	{
 		mcp.NewTool(
@@ -173,6 +180,9 @@ var issuesTools = []Tool{
 			mcp.WithString("description_html",
 				mcp.Description("Описание задачи в HTML формате"),
 			),
+			mcp.WithString("description_markdown",
+				mcp.Description("Описание задачи в формате Markdown (используется вместо description_html). Поддерживает упоминания @username и ссылки на задачи PROJ-12"),
+			),
 			mcp.WithString("priority",
 				mcp.Description("Приоритет задачи (пустая строка для сброса)"),
 				mcp.Enum("urgent", "high", "medium", "low", ""),
@@ -202,6 +212,7 @@ var issuesTools = []Tool{
 			),
 		),
 		updateIssue,
+		types.TokenScopeIssuesWrite,
 	},
 	// ========== READ-ONLY ИНСТРУМЕНТЫ ДЛЯ АНАЛИЗА ==========
 	{
@@ -222,6 +233,7 @@ var issuesTools = []Tool{
 			),
 		),
 		getSprints,
+		types.TokenScopeRead,
 	},
 	{
 		mcp.NewTool(
@@ -241,6 +253,7 @@ var issuesTools = []Tool{
 			),
 		),
 		getIssueComments,
+		types.TokenScopeRead,
 	},
 	{
 		mcp.NewTool(
@@ -254,6 +267,7 @@ var issuesTools = []Tool{
 			),
 		),
 		getIssueComment,
+		types.TokenScopeRead,
 	},
 	{
 		mcp.NewTool(
@@ -276,6 +290,7 @@ var issuesTools = []Tool{
 			),
 		),
 		getIssueActivity,
+		types.TokenScopeRead,
 	},
 	{
 		mcp.NewTool(
@@ -292,6 +307,7 @@ var issuesTools = []Tool{
 			),
 		),
 		getProjectLabels,
+		types.TokenScopeRead,
 	},
 	{
 		mcp.NewTool(
@@ -305,6 +321,7 @@ var issuesTools = []Tool{
 			),
 		),
 		getIssueLinks,
+		types.TokenScopeRead,
 	},
 	{
 		mcp.NewTool(
@@ -318,6 +335,7 @@ var issuesTools = []Tool{
 			),
 		),
 		getIssueAttachments,
+		types.TokenScopeRead,
 	},
 	{
 		mcp.NewTool(
@@ -335,6 +353,7 @@ var issuesTools = []Tool{
 			),
 		),
 		createIssueComment,
+		types.TokenScopeIssuesWrite,
 	},
 }
 
@@ -346,7 +365,7 @@ func GetIssuesTools(db *gorm.DB, bl *business.Business) []server.ServerTool {
 	for _, t := range all {
 		resources = append(resources, server.ServerTool{
 			Tool:    t.Tool,
-			Handler: WrapTool(db, bl, t.Handler),
+			Handler: WrapTool(db, bl, t),
 		})
 	}
 	return resources
@@ -500,9 +519,18 @@ func createIssue(ctx context.Context, db *gorm.DB, bl *business.Business, user *
 
 	// Получаем опциональные параметры
 	var descriptionHtml string
+	var descriptionJSON editor.Document
 	if d, ok := args["description_html"].(string); ok {
 		descriptionHtml = d
 	}
+	if md, ok := args["description_markdown"].(string); ok && md != "" {
+		body, doc, err := dao.MarkdownToHTML(db, project.Workspace, md)
+		if err != nil {
+			return logger.Error(err), nil
+		}
+		descriptionHtml = body
+		descriptionJSON = *doc
+	}
 
 	var priority *string
 	if p, ok := args["priority"].(string); ok && p != "" {
@@ -546,6 +574,7 @@ func createIssue(ctx context.Context, db *gorm.DB, bl *business.Business, user *
 		UpdatedById:     userID,
 		WorkspaceId:     project.WorkspaceId,
 		DescriptionHtml: descriptionHtml,
+		DescriptionJSON: descriptionJSON,
 		Draft:           draft,
 		LLMContent:      true,
 	}
@@ -722,6 +751,19 @@ func updateIssue(ctx context.Context, db *gorm.DB, bl *business.Business, user *
 		data["description_html"] = desc
 	}
 
+	if md, ok := args["description_markdown"].(string); ok && md != "" {
+		if !updateAll {
+			return apierrors.ErrIssueForbidden.MCPError(), nil
+		}
+		body, doc, err := dao.MarkdownToHTML(db, issue.Workspace, md)
+		if err != nil {
+			return logger.Error(err), nil
+		}
+		issue.DescriptionHtml = body
+		issue.DescriptionJSON = *doc
+		data["description_html"] = body
+	}
+
 	if priority, ok := args["priority"].(string); ok {
 		if !updateAll {
 			return apierrors.ErrIssueForbidden.MCPError(), nil
@@ -49,6 +49,7 @@ var issuesActionsTools = []Tool{
 			),
 		),
 		deleteIssue,
+		types.TokenScopeIssuesWrite,
 	},
 	{
 		mcp.NewTool(
@@ -62,6 +63,7 @@ var issuesActionsTools = []Tool{
 			),
 		),
 		getAvailableStates,
+		types.TokenScopeRead,
 	},
 	{
 		mcp.NewTool(
@@ -75,6 +77,7 @@ var issuesActionsTools = []Tool{
 			),
 		),
 		getSubIssues,
+		types.TokenScopeRead,
 	},
 	{
 		mcp.NewTool(
@@ -93,6 +96,7 @@ var issuesActionsTools = []Tool{
 			),
 		),
 		addSubIssues,
+		types.TokenScopeIssuesWrite,
 	},
 	{
 		mcp.NewTool(
@@ -106,6 +110,7 @@ var issuesActionsTools = []Tool{
 			),
 		),
 		getLinkedIssues,
+		types.TokenScopeRead,
 	},
 	{
 		mcp.NewTool(
@@ -124,6 +129,7 @@ var issuesActionsTools = []Tool{
 			),
 		),
 		setLinkedIssues,
+		types.TokenScopeIssuesWrite,
 	},
 	{
 		mcp.NewTool(
@@ -145,6 +151,7 @@ var issuesActionsTools = []Tool{
 			),
 		),
 		createIssueLink,
+		types.TokenScopeIssuesWrite,
 	},
 	{
 		mcp.NewTool(
@@ -166,6 +173,7 @@ var issuesActionsTools = []Tool{
 			),
 		),
 		updateIssueLink,
+		types.TokenScopeIssuesWrite,
 	},
 	{
 		mcp.NewTool(
@@ -179,6 +187,7 @@ var issuesActionsTools = []Tool{
 			),
 		),
 		deleteIssueLink,
+		types.TokenScopeIssuesWrite,
 	},
 	{
 		mcp.NewTool(
@@ -196,6 +205,7 @@ var issuesActionsTools = []Tool{
 			),
 		),
 		updateIssueComment,
+		types.TokenScopeIssuesWrite,
 	},
 	{
 		mcp.NewTool(
@@ -209,6 +219,7 @@ var issuesActionsTools = []Tool{
 			),
 		),
 		deleteIssueComment,
+		types.TokenScopeIssuesWrite,
 	},
 	{
 		mcp.NewTool(
@@ -226,6 +237,7 @@ var issuesActionsTools = []Tool{
 			),
 		),
 		addCommentReaction,
+		types.TokenScopeIssuesWrite,
 	},
 	{
 		mcp.NewTool(
@@ -243,6 +255,7 @@ var issuesActionsTools = []Tool{
 			),
 		),
 		removeCommentReaction,
+		types.TokenScopeIssuesWrite,
 	},
 	{
 		mcp.NewTool(
@@ -256,6 +269,7 @@ var issuesActionsTools = []Tool{
 			),
 		),
 		getIssueHistory,
+		types.TokenScopeRead,
 	},
 	{
 		mcp.NewTool(
@@ -279,6 +293,7 @@ var issuesActionsTools = []Tool{
 			),
 		),
 		getCommentHistory,
+		types.TokenScopeRead,
 	},
 	{
 		mcp.NewTool(
@@ -312,6 +327,7 @@ var issuesActionsTools = []Tool{
 			),
 		),
 		getAvailableIssuesForRelation,
+		types.TokenScopeRead,
 	},
 	{
 		mcp.NewTool(
@@ -334,6 +350,7 @@ var issuesActionsTools = []Tool{
 			),
 		),
 		moveSubIssue,
+		types.TokenScopeIssuesWrite,
 	},
 	{
 		mcp.NewTool(
@@ -347,6 +364,7 @@ var issuesActionsTools = []Tool{
 			),
 		),
 		pinIssue,
+		types.TokenScopeIssuesWrite,
 	},
 	{
 		mcp.NewTool(
@@ -360,6 +378,7 @@ var issuesActionsTools = []Tool{
 			),
 		),
 		unpinIssue,
+		types.TokenScopeIssuesWrite,
 	},
 	{
 		mcp.NewTool(
@@ -373,6 +392,7 @@ var issuesActionsTools = []Tool{
 			),
 		),
 		getIssueProperties,
+		types.TokenScopeRead,
 	},
 	{
 		mcp.NewTool(
@@ -394,6 +414,7 @@ var issuesActionsTools = []Tool{
 			),
 		),
 		setIssueProperty,
+		types.TokenScopeIssuesWrite,
 	},
 }
 
@@ -9,6 +9,7 @@ import (
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/mcp/logger"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/utils"
 	"github.com/gofrs/uuid"
 	"github.com/mark3labs/mcp-go/mcp"
@@ -33,6 +34,7 @@ var projectsTools = []Tool{
 			),
 		),
 		ProjectPermissionsMiddleware(getStateList),
+		types.TokenScopeRead,
 	},
 	{
 		mcp.NewTool(
@@ -58,6 +60,7 @@ var projectsTools = []Tool{
 			),
 		),
 		ProjectPermissionsMiddleware(getProjectStats),
+		types.TokenScopeRead,
 	},
 	{
 		mcp.NewTool(
@@ -71,6 +74,7 @@ var projectsTools = []Tool{
 			),
 		),
 		ProjectPermissionsMiddleware(getProject),
+		types.TokenScopeRead,
 	},
 	{
 		mcp.NewTool(
@@ -93,6 +97,7 @@ var projectsTools = []Tool{
 			),
 		),
 		ProjectPermissionsMiddleware(getProjectMemberList),
+		types.TokenScopeRead,
 	},
 	{
 		mcp.NewTool(
@@ -110,6 +115,7 @@ var projectsTools = []Tool{
 			),
 		),
 		ProjectPermissionsMiddleware(getProjectMember),
+		types.TokenScopeRead,
 	},
 	{
 		mcp.NewTool(
@@ -127,6 +133,7 @@ var projectsTools = []Tool{
 			),
 		),
 		ProjectPermissionsMiddleware(getIssueLabel),
+		types.TokenScopeRead,
 	},
 	{
 		mcp.NewTool(
@@ -144,6 +151,7 @@ var projectsTools = []Tool{
 			),
 		),
 		ProjectPermissionsMiddleware(getState),
+		types.TokenScopeRead,
 	},
 }
 
@@ -152,7 +160,7 @@ func GetProjectsTools(db *gorm.DB, bl *business.Business) []server.ServerTool {
 	for _, t := range projectsTools {
 		result = append(result, server.ServerTool{
 			Tool:    t.Tool,
-			Handler: WrapTool(db, bl, t.Handler),
+			Handler: WrapTool(db, bl, t),
 		})
 	}
 	return result
@@ -3,9 +3,12 @@ package tools
 import (
 	"context"
 	"errors"
+	"slices"
 
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/business"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
 	"github.com/gofrs/uuid"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
@@ -19,21 +22,36 @@ var ErrInvalidArgType = errors.New("invalid mcp arg type")
 type ToolHandler func(ctx context.Context, db *gorm.DB, bl *business.Business, user *dao.User, request mcp.CallToolRequest) (*mcp.CallToolResult, error)
 
 // Tool представляет MCP инструмент с его обработчиком.
+// Scope - область действия персонального токена доступа, необходимая для вызова инструмента.
 type Tool struct {
 	Tool    mcp.Tool
 	Handler ToolHandler
+	Scope   string
 }
 
-// WrapTool оборачивает обработчик инструмента, извлекая пользователя из контекста.
-func WrapTool(db *gorm.DB, bl *business.Business, handler ToolHandler) server.ToolHandlerFunc {
+// WrapTool оборачивает обработчик инструмента, извлекая пользователя из контекста
+// и проверяя области действия персонального токена доступа.
+func WrapTool(db *gorm.DB, bl *business.Business, tool Tool) server.ToolHandlerFunc {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		userRaw := ctx.Value("user")
 		if userRaw == nil {
 			return nil, errors.New("user not provided")
 		}
 		user := userRaw.(*dao.User)
-		return handler(ctx, db, bl, user, request)
+		if scopes, ok := ctx.Value("token_scopes").([]string); ok && !ToolAllowed(scopes, tool.Scope) {
+			return apierrors.ErrAccessTokenScope.MCPError(), nil
+		}
+		return tool.Handler(ctx, db, bl, user, request)
+	}
+}
+
+// ToolAllowed проверяет, разрешает ли набор областей действия персонального токена доступа вызов инструмента
+// с указанной областью. Инструменты чтения доступны всем токенам с доступом к MCP, область admin разрешает все
+func ToolAllowed(scopes []string, required string) bool {
+	if required == types.TokenScopeRead || slices.Contains(scopes, types.TokenScopeAdmin) {
+		return true
 	}
+	return slices.Contains(scopes, required)
 }
 
 func GetUUIDArg(args map[string]any, argName string) (uuid.UUID, error) {
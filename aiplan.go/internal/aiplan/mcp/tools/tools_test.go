@@ -0,0 +1,34 @@
+package tools
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+)
+
+func TestToolScopes(t *testing.T) {
+	all := slices.Concat(projectsTools, issuesTools, issuesActionsTools, workspacesTools, docsTools)
+	scopes := make(map[string]string, len(all))
+	for _, tool := range all {
+		if !slices.Contains(types.TokenScopes, tool.Scope) {
+			t.Errorf("%s: unknown scope %q", tool.Tool.Name, tool.Scope)
+		}
+		scopes[tool.Tool.Name] = tool.Scope
+	}
+
+	cases := map[string]string{
+		"get_issue":                types.TokenScopeRead,
+		"list_doc_templates":       types.TokenScopeRead,
+		"create_issue":             types.TokenScopeIssuesWrite,
+		"add_comment_reaction":     types.TokenScopeIssuesWrite,
+		"delete_issue":             types.TokenScopeIssuesWrite,
+		"create_doc_from_template": types.TokenScopeDocsWrite,
+		"update_doc":               types.TokenScopeDocsWrite,
+	}
+	for name, want := range cases {
+		if got := scopes[name]; got != want {
+			t.Errorf("%s: got scope %q, want %q", name, got, want)
+		}
+	}
+}
@@ -29,6 +29,7 @@ var workspacesTools = []Tool{
 			),
 		),
 		getUserWorkspaces,
+		types.TokenScopeRead,
 	},
 	{
 		mcp.NewTool(
@@ -54,6 +55,7 @@ var workspacesTools = []Tool{
 			),
 		),
 		getWorkspaceDocs,
+		types.TokenScopeRead,
 	},
 	{
 		mcp.NewTool(
@@ -73,6 +75,7 @@ var workspacesTools = []Tool{
 			),
 		),
 		getWorkspaceProjects,
+		types.TokenScopeRead,
 	},
 }
 
@@ -81,7 +84,7 @@ func GetWorkspacesTools(db *gorm.DB, bl *business.Business) []server.ServerTool
 	for _, t := range workspacesTools {
 		result = append(result, server.ServerTool{
 			Tool:    t.Tool,
-			Handler: WrapTool(db, bl, t.Handler),
+			Handler: WrapTool(db, bl, t),
 		})
 	}
 	return result
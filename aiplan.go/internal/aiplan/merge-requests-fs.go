@@ -0,0 +1,338 @@
+// Пакет aiplan предоставляет функциональность для работы с merge request'ами
+// Git репозиториев через файловую систему без использования базы данных.
+//
+// Архитектурный принцип: merge request'ы являются частью репозитория и хранятся
+// рядом с ним в директории {repoPath}/aiplan-merge-requests/ в виде JSON файлов
+// {number}.json. База данных используется только для пользователей и задач,
+// на которые ссылаются merge request'ы.
+package aiplan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+	"github.com/gofrs/uuid"
+)
+
+// Состояния merge request'а
+const (
+	MergeRequestStateOpen   = "open"
+	MergeRequestStateClosed = "closed"
+	MergeRequestStateMerged = "merged"
+)
+
+// Способы слияния merge request'а
+const (
+	MergeMethodMerge       = "merge"
+	MergeMethodFastForward = "fast-forward"
+)
+
+// MergeRequest представляет запрос на слияние веток репозитория
+type MergeRequest struct {
+	// Number - порядковый номер merge request'а в репозитории (начиная с 1)
+	Number int `json:"number"`
+
+	// Title - заголовок merge request'а
+	Title string `json:"title"`
+
+	// Description - описание merge request'а (HTML)
+	Description types.RedactorHTML `json:"description"`
+
+	// SourceBranch - ветка, изменения которой вливаются
+	SourceBranch string `json:"source_branch"`
+
+	// TargetBranch - ветка, в которую вливаются изменения
+	TargetBranch string `json:"target_branch"`
+
+	// State - состояние merge request'а (open, closed, merged)
+	State string `json:"state"`
+
+	// AuthorID - UUID автора merge request'а
+	AuthorID uuid.UUID `json:"author_id"`
+
+	// IssueIDs - UUID связанных задач
+	IssueIDs []uuid.UUID `json:"issue_ids"`
+
+	// Approvals - одобрения merge request'а
+	Approvals []MergeRequestApproval `json:"approvals"`
+
+	// Comments - комментарии к merge request'у, в том числе построчные
+	Comments []MergeRequestComment `json:"comments"`
+
+	// CreatedAt - время создания
+	CreatedAt time.Time `json:"created_at"`
+
+	// UpdatedAt - время последнего изменения
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// ClosedAt - время закрытия без слияния (может быть nil)
+	ClosedAt *time.Time `json:"closed_at,omitempty"`
+
+	// MergedAt - время слияния (может быть nil)
+	MergedAt *time.Time `json:"merged_at,omitempty"`
+
+	// MergedBy - UUID пользователя, выполнившего слияние (может быть nil)
+	MergedBy *uuid.UUID `json:"merged_by,omitempty"`
+
+	// MergeMethod - способ, которым было выполнено слияние
+	MergeMethod string `json:"merge_method,omitempty"`
+
+	// MergeCommitSHA - SHA коммита, на который указывает целевая ветка после слияния
+	MergeCommitSHA string `json:"merge_commit_sha,omitempty"`
+
+	// MergedTargetSHA - SHA целевой ветки непосредственно перед слиянием
+	MergedTargetSHA string `json:"merged_target_sha,omitempty"`
+
+	// MergedSourceSHA - SHA исходной ветки на момент слияния
+	MergedSourceSHA string `json:"merged_source_sha,omitempty"`
+}
+
+// MergeRequestApproval представляет одобрение merge request'а пользователем
+type MergeRequestApproval struct {
+	// UserID - UUID одобрившего пользователя
+	UserID uuid.UUID `json:"user_id"`
+
+	// CommitSHA - SHA последнего коммита исходной ветки на момент одобрения
+	CommitSHA string `json:"commit_sha"`
+
+	// CreatedAt - время одобрения
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MergeRequestComment представляет комментарий к merge request'у.
+// Если указан Path, комментарий привязан к строке файла в diff.
+type MergeRequestComment struct {
+	// Id - уникальный идентификатор комментария
+	Id uuid.UUID `json:"id"`
+
+	// ActorId - UUID автора комментария
+	ActorId uuid.UUID `json:"actor_id"`
+
+	// CommentHtml - текст комментария (HTML)
+	CommentHtml types.RedactorHTML `json:"comment_html"`
+
+	// ReplyToCommentId - комментарий, на который дан ответ
+	ReplyToCommentId uuid.NullUUID `json:"reply_to_comment_id"`
+
+	// Path - путь к файлу для построчного комментария (пусто для общего комментария)
+	Path string `json:"path,omitempty"`
+
+	// Line - номер строки в файле
+	Line int `json:"line,omitempty"`
+
+	// Side - сторона diff: old (строка базовой версии) или new (строка новой версии)
+	Side string `json:"side,omitempty"`
+
+	// CommitSHA - SHA коммита исходной ветки, к которому оставлен комментарий
+	CommitSHA string `json:"commit_sha,omitempty"`
+
+	// Reactions - реакции на комментарий
+	Reactions []dto.CommentReaction `json:"reactions"`
+
+	// CreatedAt - время создания
+	CreatedAt time.Time `json:"created_at"`
+
+	// UpdatedAt - время последнего изменения
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// mergeRequestsLocks хранит блокировки merge request'ов по пути репозитория, чтобы операции
+// в разных репозиториях не блокировали друг друга
+var mergeRequestsLocks sync.Map
+
+// mergeRequestsLock возвращает блокировку merge request'ов репозитория
+func mergeRequestsLock(repoPath string) *sync.Mutex {
+	lock, _ := mergeRequestsLocks.LoadOrStore(filepath.Clean(repoPath), &sync.Mutex{})
+	return lock.(*sync.Mutex)
+}
+
+// GetMergeRequestsPath возвращает путь к директории merge request'ов репозитория
+// Директория: {repoPath}/aiplan-merge-requests/
+func GetMergeRequestsPath(repoPath string) string {
+	return filepath.Join(repoPath, "aiplan-merge-requests")
+}
+
+// getMergeRequestFilePath возвращает путь к файлу merge request'а
+// Формат: {repoPath}/aiplan-merge-requests/{number}.json
+func getMergeRequestFilePath(repoPath string, number int) string {
+	return filepath.Join(GetMergeRequestsPath(repoPath), strconv.Itoa(number)+".json")
+}
+
+// LoadMergeRequest загружает merge request по номеру
+// Если файл не существует, возвращается ошибка, которую можно проверить через os.IsNotExist
+func LoadMergeRequest(repoPath string, number int) (*MergeRequest, error) {
+	data, err := os.ReadFile(getMergeRequestFilePath(repoPath, number))
+	if err != nil {
+		return nil, err
+	}
+
+	var mr MergeRequest
+	if err := json.Unmarshal(data, &mr); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal merge request: %w", err)
+	}
+	return &mr, nil
+}
+
+// saveMergeRequestUnsafe сохраняет merge request в файл без блокировки
+// Использует атомарную запись (temp file + rename) для безопасности
+// ВАЖНО: Вызывающий код должен держать mergeRequestsLock(repoPath)
+func saveMergeRequestUnsafe(repoPath string, mr *MergeRequest) error {
+	if err := os.MkdirAll(GetMergeRequestsPath(repoPath), 0755); err != nil {
+		return fmt.Errorf("failed to create merge requests directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(mr, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal merge request: %w", err)
+	}
+
+	filePath := getMergeRequestFilePath(repoPath, mr.Number)
+	tempPath := filePath + ".tmp"
+
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write temp merge request file: %w", err)
+	}
+
+	if err := os.Rename(tempPath, filePath); err != nil {
+		os.Remove(tempPath) // Cleanup temp file
+		return fmt.Errorf("failed to rename temp merge request file: %w", err)
+	}
+
+	return nil
+}
+
+// ListMergeRequests возвращает все merge request'ы репозитория, отсортированные по убыванию номера
+func ListMergeRequests(repoPath string) ([]*MergeRequest, error) {
+	entries, err := os.ReadDir(GetMergeRequestsPath(repoPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*MergeRequest{}, nil
+		}
+		return nil, fmt.Errorf("failed to read merge requests directory: %w", err)
+	}
+
+	mrs := make([]*MergeRequest, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		number, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		mr, err := LoadMergeRequest(repoPath, number)
+		if err != nil {
+			// Пропускаем поврежденные файлы
+			continue
+		}
+		mrs = append(mrs, mr)
+	}
+
+	sort.Slice(mrs, func(i, j int) bool { return mrs[i].Number > mrs[j].Number })
+	return mrs, nil
+}
+
+// CreateMergeRequest сохраняет новый merge request, присваивая ему следующий номер.
+// Если открытый merge request для тех же веток уже существует, возвращает apierrors.ErrGitMergeRequestExists
+func CreateMergeRequest(repoPath string, mr *MergeRequest) error {
+	lock := mergeRequestsLock(repoPath)
+	lock.Lock()
+	defer lock.Unlock()
+
+	existing, err := ListMergeRequests(repoPath)
+	if err != nil {
+		return err
+	}
+	for _, e := range existing {
+		if e.State == MergeRequestStateOpen && e.SourceBranch == mr.SourceBranch && e.TargetBranch == mr.TargetBranch {
+			return apierrors.ErrGitMergeRequestExists
+		}
+	}
+
+	mr.Number = 1
+	if len(existing) > 0 {
+		mr.Number = existing[0].Number + 1
+	}
+
+	now := time.Now()
+	mr.CreatedAt = now
+	mr.UpdatedAt = now
+	if mr.State == "" {
+		mr.State = MergeRequestStateOpen
+	}
+	if mr.IssueIDs == nil {
+		mr.IssueIDs = []uuid.UUID{}
+	}
+	if mr.Approvals == nil {
+		mr.Approvals = []MergeRequestApproval{}
+	}
+	if mr.Comments == nil {
+		mr.Comments = []MergeRequestComment{}
+	}
+
+	return saveMergeRequestUnsafe(repoPath, mr)
+}
+
+// UpdateMergeRequest загружает merge request, применяет к нему fn и сохраняет результат.
+// Вся операция выполняется под блокировкой репозитория, поэтому параллельные изменения не теряются.
+// Если fn возвращает ошибку, изменения не сохраняются.
+func UpdateMergeRequest(repoPath string, number int, fn func(mr *MergeRequest) error) (*MergeRequest, error) {
+	lock := mergeRequestsLock(repoPath)
+	lock.Lock()
+	defer lock.Unlock()
+
+	mr, err := LoadMergeRequest(repoPath, number)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := fn(mr); err != nil {
+		return nil, err
+	}
+
+	mr.UpdatedAt = time.Now()
+	if err := saveMergeRequestUnsafe(repoPath, mr); err != nil {
+		return nil, err
+	}
+	return mr, nil
+}
+
+// HasApproval проверяет, одобрил ли пользователь merge request
+func (mr *MergeRequest) HasApproval(userId uuid.UUID) bool {
+	for _, a := range mr.Approvals {
+		if a.UserID == userId {
+			return true
+		}
+	}
+	return false
+}
+
+// FindComment возвращает комментарий по ID или nil
+func (mr *MergeRequest) FindComment(commentId uuid.UUID) *MergeRequestComment {
+	for i := range mr.Comments {
+		if mr.Comments[i].Id == commentId {
+			return &mr.Comments[i]
+		}
+	}
+	return nil
+}
+
+// LinksIssue проверяет, связан ли merge request с задачей
+func (mr *MergeRequest) LinksIssue(issueId uuid.UUID) bool {
+	for _, id := range mr.IssueIDs {
+		if id == issueId {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,278 @@
+package aiplan
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
+	"github.com/gofrs/uuid"
+)
+
+// initTestBareRepository создает bare репозиторий с веткой main и одним коммитом.
+// Возвращает путь к bare репозиторию и путь к рабочей копии для создания коммитов.
+func initTestBareRepository(t *testing.T) (string, string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	root := t.TempDir()
+	bare := filepath.Join(root, "repo.git")
+	work := filepath.Join(root, "work")
+
+	runTestGit(t, root, "init", "--bare", "-b", "main", bare)
+	runTestGit(t, root, "clone", "-q", bare, work)
+	runTestGit(t, work, "config", "user.email", "test@example.com")
+	runTestGit(t, work, "config", "user.name", "Test")
+	commitTestFile(t, work, "README.md", "hello\n", "initial")
+	runTestGit(t, work, "push", "-q", "origin", "HEAD:main")
+
+	return bare, work
+}
+
+// runTestGit выполняет git команду и прерывает тест при ошибке
+func runTestGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v, output: %s", args, err, output)
+	}
+}
+
+// commitTestFile записывает файл и создает коммит в рабочей копии
+func commitTestFile(t *testing.T, work, name, content, message string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(work, name), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	runTestGit(t, work, "add", name)
+	runTestGit(t, work, "commit", "-q", "-m", message)
+}
+
+// TestMergeRequestStorage проверяет создание, загрузку, список и изменение merge request'ов
+func TestMergeRequestStorage(t *testing.T) {
+	repoPath := t.TempDir()
+
+	mrs, err := ListMergeRequests(repoPath)
+	if err != nil {
+		t.Fatalf("ListMergeRequests on empty repo failed: %v", err)
+	}
+	if len(mrs) != 0 {
+		t.Errorf("Expected no merge requests, got %d", len(mrs))
+	}
+
+	author := uuid.Must(uuid.NewV4())
+	for i, branch := range []string{"feature", "fix"} {
+		mr := &MergeRequest{Title: "MR", SourceBranch: branch, TargetBranch: "main", AuthorID: author}
+		if err := CreateMergeRequest(repoPath, mr); err != nil {
+			t.Fatalf("CreateMergeRequest failed: %v", err)
+		}
+		if mr.Number != i+1 {
+			t.Errorf("Expected number %d, got %d", i+1, mr.Number)
+		}
+		if mr.State != MergeRequestStateOpen {
+			t.Errorf("Expected state %s, got %s", MergeRequestStateOpen, mr.State)
+		}
+	}
+
+	mrs, err = ListMergeRequests(repoPath)
+	if err != nil {
+		t.Fatalf("ListMergeRequests failed: %v", err)
+	}
+	if len(mrs) != 2 || mrs[0].Number != 2 || mrs[1].Number != 1 {
+		t.Fatalf("Expected merge requests [2, 1], got %d items", len(mrs))
+	}
+
+	issueId := uuid.Must(uuid.NewV4())
+	updated, err := UpdateMergeRequest(repoPath, 1, func(mr *MergeRequest) error {
+		mr.IssueIDs = append(mr.IssueIDs, issueId)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateMergeRequest failed: %v", err)
+	}
+	if !updated.LinksIssue(issueId) {
+		t.Error("Expected merge request to link issue")
+	}
+
+	// Ошибка в fn не должна сохранять изменения
+	errAbort := errors.New("abort")
+	if _, err := UpdateMergeRequest(repoPath, 1, func(mr *MergeRequest) error {
+		mr.Title = "changed"
+		return errAbort
+	}); !errors.Is(err, errAbort) {
+		t.Fatalf("Expected abort error, got %v", err)
+	}
+	loaded, err := LoadMergeRequest(repoPath, 1)
+	if err != nil {
+		t.Fatalf("LoadMergeRequest failed: %v", err)
+	}
+	if loaded.Title != "MR" || !loaded.LinksIssue(issueId) {
+		t.Errorf("Unexpected merge request state after aborted update: %+v", loaded)
+	}
+
+	// Второй открытый merge request для тех же веток не создается
+	duplicate := &MergeRequest{Title: "MR", SourceBranch: "feature", TargetBranch: "main", AuthorID: author}
+	if err := CreateMergeRequest(repoPath, duplicate); !errors.Is(err, apierrors.ErrGitMergeRequestExists) {
+		t.Errorf("Expected merge request exists error, got %v", err)
+	}
+
+	if _, err := LoadMergeRequest(repoPath, 42); !os.IsNotExist(err) {
+		t.Errorf("Expected not exist error, got %v", err)
+	}
+}
+
+// TestCreateMergeRequestConcurrent проверяет, что параллельные запросы создают один открытый merge request для пары веток
+func TestCreateMergeRequestConcurrent(t *testing.T) {
+	repoPath := t.TempDir()
+	author := uuid.Must(uuid.NewV4())
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = CreateMergeRequest(repoPath, &MergeRequest{Title: "MR", SourceBranch: "feature", TargetBranch: "main", AuthorID: author})
+		}()
+	}
+	wg.Wait()
+
+	created := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, apierrors.ErrGitMergeRequestExists):
+			t.Errorf("Unexpected error: %v", err)
+		}
+	}
+	if created != 1 {
+		t.Errorf("Expected one merge request, got %d", created)
+	}
+}
+
+// TestCompareGitRefs проверяет сравнение веток относительно merge-base
+func TestCompareGitRefs(t *testing.T) {
+	bare, work := initTestBareRepository(t)
+
+	runTestGit(t, work, "checkout", "-q", "-b", "feature")
+	commitTestFile(t, work, "feature.txt", "one\ntwo\n", "add feature")
+	commitTestFile(t, work, "README.md", "hello\nworld\n", "update readme")
+	runTestGit(t, work, "push", "-q", "origin", "feature")
+
+	// Изменение в main после ответвления не должно попадать в diff
+	runTestGit(t, work, "checkout", "-q", "main")
+	commitTestFile(t, work, "main.txt", "main\n", "main change")
+	runTestGit(t, work, "push", "-q", "origin", "main")
+
+	result, err := compareGitRefs(bare, "main", "feature", true)
+	if err != nil {
+		t.Fatalf("compareGitRefs failed: %v", err)
+	}
+	if len(result.Commits) != 2 {
+		t.Errorf("Expected 2 commits, got %d", len(result.Commits))
+	}
+	if len(result.Files) != 2 {
+		t.Fatalf("Expected 2 files, got %d: %+v", len(result.Files), result.Files)
+	}
+
+	files := map[string]CompareFileDTO{}
+	for _, f := range result.Files {
+		files[f.Path] = f
+	}
+	if f := files["feature.txt"]; f.Status != "added" || f.Additions != 2 || f.Patch == "" {
+		t.Errorf("Unexpected feature.txt diff: %+v", f)
+	}
+	if f := files["README.md"]; f.Status != "modified" || f.Additions != 1 || f.Deletions != 0 {
+		t.Errorf("Unexpected README.md diff: %+v", f)
+	}
+	if result.Additions != 3 {
+		t.Errorf("Expected 3 additions, got %d", result.Additions)
+	}
+
+	if _, err := compareGitRefs(bare, "main", "missing", false); !errors.Is(err, errGitRefNotFound) {
+		t.Errorf("Expected errGitRefNotFound, got %v", err)
+	}
+}
+
+// TestMergeGitBranches проверяет fast-forward слияние, merge коммит и обнаружение конфликтов
+func TestMergeGitBranches(t *testing.T) {
+	bare, work := initTestBareRepository(t)
+	committer := gitIdentity{Name: "Test", Email: "test@example.com"}
+
+	runTestGit(t, work, "checkout", "-q", "-b", "ff")
+	commitTestFile(t, work, "ff.txt", "ff\n", "ff change")
+	runTestGit(t, work, "push", "-q", "origin", "ff")
+
+	check, err := checkGitMerge(bare, "main", "ff")
+	if err != nil {
+		t.Fatalf("checkGitMerge failed: %v", err)
+	}
+	if !check.CanFastForward || !check.Mergeable {
+		t.Errorf("Expected fast-forward merge to be possible: %+v", check)
+	}
+
+	sha, err := mergeGitBranches(bare, "main", "ff", MergeMethodFastForward, "", committer)
+	if err != nil {
+		t.Fatalf("fast-forward merge failed: %v", err)
+	}
+	if sha != check.SourceSHA {
+		t.Errorf("Expected main to point to %s, got %s", check.SourceSHA, sha)
+	}
+	if _, err := mergeGitBranches(bare, "main", "ff", MergeMethodMerge, "again", committer); !errors.Is(err, errGitNothingToMerge) {
+		t.Errorf("Expected errGitNothingToMerge, got %v", err)
+	}
+
+	// Расходящиеся ветки без конфликтов - merge коммит
+	runTestGit(t, work, "fetch", "-q", "origin")
+	runTestGit(t, work, "checkout", "-q", "-B", "main", "origin/main")
+	runTestGit(t, work, "checkout", "-q", "-b", "side")
+	commitTestFile(t, work, "side.txt", "side\n", "side change")
+	runTestGit(t, work, "push", "-q", "origin", "side")
+	runTestGit(t, work, "checkout", "-q", "main")
+	commitTestFile(t, work, "README.md", "main version\n", "main readme")
+	runTestGit(t, work, "push", "-q", "origin", "main")
+
+	if _, err := mergeGitBranches(bare, "main", "side", MergeMethodFastForward, "", committer); !errors.Is(err, errGitNotFastForward) {
+		t.Errorf("Expected errGitNotFastForward, got %v", err)
+	}
+	sha, err = mergeGitBranches(bare, "main", "side", MergeMethodMerge, "Merge side", committer)
+	if err != nil {
+		t.Fatalf("merge commit failed: %v", err)
+	}
+	parents, err := executeGitCommand(bare, "rev-list", "--parents", "-n", "1", sha)
+	if err != nil {
+		t.Fatalf("rev-list failed: %v", err)
+	}
+	if len(strings.Fields(parents)) != 3 {
+		t.Errorf("Expected merge commit with 2 parents, got %q", parents)
+	}
+
+	// Конфликт: обе ветки меняют одну и ту же строку
+	runTestGit(t, work, "fetch", "-q", "origin")
+	runTestGit(t, work, "checkout", "-q", "-B", "main", "origin/main")
+	runTestGit(t, work, "checkout", "-q", "-b", "conflict")
+	commitTestFile(t, work, "README.md", "conflict version\n", "conflict readme")
+	runTestGit(t, work, "push", "-q", "origin", "conflict")
+	runTestGit(t, work, "checkout", "-q", "main")
+	commitTestFile(t, work, "README.md", "another main version\n", "main readme again")
+	runTestGit(t, work, "push", "-q", "origin", "main")
+
+	check, err = checkGitMerge(bare, "main", "conflict")
+	if err != nil {
+		t.Fatalf("checkGitMerge failed: %v", err)
+	}
+	if check.Mergeable || len(check.Conflicts) != 1 || check.Conflicts[0] != "README.md" {
+		t.Errorf("Expected conflict in README.md, got %+v", check)
+	}
+	if _, err := mergeGitBranches(bare, "main", "conflict", MergeMethodMerge, "Merge conflict", committer); !errors.Is(err, errGitMergeConflict) {
+		t.Errorf("Expected errGitMergeConflict, got %v", err)
+	}
+}
@@ -3,11 +3,14 @@ package email
 import (
 	"database/sql"
 	"fmt"
+	"html"
 	"regexp"
 	"strings"
 	"sync"
 
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor/diff"
 	member_role "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/notifications/member-role"
 	policy "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/redactor-policy"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/utils"
@@ -199,6 +202,50 @@ func htmlReplacer(s *string) *string {
 	return utils.ToPtr(prepareToMail(prepareHtmlBody(tmp)))
 }
 
+// renderBodyDiff формирует изменения между версиями описания: добавленные блоки выделяются цветом,
+// удаленные зачеркиваются, в измененных блоках выделяются добавленные и удаленные слова.
+// Неизмененные блоки пропускаются. Возвращает false, если версии не удалось разобрать
+func renderBodyDiff(oldBody, newBody string) (string, bool) {
+	oldDoc, err := editor.ParseDocument(strings.NewReader(oldBody))
+	if err != nil {
+		return "", false
+	}
+	newDoc, err := editor.ParseDocument(strings.NewReader(newBody))
+	if err != nil {
+		return "", false
+	}
+
+	d := diff.Compare(oldDoc, newDoc)
+	parts := make([]string, 0, len(d.Blocks))
+	for _, change := range d.Blocks {
+		switch change.Op {
+		case diff.OpInsert:
+			if text := *htmlReplacer(&change.NewHTML); text != "" {
+				parts = append(parts, `<span style="color:#05bd8d">`+text+`</span>`)
+			}
+		case diff.OpDelete:
+			if text := *htmlReplacer(&change.OldHTML); text != "" {
+				parts = append(parts, `<del style="color:#999999">`+text+`</del>`)
+			}
+		case diff.OpModify:
+			var sb strings.Builder
+			for _, t := range change.Text {
+				text := prepareToMail(html.EscapeString(t.Text))
+				switch t.Op {
+				case diff.OpInsert:
+					sb.WriteString(`<span style="color:#05bd8d">` + text + `</span>`)
+				case diff.OpDelete:
+					sb.WriteString(`<del style="color:#999999">` + text + `</del>`)
+				default:
+					sb.WriteString(text)
+				}
+			}
+			parts = append(parts, sb.String())
+		}
+	}
+	return strings.Join(parts, "<br>"), true
+}
+
 type prerenderType int
 
 const (
@@ -30,7 +30,7 @@ func NewDocPipeline() (types.EntityLayer, EmailProcessor) {
 
 var docFieldConfigs = map[actField.ActivityField]EntityFieldConfig{
 	actField.Title.Field:       {collectOne, createFieldRenderer("Название", StringField)},
-	actField.Description.Field: {collectOne, createFieldRenderer("Описание", BodyField)},
+	actField.Description.Field: {collectOne, createFieldRenderer("Описание", BodyDiffField)},
 	actField.Doc.Field:         {collectAll, makeEntityComplexRenderer("Документы", WithComplexAggregateFunc(docMoveFunc))},
 	actField.Readers.Field:     {collectAll, renderDocReaders},
 	actField.Editors.Field:     {collectAll, renderDocEditors},
@@ -40,6 +40,7 @@ var docFieldConfigs = map[actField.ActivityField]EntityFieldConfig{
 
 	actField.Comment.Field:    {collectAll, makeEntityComplexRenderer("Комментарии", WithActionTime(targetDateTimeZ), WithReplaceHtml(), WithTitleFunc(getAuthorTitle), WithComplexBlock())},
 	actField.Attachment.Field: {collectAll, makeEntityComplexRenderer("Вложения")},
+	actField.ShareLink.Field:  {collectAll, makeEntityComplexRenderer("Публичные ссылки")},
 }
 
 func (d DocProcessor) LoadActivities(tx *gorm.DB) []dao.ActivityEvent {
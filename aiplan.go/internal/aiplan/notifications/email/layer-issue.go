@@ -20,7 +20,7 @@ type IssueProcessor struct {
 
 var issueFieldConfigs = map[actField.ActivityField]EntityFieldConfig{
 	actField.Name.Field:        {collectOne, createFieldRenderer("Имя", StringField)},
-	actField.Description.Field: {collectOne, createFieldRenderer("Описание", BodyField)},
+	actField.Description.Field: {collectOne, createFieldRenderer("Описание", BodyDiffField)},
 	actField.Priority.Field:    {collectOne, createFieldRenderer("Приоритет", TranslateField, WithTranslation(types.PriorityTranslation))},
 	actField.Assignees.Field:   {collectAll, renderIssueAssignee},
 	actField.Watchers.Field:    {collectAll, renderIssueWatchers},
@@ -310,7 +310,7 @@ func renderIssueSprint(tx *gorm.DB, t *EmailTemplates, acts []dao.ActivityEvent,
 }
 
 func renderIssueComment(tx *gorm.DB, t *EmailTemplates, acts []dao.ActivityEvent, entity dao.IDaoAct) FieldPrerender {
-	return renderEntityChangeComplex(tx, t, acts, "Комментарии", WithActionTime(targetDateTimeZ), WithReplaceHtml(), WithTitleFunc(getAuthorTitle), WithComplexBlock())
+	return renderEntityChangeComplex(tx, t, acts, "Комментарии", WithActionTime(targetDateTimeZ), WithReplaceHtml(), WithTitleFunc(getAuthorTitle), WithComplexBlock(), WithBodyDiff())
 }
 
 func renderIssueAttachment(tx *gorm.DB, t *EmailTemplates, acts []dao.ActivityEvent, entity dao.IDaoAct) FieldPrerender {
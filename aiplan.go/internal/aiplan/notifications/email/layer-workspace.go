@@ -24,6 +24,7 @@ var workspaceFieldConfigs = map[actField.ActivityField]EntityFieldConfig{
 	actField.Doc.Field:         {collectAll, makeEntityComplexRenderer("Документы")},
 	actField.Form.Field:        {collectAll, makeEntityComplexRenderer("Формы")},
 	actField.Sprint.Field:      {collectAll, makeEntityComplexRenderer("Спринты")},
+	actField.DocTemplate.Field: {collectAll, makeEntityComplexRenderer("Шаблоны документов")},
 
 	actField.Token.Field:  {collectOne, createFieldRenderer("Токен", StringField, WithCustomText("изменен токен пространства"))},
 	actField.Member.Field: {collectAll, renderWorkspaceMember},
@@ -550,3 +550,31 @@ func (es *EmailService) UserBlockedUntil(user dao.User, until time.Time) error {
 		TextContent: textContent,
 	})
 }
+
+func (es *EmailService) NewDeviceLogin(user dao.User, session dao.UserSession) error {
+	subject := "Вход с нового устройства"
+
+	var template dao.Template
+	if err := es.db.Where("name = ?", "new_device_login").First(&template).Error; err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := template.ParsedTemplate.Execute(&buf, session); err != nil {
+		return err
+	}
+
+	content, err := es.getHTML("Вход с нового устройства", buf.String())
+	if err != nil {
+		return err
+	}
+
+	textContent := htmlStripPolicy.Sanitize(content)
+
+	return es.Send(EmailMessage{
+		To:          user.Email,
+		Subject:     subject,
+		Content:     content,
+		TextContent: textContent,
+	})
+}
@@ -82,10 +82,19 @@ func createFieldRenderer(label string, fieldType FieldType, opts ...RendererOpti
 			return FieldPrerender{}
 		}
 
-		if fieldType == BodyField {
+		var diffBody string
+		prepared := false
+		if fieldType == BodyDiffField && v != "" {
+			diffBody, prepared = renderBodyDiff(v, n)
+		}
+
+		switch {
+		case prepared:
+			newV = toValueCtx(nil, &diffBody)
+		case fieldType == BodyField || fieldType == BodyDiffField:
 			newV = toValueCtx(nil, &n)
 			oldV = toValueCtx(nil, &v)
-		} else {
+		default:
 			en, ev := escapeText(n), escapeText(v)
 			newV = toValueCtx(&en, nil)
 			oldV = toValueCtx(&ev, nil)
@@ -97,11 +106,12 @@ func createFieldRenderer(label string, fieldType FieldType, opts ...RendererOpti
 		}
 
 		fp := t.RenderCollectOne(collectOneCtx{
-			Key:    label,
-			New:    newV,
-			Old:    oldV,
-			Start:  sql.NullTime{Time: acts[0].CreatedAt, Valid: true},
-			Author: *acts[0].Actor,
+			Key:      label,
+			New:      newV,
+			Old:      oldV,
+			Prepared: prepared,
+			Start:    sql.NullTime{Time: acts[0].CreatedAt, Valid: true},
+			Author:   *acts[0].Actor,
 		})
 		fp.Verb = acts[0].Verb
 		return fp
@@ -351,6 +361,16 @@ func buildComplexEntityChange(activities []dao.ActivityEvent, opts ...RendererOp
 				ActivityMap: change.ActivityMap,
 			})
 			count++
+		case change.Updated && change.BodyDiff != nil: // Updated*, только изменения текста
+			views = append(views, DigestComplexView{
+				Title:       change.Title,
+				New:         change.BodyDiff,
+				IsInfo:      true,
+				TimeAction:  change.TimeAction,
+				WithBlock:   config.complexBlock,
+				ActivityMap: change.ActivityMap,
+			})
+			count++
 		case change.Updated: // Updated*
 			views = append(views, DigestComplexView{
 				Title:       change.Title,
@@ -498,6 +518,11 @@ func aggregateComplexChanges(activities []dao.ActivityEvent, config rendererConf
 					v := *newVal
 					change.LastNew = &v
 				}
+				if config.bodyDiff {
+					if body, ok := renderBodyDiff(act.OldValue, act.NewValue); ok && body != "" {
+						change.BodyDiff = &body
+					}
+				}
 			case actField.VerbDeleted:
 				change.Deleted = true
 				if change.FirstOld == nil && oldVal != nil {
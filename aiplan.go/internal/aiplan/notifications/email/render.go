@@ -27,6 +27,8 @@ type entityChange struct {
 
 	FirstOld *string
 	LastNew  *string
+	// Изменения текста при WithBodyDiff
+	BodyDiff *string
 
 	Title           *string
 	TimeAction      *string
@@ -54,6 +56,7 @@ type rendererConfig struct {
 	customText                 *string
 	customComplexAggregateFunc func(c *entityChange, act dao.ActivityEvent)
 	formatInfoFunc             func(act dao.ActivityEvent) string
+	bodyDiff                   bool
 }
 
 type RendererOption func(*rendererConfig)
@@ -65,6 +68,8 @@ const (
 	BodyField
 	EmojiField
 	TranslateField
+	// BodyDiffField - описание, в письме показываются только изменения между версиями
+	BodyDiffField
 )
 
 func WithCustomText(str string) RendererOption {
@@ -109,6 +114,13 @@ func WithActionTime(template string) RendererOption {
 	}
 }
 
+// WithBodyDiff показывает для измененной сущности только изменения текста вместо старой и новой версий целиком
+func WithBodyDiff() RendererOption {
+	return func(c *rendererConfig) {
+		c.bodyDiff = true
+	}
+}
+
 func WithTitleFunc(f func(act *dao.ActivityEvent) *string) RendererOption {
 	return func(c *rendererConfig) {
 		c.titleFunc = f
@@ -52,11 +52,17 @@ type collectOneCtx struct {
 	New *actValueCtx
 	Old *actValueCtx
 
+	// Prepared - Body уже подготовлен для письма и не обрабатывается htmlReplacer
+	Prepared bool
+
 	Start  sql.NullTime
 	Author dao.User
 }
 
 func (c *collectOneCtx) Replace() {
+	if c.Prepared {
+		return
+	}
 	if c.Old != nil {
 		c.Old.Body = htmlReplacer(c.Old.Body)
 	}
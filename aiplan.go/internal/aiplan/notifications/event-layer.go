@@ -226,6 +226,7 @@ func (d docEvent) GetRecipientsSteps(event *dao.ActivityEvent) []member_role.Use
 	return []member_role.UsersStep{
 		member_role.AddUserRole(event.Doc.Author, member_role.DocAuthor),
 		member_role.AddCommentMentionedUsers(event.NewDocComment),
+		member_role.AddDocAnchorAuthor(event.NewDocComment),
 		member_role.AddDocMembers(event.DocID.UUID),
 	}
 }
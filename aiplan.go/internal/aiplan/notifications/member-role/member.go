@@ -39,6 +39,7 @@ const (
 	DocWatcher
 	DocEditor
 	DocReader
+	DocAnchorAuthor
 
 	SprintAuthor
 	SprintWatcher
@@ -181,6 +182,35 @@ func AddUserRole(actor *dao.User, role Role) UsersStep {
 	}
 }
 
+// AddDocAnchorAuthor добавляет автора фрагмента документа, к которому относится комментарий или ответ в обсуждении
+func AddDocAnchorAuthor(comment *dao.DocComment) UsersStep {
+	conf := &memberConfig{}
+
+	return func(tx *gorm.DB, users UserRegistry) error {
+		if comment == nil {
+			return nil
+		}
+
+		threadId := comment.Id
+		if comment.ThreadId.Valid {
+			threadId = comment.ThreadId.UUID
+		}
+
+		var author dao.User
+		if err := tx.
+			Where("id = (?)", tx.Model(&dao.DocComment{}).Select("anchor_author_id").Where("id = ?", threadId)).
+			First(&author).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil
+			}
+			return fmt.Errorf("get doc anchor author failed: %v", err)
+		}
+
+		users.AddUser(&author, conf, DocAnchorAuthor)
+		return nil
+	}
+}
+
 func AddOriginalCommentAuthor(act *dao.ActivityEvent) UsersStep {
 	conf := &memberConfig{}
 
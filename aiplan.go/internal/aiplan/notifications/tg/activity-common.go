@@ -1,7 +1,11 @@
 package tg
 
 import (
+	"strings"
+
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/editor/diff"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
 	actField "github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types/activities"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/utils"
@@ -58,3 +62,50 @@ func genDefault(oldV string, newV string, af actField.ActivityField, title strin
 	}
 	return msg
 }
+
+// maxDiffBlocks - сколько измененных блоков показывается в уведомлении об изменении текста
+const maxDiffBlocks = 5
+
+// genBodyDiff формирует краткое описание изменений текста: добавленное выделяется жирным, удаленное зачеркивается,
+// длинный неизмененный текст сокращается. false - версии не удалось разобрать или изменений в тексте нет
+func genBodyDiff(oldV, newV string) (string, bool) {
+	oldDoc, err := editor.ParseDocument(strings.NewReader(oldV))
+	if err != nil {
+		return "", false
+	}
+	newDoc, err := editor.ParseDocument(strings.NewReader(newV))
+	if err != nil {
+		return "", false
+	}
+
+	excerpt := diff.Compare(oldDoc, newDoc).Excerpt(maxDiffBlocks)
+	if len(excerpt.Blocks) == 0 {
+		return "", false
+	}
+
+	lines := make([]string, 0, len(excerpt.Blocks)+1)
+	for _, block := range excerpt.Blocks {
+		var sb strings.Builder
+		switch block.Op {
+		case diff.OpInsert:
+			sb.WriteString("\\+ ")
+		case diff.OpDelete:
+			sb.WriteString("\\- ")
+		}
+		for _, t := range block.Text {
+			switch {
+			case strings.TrimSpace(t.Text) == "" || t.Op == diff.OpEqual:
+				sb.WriteString(Stelegramf("%s", t.Text))
+			case t.Op == diff.OpInsert:
+				sb.WriteString(Stelegramf("*%s*", t.Text))
+			case t.Op == diff.OpDelete:
+				sb.WriteString(Stelegramf("~%s~", t.Text))
+			}
+		}
+		lines = append(lines, sb.String())
+	}
+	if excerpt.Skipped > 0 {
+		lines = append(lines, Stelegramf("_и еще изменений: %d_", excerpt.Skipped))
+	}
+	return strings.Join(lines, "\n"), true
+}
@@ -23,6 +23,7 @@ var (
 
 		actField.Comment.Field:    docComment,
 		actField.Attachment.Field: docAttachment,
+		actField.ShareLink.Field:  docShareLink,
 
 		actField.Title.Field: docDefault,
 	}
@@ -151,6 +152,19 @@ func docAttachment(act *dao.ActivityEvent, af actField.ActivityField) TgMsg {
 	)
 }
 
+func docShareLink(act *dao.ActivityEvent, af actField.ActivityField) TgMsg {
+	msg := NewTgMsg()
+	switch act.Verb {
+	case actField.VerbCreated:
+		msg.Title = "опубликовал(-a) документ"
+		msg.Body += Stelegramf("*Ссылка*: %s", act.NewValue)
+	case actField.VerbDeleted:
+		msg.Title = "отозвал(-a) публичную ссылку на документ"
+		msg.Body += Stelegramf("*Ссылка*: ~%s~", act.OldValue)
+	}
+	return msg
+}
+
 func docDefault(act *dao.ActivityEvent, af actField.ActivityField) TgMsg {
 	msg := NewTgMsg()
 
@@ -89,6 +89,10 @@ func issueDescription(act *dao.ActivityEvent, af actField.ActivityField) TgMsg {
 	}
 
 	msg.Title = "изменил(-а) описание"
+	if body, ok := genBodyDiff(act.OldValue, act.NewValue); ok {
+		msg.Body = body
+		return msg
+	}
 	msg.Body = Stelegramf("```\n%s```",
 		utils.HtmlToTg(act.NewValue),
 	)
@@ -96,10 +100,16 @@ func issueDescription(act *dao.ActivityEvent, af actField.ActivityField) TgMsg {
 }
 
 func issueComment(act *dao.ActivityEvent, af actField.ActivityField) TgMsg {
-	return genComment(act.NewIssueComment, act.OldValue, act.Verb,
+	msg := genComment(act.NewIssueComment, act.OldValue, act.Verb,
 		"изменил(-a) комментарий",
 		"прокомментировал(-a)",
 		"удалил(-a) комментарий из")
+	if act.Verb == actField.VerbUpdated {
+		if body, ok := genBodyDiff(act.OldValue, act.NewValue); ok {
+			msg.Body = body
+		}
+	}
+	return msg
 }
 
 func issueAttachment(act *dao.ActivityEvent, af actField.ActivityField) TgMsg {
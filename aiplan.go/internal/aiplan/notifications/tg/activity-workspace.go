@@ -15,6 +15,7 @@ var (
 		actField.Doc.Field:         workspaceDoc,
 		actField.Form.Field:        workspaceForm,
 		actField.Sprint.Field:      workspaceSprint,
+		actField.DocTemplate.Field: workspaceDocTemplate,
 		actField.Description.Field: workspaceDescription,
 
 		actField.Token.Field:       workspaceToken,
@@ -93,6 +94,22 @@ func workspaceSprint(act *dao.ActivityEvent, af actField.ActivityField) TgMsg {
 	return msg
 }
 
+func workspaceDocTemplate(act *dao.ActivityEvent, af actField.ActivityField) TgMsg {
+	msg := NewTgMsg()
+	switch act.Verb {
+	case actField.VerbCreated:
+		msg.Title = "создал(-a) в пространстве"
+		msg.Body = Stelegramf("*Шаблон документа:* %s", act.NewValue)
+	case actField.VerbUpdated:
+		msg.Title = "изменил(-a) в пространстве"
+		msg.Body = Stelegramf("*Шаблон документа:* %s", act.NewValue)
+	case actField.VerbDeleted:
+		msg.Title = "удалил(-a) из пространства"
+		msg.Body = Stelegramf("*Шаблон документа:* ~%s~", fmt.Sprint(act.OldValue))
+	}
+	return msg
+}
+
 func workspaceDescription(act *dao.ActivityEvent, af actField.ActivityField) TgMsg {
 	msg := NewTgMsg()
 	if act.Verb != actField.VerbUpdated {
@@ -322,3 +322,14 @@ func (t *TgService) UserBlockedUntil(user dao.User, until time.Time) {
 	msg.Body = Stelegramf("из-за подозрительной активности до *%s*", until.Format("02.01.2006 15:04"))
 	t.Send(*user.TelegramId, msg)
 }
+
+func (t *TgService) NewDeviceLogin(user dao.User, session dao.UserSession) {
+	if user.TelegramId == nil || t.Disabled {
+		return
+	}
+
+	msg := NewTgMsg()
+	msg.Title = Stelegramf("🔐 Вход в учетную запись с нового устройства")
+	msg.Body = Stelegramf("*%s*\nIP: %s\nЕсли это были не вы, завершите сессию в настройках профиля и смените пароль", session.UserAgent, session.Ip)
+	t.Send(*user.TelegramId, msg)
+}
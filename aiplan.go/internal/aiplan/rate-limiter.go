@@ -1,7 +1,7 @@
-// Пакет aiplan предоставляет функциональность ограничения частоты SSH запросов (rate limiting)
-// для защиты от brute-force атак на SSH сервер.
+// Пакет aiplan предоставляет функциональность ограничения частоты запросов (rate limiting)
+// для защиты от brute-force атак и спама: аутентификация SSH, пароли публичных ссылок, ответы на формы.
 //
-// Rate limiter отслеживает количество попыток аутентификации с каждого IP адреса
+// Rate limiter отслеживает количество попыток по ключу (например, IP адресу)
 // и блокирует дальнейшие попытки при превышении лимита.
 package aiplan
 
@@ -10,8 +10,8 @@ import (
 	"time"
 )
 
-// SSHRateLimiter ограничивает частоту SSH попыток аутентификации по IP адресу
-type SSHRateLimiter struct {
+// RateLimiter ограничивает частоту попыток по ключу, обычно IP адресу, в скользящем временном окне
+type RateLimiter struct {
 	// attempts - map IP адреса → список timestamp попыток
 	attempts map[string][]time.Time
 
@@ -28,11 +28,11 @@ type SSHRateLimiter struct {
 	stopCleanup chan struct{}
 }
 
-// NewSSHRateLimiter создает новый rate limiter для SSH
+// NewRateLimiter создает новый rate limiter
 // maxAttempts - максимальное количество попыток (например, 5)
 // window - временное окно (например, 1 минута)
-func NewSSHRateLimiter(maxAttempts int, window time.Duration) *SSHRateLimiter {
-	limiter := &SSHRateLimiter{
+func NewRateLimiter(maxAttempts int, window time.Duration) *RateLimiter {
+	limiter := &RateLimiter{
 		attempts:    make(map[string][]time.Time),
 		maxAttempts: maxAttempts,
 		window:      window,
@@ -48,7 +48,13 @@ func NewSSHRateLimiter(maxAttempts int, window time.Duration) *SSHRateLimiter {
 // CheckAndRecord проверяет, не превышен ли лимит попыток для указанного IP,
 // и записывает новую попытку.
 // Возвращает true, если попытка разрешена, false - если лимит превышен.
-func (rl *SSHRateLimiter) CheckAndRecord(ip string) bool {
+func (rl *RateLimiter) CheckAndRecord(ip string) bool {
+	return rl.CheckAndRecordLimit(ip, rl.maxAttempts)
+}
+
+// CheckAndRecordLimit работает как CheckAndRecord, но с лимитом maxAttempts вместо заданного при создании.
+// Используется, когда лимит зависит от ключа, например от настроек формы
+func (rl *RateLimiter) CheckAndRecordLimit(ip string, maxAttempts int) bool {
 	now := time.Now()
 
 	rl.mu.Lock()
@@ -70,7 +76,7 @@ func (rl *SSHRateLimiter) CheckAndRecord(ip string) bool {
 	}
 
 	// Проверяем, не превышен ли лимит
-	if len(validAttempts) >= rl.maxAttempts {
+	if len(validAttempts) >= maxAttempts {
 		// Лимит превышен - не добавляем попытку и возвращаем false
 		return false
 	}
@@ -83,7 +89,7 @@ func (rl *SSHRateLimiter) CheckAndRecord(ip string) bool {
 }
 
 // startCleanup запускает фоновую горутину для периодической очистки старых записей
-func (rl *SSHRateLimiter) startCleanup() {
+func (rl *RateLimiter) startCleanup() {
 	// Очищаем каждые 1 минуту
 	ticker := time.NewTicker(1 * time.Minute)
 	defer ticker.Stop()
@@ -99,7 +105,7 @@ func (rl *SSHRateLimiter) startCleanup() {
 }
 
 // cleanup удаляет старые записи из map для освобождения памяти
-func (rl *SSHRateLimiter) cleanup() {
+func (rl *RateLimiter) cleanup() {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
@@ -127,6 +133,6 @@ func (rl *SSHRateLimiter) cleanup() {
 }
 
 // Stop останавливает фоновую горутину очистки
-func (rl *SSHRateLimiter) Stop() {
+func (rl *RateLimiter) Stop() {
 	close(rl.stopCleanup)
 }
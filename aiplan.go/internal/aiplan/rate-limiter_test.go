@@ -5,10 +5,10 @@ import (
 	"time"
 )
 
-// TestSSHRateLimiter проверяет работу rate limiter
-func TestSSHRateLimiter(t *testing.T) {
+// TestRateLimiter проверяет работу rate limiter
+func TestRateLimiter(t *testing.T) {
 	// Создаем rate limiter: максимум 3 попытки за 100ms
-	limiter := NewSSHRateLimiter(3, 100*time.Millisecond)
+	limiter := NewRateLimiter(3, 100*time.Millisecond)
 	defer limiter.Stop()
 
 	testIP := "192.168.1.1"
@@ -34,9 +34,9 @@ func TestSSHRateLimiter(t *testing.T) {
 	}
 }
 
-// TestSSHRateLimiterMultipleIPs проверяет изоляцию между разными IP
-func TestSSHRateLimiterMultipleIPs(t *testing.T) {
-	limiter := NewSSHRateLimiter(2, 100*time.Millisecond)
+// TestRateLimiterMultipleIPs проверяет изоляцию между разными IP
+func TestRateLimiterMultipleIPs(t *testing.T) {
+	limiter := NewRateLimiter(2, 100*time.Millisecond)
 	defer limiter.Stop()
 
 	ip1 := "192.168.1.1"
@@ -65,9 +65,9 @@ func TestSSHRateLimiterMultipleIPs(t *testing.T) {
 	}
 }
 
-// TestSSHRateLimiterCleanup проверяет очистку старых записей
-func TestSSHRateLimiterCleanup(t *testing.T) {
-	limiter := NewSSHRateLimiter(5, 50*time.Millisecond)
+// TestRateLimiterCleanup проверяет очистку старых записей
+func TestRateLimiterCleanup(t *testing.T) {
+	limiter := NewRateLimiter(5, 50*time.Millisecond)
 	defer limiter.Stop()
 
 	testIP := "192.168.1.1"
@@ -100,9 +100,9 @@ func TestSSHRateLimiterCleanup(t *testing.T) {
 	}
 }
 
-// TestSSHRateLimiterZeroAttempts проверяет поведение с нулевым лимитом
-func TestSSHRateLimiterZeroAttempts(t *testing.T) {
-	limiter := NewSSHRateLimiter(0, 100*time.Millisecond)
+// TestRateLimiterZeroAttempts проверяет поведение с нулевым лимитом
+func TestRateLimiterZeroAttempts(t *testing.T) {
+	limiter := NewRateLimiter(0, 100*time.Millisecond)
 	defer limiter.Stop()
 
 	testIP := "192.168.1.1"
@@ -112,3 +112,21 @@ func TestSSHRateLimiterZeroAttempts(t *testing.T) {
 		t.Error("With zero max attempts, all attempts should be blocked")
 	}
 }
+
+// TestRateLimiterCustomLimit проверяет лимит, заданный для отдельного ключа
+func TestRateLimiterCustomLimit(t *testing.T) {
+	limiter := NewRateLimiter(1, 100*time.Millisecond)
+	defer limiter.Stop()
+
+	for i := 0; i < 3; i++ {
+		if !limiter.CheckAndRecordLimit("form", 3) {
+			t.Errorf("Attempt %d should be allowed", i+1)
+		}
+	}
+	if limiter.CheckAndRecordLimit("form", 3) {
+		t.Error("4th attempt should be blocked")
+	}
+	if limiter.CheckAndRecord("form") {
+		t.Error("Default limit should apply to recorded attempts")
+	}
+}
@@ -0,0 +1,637 @@
+// Пакет aiplan предоставляет командный интерфейс трекера задач поверх встроенного SSH сервера.
+//
+// Пользователь, добавивший SSH ключ для Git, может работать с задачами из терминала:
+//
+//	ssh -p 22222 git@aiplan.example.com issues list --project PROJ
+//	ssh -p 22222 git@aiplan.example.com issue show PROJ-12
+//	ssh -p 22222 git@aiplan.example.com issue create --project PROJ --title "Новая задача"
+//	ssh -p 22222 git@aiplan.example.com issue move PROJ-12 Done
+//	ssh -p 22222 git@aiplan.example.com comment PROJ-12 "Текст комментария"
+//
+// Поиск выполняется через пакет search, изменения - через бизнес-слой, поэтому
+// правила статусов и activity работают так же, как в REST API. Права доступа
+// повторяют проверки ProjectPermissionMiddleware и IssuePermissionMiddleware.
+package aiplan
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/apierrors"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/business"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dto"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/search"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/utils"
+	"github.com/gofrs/uuid"
+	"gorm.io/gorm"
+)
+
+// sshCLIUsage - справка по командам, выводится командой help и при ошибке в аргументах
+const sshCLIUsage = `AIPlan SSH commands:
+
+  issues list [--project PROJ] [--workspace SLUG] [--state NAME] [--assignee me]
+              [--search TEXT] [--active] [--limit N] [--offset N] [--json]
+  issue show PROJ-12 [--workspace SLUG] [--json]
+  issue create --project PROJ --title TEXT [--workspace SLUG] [--description TEXT]
+               [--priority urgent|high|medium|low] [--state NAME] [--assignee me|USERNAME|EMAIL]... [--json]
+  issue move PROJ-12 STATE [--workspace SLUG] [--json]
+  comment PROJ-12 TEXT [--workspace SLUG]
+  help
+
+Issues can be referenced as PROJ-12, workspace-PROJ-12 or by UUID.
+Use --workspace when the same project identifier exists in several workspaces.
+`
+
+// sshCLIMaxLimit - максимальное количество задач в одном ответе issues list
+const sshCLIMaxLimit = 100
+
+// errSSHCLIUsage - ошибка разбора аргументов команды, после которой выводится справка
+var errSSHCLIUsage = errors.New("invalid command usage, run 'help' to see available commands")
+
+// sshCLI выполняет команды трекера задач от имени аутентифицированного пользователя
+type sshCLI struct {
+	db   *gorm.DB
+	bl   *business.Business
+	user *dao.User
+	out  io.Writer
+}
+
+// stringsFlag - флаг, который можно указать несколько раз (например --assignee)
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// run разбирает команду и выполняет ее
+func (cli *sshCLI) run(args []string) error {
+	if len(args) == 0 {
+		return errSSHCLIUsage
+	}
+
+	switch args[0] {
+	case "help", "--help", "-h":
+		_, err := io.WriteString(cli.out, sshCLIUsage)
+		return err
+	case "issues":
+		if len(args) < 2 || args[1] != "list" {
+			return errSSHCLIUsage
+		}
+		return cli.issuesList(args[2:])
+	case "issue":
+		if len(args) < 2 {
+			return errSSHCLIUsage
+		}
+		switch args[1] {
+		case "show":
+			return cli.issueShow(args[2:])
+		case "create":
+			return cli.issueCreate(args[2:])
+		case "move":
+			return cli.issueMove(args[2:])
+		}
+		return errSSHCLIUsage
+	case "comment":
+		return cli.comment(args[1:])
+	}
+
+	return fmt.Errorf("unknown command %q, run 'help' to see available commands", args[0])
+}
+
+// parseSSHCLIFlags разбирает флаги, допуская их смешивание с позиционными аргументами
+// (стандартный flag останавливается на первом позиционном аргументе).
+// Возвращает позиционные аргументы в исходном порядке.
+func parseSSHCLIFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, errSSHCLIUsage
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// newSSHCLIFlagSet создает FlagSet, который не печатает ошибки сам
+func newSSHCLIFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// parseIssueRef разбирает ссылку на задачу вида PROJ-12 или workspace-PROJ-12
+// Возвращает: (workspaceSlug, projectIdentifier, sequenceId, error)
+func parseIssueRef(ref string) (string, string, int, error) {
+	sep := strings.LastIndex(ref, "-")
+	if sep <= 0 {
+		return "", "", 0, apierrors.ErrIssueNotFound
+	}
+	seq, err := strconv.Atoi(ref[sep+1:])
+	if err != nil || seq <= 0 {
+		return "", "", 0, apierrors.ErrIssueNotFound
+	}
+
+	prefix := ref[:sep]
+	workspaceSlug := ""
+	if wsSep := strings.LastIndex(prefix, "-"); wsSep != -1 {
+		workspaceSlug = prefix[:wsSep]
+		prefix = prefix[wsSep+1:]
+	}
+	if prefix == "" {
+		return "", "", 0, apierrors.ErrIssueNotFound
+	}
+	return workspaceSlug, strings.ToUpper(prefix), seq, nil
+}
+
+// commentTextToHTML превращает текст комментария из терминала в HTML: каждая строка - отдельный абзац
+func commentTextToHTML(text string) string {
+	var sb strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		sb.WriteString("<p>")
+		sb.WriteString(html.EscapeString(strings.TrimRight(line, "\r")))
+		sb.WriteString("</p>")
+	}
+	return sb.String()
+}
+
+// memberProjectsQuery возвращает подзапрос проектов, в которых состоит пользователь
+func (cli *sshCLI) memberProjectsQuery() *gorm.DB {
+	return cli.db.Model(&dao.ProjectMember{}).Select("project_id").Where("member_id = ?", cli.user.ID)
+}
+
+// loadMembers загружает членство пользователя в проекте и workspace
+func (cli *sshCLI) loadMembers(projectId, workspaceId uuid.UUID) (*dao.ProjectMember, *dao.WorkspaceMember, error) {
+	var projectMember dao.ProjectMember
+	if err := cli.db.Where("project_id = ? AND member_id = ?", projectId, cli.user.ID).First(&projectMember).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, apierrors.ErrProjectMemberNotFound
+		}
+		return nil, nil, err
+	}
+
+	var workspaceMember dao.WorkspaceMember
+	if err := cli.db.Where("workspace_id = ? AND member_id = ?", workspaceId, cli.user.ID).First(&workspaceMember).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, apierrors.ErrWorkspaceMemberNotFound
+		}
+		return nil, nil, err
+	}
+	return &projectMember, &workspaceMember, nil
+}
+
+// resolveProject ищет проект по идентификатору (PROJ) или UUID среди проектов пользователя
+func (cli *sshCLI) resolveProject(ref, workspaceSlug string) (*dao.Project, error) {
+	query := cli.db.Joins("Workspace").Where("projects.id IN (?)", cli.memberProjectsQuery())
+	if id, err := uuid.FromString(ref); err == nil {
+		query = query.Where("projects.id = ?", id)
+	} else {
+		query = query.Where("projects.identifier = ?", strings.ToUpper(ref))
+	}
+	if workspaceSlug != "" {
+		query = query.Where(`"Workspace".slug = ?`, workspaceSlug)
+	}
+
+	var projects []dao.Project
+	if err := query.Limit(2).Find(&projects).Error; err != nil {
+		return nil, err
+	}
+	switch len(projects) {
+	case 0:
+		return nil, apierrors.ErrProjectNotFound
+	case 1:
+		return &projects[0], nil
+	}
+	return nil, fmt.Errorf("project %s exists in several workspaces, specify --workspace", ref)
+}
+
+// resolveIssue ищет задачу по ссылке среди проектов пользователя и загружает ее со связями
+func (cli *sshCLI) resolveIssue(ref, workspaceSlug string) (*dao.Issue, error) {
+	query := cli.db.
+		Joins("Workspace").
+		Joins("Project").
+		Joins("State").
+		Joins("Author").
+		Preload("Assignees").
+		Preload("Labels").
+		Where("issues.project_id IN (?)", cli.memberProjectsQuery())
+
+	if id, err := uuid.FromString(ref); err == nil {
+		query = query.Where("issues.id = ?", id)
+	} else {
+		refWorkspace, identifier, seq, err := parseIssueRef(ref)
+		if err != nil {
+			return nil, err
+		}
+		if refWorkspace != "" {
+			workspaceSlug = refWorkspace
+		}
+		query = query.
+			Where(`"Project".identifier = ?`, identifier).
+			Where("issues.sequence_id = ?", seq)
+		if workspaceSlug != "" {
+			query = query.Where(`"Workspace".slug = ?`, workspaceSlug)
+		}
+	}
+
+	var issues []dao.Issue
+	if err := query.Limit(2).Find(&issues).Error; err != nil {
+		return nil, err
+	}
+	switch len(issues) {
+	case 0:
+		return nil, apierrors.ErrIssueNotFound
+	case 1:
+		return &issues[0], nil
+	}
+	return nil, fmt.Errorf("issue %s exists in several workspaces, specify --workspace or use workspace-%s", ref, ref)
+}
+
+// resolveState ищет статус проекта по названию без учета регистра
+func (cli *sshCLI) resolveState(projectId uuid.UUID, name string) (*dao.State, error) {
+	var states []dao.State
+	if err := cli.db.Where("project_id = ?", projectId).Order("sequence").Find(&states).Error; err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(states))
+	for i := range states {
+		if strings.EqualFold(states[i].Name, name) {
+			return &states[i], nil
+		}
+		names = append(names, states[i].Name)
+	}
+	return nil, fmt.Errorf("%s: %q, available: %s", apierrors.ErrProjectStateNotFound.Error(), name, strings.Join(names, ", "))
+}
+
+// resolveAssignees ищет исполнителей среди участников проекта по username, email или "me"
+func (cli *sshCLI) resolveAssignees(projectId uuid.UUID, refs []string) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0, len(refs))
+	for _, ref := range refs {
+		if ref == "me" {
+			ids = append(ids, cli.user.ID)
+			continue
+		}
+
+		var user dao.User
+		if err := cli.db.
+			Where("users.id IN (?)", cli.db.Model(&dao.ProjectMember{}).Select("member_id").Where("project_id = ?", projectId)).
+			Where("lower(username) = lower(?) OR lower(email) = lower(?)", ref, ref).
+			First(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("%s: %s", apierrors.ErrProjectMemberNotFound.Error(), ref)
+			}
+			return nil, err
+		}
+		ids = append(ids, user.ID)
+	}
+	return ids, nil
+}
+
+// canEditIssue повторяет IssuePermissionMiddleware для изменения задачи и комментариев
+func canEditIssue(user *dao.User, issue *dao.Issue, projectMember *dao.ProjectMember, workspaceMember *dao.WorkspaceMember) bool {
+	return user.ID == issue.CreatedById ||
+		workspaceMember.Role == types.AdminRole ||
+		projectMember.Role >= types.MemberRole
+}
+
+// writeJSON выводит значение в формате JSON
+func (cli *sshCLI) writeJSON(v any) error {
+	enc := json.NewEncoder(cli.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// issueUserNames возвращает имена пользователей через запятую
+func issueUserNames(users *[]dao.User) string {
+	if users == nil {
+		return ""
+	}
+	names := make([]string, 0, len(*users))
+	for i := range *users {
+		names = append(names, (*users)[i].GetName())
+	}
+	return strings.Join(names, ", ")
+}
+
+// issuesList выводит список задач: issues list [--project PROJ] ...
+func (cli *sshCLI) issuesList(args []string) error {
+	fs := newSSHCLIFlagSet("issues list")
+	projectRef := fs.String("project", "", "")
+	workspaceSlug := fs.String("workspace", "", "")
+	stateName := fs.String("state", "", "")
+	assignee := fs.String("assignee", "", "")
+	searchQuery := fs.String("search", "", "")
+	onlyActive := fs.Bool("active", false, "")
+	limit := fs.Int("limit", 50, "")
+	offset := fs.Int("offset", 0, "")
+	asJSON := fs.Bool("json", false, "")
+	if positional, err := parseSSHCLIFlags(fs, args); err != nil || len(positional) > 0 {
+		return errSSHCLIUsage
+	}
+
+	searchParams := &types.SearchParams{
+		LightSearch:  true,
+		OrderByParam: "sequence_id",
+		Desc:         true,
+		OnlyActive:   *onlyActive,
+		Limit:        max(1, min(*limit, sshCLIMaxLimit)),
+		Offset:       max(0, *offset),
+	}
+	searchParams.Filters.SearchQuery = *searchQuery
+	if *searchQuery != "" {
+		searchParams.OrderByParam = "search_rank"
+	}
+	if *workspaceSlug != "" {
+		searchParams.Filters.WorkspaceSlugs = []string{*workspaceSlug}
+	}
+
+	// Без проекта - глобальный поиск по всем проектам пользователя, как /issues/search/
+	projectMember := dao.ProjectMember{}
+	globalSearch := true
+	if *projectRef != "" {
+		project, err := cli.resolveProject(*projectRef, *workspaceSlug)
+		if err != nil {
+			return err
+		}
+		member, _, err := cli.loadMembers(project.ID, project.WorkspaceId)
+		if err != nil {
+			return err
+		}
+		projectMember = *member
+		globalSearch = false
+
+		if *stateName != "" {
+			state, err := cli.resolveState(project.ID, *stateName)
+			if err != nil {
+				return err
+			}
+			searchParams.Filters.StateIds = []uuid.UUID{state.ID}
+		}
+	} else if *stateName != "" {
+		return fmt.Errorf("--state requires --project")
+	}
+
+	switch *assignee {
+	case "":
+	case "me":
+		searchParams.Filters.AssignedToMe = true
+	default:
+		return fmt.Errorf("--assignee supports only 'me'")
+	}
+
+	issues, count, err := search.SearchIssuesList(cli.db, *cli.user, projectMember, nil, globalSearch, searchParams)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return cli.writeJSON(dto.IssuesLightSearchResponse{
+			PaginationMeta: dto.PaginationMeta{
+				Count:  count,
+				Offset: searchParams.Offset,
+				Limit:  searchParams.Limit,
+			},
+			Issues: utils.SliceToSlice(&issues, func(iwc *dao.IssueWithCount) dto.SearchLightweightIssue { return iwc.ToSearchLightDTO() }),
+		})
+	}
+
+	tw := tabwriter.NewWriter(cli.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tSTATE\tPRIORITY\tASSIGNEES\tTITLE")
+	for i := range issues {
+		issue := &issues[i].Issue
+		state, priority := "", ""
+		if issue.State != nil {
+			state = issue.State.Name
+		}
+		if issue.Priority != nil {
+			priority = *issue.Priority
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", issue.String(), state, priority, issueUserNames(issue.Assignees), issue.Name)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(cli.out, "\nShown %d of %d (offset %d)\n", len(issues), count, searchParams.Offset)
+	return err
+}
+
+// printIssue выводит задачу в виде таблицы или JSON
+func (cli *sshCLI) printIssue(issue *dao.Issue, asJSON bool) error {
+	if asJSON {
+		return cli.writeJSON(issue.ToDTO())
+	}
+
+	tw := tabwriter.NewWriter(cli.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\t%s\n", issue.String(), issue.Name)
+	if issue.State != nil {
+		fmt.Fprintf(tw, "State:\t%s\n", issue.State.Name)
+	}
+	if issue.Priority != nil {
+		fmt.Fprintf(tw, "Priority:\t%s\n", *issue.Priority)
+	}
+	if issue.Author != nil {
+		fmt.Fprintf(tw, "Author:\t%s\n", issue.Author.GetName())
+	}
+	if names := issueUserNames(issue.Assignees); names != "" {
+		fmt.Fprintf(tw, "Assignees:\t%s\n", names)
+	}
+	if issue.Labels != nil && len(*issue.Labels) > 0 {
+		labels := make([]string, 0, len(*issue.Labels))
+		for _, label := range *issue.Labels {
+			labels = append(labels, label.Name)
+		}
+		fmt.Fprintf(tw, "Labels:\t%s\n", strings.Join(labels, ", "))
+	}
+	fmt.Fprintf(tw, "Created:\t%s\n", issue.CreatedAt.Format("2006-01-02 15:04"))
+	if issue.TargetDate != nil {
+		fmt.Fprintf(tw, "Target date:\t%s\n", issue.TargetDate.Time.Format("2006-01-02"))
+	}
+	if issue.ShortURL != nil {
+		fmt.Fprintf(tw, "URL:\t%s\n", issue.ShortURL.String())
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if issue.DescriptionStripped != nil && strings.TrimSpace(*issue.DescriptionStripped) != "" {
+		if _, err := fmt.Fprintf(cli.out, "\n%s\n", strings.TrimSpace(*issue.DescriptionStripped)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// issueShow выводит задачу: issue show PROJ-12
+func (cli *sshCLI) issueShow(args []string) error {
+	fs := newSSHCLIFlagSet("issue show")
+	workspaceSlug := fs.String("workspace", "", "")
+	asJSON := fs.Bool("json", false, "")
+	positional, err := parseSSHCLIFlags(fs, args)
+	if err != nil || len(positional) != 1 {
+		return errSSHCLIUsage
+	}
+
+	issue, err := cli.resolveIssue(positional[0], *workspaceSlug)
+	if err != nil {
+		return err
+	}
+	return cli.printIssue(issue, *asJSON)
+}
+
+// issueCreate создает задачу: issue create --project PROJ --title TEXT
+func (cli *sshCLI) issueCreate(args []string) error {
+	fs := newSSHCLIFlagSet("issue create")
+	projectRef := fs.String("project", "", "")
+	workspaceSlug := fs.String("workspace", "", "")
+	title := fs.String("title", "", "")
+	description := fs.String("description", "", "")
+	priority := fs.String("priority", "", "")
+	stateName := fs.String("state", "", "")
+	var assignees stringsFlag
+	fs.Var(&assignees, "assignee", "")
+	asJSON := fs.Bool("json", false, "")
+	if positional, err := parseSSHCLIFlags(fs, args); err != nil || len(positional) > 0 || *projectRef == "" {
+		return errSSHCLIUsage
+	}
+
+	if strings.TrimSpace(*title) == "" {
+		return apierrors.ErrIssueNameEmpty
+	}
+	if *priority != "" && !slices.Contains([]string{"urgent", "high", "medium", "low"}, *priority) {
+		return fmt.Errorf("invalid priority %q, expected urgent, high, medium or low", *priority)
+	}
+
+	project, err := cli.resolveProject(*projectRef, *workspaceSlug)
+	if err != nil {
+		return err
+	}
+	projectMember, workspaceMember, err := cli.loadMembers(project.ID, project.WorkspaceId)
+	if err != nil {
+		return err
+	}
+
+	// Как в ProjectPermissionMiddleware: создавать задачи могут администраторы workspace и участники проекта кроме гостей
+	if workspaceMember.Role != types.AdminRole && projectMember.Role <= types.GuestRole {
+		return apierrors.ErrProjectForbidden
+	}
+
+	issue := dao.Issue{
+		ID:   dao.GenUUID(),
+		Name: strings.TrimSpace(*title),
+	}
+	if *priority != "" {
+		issue.Priority = priority
+	}
+	if *description != "" {
+		issue.DescriptionHtml = commentTextToHTML(*description)
+	}
+	if *stateName != "" {
+		state, err := cli.resolveState(project.ID, *stateName)
+		if err != nil {
+			return err
+		}
+		issue.StateId = state.ID
+	}
+
+	assigneeIds, err := cli.resolveAssignees(project.ID, assignees)
+	if err != nil {
+		return err
+	}
+
+	if err := cli.bl.CreateIssue(&issue, *project, *cli.user, projectMember.Role == types.AdminRole, assigneeIds); err != nil {
+		return err
+	}
+
+	created, err := cli.resolveIssue(issue.ID.String(), "")
+	if err != nil {
+		return err
+	}
+	return cli.printIssue(created, *asJSON)
+}
+
+// issueMove переводит задачу в другой статус: issue move PROJ-12 Done
+func (cli *sshCLI) issueMove(args []string) error {
+	fs := newSSHCLIFlagSet("issue move")
+	workspaceSlug := fs.String("workspace", "", "")
+	asJSON := fs.Bool("json", false, "")
+	positional, err := parseSSHCLIFlags(fs, args)
+	if err != nil || len(positional) < 2 {
+		return errSSHCLIUsage
+	}
+
+	issue, err := cli.resolveIssue(positional[0], *workspaceSlug)
+	if err != nil {
+		return err
+	}
+	projectMember, workspaceMember, err := cli.loadMembers(issue.ProjectId, issue.WorkspaceId)
+	if err != nil {
+		return err
+	}
+	if !canEditIssue(cli.user, issue, projectMember, workspaceMember) {
+		return apierrors.ErrIssueForbidden
+	}
+
+	// Название статуса может состоять из нескольких слов: issue move PROJ-12 In progress
+	state, err := cli.resolveState(issue.ProjectId, strings.Join(positional[1:], " "))
+	if err != nil {
+		return err
+	}
+
+	if err := cli.bl.ChangeIssueState(issue, *state, *cli.user, projectMember.Role == types.AdminRole); err != nil {
+		return err
+	}
+	return cli.printIssue(issue, *asJSON)
+}
+
+// comment добавляет комментарий к задаче: comment PROJ-12 TEXT
+func (cli *sshCLI) comment(args []string) error {
+	fs := newSSHCLIFlagSet("comment")
+	workspaceSlug := fs.String("workspace", "", "")
+	positional, err := parseSSHCLIFlags(fs, args)
+	if err != nil || len(positional) < 2 {
+		return errSSHCLIUsage
+	}
+
+	text := strings.Join(positional[1:], " ")
+	if strings.TrimSpace(text) == "" {
+		return apierrors.ErrIssueCommentEmpty
+	}
+
+	issue, err := cli.resolveIssue(positional[0], *workspaceSlug)
+	if err != nil {
+		return err
+	}
+	projectMember, workspaceMember, err := cli.loadMembers(issue.ProjectId, issue.WorkspaceId)
+	if err != nil {
+		return err
+	}
+	if !canEditIssue(cli.user, issue, projectMember, workspaceMember) {
+		return apierrors.ErrIssueForbidden
+	}
+
+	if err := cli.bl.CreateIssueComment(*issue, *cli.user, commentTextToHTML(text), uuid.Nil, false); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(cli.out, "Comment added to %s\n", issue.String())
+	return err
+}
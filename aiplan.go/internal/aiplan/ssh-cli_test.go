@@ -0,0 +1,137 @@
+package aiplan
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/types"
+	"github.com/gofrs/uuid"
+)
+
+// TestParseIssueRef проверяет разбор ссылок на задачи
+func TestParseIssueRef(t *testing.T) {
+	tests := []struct {
+		ref           string
+		wantWorkspace string
+		wantProject   string
+		wantSeq       int
+		wantErr       bool
+	}{
+		{ref: "PROJ-12", wantProject: "PROJ", wantSeq: 12},
+		{ref: "proj-7", wantProject: "PROJ", wantSeq: 7},
+		{ref: "my-team-PROJ-12", wantWorkspace: "my-team", wantProject: "PROJ", wantSeq: 12},
+		{ref: "PROJ", wantErr: true},
+		{ref: "PROJ-", wantErr: true},
+		{ref: "PROJ-abc", wantErr: true},
+		{ref: "PROJ-0", wantErr: true},
+		{ref: "-12", wantErr: true},
+		{ref: "ws--12", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			ws, project, seq, err := parseIssueRef(tt.ref)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseIssueRef(%q) expected error, got ws=%q project=%q seq=%d", tt.ref, ws, project, seq)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseIssueRef(%q) unexpected error: %v", tt.ref, err)
+			}
+			if ws != tt.wantWorkspace || project != tt.wantProject || seq != tt.wantSeq {
+				t.Errorf("parseIssueRef(%q) = (%q, %q, %d), want (%q, %q, %d)",
+					tt.ref, ws, project, seq, tt.wantWorkspace, tt.wantProject, tt.wantSeq)
+			}
+		})
+	}
+}
+
+// TestParseSSHCLIFlags проверяет, что флаги можно указывать вперемешку с позиционными аргументами
+func TestParseSSHCLIFlags(t *testing.T) {
+	fs := newSSHCLIFlagSet("test")
+	workspace := fs.String("workspace", "", "")
+	asJSON := fs.Bool("json", false, "")
+
+	positional, err := parseSSHCLIFlags(fs, []string{"PROJ-12", "--json", "In", "progress", "--workspace", "team"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(positional, []string{"PROJ-12", "In", "progress"}) {
+		t.Errorf("positional = %v", positional)
+	}
+	if *workspace != "team" || !*asJSON {
+		t.Errorf("flags not parsed: workspace=%q json=%v", *workspace, *asJSON)
+	}
+
+	if _, err := parseSSHCLIFlags(newSSHCLIFlagSet("test"), []string{"--unknown"}); !errors.Is(err, errSSHCLIUsage) {
+		t.Errorf("expected usage error for unknown flag, got %v", err)
+	}
+}
+
+// TestCommentTextToHTML проверяет экранирование текста комментария
+func TestCommentTextToHTML(t *testing.T) {
+	got := commentTextToHTML("first <b>line</b>\r\nsecond & last\n")
+	want := "<p>first &lt;b&gt;line&lt;/b&gt;</p><p>second &amp; last</p>"
+	if got != want {
+		t.Errorf("commentTextToHTML() = %q, want %q", got, want)
+	}
+}
+
+// TestCanEditIssue проверяет права на изменение задачи через SSH
+func TestCanEditIssue(t *testing.T) {
+	author := uuid.Must(uuid.NewV4())
+	other := uuid.Must(uuid.NewV4())
+	issue := &dao.Issue{CreatedById: author}
+
+	tests := []struct {
+		name          string
+		userId        uuid.UUID
+		projectRole   int
+		workspaceRole int
+		want          bool
+	}{
+		{"author guest", author, types.GuestRole, types.GuestRole, true},
+		{"project member", other, types.MemberRole, types.MemberRole, true},
+		{"project admin", other, types.AdminRole, types.MemberRole, true},
+		{"workspace admin guest in project", other, types.GuestRole, types.AdminRole, true},
+		{"guest", other, types.GuestRole, types.MemberRole, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := canEditIssue(&dao.User{ID: tt.userId}, issue,
+				&dao.ProjectMember{Role: tt.projectRole}, &dao.WorkspaceMember{Role: tt.workspaceRole})
+			if got != tt.want {
+				t.Errorf("canEditIssue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSSHCLIUsage проверяет вывод справки и ошибки неизвестных команд без обращения к БД
+func TestSSHCLIUsage(t *testing.T) {
+	var out bytes.Buffer
+	cli := &sshCLI{user: &dao.User{}, out: &out}
+
+	if err := cli.run([]string{"help"}); err != nil {
+		t.Fatalf("help returned error: %v", err)
+	}
+	if !strings.Contains(out.String(), "issues list") {
+		t.Errorf("help output does not describe commands: %q", out.String())
+	}
+
+	for _, args := range [][]string{{}, {"issues"}, {"issue", "delete"}, {"issue", "show"}, {"comment", "PROJ-1"}, {"issues", "list", "extra"}} {
+		if err := cli.run(args); !errors.Is(err, errSSHCLIUsage) {
+			t.Errorf("run(%v) = %v, want usage error", args, err)
+		}
+	}
+	if err := cli.run([]string{"rm", "-rf"}); err == nil || !strings.Contains(err.Error(), "unknown command") {
+		t.Errorf("expected unknown command error, got %v", err)
+	}
+}
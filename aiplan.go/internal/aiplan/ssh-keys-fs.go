@@ -195,6 +195,9 @@ func AddSSHKey(userId, userEmail, name, publicKey, gitReposPath string) (*SSHKey
 	if err == nil && existingKey != nil {
 		return nil, fmt.Errorf("SSH key with fingerprint %s already exists for user %s", fingerprint, existingUserId)
 	}
+	if existingKey, ws, repo, err := findDeployKeyByFingerprintUnsafe(fingerprint, gitReposPath); err == nil && existingKey != nil {
+		return nil, fmt.Errorf("SSH key with fingerprint %s already exists as deploy key of %s/%s", fingerprint, ws, repo)
+	}
 
 	// Загружаем существующие ключи пользователя или создаем новый файл
 	userKeys, err := LoadUserSSHKeys(userId, gitReposPath)
@@ -9,6 +9,8 @@
 // - Права доступа основаны на workspace membership
 // - Публичные репозитории: read для всех, write для членов
 // - Приватные репозитории: read/write только для членов
+// - Deploy ключи: доступ только к своему репозиторию, write только если ключ не read-only
+// - Команды, не относящиеся к Git, выполняются командным интерфейсом трекера (ssh-cli.go)
 package aiplan
 
 import (
@@ -22,6 +24,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/business"
 	"github.com/aisa-it/aiplan/aiplan.go/internal/aiplan/dao"
 	"github.com/gliderlabs/ssh"
 	gossh "golang.org/x/crypto/ssh"
@@ -33,6 +36,9 @@ type SSHServer struct {
 	// db - подключение к базе данных для проверки пользователей и прав
 	db *gorm.DB
 
+	// bl - бизнес-слой для команд трекера задач
+	bl *business.Business
+
 	// gitReposPath - путь к директории Git репозиториев
 	gitReposPath string
 
@@ -49,7 +55,7 @@ type SSHServer struct {
 	server *ssh.Server
 
 	// rateLimiter - ограничитель частоты запросов (будет реализован в Phase 4)
-	rateLimiter *SSHRateLimiter
+	rateLimiter *RateLimiter
 }
 
 // SSHServerConfig конфигурация SSH сервера
@@ -57,6 +63,9 @@ type SSHServerConfig struct {
 	// DB - подключение к базе данных
 	DB *gorm.DB
 
+	// Business - бизнес-слой для команд трекера задач
+	Business *business.Business
+
 	// GitReposPath - путь к директории Git репозиториев
 	GitReposPath string
 
@@ -73,10 +82,20 @@ type SSHServerConfig struct {
 	RateLimitEnabled bool
 }
 
+// sshDeployKey описывает deploy ключ, которым аутентифицирована SSH сессия
+type sshDeployKey struct {
+	ID            string
+	Name          string
+	WorkspaceSlug string
+	RepoName      string
+	ReadOnly      bool
+}
+
 // NewSSHServer создает новый SSH сервер для Git операций
 func NewSSHServer(config SSHServerConfig) (*SSHServer, error) {
 	sshServer := &SSHServer{
 		db:           config.DB,
+		bl:           config.Business,
 		gitReposPath: config.GitReposPath,
 		host:         config.Host,
 		port:         config.Port,
@@ -99,7 +118,7 @@ func NewSSHServer(config SSHServerConfig) (*SSHServer, error) {
 	// Инициализируем rate limiter, если включен (Phase 4)
 	if config.RateLimitEnabled {
 		// Rate limiter: максимум 5 попыток в минуту
-		sshServer.rateLimiter = NewSSHRateLimiter(5, 1*time.Minute)
+		sshServer.rateLimiter = NewRateLimiter(5, 1*time.Minute)
 	}
 
 	slog.Info("SSH server created",
@@ -187,6 +206,10 @@ func (s *SSHServer) handlePublicKeyAuth(ctx ssh.Context, key ssh.PublicKey) bool
 	// Ищем SSH ключ по fingerprint в файловой системе
 	sshKey, userId, err := FindSSHKeyByFingerprint(fingerprint, s.gitReposPath)
 	if err != nil {
+		// Ключ не принадлежит пользователю - проверяем deploy ключи репозиториев
+		if s.handleDeployKeyAuth(ctx, fingerprint, remoteAddr) {
+			return true
+		}
 		slog.Warn("SSH key not found",
 			"fingerprint", fingerprint,
 			"remote_addr", remoteAddr,
@@ -238,35 +261,98 @@ func (s *SSHServer) handlePublicKeyAuth(ctx ssh.Context, key ssh.PublicKey) bool
 	return true
 }
 
+// handleDeployKeyAuth аутентифицирует подключение по deploy ключу репозитория
+// Возвращает true, если ключ найден и репозиторий существует
+func (s *SSHServer) handleDeployKeyAuth(ctx ssh.Context, fingerprint, remoteAddr string) bool {
+	deployKey, workspaceSlug, repoName, err := FindDeployKeyByFingerprint(fingerprint, s.gitReposPath)
+	if err != nil {
+		return false
+	}
+
+	ctx.SetValue("deploy_key", &sshDeployKey{
+		ID:            deployKey.ID,
+		Name:          deployKey.Name,
+		WorkspaceSlug: workspaceSlug,
+		RepoName:      repoName,
+		ReadOnly:      deployKey.ReadOnly,
+	})
+
+	slog.Info("SSH authentication successful with deploy key",
+		"workspace", workspaceSlug,
+		"repo", repoName,
+		"key_id", deployKey.ID,
+		"key_name", deployKey.Name,
+		"read_only", deployKey.ReadOnly,
+		"fingerprint", fingerprint,
+		"remote_addr", remoteAddr)
+
+	// Асинхронно обновляем last_used_at для ключа
+	go func() {
+		if err := UpdateDeployKeyLastUsed(workspaceSlug, repoName, deployKey.ID, s.gitReposPath); err != nil {
+			slog.Warn("Failed to update deploy key last_used_at",
+				"workspace", workspaceSlug,
+				"repo", repoName,
+				"key_id", deployKey.ID,
+				"err", err)
+		}
+	}()
+
+	return true
+}
+
 // handleSSHSession обрабатывает SSH сессию
 func (s *SSHServer) handleSSHSession(sess ssh.Session) {
-	// Получаем пользователя из context (установлен в handlePublicKeyAuth)
-	userValue := sess.Context().Value("user")
-	if userValue == nil {
+	// Получаем пользователя или deploy ключ из context (установлены в handlePublicKeyAuth)
+	user, _ := sess.Context().Value("user").(*dao.User)
+	deployKey, _ := sess.Context().Value("deploy_key").(*sshDeployKey)
+	if user == nil && deployKey == nil {
 		slog.Error("SSH session without user in context")
 		io.WriteString(sess.Stderr(), "Internal error: user not found\n")
 		sess.Exit(1)
 		return
 	}
 
-	user := userValue.(*dao.User)
-
 	// Получаем команду
 	command := sess.Command()
 
 	// Если команда пустая - это интерактивная сессия
 	if len(command) == 0 {
 		// Выводим приветственное сообщение
-		welcomeMsg := fmt.Sprintf("Hi there, %s! You've successfully authenticated, but AIPlan does not provide shell access.\n", user.Email)
+		welcomeMsg := fmt.Sprintf("Hi there, %s! You've successfully authenticated, but AIPlan does not provide shell access.\n", sshActorName(user, deployKey))
+		if user != nil {
+			welcomeMsg += "Run 'ssh <host> help' to see available issue tracker commands.\n"
+		}
 		io.WriteString(sess, welcomeMsg)
 		sess.Exit(0)
 		return
 	}
 
+	// Команды трекера задач доступны только пользователям
+	if !strings.HasPrefix(command[0], "git-") {
+		if user == nil {
+			io.WriteString(sess.Stderr(), "Error: deploy keys can only be used for git operations\n")
+			sess.Exit(1)
+			return
+		}
+
+		cli := &sshCLI{db: s.db, bl: s.bl, user: user, out: sess}
+		if err := cli.run(command); err != nil {
+			slog.Info("SSH command failed",
+				"user", user.Email,
+				"command", strings.Join(command, " "),
+				"err", err)
+			io.WriteString(sess.Stderr(), fmt.Sprintf("Error: %v\n", err))
+			sess.Exit(1)
+			return
+		}
+		sess.Exit(0)
+		return
+	}
+
 	// Обрабатываем Git команду
-	if err := s.handleGitCommand(sess, user, command); err != nil {
+	if err := s.handleGitCommand(sess, user, deployKey, command); err != nil {
 		slog.Error("Git command failed",
-			"user", user.Email,
+			"user", sshActorName(user, deployKey),
 			"command", strings.Join(command, " "),
 			"err", err)
 		io.WriteString(sess.Stderr(), fmt.Sprintf("Error: %v\n", err))
@@ -277,8 +363,17 @@ func (s *SSHServer) handleSSHSession(sess ssh.Session) {
 	sess.Exit(0)
 }
 
+// sshActorName возвращает имя пользователя или deploy ключа для логов и сообщений
+func sshActorName(user *dao.User, deployKey *sshDeployKey) string {
+	if user != nil {
+		return user.Email
+	}
+	return fmt.Sprintf("deploy key %q (%s/%s)", deployKey.Name, deployKey.WorkspaceSlug, deployKey.RepoName)
+}
+
 // handleGitCommand обрабатывает Git команду
-func (s *SSHServer) handleGitCommand(sess ssh.Session, user *dao.User, command []string) error {
+// Ровно один из user и deployKey не равен nil
+func (s *SSHServer) handleGitCommand(sess ssh.Session, user *dao.User, deployKey *sshDeployKey, command []string) error {
 	startTime := time.Now()
 
 	// Парсим Git команду
@@ -289,7 +384,7 @@ func (s *SSHServer) handleGitCommand(sess ssh.Session, user *dao.User, command [
 
 	remoteAddr := sess.RemoteAddr().String()
 	slog.Info("Git command received",
-		"user", user.Email,
+		"user", sshActorName(user, deployKey),
 		"git_cmd", gitCmd,
 		"repo_path", repoPath,
 		"remote_addr", remoteAddr)
@@ -301,7 +396,7 @@ func (s *SSHServer) handleGitCommand(sess ssh.Session, user *dao.User, command [
 	}
 
 	// Проверяем права доступа к репозиторию
-	canRead, canWrite, err := s.checkGitPermissions(user, workspaceSlug, repoName, gitCmd)
+	canRead, canWrite, err := s.checkGitPermissions(user, deployKey, workspaceSlug, repoName, gitCmd)
 	if err != nil {
 		return fmt.Errorf("permission check failed: %w", err)
 	}
@@ -311,7 +406,8 @@ func (s *SSHServer) handleGitCommand(sess ssh.Session, user *dao.User, command [
 		return fmt.Errorf("no write access to repository %s/%s", workspaceSlug, repoName)
 	}
 
-	if gitCmd == "git-upload-pack" && !canRead {
+	// git-upload-pack и git-upload-archive требуют права на чтение
+	if gitCmd != "git-receive-pack" && !canRead {
 		return fmt.Errorf("no read access to repository %s/%s", workspaceSlug, repoName)
 	}
 
@@ -323,6 +419,11 @@ func (s *SSHServer) handleGitCommand(sess ssh.Session, user *dao.User, command [
 		return fmt.Errorf("repository not found: %s/%s", workspaceSlug, repoName)
 	}
 
+	// Зеркало доступно только для чтения: изменения приходят из удаленного репозитория
+	if gitCmd == "git-receive-pack" && IsGitMirror(fullRepoPath) {
+		return fmt.Errorf("repository %s/%s is a read-only mirror", workspaceSlug, repoName)
+	}
+
 	// Создаем команду Git
 	cmd := exec.Command(gitCmd, fullRepoPath)
 
@@ -333,10 +434,16 @@ func (s *SSHServer) handleGitCommand(sess ssh.Session, user *dao.User, command [
 
 	// Настраиваем переменные окружения для Git
 	// Это позволит Git знать, кто выполняет операцию
-	cmd.Env = append(os.Environ(),
-		fmt.Sprintf("GIT_COMMITTER_NAME=%s %s", user.FirstName, user.LastName),
-		fmt.Sprintf("GIT_COMMITTER_EMAIL=%s", user.Email),
-	)
+	if user != nil {
+		cmd.Env = append(os.Environ(),
+			fmt.Sprintf("GIT_COMMITTER_NAME=%s %s", user.FirstName, user.LastName),
+			fmt.Sprintf("GIT_COMMITTER_EMAIL=%s", user.Email),
+		)
+	} else {
+		cmd.Env = append(os.Environ(),
+			fmt.Sprintf("GIT_COMMITTER_NAME=Deploy key %s", deployKey.Name),
+		)
+	}
 
 	// Выполняем команду
 	if err := cmd.Run(); err != nil {
@@ -345,7 +452,7 @@ func (s *SSHServer) handleGitCommand(sess ssh.Session, user *dao.User, command [
 
 	duration := time.Since(startTime)
 	slog.Info("Git command completed successfully",
-		"user", user.Email,
+		"user", sshActorName(user, deployKey),
 		"git_cmd", gitCmd,
 		"repo", fmt.Sprintf("%s/%s", workspaceSlug, repoName),
 		"duration_ms", duration.Milliseconds())
@@ -354,8 +461,22 @@ func (s *SSHServer) handleGitCommand(sess ssh.Session, user *dao.User, command [
 }
 
 // checkGitPermissions проверяет права доступа к репозиторию
+// Для deploy ключа права определяются только самим ключом и его репозиторием
 // Возвращает: (canRead, canWrite, error)
-func (s *SSHServer) checkGitPermissions(user *dao.User, workspaceSlug, repoName, gitCmd string) (bool, bool, error) {
+func (s *SSHServer) checkGitPermissions(user *dao.User, deployKey *sshDeployKey, workspaceSlug, repoName, gitCmd string) (bool, bool, error) {
+	if deployKey != nil {
+		if deployKey.WorkspaceSlug != workspaceSlug || deployKey.RepoName != repoName {
+			slog.Warn("Deploy key access denied to foreign repository",
+				"key_id", deployKey.ID,
+				"key_repo", fmt.Sprintf("%s/%s", deployKey.WorkspaceSlug, deployKey.RepoName),
+				"workspace", workspaceSlug,
+				"repo", repoName,
+				"git_cmd", gitCmd)
+			return false, false, nil
+		}
+		return true, !deployKey.ReadOnly, nil
+	}
+
 	// Загружаем workspace по slug
 	var workspace dao.Workspace
 	if err := s.db.Where("slug = ?", workspaceSlug).First(&workspace).Error; err != nil {
@@ -186,3 +186,35 @@ func TestParseRepositoryPath(t *testing.T) {
 		})
 	}
 }
+
+// TestCheckGitPermissionsDeployKey проверяет, что deploy ключ дает доступ только к своему репозиторию
+func TestCheckGitPermissionsDeployKey(t *testing.T) {
+	s := &SSHServer{}
+
+	tests := []struct {
+		name      string
+		readOnly  bool
+		workspace string
+		repo      string
+		wantRead  bool
+		wantWrite bool
+	}{
+		{"Read-only key, own repository", true, "ws", "repo", true, false},
+		{"Writable key, own repository", false, "ws", "repo", true, true},
+		{"Foreign repository", false, "ws", "other", false, false},
+		{"Foreign workspace", false, "other", "repo", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployKey := &sshDeployKey{ID: "key", Name: "CI", WorkspaceSlug: "ws", RepoName: "repo", ReadOnly: tt.readOnly}
+			canRead, canWrite, err := s.checkGitPermissions(nil, deployKey, tt.workspace, tt.repo, "git-receive-pack")
+			if err != nil {
+				t.Fatalf("checkGitPermissions() unexpected error = %v", err)
+			}
+			if canRead != tt.wantRead || canWrite != tt.wantWrite {
+				t.Errorf("checkGitPermissions() = (%v, %v), want (%v, %v)", canRead, canWrite, tt.wantRead, tt.wantWrite)
+			}
+		})
+	}
+}
@@ -15,8 +15,8 @@ type Token struct {
 	Type         string
 }
 
-// Генерация JWT ключа
-func GenJwtToken(secret []byte, tokenType string, userid uuid.UUID) (*Token, error) {
+// Генерация JWT ключа. sessionId - ID сессии пользователя, передается в claim sid
+func GenJwtToken(secret []byte, tokenType string, userid uuid.UUID, sessionId uuid.UUID) (*Token, error) {
 	u, _ := uuid.NewV4()
 	claims := jwt.MapClaims{
 		"exp":        jwt.NewNumericDate(time.Now().Add(types.TokenExpiresPeriod)),
@@ -25,6 +25,9 @@ func GenJwtToken(secret []byte, tokenType string, userid uuid.UUID) (*Token, err
 		"token_type": tokenType,
 		"user_id":    userid.String(),
 	}
+	if !sessionId.IsNil() {
+		claims["sid"] = sessionId.String()
+	}
 	if tokenType == "refresh" {
 		claims["exp"] = jwt.NewNumericDate(time.Now().Add(types.RefreshTokenExpiresPeriod))
 	}
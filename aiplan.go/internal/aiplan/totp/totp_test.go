@@ -0,0 +1,62 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCode(t *testing.T) {
+	// тестовые значения RFC 6238 для SHA1, последние 6 цифр
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	cases := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, expected := range cases {
+		code, err := Code(secret, Step(time.Unix(unix, 0)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if code != expected {
+			t.Errorf("%d: code %s, expected %s", unix, code, expected)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	prev, _ := Code(secret, Step(now)-1)
+	current, _ := Code(secret, Step(now))
+
+	step, ok := Validate(secret, current[:3]+" "+current[3:], now, 0)
+	if !ok || step != Step(now) {
+		t.Fatalf("Current code rejected")
+	}
+	if _, ok := Validate(secret, current, now, step); ok {
+		t.Error("Code reused")
+	}
+	if _, ok := Validate(secret, prev, now, 0); !ok {
+		t.Error("Previous step code rejected")
+	}
+	if _, ok := Validate(secret, prev, now.Add(2*Period), 0); ok {
+		t.Error("Expired code accepted")
+	}
+	if _, ok := Validate(secret, "12345", now, 0); ok {
+		t.Error("Short code accepted")
+	}
+
+	uri := ProvisioningURI(secret, "AIPlan", "user@example.com")
+	if !strings.HasPrefix(uri, "otpauth://totp/AIPlan:user@example.com?") || !strings.Contains(uri, "secret="+secret) {
+		t.Errorf("Unexpected URI: %s", uri)
+	}
+}
@@ -94,7 +94,9 @@ var (
 	Blocks   = FieldMapping{"blocks_list", "blocks"}
 	Blocking = FieldMapping{"blockers_list", "blocking"}
 
-	Attachment = FieldMapping{"", "attachment"}
+	Attachment  = FieldMapping{"", "attachment"}
+	ShareLink   = FieldMapping{"", "share_link"}
+	DocTemplate = FieldMapping{"", "doc_template"}
 
 	Description     = FieldMapping{"description", "description"}
 	DescriptionHtml = FieldMapping{"description_html", "description"}
@@ -16,6 +16,22 @@ const (
 	EmailCodeLimitReq               = time.Minute
 )
 
+// Области действия персональных токенов доступа
+const (
+	// Чтение данных, доступных пользователю
+	TokenScopeRead = "read"
+	// Создание и изменение задач и связанных с ними данных, включает чтение
+	TokenScopeIssuesWrite = "issues:write"
+	// Создание и изменение документов, включает чтение
+	TokenScopeDocsWrite = "docs:write"
+	// Полный доступ к аккаунту, включая администрирование
+	TokenScopeAdmin = "admin"
+	// Доступ к MCP серверу
+	TokenScopeMCP = "mcp"
+)
+
+var TokenScopes = []string{TokenScopeRead, TokenScopeIssuesWrite, TokenScopeDocsWrite, TokenScopeAdmin, TokenScopeMCP}
+
 const (
 	LayerRoot EntityLayer = iota
 	LayerWorkspace
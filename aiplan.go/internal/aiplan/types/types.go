@@ -359,6 +359,19 @@ type FormFields struct {
 	IssueNameField bool                 `json:"issue_name_field"`
 	Validate       *ValidationRule      `json:"validate,omitempty" extensions:"x-nullable"`
 	DependOn       *FormFieldDependency `json:"depend_on,omitempty" extensions:"x-nullable"`
+	IssueField     *FormIssueField      `json:"issue_field,omitempty" extensions:"x-nullable"`
+	// Индекс раздела формы, к которому относится поле
+	Section int `json:"section,omitempty"`
+}
+
+// FormIssueField - сопоставление поля формы атрибуту задачи, создаваемой по ответу в проекте формы
+type FormIssueField struct {
+	Attribute string `json:"attribute" enums:"priority,labels,assignees,target_date,sprint,property,issue"`
+	// Шаблон свойства проекта для attribute = property
+	PropertyId *uuid.UUID `json:"property_id,omitempty" extensions:"x-nullable"`
+	// Значения атрибута для вариантов ответа select/multiselect в порядке вариантов:
+	// приоритет, id тега, пользователя или спринта, вариант свойства. Пустая строка - вариант не сопоставлен
+	Values []string `json:"values,omitempty"`
 }
 
 type FormFieldDependency struct {
@@ -367,6 +380,55 @@ type FormFieldDependency struct {
 	ExpectedValue bool `json:"value"`                                // Ожидаемое значение зависимого поля (или варианта ответа)
 }
 
+// FormSectionEnd - переход к отправке формы вместо следующего раздела
+const FormSectionEnd = -1
+
+// FormSections - разделы (страницы) формы
+type FormSections []FormSection
+
+// FormSection - раздел формы. После заполнения раздела проверяются условия Branches по порядку, при первом выполненном
+// условии выполняется переход к разделу GoTo. Если ни одно условие не выполнено, выполняется переход к разделу Next,
+// а без него - к следующему по порядку разделу
+type FormSection struct {
+	Title       string              `json:"title,omitempty"`
+	Description string              `json:"description,omitempty"`
+	Branches    []FormSectionBranch `json:"branches,omitempty"`
+	Next        *int                `json:"next,omitempty" extensions:"x-nullable"`
+}
+
+// FormSectionBranch - условный переход между разделами. Условие задается так же, как depend_on поля
+type FormSectionBranch struct {
+	FormFieldDependency
+	GoTo int `json:"go_to"` // Индекс раздела для перехода или -1 для отправки формы
+}
+
+func (fs FormSections) Value() (driver.Value, error) {
+	b, err := json.Marshal(fs)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (fs *FormSections) Scan(value interface{}) error {
+	if value == nil {
+		*fs = FormSections{}
+		return nil
+	}
+
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return errors.New(fmt.Sprint("Failed to unmarshal JSONB value:", value))
+	}
+
+	return json.Unmarshal(bytes, fs)
+}
+
 type ValidationRule struct {
 	ValidationType string        `json:"validation_type"`
 	ValueType      string        `json:"value_type,omitempty"`
@@ -1013,7 +1075,8 @@ func (ns WorkspaceMemberNS) IsNotify(field actField.ActivityField, entity Entity
 		actField.ReaderRole.Field,
 		actField.EditorRole.Field,
 		actField.Editors.Field,
-		actField.Readers.Field:
+		actField.Readers.Field,
+		actField.ShareLink.Field:
 		if isDoc {
 			return !ns.DisableDocRole
 		}
@@ -1061,6 +1124,10 @@ func (ns WorkspaceMemberNS) IsNotify(field actField.ActivityField, entity Entity
 		if isWorkspaceAdmin {
 			return !ns.DisableWorkspaceProject
 		}
+	case actField.DocTemplate.Field:
+		if isWorkspaceAdmin {
+			return !ns.DisableWorkspaceDoc
+		}
 	case actField.Form.Field:
 		if isWorkspaceAdmin {
 			return false // TODO disabled BAK-317
@@ -1148,6 +1215,47 @@ func (fn *FormAnswerNotify) Scan(value interface{}) error {
 	return nil
 }
 
+// FormProtection - защита формы от спама. Captcha и Honeypot проверяются для ответов без аутентификации
+type FormProtection struct {
+	Captcha  bool `json:"captcha"`
+	Honeypot bool `json:"honeypot"`
+	// Максимум ответов с одного IP адреса в час, 0 - без ограничения
+	RateLimit int `json:"rate_limit,omitempty"`
+	// Максимум ответов на форму, 0 - без ограничения
+	MaxAnswers int `json:"max_answers,omitempty"`
+	// Максимальный размер вложения в байтах, 0 - общее ограничение
+	MaxAttachmentSize int64 `json:"max_attachment_size,omitempty"`
+	// Допустимые типы вложений: MIME-тип (image/png), группа (image/*) или расширение (.pdf)
+	AttachmentTypes []string `json:"attachment_types,omitempty"`
+}
+
+func (fp FormProtection) Value() (driver.Value, error) {
+	b, err := json.Marshal(fp)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (fp *FormProtection) Scan(value interface{}) error {
+	if value == nil {
+		*fp = FormProtection{}
+		return nil
+	}
+
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return errors.New(fmt.Sprint("Failed to unmarshal JSONB value:", value))
+	}
+
+	return json.Unmarshal(bytes, fp)
+}
+
 type JsonURL struct {
 	URL *url.URL `swaggertype:"string" format:"uri"`
 }
@@ -26,6 +26,7 @@ const (
 	formFieldAttachment  = "attachment"
 	formFieldSelect      = "select"
 	formFieldMultiselect = "multiselect"
+	formFieldHidden      = "hidden"
 )
 
 type FormValidateStruct struct {
@@ -53,6 +54,7 @@ func FormValidator() map[string]validateFunc {
 	validMap[formFieldNumeric] = validateNumeric
 	validMap[formFieldCheckbox] = validateCheckbox
 	validMap[formFieldInput] = validateString
+	validMap[formFieldHidden] = validateString
 	validMap[formFieldTextarea] = validateString
 	validMap[formFieldColor] = validateColor
 	validMap[formFieldDate] = validateTimestamp